@@ -0,0 +1,100 @@
+package UI
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/client/storage"
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/rivo/tview"
+)
+
+// conflict shows local and server versions of the item that was edited on another device meanwhile
+// user keeps one of them or merges them by editing the local version on top of the server one
+func (u *ui) conflict(conflict storage.Conflict) {
+	mine := tview.NewTextView().SetText(u.describeVersion(conflict.Local))
+	mine.SetBorder(true).SetTitle(" Mine ")
+	server := tview.NewTextView().SetText(u.describeVersion(conflict.Server))
+	server.SetBorder(true).SetTitle(" Server ")
+
+	id := conflict.Server.ID
+	resolve := func(keepLocal bool) {
+		if err := u.storage.ResolveConflict(id, keepLocal); err != nil {
+			u.throwModal(err, "conflict")
+			return
+		}
+		u.goToMenu()
+	}
+	buttons := tview.NewForm().
+		AddButton("Keep mine", func() {
+			resolve(true)
+		}).
+		AddButton("Keep server", func() {
+			resolve(false)
+		}).
+		AddButton("Merge", func() {
+			u.merge(conflict)
+		}).
+		AddButton("Back", func() {
+			u.goToMenu()
+		})
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(tview.NewFlex().
+			AddItem(mine, 0, 1, false).
+			AddItem(server, 0, 1, false), 0, 1, false).
+		AddItem(buttons, 3, 0, true)
+	layout.SetBorder(true).SetTitle(fmt.Sprintf(" Conflict in %s ", u.nameOf(conflict.Server))).SetTitleAlign(tview.AlignCenter)
+
+	u.pages.AddAndSwitchToPage("conflict", u.grid(u.addItemButtons(), layout), true)
+}
+
+// merge opens the edit page with local version of the item on top of the server revision
+// saving it resolves the conflict
+func (u *ui) merge(conflict storage.Conflict) {
+	data, err := u.storage.Decrypt(conflict.Local)
+	if err != nil {
+		if errors.Is(err, models.ErrDeleted) {
+			err = fmt.Errorf("deleted item can't be merged, keep one of the versions")
+		}
+		u.throwModal(err, "conflict")
+		return
+	}
+	wrapper := conflict.Local
+	wrapper.Revision = conflict.Server.Revision
+	// Form shows the name as is and saving encrypts it again
+	if wrapper, err = u.storage.Label(wrapper); err != nil {
+		u.throwModal(err, "conflict")
+		return
+	}
+
+	switch elem := data.(type) {
+	case models.Login:
+		u.pages.AddAndSwitchToPage("login", u.grid(u.addItemButtons(), u.login(elem, wrapper)), true)
+	case models.ArbitraryText:
+		u.pages.AddAndSwitchToPage("text", u.grid(u.addItemButtons(), u.text(elem, wrapper)), true)
+	case models.BankCard:
+		u.pages.AddAndSwitchToPage("bank_card", u.grid(u.addItemButtons(), u.bankCard(elem, wrapper)), true)
+	case models.Binary:
+		u.pages.AddAndSwitchToPage("binary", u.grid(u.addItemButtons(), u.binary(elem, wrapper)), true)
+	case models.SSHKey:
+		u.pages.AddAndSwitchToPage("ssh_key", u.grid(u.addItemButtons(), u.sshKey(elem, wrapper)), true)
+	case models.Folder:
+		u.editFolder(elem, wrapper)
+	}
+}
+
+// describeVersion returns human-readable content of the item version
+func (u *ui) describeVersion(wrapper models.DataWrapper) string {
+	header := fmt.Sprintf("%s\nRevision %d, updated %s\n\n", u.nameOf(wrapper), wrapper.Revision,
+		time.Unix(wrapper.UpdatedAt, 0).Format(time.DateTime))
+	data, err := u.storage.Decrypt(wrapper)
+	if err != nil {
+		if errors.Is(err, models.ErrDeleted) {
+			return header + "----deleted----"
+		}
+		return header + err.Error()
+	}
+	return header + describe(data)
+}
@@ -0,0 +1,200 @@
+package UI
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/client/storage"
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/rivo/tview"
+)
+
+// emergency shows trusted contacts of the user with the form to add one more, and users that trust the user
+// selecting a contact lets reject its request or remove it, selecting a grantor requests access or opens its vault
+func (u *ui) emergency() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	contacts, err := u.mediator.EmergencyContacts(ctx)
+	if err != nil {
+		u.throwModal(err, "menu")
+		return
+	}
+	grantors, err := u.mediator.EmergencyGrantors(ctx)
+	if err != nil {
+		u.throwModal(err, "menu")
+		return
+	}
+
+	email, waitDays := "", "7"
+	form := tview.NewForm().
+		AddInputField("Email", "", 30, nil, func(in string) {
+			email = in
+		}).
+		AddInputField("Waiting days", waitDays, 5, tview.InputFieldInteger, func(in string) {
+			waitDays = in
+		}).
+		AddButton("Trust", func() {
+			days, err := strconv.ParseInt(waitDays, 10, 64)
+			if email == "" || err != nil {
+				u.throwModal(fmt.Errorf("email and waiting days are required"), "emergency")
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if _, err = u.mediator.AddEmergencyContact(ctx, email, days); err != nil {
+				u.throwModal(err, "emergency")
+				return
+			}
+			u.emergency()
+		}).
+		AddButton("Back", func() {
+			u.goToMenu()
+		})
+	form.SetBorder(true).SetTitle(" New trusted contact ").SetTitleAlign(tview.AlignCenter)
+
+	now := time.Now().Unix()
+	contactList := tview.NewList()
+	for _, contact := range contacts {
+		contact := contact
+		contactList.AddItem(contact.GranteeID, describeAccess(contact, now), 0, func() {
+			u.emergencyContact(contact)
+		})
+	}
+	contactList.SetBorder(true).SetTitle(" Trusted contacts ")
+
+	grantorList := tview.NewList()
+	for _, grantor := range grantors {
+		grantor := grantor
+		grantorList.AddItem(grantor.GrantorID, describeAccess(grantor, now), 0, func() {
+			if grantor.Status == models.EmergencyGranted {
+				u.emergencyVault(grantor.GrantorID)
+				return
+			}
+			u.requestEmergency(grantor)
+		})
+	}
+	grantorList.SetBorder(true).SetTitle(" Trusted by ")
+
+	lists := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(contactList, 0, 1, false).
+		AddItem(grantorList, 0, 1, false)
+	layout := tview.NewFlex().
+		AddItem(form, 0, 1, true).
+		AddItem(lists, 0, 1, false)
+	u.pages.AddAndSwitchToPage("emergency", u.grid(u.addItemButtons(), layout), true)
+}
+
+// emergencyContact asks what to do with the trusted contact, requested access can be rejected
+func (u *ui) emergencyContact(contact models.EmergencyAccess) {
+	buttons := []string{"Remove", "Back"}
+	if contact.Status == models.EmergencyRequested || contact.Status == models.EmergencyGranted {
+		buttons = append([]string{"Reject"}, buttons...)
+	}
+	u.pages.AddAndSwitchToPage("emergency_contact", tview.NewModal().
+		SetText(fmt.Sprintf("%s\n%s", contact.GranteeID, describeAccess(contact, time.Now().Unix()))).
+		AddButtons(buttons).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			var err error
+			switch buttonLabel {
+			case "Reject":
+				err = u.mediator.RejectEmergency(ctx, contact.GranteeID)
+			case "Remove":
+				err = u.mediator.RemoveEmergencyContact(ctx, contact.GranteeID)
+			}
+			if err != nil {
+				u.throwModal(err, "emergency")
+				return
+			}
+			u.emergency()
+		}), false)
+}
+
+// requestEmergency asks to request access to the vault of the grantor
+func (u *ui) requestEmergency(grantor models.EmergencyAccess) {
+	text := fmt.Sprintf("Request access to the vault of %s?\nAccess is granted in %d days unless %s rejects it",
+		grantor.GrantorID, grantor.WaitDays, grantor.GrantorID)
+	if grantor.Status == models.EmergencyRequested {
+		text = fmt.Sprintf("Access to the vault of %s is requested\nIt is granted at %s unless %s rejects it",
+			grantor.GrantorID, time.Unix(grantor.GrantedAt(), 0).Format(time.DateTime), grantor.GrantorID)
+	}
+	buttons := []string{"Request", "Back"}
+	if grantor.Status == models.EmergencyRequested {
+		buttons = []string{"Back"}
+	}
+	u.pages.AddAndSwitchToPage("emergency_request", tview.NewModal().
+		SetText(text).
+		AddButtons(buttons).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			if buttonLabel != "Request" {
+				u.pages.SwitchToPage("emergency")
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if _, err := u.mediator.RequestEmergency(ctx, grantor.GrantorID); err != nil {
+				u.throwModal(err, "emergency")
+				return
+			}
+			u.emergency()
+		}), false)
+}
+
+// emergencyVault shows items of the grantor read only, they are never saved to the vault of the user
+func (u *ui) emergencyVault(grantorID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	vault, err := u.mediator.EmergencyVault(ctx, grantorID)
+	if err != nil {
+		u.throwModal(err, "emergency")
+		return
+	}
+
+	sort.Slice(vault.Data, func(i, j int) bool {
+		return strings.ToLower(vault.Data[i].Name) < strings.ToLower(vault.Data[j].Name)
+	})
+	list := tview.NewList()
+	for _, item := range vault.Data {
+		item := item
+		if item.Type == models.FolderType {
+			continue
+		}
+		list.AddItem(item.Name, string(item.Type), 0, func() {
+			data, err := storage.OpenWith(item, vault.Secret)
+			if err != nil {
+				u.throwModal(err, "emergency_vault")
+				return
+			}
+			u.pages.AddAndSwitchToPage("emergency_item", tview.NewModal().
+				SetText(fmt.Sprintf("%s\n\n%s", item.Name, describe(data))).
+				AddButtons([]string{"Back"}).
+				SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+					u.pages.SwitchToPage("emergency_vault")
+				}), false)
+		})
+	}
+	list.AddItem("Back", "", 0, func() {
+		u.emergency()
+	})
+	list.SetBorder(true).SetTitle(fmt.Sprintf(" Vault of %s ", grantorID)).SetTitleAlign(tview.AlignCenter)
+
+	u.pages.AddAndSwitchToPage("emergency_vault", u.grid(u.addItemButtons(), list), true)
+}
+
+// describeAccess returns human-readable state of emergency access at the unix time
+func describeAccess(access models.EmergencyAccess, now int64) string {
+	switch access.StatusAt(now) {
+	case models.EmergencyRequested:
+		return fmt.Sprintf("requested, granted at %s unless rejected", time.Unix(access.GrantedAt(), 0).Format(time.DateTime))
+	case models.EmergencyGranted:
+		return "access granted"
+	case models.EmergencyRejected:
+		return fmt.Sprintf("rejected, waiting period %d days", access.WaitDays)
+	}
+	return fmt.Sprintf("waiting period %d days", access.WaitDays)
+}
@@ -0,0 +1,132 @@
+package UI
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gynshu-one/goph-keeper/client/storage"
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/rivo/tview"
+)
+
+// favoritesQuery is the quick list every user has
+const favoritesQuery = "favorites"
+
+// quickLists creates the list of favorites and saved filters, selecting one shows items matching it
+func (u *ui) quickLists() tview.Primitive {
+	list := tview.NewList()
+	show := func(query string) func() {
+		return func() {
+			u.query = query
+			u.showMenu()
+		}
+	}
+	list.AddItem("Favorites", favoritesQuery, 0, show(favoritesQuery))
+	saved, err := u.storage.SavedFilters()
+	if err != nil {
+		list.AddItem(describeError(err), "", 0, nil)
+	}
+	for _, filter := range saved {
+		list.AddItem(filter.Name, filter.Query, 0, show(filter.Query))
+	}
+	list.AddItem("Filters...", "tag:<tag> type:<type> favorites", 0, func() {
+		u.filters()
+	})
+	// quick list shown in the menu stays selected
+	for i := 0; i < list.GetItemCount()-1; i++ {
+		if _, query := list.GetItemText(i); query == u.query {
+			list.SetCurrentItem(i)
+		}
+	}
+	list.SetBorder(true).SetTitle(" Quick lists ")
+	return list
+}
+
+// filters shows saved filters with the form to try a filter and save it as a quick list
+// selecting a saved filter lets delete it
+func (u *ui) filters() {
+	saved, err := u.storage.SavedFilters()
+	if err != nil {
+		u.throwModal(err, "menu")
+		return
+	}
+
+	name, query := "", u.query
+	form := tview.NewForm().
+		AddInputField("Query", query, 40, nil, func(in string) {
+			query = in
+		}).
+		AddInputField("Name", "", 30, nil, func(in string) {
+			name = in
+		}).
+		AddButton("Show", func() {
+			if _, err := storage.ParseFilter(query); err != nil {
+				u.throwModal(err, "filters")
+				return
+			}
+			u.query = strings.TrimSpace(query)
+			u.goToMenu()
+		}).
+		AddButton("Save", func() {
+			if name == "" || strings.TrimSpace(query) == "" {
+				u.throwModal(fmt.Errorf("name and query are required"), "filters")
+				return
+			}
+			if _, err := storage.ParseFilter(query); err != nil {
+				u.throwModal(err, "filters")
+				return
+			}
+			err := u.storage.AddEncrypt(&models.SavedFilter{Name: name, Query: strings.TrimSpace(query)},
+				models.DataWrapper{Type: models.FilterType})
+			if err != nil {
+				u.throwModal(err, "filters")
+				return
+			}
+			u.filters()
+		}).
+		AddButton("Back", func() {
+			u.goToMenu()
+		})
+	form.SetBorder(true).SetTitle(" Filter items ").SetTitleAlign(tview.AlignCenter)
+
+	list := tview.NewList()
+	for _, filter := range saved {
+		filter := filter
+		list.AddItem(filter.Name, filter.Query, 0, func() {
+			u.deleteFilter(filter)
+		})
+	}
+	list.SetBorder(true).SetTitle(" Saved filters ")
+
+	layout := tview.NewFlex().
+		AddItem(form, 0, 1, true).
+		AddItem(list, 0, 1, false)
+	u.pages.AddAndSwitchToPage("filters", u.grid(u.addItemButtons(), layout), true)
+}
+
+// deleteFilter asks to delete the saved filter, items matching it are kept
+func (u *ui) deleteFilter(filter storage.SavedFilter) {
+	u.pages.AddAndSwitchToPage("filter_delete", tview.NewModal().
+		SetText(fmt.Sprintf("Delete quick list %s?\n%s", filter.Name, filter.Query)).
+		AddButtons([]string{"Delete", "Back"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			if buttonLabel != "Delete" {
+				u.pages.SwitchToPage("filters")
+				return
+			}
+			if err := u.storage.Delete(filter.Wrapper.ID); err != nil {
+				u.throwModal(err, "filters")
+				return
+			}
+			u.filters()
+		}), false)
+}
+
+// metaFields adds tags and favorite flag of the item to its form
+func metaFields(form *tview.Form, meta *models.Meta) {
+	form.AddInputField("Tags", strings.Join(meta.Tags, ", "), 30, nil, func(in string) {
+		meta.Tags = models.ParseTags(in)
+	}).AddCheckbox("Favorite", meta.Favorite, func(checked bool) {
+		meta.Favorite = checked
+	})
+}
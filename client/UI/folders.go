@@ -0,0 +1,164 @@
+package UI
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gynshu-one/goph-keeper/client/storage"
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/rivo/tview"
+)
+
+// folderPane creates the tree of folders and quick lists with buttons to change the selected folder
+// selecting a folder shows items in it, the root of the tree is the whole vault
+func (u *ui) folderPane() tview.Primitive {
+	root := tview.NewTreeNode("Vault").SetReference("")
+	tree := tview.NewTreeView().SetRoot(root).SetCurrentNode(root)
+	var add func(node *tview.TreeNode, parentID string)
+	add = func(node *tview.TreeNode, parentID string) {
+		for _, folder := range sortedFolders(u.folders, parentID) {
+			child := tview.NewTreeNode(folder.Name).SetReference(folder.Wrapper.ID)
+			node.AddChild(child)
+			if folder.Wrapper.ID == u.folder {
+				tree.SetCurrentNode(child)
+			}
+			add(child, folder.Wrapper.ID)
+		}
+	}
+	add(root, "")
+	tree.SetSelectedFunc(func(node *tview.TreeNode) {
+		u.folder, u.query = node.GetReference().(string), ""
+		u.showMenu()
+	})
+	tree.SetBorder(true).SetTitle(" Folders ")
+
+	buttons := tview.NewForm().AddButton("New folder", func() {
+		u.editFolder(models.Folder{}, models.DataWrapper{Type: models.FolderType, FolderID: u.folder})
+	})
+	if folder, ok := u.folders[u.folder]; ok {
+		buttons.AddButton("Edit", func() {
+			u.editFolder(models.Folder{Name: folder.Name}, folder.Wrapper)
+		}).AddButton("Delete", func() {
+			u.deleteFolder(folder)
+		})
+	}
+
+	return tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(tree, 0, 2, true).
+		AddItem(u.quickLists(), 0, 1, false).
+		AddItem(buttons, 3, 0, false)
+}
+
+// editFolder creates a form to name the folder and choose the folder it is in, the same form is used for new folders
+func (u *ui) editFolder(data models.Folder, wrapper models.DataWrapper) {
+	parentID := wrapper.FolderID
+	form := tview.NewForm().
+		AddInputField("Name", data.Name, 30, nil, func(in string) {
+			data.Name = in
+		})
+	u.folderField(form, parentID, func(folderID string) {
+		parentID = folderID
+	})
+	form.AddButton("Save", func() {
+		if data.Name == "" {
+			u.throwModal(fmt.Errorf("name is empty"), "folder")
+			return
+		}
+		// New folder can't be inside itself, so it is put into the chosen one at once
+		if wrapper.ID == "" {
+			wrapper.FolderID = parentID
+		}
+		err := u.storage.AddEncrypt(&data, wrapper)
+		if err == nil && wrapper.ID != "" && parentID != wrapper.FolderID {
+			err = u.storage.Move(wrapper.ID, parentID)
+		}
+		if err != nil {
+			u.throwModal(err, "folder")
+			return
+		}
+		u.goToMenu()
+	}).AddButton("Back", func() {
+		u.goToMenu()
+	})
+	if wrapper.ID != "" {
+		form.SetTitle(" Edit folder ")
+	} else {
+		form.SetTitle(" New folder ")
+	}
+	form.SetBorder(true).SetTitleAlign(tview.AlignCenter)
+	u.pages.AddAndSwitchToPage("folder", u.grid(u.addItemButtons(), form), true)
+}
+
+// deleteFolder asks to delete the folder, items and folders in it are moved to its parent
+func (u *ui) deleteFolder(folder storage.Folder) {
+	u.pages.AddAndSwitchToPage("folder_delete", tview.NewModal().
+		SetText(fmt.Sprintf("Delete folder %s?\nItems in it are moved to %s", folder.Name,
+			folderPath(u.folders, folder.ParentID))).
+		AddButtons([]string{"Delete", "Back"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			if buttonLabel != "Delete" {
+				u.pages.SwitchToPage("menu")
+				return
+			}
+			if err := u.storage.DeleteFolder(folder.Wrapper.ID); err != nil {
+				u.throwModal(err, "menu")
+				return
+			}
+			u.folder = folder.ParentID
+			u.goToMenu()
+		}), false)
+}
+
+// folderField adds a drop-down to choose the folder to the form, selected is called with the id of the chosen one
+func (u *ui) folderField(form *tview.Form, folderID string, selected func(folderID string)) {
+	ids := []string{""}
+	for id := range u.folders {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return strings.ToLower(folderPath(u.folders, ids[i])) < strings.ToLower(folderPath(u.folders, ids[j]))
+	})
+	paths := make([]string, len(ids))
+	current := 0
+	for i, id := range ids {
+		paths[i] = folderPath(u.folders, id)
+		if id == storage.FolderOf(models.DataWrapper{FolderID: folderID}, u.folders) {
+			current = i
+		}
+	}
+	form.AddDropDown("Folder", paths, current, func(_ string, index int) {
+		if index >= 0 {
+			selected(ids[index])
+		}
+	})
+}
+
+// folderPath returns names of the folder and the folders it is in, e.g. "/work/servers"
+func folderPath(folders map[string]storage.Folder, folderID string) string {
+	var names []string
+	// Folders don't loop, but the depth is limited anyway
+	for i := 0; folderID != "" && i <= len(folders); i++ {
+		folder, ok := folders[folderID]
+		if !ok {
+			break
+		}
+		names = append([]string{folder.Name}, names...)
+		folderID = folder.ParentID
+	}
+	return "/" + strings.Join(names, "/")
+}
+
+// sortedFolders returns folders directly in the parent sorted by name
+func sortedFolders(folders map[string]storage.Folder, parentID string) []storage.Folder {
+	var result []storage.Folder
+	for _, folder := range folders {
+		if folder.ParentID == parentID {
+			result = append(result, folder)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Name) < strings.ToLower(result[j].Name)
+	})
+	return result
+}
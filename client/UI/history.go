@@ -0,0 +1,101 @@
+package UI
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/rivo/tview"
+)
+
+// history shows previous versions of the item received from server
+// selecting a version shows its content and allows to restore it
+func (u *ui) history(wrapper models.DataWrapper) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	revisions, err := u.mediator.History(ctx, wrapper.ID)
+	if err != nil {
+		u.throwModal(err, "menu")
+		return
+	}
+
+	list := tview.NewList()
+	for _, revision := range revisions {
+		revision := revision
+		updated := time.Unix(revision.UpdatedAt, 0).Format(time.DateTime)
+		if revision.DeletedAt > 0 {
+			list.AddItem(fmt.Sprintf("Revision %d", revision.Revision), updated+" ----deleted----", 0, nil)
+			continue
+		}
+		list.AddItem(fmt.Sprintf("Revision %d", revision.Revision), updated, 0, func() {
+			u.revision(revision)
+		})
+	}
+	list.AddItem("Back", "", 0, func() {
+		u.goToMenu()
+	})
+	list.SetBorder(true).SetTitle(fmt.Sprintf(" History of %s ", u.nameOf(wrapper))).SetTitleAlign(tview.AlignCenter)
+
+	u.pages.AddAndSwitchToPage("history", u.grid(u.addItemButtons(), list), true)
+}
+
+// revision shows content of the previous version of the item and asks to restore it
+func (u *ui) revision(revision models.DataWrapper) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	revision, err := u.mediator.Revision(ctx, revision.ID, revision.Revision)
+	if err != nil {
+		u.throwModal(err, "history")
+		return
+	}
+	data, err := u.storage.Decrypt(revision)
+	if err != nil {
+		u.throwModal(err, "history")
+		return
+	}
+
+	u.pages.AddAndSwitchToPage("revision", tview.NewModal().
+		SetText(fmt.Sprintf("%s (revision %d)\n\n%s", u.nameOf(revision), revision.Revision, describe(data))).
+		AddButtons([]string{"Restore", "Back"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			if buttonLabel != "Restore" {
+				u.pages.SwitchToPage("history")
+				return
+			}
+			if err = u.storage.Restore(revision); err != nil {
+				u.throwModal(err, "history")
+				return
+			}
+			u.goToMenu()
+		}), false)
+}
+
+// describe returns human-readable content of decrypted item
+func describe(data any) string {
+	var b strings.Builder
+	switch elem := data.(type) {
+	case models.Login:
+		fmt.Fprintf(&b, "Info: %s\nUsername: %s\nPassword: %s\n", elem.Info, elem.Username, elem.Password)
+		fmt.Fprintf(&b, "OneTimeOrigin: %s\nRecoveryCodes: %s", elem.OneTimeOrigin, elem.RecoveryCodes)
+	case models.ArbitraryText:
+		fmt.Fprintf(&b, "Text: %s", elem.Text)
+	case models.BankCard:
+		fmt.Fprintf(&b, "Info: %s\nCardNum: %s\nCardName: %s\n", elem.Info, elem.CardNum, elem.CardName)
+		fmt.Fprintf(&b, "CardCvv: %s\nCardExp: %s", elem.CardCvv, elem.CardExp)
+	case models.Binary:
+		fmt.Fprintf(&b, "Info: %s\nSize: %d bytes", elem.Info, elem.Size())
+	case models.SSHKey:
+		fmt.Fprintf(&b, "Comment: %s\nPublicKey: %s", elem.Comment, elem.PublicKey)
+	case models.Folder:
+		fmt.Fprintf(&b, "Folder: %s", elem.Name)
+	case models.SavedFilter:
+		fmt.Fprintf(&b, "Quick list: %s\nQuery: %s", elem.Name, elem.Query)
+	}
+	if labeled, ok := data.(models.Labeled); ok {
+		meta := labeled.Labels()
+		fmt.Fprintf(&b, "\nTags: %s\nFavorite: %t", strings.Join(meta.Tags, ", "), meta.Favorite)
+	}
+	return b.String()
+}
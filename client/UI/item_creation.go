@@ -3,7 +3,6 @@ package UI
 import (
 	"context"
 	"fmt"
-	"io"
 	"os"
 
 	"github.com/gynshu-one/goph-keeper/common/models"
@@ -16,8 +15,12 @@ func (u *ui) text(data models.ArbitraryText, wrapper models.DataWrapper) *tview.
 		data = models.ArbitraryText{}
 	}
 	if wrapper.ID == "" {
+		// New item of an organization keeps its owner and collection, new item of the user keeps its folder
 		wrapper = models.DataWrapper{
-			Type: models.ArbitraryTextType,
+			Type:         models.ArbitraryTextType,
+			OwnerID:      wrapper.OwnerID,
+			CollectionID: wrapper.CollectionID,
+			FolderID:     wrapper.FolderID,
 		}
 	}
 	form := tview.NewForm().
@@ -37,7 +40,7 @@ func (u *ui) text(data models.ArbitraryText, wrapper models.DataWrapper) *tview.
 				return
 			}
 
-			err := u.storage.AddEncrypt(&data, wrapper)
+			err := u.save(&data, wrapper)
 			if err != nil {
 				u.throwModal(err, "text")
 				return
@@ -46,21 +49,11 @@ func (u *ui) text(data models.ArbitraryText, wrapper models.DataWrapper) *tview.
 		}).AddButton("Back", func() {
 		u.goToMenu()
 	})
+	metaFields(form, &data.Meta)
+	u.itemFolderField(form, &wrapper)
 	// meaning we are creating item not editing
 	if wrapper.ID != "" {
-		form.AddButton("Delete", func() {
-			err := u.storage.Delete(wrapper.ID)
-			if err != nil {
-				u.throwModal(err, "text")
-				return
-			}
-			err = u.mediator.Sync(context.Background())
-			if err != nil {
-				u.throwModal(err, "text")
-				return
-			}
-			u.throwModal(fmt.Errorf("item will be deleted from server in 30 days"), "text")
-		})
+		u.ownerButtons(form, wrapper, "text")
 		form.SetTitle(" Edit text ")
 	} else {
 		form.SetTitle(" Add text ")
@@ -74,8 +67,12 @@ func (u *ui) bankCard(data models.BankCard, wrapper models.DataWrapper) *tview.F
 		data = models.BankCard{}
 	}
 	if wrapper.ID == "" {
+		// New item of an organization keeps its owner and collection, new item of the user keeps its folder
 		wrapper = models.DataWrapper{
-			Type: models.BankCardType,
+			Type:         models.BankCardType,
+			OwnerID:      wrapper.OwnerID,
+			CollectionID: wrapper.CollectionID,
+			FolderID:     wrapper.FolderID,
 		}
 	}
 	form := tview.NewForm().
@@ -106,7 +103,7 @@ func (u *ui) bankCard(data models.BankCard, wrapper models.DataWrapper) *tview.F
 				u.throwModal(fmt.Errorf("card number is empty"), "bank_card")
 				return
 			}
-			err := u.storage.AddEncrypt(&data, wrapper)
+			err := u.save(&data, wrapper)
 			if err != nil {
 				u.throwModal(err, "bank_card")
 				return
@@ -115,21 +112,11 @@ func (u *ui) bankCard(data models.BankCard, wrapper models.DataWrapper) *tview.F
 		}).AddButton("Back", func() {
 		u.goToMenu()
 	})
+	metaFields(form, &data.Meta)
+	u.itemFolderField(form, &wrapper)
 	// meaning we are creating item not editing
 	if wrapper.ID != "" {
-		form.AddButton("Delete", func() {
-			err := u.storage.Delete(wrapper.ID)
-			if err != nil {
-				u.throwModal(err, "bank_card")
-				return
-			}
-			err = u.mediator.Sync(context.Background())
-			if err != nil {
-				u.throwModal(err, "bank_card")
-				return
-			}
-			u.throwModal(fmt.Errorf("item will be deleted from server in 30 days"), "bank_card")
-		})
+		u.ownerButtons(form, wrapper, "bank_card")
 		form.SetTitle(" Edit bank card ")
 	} else {
 		form.SetTitle(" Add bank card ")
@@ -139,16 +126,18 @@ func (u *ui) bankCard(data models.BankCard, wrapper models.DataWrapper) *tview.F
 }
 
 // binary creates a form for binary data, the same for will be used for editing
+// the file is encrypted to upload queue on save and uploaded on sync, it's downloaded only when saved to disk
 func (u *ui) binary(data models.Binary, wrapper models.DataWrapper) *tview.Form {
-	if data.Binary == nil {
+	if data.Binary == nil && data.Blob == nil {
 		data = models.Binary{}
 	}
 	if wrapper.ID == "" {
 		wrapper = models.DataWrapper{
-			Type: models.BinaryType,
+			Type:     models.BinaryType,
+			FolderID: wrapper.FolderID,
 		}
 	}
-	path := ""
+	path, saveTo := "", ""
 	form := tview.NewForm().
 		AddInputField("Name", wrapper.Name, 30, nil, func(in string) {
 			wrapper.Name = in
@@ -160,34 +149,30 @@ func (u *ui) binary(data models.Binary, wrapper models.DataWrapper) *tview.Form
 			path = in
 		}).
 		AddButton("Save", func() {
-			file, err := os.Open(path)
-			if err != nil {
-				u.throwModal(err, "binary")
-				return
-			}
-			defer func(file *os.File) {
-				err = file.Close()
-				if err != nil {
-					u.throwModal(err, "binary")
-					return
-				}
-			}(file)
-			readAll, err := io.ReadAll(file)
-			if err != nil {
-				u.throwModal(err, "binary")
+			if wrapper.Name == "" {
+				u.throwModal(fmt.Errorf("name is empty"), "binary")
 				return
 			}
-			data.Binary = readAll
-			if len(data.Binary) == 0 {
+			// Item that is edited keeps its file if no new one is chosen
+			if path == "" && data.Size() == 0 {
 				u.throwModal(fmt.Errorf("binary is empty"), "binary")
 				return
 			}
-			if wrapper.Name == "" {
-				u.throwModal(fmt.Errorf("name is empty"), "binary")
+			if path != "" && !owned(wrapper) {
+				u.throwModal(errSharedFile, "binary")
 				return
 			}
+			if path != "" {
+				ref, err := u.mediator.AttachFile(path)
+				if err != nil {
+					u.throwModal(err, "binary")
+					return
+				}
+				data.Binary, data.Blob = nil, &ref
+				wrapper.Blobs = []string{ref.ID}
+			}
 
-			err = u.storage.AddEncrypt(&data, wrapper)
+			err := u.save(&data, wrapper)
 			if err != nil {
 				u.throwModal(err, "binary")
 				return
@@ -196,21 +181,31 @@ func (u *ui) binary(data models.Binary, wrapper models.DataWrapper) *tview.Form
 		}).AddButton("Back", func() {
 		u.goToMenu()
 	})
+	metaFields(form, &data.Meta)
+	u.itemFolderField(form, &wrapper)
 	// meaning we are creating item not editing
 	if wrapper.ID != "" {
-		form.AddButton("Delete", func() {
-			err := u.storage.Delete(wrapper.ID)
-			if err != nil {
-				u.throwModal(err, "binary")
+		form.AddInputField("Save to", "", 30, nil, func(in string) {
+			saveTo = in
+		})
+		form.AddButton("Save file", func() {
+			if saveTo == "" {
+				u.throwModal(fmt.Errorf("path to save to is empty"), "binary")
 				return
 			}
-			err = u.mediator.Sync(context.Background())
+			var err error
+			if data.Blob != nil {
+				err = u.mediator.SaveFile(context.Background(), *data.Blob, saveTo)
+			} else {
+				err = os.WriteFile(saveTo, data.Binary, 0o600)
+			}
 			if err != nil {
 				u.throwModal(err, "binary")
 				return
 			}
-			u.throwModal(fmt.Errorf("item will be deleted from server in 30 days"), "binary")
+			u.throwModal(fmt.Errorf("file is saved to %s", saveTo), "binary")
 		})
+		u.ownerButtons(form, wrapper, "binary")
 		form.SetTitle(" Edit binary ")
 	} else {
 		form.SetTitle(" Add binary ")
@@ -225,8 +220,12 @@ func (u *ui) login(data models.Login, wrapper models.DataWrapper) *tview.Form {
 		data = models.Login{}
 	}
 	if wrapper.ID == "" {
+		// New item of an organization keeps its owner and collection, new item of the user keeps its folder
 		wrapper = models.DataWrapper{
-			Type: models.LoginType,
+			Type:         models.LoginType,
+			OwnerID:      wrapper.OwnerID,
+			CollectionID: wrapper.CollectionID,
+			FolderID:     wrapper.FolderID,
 		}
 	}
 	form := tview.NewForm().
@@ -268,7 +267,7 @@ func (u *ui) login(data models.Login, wrapper models.DataWrapper) *tview.Form {
 					return
 				}
 			}
-			err := u.storage.AddEncrypt(&data, wrapper)
+			err := u.save(&data, wrapper)
 			if err != nil {
 				u.throwModal(err, "login")
 				return
@@ -278,21 +277,11 @@ func (u *ui) login(data models.Login, wrapper models.DataWrapper) *tview.Form {
 		u.goToMenu()
 	})
 
+	metaFields(form, &data.Meta)
+	u.itemFolderField(form, &wrapper)
 	// meaning we are creating item not editing
 	if wrapper.ID != "" {
-		form.AddButton("Delete", func() {
-			err := u.storage.Delete(wrapper.ID)
-			if err != nil {
-				u.throwModal(err, "login")
-				return
-			}
-			err = u.mediator.Sync(context.Background())
-			if err != nil {
-				u.throwModal(err, "login")
-				return
-			}
-			u.throwModal(fmt.Errorf("item will be deleted from server in 30 days"), "login")
-		})
+		u.ownerButtons(form, wrapper, "login")
 	}
 	if data.OneTimeOrigin != "" {
 		form.AddButton("Get One Time Password", func() {
@@ -316,14 +305,69 @@ func (u *ui) login(data models.Login, wrapper models.DataWrapper) *tview.Form {
 	return form
 }
 
+// ownerButtons adds buttons to delete the item, see its history and share it to the form of the item
+// items shared with the user don't have them, only their owner can do that
+// items of organizations only get Delete
+func (u *ui) ownerButtons(form *tview.Form, wrapper models.DataWrapper, page string) {
+	if org, ok := u.orgs[wrapper.OwnerID]; ok {
+		u.orgItemButtons(form, org, wrapper, page)
+		return
+	}
+	if !owned(wrapper) {
+		return
+	}
+	form.AddButton("Delete", func() {
+		err := u.storage.Delete(wrapper.ID)
+		if err != nil {
+			u.throwModal(err, page)
+			return
+		}
+		err = u.mediator.Sync(context.Background())
+		if err != nil {
+			u.throwModal(err, page)
+			return
+		}
+		u.throwModal(fmt.Errorf("item will be deleted from server in 30 days"), page)
+	})
+	form.AddButton("History", func() {
+		u.history(wrapper)
+	})
+	form.AddButton("Share", func() {
+		u.sharing(wrapper)
+	})
+}
+
+// itemFolderField adds the drop-down to move the item to another folder to the form
+// only items of the user are in folders, items of organizations are in collections
+func (u *ui) itemFolderField(form *tview.Form, wrapper *models.DataWrapper) {
+	if !owned(*wrapper) {
+		return
+	}
+	u.folderField(form, wrapper.FolderID, func(folderID string) {
+		wrapper.FolderID = folderID
+	})
+}
+
+// addItemButtons creates buttons of the header, new items are put into the folder selected in the menu
 func (u *ui) addItemButtons() *tview.Form {
+	inFolder := models.DataWrapper{FolderID: u.folder}
 	return tview.NewForm().AddButton("New Text", func() {
-		u.pages.SwitchToPage("text")
+		u.pages.AddAndSwitchToPage("text", u.grid(u.addItemButtons(), u.text(models.ArbitraryText{}, inFolder)), true)
 	}).AddButton("New Bank Card", func() {
-		u.pages.SwitchToPage("bank_card")
+		u.pages.AddAndSwitchToPage("bank_card", u.grid(u.addItemButtons(), u.bankCard(models.BankCard{}, inFolder)), true)
 	}).AddButton("New Binary", func() {
-		u.pages.SwitchToPage("binary")
+		u.pages.AddAndSwitchToPage("binary", u.grid(u.addItemButtons(), u.binary(models.Binary{}, inFolder)), true)
 	}).AddButton("New Login", func() {
-		u.pages.SwitchToPage("login")
+		u.pages.AddAndSwitchToPage("login", u.grid(u.addItemButtons(), u.login(models.Login{}, inFolder)), true)
+	}).AddButton("New SSH Key", func() {
+		u.pages.AddAndSwitchToPage("ssh_key", u.grid(u.addItemButtons(), u.sshKey(models.SSHKey{}, inFolder)), true)
+	}).AddButton("Shared with me", func() {
+		u.sharedWithMe()
+	}).AddButton("Organizations", func() {
+		u.organizations()
+	}).AddButton("Emergency access", func() {
+		u.emergency()
+	}).AddButton("Send", func() {
+		u.sends()
 	}).SetButtonsAlign(tview.AlignCenter)
 }
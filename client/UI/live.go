@@ -0,0 +1,85 @@
+package UI
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/client/sync"
+	"github.com/gynshu-one/goph-keeper/common/models"
+)
+
+const (
+	// minReconnectDelay and maxReconnectDelay bound the delay before reconnecting to event stream
+	minReconnectDelay = time.Second
+	maxReconnectDelay = time.Minute
+)
+
+// watch keeps subscription to changes made on other devices and refreshes the items list when they happen
+// it reconnects with growing delay until the app stops
+func (u *ui) watch() {
+	delay := minReconnectDelay
+	for {
+		connected := time.Now()
+		err := u.mediator.Events(context.Background(), u.refresh)
+		// Stream that was open for a while is not a failure
+		if err == nil || time.Since(connected) > maxReconnectDelay {
+			delay = minReconnectDelay
+		}
+		time.Sleep(delay)
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+// refresh makes background sync receive changes of the event if they are not synced yet
+func (u *ui) refresh(event models.ChangeEvent) {
+	if event.Cursor > u.storage.Cursor() {
+		u.scheduler.Trigger()
+	}
+}
+
+// editPages are pages with forms, background sync waits until the user leaves them
+var editPages = map[string]bool{
+	"text":           true,
+	"arbitrary text": true,
+	"bank_card":      true,
+	"bank card":      true,
+	"binary":         true,
+	"login":          true,
+	"ssh_key":        true,
+	"conflict":       true,
+	"folder":         true,
+	"filters":        true,
+}
+
+// pauseWhileEditing pauses background sync while a form is shown
+// it's called by pages every time the front page changes
+func (u *ui) pauseWhileEditing() {
+	if page, _ := u.pages.GetFrontPage(); editPages[page] {
+		u.scheduler.Pause()
+		return
+	}
+	u.scheduler.Resume()
+}
+
+// showStatus shows the state of background sync
+// items list is rebuilt only if it is shown, so pages user is working with are not interrupted
+// must be called from the app goroutine
+func (u *ui) showStatus(status sync.Status) {
+	switch {
+	case status.Syncing:
+		u.status.SetText("syncing...")
+	case status.Paused:
+		u.status.SetText("sync paused while editing")
+	case status.Err != nil:
+		u.status.SetText(fmt.Sprintf("sync failed, retry in %s: %v", status.Retry.Round(time.Second), describeError(status.Err)))
+	default:
+		u.status.SetText("synced at " + status.LastSync.Format(time.TimeOnly))
+	}
+	if page, _ := u.pages.GetFrontPage(); status.Changed && page == "menu" {
+		u.showMenu()
+	}
+}
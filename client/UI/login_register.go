@@ -2,10 +2,13 @@ package UI
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/gynshu-one/goph-keeper/client/auth"
+	"github.com/gynshu-one/goph-keeper/client/storage"
+	"github.com/gynshu-one/goph-keeper/client/sync"
 	"github.com/gynshu-one/goph-keeper/common/utils"
 	"github.com/rivo/tview"
 )
@@ -46,9 +49,7 @@ func (u *ui) register() *tview.Form {
 				u.throwModal(err, "register")
 				return
 			}
-			auth.SetSecret(secret)
-			auth.SetPass(pass)
-			u.goToMenu()
+			u.enter(pass, secret)
 			return
 		}).AddButton("SignIn", func() {
 		if secret == "" || pass == "" || auth.CurrentUser.Username == "" {
@@ -62,15 +63,46 @@ func (u *ui) register() *tview.Form {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 		err = u.mediator.SignIn(ctx, auth.CurrentUser.Username, pass)
+		// Saved credentials let the user work with local cache until server is back
+		if errors.Is(err, sync.ErrOffline) && pass == auth.GetPass() && secret == auth.GetSecret() {
+			err = nil
+		}
 		if err != nil {
 			u.throwModal(err, "register")
 			return
 		}
-		auth.SetSecret(secret)
-		auth.SetPass(pass)
-		u.goToMenu()
+		u.enter(pass, secret)
 		return
+	}).AddButton("Open send link", func() {
+		u.openSend("register")
 	})
 	form.SetBorder(true).SetTitle(" SignUp or login (for simplicity your login info will be saved in OS keychain)").SetTitleAlign(tview.AlignLeft)
 	return form
 }
+
+// enter saves credentials of the user, loads its local cache and opens the menu
+func (u *ui) enter(pass, secret string) {
+	auth.SetSecret(secret)
+	auth.SetPass(pass)
+	err := u.loadCache()
+	u.goToMenu()
+	if err != nil {
+		u.throwModal(fmt.Errorf("local cache is not loaded: %w", err), "menu")
+		return
+	}
+	// Key pair is made on the first login, so other users can share items with the user
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = u.mediator.SetupKeys(ctx); err != nil && !errors.Is(err, sync.ErrOffline) {
+		u.throwModal(fmt.Errorf("keys for sharing are not set up: %w", err), "menu")
+	}
+}
+
+// loadCache loads local cache of the current user, so items are shown and can be edited without server
+func (u *ui) loadCache() error {
+	path, err := storage.CachePath(auth.CurrentUser.Username)
+	if err != nil {
+		return err
+	}
+	return u.storage.Load(path)
+}
@@ -0,0 +1,368 @@
+package UI
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/client/auth"
+	"github.com/gynshu-one/goph-keeper/client/storage"
+	"github.com/gynshu-one/goph-keeper/client/sync"
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/rivo/tview"
+)
+
+var (
+	errOrgReadOnly  = errors.New("your role in the organization is read only")
+	errNoCollection = errors.New("add a collection to the organization first")
+)
+
+// loadOrgs reads organizations of the user with their keys, items of the organizations are saved with them
+func (u *ui) loadOrgs() ([]sync.Org, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	orgs, err := u.mediator.Orgs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	u.orgs = make(map[string]sync.Org, len(orgs))
+	for _, org := range orgs {
+		u.orgs[org.Organization.ID] = org
+	}
+	return orgs, nil
+}
+
+// organizations shows organizations of the user and invitations to accept, and the form to create a new one
+func (u *ui) organizations() {
+	orgs, err := u.loadOrgs()
+	if err != nil {
+		u.throwModal(err, "menu")
+		return
+	}
+
+	list := tview.NewList()
+	for _, org := range orgs {
+		org := org
+		if !org.Member.Accepted {
+			list.AddItem(org.Organization.Name, "invitation, select to accept", 0, func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				if err := u.mediator.Accept(ctx, org.Organization.ID); err != nil {
+					u.throwModal(err, "orgs")
+					return
+				}
+				u.organizations()
+			})
+			continue
+		}
+		list.AddItem(org.Organization.Name, string(org.Member.Role), 0, func() {
+			u.orgPage(org.Organization.ID)
+		})
+	}
+	list.SetBorder(true).SetTitle(" Organizations ")
+
+	name := ""
+	form := tview.NewForm().
+		AddInputField("Name", "", 30, nil, func(in string) {
+			name = in
+		}).
+		AddButton("Create", func() {
+			if name == "" {
+				u.throwModal(fmt.Errorf("name is empty"), "orgs")
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if _, err := u.mediator.CreateOrg(ctx, name); err != nil {
+				u.throwModal(err, "orgs")
+				return
+			}
+			u.organizations()
+		}).
+		AddButton("Back", func() {
+			u.goToMenu()
+		})
+	form.SetBorder(true).SetTitle(" New organization ").SetTitleAlign(tview.AlignCenter)
+
+	layout := tview.NewFlex().
+		AddItem(list, 0, 1, true).
+		AddItem(form, 0, 1, false)
+	u.pages.AddAndSwitchToPage("orgs", u.grid(u.addItemButtons(), layout), true)
+}
+
+// orgPage shows items of the organization by collections and lets add items to the chosen collection
+// owner and admins also add collections and manage members
+func (u *ui) orgPage(orgID string) {
+	org, ok := u.orgs[orgID]
+	if !ok {
+		u.organizations()
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	collections, err := u.mediator.Collections(ctx, orgID)
+	if err != nil {
+		u.throwModal(err, "orgs")
+		return
+	}
+	items, err := u.mediator.OrgItems(ctx, org)
+	if errors.Is(err, sync.ErrConflict) {
+		// The key was rotated since organizations were read
+		if _, err = u.loadOrgs(); err == nil {
+			org = u.orgs[orgID]
+			items, err = u.mediator.OrgItems(ctx, org)
+		}
+	}
+	if err != nil {
+		u.throwModal(err, "orgs")
+		return
+	}
+
+	names := make(map[string]string, len(collections))
+	options := make([]string, len(collections))
+	for i, collection := range collections {
+		names[collection.ID] = collection.Name
+		options[i] = collection.Name
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if names[items[i].CollectionID] != names[items[j].CollectionID] {
+			return names[items[i].CollectionID] < names[items[j].CollectionID]
+		}
+		return strings.ToLower(items[i].Name) < strings.ToLower(items[j].Name)
+	})
+	list := tview.NewList()
+	for _, item := range items {
+		item := item
+		list.AddItem(item.Name, fmt.Sprintf("%s in %s", item.Type, names[item.CollectionID]), 0, func() {
+			data, err := storage.Open(item, org.Key)
+			if err != nil {
+				u.throwModal(err, "org")
+				return
+			}
+			u.showItem(data, item)
+		})
+	}
+	list.SetBorder(true).SetTitle(fmt.Sprintf(" %s ", org.Organization.Name))
+
+	collection := 0
+	newItem := func(page string) {
+		if len(collections) == 0 {
+			u.throwModal(errNoCollection, "org")
+			return
+		}
+		if !org.Member.Role.CanWrite() {
+			u.throwModal(errOrgReadOnly, "org")
+			return
+		}
+		wrapper := models.DataWrapper{OwnerID: orgID, CollectionID: collections[collection].ID}
+		switch page {
+		case "text":
+			u.pages.AddAndSwitchToPage(page, u.grid(u.addItemButtons(), u.text(models.ArbitraryText{}, wrapper)), true)
+		case "login":
+			u.pages.AddAndSwitchToPage(page, u.grid(u.addItemButtons(), u.login(models.Login{}, wrapper)), true)
+		case "bank_card":
+			u.pages.AddAndSwitchToPage(page, u.grid(u.addItemButtons(), u.bankCard(models.BankCard{}, wrapper)), true)
+		}
+	}
+	form := tview.NewForm().
+		AddDropDown("Collection", options, 0, func(_ string, index int) {
+			collection = index
+		}).
+		AddButton("New Text", func() {
+			newItem("text")
+		}).
+		AddButton("New Login", func() {
+			newItem("login")
+		}).
+		AddButton("New Bank Card", func() {
+			newItem("bank_card")
+		})
+	if org.Member.Role.CanManage() {
+		collectionName := ""
+		form.AddInputField("New collection", "", 30, nil, func(in string) {
+			collectionName = in
+		}).AddButton("Add collection", func() {
+			if collectionName == "" {
+				u.throwModal(fmt.Errorf("name of collection is empty"), "org")
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if _, err := u.mediator.AddCollection(ctx, orgID, collectionName); err != nil {
+				u.throwModal(err, "org")
+				return
+			}
+			u.orgPage(orgID)
+		}).AddButton("Members", func() {
+			u.orgMembers(orgID)
+		})
+	}
+	if org.Member.Role != models.RoleOwner {
+		form.AddButton("Leave", func() {
+			u.removeMember(org, auth.CurrentUser.Username)
+		})
+	}
+	form.AddButton("Back", func() {
+		u.organizations()
+	})
+	form.SetBorder(true).SetTitle(fmt.Sprintf(" %s, %s ", org.Organization.Name, org.Member.Role)).
+		SetTitleAlign(tview.AlignCenter)
+
+	layout := tview.NewFlex().
+		AddItem(form, 0, 1, true).
+		AddItem(list, 0, 1, false)
+	u.pages.AddAndSwitchToPage("org", u.grid(u.addItemButtons(), layout), true)
+}
+
+// orgMembers shows members of the organization and the form to invite one more
+// selecting a member removes it, the org key is rotated then
+func (u *ui) orgMembers(orgID string) {
+	org := u.orgs[orgID]
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	members, err := u.mediator.Members(ctx, orgID)
+	if err != nil {
+		u.throwModal(err, "org")
+		return
+	}
+
+	email, role := "", models.RoleMember
+	roles := []string{string(models.RoleMember), string(models.RoleReadOnly)}
+	if org.Member.Role == models.RoleOwner {
+		roles = append(roles, string(models.RoleAdmin))
+	}
+	form := tview.NewForm().
+		AddInputField("Email", "", 30, nil, func(in string) {
+			email = in
+		}).
+		AddDropDown("Role", roles, 0, func(option string, _ int) {
+			role = models.Role(option)
+		}).
+		AddButton("Invite", func() {
+			if email == "" {
+				u.throwModal(fmt.Errorf("email is empty"), "members")
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := u.mediator.Invite(ctx, org, email, role); err != nil {
+				u.throwModal(err, "members")
+				return
+			}
+			u.orgMembers(orgID)
+		}).
+		AddButton("Rotate key", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := u.mediator.RotateOrgKey(ctx, orgID); err != nil {
+				u.throwModal(err, "members")
+				return
+			}
+			if _, err := u.loadOrgs(); err != nil {
+				u.throwModal(err, "menu")
+				return
+			}
+			u.orgMembers(orgID)
+		}).
+		AddButton("Back", func() {
+			u.orgPage(orgID)
+		})
+	form.SetBorder(true).SetTitle(fmt.Sprintf(" Invite to %s ", org.Organization.Name)).SetTitleAlign(tview.AlignCenter)
+
+	list := tview.NewList()
+	for _, member := range members {
+		member := member
+		note := string(member.Role)
+		if !member.Accepted {
+			note += ", invited"
+		}
+		if member.Role != models.RoleOwner && member.UserID != auth.CurrentUser.Username {
+			note += ", select to remove"
+		}
+		list.AddItem(member.UserID, note, 0, func() {
+			if member.Role == models.RoleOwner || member.UserID == auth.CurrentUser.Username {
+				return
+			}
+			u.removeMember(org, member.UserID)
+		})
+	}
+	list.SetBorder(true).SetTitle(" Members ")
+
+	layout := tview.NewFlex().
+		AddItem(form, 0, 1, true).
+		AddItem(list, 0, 1, false)
+	u.pages.AddAndSwitchToPage("members", u.grid(u.addItemButtons(), layout), true)
+}
+
+// removeMember asks to remove the user from the organization, the user itself leaves it
+func (u *ui) removeMember(org sync.Org, userID string) {
+	text := fmt.Sprintf("Remove %s from %s?\nThe org key is rotated, the rest of members keep their access", userID, org.Organization.Name)
+	back := "members"
+	if userID == auth.CurrentUser.Username {
+		text, back = fmt.Sprintf("Leave %s?", org.Organization.Name), "org"
+	}
+	u.pages.AddAndSwitchToPage("remove_member", tview.NewModal().
+		SetText(text).
+		AddButtons([]string{"Yes", "Back"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			if buttonLabel != "Yes" {
+				u.pages.SwitchToPage(back)
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := u.mediator.RemoveMember(ctx, org.Organization.ID, userID); err != nil {
+				u.throwModal(err, back)
+				return
+			}
+			if back == "org" {
+				u.organizations()
+				return
+			}
+			if _, err := u.loadOrgs(); err != nil {
+				u.throwModal(err, "menu")
+				return
+			}
+			u.orgMembers(org.Organization.ID)
+		}), false)
+}
+
+// saveOrgItem encrypts the item of the organization with the org key and sends it to server right away
+func (u *ui) saveOrgItem(org sync.Org, data models.BasicData, wrapper models.DataWrapper) error {
+	if !org.Member.Role.CanWrite() {
+		return errOrgReadOnly
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := u.mediator.SaveOrgItem(ctx, org, data, wrapper)
+	if err != nil {
+		return err
+	}
+	if result.Status != models.StatusApplied && result.Status != models.StatusStale {
+		return fmt.Errorf("item is not saved, %s: %s", result.Status, result.Reason)
+	}
+	return nil
+}
+
+// orgItemButtons adds the button to delete the item of the organization to its form if the role lets edit it
+func (u *ui) orgItemButtons(form *tview.Form, org sync.Org, wrapper models.DataWrapper, page string) {
+	if !org.Member.Role.CanWrite() {
+		return
+	}
+	form.AddButton("Delete", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		result, err := u.mediator.DeleteOrgItem(ctx, org, wrapper)
+		if err == nil && result.Status != models.StatusApplied {
+			err = fmt.Errorf("item is not deleted, %s: %s", result.Status, result.Reason)
+		}
+		if err != nil {
+			u.throwModal(err, page)
+			return
+		}
+		u.orgPage(org.Organization.ID)
+	})
+}
@@ -0,0 +1,141 @@
+package UI
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/rivo/tview"
+)
+
+// sends shows sends of the user with the form to send one more secret
+// selecting a send lets delete it before it expires
+func (u *ui) sends() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	sends, err := u.mediator.Sends(ctx)
+	if err != nil {
+		u.throwModal(err, "menu")
+		return
+	}
+
+	secret, hours, views := "", "24", "1"
+	form := tview.NewForm().
+		AddPasswordField("Secret", "", 40, '*', func(in string) {
+			secret = in
+		}).
+		AddInputField("Expires in hours", hours, 5, tview.InputFieldInteger, func(in string) {
+			hours = in
+		}).
+		AddInputField("Max views", views, 5, tview.InputFieldInteger, func(in string) {
+			views = in
+		}).
+		AddButton("Send", func() {
+			lifetime, hoursErr := strconv.ParseInt(hours, 10, 64)
+			maxViews, viewsErr := strconv.ParseInt(views, 10, 64)
+			if secret == "" || hoursErr != nil || viewsErr != nil {
+				u.throwModal(fmt.Errorf("secret, hours and views are required"), "sends")
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			link, err := u.mediator.CreateSend(ctx, secret, time.Duration(lifetime)*time.Hour, maxViews)
+			if err != nil {
+				u.throwModal(err, "sends")
+				return
+			}
+			u.sendLink(link)
+		}).
+		AddButton("Open link", func() {
+			u.openSend("sends")
+		}).
+		AddButton("Back", func() {
+			u.goToMenu()
+		})
+	form.SetBorder(true).SetTitle(" New send ").SetTitleAlign(tview.AlignCenter)
+
+	list := tview.NewList()
+	for _, send := range sends {
+		send := send
+		list.AddItem(time.Unix(send.CreatedAt, 0).Format(time.DateTime), describeSend(send), 0, func() {
+			u.deleteSend(send)
+		})
+	}
+	list.SetBorder(true).SetTitle(" My sends ")
+
+	layout := tview.NewFlex().
+		AddItem(form, 0, 1, true).
+		AddItem(list, 0, 1, false)
+	u.pages.AddAndSwitchToPage("sends", u.grid(u.addItemButtons(), layout), true)
+}
+
+// sendLink shows the link of the created send, it can't be shown again as the key is not kept anywhere
+func (u *ui) sendLink(link string) {
+	text := tview.NewTextView().SetText(link).SetWrap(true).SetWordWrap(false)
+	text.SetBorder(true).SetTitle(" Link, copy it now, it is shown only once ")
+	back := tview.NewForm().AddButton("Back", func() {
+		u.sends()
+	}).SetButtonsAlign(tview.AlignCenter)
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(text, 0, 1, false).
+		AddItem(back, 3, 0, true)
+	u.pages.AddAndSwitchToPage("send_link", u.grid(u.addItemButtons(), layout), true)
+}
+
+// deleteSend asks to delete the send, its link doesn't open after that
+func (u *ui) deleteSend(send models.Send) {
+	u.pages.AddAndSwitchToPage("send_delete", tview.NewModal().
+		SetText(fmt.Sprintf("Delete the send?\n%s", describeSend(send))).
+		AddButtons([]string{"Delete", "Back"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			if buttonLabel != "Delete" {
+				u.pages.SwitchToPage("sends")
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := u.mediator.DeleteSend(ctx, send.ID); err != nil {
+				u.throwModal(err, "sends")
+				return
+			}
+			u.sends()
+		}), false)
+}
+
+// openSend asks for the link of a send and shows its secret, back returns to the page
+// it works without signing in, so anyone the link was sent to can open it
+func (u *ui) openSend(back string) {
+	link := ""
+	form := tview.NewForm().
+		AddInputField("Link", "", 60, nil, func(in string) {
+			link = in
+		}).
+		AddButton("Open", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			secret, err := u.mediator.ReceiveSend(ctx, link)
+			if err != nil {
+				u.throwModal(err, "send_open")
+				return
+			}
+			u.pages.AddAndSwitchToPage("send_secret", tview.NewModal().
+				SetText(fmt.Sprintf("%s\n\nThe view is used up, the link may not open again", secret)).
+				AddButtons([]string{"Back"}).
+				SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+					u.pages.SwitchToPage(back)
+				}), false)
+		}).
+		AddButton("Back", func() {
+			u.pages.SwitchToPage(back)
+		})
+	form.SetBorder(true).SetTitle(" Open a send link ").SetTitleAlign(tview.AlignCenter)
+	u.pages.AddAndSwitchToPage("send_open", u.grid(nil, form), true)
+}
+
+// describeSend returns human-readable state of the send
+func describeSend(send models.Send) string {
+	return fmt.Sprintf("viewed %d of %d times, expires at %s",
+		send.Views, send.MaxViews, time.Unix(send.ExpiresAt, 0).Format(time.DateTime))
+}
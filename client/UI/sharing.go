@@ -0,0 +1,186 @@
+package UI
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/client/auth"
+	"github.com/gynshu-one/goph-keeper/client/storage"
+	"github.com/gynshu-one/goph-keeper/client/sync"
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/rivo/tview"
+)
+
+var (
+	errReadOnly   = errors.New("item is shared with you read only")
+	errSharedFile = errors.New("file of shared item can be replaced only by its owner")
+	errSharedName = errors.New("shared item can be renamed only by its owner")
+)
+
+// sharedWithMe shows items other users shared with the user
+// item is opened with the form of its type, edits of items shared with write permission are sent to server right away
+func (u *ui) sharedWithMe() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	items, err := u.mediator.Shared(ctx)
+	if err != nil {
+		u.throwModal(err, "menu")
+		return
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return strings.ToLower(items[i].Data.Name) < strings.ToLower(items[j].Data.Name)
+	})
+
+	u.shared = make(map[string]sync.SharedItem, len(items))
+	list := tview.NewList()
+	for _, item := range items {
+		item := item
+		u.shared[item.Data.ID] = item
+		note := fmt.Sprintf("%s from %s, %s", item.Data.Type, item.Share.OwnerID, item.Share.Permission)
+		list.AddItem(item.Data.Name, note, 0, func() {
+			u.openShared(item)
+		})
+	}
+	list.AddItem("Back", "", 0, func() {
+		u.goToMenu()
+	})
+	list.SetBorder(true).SetTitle(" Shared with me ").SetTitleAlign(tview.AlignCenter)
+
+	u.pages.AddAndSwitchToPage("shared", u.grid(u.addItemButtons(), list), true)
+}
+
+// openShared decrypts the shared item with its key and shows it in the form of its type
+func (u *ui) openShared(item sync.SharedItem) {
+	data, err := storage.Open(item.Data, item.Key)
+	if err != nil {
+		u.throwModal(err, "shared")
+		return
+	}
+	u.showItem(data, item.Data)
+}
+
+// showItem shows decrypted data of the item in the form of its type
+func (u *ui) showItem(data any, wrapper models.DataWrapper) {
+	switch elem := data.(type) {
+	case models.Login:
+		u.pages.AddAndSwitchToPage("login", u.grid(u.addItemButtons(), u.login(elem, wrapper)), true)
+	case models.ArbitraryText:
+		u.pages.AddAndSwitchToPage("text", u.grid(u.addItemButtons(), u.text(elem, wrapper)), true)
+	case models.BankCard:
+		u.pages.AddAndSwitchToPage("bank_card", u.grid(u.addItemButtons(), u.bankCard(elem, wrapper)), true)
+	case models.Binary:
+		u.pages.AddAndSwitchToPage("binary", u.grid(u.addItemButtons(), u.binary(elem, wrapper)), true)
+	}
+}
+
+// save encrypts the item into storage
+// item shared with the user is encrypted with its key and sent to server right away, it's not kept locally
+// items of organizations are encrypted with the org key and sent the same way
+func (u *ui) save(data models.BasicData, wrapper models.DataWrapper) error {
+	if org, ok := u.orgs[wrapper.OwnerID]; ok {
+		return u.saveOrgItem(org, data, wrapper)
+	}
+	item, ok := u.shared[wrapper.ID]
+	if !ok || owned(wrapper) {
+		return u.storage.AddEncrypt(data, wrapper)
+	}
+	if item.Share.Permission != models.PermissionWrite {
+		return errReadOnly
+	}
+	if wrapper.Name != item.Data.Name {
+		return errSharedName
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := u.mediator.UpdateShared(ctx, item, data)
+	if err != nil {
+		return err
+	}
+	if result.Status != models.StatusApplied && result.Status != models.StatusStale {
+		return fmt.Errorf("item is not saved, %s: %s", result.Status, result.Reason)
+	}
+	return nil
+}
+
+// owned tells whether the item belongs to the user, new items have no owner yet
+func owned(wrapper models.DataWrapper) bool {
+	return wrapper.OwnerID == "" || wrapper.OwnerID == auth.CurrentUser.Username
+}
+
+// sharing shows the users the item is shared with and the form to share it with one more
+// selecting a user revokes its access, the item gets a new key then
+func (u *ui) sharing(wrapper models.DataWrapper) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	shares, err := u.mediator.Shares(ctx, wrapper.ID)
+	if err != nil {
+		u.throwModal(err, "menu")
+		return
+	}
+
+	email, permission := "", models.PermissionRead
+	permissions := []string{string(models.PermissionRead), string(models.PermissionWrite)}
+	form := tview.NewForm().
+		AddInputField("Email", "", 30, nil, func(in string) {
+			email = in
+		}).
+		AddDropDown("Permission", permissions, 0, func(option string, _ int) {
+			permission = models.Permission(option)
+		}).
+		AddButton("Share", func() {
+			if email == "" {
+				u.throwModal(fmt.Errorf("email is empty"), "sharing")
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := u.mediator.ShareItem(ctx, wrapper.ID, email, permission); err != nil {
+				u.throwModal(err, "sharing")
+				return
+			}
+			u.sharing(wrapper)
+		}).
+		AddButton("Back", func() {
+			u.goToMenu()
+		})
+
+	list := tview.NewList()
+	for _, share := range shares {
+		share := share
+		list.AddItem(share.UserID, string(share.Permission)+", select to revoke", 0, func() {
+			u.revoke(wrapper, share.UserID)
+		})
+	}
+	list.SetBorder(true).SetTitle(" Shared with ")
+	form.SetBorder(true).SetTitle(fmt.Sprintf(" Share %s ", wrapper.Name)).SetTitleAlign(tview.AlignCenter)
+
+	layout := tview.NewFlex().
+		AddItem(form, 0, 1, true).
+		AddItem(list, 0, 1, false)
+	u.pages.AddAndSwitchToPage("sharing", u.grid(u.addItemButtons(), layout), true)
+}
+
+// revoke asks to revoke access of the user to the item
+func (u *ui) revoke(wrapper models.DataWrapper, userID string) {
+	u.pages.AddAndSwitchToPage("revoke", tview.NewModal().
+		SetText(fmt.Sprintf("Revoke access of %s to %s?\nThe item gets a new key, the rest of users keep their access", userID, wrapper.Name)).
+		AddButtons([]string{"Revoke", "Back"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			if buttonLabel != "Revoke" {
+				u.pages.SwitchToPage("sharing")
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := u.mediator.Revoke(ctx, wrapper.ID, userID); err != nil {
+				u.throwModal(err, "sharing")
+				return
+			}
+			u.sharing(wrapper)
+		}), false)
+}
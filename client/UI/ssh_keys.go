@@ -0,0 +1,175 @@
+package UI
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/client/sshagent"
+	"github.com/gynshu-one/goph-keeper/client/storage"
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/rivo/tview"
+	"github.com/rs/zerolog/log"
+)
+
+// confirmTimeout is how long the agent waits for the user to allow the use of a key, then it's denied
+const confirmTimeout = 30 * time.Second
+
+// keySources are the ways a new ssh key is made
+var keySources = []string{"generate " + models.SSHKeyEd25519, "generate " + models.SSHKeyRSA, "import from file"}
+
+// sshKey creates a form for ssh key, the same form is used for editing
+// new key is generated or imported, key that is edited keeps its key pair and can be exported
+func (u *ui) sshKey(data models.SSHKey, wrapper models.DataWrapper) *tview.Form {
+	if data.PrivateKey == "" {
+		data = models.SSHKey{}
+	}
+	if wrapper.ID == "" {
+		wrapper = models.DataWrapper{
+			Type:     models.SSHKeyType,
+			FolderID: wrapper.FolderID,
+		}
+	}
+	source, path, exportTo := 0, "", ""
+	form := tview.NewForm().
+		AddInputField("Name", wrapper.Name, 30, nil, func(in string) {
+			wrapper.Name = in
+		})
+	if wrapper.ID == "" {
+		form.AddInputField("Comment", data.Comment, 30, nil, func(in string) {
+			data.Comment = in
+		}).AddPasswordField("Passphrase", data.Passphrase, 30, '*', func(in string) {
+			data.Passphrase = in
+		}).AddDropDown("Key", keySources, 0, func(_ string, index int) {
+			source = index
+		}).AddInputField("Path", "", 30, nil, func(in string) {
+			path = in
+		})
+	} else {
+		form.AddTextView("Public key", data.PublicKey, 50, 3, false, false)
+	}
+	form.AddButton("Save", func() {
+		if wrapper.Name == "" {
+			u.throwModal(fmt.Errorf("name is empty"), "ssh_key")
+			return
+		}
+		if data.PrivateKey == "" {
+			key, err := newSSHKey(source, path, data.Comment, data.Passphrase)
+			if err != nil {
+				u.throwModal(err, "ssh_key")
+				return
+			}
+			key.Meta = data.Meta
+			data = key
+		}
+		err := u.save(&data, wrapper)
+		if err != nil {
+			u.throwModal(err, "ssh_key")
+			return
+		}
+		u.goToMenu()
+	}).AddButton("Back", func() {
+		u.goToMenu()
+	})
+	metaFields(form, &data.Meta)
+	u.itemFolderField(form, &wrapper)
+	// meaning we are creating item not editing
+	if wrapper.ID != "" {
+		form.AddInputField("Export to", "", 30, nil, func(in string) {
+			exportTo = in
+		})
+		form.AddButton("Export private key", func() {
+			if exportTo == "" {
+				u.throwModal(fmt.Errorf("path to export to is empty"), "ssh_key")
+				return
+			}
+			if err := os.WriteFile(exportTo, []byte(data.PrivateKey), 0o600); err != nil {
+				u.throwModal(err, "ssh_key")
+				return
+			}
+			u.throwModal(fmt.Errorf("private key is exported to %s", exportTo), "ssh_key")
+		})
+		u.ownerButtons(form, wrapper, "ssh_key")
+		form.SetTitle(" Edit ssh key ")
+	} else {
+		form.SetTitle(" Add ssh key ")
+	}
+	form.SetBorder(true).SetTitleAlign(tview.AlignCenter)
+	return form
+}
+
+// newSSHKey generates the key or imports it from the file at path, source is the index of keySources
+func newSSHKey(source int, path, comment, passphrase string) (models.SSHKey, error) {
+	switch source {
+	case 0:
+		return models.GenerateSSHKey(models.SSHKeyEd25519, comment, passphrase)
+	case 1:
+		return models.GenerateSSHKey(models.SSHKeyRSA, comment, passphrase)
+	}
+	if path == "" {
+		return models.SSHKey{}, fmt.Errorf("path to private key is empty")
+	}
+	privateKey, err := os.ReadFile(path)
+	if err != nil {
+		return models.SSHKey{}, err
+	}
+	return models.ImportSSHKey(privateKey, comment, passphrase)
+}
+
+// serveAgent serves ssh keys of the vault on the unix socket at path, it runs until the client is closed
+func (u *ui) serveAgent(path string) {
+	err := sshagent.Serve(context.Background(), path, sshagent.NewAgent(u.vaultKeys, u.confirmKey))
+	if err != nil {
+		log.Err(err).Str("socket", path).Msg("ssh agent stopped")
+		u.app.QueueUpdateDraw(func() {
+			u.status.SetText("ssh agent stopped: " + err.Error())
+		})
+	}
+}
+
+// vaultKeys returns ssh keys of the user sorted by name
+func (u *ui) vaultKeys() ([]sshagent.Key, error) {
+	items, err := u.storage.Search(storage.Filter{Types: []string{models.SSHKeyType}})
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]sshagent.Key, 0, len(items))
+	for _, item := range items {
+		data, wrapper, err := u.storage.FindDecrypt(item.ID)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, sshagent.Key{Name: wrapper.Name, SSHKey: data.(models.SSHKey)})
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return strings.ToLower(keys[i].Name) < strings.ToLower(keys[j].Name)
+	})
+	return keys, nil
+}
+
+// confirmKey asks the user to allow the key to sign once over the page that is shown
+// the use is denied if the user doesn't answer in confirmTimeout
+func (u *ui) confirmKey(key sshagent.Key) bool {
+	answer := make(chan bool, 1)
+	u.app.QueueUpdateDraw(func() {
+		u.pages.AddPage("ssh_confirm", tview.NewModal().
+			SetText(fmt.Sprintf("Allow ssh key %s to sign once?\n%s", key.Name, key.PublicKey)).
+			AddButtons([]string{"Allow", "Deny"}).
+			SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+				answer <- buttonLabel == "Allow"
+				u.pages.RemovePage("ssh_confirm")
+			}), true, true)
+	})
+	select {
+	case allowed := <-answer:
+		return allowed
+	case <-time.After(confirmTimeout):
+		u.app.QueueUpdateDraw(func() {
+			u.pages.RemovePage("ssh_confirm")
+		})
+		return false
+	}
+}
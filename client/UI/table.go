@@ -2,36 +2,83 @@ package UI
 
 import (
 	"errors"
+	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/gynshu-one/goph-keeper/client/storage"
+	"github.com/gynshu-one/goph-keeper/client/sync"
 	"github.com/gynshu-one/goph-keeper/common/models"
 	"github.com/rivo/tview"
 )
 
-// itemsTable creates a table with all items.
+// itemsTable creates a table with items of the selected folder or quick list.
 // this will be recreated and updated content every time we go to menu
 func (u *ui) itemsTable() (list *tview.List) {
 	items := u.storage.Get()
 	list = tview.NewList()
-	// sort items
+	if u.query != "" {
+		filter, err := storage.ParseFilter(u.query)
+		if err == nil {
+			items, err = u.storage.Search(filter)
+		}
+		// filter saved on another device may be unknown to this version of client
+		if err != nil {
+			list.AddItem(describeError(err), "", 0, nil)
+			items = nil
+		}
+	}
+	// names and types are encrypted, so they are decrypted to be sorted
+	labels := make(map[string]models.DataWrapper, len(items))
+	for _, item := range items {
+		labels[item.ID] = u.labelOf(item)
+	}
 	sort.Slice(items, func(i, j int) bool {
-		return strings.ToLower(items[i].Name) < strings.ToLower(items[j].Name)
+		return strings.ToLower(labels[items[i].ID].Name) < strings.ToLower(labels[items[j].ID].Name)
 	})
 
 	// add items to list func for clean code
-	f := func(name string, form *tview.Form, item models.DataWrapper) {
-		list.AddItem(item.Name, item.Type, 0, func() {
+	f := func(name string, form *tview.Form, item models.DataWrapper, data any) {
+		label := labels[item.ID].Name
+		if labeled, ok := data.(models.Labeled); ok && labeled.Labels().Favorite {
+			label = "★ " + label
+		}
+		list.AddItem(label, syncNote(labels[item.ID], u.mediator), 0, func() {
 			u.pages.AddAndSwitchToPage(name,
 				u.grid(u.addItemButtons(), form), true)
 		})
 	}
 
 	for _, item := range items {
+		if u.query == "" && storage.FolderOf(item, u.folders) != u.folder {
+			continue
+		}
+		// items edited on another device meanwhile have to be resolved first
+		if conflict, ok := u.storage.Conflict(item.ID); ok {
+			name := labels[item.ID].Name
+			if folder, ok := u.folders[item.ID]; ok {
+				name = folder.Name
+			} else if name == "" {
+				name = labels[item.ID].Type
+			}
+			list.AddItem(name, "----conflict----", 0, func() {
+				u.conflict(conflict)
+			})
+			continue
+		}
+
+		// folders are shown in the tree, saved filters in quick lists
+		if t := labels[item.ID].Type; t == models.FolderType || t == models.FilterType {
+			continue
+		}
 		decrypt, wrapper, err := u.storage.FindDecrypt(item.ID)
 		if err != nil {
 			if errors.Is(err, models.ErrDeleted) {
-				list.AddItem(item.Name, "----deleted----", 0, nil)
+				// deleted items can still be restored from history
+				list.AddItem(labels[item.ID].Name, "----deleted----", 0, func() {
+					u.history(wrapper)
+				})
 				continue
 			}
 			u.throwModal(err, "login")
@@ -40,15 +87,70 @@ func (u *ui) itemsTable() (list *tview.List) {
 
 		switch elem := decrypt.(type) {
 		case models.Login:
-			f("login", u.login(elem, wrapper), item)
+			f("login", u.login(elem, wrapper), item, elem)
 		case models.ArbitraryText:
-			f("arbitrary text", u.text(elem, wrapper), item)
+			f("arbitrary text", u.text(elem, wrapper), item, elem)
 		case models.BankCard:
-			f("bank card", u.bankCard(elem, wrapper), item)
+			f("bank card", u.bankCard(elem, wrapper), item, elem)
 		case models.Binary:
-			f("binary", u.binary(elem, wrapper), item)
+			f("binary", u.binary(elem, wrapper), item, elem)
+		case models.SSHKey:
+			f("ssh_key", u.sshKey(elem, wrapper), item, elem)
 		}
 	}
 
+	list.SetBorder(true).SetTitle(usageTitle(u.mediator.Usage())).SetTitleAlign(tview.AlignCenter)
 	return list
 }
+
+// nameOf returns decrypted name of the item of the user, name that can't be decrypted is replaced with a note
+func (u *ui) nameOf(item models.DataWrapper) string {
+	return u.labelOf(item).Name
+}
+
+// labelOf returns the item of the user with its name and type decrypted
+// name that can't be decrypted is replaced with a note
+func (u *ui) labelOf(item models.DataWrapper) models.DataWrapper {
+	label, err := u.storage.Label(item)
+	if err != nil {
+		label.Name = "----name can't be decrypted----"
+	}
+	return label
+}
+
+// syncNote returns item type and a note if server didn't accept the last change of the item
+func syncNote(item models.DataWrapper, mediator sync.Mediator) string {
+	result, ok := mediator.Result(item.ID)
+	if !ok || result.Status == models.StatusApplied || result.Status == models.StatusStale {
+		return item.Type
+	}
+	return fmt.Sprintf("%s ----%s: %s----", item.Type, result.Status, result.Reason)
+}
+
+// usageTitle formats storage usage reported by server, e.g. " Items (12/10000, 1.2MB/100MB) "
+// limits are omitted when server does not enforce them
+func usageTitle(usage models.Usage) string {
+	items := strconv.FormatInt(usage.Items, 10)
+	if usage.MaxItems > 0 {
+		items += "/" + strconv.FormatInt(usage.MaxItems, 10)
+	}
+	size := formatBytes(usage.Bytes)
+	if usage.MaxBytes > 0 {
+		size += "/" + formatBytes(usage.MaxBytes)
+	}
+	return fmt.Sprintf(" Items (%s, %s) ", items, size)
+}
+
+// formatBytes returns human-readable size
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
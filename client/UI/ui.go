@@ -2,7 +2,9 @@ package UI
 
 import (
 	"context"
+	"errors"
 
+	"github.com/gynshu-one/goph-keeper/client/config"
 	"github.com/gynshu-one/goph-keeper/client/storage"
 	"github.com/gynshu-one/goph-keeper/client/sync"
 	"github.com/gynshu-one/goph-keeper/common/models"
@@ -12,6 +14,8 @@ import (
 // UI is an interface for the UI
 type UI interface {
 	Pages() *tview.Pages
+	// Close writes local cache of the user
+	Close() error
 }
 
 type ui struct {
@@ -19,6 +23,22 @@ type ui struct {
 	app      *tview.Application
 	mediator sync.Mediator
 	storage  storage.Storage
+	// scheduler syncs in background on poll and dump timers
+	scheduler *sync.Scheduler
+	// status shows the state of background sync at the bottom of every page
+	status *tview.TextView
+	// shared are items other users shared with the user by item id, they are read when "Shared with me" is opened
+	shared map[string]sync.SharedItem
+	// orgs are organizations of the user by id, they are read when "Organizations" is opened
+	orgs map[string]sync.Org
+	// folders are folders of the user by id, they are read every time the menu is shown
+	folders map[string]storage.Folder
+	// folder is the folder selected in the tree of the menu, empty for the root
+	folder string
+	// query is the filter of the quick list selected in the menu, items of the folder are shown when it's empty
+	query string
+	// watching is set when subscription to changes made on other devices, background sync and flush of local cache are started
+	watching bool
 }
 
 // NewUI creates a new UI instance with the given application
@@ -29,42 +49,106 @@ func NewUI(app *tview.Application) UI {
 		app:      app,
 		mediator: sync.NewMediator(newStorage),
 		storage:  newStorage,
+		status:   tview.NewTextView().SetTextAlign(tview.AlignRight),
 	}
+	u.scheduler = sync.NewScheduler(u.mediator, newStorage, config.GetConfig().PollTimer, config.GetConfig().DumpTimer,
+		func(status sync.Status) {
+			app.QueueUpdateDraw(func() {
+				u.showStatus(status)
+			})
+		})
 	return u
 }
 
 // Pages returns the predefined dynamic pages such as register, menu, new item pages etc.
 // This is basically UI routing
 func (u *ui) Pages() *tview.Pages {
+	u.pages.SetChangedFunc(u.pauseWhileEditing)
 	u.pages.AddPage("register", u.grid(nil, u.register()), true, true)
 
 	u.pages.AddPage("text", u.grid(u.addItemButtons(), u.text(models.ArbitraryText{}, models.DataWrapper{})), true, false)
 	u.pages.AddPage("bank_card", u.grid(u.addItemButtons(), u.bankCard(models.BankCard{}, models.DataWrapper{})), true, false)
 	u.pages.AddPage("binary", u.grid(u.addItemButtons(), u.binary(models.Binary{}, models.DataWrapper{})), true, false)
 	u.pages.AddPage("login", u.grid(u.addItemButtons(), u.login(models.Login{}, models.DataWrapper{})), true, false)
+	u.pages.AddPage("ssh_key", u.grid(u.addItemButtons(), u.sshKey(models.SSHKey{}, models.DataWrapper{})), true, false)
 
 	return u.pages
 }
 
+// Close writes local cache of the user
+func (u *ui) Close() error {
+	return u.storage.Flush()
+}
+
 // throwModal throws a modal with the given message and redirects to the given page
 func (u *ui) throwModal(message error, redirect string) {
 	u.pages.AddAndSwitchToPage("error", tview.NewModal().
-		SetText(message.Error()).
+		SetText(describeError(message)).
 		AddButtons([]string{"Ok"}).
 		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
 			u.pages.SwitchToPage(redirect)
 		}), false)
 }
 
+// describeError returns message of the error user can act on
+// errors of server keep their details, other errors are shown as is
+func describeError(err error) string {
+	switch {
+	case errors.Is(err, sync.ErrOffline):
+		return "Server is not reachable, changes are saved locally and sent when it's back"
+	case errors.Is(err, sync.ErrUnauthorized):
+		return "Session expired and signing in again failed, please log in"
+	case errors.Is(err, sync.ErrQuotaExceeded):
+		return "Storage limit is reached, delete some items or files: " + err.Error()
+	case errors.Is(err, sync.ErrServer):
+		return "Server failed, please try again later: " + err.Error()
+	case errors.Is(err, sync.ErrConflict):
+		return "It was changed on server meanwhile, please open it again: " + err.Error()
+	}
+	return err.Error()
+}
+
 // goToMenu redirects to the menu page
+// the first call after login starts watching changes made on other devices, background sync and ssh agent if it's on
 func (u *ui) goToMenu() {
 	err := u.mediator.Sync(context.Background())
-	if err != nil {
+	if err != nil && !errors.Is(err, sync.ErrOffline) {
 		u.throwModal(err, "menu")
 		return
 	}
+	if err != nil {
+		// Changes are kept in local cache and sent by background sync when server is back
+		u.status.SetText("offline, changes are saved locally")
+	}
+	if !u.watching {
+		u.watching = true
+		go u.watch()
+		go u.scheduler.Run(context.Background())
+		go storage.FlushEvery(context.Background(), u.storage, config.GetConfig().DumpTimer)
+		if path := config.GetConfig().SSHAgent; path != "" {
+			go u.serveAgent(path)
+		}
+	}
+	u.showMenu()
+}
+
+// showMenu rebuilds the menu page with the tree of folders and items of the selected folder from storage
+func (u *ui) showMenu() {
+	folders, err := u.storage.Folders()
+	if err != nil {
+		u.throwModal(err, "login")
+		return
+	}
+	u.folders = folders
+	// Selected folder could be deleted on another device
+	if _, ok := folders[u.folder]; !ok {
+		u.folder = ""
+	}
+	layout := tview.NewFlex().
+		AddItem(u.folderPane(), 0, 1, false).
+		AddItem(u.itemsTable(), 0, 3, true)
 	u.pages.RemovePage("menu")
-	u.pages.AddAndSwitchToPage("menu", u.grid(u.addItemButtons(), u.itemsTable()), true)
+	u.pages.AddAndSwitchToPage("menu", u.grid(u.addItemButtons(), layout), true)
 }
 
 // grid creates a grid lyout with the given header and element
@@ -79,12 +163,13 @@ func (u *ui) grid(header tview.Primitive, elem tview.Primitive) *tview.Grid {
 		header = newPrimitive("Header")
 	}
 	grid := tview.NewGrid().
-		SetRows(3, 0, 1).
+		SetRows(3, 0, 1, 1).
 		SetBorders(true).
 		AddItem(header, 0, 0, 1, 1, 0, 0, false).
 		AddItem(tview.NewButton("Quit").SetSelectedFunc(func() {
 			u.app.Stop()
-		}), 2, 0, 1, 1, 0, 0, false)
+		}), 2, 0, 1, 1, 0, 0, false).
+		AddItem(u.status, 3, 0, 1, 1, 0, 0, false)
 
 	// Layout for screens wider than 100 cells.
 	grid.AddItem(elem, 1, 0, 1, 1, 0, 0, false)
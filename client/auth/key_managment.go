@@ -11,6 +11,9 @@ import (
 
 var userFile string
 
+// ErrNoSecret is returned by Secret when the secret can't be read from os keyring
+var ErrNoSecret = errors.New("secret is not available")
+
 func init() {
 	// Determine user home dir
 	homeDir, err := os.UserHomeDir()
@@ -104,3 +107,13 @@ func GetSecret() string {
 	}
 	return secret
 }
+
+// Secret gets secret from local os keyring like GetSecret, but missing secret is an error,
+// so nothing is encrypted with an empty passphrase
+func Secret() (string, error) {
+	secret := GetSecret()
+	if secret == "" {
+		return "", ErrNoSecret
+	}
+	return secret, nil
+}
@@ -2,6 +2,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 
 	"github.com/gynshu-one/goph-keeper/client/UI"
@@ -23,6 +24,9 @@ func main() {
 	}
 	fmt.Printf("Build version: %s\n", buildVersion)
 	fmt.Printf("Build date: %s\n", buildDate)
+	// Flags are defined by config, e.g. -transport and -grpc_addr choose the API of server,
+	// -poll and -dump the intervals of background sync
+	flag.Parse()
 
 	// Create a new application.
 	app := tview.NewApplication()
@@ -35,4 +39,8 @@ func main() {
 	if err := app.SetRoot(pages, true).EnableMouse(true).Run(); err != nil {
 		log.Fatal().Err(err).Msg("Failed to run app")
 	}
+	// Keep local changes that were not flushed yet
+	if err := newUI.Close(); err != nil {
+		log.Err(err).Msg("Failed to write local cache")
+	}
 }
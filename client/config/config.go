@@ -14,11 +14,25 @@ const (
 	ServiceName = "goph-keeper"
 )
 
+// Transports of client/sync
+const (
+	TransportREST = "rest"
+	TransportGRPC = "grpc"
+)
+
 type config struct {
 	// Server is the server configuration
-	ServerIP  string
+	ServerIP string
+	// GrpcAddr is the address of server's gRPC API used by TransportGRPC
+	GrpcAddr string
+	// Transport is the API client talks to server with, TransportREST or TransportGRPC
+	Transport string
+	// PollTimer is the interval of background sync receiving changes of other devices, 0 turns it off
 	PollTimer time.Duration
+	// DumpTimer is the interval of background sync sending local changes and of writes of the local cache, 0 turns it off
 	DumpTimer time.Duration
+	// SSHAgent is the unix socket ssh keys of the vault are served on, empty if the agent is off
+	SSHAgent string
 }
 
 // NewConfig creates a new configuration struct
@@ -28,8 +42,11 @@ func init() {
 
 	// Set the default values
 	flag.StringVar(&instance.ServerIP, "addr", "localhost:8080", "Server IP address default: localhost:8080")
-	flag.DurationVar(&instance.PollTimer, "poll", 5*time.Second, "Poll timer default: 5s")
-	flag.DurationVar(&instance.DumpTimer, "dump", 10*time.Second, "Dump timer default: 10s")
+	flag.StringVar(&instance.GrpcAddr, "grpc_addr", "localhost:9090", "Server gRPC address default: localhost:9090")
+	flag.StringVar(&instance.Transport, "transport", TransportREST, "Server API, rest or grpc default: rest")
+	flag.DurationVar(&instance.PollTimer, "poll", 5*time.Second, "Poll timer, 0 turns it off default: 5s")
+	flag.DurationVar(&instance.DumpTimer, "dump", 10*time.Second, "Dump timer, 0 turns it off default: 10s")
+	flag.StringVar(&instance.SSHAgent, "ssh_agent", "", "Unix socket to serve ssh keys of the vault on default: off")
 
 	// Parse the flags and ignore the rest
 	flag.CommandLine.SetOutput(io.Discard)
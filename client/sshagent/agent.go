@@ -0,0 +1,229 @@
+package sshagent
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+var (
+	// ErrDenied is returned when the user doesn't confirm the use of the key
+	ErrDenied = errors.New("use of the key is denied")
+	// ErrReadOnly is returned by requests that change keys, they are managed in the vault
+	ErrReadOnly = errors.New("keys of the agent are managed in the vault")
+	// ErrNotFound is returned when the key to sign with is not in the vault
+	ErrNotFound = errors.New("key is not in the vault")
+)
+
+// Key is an ssh key of the vault
+type Key struct {
+	// Name is the name of the item the key is kept in
+	Name string
+	models.SSHKey
+}
+
+// Keys returns keys the agent serves, they are read on every request, so changes of the vault are seen at once
+type Keys func() ([]Key, error)
+
+// Confirm asks the user whether the key may sign, it blocks until the user answers
+type Confirm func(key Key) bool
+
+// vaultAgent is the agent that signs with keys of the vault after the user confirms it
+type vaultAgent struct {
+	keys    Keys
+	confirm Confirm
+	// confirming makes the user answer one request at a time
+	confirming *sync.Mutex
+}
+
+// NewAgent creates the agent serving the keys, confirm is asked before every signature
+func NewAgent(keys Keys, confirm Confirm) agent.ExtendedAgent {
+	return &vaultAgent{keys: keys, confirm: confirm, confirming: &sync.Mutex{}}
+}
+
+// List returns public keys of the vault with names of their items as comments
+// key whose public key can't be read is skipped
+func (a *vaultAgent) List() ([]*agent.Key, error) {
+	keys, err := a.keys()
+	if err != nil {
+		return nil, err
+	}
+	listed := make([]*agent.Key, 0, len(keys))
+	for _, key := range keys {
+		public, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key.PublicKey))
+		if err != nil {
+			log.Err(err).Str("key", key.Name).Msg("failed to read public key")
+			continue
+		}
+		listed = append(listed, &agent.Key{Format: public.Type(), Blob: public.Marshal(), Comment: key.Name})
+	}
+	return listed, nil
+}
+
+// Sign signs the data with the key if the user confirms it
+func (a *vaultAgent) Sign(key ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	return a.SignWithFlags(key, data, 0)
+}
+
+// SignWithFlags signs the data with the key if the user confirms it, flags choose SHA-2 signatures of RSA keys
+func (a *vaultAgent) SignWithFlags(key ssh.PublicKey, data []byte, flags agent.SignatureFlags) (*ssh.Signature, error) {
+	found, err := a.find(key)
+	if err != nil {
+		return nil, err
+	}
+	a.confirming.Lock()
+	confirmed := a.confirm(found)
+	a.confirming.Unlock()
+	if !confirmed {
+		return nil, ErrDenied
+	}
+
+	private, err := found.RawKey()
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		return nil, err
+	}
+	if flags == 0 {
+		return signer.Sign(rand.Reader, data)
+	}
+	algorithmSigner, ok := signer.(ssh.AlgorithmSigner)
+	if !ok {
+		return nil, fmt.Errorf("key %s doesn't support signature flags", found.Name)
+	}
+	switch flags {
+	case agent.SignatureFlagRsaSha256:
+		return algorithmSigner.SignWithAlgorithm(rand.Reader, data, ssh.KeyAlgoRSASHA256)
+	case agent.SignatureFlagRsaSha512:
+		return algorithmSigner.SignWithAlgorithm(rand.Reader, data, ssh.KeyAlgoRSASHA512)
+	}
+	return nil, fmt.Errorf("unsupported signature flags: %d", flags)
+}
+
+// find returns the key of the vault with the public key
+func (a *vaultAgent) find(public ssh.PublicKey) (Key, error) {
+	keys, err := a.keys()
+	if err != nil {
+		return Key{}, err
+	}
+	wanted := public.Marshal()
+	for _, key := range keys {
+		parsed, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key.PublicKey))
+		if err == nil && bytes.Equal(parsed.Marshal(), wanted) {
+			return key, nil
+		}
+	}
+	return Key{}, ErrNotFound
+}
+
+// Signers is not supported, signers would sign without confirmation
+func (a *vaultAgent) Signers() ([]ssh.Signer, error) {
+	return nil, ErrReadOnly
+}
+
+// Add is not supported, keys are added in the vault
+func (a *vaultAgent) Add(agent.AddedKey) error {
+	return ErrReadOnly
+}
+
+// Remove is not supported, keys are deleted in the vault
+func (a *vaultAgent) Remove(ssh.PublicKey) error {
+	return ErrReadOnly
+}
+
+// RemoveAll is not supported, keys are deleted in the vault
+func (a *vaultAgent) RemoveAll() error {
+	return ErrReadOnly
+}
+
+// Lock is not supported, every use of a key is confirmed anyway
+func (a *vaultAgent) Lock([]byte) error {
+	return ErrReadOnly
+}
+
+// Unlock is not supported, the agent is never locked
+func (a *vaultAgent) Unlock([]byte) error {
+	return ErrReadOnly
+}
+
+// Extension is not supported
+func (a *vaultAgent) Extension(string, []byte) ([]byte, error) {
+	return nil, agent.ErrExtensionUnsupported
+}
+
+// Serve serves the agent on the unix socket at path until ctx is done
+// socket left by the previous run is replaced, only the user can connect to the socket
+func Serve(ctx context.Context, path string, a agent.Agent) error {
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err = os.Remove(path); err != nil {
+			return err
+		}
+	}
+	listener, err := listen(path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+	defer listener.Close()
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			if err := agent.ServeAgent(a, conn); err != nil && !errors.Is(err, io.EOF) {
+				log.Err(err).Msg("ssh agent connection failed")
+			}
+		}()
+	}
+}
+
+// listen creates the unix socket at path only the user can connect to
+// socket is made in a directory only the user can enter and linked to path once its permissions are set,
+// so it's never reachable with permissions of umask, umask itself is shared by goroutines writing files and isn't changed
+func listen(path string) (net.Listener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".agent")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	private := filepath.Join(dir, "s")
+	listener, err := net.Listen("unix", private)
+	if err != nil {
+		return nil, err
+	}
+	// Socket is removed from path by Serve, the private one is removed with its directory
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err = os.Chmod(private, 0o600); err == nil {
+		// Unlike rename, link doesn't replace a file that is at path already
+		err = os.Link(private, path)
+	}
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
@@ -0,0 +1,140 @@
+package sshagent
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func TestAgent(t *testing.T) {
+	ed25519Key, err := models.GenerateSSHKey(models.SSHKeyEd25519, "", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := models.GenerateSSHKey(models.SSHKeyRSA, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []Key{{Name: "github", SSHKey: ed25519Key}, {Name: "server", SSHKey: rsaKey}}
+	allow := true
+	var confirmed []string
+	a := NewAgent(func() ([]Key, error) {
+		return keys, nil
+	}, func(key Key) bool {
+		confirmed = append(confirmed, key.Name)
+		return allow
+	})
+
+	// Socket is in a short path, unix socket paths are limited to about 100 bytes
+	dir, err := os.MkdirTemp("", "agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "agent.sock")
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(ctx, path, a)
+	}()
+	var conn net.Conn
+	for i := 0; i < 100; i++ {
+		if conn, err = net.Dial("unix", path); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Agent is not served: %v", err)
+	}
+	defer conn.Close()
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected socket only for the user, got %v and %v", info.Mode(), err)
+	}
+	client := agent.NewClient(conn)
+
+	listed, err := client.List()
+	if err != nil || len(listed) != 2 || listed[0].Comment != "github" || listed[1].Format != ssh.KeyAlgoRSA {
+		t.Fatalf("Unexpected keys: %v and %v", listed, err)
+	}
+
+	// Every signature is confirmed, RSA keys sign with SHA-2 when asked
+	data := []byte("session")
+	for i, flags := range []agent.SignatureFlags{0, agent.SignatureFlagRsaSha256} {
+		signature, err := client.SignWithFlags(listed[i], data, flags)
+		if err != nil {
+			t.Fatalf("Sign failed with error: %v", err)
+		}
+		if err = listed[i].Verify(data, signature); err != nil {
+			t.Errorf("Signature of %s is not valid: %v", listed[i].Comment, err)
+		}
+	}
+	if signature, _ := client.SignWithFlags(listed[1], data, agent.SignatureFlagRsaSha256); signature.Format != ssh.KeyAlgoRSASHA256 {
+		t.Errorf("Expected %s signature, got %s", ssh.KeyAlgoRSASHA256, signature.Format)
+	}
+	if strings.Join(confirmed, ",") != "github,server,server" {
+		t.Errorf("Unexpected confirmations: %v", confirmed)
+	}
+
+	allow = false
+	if _, err = client.Sign(listed[0], data); err == nil {
+		t.Error("Key signed without confirmation")
+	}
+	// Keys are managed in the vault
+	if err = client.RemoveAll(); err == nil {
+		t.Error("Keys are removed through the agent")
+	}
+
+	keys = keys[:1]
+	allow = true
+	if _, err = client.Sign(listed[1], data); err == nil {
+		t.Error("Key deleted from the vault signed")
+	}
+
+	cancel()
+	if err = <-served; err != nil {
+		t.Errorf("Serve returned an error: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Socket is left after Serve returned: %v", entries)
+	}
+
+	// File that is not a socket is never replaced
+	if err = os.WriteFile(path, []byte("id_ed25519"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err = Serve(context.Background(), path, a); err == nil {
+		t.Error("Serve replaced a file")
+	}
+	if content, _ := os.ReadFile(path); string(content) != "id_ed25519" {
+		t.Errorf("File was changed: %s", content)
+	}
+}
+
+func TestAgentErrors(t *testing.T) {
+	a := NewAgent(func() ([]Key, error) {
+		return nil, nil
+	}, func(Key) bool {
+		return true
+	})
+	if _, err := a.Signers(); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected %v, got %v", ErrReadOnly, err)
+	}
+	key, err := models.GenerateSSHKey(models.SSHKeyEd25519, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, _, _, _, _ := ssh.ParseAuthorizedKey([]byte(key.PublicKey))
+	if _, err = a.Sign(public, []byte("data")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected %v, got %v", ErrNotFound, err)
+	}
+}
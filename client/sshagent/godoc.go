@@ -0,0 +1,3 @@
+// Package sshagent serves ssh keys of the vault over the ssh-agent protocol on a unix socket
+// keys never leave the client, every signature has to be confirmed by the user
+package sshagent
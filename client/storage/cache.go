@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/client/auth"
+	"github.com/gynshu-one/goph-keeper/client/config"
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/gynshu-one/goph-keeper/common/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// cacheVersion is the version of snapshot format, cache of other version is not loaded
+const cacheVersion = 1
+
+// snapshot is the state of the storage written to the cache file
+type snapshot struct {
+	Version   int                  `json:"version"`
+	Items     []models.DataWrapper `json:"items"`
+	Dirty     []string             `json:"dirty,omitempty"`
+	Cursor    int64                `json:"cursor"`
+	Conflicts map[string]Conflict  `json:"conflicts,omitempty"`
+	// Stale is set if download of all data was interrupted, it may be empty then
+	Stale []string `json:"stale"`
+}
+
+// CachePath returns path of the cache file of the user in ~/.goph-keeper
+// file name is a hash of username, so it's safe for any username
+func CachePath(username string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(username))
+	return filepath.Join(homeDir, "."+config.ServiceName, hex.EncodeToString(sum[:8])+".vault"), nil
+}
+
+// Load replaces data in the storage with the encrypted cache file at path
+// and makes Flush write to it, missing file is not an error
+// file is encrypted with the secret of the current user, so it can't be read with another one,
+// file that can't be read is a cache miss: data is downloaded from server again and the file is replaced on the next Flush
+// returns auth.ErrNoSecret if the secret of the user can't be read
+func (s *storage) Load(path string) error {
+	s.mu.Lock()
+	s.path = path
+	s.mu.Unlock()
+
+	secret, err := auth.Secret()
+	if err != nil {
+		return err
+	}
+	encrypted, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	snap, err := readCache(encrypted, secret)
+	if err != nil {
+		log.Warn().Err(err).Msg("Local cache can't be read, data is downloaded again")
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.repo = make(map[string]models.DataWrapper, len(snap.Items))
+	for _, item := range snap.Items {
+		s.repo[item.ID] = item
+	}
+	s.dirty = make(map[string]struct{}, len(snap.Dirty))
+	for _, id := range snap.Dirty {
+		s.dirty[id] = struct{}{}
+	}
+	s.conflicts = snap.Conflicts
+	if s.conflicts == nil {
+		s.conflicts = make(map[string]Conflict)
+	}
+	s.stale = nil
+	if snap.Stale != nil {
+		s.stale = make(map[string]struct{}, len(snap.Stale))
+		for _, id := range snap.Stale {
+			s.stale[id] = struct{}{}
+		}
+	}
+	s.cursor = snap.Cursor
+	s.changed = false
+	return nil
+}
+
+// Flush writes data to the cache file if it was changed since the last flush
+// the file is replaced atomically, so it's never half written
+func (s *storage) Flush() error {
+	s.flushing.Lock()
+	defer s.flushing.Unlock()
+
+	s.mu.Lock()
+	if s.path == "" || !s.changed {
+		s.mu.Unlock()
+		return nil
+	}
+	path, snap := s.path, s.snapshot()
+	s.changed = false
+	s.mu.Unlock()
+
+	if err := writeCache(path, snap); err != nil {
+		s.mu.Lock()
+		s.changed = true
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// snapshot returns the state of the storage, must be called under the lock
+func (s *storage) snapshot() snapshot {
+	snap := snapshot{Version: cacheVersion, Cursor: s.cursor, Conflicts: make(map[string]Conflict, len(s.conflicts))}
+	for id, conflict := range s.conflicts {
+		snap.Conflicts[id] = conflict
+	}
+	for _, item := range s.repo {
+		snap.Items = append(snap.Items, item)
+	}
+	for id := range s.dirty {
+		snap.Dirty = append(snap.Dirty, id)
+	}
+	if s.stale != nil {
+		snap.Stale = make([]string, 0, len(s.stale))
+		for id := range s.stale {
+			snap.Stale = append(snap.Stale, id)
+		}
+	}
+	return snap
+}
+
+// readCache decrypts the snapshot from the content of the cache file
+func readCache(encrypted []byte, secret string) (snap snapshot, err error) {
+	compressed, err := utils.DecryptData(encrypted, secret)
+	if err != nil {
+		return snap, fmt.Errorf("failed to decrypt local cache: %w", err)
+	}
+	data, err := utils.Decompress(compressed)
+	if err != nil {
+		return snap, err
+	}
+	if err = json.Unmarshal(data, &snap); err != nil {
+		return snap, err
+	}
+	if snap.Version != cacheVersion {
+		return snap, fmt.Errorf("unknown version %d of local cache", snap.Version)
+	}
+	return snap, nil
+}
+
+// writeCache encrypts the snapshot with the secret of the current user and writes it to path
+// returns auth.ErrNoSecret if the secret of the user can't be read
+func writeCache(path string, snap snapshot) error {
+	secret, err := auth.Secret()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	encrypted, err := utils.EncryptData(utils.Compress(data), secret)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Does nothing if the file was renamed
+		_ = os.Remove(file.Name())
+	}()
+	if _, err = file.Write(encrypted); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// FlushEvery flushes the storage every interval until ctx is done and once more after it
+// zero or negative interval disables it
+func FlushEvery(ctx context.Context, s Storage, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := s.Flush(); err != nil {
+				log.Err(err).Msg("Failed to flush local cache")
+			}
+			return
+		case <-ticker.C:
+			if err := s.Flush(); err != nil {
+				log.Err(err).Msg("Failed to flush local cache")
+			}
+		}
+	}
+}
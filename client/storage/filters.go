@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+)
+
+// ErrInvalidFilter is returned by ParseFilter when the query has a term it doesn't know
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// itemTypes are types of items filters match, folders and saved filters are not items
+var itemTypes = map[string]bool{
+	models.ArbitraryTextType: true,
+	models.BankCardType:      true,
+	models.BinaryType:        true,
+	models.LoginType:         true,
+	models.SSHKeyType:        true,
+}
+
+// Filter matches items by their labels and type, all its terms have to match
+type Filter struct {
+	Favorite bool
+	Tags     []string
+	Types    []string
+}
+
+// SavedFilter is a saved filter of the user with its name and query decrypted
+type SavedFilter struct {
+	Wrapper models.DataWrapper
+	Name    string
+	Query   string
+}
+
+// ParseFilter parses the query of space separated terms: "favorites", "tag:<tag>" and "type:<type>"
+// e.g. "favorites tag:prod" matches favorite items tagged with prod
+func ParseFilter(query string) (Filter, error) {
+	var filter Filter
+	for _, term := range strings.Fields(strings.ToLower(query)) {
+		key, value, found := strings.Cut(term, ":")
+		switch {
+		case !found && (key == "favorites" || key == "favorite"):
+			filter.Favorite = true
+		case found && key == "tag" && value != "":
+			filter.Tags = append(filter.Tags, value)
+		case found && key == "type" && itemTypes[value]:
+			filter.Types = append(filter.Types, value)
+		default:
+			return Filter{}, fmt.Errorf("%w: unknown term %q", ErrInvalidFilter, term)
+		}
+	}
+	return filter, nil
+}
+
+// Match tells whether the item with its decrypted label and data matches the filter
+func (f Filter) Match(wrapper models.DataWrapper, data any) bool {
+	for _, t := range f.Types {
+		if wrapper.Type != t {
+			return false
+		}
+	}
+	labeled, ok := data.(models.Labeled)
+	if !ok {
+		return false
+	}
+	meta := labeled.Labels()
+	if f.Favorite && !meta.Favorite {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !meta.HasTag(tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// Search returns not deleted items matching the filter
+// types and labels are encrypted inside items, so every item is decrypted to match it
+func (s *storage) Search(filter Filter) ([]models.DataWrapper, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var found []models.DataWrapper
+	for _, wrapper := range s.repo {
+		if wrapper.DeletedAt > 0 {
+			continue
+		}
+		label, err := s.Label(wrapper)
+		if err != nil {
+			return nil, err
+		}
+		if !itemTypes[label.Type] {
+			continue
+		}
+		data, err := s.Decrypt(wrapper)
+		if err != nil {
+			return nil, err
+		}
+		if filter.Match(label, data) {
+			found = append(found, wrapper)
+		}
+	}
+	return found, nil
+}
+
+// SavedFilters returns not deleted saved filters sorted by name
+func (s *storage) SavedFilters() ([]SavedFilter, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var filters []SavedFilter
+	for _, wrapper := range s.repo {
+		if wrapper.DeletedAt > 0 {
+			continue
+		}
+		label, err := s.Label(wrapper)
+		if err != nil {
+			return nil, err
+		}
+		if label.Type != models.FilterType {
+			continue
+		}
+		data, err := s.Decrypt(wrapper)
+		if err != nil {
+			return nil, err
+		}
+		saved := data.(models.SavedFilter)
+		filters = append(filters, SavedFilter{Wrapper: wrapper, Name: saved.Name, Query: saved.Query})
+	}
+	sort.Slice(filters, func(i, j int) bool {
+		return strings.ToLower(filters[i].Name) < strings.ToLower(filters[j].Name)
+	})
+	return filters, nil
+}
@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+)
+
+var (
+	// ErrNotFolder is returned by Move when the item is moved into something that is not a folder
+	ErrNotFolder = errors.New("there is no such folder")
+	// ErrFolderCycle is returned by Move when the folder is moved into itself or into a folder inside it
+	ErrFolderCycle = errors.New("folder can't be moved into itself")
+)
+
+// Folder is a folder of the user with its name decrypted
+type Folder struct {
+	Wrapper models.DataWrapper
+	Name    string
+	// ParentID is the folder it is in, empty for the root
+	ParentID string
+}
+
+// Folders returns not deleted folders by id
+// folders whose parent is deleted or which are in a loop made by moves on different devices are in the root
+func (s *storage) Folders() (map[string]Folder, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	folders := make(map[string]Folder)
+	for _, wrapper := range s.repo {
+		if wrapper.DeletedAt > 0 {
+			continue
+		}
+		label, err := s.Label(wrapper)
+		if err != nil {
+			return nil, err
+		}
+		if label.Type != models.FolderType {
+			continue
+		}
+		data, err := s.Decrypt(wrapper)
+		if err != nil {
+			return nil, err
+		}
+		folders[wrapper.ID] = Folder{Wrapper: wrapper, Name: data.(models.Folder).Name, ParentID: wrapper.FolderID}
+	}
+	for id, folder := range folders {
+		if _, ok := folders[folder.ParentID]; !ok || inside(folders, folder.ParentID, id) {
+			folder.ParentID = ""
+			folders[id] = folder
+		}
+	}
+	return folders, nil
+}
+
+// FolderOf returns the folder the item is in, empty for the root or if its folder is deleted
+func FolderOf(wrapper models.DataWrapper, folders map[string]Folder) string {
+	if _, ok := folders[wrapper.FolderID]; ok {
+		return wrapper.FolderID
+	}
+	return ""
+}
+
+// Move puts the item or folder into the folder, empty folderID is the root
+// the item is not re-encrypted, it is sent on the next sync as any edit
+func (s *storage) Move(id, folderID string) error {
+	folders, err := s.Folders()
+	if err != nil {
+		return err
+	}
+	if _, ok := folders[folderID]; folderID != "" && !ok {
+		return ErrNotFolder
+	}
+	if folderID == id || inside(folders, folderID, id) {
+		return ErrFolderCycle
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.repo[id]
+	if !ok || item.DeletedAt > 0 {
+		return fmt.Errorf("item with id %s not found", id)
+	}
+	s.changed = true
+	item.FolderID = folderID
+	item.UpdatedAt = time.Now().Unix()
+	s.repo[id] = item
+	s.dirty[id] = struct{}{}
+	return nil
+}
+
+// DeleteFolder deletes the folder, items and folders in it are moved to its parent
+func (s *storage) DeleteFolder(id string) error {
+	folders, err := s.Folders()
+	if err != nil {
+		return err
+	}
+	folder, ok := folders[id]
+	if !ok {
+		return ErrNotFolder
+	}
+
+	s.mu.Lock()
+	t := time.Now().Unix()
+	for childID, child := range s.repo {
+		if child.FolderID != id || child.DeletedAt > 0 {
+			continue
+		}
+		s.changed = true
+		child.FolderID = folder.ParentID
+		child.UpdatedAt = t
+		s.repo[childID] = child
+		s.dirty[childID] = struct{}{}
+	}
+	s.mu.Unlock()
+	return s.Delete(id)
+}
+
+// inside tells whether folderID is ancestorID or a folder inside it
+func inside(folders map[string]Folder, folderID, ancestorID string) bool {
+	// Every step goes up, so a loop is left after len(folders) steps
+	for i := 0; folderID != "" && i <= len(folders); i++ {
+		if folderID == ancestorID {
+			return true
+		}
+		folderID = folders[folderID].ParentID
+	}
+	return false
+}
@@ -1,4 +1,5 @@
 // Package storage
 // is a simple storage for data with mutex and map
 // it decrypts data on get and encrypts on set
+// data is persisted to an encrypted cache file in ~/.goph-keeper, so the client works offline
 package storage
@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/gynshu-one/goph-keeper/client/auth"
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/gynshu-one/goph-keeper/common/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// ItemKey returns the key the item is encrypted with if it has its own one, empty if it's encrypted with the secret
+func (s *storage) ItemKey(id string) (string, error) {
+	s.mu.RLock()
+	item, ok := s.repo[id]
+	s.mu.RUnlock()
+	if !ok || item.DeletedAt > 0 {
+		return "", models.ErrDeleted
+	}
+	if len(item.Key) == 0 {
+		return "", nil
+	}
+	return itemPassphrase(item.Key)
+}
+
+// Rekey gives the item a new key of its own, re-encrypts it and returns the key
+// the item is sent to server on the next sync
+func (s *storage) Rekey(id string) (string, error) {
+	key, err := utils.GenerateItemKey()
+	if err != nil {
+		return "", err
+	}
+	encryptedKey, err := utils.EncryptData([]byte(key), secret())
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.repo[id]
+	if !ok || item.DeletedAt > 0 {
+		return "", models.ErrDeleted
+	}
+	if item.Data, err = reseal(item.Data, item.Key, encryptedKey); err != nil {
+		return "", err
+	}
+	if item, err = resealLabel(item, encryptedKey); err != nil {
+		return "", err
+	}
+	item.Key = encryptedKey
+	s.changed = true
+	s.repo[id] = item
+	s.dirty[id] = struct{}{}
+	return key, nil
+}
+
+// itemPassphrase returns the passphrase data of the item with the key is encrypted with
+// item without key of its own is encrypted with the secret
+func itemPassphrase(key []byte) (string, error) {
+	return passphraseWith(key, secret())
+}
+
+// passphraseWith returns the passphrase data of the item with the key is encrypted with
+// when items are encrypted with the given secret, which is not necessary the secret of the user
+func passphraseWith(key []byte, secret string) (string, error) {
+	if len(key) == 0 {
+		return secret, nil
+	}
+	passphrase, err := utils.DecryptData(key, secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt item key: %w", err)
+	}
+	return string(passphrase), nil
+}
+
+// reseal re-encrypts data encrypted by item key from with item key to
+// sealed plaintext is encrypted as is, so it's not decoded
+func reseal(data, from, to []byte) ([]byte, error) {
+	if bytes.Equal(from, to) || len(data) == 0 {
+		return data, nil
+	}
+	fromPassphrase, err := itemPassphrase(from)
+	if err != nil {
+		return nil, err
+	}
+	toPassphrase, err := itemPassphrase(to)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := utils.DecryptData(data, fromPassphrase)
+	if err != nil {
+		return nil, err
+	}
+	return utils.EncryptData(plaintext, toPassphrase)
+}
+
+// resealLabel re-encrypts the label of the wrapper encrypted by its key with item key to
+// plaintext label is kept, it's encrypted by SealLabels
+func resealLabel(wrapper models.DataWrapper, to []byte) (models.DataWrapper, error) {
+	if bytes.Equal(wrapper.Key, to) || wrapper.LabelVersion == models.LabelPlain {
+		return wrapper, nil
+	}
+	fromPassphrase, err := itemPassphrase(wrapper.Key)
+	if err != nil {
+		return wrapper, err
+	}
+	toPassphrase, err := itemPassphrase(to)
+	if err != nil {
+		return wrapper, err
+	}
+	if wrapper, err = models.OpenLabel(wrapper, fromPassphrase); err != nil {
+		return wrapper, err
+	}
+	return models.SealLabel(wrapper, toPassphrase)
+}
+
+// secret returns the secret from os keyring
+func secret() string {
+	secret := auth.GetSecret()
+	if secret == "" {
+		log.Fatal().Msg("secret is nil")
+	}
+	return secret
+}
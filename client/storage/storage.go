@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"bytes"
 	"fmt"
 	"sync"
 	"time"
@@ -8,7 +9,6 @@ import (
 	"github.com/google/uuid"
 	"github.com/gynshu-one/goph-keeper/client/auth"
 	"github.com/gynshu-one/goph-keeper/common/models"
-	"github.com/rs/zerolog/log"
 )
 
 // Storage is a struct that holds a sync.Map to store all models.
@@ -19,33 +19,114 @@ type Storage interface {
 	// by creating models.DataWrapper struct and adding it to the storage
 	// Wrapper should be passed with Name and Type fields
 	AddEncrypt(data models.BasicData, wrapper models.DataWrapper) error
-	// Swap replaces all data in the storage with new data
-	// this is server client exchange method
-	Swap(data []models.DataWrapper) error
+	// Merge stores server versions of items unless local ones have newer revision
+	// items changed locally and not sent yet are kept, they are sent on the next sync
+	Merge(data []models.DataWrapper) error
+	// Dirty returns items changed locally since they were sent to server
+	Dirty() []models.DataWrapper
+	// Cursor returns the cursor of the last sync
+	Cursor() int64
+	// Apply applies the server response to the items sent to it:
+	// sent items are not dirty anymore unless they were changed again while the request was made,
+	// items rejected by server are removed, changed items are merged and the cursor is moved
+	// if response is Reset, all data except dirty items is replaced
+	// items in conflict get server version, local version is kept until the conflict is resolved
+	Apply(sent []models.DataWrapper, response models.SyncResponse) error
+	// ApplyChange stores the item received from changes stream
+	// items changed locally since the stream started are kept, they are sent on the next sync
+	ApplyChange(item models.DataWrapper) error
+	// Checkpoint moves the cursor to the checkpoint of changes stream
+	// Reset checkpoint starts download of all data, items that are not received till Done checkpoint are removed
+	Checkpoint(checkpoint models.Checkpoint) error
+	// Resetting tells the download of all data was interrupted and should be resumed from the cursor
+	Resetting() bool
+	// Conflict returns local and server versions of the item if server rejected its local edit
+	Conflict(id string) (Conflict, bool)
+	// ResolveConflict resolves the conflict of the item
+	// keepLocal replaces server version with the local one on the next sync, otherwise local version is dropped
+	ResolveConflict(id string, keepLocal bool) error
 	// FindDecrypt finds a model in the storage by id and decrypts it
-	// returns decrypted data and wrapper
+	// returns decrypted data and wrapper with its name and type decrypted
 	// if wrapper content (data) is deleted returns error and wrapper
 	// for ui
 	FindDecrypt(id string) (data any, wrapper models.DataWrapper, err error)
+	// Label decrypts name and type of the given wrapper which is not necessary in the storage
+	// wrapper with plaintext label is returned as is
+	Label(wrapper models.DataWrapper) (models.DataWrapper, error)
+	// SealLabels encrypts names and types stored in plaintext before labels were encrypted
+	// such items are sent to server on the next sync
+	SealLabels() error
+	// Decrypt decrypts data of the given wrapper which is not necessary in the storage
+	// such as previous versions of items received from server
+	Decrypt(wrapper models.DataWrapper) (data any, err error)
+	// Restore replaces the item with the previous version of it
+	// the item is marked as updated now, so it will win over the current version on the next sync
+	Restore(revision models.DataWrapper) error
 	// Delete sets deleted time and clears data field of
 	Delete(id string) error
+	// ItemKey returns the key the item is encrypted with if it has its own one, empty if it's encrypted with the secret
+	ItemKey(id string) (string, error)
+	// Rekey gives the item a new key of its own, re-encrypts it and returns the key
+	// it's used when the item is shared for the first time and when a share of it is revoked
+	Rekey(id string) (string, error)
+	// Folders returns not deleted folders by id with their names decrypted
+	Folders() (map[string]Folder, error)
+	// Move puts the item or folder into the folder, empty folderID is the root
+	Move(id, folderID string) error
+	// DeleteFolder deletes the folder, items and folders in it are moved to its parent
+	DeleteFolder(id string) error
+	// Search returns not deleted items matching the filter
+	Search(filter Filter) ([]models.DataWrapper, error)
+	// SavedFilters returns not deleted saved filters sorted by name
+	SavedFilters() ([]SavedFilter, error)
 
 	// Get returns all data from storage
 	// for server
 	Get() (data []models.DataWrapper)
+
+	// Load replaces data in the storage with the encrypted cache file at path
+	// and makes Flush write to it, missing file is not an error
+	Load(path string) error
+	// Flush writes data to the cache file if it was changed since the last flush
+	Flush() error
+}
+
+// Conflict is a local edit of the item made on top of a revision that is not the current one on server
+type Conflict struct {
+	Local  models.DataWrapper
+	Server models.DataWrapper
 }
 
 type storage struct {
 	mu *sync.RWMutex
 	// repo is a map of models.DataWrapper key is ID field of data
 	repo map[string]models.DataWrapper
+	// dirty is a set of ids of items changed locally and not sent to server yet
+	dirty map[string]struct{}
+	// cursor is the position in the server change feed received on the last sync
+	cursor int64
+	// conflicts are local edits rejected by server, key is item id
+	conflicts map[string]Conflict
+	// stale are ids of items not received yet during download of all data
+	// nil when no download of all data is in progress
+	stale map[string]struct{}
+
+	// path is the cache file, empty if the storage is not persisted
+	path string
+	// changed is set when data was changed since the last flush
+	changed bool
+	// flushing serializes writes of the cache file
+	flushing *sync.Mutex
 }
 
 // NewStorage creates a new storage instance
 func NewStorage() Storage {
 	return &storage{
-		mu:   &sync.RWMutex{},
-		repo: make(map[string]models.DataWrapper),
+		mu:        &sync.RWMutex{},
+		repo:      make(map[string]models.DataWrapper),
+		dirty:     make(map[string]struct{}),
+		conflicts: make(map[string]Conflict),
+		flushing:  &sync.Mutex{},
 	}
 }
 
@@ -53,16 +134,25 @@ func NewStorage() Storage {
 // Use only For NEW Data
 // it encrypts data and saves it to the storage
 // by creating models.DataWrapper struct and adding it to the storage
-// Wrapper should be passed with Name and Type fields
+// Wrapper should be passed with Name and Type fields in plaintext
+// item that has its own key is encrypted with it, so are its name and type
 func (s *storage) AddEncrypt(data models.BasicData, wrapper models.DataWrapper) error {
-	secret := auth.GetSecret()
-	if secret == "" {
-		log.Fatal().Msg("secret is nil")
+	if len(wrapper.Key) == 0 {
+		s.mu.RLock()
+		wrapper.Key = s.repo[wrapper.ID].Key
+		s.mu.RUnlock()
 	}
-	encrypted, err := data.EncryptAll(secret)
+	passphrase, err := itemPassphrase(wrapper.Key)
 	if err != nil {
 		return err
 	}
+	encrypted, err := data.EncryptAll(passphrase)
+	if err != nil {
+		return err
+	}
+	if wrapper, err = models.SealLabel(wrapper, passphrase); err != nil {
+		return err
+	}
 	t := time.Now().Unix()
 	wrapper.Data = encrypted
 	if wrapper.CreatedAt == 0 {
@@ -76,12 +166,16 @@ func (s *storage) AddEncrypt(data models.BasicData, wrapper models.DataWrapper)
 	wrapper.DeletedAt = 0
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.changed = true
 	s.repo[wrapper.ID] = wrapper
+	s.dirty[wrapper.ID] = struct{}{}
+	// Saved item is the resolution of its conflict
+	delete(s.conflicts, wrapper.ID)
 	return nil
 }
 
 // FindDecrypt finds a model in the storage by id and decrypts it
-// returns decrypted data and wrapper
+// returns decrypted data and wrapper with its name and type decrypted, so it's saved by AddEncrypt as is
 // if wrapper content (data) is deleted returns error and wrapper
 func (s *storage) FindDecrypt(id string) (data any, wrapper models.DataWrapper, err error) {
 	s.mu.RLock()
@@ -93,51 +187,180 @@ func (s *storage) FindDecrypt(id string) (data any, wrapper models.DataWrapper,
 		return nil, wrapper, models.ErrDeleted
 	}
 
-	// Decrypt data with os keyring secret
-	secret := auth.GetSecret()
-	if secret == "" {
-		log.Fatal().Msg("secret is nil")
+	if wrapper.DeletedAt > 0 {
+		return nil, wrapper, models.ErrDeleted
+	}
+
+	data, err = s.Decrypt(wrapper)
+	if err != nil {
+		return nil, wrapper, err
+	}
+	wrapper, err = s.Label(wrapper)
+	return data, wrapper, err
+}
+
+// Label decrypts name and type of the given wrapper which is not necessary in the storage
+// wrapper with plaintext label is returned as is
+func (s *storage) Label(wrapper models.DataWrapper) (models.DataWrapper, error) {
+	passphrase, err := itemPassphrase(wrapper.Key)
+	if err != nil {
+		return wrapper, err
+	}
+	return models.OpenLabel(wrapper, passphrase)
+}
+
+// OpenLabelWith decrypts name and type of the wrapper that belongs to a vault encrypted with the secret
+func OpenLabelWith(wrapper models.DataWrapper, secret string) (models.DataWrapper, error) {
+	passphrase, err := passphraseWith(wrapper.Key, secret)
+	if err != nil {
+		return wrapper, err
 	}
+	return models.OpenLabel(wrapper, passphrase)
+}
+
+// SealLabels encrypts names and types stored in plaintext before labels were encrypted
+// such items are sent to server on the next sync, items in conflict keep their labels until it is resolved
+func (s *storage) SealLabels() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
+	for id, item := range s.repo {
+		if _, ok := s.conflicts[id]; ok || item.LabelVersion != models.LabelPlain {
+			continue
+		}
+		passphrase, err := itemPassphrase(item.Key)
+		if err != nil {
+			return err
+		}
+		if item, err = models.SealLabel(item, passphrase); err != nil {
+			return err
+		}
+		s.changed = true
+		s.repo[id] = item
+		s.dirty[id] = struct{}{}
+	}
+	return nil
+}
+
+// Decrypt decrypts data of the given wrapper which is not necessary in the storage
+// such as previous versions of items received from server
+func (s *storage) Decrypt(wrapper models.DataWrapper) (data any, err error) {
 	if wrapper.DeletedAt > 0 {
-		return nil, wrapper, models.ErrDeleted
+		return nil, models.ErrDeleted
 	}
+	passphrase, err := itemPassphrase(wrapper.Key)
+	if err != nil {
+		return nil, err
+	}
+	return Open(wrapper, passphrase)
+}
 
+// OpenWith decrypts data of the wrapper that belongs to a vault encrypted with the secret
+// it's used for items of another user opened by emergency access
+func OpenWith(wrapper models.DataWrapper, secret string) (data any, err error) {
+	passphrase, err := passphraseWith(wrapper.Key, secret)
+	if err != nil {
+		return nil, err
+	}
+	return Open(wrapper, passphrase)
+}
+
+// Open decrypts data of the wrapper with the passphrase
+// it's used for items shared by other users, their keys are not encrypted with the secret of the user
+func Open(wrapper models.DataWrapper, passphrase string) (data any, err error) {
+	secret := passphrase
+	// Type is sealed into the label
+	if wrapper, err = models.OpenLabel(wrapper, secret); err != nil {
+		return nil, err
+	}
 	// Determine type and decrypt
 	switch wrapper.Type {
 	case models.LoginType:
 		var login models.Login
 		if err = login.DecryptAll(secret, wrapper.Data); err != nil {
-			return nil, wrapper, err
+			return nil, err
 		}
-		return login, wrapper, nil
+		return login, nil
 	case models.ArbitraryTextType:
 		var text models.ArbitraryText
 		if err = text.DecryptAll(secret, wrapper.Data); err != nil {
-			return nil, wrapper, err
+			return nil, err
 		}
-		return text, wrapper, nil
+		return text, nil
 	case models.BankCardType:
 		var bankCard models.BankCard
 		if err = bankCard.DecryptAll(secret, wrapper.Data); err != nil {
-			return nil, wrapper, err
+			return nil, err
 		}
-		return bankCard, wrapper, nil
+		return bankCard, nil
 	case models.BinaryType:
 		var binary models.Binary
 		if err = binary.DecryptAll(secret, wrapper.Data); err != nil {
-			return nil, wrapper, err
+			return nil, err
+		}
+		return binary, nil
+	case models.SSHKeyType:
+		var key models.SSHKey
+		if err = key.DecryptAll(secret, wrapper.Data); err != nil {
+			return nil, err
 		}
-		return binary, wrapper, nil
+		return key, nil
+	case models.FolderType:
+		var folder models.Folder
+		if err = folder.DecryptAll(secret, wrapper.Data); err != nil {
+			return nil, err
+		}
+		return folder, nil
+	case models.FilterType:
+		var filter models.SavedFilter
+		if err = filter.DecryptAll(secret, wrapper.Data); err != nil {
+			return nil, err
+		}
+		return filter, nil
 	}
 
-	return nil, wrapper, models.ErrUnknownType
+	return nil, models.ErrUnknownType
+}
+
+// Restore replaces the item with the previous version of it
+// the item is marked as updated now, so it will win over the current version on the next sync
+func (s *storage) Restore(revision models.DataWrapper) error {
+	if revision.DeletedAt > 0 || len(revision.Data) == 0 {
+		return models.ErrDeleted
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.changed = true
+
+	item, ok := s.repo[revision.ID]
+	if !ok {
+		item = revision
+	}
+	// Previous version may be encrypted with a key that was rotated since
+	data, err := reseal(revision.Data, revision.Key, item.Key)
+	if err != nil {
+		return err
+	}
+	// Previous version may have plaintext label, it's sealed by SealLabels
+	label, err := resealLabel(revision, item.Key)
+	if err != nil {
+		return err
+	}
+	item.Name, item.Type, item.LabelVersion = label.Name, label.Type, label.LabelVersion
+	item.Data = data
+	item.Blobs = revision.Blobs
+	item.UpdatedAt = time.Now().Unix()
+	item.DeletedAt = 0
+	s.repo[item.ID] = item
+	s.dirty[item.ID] = struct{}{}
+	return nil
 }
 
 // Delete sets deleted time and clears data field of
 func (s *storage) Delete(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.changed = true
 
 	// Find wrapper
 	item, ok := s.repo[id]
@@ -150,23 +373,218 @@ func (s *storage) Delete(id string) error {
 	item.UpdatedAt = time.Now().Unix()
 	item.Data = nil
 	s.repo[id] = item
+	s.dirty[id] = struct{}{}
 	return nil
 }
 
-// Swap replaces all data in the storage with new data
-// this is server client exchange method
-func (s *storage) Swap(data []models.DataWrapper) error {
+// Merge stores server versions of items unless local ones have newer revision
+// items changed locally and not sent yet are kept, they are sent on the next sync
+func (s *storage) Merge(data []models.DataWrapper) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if data == nil {
+	s.changed = true
+
+	for _, item := range data {
+		s.merge(item)
+	}
+	return nil
+}
+
+// merge stores server version of the item unless local one is dirty or has newer revision
+// must be called under the lock
+func (s *storage) merge(item models.DataWrapper) {
+	if _, ok := s.dirty[item.ID]; ok {
+		return
+	}
+	if local, ok := s.repo[item.ID]; ok && local.Revision > item.Revision {
+		return
+	}
+	s.repo[item.ID] = item
+}
+
+// Dirty returns items changed locally since they were sent to server
+func (s *storage) Dirty() (data []models.DataWrapper) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for id := range s.dirty {
+		data = append(data, s.repo[id])
+	}
+	return
+}
+
+// Cursor returns the cursor of the last sync
+func (s *storage) Cursor() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cursor
+}
+
+// Apply applies the server response to the items sent to it:
+// sent items are not dirty anymore unless they were changed again while the request was made,
+// items rejected by server are removed, changed items are merged and the cursor is moved
+// if response is Reset, all data except dirty items is replaced
+// items in conflict get server version, local version is kept until the conflict is resolved
+func (s *storage) Apply(sent []models.DataWrapper, response models.SyncResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.changed = true
+
+	if response.Reset {
+		repo := make(map[string]models.DataWrapper, len(response.Data))
+		// Items that were not sent yet are kept
+		for id := range s.dirty {
+			repo[id] = s.repo[id]
+		}
+		s.repo = repo
+	}
+	results := make(map[string]models.SyncResult, len(response.Results))
+	for _, result := range response.Results {
+		results[result.ID] = result
+	}
+	current := make(map[string]models.DataWrapper, len(response.Data))
+	for _, item := range response.Data {
+		current[item.ID] = item
+	}
+
+	for _, item := range sent {
+		local, ok := s.repo[item.ID]
+		// Edit made while the request was in flight stays dirty and is sent on the next sync
+		edited := ok && !sameEdit(local, item)
+		result := results[item.ID]
+		switch result.Status {
+		case models.StatusApplied:
+			// Server incremented the revision the item was sent with
+			if ok {
+				local.Revision = item.Revision + 1
+				s.repo[item.ID] = local
+			}
+		case models.StatusStale:
+			// Server has the sent content, so the new edit is on top of its version
+			if server, found := current[item.ID]; found && edited {
+				local.Revision = server.Revision
+				s.repo[item.ID] = local
+			}
+		case models.StatusRejected:
+			delete(s.repo, item.ID)
+			delete(s.dirty, item.ID)
+			continue
+		case models.StatusConflict:
+			if result.Server == nil {
+				break
+			}
+			if !edited {
+				local = item
+			}
+			s.conflicts[item.ID] = Conflict{Local: local, Server: *result.Server}
+			delete(s.dirty, item.ID)
+			// Changes may already have even newer version
+			if s.repo[item.ID].Revision < result.Server.Revision {
+				s.repo[item.ID] = *result.Server
+			}
+			continue
+		}
+		if !edited {
+			delete(s.dirty, item.ID)
+		}
+	}
+	for _, item := range response.Data {
+		s.merge(item)
+	}
+	s.cursor = response.Cursor
+	return nil
+}
+
+// sameEdit tells whether both versions of the item are the same local edit
+// data is encrypted with random nonce, so every edit changes it
+func sameEdit(a, b models.DataWrapper) bool {
+	return a.Revision == b.Revision && a.UpdatedAt == b.UpdatedAt && a.DeletedAt == b.DeletedAt &&
+		a.Name == b.Name && a.FolderID == b.FolderID && bytes.Equal(a.Data, b.Data) && bytes.Equal(a.Key, b.Key)
+}
+
+// ApplyChange stores the item received from changes stream
+// items changed locally since the stream started are kept, they are sent on the next sync
+func (s *storage) ApplyChange(item models.DataWrapper) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.changed = true
+
+	delete(s.stale, item.ID)
+	s.merge(item)
+	return nil
+}
+
+// Checkpoint moves the cursor to the checkpoint of changes stream
+// Reset checkpoint starts download of all data, items that are not received till Done checkpoint are removed
+func (s *storage) Checkpoint(checkpoint models.Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.changed = true
+
+	if checkpoint.Reset {
+		// Local data is kept until the download is done, so interrupted one doesn't empty the vault
+		s.stale = make(map[string]struct{}, len(s.repo))
+		for id := range s.repo {
+			if _, ok := s.dirty[id]; !ok {
+				s.stale[id] = struct{}{}
+			}
+		}
+	}
+	if checkpoint.Done {
+		for id := range s.stale {
+			delete(s.repo, id)
+		}
+		s.stale = nil
+	}
+	s.cursor = checkpoint.Cursor
+	return nil
+}
+
+// Resetting tells the download of all data was interrupted and should be resumed from the cursor
+func (s *storage) Resetting() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.stale != nil
+}
+
+// Conflict returns local and server versions of the item if server rejected its local edit
+func (s *storage) Conflict(id string) (Conflict, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	conflict, ok := s.conflicts[id]
+	return conflict, ok
+}
+
+// ResolveConflict resolves the conflict of the item
+// keepLocal replaces server version with the local one on the next sync, otherwise local version is dropped
+func (s *storage) ResolveConflict(id string, keepLocal bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.changed = true
+
+	conflict, ok := s.conflicts[id]
+	if !ok {
+		return fmt.Errorf("item with id %s has no conflict", id)
+	}
+	delete(s.conflicts, id)
+	if !keepLocal {
 		return nil
 	}
 
-	// Completely replace the storage
-	s.repo = make(map[string]models.DataWrapper)
-	for i := range data {
-		s.repo[data[i].ID] = data[i]
+	// Local version becomes an edit of the current server revision, with its key if it was rotated
+	item := conflict.Local
+	data, err := reseal(item.Data, item.Key, s.repo[id].Key)
+	if err != nil {
+		return err
 	}
+	if item, err = resealLabel(item, s.repo[id].Key); err != nil {
+		return err
+	}
+	item.Data, item.Key = data, s.repo[id].Key
+	item.Revision = s.repo[id].Revision
+	item.UpdatedAt = time.Now().Unix()
+	s.repo[id] = item
+	s.dirty[id] = struct{}{}
 	return nil
 }
 
@@ -1,6 +1,13 @@
 package storage
 
 import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/gynshu-one/goph-keeper/client/auth"
@@ -97,7 +104,7 @@ func TestDelete(t *testing.T) {
 	}
 }
 
-func TestSwapAndGetData(t *testing.T) {
+func TestMergeAndGetData(t *testing.T) {
 	keyring.MockInit()
 	// Set a secret for encryption
 	auth.SetSecret("test_secret")
@@ -117,10 +124,10 @@ func TestSwapAndGetData(t *testing.T) {
 		},
 	}
 
-	// Swap the test data into the storage
-	err := s.Swap(testData)
+	// Merge the test data into the storage
+	err := s.Merge(testData)
 	if err != nil {
-		t.Errorf("Swap returned an error: %v", err)
+		t.Errorf("Merge returned an error: %v", err)
 	}
 
 	// Get the data from the storage
@@ -153,7 +160,7 @@ func TestGetNonExistentData(t *testing.T) {
 	}
 }
 
-func TestSwap(t *testing.T) {
+func TestMerge(t *testing.T) {
 	keyring.MockInit()
 	// Create a new storage instance
 	s := NewStorage()
@@ -164,8 +171,8 @@ func TestSwap(t *testing.T) {
 		{ID: "2", Type: "baz", Data: []byte("qux")},
 	}
 
-	// Swap the data in the storage
-	err := s.Swap(testData)
+	// Merge the data into the storage
+	err := s.Merge(testData)
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -184,3 +191,605 @@ func TestSwap(t *testing.T) {
 		}
 	}
 }
+
+func TestRestore(t *testing.T) {
+	keyring.MockInit()
+	// Set a secret for encryption
+	auth.SetSecret("test_secret")
+
+	// Create a new storage instance
+	s := NewStorage()
+
+	// Add and remember the first version
+	testWrapper := models.DataWrapper{ID: "test_id", Name: "first", Type: models.ArbitraryTextType}
+	err := s.AddEncrypt(&models.ArbitraryText{Text: "first"}, testWrapper)
+	if err != nil {
+		t.Fatalf("AddEncrypt returned an error: %v", err)
+	}
+	revision := s.Get()[0]
+
+	// Update and delete the item
+	testWrapper.Name = "second"
+	err = s.AddEncrypt(&models.ArbitraryText{Text: "second"}, testWrapper)
+	if err != nil {
+		t.Fatalf("AddEncrypt returned an error: %v", err)
+	}
+	if err = s.Delete("test_id"); err != nil {
+		t.Fatalf("Delete returned an error: %v", err)
+	}
+
+	// Restore the first version
+	if err = s.Restore(revision); err != nil {
+		t.Fatalf("Restore returned an error: %v", err)
+	}
+	data, wrapper, err := s.FindDecrypt("test_id")
+	if err != nil {
+		t.Fatalf("FindDecrypt returned an error: %v", err)
+	}
+	if data.(models.ArbitraryText).Text != "first" || wrapper.Name != "first" {
+		t.Errorf("Restored item does not match the first version")
+	}
+
+	// Deleted versions can't be restored
+	revision.DeletedAt = 1
+	if err = s.Restore(revision); err != models.ErrDeleted {
+		t.Errorf("Expected error: %v, got: %v", models.ErrDeleted, err)
+	}
+}
+
+func TestFolders(t *testing.T) {
+	keyring.MockInit()
+	auth.SetSecret("test_secret")
+	s := NewStorage()
+
+	// Work folder has Projects folder with an item in it
+	for _, folder := range []models.DataWrapper{
+		{ID: "work", Type: models.FolderType},
+		{ID: "projects", Type: models.FolderType, FolderID: "work"},
+	} {
+		if err := s.AddEncrypt(&models.Folder{Name: folder.ID}, folder); err != nil {
+			t.Fatalf("AddEncrypt returned an error: %v", err)
+		}
+	}
+	item := models.DataWrapper{ID: "item", Type: models.ArbitraryTextType, Name: "item", FolderID: "projects"}
+	if err := s.AddEncrypt(&models.ArbitraryText{Text: "text"}, item); err != nil {
+		t.Fatalf("AddEncrypt returned an error: %v", err)
+	}
+	folders, err := s.Folders()
+	if err != nil {
+		t.Fatalf("Folders returned an error: %v", err)
+	}
+	if len(folders) != 2 || folders["projects"].Name != "projects" || folders["projects"].ParentID != "work" {
+		t.Fatalf("Unexpected folders: %+v", folders)
+	}
+	if bytes.Contains(folders["work"].Wrapper.Data, []byte("work")) {
+		t.Error("Folder name is not encrypted")
+	}
+
+	// Folder can't be moved into itself or a folder inside it, items can't be moved into items
+	for _, move := range [][2]string{{"work", "work"}, {"work", "projects"}} {
+		if err = s.Move(move[0], move[1]); !errors.Is(err, ErrFolderCycle) {
+			t.Errorf("Expected %v moving %s to %s, got %v", ErrFolderCycle, move[0], move[1], err)
+		}
+	}
+	if err = s.Move("work", "item"); !errors.Is(err, ErrNotFolder) {
+		t.Errorf("Expected %v, got %v", ErrNotFolder, err)
+	}
+	if err = s.Move("item", "work"); err != nil {
+		t.Fatalf("Move returned an error: %v", err)
+	}
+	if _, wrapper, _ := s.FindDecrypt("item"); wrapper.FolderID != "work" {
+		t.Errorf("Expected item in work, got %s", wrapper.FolderID)
+	}
+
+	// Items and folders of the deleted folder go to its parent
+	if err = s.Move("item", "projects"); err != nil {
+		t.Fatalf("Move returned an error: %v", err)
+	}
+	if err = s.DeleteFolder("work"); err != nil {
+		t.Fatalf("DeleteFolder returned an error: %v", err)
+	}
+	folders, _ = s.Folders()
+	if len(folders) != 1 || folders["projects"].ParentID != "" {
+		t.Errorf("Expected projects in the root, got %+v", folders)
+	}
+	if err = s.DeleteFolder("projects"); err != nil {
+		t.Fatalf("DeleteFolder returned an error: %v", err)
+	}
+	if _, wrapper, _ := s.FindDecrypt("item"); wrapper.FolderID != "" {
+		t.Errorf("Expected item in the root, got %s", wrapper.FolderID)
+	}
+}
+
+func TestFolderLoop(t *testing.T) {
+	keyring.MockInit()
+	auth.SetSecret("test_secret")
+	s := NewStorage()
+
+	// Two devices moved folders into each other, one of them ends up in the root
+	for _, folder := range []models.DataWrapper{
+		{ID: "a", Type: models.FolderType, FolderID: "b"},
+		{ID: "b", Type: models.FolderType, FolderID: "a"},
+		{ID: "c", Type: models.FolderType, FolderID: "deleted"},
+	} {
+		if err := s.AddEncrypt(&models.Folder{Name: folder.ID}, folder); err != nil {
+			t.Fatalf("AddEncrypt returned an error: %v", err)
+		}
+	}
+	folders, err := s.Folders()
+	if err != nil {
+		t.Fatalf("Folders returned an error: %v", err)
+	}
+	if (folders["a"].ParentID == "") == (folders["b"].ParentID == "") {
+		t.Errorf("Expected exactly one of the looped folders in the root, got %+v", folders)
+	}
+	if folders["c"].ParentID != "" {
+		t.Errorf("Expected folder of a deleted folder in the root, got %+v", folders["c"])
+	}
+}
+
+func TestFilters(t *testing.T) {
+	keyring.MockInit()
+	auth.SetSecret("test_secret")
+	s := NewStorage()
+
+	items := []struct {
+		wrapper models.DataWrapper
+		data    models.BasicData
+	}{
+		{models.DataWrapper{ID: "db", Type: models.LoginType},
+			&models.Login{Meta: models.Meta{Tags: models.ParseTags("Prod, db,prod"), Favorite: true}}},
+		{models.DataWrapper{ID: "card", Type: models.BankCardType},
+			&models.BankCard{Meta: models.Meta{Tags: []string{"prod"}}}},
+		{models.DataWrapper{ID: "note", Type: models.ArbitraryTextType},
+			&models.ArbitraryText{Meta: models.Meta{Favorite: true}}},
+		{models.DataWrapper{ID: "folder", Type: models.FolderType}, &models.Folder{Name: "prod"}},
+		{models.DataWrapper{ID: "saved", Type: models.FilterType}, &models.SavedFilter{Name: "Prod", Query: "tag:prod"}},
+	}
+	for _, item := range items {
+		if err := s.AddEncrypt(item.data, item.wrapper); err != nil {
+			t.Fatalf("AddEncrypt returned an error: %v", err)
+		}
+	}
+	if _, wrapper, _ := s.FindDecrypt("db"); bytes.Contains(wrapper.Data, []byte("prod")) {
+		t.Error("Tags are not encrypted")
+	}
+	if data, _, _ := s.FindDecrypt("db"); len(data.(models.Login).Tags) != 2 {
+		t.Errorf("Expected tags deduplicated, got %+v", data)
+	}
+
+	// Terms of the query have to match all, folders and saved filters are not items
+	for query, expected := range map[string][]string{
+		"":                     {"card", "db", "note"},
+		"favorites":            {"db", "note"},
+		"tag:PROD":             {"card", "db"},
+		"favorites tag:prod":   {"db"},
+		"type:bank_card":       {"card"},
+		"type:login tag:other": nil,
+	} {
+		filter, err := ParseFilter(query)
+		if err != nil {
+			t.Fatalf("ParseFilter(%q) returned an error: %v", query, err)
+		}
+		found, err := s.Search(filter)
+		if err != nil {
+			t.Fatalf("Search returned an error: %v", err)
+		}
+		var ids []string
+		for _, item := range found {
+			ids = append(ids, item.ID)
+		}
+		sort.Strings(ids)
+		if !reflect.DeepEqual(ids, expected) {
+			t.Errorf("Expected %v for %q, got %v", expected, query, ids)
+		}
+	}
+	for _, invalid := range []string{"prod", "tag:", "type:folder", "owner:me"} {
+		if _, err := ParseFilter(invalid); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("Expected %v for %q, got %v", ErrInvalidFilter, invalid, err)
+		}
+	}
+
+	saved, err := s.SavedFilters()
+	if err != nil {
+		t.Fatalf("SavedFilters returned an error: %v", err)
+	}
+	if len(saved) != 1 || saved[0].Name != "Prod" || saved[0].Query != "tag:prod" {
+		t.Errorf("Unexpected saved filters: %+v", saved)
+	}
+}
+
+func TestSealLabels(t *testing.T) {
+	keyring.MockInit()
+	auth.SetSecret("test_secret")
+	s := NewStorage()
+
+	if err := s.AddEncrypt(&models.ArbitraryText{Text: "text"},
+		models.DataWrapper{ID: "new", Type: models.ArbitraryTextType, Name: "Company VPN"}); err != nil {
+		t.Fatalf("AddEncrypt returned an error: %v", err)
+	}
+	stored := s.Get()[0]
+	if stored.LabelVersion != models.LabelSealed || stored.Type != models.SealedType || strings.Contains(stored.Name, "VPN") {
+		t.Fatalf("Label is not encrypted: %s %s", stored.Type, stored.Name)
+	}
+	if _, wrapper, err := s.FindDecrypt("new"); err != nil || wrapper.Name != "Company VPN" || wrapper.Type != models.ArbitraryTextType {
+		t.Fatalf("Expected decrypted label, got %+v and %v", wrapper, err)
+	}
+
+	// Items of earlier versions have plaintext labels, they are encrypted and sent again
+	if err := s.Apply(s.Dirty(), models.SyncResponse{Data: []models.DataWrapper{
+		{ID: "old", Type: models.ArbitraryTextType, Name: "Personal Visa", Revision: 1},
+	}}); err != nil {
+		t.Fatalf("Apply returned an error: %v", err)
+	}
+	// Name that looks like an encrypted one is still plaintext
+	plain := models.DataWrapper{Name: "enc:Personal Visa", Type: models.BankCardType}
+	if label, err := s.Label(plain); err != nil || !reflect.DeepEqual(label, plain) {
+		t.Errorf("Expected plaintext label as is, got %+v and %v", label, err)
+	}
+	if len(s.Dirty()) != 0 {
+		t.Fatalf("Expected no dirty items, got %+v", s.Dirty())
+	}
+	if err := s.SealLabels(); err != nil {
+		t.Fatalf("SealLabels returned an error: %v", err)
+	}
+	dirty := s.Dirty()
+	if len(dirty) != 1 || dirty[0].ID != "old" || dirty[0].LabelVersion != models.LabelSealed || dirty[0].Type != models.SealedType {
+		t.Fatalf("Expected old item with encrypted label, got %+v", dirty)
+	}
+	if label, err := s.Label(dirty[0]); err != nil || label.Name != "Personal Visa" || label.Type != models.ArbitraryTextType {
+		t.Errorf("Expected Personal Visa text, got %+v and %v", label, err)
+	}
+
+	// Label gets the new key of the item too
+	if _, err := s.Rekey("new"); err != nil {
+		t.Fatalf("Rekey returned an error: %v", err)
+	}
+	if _, wrapper, err := s.FindDecrypt("new"); err != nil || wrapper.Name != "Company VPN" {
+		t.Errorf("Expected decrypted name after rekey, got %q and %v", wrapper.Name, err)
+	}
+}
+
+func TestConflictRekeyed(t *testing.T) {
+	keyring.MockInit()
+	auth.SetSecret("test_secret")
+	s := NewStorage()
+
+	if err := s.AddEncrypt(&models.ArbitraryText{Text: "local"},
+		models.DataWrapper{ID: "1", Type: models.ArbitraryTextType, Name: "Local name", Revision: 1}); err != nil {
+		t.Fatalf("AddEncrypt returned an error: %v", err)
+	}
+	sent := s.Dirty()
+
+	// Key of the item was rotated on another device
+	other := NewStorage()
+	if err := other.AddEncrypt(&models.ArbitraryText{Text: "server"},
+		models.DataWrapper{ID: "1", Type: models.ArbitraryTextType, Name: "Server name"}); err != nil {
+		t.Fatalf("AddEncrypt returned an error: %v", err)
+	}
+	if _, err := other.Rekey("1"); err != nil {
+		t.Fatalf("Rekey returned an error: %v", err)
+	}
+	server := other.Get()[0]
+	server.Revision = 2
+	err := s.Apply(sent, models.SyncResponse{
+		Results: []models.SyncResult{{ID: "1", Status: models.StatusConflict, Server: &server}},
+	})
+	if err != nil {
+		t.Fatalf("Apply returned an error: %v", err)
+	}
+
+	if err = s.ResolveConflict("1", true); err != nil {
+		t.Fatalf("ResolveConflict returned an error: %v", err)
+	}
+	data, wrapper, err := s.FindDecrypt("1")
+	if err != nil {
+		t.Fatalf("FindDecrypt returned an error: %v", err)
+	}
+	if wrapper.Name != "Local name" || data.(models.ArbitraryText).Text != "local" {
+		t.Errorf("Expected local version with the rotated key, got %q and %+v", wrapper.Name, data)
+	}
+	if dirty := s.Dirty(); len(dirty) != 1 || !bytes.Equal(dirty[0].Key, server.Key) {
+		t.Errorf("Local version isn't queued with the rotated key: %+v", dirty)
+	}
+}
+
+func TestDirtyAndApply(t *testing.T) {
+	keyring.MockInit()
+	auth.SetSecret("test_secret")
+	s := NewStorage()
+
+	// New items are dirty
+	for _, id := range []string{"1", "2", "3"} {
+		if err := s.AddEncrypt(&models.ArbitraryText{Text: id}, models.DataWrapper{ID: id, Type: models.ArbitraryTextType}); err != nil {
+			t.Fatalf("AddEncrypt returned an error: %v", err)
+		}
+	}
+	sent := s.Dirty()
+	if len(sent) != 3 {
+		t.Fatalf("Expected 3 dirty items, got %d", len(sent))
+	}
+
+	// Server applied "1", rejected "2", and "4" was changed on another device
+	err := s.Apply(sent, models.SyncResponse{
+		Results: []models.SyncResult{
+			{ID: "1", Status: models.StatusApplied},
+			{ID: "2", Status: models.StatusRejected},
+			{ID: "3", Status: models.StatusApplied},
+		},
+		Data:   []models.DataWrapper{{ID: "1", Revision: 1, Seq: 1}, {ID: "4", Revision: 3, Seq: 4}},
+		Cursor: 4,
+	})
+	if err != nil {
+		t.Fatalf("Apply returned an error: %v", err)
+	}
+	if len(s.Dirty()) != 0 {
+		t.Errorf("Sent items are still dirty")
+	}
+	if s.Cursor() != 4 {
+		t.Errorf("Cursor is %d, want 4", s.Cursor())
+	}
+	got := make(map[string]models.DataWrapper)
+	for _, item := range s.Get() {
+		got[item.ID] = item
+	}
+	if _, ok := got["2"]; ok {
+		t.Errorf("Rejected item wasn't removed")
+	}
+	if got["1"].Seq != 1 || got["4"].Revision != 3 || len(got) != 3 {
+		t.Errorf("Changes weren't applied: %+v", got)
+	}
+
+	// Deleted item is dirty again
+	if err = s.Delete("3"); err != nil {
+		t.Fatalf("Delete returned an error: %v", err)
+	}
+	if dirty := s.Dirty(); len(dirty) != 1 || dirty[0].ID != "3" {
+		t.Errorf("Expected deleted item to be dirty, got %+v", dirty)
+	}
+
+	// Reset replaces all data except items that were not sent yet
+	err = s.Apply(nil, models.SyncResponse{Data: []models.DataWrapper{{ID: "5"}}, Cursor: 7, Reset: true})
+	if err != nil {
+		t.Fatalf("Apply returned an error: %v", err)
+	}
+	got = make(map[string]models.DataWrapper)
+	for _, item := range s.Get() {
+		got[item.ID] = item
+	}
+	if _, ok := got["5"]; !ok || len(got) != 2 || got["3"].DeletedAt == 0 || s.Cursor() != 7 {
+		t.Errorf("Reset didn't replace data: %+v", got)
+	}
+}
+
+func TestConflict(t *testing.T) {
+	keyring.MockInit()
+	auth.SetSecret("test_secret")
+	s := NewStorage()
+
+	// Local edit of revision 1
+	err := s.AddEncrypt(&models.ArbitraryText{Text: "local"}, models.DataWrapper{ID: "1", Type: models.ArbitraryTextType, Revision: 1})
+	if err != nil {
+		t.Fatalf("AddEncrypt returned an error: %v", err)
+	}
+	sent := s.Dirty()
+
+	// Server has revision 2 already
+	server := models.DataWrapper{ID: "1", Type: models.ArbitraryTextType, Revision: 2, Data: []byte("server")}
+	err = s.Apply(sent, models.SyncResponse{
+		Results: []models.SyncResult{{ID: "1", Status: models.StatusConflict, Server: &server}},
+	})
+	if err != nil {
+		t.Fatalf("Apply returned an error: %v", err)
+	}
+
+	conflict, ok := s.Conflict("1")
+	if !ok {
+		t.Fatalf("Conflict wasn't recorded")
+	}
+	if conflict.Local.Revision != 1 || conflict.Server.Revision != 2 {
+		t.Errorf("Unexpected conflict %+v", conflict)
+	}
+	if got := s.Get(); len(got) != 1 || got[0].Revision != 2 {
+		t.Errorf("Server version wasn't stored: %+v", got)
+	}
+
+	// Keep local version on top of server revision
+	if err = s.ResolveConflict("1", true); err != nil {
+		t.Fatalf("ResolveConflict returned an error: %v", err)
+	}
+	if _, ok = s.Conflict("1"); ok {
+		t.Errorf("Conflict wasn't resolved")
+	}
+	dirty := s.Dirty()
+	if len(dirty) != 1 || dirty[0].Revision != 2 || !bytes.Equal(dirty[0].Data, conflict.Local.Data) {
+		t.Errorf("Local version isn't queued as edit of revision 2: %+v", dirty)
+	}
+	if err = s.ResolveConflict("1", false); err == nil {
+		t.Errorf("Expected error for item without conflict")
+	}
+}
+
+func TestChangesStream(t *testing.T) {
+	keyring.MockInit()
+	auth.SetSecret("test_secret")
+	s := NewStorage()
+
+	err := s.Apply(nil, models.SyncResponse{Data: []models.DataWrapper{{ID: "1"}, {ID: "2"}}, Cursor: 2, Reset: true})
+	if err != nil {
+		t.Fatalf("Apply returned an error: %v", err)
+	}
+	if err = s.AddEncrypt(&models.ArbitraryText{Text: "3"}, models.DataWrapper{ID: "3", Type: models.ArbitraryTextType}); err != nil {
+		t.Fatalf("AddEncrypt returned an error: %v", err)
+	}
+
+	// Download of all data is interrupted after the first page
+	if err = s.Checkpoint(models.Checkpoint{Reset: true}); err != nil {
+		t.Fatalf("Checkpoint returned an error: %v", err)
+	}
+	if err = s.ApplyChange(models.DataWrapper{ID: "1", Revision: 2}); err != nil {
+		t.Fatalf("ApplyChange returned an error: %v", err)
+	}
+	if err = s.Checkpoint(models.Checkpoint{Cursor: 5}); err != nil {
+		t.Fatalf("Checkpoint returned an error: %v", err)
+	}
+	if !s.Resetting() || s.Cursor() != 5 || len(s.Get()) != 3 {
+		t.Fatalf("Interrupted download changed data: resetting %v, cursor %d, items %d",
+			s.Resetting(), s.Cursor(), len(s.Get()))
+	}
+
+	// Resumed download doesn't have "2" and skips local edit of "3"
+	if err = s.ApplyChange(models.DataWrapper{ID: "3", Revision: 4}); err != nil {
+		t.Fatalf("ApplyChange returned an error: %v", err)
+	}
+	if err = s.Checkpoint(models.Checkpoint{Cursor: 9, Done: true}); err != nil {
+		t.Fatalf("Checkpoint returned an error: %v", err)
+	}
+	got := make(map[string]models.DataWrapper)
+	for _, item := range s.Get() {
+		got[item.ID] = item
+	}
+	if _, ok := got["2"]; ok || len(got) != 2 || got["1"].Revision != 2 || got["3"].Revision != 0 {
+		t.Errorf("Download didn't replace data: %+v", got)
+	}
+	if s.Resetting() || s.Cursor() != 9 {
+		t.Errorf("Download isn't done: resetting %v, cursor %d", s.Resetting(), s.Cursor())
+	}
+}
+
+func TestCache(t *testing.T) {
+	keyring.MockInit()
+	auth.SetSecret("test_secret")
+	path := filepath.Join(t.TempDir(), "test.vault")
+
+	// Missing cache is empty
+	s := NewStorage()
+	if err := s.Load(path); err != nil {
+		t.Fatalf("Load returned an error: %v", err)
+	}
+	err := s.Apply(nil, models.SyncResponse{Data: []models.DataWrapper{{ID: "1", Revision: 1}}, Cursor: 3, Reset: true})
+	if err != nil {
+		t.Fatalf("Apply returned an error: %v", err)
+	}
+	if err = s.AddEncrypt(&models.ArbitraryText{Text: "offline"}, models.DataWrapper{ID: "2", Type: models.ArbitraryTextType}); err != nil {
+		t.Fatalf("AddEncrypt returned an error: %v", err)
+	}
+	if err = s.Flush(); err != nil {
+		t.Fatalf("Flush returned an error: %v", err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte(`"cursor"`)) {
+		t.Errorf("Cache is not encrypted")
+	}
+
+	// Unsynced edit survives restart
+	loaded := NewStorage()
+	if err = loaded.Load(path); err != nil {
+		t.Fatalf("Load returned an error: %v", err)
+	}
+	if loaded.Cursor() != 3 || len(loaded.Get()) != 2 {
+		t.Errorf("Cache wasn't loaded: cursor %d, items %d", loaded.Cursor(), len(loaded.Get()))
+	}
+	if dirty := loaded.Dirty(); len(dirty) != 1 || dirty[0].ID != "2" {
+		t.Errorf("Unexpected dirty items %+v", dirty)
+	}
+	data, _, err := loaded.FindDecrypt("2")
+	if err != nil || data.(models.ArbitraryText).Text != "offline" {
+		t.Errorf("Unexpected item %v, error %v", data, err)
+	}
+
+	// Cache can't be read with another secret, it's a cache miss then
+	auth.SetSecret("another_secret")
+	other := NewStorage()
+	if err = other.Load(path); err != nil || other.Cursor() != 0 || len(other.Get()) != 0 {
+		t.Errorf("Cache was loaded with another secret: %v", err)
+	}
+
+	// Truncated cache is a cache miss too
+	auth.SetSecret("test_secret")
+	for _, size := range []int{0, 3} {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(path, content[:size], 0600); err != nil {
+			t.Fatal(err)
+		}
+		truncated := NewStorage()
+		if err = truncated.Load(path); err != nil || truncated.Cursor() != 0 || len(truncated.Get()) != 0 {
+			t.Errorf("Cache of %d bytes was not a cache miss: %v", size, err)
+		}
+	}
+
+	// Nothing is read or written without the secret
+	keyring.MockInit()
+	if err = NewStorage().Load(path); !errors.Is(err, auth.ErrNoSecret) {
+		t.Errorf("Expected %v on load, got %v", auth.ErrNoSecret, err)
+	}
+	if err = loaded.Apply(nil, models.SyncResponse{Cursor: 4}); err != nil {
+		t.Fatalf("Apply returned an error: %v", err)
+	}
+	if err = loaded.Flush(); !errors.Is(err, auth.ErrNoSecret) {
+		t.Errorf("Expected %v on flush, got %v", auth.ErrNoSecret, err)
+	}
+}
+
+func TestMergeKeepsLocalEdits(t *testing.T) {
+	keyring.MockInit()
+	auth.SetSecret("test_secret")
+	s := NewStorage()
+	add := func(id, text string, revision int64) {
+		t.Helper()
+		err := s.AddEncrypt(&models.ArbitraryText{Text: text}, models.DataWrapper{ID: id, Type: models.ArbitraryTextType, Revision: revision})
+		if err != nil {
+			t.Fatalf("AddEncrypt returned an error: %v", err)
+		}
+	}
+	add("1", "first", 0)
+	add("2", "second", 0)
+	sent := s.Dirty()
+
+	// "1" is edited again while the request is in flight
+	add("1", "edited", 0)
+	err := s.Apply(sent, models.SyncResponse{
+		Results: []models.SyncResult{
+			{ID: "1", Status: models.StatusApplied},
+			{ID: "2", Status: models.StatusApplied},
+		},
+		Cursor: 2,
+	})
+	if err != nil {
+		t.Fatalf("Apply returned an error: %v", err)
+	}
+	dirty := s.Dirty()
+	if len(dirty) != 1 || dirty[0].ID != "1" || dirty[0].Revision != 1 {
+		t.Fatalf("Edit made during sync wasn't kept on top of the applied revision: %+v", dirty)
+	}
+	data, _, err := s.FindDecrypt("1")
+	if err != nil || data.(models.ArbitraryText).Text != "edited" {
+		t.Errorf("Unexpected item %v, error %v", data, err)
+	}
+
+	// Server versions replace only items that are not dirty and not newer locally
+	err = s.Merge([]models.DataWrapper{
+		{ID: "1", Revision: 1, Data: []byte("own change")},
+		{ID: "2", Revision: 0, Data: []byte("outdated")},
+		{ID: "3", Revision: 4, Data: []byte("other device")},
+	})
+	if err != nil {
+		t.Fatalf("Merge returned an error: %v", err)
+	}
+	got := make(map[string]models.DataWrapper)
+	for _, item := range s.Get() {
+		got[item.ID] = item
+	}
+	if string(got["1"].Data) == "own change" || string(got["2"].Data) == "outdated" || got["2"].Revision != 1 {
+		t.Errorf("Local versions were replaced: %+v", got)
+	}
+	if string(got["3"].Data) != "other device" {
+		t.Errorf("New item wasn't stored: %+v", got["3"])
+	}
+}
@@ -0,0 +1,308 @@
+package sync
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"github.com/gynshu-one/goph-keeper/client/auth"
+	"github.com/gynshu-one/goph-keeper/client/config"
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/gynshu-one/goph-keeper/common/utils"
+)
+
+const (
+	// fileChunkSize is the size of file part encrypted at once, so big files are never read into memory
+	fileChunkSize = 1 << 20
+	// chunkOverhead is how much bigger encrypted part is, it has nonce and tag of AES-GCM
+	chunkOverhead = 12 + 16
+)
+
+// blobsDir is the directory files wait for upload and partial downloads are kept in
+// it's a variable, so tests don't touch home directory
+var blobsDir = func() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, "."+config.ServiceName, "blobs"), nil
+}
+
+// blobPath returns path of the file of the blob in blobsDir with the suffix
+func blobPath(id, suffix string) (string, error) {
+	dir, err := blobsDir()
+	if err != nil {
+		return "", err
+	}
+	if err = os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, filepath.Base(id)+suffix), nil
+}
+
+// AttachFile encrypts the file at path into upload queue and returns the reference to keep in the item
+// the file is uploaded by Sync before the item that refers to it is sent, so it works offline too
+// parts are encrypted deterministically with the key derived from the content, so the same file attached again
+// has the same hash and server stores it once without upload, and the key can be shared with the item
+func (m *mediator) AttachFile(path string) (models.BlobRef, error) {
+	source, err := os.Open(path)
+	if err != nil {
+		return models.BlobRef{}, err
+	}
+	defer func() {
+		_ = source.Close()
+	}()
+
+	key, err := fileKey(source)
+	if err != nil {
+		return models.BlobRef{}, err
+	}
+	if _, err = source.Seek(0, io.SeekStart); err != nil {
+		return models.BlobRef{}, err
+	}
+	ref := models.BlobRef{ID: uuid.NewString(), Key: key}
+	upload, err := blobPath(ref.ID, ".upload")
+	if err != nil {
+		return ref, err
+	}
+	file, err := os.CreateTemp(filepath.Dir(upload), filepath.Base(upload)+".*")
+	if err != nil {
+		return ref, err
+	}
+	defer func() {
+		// Does nothing if the file was renamed
+		_ = os.Remove(file.Name())
+	}()
+
+	hash := sha256.New()
+	out := io.MultiWriter(file, hash)
+	buf := make([]byte, fileChunkSize)
+	for part := uint64(0); ; part++ {
+		n, err := io.ReadFull(source, buf)
+		if n > 0 {
+			// Part number is in the nonce, so equal parts of the file don't look equal
+			encrypted, err := utils.EncryptDataDeterministic(buf[:n], binary.BigEndian.AppendUint64(nil, part), key)
+			if err != nil {
+				_ = file.Close()
+				return ref, err
+			}
+			if _, err = out.Write(encrypted); err != nil {
+				_ = file.Close()
+				return ref, err
+			}
+			ref.Size += int64(n)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			_ = file.Close()
+			return ref, err
+		}
+	}
+	if err = file.Close(); err != nil {
+		return ref, err
+	}
+	if ref.Size == 0 {
+		return ref, fmt.Errorf("file %s is empty", path)
+	}
+	ref.Hash = hex.EncodeToString(hash.Sum(nil))
+	return ref, os.Rename(file.Name(), upload)
+}
+
+// fileKey returns the key of the file content, it's made with the secret, so other users can't guess it
+// returns auth.ErrNoSecret if the secret can't be read, the key would be known to anyone with the content then
+func fileKey(content io.Reader) (string, error) {
+	secret, err := auth.Secret()
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	if _, err = io.Copy(hash, content); err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(hash.Sum(nil))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// uploadBlobs uploads files of the items that wait in upload queue
+func (m *mediator) uploadBlobs(ctx context.Context, items []models.DataWrapper) error {
+	for _, item := range items {
+		for _, id := range item.Blobs {
+			if err := m.uploadBlob(ctx, id); err != nil {
+				return fmt.Errorf("failed to upload file of item %s: %w", item.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// uploadBlob uploads the blob from upload queue in chunks and removes it from the queue
+// server tells where interrupted upload continues from, so only the rest is sent
+func (m *mediator) uploadBlob(ctx context.Context, id string) error {
+	path, err := blobPath(id, ".upload")
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		// Uploaded already
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return err
+	}
+	blob, err := m.transport.CreateBlob(ctx, auth.CurrentUser.SessionID,
+		models.Blob{ID: id, Size: size, Hash: hex.EncodeToString(hash.Sum(nil))})
+	if err != nil {
+		return err
+	}
+
+	chunk := make([]byte, models.BlobChunkSize)
+	for blob.Offset < size {
+		n, err := file.ReadAt(chunk, blob.Offset)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		next, err := m.transport.UploadBlob(ctx, auth.CurrentUser.SessionID, id, blob.Offset, chunk[:n])
+		if errors.Is(err, ErrBlobOffset) {
+			// Chunk was written by a request whose response was lost, ask where to continue
+			next, err = m.transport.CreateBlob(ctx, auth.CurrentUser.SessionID, blob)
+		}
+		if err != nil {
+			return err
+		}
+		blob = next
+	}
+	_ = file.Close()
+	return os.Remove(path)
+}
+
+// SaveFile downloads the file of the reference in chunks, checks its hash, decrypts it and writes it to path
+// downloaded part is kept, so interrupted download continues from where it stopped
+func (m *mediator) SaveFile(ctx context.Context, ref models.BlobRef, path string) error {
+	partial, err := blobPath(ref.ID, ".download")
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(partial, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	size := encryptedSize(ref.Size)
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	for offset < size {
+		chunk, err := m.transport.DownloadBlob(ctx, auth.CurrentUser.SessionID, ref.ID, offset)
+		if err != nil {
+			return err
+		}
+		if len(chunk) == 0 {
+			return fmt.Errorf("%w: file ends at %d, want %d bytes", ErrBlobHash, offset, size)
+		}
+		if _, err = file.Write(chunk); err != nil {
+			return err
+		}
+		offset += int64(len(chunk))
+	}
+
+	// Server can't replace the file, its hash is in encrypted item
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return err
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != ref.Hash {
+		_ = file.Close()
+		_ = os.Remove(partial)
+		return ErrBlobHash
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	key := ref.Key
+	if key == "" {
+		// Files of items without their own key are encrypted with the secret
+		if key, err = auth.Secret(); err != nil {
+			_ = file.Close()
+			return err
+		}
+	}
+	if err = decryptFile(file, key, path); err != nil {
+		return err
+	}
+	_ = file.Close()
+	return os.Remove(partial)
+}
+
+// decryptFile decrypts encrypted parts of the file made by AttachFile with the key and writes them to path
+// path is replaced only when all of it is decrypted
+func decryptFile(encrypted io.Reader, key, path string) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Does nothing if the file was renamed
+		_ = os.Remove(file.Name())
+	}()
+
+	buf := make([]byte, fileChunkSize+chunkOverhead)
+	for {
+		n, err := io.ReadFull(encrypted, buf)
+		if n > 0 {
+			decrypted, err := utils.DecryptData(buf[:n], key)
+			if err != nil {
+				_ = file.Close()
+				return err
+			}
+			if _, err = file.Write(decrypted); err != nil {
+				_ = file.Close()
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			_ = file.Close()
+			return err
+		}
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// encryptedSize returns size of the file of the size encrypted by AttachFile
+func encryptedSize(size int64) int64 {
+	chunks := (size + fileChunkSize - 1) / fileChunkSize
+	return size + chunks*chunkOverhead
+}
@@ -0,0 +1,180 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/gynshu-one/goph-keeper/client/auth"
+	"github.com/gynshu-one/goph-keeper/client/storage"
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/zalando/go-keyring"
+)
+
+// blobTransport keeps blobs in memory like server does
+// uploads and downloads fail once after failAfter chunks when it's set
+type blobTransport struct {
+	Transport
+	blobs     map[string]models.Blob
+	content   map[string][]byte
+	failAfter int
+	chunks    int
+	synced    []models.DataWrapper
+}
+
+func newBlobTransport() *blobTransport {
+	return &blobTransport{blobs: make(map[string]models.Blob), content: make(map[string][]byte)}
+}
+
+// fail tells whether the request fails as interrupted
+func (t *blobTransport) fail() bool {
+	t.chunks++
+	if t.failAfter > 0 && t.chunks > t.failAfter {
+		t.failAfter = 0
+		return true
+	}
+	return false
+}
+
+func (t *blobTransport) CreateBlob(_ context.Context, _ string, blob models.Blob) (models.Blob, error) {
+	if stored, ok := t.blobs[blob.ID]; ok {
+		return stored, nil
+	}
+	blob.Offset = 0
+	t.blobs[blob.ID] = blob
+	return blob, nil
+}
+
+func (t *blobTransport) UploadBlob(_ context.Context, _, id string, offset int64, chunk []byte) (models.Blob, error) {
+	blob := t.blobs[id]
+	if offset != blob.Offset {
+		return blob, &Error{Kind: ErrBlobOffset}
+	}
+	t.content[id] = append(t.content[id], chunk...)
+	blob.Offset += int64(len(chunk))
+	t.blobs[id] = blob
+	if t.fail() {
+		// Chunk is written, but client doesn't know it
+		return models.Blob{}, &Error{Kind: ErrOffline}
+	}
+	return blob, nil
+}
+
+func (t *blobTransport) DownloadBlob(_ context.Context, _, id string, offset int64) ([]byte, error) {
+	if t.fail() {
+		return nil, &Error{Kind: ErrOffline}
+	}
+	content := t.content[id]
+	return content[offset:min(int64(len(content)), offset+models.BlobChunkSize)], nil
+}
+
+func (t *blobTransport) Sync(_ context.Context, _ string, cursor int64, _ bool, _ string, sent []models.DataWrapper) (*models.SyncResponse, models.Usage, error) {
+	for _, item := range sent {
+		for _, id := range item.Blobs {
+			if blob, ok := t.blobs[id]; !ok || !blob.Complete() {
+				return nil, models.Usage{}, errors.New("blob is not uploaded")
+			}
+		}
+	}
+	t.synced = append(t.synced, sent...)
+	return &models.SyncResponse{Cursor: cursor}, models.Usage{}, nil
+}
+
+func (t *blobTransport) Changes(_ context.Context, _ string, cursor int64, _ bool, handle func(line models.ChangeLine) error) error {
+	return handle(models.ChangeLine{Checkpoint: &models.Checkpoint{Cursor: cursor, Done: true}})
+}
+
+func TestBlobs(t *testing.T) {
+	dir := t.TempDir()
+	blobsDir = func() (string, error) {
+		return filepath.Join(dir, "blobs"), nil
+	}
+	keyring.MockInit()
+	auth.CurrentUser.Username = "testuser"
+	auth.CurrentUser.SessionID = "test"
+	auth.SetSecret("test_secret")
+
+	transport := newBlobTransport()
+	s := storage.NewStorage()
+	md := newMediatorWith(s, transport)
+	md.transport.(*retryTransport).delay = 0
+
+	// File of a few chunks that doesn't end at chunk boundary
+	content := bytes.Repeat([]byte("file content "), 250000)
+	path := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ref, err := md.AttachFile(path)
+	if err != nil {
+		t.Fatalf("AttachFile failed with error: %v", err)
+	}
+	if ref.Size != int64(len(content)) {
+		t.Errorf("Reference has size %d, want %d", ref.Size, len(content))
+	}
+	// The same file gets the same content, so server stores it once
+	if again, err := md.AttachFile(path); err != nil || again.Hash != ref.Hash || again.ID == ref.ID {
+		t.Errorf("Expected the same content in a new blob, got %+v and %v", again, err)
+	}
+
+	// File is uploaded with the item, even though the upload is interrupted
+	transport.failAfter = 1
+	binary := &models.Binary{Info: "file.txt", Blob: &ref}
+	if err = s.AddEncrypt(binary, models.DataWrapper{Type: models.BinaryType, Name: "file", Blobs: []string{ref.ID}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = md.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync failed with error: %v", err)
+	}
+	if len(transport.synced) != 1 || !slices.Equal(transport.synced[0].Blobs, []string{ref.ID}) {
+		t.Errorf("Unexpected synced items %+v", transport.synced)
+	}
+	uploaded := transport.content[ref.ID]
+	sum := sha256.Sum256(uploaded)
+	if int64(len(uploaded)) != encryptedSize(ref.Size) || hex.EncodeToString(sum[:]) != ref.Hash {
+		t.Errorf("Uploaded %d bytes don't match the reference", len(uploaded))
+	}
+	if bytes.Contains(uploaded, []byte("file content")) {
+		t.Errorf("File was uploaded without encryption")
+	}
+	if _, err = os.Stat(filepath.Join(dir, "blobs", ref.ID+".upload")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("File is still in upload queue: %v", err)
+	}
+
+	// Interrupted download continues from the downloaded part
+	transport.chunks, transport.failAfter = 0, 1
+	saved := filepath.Join(dir, "saved.txt")
+	md.transport = transport
+	if err = md.SaveFile(context.Background(), ref, saved); !errors.Is(err, ErrOffline) {
+		t.Fatalf("Expected interrupted download, got %v", err)
+	}
+	transport.chunks = 0
+	if err = md.SaveFile(context.Background(), ref, saved); err != nil {
+		t.Fatalf("SaveFile failed with error: %v", err)
+	}
+	if transport.chunks != 3 {
+		t.Errorf("Downloaded %d chunks after interruption, want 3", transport.chunks)
+	}
+	got, err := os.ReadFile(saved)
+	if err != nil || !bytes.Equal(got, content) {
+		t.Errorf("Saved file doesn't match the original: %v", err)
+	}
+
+	// Replaced content is not saved
+	transport.content[ref.ID][0] ^= 1
+	if err = md.SaveFile(context.Background(), ref, filepath.Join(dir, "replaced.txt")); !errors.Is(err, ErrBlobHash) {
+		t.Errorf("Expected %v, got %v", ErrBlobHash, err)
+	}
+
+	// Key of a file made without the secret would be known to anyone with its content
+	keyring.MockInit()
+	if _, err = md.AttachFile(path); !errors.Is(err, auth.ErrNoSecret) {
+		t.Errorf("Expected %v, got %v", auth.ErrNoSecret, err)
+	}
+}
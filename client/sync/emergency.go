@@ -0,0 +1,91 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gynshu-one/goph-keeper/client/auth"
+	"github.com/gynshu-one/goph-keeper/client/storage"
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/gynshu-one/goph-keeper/common/utils"
+)
+
+// Vault is the vault of another user opened by emergency access
+type Vault struct {
+	models.EmergencyVault
+	// Secret is the opened vault key of the grantor, items are opened with storage.OpenWith
+	Secret string
+}
+
+// AddEmergencyContact seals the vault key to public key of the user with the email and makes it a trusted contact
+// the contact gets the key when it requests access and the user doesn't reject it for waitDays
+// adding the contact again replaces its waiting period and drops its request
+// returns auth.ErrNoSecret if the vault key can't be read, the contact would get a key that opens nothing
+func (m *mediator) AddEmergencyContact(ctx context.Context, email string, waitDays int64) (models.EmergencyAccess, error) {
+	secret, err := auth.Secret()
+	if err != nil {
+		return models.EmergencyAccess{}, err
+	}
+	public, err := m.transport.PublicKey(ctx, auth.CurrentUser.SessionID, email)
+	if err != nil {
+		return models.EmergencyAccess{}, err
+	}
+	sealed, err := utils.SealKey(secret, public)
+	if err != nil {
+		return models.EmergencyAccess{}, err
+	}
+	return m.transport.SetEmergencyContact(ctx, auth.CurrentUser.SessionID, models.EmergencyAccess{
+		GranteeID: email,
+		WaitDays:  waitDays,
+		Key:       sealed,
+	})
+}
+
+// EmergencyContacts returns trusted contacts of the user
+func (m *mediator) EmergencyContacts(ctx context.Context) ([]models.EmergencyAccess, error) {
+	return m.transport.EmergencyContacts(ctx, auth.CurrentUser.SessionID)
+}
+
+// RemoveEmergencyContact removes the trusted contact of the user, it can't request access anymore
+func (m *mediator) RemoveEmergencyContact(ctx context.Context, email string) error {
+	return m.transport.RemoveEmergencyContact(ctx, auth.CurrentUser.SessionID, email)
+}
+
+// RejectEmergency rejects the request of the trusted contact, granted access is taken back the same way
+func (m *mediator) RejectEmergency(ctx context.Context, email string) error {
+	_, err := m.transport.RejectEmergency(ctx, auth.CurrentUser.SessionID, email)
+	return err
+}
+
+// EmergencyGrantors returns users that trust the user
+func (m *mediator) EmergencyGrantors(ctx context.Context) ([]models.EmergencyAccess, error) {
+	return m.transport.EmergencyGrantors(ctx, auth.CurrentUser.SessionID)
+}
+
+// RequestEmergency requests access to the vault of the grantor, it's granted when the waiting period is over
+func (m *mediator) RequestEmergency(ctx context.Context, grantorID string) (models.EmergencyAccess, error) {
+	return m.transport.RequestEmergency(ctx, auth.CurrentUser.SessionID, grantorID)
+}
+
+// EmergencyVault returns the vault of the grantor with its key opened by the private key of the user
+// and names of its items decrypted, items of the vault are not kept locally
+func (m *mediator) EmergencyVault(ctx context.Context, grantorID string) (Vault, error) {
+	keys, err := m.keyPair(ctx)
+	if err != nil {
+		return Vault{}, err
+	}
+	vault, err := m.transport.EmergencyVault(ctx, auth.CurrentUser.SessionID, grantorID)
+	if err != nil {
+		return Vault{}, err
+	}
+	secret, err := utils.OpenKey(vault.Access.Key, keys.private)
+	if err != nil {
+		return Vault{}, err
+	}
+	for i := range vault.Data {
+		if vault.Data[i], err = storage.OpenLabelWith(vault.Data[i], secret); err != nil {
+			return Vault{}, fmt.Errorf("failed to decrypt label of item %s: %w", vault.Data[i].ID, err)
+		}
+	}
+	return Vault{EmergencyVault: vault, Secret: secret}, nil
+}
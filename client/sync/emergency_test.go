@@ -0,0 +1,130 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gynshu-one/goph-keeper/client/auth"
+	"github.com/gynshu-one/goph-keeper/client/storage"
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/zalando/go-keyring"
+)
+
+// emergencyTransport keeps trusted contacts like server does, waiting period is over as soon as access is requested
+type emergencyTransport struct {
+	*shareTransport
+	access map[string]models.EmergencyAccess
+}
+
+func (t *emergencyTransport) SetEmergencyContact(_ context.Context, _ string, access models.EmergencyAccess) (models.EmergencyAccess, error) {
+	access.GrantorID, access.Status = auth.CurrentUser.Username, models.EmergencyIdle
+	t.access[access.GranteeID] = access
+	return access, nil
+}
+
+func (t *emergencyTransport) RejectEmergency(_ context.Context, _, granteeID string) (models.EmergencyAccess, error) {
+	access, ok := t.access[granteeID]
+	if !ok || access.Status != models.EmergencyRequested {
+		return models.EmergencyAccess{}, &Error{Kind: ErrConflict}
+	}
+	access.Status = models.EmergencyRejected
+	t.access[granteeID] = access
+	return access, nil
+}
+
+func (t *emergencyTransport) RequestEmergency(_ context.Context, _, _ string) (models.EmergencyAccess, error) {
+	access, ok := t.access[auth.CurrentUser.Username]
+	if !ok {
+		return models.EmergencyAccess{}, &Error{Kind: ErrNotFound}
+	}
+	access.Status = models.EmergencyRequested
+	t.access[access.GranteeID] = access
+	return access, nil
+}
+
+func (t *emergencyTransport) EmergencyVault(_ context.Context, _, _ string) (models.EmergencyVault, error) {
+	access := t.access[auth.CurrentUser.Username]
+	if access.Status != models.EmergencyRequested {
+		return models.EmergencyVault{}, &Error{Kind: ErrRequest}
+	}
+	vault := models.EmergencyVault{Access: access}
+	for _, item := range t.items {
+		vault.Data = append(vault.Data, item)
+	}
+	return vault, nil
+}
+
+func TestEmergencyAccess(t *testing.T) {
+	keyring.MockInit()
+	ctx := context.Background()
+	transport := &emergencyTransport{shareTransport: newShareTransport(), access: make(map[string]models.EmergencyAccess)}
+	users := make(map[string]*mediator)
+	for _, username := range []string{"bob", "alice"} {
+		signIn(username)
+		users[username] = newMediatorWith(storage.NewStorage(), transport)
+		if err := users[username].SetupKeys(ctx); err != nil {
+			t.Fatalf("SetupKeys of %s failed with error: %v", username, err)
+		}
+	}
+
+	// Alice has an item encrypted with the secret and one with its own key, Bob becomes the trusted contact
+	alice := users["alice"]
+	for _, id := range []string{"plain", "keyed"} {
+		login := &models.Login{Username: "alice", Password: id}
+		if err := alice.storage.AddEncrypt(login, models.DataWrapper{ID: id, Type: models.LoginType, Name: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := alice.storage.Rekey("keyed"); err != nil {
+		t.Fatal(err)
+	}
+	if err := alice.Sync(ctx); err != nil {
+		t.Fatalf("Sync failed with error: %v", err)
+	}
+	if _, err := alice.AddEmergencyContact(ctx, "bob", 3); err != nil {
+		t.Fatalf("AddEmergencyContact failed with error: %v", err)
+	}
+	if string(transport.access["bob"].Key) == auth.GetSecret() {
+		t.Fatal("Vault key is sent without encryption")
+	}
+	// Contact never gets a key that opens nothing
+	auth.CurrentUser.Username = "without secret"
+	if _, err := alice.AddEmergencyContact(ctx, "bob", 3); !errors.Is(err, auth.ErrNoSecret) {
+		t.Errorf("Expected %v, got %v", auth.ErrNoSecret, err)
+	}
+	signIn("alice")
+
+	// Bob can't open the vault before access is granted
+	signIn("bob")
+	bob := users["bob"]
+	if _, err := bob.EmergencyVault(ctx, "alice"); !errors.Is(err, ErrRequest) {
+		t.Errorf("Expected %v before request, got %v", ErrRequest, err)
+	}
+	if _, err := bob.RequestEmergency(ctx, "alice"); err != nil {
+		t.Fatalf("RequestEmergency failed with error: %v", err)
+	}
+	vault, err := bob.EmergencyVault(ctx, "alice")
+	if err != nil || len(vault.Data) != 2 {
+		t.Fatalf("Expected vault with 2 items, got %d and %v", len(vault.Data), err)
+	}
+	for _, item := range vault.Data {
+		data, err := storage.OpenWith(item, vault.Secret)
+		if err != nil || data.(models.Login).Password != item.ID || item.Name != item.ID {
+			t.Errorf("Item %s is not opened: %+v, %v", item.ID, data, err)
+		}
+	}
+
+	// Alice rejects the request
+	signIn("alice")
+	if err = alice.RejectEmergency(ctx, "bob"); err != nil {
+		t.Fatalf("RejectEmergency failed with error: %v", err)
+	}
+	if err = alice.RejectEmergency(ctx, "bob"); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected %v for rejected access, got %v", ErrConflict, err)
+	}
+	signIn("bob")
+	if _, err = bob.EmergencyVault(ctx, "alice"); err == nil {
+		t.Error("Vault is opened after the request was rejected")
+	}
+}
@@ -0,0 +1,89 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrUnauthorized is returned by Transport when session is not valid anymore
+	ErrUnauthorized = errors.New("session is not valid")
+	// ErrOffline is returned by Transport when server can't be reached
+	ErrOffline = errors.New("server is not reachable")
+	// ErrServer is returned by Transport when server failed to handle the request, it may succeed later
+	ErrServer = errors.New("server error")
+	// ErrQuotaExceeded is returned by Transport when data doesn't fit limits of the user
+	ErrQuotaExceeded = errors.New("storage limit is reached")
+	// ErrRequest is returned by Transport when server rejected the request, repeating it doesn't help
+	ErrRequest = errors.New("request rejected by server")
+	// ErrNotFound is returned by Transport when server doesn't have what was requested
+	ErrNotFound = errors.New("not found on server")
+	// ErrConflict is returned by Transport when what the request was made from was changed on server meanwhile,
+	// e.g. the org key was rotated
+	ErrConflict = errors.New("changed on server meanwhile")
+	// ErrChangesIncomplete is returned by sync when changes stream ended without Done checkpoint every time
+	ErrChangesIncomplete = errors.New("changes stream is incomplete")
+	// ErrBlobOffset is returned by Transport when uploaded chunk is not at the end of uploaded content,
+	// e.g. it was written already by a request that timed out
+	ErrBlobOffset = errors.New("blob is uploaded up to another offset")
+	// ErrNotSynced is returned when the item can't be shared because server doesn't have its last version
+	ErrNotSynced = errors.New("item is not synced, resolve its conflict first")
+	// ErrBlobHash is returned when downloaded file doesn't match the hash it was uploaded with
+	ErrBlobHash = errors.New("file doesn't match its hash")
+	// ErrInvalidLink is returned when the link is not a link of a send or has no key
+	ErrInvalidLink = errors.New("link is not a send link")
+)
+
+// Error is an error reported by server
+// errors.Is tells its Kind, one of ErrUnauthorized, ErrServer, ErrQuotaExceeded, ErrRequest, ErrNotFound,
+// ErrConflict and ErrOffline
+type Error struct {
+	Kind error
+	// Message is the message of server
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return e.Kind.Error()
+	}
+	return e.Kind.Error() + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// statusError returns Error of REST response with the status code
+func statusError(code int, body []byte) error {
+	kind := ErrRequest
+	switch {
+	case code == http.StatusUnauthorized:
+		kind = ErrUnauthorized
+	case code == http.StatusRequestEntityTooLarge:
+		kind = ErrQuotaExceeded
+	case code == http.StatusNotFound:
+		kind = ErrNotFound
+	case code == http.StatusConflict:
+		kind = ErrConflict
+	case code >= http.StatusInternalServerError:
+		kind = ErrServer
+	}
+	return &Error{Kind: kind, Message: strings.TrimSpace(string(body))}
+}
+
+// offline marks error of request that didn't reach server as ErrOffline
+// canceled request is not marked, it's not a connection problem
+func offline(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	return &Error{Kind: ErrOffline, Message: err.Error()}
+}
+
+// retryable tells whether repeating the request may succeed
+func retryable(err error) bool {
+	return errors.Is(err, ErrOffline) || errors.Is(err, ErrServer)
+}
@@ -1,4 +1,6 @@
 // Package sync is responsible for syncing data between client and server
 // It uses Mediator interface to send and receive data
 // Mediator also responsible for signing up and signing in, uses  package for key management
+// Requests are carried by Transport, REST (resty) or gRPC depending on config
+// Scheduler syncs in background on poll and dump timers of config
 package sync
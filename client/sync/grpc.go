@@ -0,0 +1,583 @@
+package sync
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/gynshu-one/goph-keeper/common/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// sessionMetadata is the metadata key server reads session id from
+const sessionMetadata = "session_id"
+
+// grpcTransport talks to gRPC API of the server
+type grpcTransport struct {
+	conn   *grpc.ClientConn
+	client pb.KeeperClient
+	// err is the error of creating the connection, it's returned by every call
+	err error
+}
+
+// newGRPCTransport creates transport to the address, connection is established on the first call
+// messages are compressed with zstd by default
+func newGRPCTransport(addr string, opts ...grpc.DialOption) *grpcTransport {
+	if len(opts) == 0 {
+		opts = []grpc.DialOption{
+			grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{InsecureSkipVerify: true})),
+			grpc.WithDefaultCallOptions(grpc.UseCompressor(pb.ZstdCompressor)),
+		}
+	}
+	conn, err := grpc.NewClient(addr, opts...)
+	if err != nil {
+		return &grpcTransport{err: err}
+	}
+	return &grpcTransport{conn: conn, client: pb.NewKeeperClient(conn)}
+}
+
+// SignUp calls Register
+func (t *grpcTransport) SignUp(ctx context.Context, username, password string) (string, error) {
+	if t.err != nil {
+		return "", t.err
+	}
+	session, err := t.client.Register(ctx, &pb.Credentials{Email: username, Password: password})
+	if err != nil {
+		return "", fromStatus(err)
+	}
+	return session.GetId(), nil
+}
+
+// SignIn calls Login
+func (t *grpcTransport) SignIn(ctx context.Context, username, password string) (string, error) {
+	if t.err != nil {
+		return "", t.err
+	}
+	session, err := t.client.Login(ctx, &pb.Credentials{Email: username, Password: password})
+	if err != nil {
+		return "", fromStatus(err)
+	}
+	return session.GetId(), nil
+}
+
+// Sync calls Sync, usage comes in the response
+func (t *grpcTransport) Sync(ctx context.Context, sessionID string, cursor int64, pushOnly bool, key string, sent []models.DataWrapper) (*models.SyncResponse, models.Usage, error) {
+	if t.err != nil {
+		return nil, models.Usage{}, t.err
+	}
+	response, err := t.client.Sync(withSession(ctx, sessionID), &pb.SyncRequest{
+		Cursor:         cursor,
+		Data:           pb.FromDataList(sent),
+		PushOnly:       pushOnly,
+		IdempotencyKey: key,
+	})
+	if err != nil {
+		return nil, models.Usage{}, fromStatus(err)
+	}
+	syncResponse, usage := response.Model()
+	return &syncResponse, usage, nil
+}
+
+// Changes reads the stream of Changes call
+func (t *grpcTransport) Changes(ctx context.Context, sessionID string, cursor int64, resume bool, handle func(line models.ChangeLine) error) error {
+	if t.err != nil {
+		return t.err
+	}
+	stream, err := t.client.Changes(withSession(ctx, sessionID), &pb.ChangesRequest{Cursor: cursor, Resume: resume})
+	if err != nil {
+		return fromStatus(err)
+	}
+	for {
+		line, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fromStatus(err)
+		}
+		if err = handle(line.Model()); err != nil {
+			return err
+		}
+	}
+}
+
+// History calls ListRevisions
+func (t *grpcTransport) History(ctx context.Context, sessionID, id string) ([]models.DataWrapper, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	response, err := t.client.ListRevisions(withSession(ctx, sessionID), &pb.ListRevisionsRequest{Id: id})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return pb.DataList(response.GetRevisions()), nil
+}
+
+// Revision calls GetRevision
+func (t *grpcTransport) Revision(ctx context.Context, sessionID, id string, revision int64) (models.DataWrapper, error) {
+	if t.err != nil {
+		return models.DataWrapper{}, t.err
+	}
+	data, err := t.client.GetRevision(withSession(ctx, sessionID), &pb.GetRevisionRequest{Id: id, Revision: revision})
+	if err != nil {
+		return models.DataWrapper{}, fromStatus(err)
+	}
+	return data.Model(), nil
+}
+
+// Events reads the stream of Events call
+func (t *grpcTransport) Events(ctx context.Context, sessionID string, handle func(event models.ChangeEvent)) error {
+	if t.err != nil {
+		return t.err
+	}
+	stream, err := t.client.Events(withSession(ctx, sessionID), &pb.EventsRequest{})
+	if err != nil {
+		return fromStatus(err)
+	}
+	for {
+		event, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return ctx.Err()
+		}
+		if err != nil {
+			if status.Code(err) == codes.Canceled {
+				return ctx.Err()
+			}
+			return fromStatus(err)
+		}
+		handle(models.ChangeEvent{Cursor: event.GetCursor()})
+	}
+}
+
+// CreateBlob calls CreateBlob
+func (t *grpcTransport) CreateBlob(ctx context.Context, sessionID string, blob models.Blob) (models.Blob, error) {
+	if t.err != nil {
+		return models.Blob{}, t.err
+	}
+	created, err := t.client.CreateBlob(withSession(ctx, sessionID), pb.FromBlob(blob))
+	if err != nil {
+		return models.Blob{}, fromStatus(err)
+	}
+	return created.Model(), nil
+}
+
+// UploadBlob calls UploadBlob, FailedPrecondition means the offset is wrong
+func (t *grpcTransport) UploadBlob(ctx context.Context, sessionID, id string, offset int64, chunk []byte) (models.Blob, error) {
+	if t.err != nil {
+		return models.Blob{}, t.err
+	}
+	blob, err := t.client.UploadBlob(withSession(ctx, sessionID), &pb.BlobChunk{Id: id, Offset: offset, Data: chunk})
+	if status.Code(err) == codes.FailedPrecondition {
+		return models.Blob{}, &Error{Kind: ErrBlobOffset, Message: status.Convert(err).Message()}
+	}
+	if err != nil {
+		return models.Blob{}, fromStatus(err)
+	}
+	return blob.Model(), nil
+}
+
+// DownloadBlob calls DownloadBlob
+func (t *grpcTransport) DownloadBlob(ctx context.Context, sessionID, id string, offset int64) ([]byte, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	chunk, err := t.client.DownloadBlob(withSession(ctx, sessionID), &pb.BlobChunk{Id: id, Offset: offset})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return chunk.GetData(), nil
+}
+
+// Keys calls GetKeys
+func (t *grpcTransport) Keys(ctx context.Context, sessionID string) (models.UserKeys, error) {
+	if t.err != nil {
+		return models.UserKeys{}, t.err
+	}
+	keys, err := t.client.GetKeys(withSession(ctx, sessionID), &pb.GetKeysRequest{})
+	if err != nil {
+		return models.UserKeys{}, fromStatus(err)
+	}
+	return keys.Model(), nil
+}
+
+// SetKeys calls SetKeys
+func (t *grpcTransport) SetKeys(ctx context.Context, sessionID string, keys models.UserKeys) error {
+	if t.err != nil {
+		return t.err
+	}
+	if _, err := t.client.SetKeys(withSession(ctx, sessionID), pb.FromUserKeys(keys)); err != nil {
+		return fromStatus(err)
+	}
+	return nil
+}
+
+// PublicKey calls GetPublicKey
+func (t *grpcTransport) PublicKey(ctx context.Context, sessionID, email string) ([]byte, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	keys, err := t.client.GetPublicKey(withSession(ctx, sessionID), &pb.PublicKeyRequest{Email: email})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return keys.GetPublicKey(), nil
+}
+
+// Share calls ShareItem
+func (t *grpcTransport) Share(ctx context.Context, sessionID string, share models.Share) (models.Share, error) {
+	if t.err != nil {
+		return models.Share{}, t.err
+	}
+	stored, err := t.client.ShareItem(withSession(ctx, sessionID), pb.FromShare(share))
+	if err != nil {
+		return models.Share{}, fromStatus(err)
+	}
+	return stored.Model(), nil
+}
+
+// Shares calls ListShares
+func (t *grpcTransport) Shares(ctx context.Context, sessionID, itemID string) ([]models.Share, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	response, err := t.client.ListShares(withSession(ctx, sessionID), &pb.ListSharesRequest{ItemId: itemID})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return pb.ShareList(response.GetShares()), nil
+}
+
+// Unshare calls RevokeShare
+func (t *grpcTransport) Unshare(ctx context.Context, sessionID, itemID, userID string) error {
+	if t.err != nil {
+		return t.err
+	}
+	_, err := t.client.RevokeShare(withSession(ctx, sessionID), &pb.RevokeShareRequest{ItemId: itemID, UserId: userID})
+	if err != nil {
+		return fromStatus(err)
+	}
+	return nil
+}
+
+// Shared calls Shared
+func (t *grpcTransport) Shared(ctx context.Context, sessionID string) ([]models.SharedItem, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	response, err := t.client.Shared(withSession(ctx, sessionID), &pb.SharedRequest{})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return pb.SharedItems(response.GetItems()), nil
+}
+
+// UpdateShared calls UpdateShared
+func (t *grpcTransport) UpdateShared(ctx context.Context, sessionID string, data models.DataWrapper) (models.SyncResult, error) {
+	if t.err != nil {
+		return models.SyncResult{}, t.err
+	}
+	result, err := t.client.UpdateShared(withSession(ctx, sessionID), pb.FromData(data))
+	if err != nil {
+		return models.SyncResult{}, fromStatus(err)
+	}
+	return result.Model(), nil
+}
+
+// CreateOrg calls CreateOrg
+func (t *grpcTransport) CreateOrg(ctx context.Context, sessionID string, membership models.Membership) (models.Membership, error) {
+	if t.err != nil {
+		return models.Membership{}, t.err
+	}
+	created, err := t.client.CreateOrg(withSession(ctx, sessionID), pb.FromMembership(membership))
+	if err != nil {
+		return models.Membership{}, fromStatus(err)
+	}
+	return created.Model(), nil
+}
+
+// Orgs calls ListOrgs
+func (t *grpcTransport) Orgs(ctx context.Context, sessionID string) ([]models.Membership, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	response, err := t.client.ListOrgs(withSession(ctx, sessionID), &pb.ListOrgsRequest{})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return pb.Memberships(response.GetMemberships()), nil
+}
+
+// Members calls ListMembers
+func (t *grpcTransport) Members(ctx context.Context, sessionID, orgID string) ([]models.Member, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	response, err := t.client.ListMembers(withSession(ctx, sessionID), &pb.OrgRequest{OrgId: orgID})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return pb.MemberList(response.GetMembers()), nil
+}
+
+// SetMember calls SetMember
+func (t *grpcTransport) SetMember(ctx context.Context, sessionID string, member models.Member) (models.Member, error) {
+	if t.err != nil {
+		return models.Member{}, t.err
+	}
+	stored, err := t.client.SetMember(withSession(ctx, sessionID), pb.FromMember(member))
+	if err != nil {
+		return models.Member{}, fromStatus(err)
+	}
+	return stored.Model(), nil
+}
+
+// RemoveMember calls RemoveMember
+func (t *grpcTransport) RemoveMember(ctx context.Context, sessionID, orgID, userID string) error {
+	if t.err != nil {
+		return t.err
+	}
+	_, err := t.client.RemoveMember(withSession(ctx, sessionID), &pb.RemoveMemberRequest{OrgId: orgID, UserId: userID})
+	if err != nil {
+		return fromStatus(err)
+	}
+	return nil
+}
+
+// AcceptInvite calls AcceptInvite
+func (t *grpcTransport) AcceptInvite(ctx context.Context, sessionID, orgID string) error {
+	if t.err != nil {
+		return t.err
+	}
+	if _, err := t.client.AcceptInvite(withSession(ctx, sessionID), &pb.OrgRequest{OrgId: orgID}); err != nil {
+		return fromStatus(err)
+	}
+	return nil
+}
+
+// Collections calls ListCollections
+func (t *grpcTransport) Collections(ctx context.Context, sessionID, orgID string) ([]models.Collection, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	response, err := t.client.ListCollections(withSession(ctx, sessionID), &pb.OrgRequest{OrgId: orgID})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return pb.CollectionList(response.GetCollections()), nil
+}
+
+// SetCollection calls SetCollection
+func (t *grpcTransport) SetCollection(ctx context.Context, sessionID string, collection models.Collection) (models.Collection, error) {
+	if t.err != nil {
+		return models.Collection{}, t.err
+	}
+	stored, err := t.client.SetCollection(withSession(ctx, sessionID), pb.FromCollection(collection))
+	if err != nil {
+		return models.Collection{}, fromStatus(err)
+	}
+	return stored.Model(), nil
+}
+
+// OrgData calls GetOrgData
+func (t *grpcTransport) OrgData(ctx context.Context, sessionID, orgID string) (models.OrgData, error) {
+	if t.err != nil {
+		return models.OrgData{}, t.err
+	}
+	data, err := t.client.GetOrgData(withSession(ctx, sessionID), &pb.OrgRequest{OrgId: orgID})
+	if err != nil {
+		return models.OrgData{}, fromStatus(err)
+	}
+	return data.Model(), nil
+}
+
+// WriteOrgData calls WriteOrgData
+func (t *grpcTransport) WriteOrgData(ctx context.Context, sessionID string, data models.OrgData) ([]models.SyncResult, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	response, err := t.client.WriteOrgData(withSession(ctx, sessionID), pb.FromOrgData(data))
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return pb.SyncResults(response.GetResults()), nil
+}
+
+// RotateOrgKey calls RotateOrgKey
+func (t *grpcTransport) RotateOrgKey(ctx context.Context, sessionID string, rotation models.KeyRotation) ([]models.SyncResult, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	response, err := t.client.RotateOrgKey(withSession(ctx, sessionID), pb.FromKeyRotation(rotation))
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return pb.SyncResults(response.GetResults()), nil
+}
+
+// EmergencyContacts calls ListEmergencyContacts
+func (t *grpcTransport) EmergencyContacts(ctx context.Context, sessionID string) ([]models.EmergencyAccess, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	response, err := t.client.ListEmergencyContacts(withSession(ctx, sessionID), &pb.ListEmergencyRequest{})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return pb.EmergencyList(response.GetAccess()), nil
+}
+
+// SetEmergencyContact calls SetEmergencyContact
+func (t *grpcTransport) SetEmergencyContact(ctx context.Context, sessionID string, access models.EmergencyAccess) (models.EmergencyAccess, error) {
+	if t.err != nil {
+		return models.EmergencyAccess{}, t.err
+	}
+	stored, err := t.client.SetEmergencyContact(withSession(ctx, sessionID), pb.FromEmergencyAccess(access))
+	if err != nil {
+		return models.EmergencyAccess{}, fromStatus(err)
+	}
+	return stored.Model(), nil
+}
+
+// RemoveEmergencyContact calls RemoveEmergencyContact
+func (t *grpcTransport) RemoveEmergencyContact(ctx context.Context, sessionID, granteeID string) error {
+	if t.err != nil {
+		return t.err
+	}
+	_, err := t.client.RemoveEmergencyContact(withSession(ctx, sessionID), &pb.EmergencyRequest{UserId: granteeID})
+	if err != nil {
+		return fromStatus(err)
+	}
+	return nil
+}
+
+// RejectEmergency calls RejectEmergencyAccess
+func (t *grpcTransport) RejectEmergency(ctx context.Context, sessionID, granteeID string) (models.EmergencyAccess, error) {
+	if t.err != nil {
+		return models.EmergencyAccess{}, t.err
+	}
+	access, err := t.client.RejectEmergencyAccess(withSession(ctx, sessionID), &pb.EmergencyRequest{UserId: granteeID})
+	if err != nil {
+		return models.EmergencyAccess{}, fromStatus(err)
+	}
+	return access.Model(), nil
+}
+
+// EmergencyGrantors calls ListEmergencyGrantors
+func (t *grpcTransport) EmergencyGrantors(ctx context.Context, sessionID string) ([]models.EmergencyAccess, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	response, err := t.client.ListEmergencyGrantors(withSession(ctx, sessionID), &pb.ListEmergencyRequest{})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return pb.EmergencyList(response.GetAccess()), nil
+}
+
+// RequestEmergency calls RequestEmergencyAccess
+func (t *grpcTransport) RequestEmergency(ctx context.Context, sessionID, grantorID string) (models.EmergencyAccess, error) {
+	if t.err != nil {
+		return models.EmergencyAccess{}, t.err
+	}
+	access, err := t.client.RequestEmergencyAccess(withSession(ctx, sessionID), &pb.EmergencyRequest{UserId: grantorID})
+	if err != nil {
+		return models.EmergencyAccess{}, fromStatus(err)
+	}
+	return access.Model(), nil
+}
+
+// EmergencyVault calls GetEmergencyVault
+func (t *grpcTransport) EmergencyVault(ctx context.Context, sessionID, grantorID string) (models.EmergencyVault, error) {
+	if t.err != nil {
+		return models.EmergencyVault{}, t.err
+	}
+	vault, err := t.client.GetEmergencyVault(withSession(ctx, sessionID), &pb.EmergencyRequest{UserId: grantorID})
+	if err != nil {
+		return models.EmergencyVault{}, fromStatus(err)
+	}
+	return vault.Model(), nil
+}
+
+// Sends calls ListSends
+func (t *grpcTransport) Sends(ctx context.Context, sessionID string) ([]models.Send, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	response, err := t.client.ListSends(withSession(ctx, sessionID), &pb.ListSendsRequest{})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return pb.SendList(response.GetSends()), nil
+}
+
+// CreateSend calls CreateSend
+func (t *grpcTransport) CreateSend(ctx context.Context, sessionID string, send models.Send) (models.Send, error) {
+	if t.err != nil {
+		return models.Send{}, t.err
+	}
+	created, err := t.client.CreateSend(withSession(ctx, sessionID), pb.FromSend(send))
+	if err != nil {
+		return models.Send{}, fromStatus(err)
+	}
+	return created.Model(), nil
+}
+
+// DeleteSend calls DeleteSend
+func (t *grpcTransport) DeleteSend(ctx context.Context, sessionID, id string) error {
+	if t.err != nil {
+		return t.err
+	}
+	if _, err := t.client.DeleteSend(withSession(ctx, sessionID), &pb.SendRequest{Id: id}); err != nil {
+		return fromStatus(err)
+	}
+	return nil
+}
+
+// ReceiveSend calls ReceiveSend without a session
+func (t *grpcTransport) ReceiveSend(ctx context.Context, id string) (models.Send, error) {
+	if t.err != nil {
+		return models.Send{}, t.err
+	}
+	send, err := t.client.ReceiveSend(ctx, &pb.SendRequest{Id: id})
+	if err != nil {
+		return models.Send{}, fromStatus(err)
+	}
+	return send.Model(), nil
+}
+
+// withSession returns context of the call with session id in metadata
+func withSession(ctx context.Context, sessionID string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, sessionMetadata, sessionID)
+}
+
+// fromStatus converts gRPC status to Error of the same kind as REST transport returns
+func fromStatus(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	kind := ErrRequest
+	switch st.Code() {
+	case codes.Canceled:
+		return err
+	case codes.Unauthenticated:
+		kind = ErrUnauthorized
+	case codes.ResourceExhausted:
+		kind = ErrQuotaExceeded
+	case codes.NotFound:
+		kind = ErrNotFound
+	case codes.FailedPrecondition, codes.Aborted:
+		kind = ErrConflict
+	case codes.Unavailable, codes.DeadlineExceeded:
+		kind = ErrOffline
+	case codes.Internal, codes.Unknown, codes.DataLoss:
+		kind = ErrServer
+	}
+	return &Error{Kind: kind, Message: st.Message()}
+}
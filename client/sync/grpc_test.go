@@ -0,0 +1,143 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/gynshu-one/goph-keeper/client/auth"
+	"github.com/gynshu-one/goph-keeper/client/config"
+	"github.com/gynshu-one/goph-keeper/client/storage"
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/gynshu-one/goph-keeper/common/pb"
+	"github.com/zalando/go-keyring"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// mockKeeper is a Keeper server that accepts only "test" session
+type mockKeeper struct {
+	pb.UnimplementedKeeperServer
+}
+
+func (mockKeeper) Login(_ context.Context, in *pb.Credentials) (*pb.Session, error) {
+	if in.GetPassword() != "password" {
+		return nil, status.Error(codes.Unauthenticated, "invalid master key")
+	}
+	return &pb.Session{Id: "test"}, nil
+}
+
+func (mockKeeper) Sync(ctx context.Context, in *pb.SyncRequest) (*pb.SyncResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(sessionMetadata); len(values) == 0 || values[0] != "test" {
+		return nil, status.Error(codes.Unauthenticated, "session not found")
+	}
+	if !in.GetPushOnly() {
+		return nil, status.Error(codes.InvalidArgument, "changes are expected to be streamed")
+	}
+	return pb.FromSyncResponse(models.SyncResponse{
+		Results: []models.SyncResult{{ID: "1", Status: models.StatusConflict, Reason: "test"}},
+		Data:    []models.DataWrapper{{ID: "1", Revision: 2}},
+		Cursor:  in.GetCursor(),
+	}, models.Usage{Items: 3, MaxItems: 10}), nil
+}
+
+// Changes moves cursor by 5 on every sync, download of all data is interrupted once
+func (mockKeeper) Changes(in *pb.ChangesRequest, stream pb.Keeper_ChangesServer) error {
+	line := func(line models.ChangeLine) error {
+		return stream.Send(pb.FromChangeLine(line))
+	}
+	if in.GetCursor() == 0 {
+		_ = line(models.ChangeLine{Checkpoint: &models.Checkpoint{Reset: true}})
+		_ = line(models.ChangeLine{Data: &models.DataWrapper{ID: "1", Revision: 2}})
+		_ = line(models.ChangeLine{Checkpoint: &models.Checkpoint{Cursor: 3}})
+		return status.Error(codes.Unavailable, "interrupted")
+	}
+	cursor := in.GetCursor() + 5
+	if in.GetResume() {
+		cursor = 5
+	}
+	_ = line(models.ChangeLine{Checkpoint: &models.Checkpoint{Cursor: in.GetCursor()}})
+	return line(models.ChangeLine{Checkpoint: &models.Checkpoint{Cursor: cursor, Done: true}})
+}
+
+func (mockKeeper) Events(_ *pb.EventsRequest, stream pb.Keeper_EventsServer) error {
+	return stream.Send(&pb.ChangeEvent{Cursor: 3})
+}
+
+// newMockGRPCTransport returns transport connected to mockKeeper over in-memory connection
+func newMockGRPCTransport(t *testing.T) *grpcTransport {
+	t.Helper()
+	server := grpc.NewServer()
+	pb.RegisterKeeperServer(server, mockKeeper{})
+	listener := bufconn.Listen(1 << 20)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	return newGRPCTransport("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.UseCompressor(pb.ZstdCompressor)))
+}
+
+func TestGRPCTransport(t *testing.T) {
+	keyring.MockInit()
+	newMediator := newMediatorWith(storage.NewStorage(), newMockGRPCTransport(t))
+
+	if err := newMediator.SignIn(context.Background(), "testuser", "wrong"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+
+	// Expired session is renewed and sync is repeated with it
+	if err := keyring.Set(config.ServiceName, "testuser", "password"); err != nil {
+		t.Fatal(err)
+	}
+	auth.CurrentUser.Username, auth.CurrentUser.SessionID = "testuser", "expired"
+	// Server data has plaintext labels, they are sealed with the secret
+	auth.SetSecret("test_secret")
+	if err := newMediator.Sync(context.Background()); err != nil {
+		t.Errorf("Sync failed with error: %v", err)
+	}
+	if auth.CurrentUser.SessionID != "test" {
+		t.Errorf("Session %s wasn't stored", auth.CurrentUser.SessionID)
+	}
+
+	// Sync works the same way as with REST, interrupted download is resumed
+	if err := newMediator.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync failed with error: %v", err)
+	}
+	if usage := newMediator.Usage(); usage.Items != 3 || usage.MaxItems != 10 {
+		t.Errorf("Unexpected usage %+v", usage)
+	}
+	result, ok := newMediator.Result("1")
+	if !ok || result.Status != models.StatusConflict || result.Reason != "test" {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	if data := newMediator.storage.Get(); len(data) != 1 || data[0].Revision != 2 {
+		t.Errorf("Server data wasn't stored: %+v", data)
+	}
+	if cursor := newMediator.storage.Cursor(); cursor != 10 {
+		t.Errorf("Cursor is %d, want 10", cursor)
+	}
+
+	// Stream ends when server returns
+	var events []models.ChangeEvent
+	err := newMediator.Events(context.Background(), func(event models.ChangeEvent) {
+		events = append(events, event)
+	})
+	if err != nil {
+		t.Errorf("Events failed with error: %v", err)
+	}
+	if len(events) != 1 || events[0].Cursor != 3 {
+		t.Errorf("Unexpected events %+v", events)
+	}
+}
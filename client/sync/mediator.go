@@ -2,12 +2,11 @@ package sync
 
 import (
 	"context"
-	"crypto/tls"
-	"encoding/json"
 	"fmt"
-	"net/http"
+	"sync"
+	"time"
 
-	"github.com/go-resty/resty/v2"
+	"github.com/google/uuid"
 	"github.com/gynshu-one/goph-keeper/client/auth"
 	"github.com/gynshu-one/goph-keeper/client/config"
 	"github.com/gynshu-one/goph-keeper/client/storage"
@@ -15,151 +14,271 @@ import (
 	"github.com/zalando/go-keyring"
 )
 
-const (
-	RegisterEndpoint = "/user/create"
-	LoginEndpoint    = "/user/login"
-	Endpoint         = "/user/sync"
-)
-
 // Mediator is a mediator between client and server
 // It is responsible for sending data to server and receiving data from server
 // as well as signing up and signing in
-// uses resty as http client
+// uses Transport chosen in config to talk to server
 type Mediator interface {
 	Sync(ctx context.Context) error
 	SignUp(ctx context.Context, username, password string) error
 	SignIn(ctx context.Context, username, password string) error
+	// History returns previous versions of the item with the given id, newest first, without data
+	History(ctx context.Context, id string) ([]models.DataWrapper, error)
+	// Revision returns previous version of the item with its encrypted data
+	Revision(ctx context.Context, id string, revision int64) (models.DataWrapper, error)
+	// Usage returns storage usage and limits reported by server on the last sync
+	Usage() models.Usage
+	// Result returns status of the item reported by server on the last sync
+	Result(id string) (models.SyncResult, bool)
+	// Events subscribes to changes of user's data made on other devices
+	// and calls handle for every change until the stream is closed or ctx is done
+	Events(ctx context.Context, handle func(event models.ChangeEvent)) error
+	// AttachFile encrypts the file into upload queue and returns the reference to keep in the item
+	// the file is uploaded by Sync before the item that refers to it
+	AttachFile(path string) (models.BlobRef, error)
+	// SaveFile downloads the file of the reference, decrypts it and writes it to path
+	// interrupted download continues from where it stopped
+	SaveFile(ctx context.Context, ref models.BlobRef, path string) error
+	// SetupKeys makes sure the user has a key pair on server, so other users can share items with it
+	SetupKeys(ctx context.Context) error
+	// ShareItem shares the item with the user with the email
+	ShareItem(ctx context.Context, id, email string, permission models.Permission) error
+	// Shares returns the users the item is shared with
+	Shares(ctx context.Context, id string) ([]models.Share, error)
+	// Revoke revokes the share of the item with the user and rotates the key of the item
+	Revoke(ctx context.Context, id, userID string) error
+	// Shared returns items other users shared with the user with their keys
+	Shared(ctx context.Context) ([]SharedItem, error)
+	// UpdateShared encrypts the data with the key of the shared item and sends it to server
+	UpdateShared(ctx context.Context, item SharedItem, data models.BasicData) (models.SyncResult, error)
+	// CreateOrg creates the organization owned by the user with a new org key
+	CreateOrg(ctx context.Context, name string) (Org, error)
+	// Orgs returns organizations of the user including invitations with their keys
+	Orgs(ctx context.Context) ([]Org, error)
+	// Members returns members of the organization
+	Members(ctx context.Context, orgID string) ([]models.Member, error)
+	// Invite invites the user with the email to the organization or changes its role
+	Invite(ctx context.Context, org Org, email string, role models.Role) error
+	// Accept accepts the invitation to the organization
+	Accept(ctx context.Context, orgID string) error
+	// RemoveMember removes the user from the organization and rotates the org key
+	RemoveMember(ctx context.Context, orgID, userID string) error
+	// RotateOrgKey replaces the org key and re-encrypts every item of the organization with it
+	RotateOrgKey(ctx context.Context, orgID string) error
+	// Collections returns collections of the organization
+	Collections(ctx context.Context, orgID string) ([]models.Collection, error)
+	// AddCollection adds the collection with the name to the organization
+	AddCollection(ctx context.Context, orgID, name string) (models.Collection, error)
+	// OrgItems returns items of the organization encrypted with the org key
+	OrgItems(ctx context.Context, org Org) ([]models.DataWrapper, error)
+	// SaveOrgItem encrypts the data with the org key and sends it to server
+	SaveOrgItem(ctx context.Context, org Org, data models.BasicData, wrapper models.DataWrapper) (models.SyncResult, error)
+	// DeleteOrgItem marks the item of the organization as deleted on server
+	DeleteOrgItem(ctx context.Context, org Org, wrapper models.DataWrapper) (models.SyncResult, error)
+	// AddEmergencyContact seals the vault key to the user with the email and makes it a trusted contact
+	AddEmergencyContact(ctx context.Context, email string, waitDays int64) (models.EmergencyAccess, error)
+	// EmergencyContacts returns trusted contacts of the user
+	EmergencyContacts(ctx context.Context) ([]models.EmergencyAccess, error)
+	// RemoveEmergencyContact removes the trusted contact of the user
+	RemoveEmergencyContact(ctx context.Context, email string) error
+	// RejectEmergency rejects the request of the trusted contact
+	RejectEmergency(ctx context.Context, email string) error
+	// EmergencyGrantors returns users that trust the user
+	EmergencyGrantors(ctx context.Context) ([]models.EmergencyAccess, error)
+	// RequestEmergency requests access to the vault of the grantor
+	RequestEmergency(ctx context.Context, grantorID string) (models.EmergencyAccess, error)
+	// EmergencyVault returns the vault of the grantor with its key opened
+	EmergencyVault(ctx context.Context, grantorID string) (Vault, error)
+	// CreateSend encrypts the secret with a random key and returns the link to it with the key
+	CreateSend(ctx context.Context, secret string, lifetime time.Duration, maxViews int64) (string, error)
+	// Sends returns sends of the user that can still be viewed
+	Sends(ctx context.Context) ([]models.Send, error)
+	// DeleteSend removes the send of the user
+	DeleteSend(ctx context.Context, id string) error
+	// ReceiveSend opens the link of a send and returns its secret, it uses up a view of the send
+	ReceiveSend(ctx context.Context, link string) (string, error)
 }
 
 type mediator struct {
-	client  *resty.Client
-	storage storage.Storage
+	transport Transport
+	storage   storage.Storage
+	// syncing serializes syncs made by UI and Scheduler
+	syncing sync.Mutex
+	// mu guards usage and results
+	mu    sync.RWMutex
+	usage models.Usage
+	// results are statuses of items sent on the last sync, key is item id
+	results map[string]models.SyncResult
+	// keys is the decrypted key pair of the user, it's read from server once
+	keys *keyPair
 }
 
 // NewMediator creates new mediator
 func NewMediator(storage storage.Storage) *mediator {
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-	md := &mediator{
-		client:  resty.NewWithClient(&http.Client{Transport: tr}),
-		storage: storage,
-	}
+	return newMediatorWith(storage, newTransport())
+}
+
+// newMediatorWith creates mediator that talks to server with the transport
+// requests are retried and expired session is renewed with the password from keyring
+func newMediatorWith(storage storage.Storage, transport Transport) *mediator {
+	md := &mediator{storage: storage}
+	md.transport = newRetryTransport(transport, md.renewSession)
 	return md
 }
 
 // SignUp sends request to server to create new user
-// if request is successful, it will create session_id file
-// and store session_id and username in it
+// if request is successful, it will store session_id and username of current user
 // if request is not successful, it will return error
 func (m *mediator) SignUp(ctx context.Context, username, password string) error {
 	if username == "" || password == "" {
 		return fmt.Errorf("username or password is empty")
 	}
-
-	// Make request to server
-	get, err := m.client.NewRequest().SetContext(ctx).
-		SetQueryParam("email", username).
-		SetQueryParam("password", password).Get("https://" + config.GetConfig().ServerIP + RegisterEndpoint)
+	sessionID, err := m.transport.SignUp(ctx, username, password)
 	if err != nil {
 		return err
 	}
-	if get.StatusCode() != 200 {
-		return fmt.Errorf("failed to register, status code: %d, and error %s", get.StatusCode(), get.Body())
-	}
-	return setCookies(get.Cookies(), username)
+	setSession(sessionID, username)
+	return nil
 }
 
 // SignIn sends request to server to login user
-// if request is successful, it will create session_id file
-// and store session_id and username in it
+// if request is successful, it will store session_id and username of current user
 func (m *mediator) SignIn(ctx context.Context, username, password string) error {
 	if username == "" || password == "" {
 		return fmt.Errorf("username or password is empty")
 	}
-
-	// Make request to server
-	get, err := m.client.NewRequest().SetContext(ctx).
-		SetQueryParam("email", username).
-		SetQueryParam("password", password).Get("https://" + config.GetConfig().ServerIP + LoginEndpoint)
+	sessionID, err := m.transport.SignIn(ctx, username, password)
 	if err != nil {
 		return err
 	}
-	if get.StatusCode() != 200 {
-		return fmt.Errorf("failed to login, status code: %d and response %s", get.StatusCode(), get.Body())
-	}
-	return setCookies(get.Cookies(), username)
+	setSession(sessionID, username)
+	return nil
 }
 
-// Sync sends request to server to get data then swap it with local data
+// changesAttempts is how many times interrupted changes stream is resumed during one sync
+const changesAttempts = 3
+
+// Sync uploads files of items changed locally, sends the items to server and applies its results
+// then downloads changes of other devices since the cursor of the last sync from changes stream
+// interrupted stream is resumed from its last checkpoint
 func (m *mediator) Sync(ctx context.Context) error {
-	// Make request to server to get data don't forget to set cookie
-	response, err := m.client.NewRequest().SetContext(ctx).
-		SetBody(m.storage.Get()).SetCookie(&http.Cookie{
-		Name:  "session_id",
-		Value: auth.CurrentUser.SessionID,
-	}).Post("https://" + config.GetConfig().ServerIP + Endpoint)
-	if err != nil {
+	m.syncing.Lock()
+	defer m.syncing.Unlock()
+
+	// Names and types stored in plaintext by earlier versions are encrypted and sent with the rest of changes
+	if err := m.storage.SealLabels(); err != nil {
+		return err
+	}
+	sent := m.storage.Dirty()
+	if sent == nil {
+		sent = []models.DataWrapper{}
+	}
+	// Server doesn't accept items that refer to files it doesn't have
+	if err := m.uploadBlobs(ctx, sent); err != nil {
 		return err
 	}
 
-	// Check if user is unauthorized
-	if response.StatusCode() == http.StatusUnauthorized {
-		// If so, try to get pass from keyring
-		pass, err_ := keyring.Get(config.ServiceName, auth.CurrentUser.Username)
-		if err_ != nil {
-			return err_
-		}
-
-		// And sign in again
-		err_ = m.SignIn(ctx, auth.CurrentUser.Username, pass)
-		if err_ != nil {
-			return err_
+	// Retried request has the same key, so server doesn't apply it twice
+	response, usage, err := m.transport.Sync(ctx, auth.CurrentUser.SessionID, m.storage.Cursor(), true,
+		uuid.NewString(), sent)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.usage = usage
+	if response != nil {
+		// Remember what server did with every item
+		m.results = make(map[string]models.SyncResult, len(response.Results))
+		for _, result := range response.Results {
+			m.results[result.ID] = result
 		}
 	}
+	m.mu.Unlock()
 
-	// Check if response is empty
-	if response.StatusCode() == http.StatusNoContent {
-		return nil
+	if response != nil {
+		if err = m.storage.Apply(sent, *response); err != nil {
+			return err
+		}
 	}
+	return m.pull(ctx)
+}
 
-	// If not, unmarshal and swap data
-	var serverData []models.DataWrapper
-	body := response.Body()
-	if len(body) == 0 {
-		return nil
-	}
-	if err = json.Unmarshal(body, &serverData); err != nil {
-		if err.Error() == "EOF" {
-			serverData = nil
+// pull applies changes stream to the storage until its Done checkpoint
+func (m *mediator) pull(ctx context.Context) (err error) {
+	for attempt := 0; attempt < changesAttempts; attempt++ {
+		done := false
+		err = m.transport.Changes(ctx, auth.CurrentUser.SessionID, m.storage.Cursor(), m.storage.Resetting(),
+			func(line models.ChangeLine) error {
+				if line.Data != nil {
+					return m.storage.ApplyChange(*line.Data)
+				}
+				if line.Checkpoint == nil {
+					return nil
+				}
+				done = line.Checkpoint.Done
+				return m.storage.Checkpoint(*line.Checkpoint)
+			})
+		if done {
 			return nil
-		} else {
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil && !retryable(err) {
 			return err
 		}
 	}
-	err = m.storage.Swap(serverData)
-	if err != nil {
-		return err
+	if err == nil {
+		err = ErrChangesIncomplete
 	}
-	return nil
+	return err
 }
 
-func setCookies(cookie []*http.Cookie, username string) error {
-	// Read cookie from response
-	if len(cookie) == 0 {
-		return fmt.Errorf("failed to get cookie")
+// renewSession signs in again with the password from keyring and returns id of the new session
+func (m *mediator) renewSession(ctx context.Context) (string, error) {
+	pass, err := keyring.Get(config.ServiceName, auth.CurrentUser.Username)
+	if err != nil {
+		return "", err
 	}
-
-	// Loop through cookies and the one with session_id
-	for _, c := range cookie {
-		if c.Name == "session_id" {
-			if c.Value == "" {
-				return fmt.Errorf("failed to get cookie")
-			}
-			auth.CurrentUser.Username = username
-			auth.CurrentUser.SessionID = c.Value
-			return nil
-		}
+	if err = m.SignIn(ctx, auth.CurrentUser.Username, pass); err != nil {
+		return "", err
 	}
-	return fmt.Errorf("failed to get cookie")
+	return auth.CurrentUser.SessionID, nil
+}
+
+// Result returns status of the item reported by server on the last sync
+func (m *mediator) Result(id string) (models.SyncResult, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	result, ok := m.results[id]
+	return result, ok
+}
+
+// Usage returns storage usage and limits reported by server on the last sync
+func (m *mediator) Usage() models.Usage {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.usage
+}
+
+// History sends request to server to get previous versions of the item
+func (m *mediator) History(ctx context.Context, id string) ([]models.DataWrapper, error) {
+	return m.transport.History(ctx, auth.CurrentUser.SessionID, id)
+}
+
+// Revision sends request to server to get previous version of the item
+func (m *mediator) Revision(ctx context.Context, id string, revision int64) (models.DataWrapper, error) {
+	return m.transport.Revision(ctx, auth.CurrentUser.SessionID, id, revision)
+}
+
+// Events subscribes to changes of user's data made on other devices
+// and calls handle for every change until the stream is closed or ctx is done
+func (m *mediator) Events(ctx context.Context, handle func(event models.ChangeEvent)) error {
+	return m.transport.Events(ctx, auth.CurrentUser.SessionID, handle)
+}
+
+// setSession stores session of the current user
+func setSession(sessionID, username string) {
+	auth.CurrentUser.Username = username
+	auth.CurrentUser.SessionID = sessionID
 }
@@ -6,11 +6,13 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/gynshu-one/goph-keeper/client/auth"
 	"github.com/gynshu-one/goph-keeper/client/storage"
+	"github.com/gynshu-one/goph-keeper/common/models"
 	"github.com/rs/zerolog/log"
 	"github.com/zalando/go-keyring"
 )
@@ -23,9 +25,13 @@ func TestNewMediator(t *testing.T) {
 	// Create a new newMediator instance
 	newMediator := NewMediator(newStorage)
 
-	// Check if the newMediator client is not nil
-	if newMediator.client == nil {
-		t.Errorf("Mediator client is nil")
+	// Check if the newMediator transport is REST by default and its requests are retried
+	retry, ok := newMediator.transport.(*retryTransport)
+	if !ok {
+		t.Fatalf("Mediator transport is %T", newMediator.transport)
+	}
+	if _, ok = retry.Transport.(*restTransport); !ok {
+		t.Errorf("Mediator transport is %T", retry.Transport)
 	}
 
 	// Check if the newMediator storage is not nil
@@ -65,7 +71,41 @@ func MockChiHTTPServer() *httptest.Server {
 			w.WriteHeader(http.StatusOK)
 		})
 		r.With().Post("/sync", func(writer http.ResponseWriter, request *http.Request) {
+			if request.URL.Query().Get(models.PushOnlyParam) != "true" {
+				writer.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			writer.Header().Set(models.UsageItemsHeader, "3")
+			writer.Header().Set(models.UsageMaxItemsHeader, "10")
 			writer.WriteHeader(http.StatusOK)
+			// Changes are downloaded from changes endpoint, so the cursor stays
+			cursor := request.URL.Query().Get(models.CursorParam)
+			if cursor == "" {
+				cursor = "0"
+			}
+			_, _ = writer.Write([]byte(`{"results":[{"id":"1","status":"conflict","reason":"test"}],` +
+				`"data":[{"id":"1","revision":2}],"cursor":` + cursor + `}`))
+		})
+		r.With().Get("/changes", func(writer http.ResponseWriter, request *http.Request) {
+			writer.Header().Set("Content-Type", models.ChangesContentType)
+			// Cursor moves by 5 on every sync
+			cursor, _ := strconv.ParseInt(request.URL.Query().Get(models.CursorParam), 10, 64)
+			_, _ = writer.Write([]byte(`{"checkpoint":{"cursor":` + strconv.FormatInt(cursor, 10) +
+				`,"reset":` + strconv.FormatBool(cursor == 0) + `}}` + "\n" +
+				`{"data":{"id":"1","revision":2}}` + "\n" +
+				`{"checkpoint":{"cursor":` + strconv.FormatInt(cursor+5, 10) + `,"done":true}}` + "\n"))
+		})
+		r.With().Get("/events", func(writer http.ResponseWriter, request *http.Request) {
+			writer.Header().Set("Content-Type", "text/event-stream")
+			_, _ = writer.Write([]byte(": keep-alive\n\nevent: change\ndata: {\"cursor\":3}\n\n" +
+				"event: unknown\ndata: {}\n\n"))
+		})
+		r.With().Get("/history/{id}", func(writer http.ResponseWriter, request *http.Request) {
+			_, _ = writer.Write([]byte(`[{"id":"` + chi.URLParam(request, "id") + `","revision":1}]`))
+		})
+		r.With().Get("/history/{id}/{revision}", func(writer http.ResponseWriter, request *http.Request) {
+			_, _ = writer.Write([]byte(`{"id":"` + chi.URLParam(request, "id") + `","revision":` +
+				chi.URLParam(request, "revision") + `,"data":"ZGF0YQ=="}`))
 		})
 	})
 	server := httptest.NewUnstartedServer(r)
@@ -117,6 +157,8 @@ func TestSync(t *testing.T) {
 	keyring.MockInit()
 	server := MockChiHTTPServer()
 	defer server.Close()
+	// Server data has plaintext labels, they are sealed with the secret
+	auth.SetSecret("test_secret")
 
 	// Create a mediator with the mock server
 	newMediator := NewMediator(storage.NewStorage())
@@ -127,31 +169,97 @@ func TestSync(t *testing.T) {
 		t.Errorf("Sync failed with error: %v", err)
 	}
 
+	// Check usage reported by server
+	usage := newMediator.Usage()
+	if usage.Items != 3 || usage.MaxItems != 10 || usage.Bytes != 0 {
+		t.Errorf("Unexpected usage %+v", usage)
+	}
+
+	// Check statuses of items and data reported by server
+	result, ok := newMediator.Result("1")
+	if !ok || result.Status != models.StatusConflict || result.Reason != "test" {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	if _, ok = newMediator.Result("2"); ok {
+		t.Errorf("Got result for item that wasn't synced")
+	}
+	if data := newMediator.storage.Get(); len(data) != 1 || data[0].Revision != 2 {
+		t.Errorf("Server data wasn't stored: %+v", data)
+	}
+	// Next sync sends the cursor received from server
+	if err = newMediator.Sync(context.Background()); err != nil {
+		t.Errorf("Sync failed with error: %v", err)
+	}
+	if cursor := newMediator.storage.Cursor(); cursor != 10 {
+		t.Errorf("Cursor is %d, want 10", cursor)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	keyring.MockInit()
+	server := MockChiHTTPServer()
+	defer server.Close()
+
+	newMediator := NewMediator(storage.NewStorage())
+
+	// Only change events are handled, stream ends when server closes it
+	var events []models.ChangeEvent
+	err := newMediator.Events(context.Background(), func(event models.ChangeEvent) {
+		events = append(events, event)
+	})
+	if err != nil {
+		t.Errorf("Events failed with error: %v", err)
+	}
+	if len(events) != 1 || events[0].Cursor != 3 {
+		t.Errorf("Unexpected events %+v", events)
+	}
 }
 
-func TestSetCookies(t *testing.T) {
+func TestHistory(t *testing.T) {
 	keyring.MockInit()
+	server := MockChiHTTPServer()
+	defer server.Close()
+
+	// Create a mediator with the mock server
+	newMediator := NewMediator(storage.NewStorage())
+
+	// Test the History function
+	revisions, err := newMediator.History(context.Background(), "item")
+	if err != nil {
+		t.Fatalf("History failed with error: %v", err)
+	}
+	if len(revisions) != 1 || revisions[0].ID != "item" || revisions[0].Revision != 1 {
+		t.Errorf("Unexpected revisions: %+v", revisions)
+	}
+
+	// Test the Revision function
+	revision, err := newMediator.Revision(context.Background(), "item", 1)
+	if err != nil {
+		t.Fatalf("Revision failed with error: %v", err)
+	}
+	if revision.ID != "item" || revision.Revision != 1 || string(revision.Data) != "data" {
+		t.Errorf("Unexpected revision: %+v", revision)
+	}
+}
+
+func TestSessionFromCookies(t *testing.T) {
 	// Define test cookies
 	cookies := []*http.Cookie{
+		{Name: "other", Value: "other"},
 		{Name: "session_id", Value: "test-session-id"},
 	}
 
-	// Define test username
-	username := "test-username"
-
-	// Set the cookies
-	err := setCookies(cookies, username)
+	// Get the session
+	sessionID, err := sessionFromCookies(cookies)
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
-
-	// Check if the current user username matches the test username
-	if auth.CurrentUser.Username != username {
-		t.Errorf("Current user username does not match test username")
+	if sessionID != "test-session-id" {
+		t.Errorf("Session ID %s does not match test session ID", sessionID)
 	}
 
-	// Check if the current user session ID matches the test session ID
-	if auth.CurrentUser.SessionID != "test-session-id" {
-		t.Errorf("Current user session ID does not match test session ID")
+	// No session cookie
+	if _, err = sessionFromCookies(cookies[:1]); err == nil {
+		t.Errorf("Expected error without session cookie")
 	}
 }
@@ -0,0 +1,271 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gynshu-one/goph-keeper/client/auth"
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/gynshu-one/goph-keeper/common/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// rotationAttempts is how many times the org key rotation is made again when items were changed meanwhile
+const rotationAttempts = 3
+
+// Org is the organization of the user
+type Org struct {
+	models.Membership
+	// Key is the opened org key, items of the organization are encrypted with it
+	Key string
+}
+
+// CreateOrg creates the organization owned by the user with a new org key sealed to public key of the user
+func (m *mediator) CreateOrg(ctx context.Context, name string) (Org, error) {
+	keys, err := m.keyPair(ctx)
+	if err != nil {
+		return Org{}, err
+	}
+	key, err := utils.GenerateItemKey()
+	if err != nil {
+		return Org{}, err
+	}
+	sealed, err := utils.SealKey(key, keys.public)
+	if err != nil {
+		return Org{}, err
+	}
+	created, err := m.transport.CreateOrg(ctx, auth.CurrentUser.SessionID, models.Membership{
+		Organization: models.Organization{Name: name},
+		Member:       models.Member{Key: sealed},
+	})
+	if err != nil {
+		return Org{}, err
+	}
+	return Org{Membership: created, Key: key}, nil
+}
+
+// Orgs returns organizations of the user including invitations with their keys opened by the private key
+// organization whose key can't be opened is skipped
+func (m *mediator) Orgs(ctx context.Context) ([]Org, error) {
+	keys, err := m.keyPair(ctx)
+	if err != nil {
+		return nil, err
+	}
+	memberships, err := m.transport.Orgs(ctx, auth.CurrentUser.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	orgs := make([]Org, 0, len(memberships))
+	for _, membership := range memberships {
+		key, err := utils.OpenKey(membership.Member.Key, keys.private)
+		if err != nil {
+			log.Err(err).Str("org", membership.Organization.ID).Msg("failed to open org key")
+			continue
+		}
+		orgs = append(orgs, Org{Membership: membership, Key: key})
+	}
+	return orgs, nil
+}
+
+// org returns the organization of the user with the id
+func (m *mediator) org(ctx context.Context, id string) (Org, error) {
+	orgs, err := m.Orgs(ctx)
+	if err != nil {
+		return Org{}, err
+	}
+	for _, org := range orgs {
+		if org.Organization.ID == id {
+			return org, nil
+		}
+	}
+	return Org{}, fmt.Errorf("%w: organization %s", ErrNotFound, id)
+}
+
+// Members returns members of the organization
+func (m *mediator) Members(ctx context.Context, orgID string) ([]models.Member, error) {
+	return m.transport.Members(ctx, auth.CurrentUser.SessionID, orgID)
+}
+
+// Invite seals the org key to public key of the user with the email and invites it to the organization with the role
+// role of a member is changed the same way
+func (m *mediator) Invite(ctx context.Context, org Org, email string, role models.Role) error {
+	public, err := m.transport.PublicKey(ctx, auth.CurrentUser.SessionID, email)
+	if err != nil {
+		return err
+	}
+	sealed, err := utils.SealKey(org.Key, public)
+	if err != nil {
+		return err
+	}
+	_, err = m.transport.SetMember(ctx, auth.CurrentUser.SessionID, models.Member{
+		OrgID:      org.Organization.ID,
+		UserID:     email,
+		Role:       role,
+		Key:        sealed,
+		KeyVersion: org.Member.KeyVersion,
+	})
+	return err
+}
+
+// Accept accepts the invitation to the organization
+func (m *mediator) Accept(ctx context.Context, orgID string) error {
+	return m.transport.AcceptInvite(ctx, auth.CurrentUser.SessionID, orgID)
+}
+
+// RemoveMember removes the user from the organization and rotates the org key,
+// so the user can't read next versions of items with the key it had
+// the user leaves the organization if it's the user itself, then the key is rotated by a manager
+func (m *mediator) RemoveMember(ctx context.Context, orgID, userID string) error {
+	err := m.transport.RemoveMember(ctx, auth.CurrentUser.SessionID, orgID, userID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if userID == auth.CurrentUser.Username {
+		return nil
+	}
+	return m.RotateOrgKey(ctx, orgID)
+}
+
+// RotateOrgKey replaces the org key with a new one sealed to every member and re-encrypts every item with it
+// rotation is made again if items were changed while it was made
+func (m *mediator) RotateOrgKey(ctx context.Context, orgID string) error {
+	for attempt := 1; ; attempt++ {
+		err := m.rotateOrgKey(ctx, orgID)
+		if !errors.Is(err, ErrConflict) || attempt >= rotationAttempts {
+			return err
+		}
+	}
+}
+
+// rotateOrgKey makes the rotation from current items and members of the organization
+func (m *mediator) rotateOrgKey(ctx context.Context, orgID string) error {
+	org, err := m.org(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	data, err := m.OrgItems(ctx, org)
+	if err != nil {
+		return err
+	}
+	members, err := m.transport.Members(ctx, auth.CurrentUser.SessionID, orgID)
+	if err != nil {
+		return err
+	}
+	key, err := utils.GenerateItemKey()
+	if err != nil {
+		return err
+	}
+
+	rotation := models.KeyRotation{OrgID: orgID, KeyVersion: org.Member.KeyVersion + 1}
+	for _, member := range members {
+		public, err := m.transport.PublicKey(ctx, auth.CurrentUser.SessionID, member.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to get public key of %s: %w", member.UserID, err)
+		}
+		sealed, err := utils.SealKey(key, public)
+		if err != nil {
+			return err
+		}
+		rotation.Members = append(rotation.Members, models.Member{OrgID: orgID, UserID: member.UserID, Key: sealed})
+	}
+	for _, item := range data {
+		plaintext, err := utils.DecryptData(item.Data, org.Key)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt item %s: %w", item.ID, err)
+		}
+		if item.Data, err = utils.EncryptData(plaintext, key); err != nil {
+			return err
+		}
+		if item, err = models.SealLabel(item, key); err != nil {
+			return err
+		}
+		rotation.Data = append(rotation.Data, item)
+	}
+
+	results, err := m.transport.RotateOrgKey(ctx, auth.CurrentUser.SessionID, rotation)
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		if result.Status != models.StatusApplied {
+			return fmt.Errorf("%w: item %s is %s", ErrConflict, result.ID, result.Status)
+		}
+	}
+	return nil
+}
+
+// Collections returns collections of the organization
+func (m *mediator) Collections(ctx context.Context, orgID string) ([]models.Collection, error) {
+	return m.transport.Collections(ctx, auth.CurrentUser.SessionID, orgID)
+}
+
+// AddCollection adds the collection with the name to the organization
+func (m *mediator) AddCollection(ctx context.Context, orgID, name string) (models.Collection, error) {
+	return m.transport.SetCollection(ctx, auth.CurrentUser.SessionID, models.Collection{OrgID: orgID, Name: name})
+}
+
+// OrgItems returns items of the organization encrypted with the org key with their names and types decrypted,
+// they are not kept locally
+// returns ErrConflict if the key was rotated since the organization was read
+func (m *mediator) OrgItems(ctx context.Context, org Org) ([]models.DataWrapper, error) {
+	data, err := m.transport.OrgData(ctx, auth.CurrentUser.SessionID, org.Organization.ID)
+	if err != nil {
+		return nil, err
+	}
+	if data.KeyVersion != org.Member.KeyVersion {
+		return nil, fmt.Errorf("%w: org key was rotated", ErrConflict)
+	}
+	for i := range data.Data {
+		if data.Data[i], err = models.OpenLabel(data.Data[i], org.Key); err != nil {
+			return nil, fmt.Errorf("failed to decrypt label of item %s: %w", data.Data[i].ID, err)
+		}
+	}
+	return data.Data, nil
+}
+
+// SaveOrgItem encrypts the data with the org key and sends it to server as the next version of the item
+// new item gets an id, name and collection of the item are taken from wrapper
+func (m *mediator) SaveOrgItem(ctx context.Context, org Org, data models.BasicData, wrapper models.DataWrapper) (models.SyncResult, error) {
+	encrypted, err := data.EncryptAll(org.Key)
+	if err != nil {
+		return models.SyncResult{}, err
+	}
+	t := time.Now().Unix()
+	wrapper.Data = encrypted
+	if wrapper.ID == "" {
+		wrapper.ID, wrapper.CreatedAt = uuid.NewString(), t
+	}
+	wrapper.UpdatedAt = t
+	return m.writeOrgItem(ctx, org, wrapper)
+}
+
+// DeleteOrgItem marks the item of the organization as deleted
+func (m *mediator) DeleteOrgItem(ctx context.Context, org Org, wrapper models.DataWrapper) (models.SyncResult, error) {
+	t := time.Now().Unix()
+	wrapper.DeletedAt, wrapper.UpdatedAt = t, t
+	return m.writeOrgItem(ctx, org, wrapper)
+}
+
+// writeOrgItem encrypts the name and type of the item of the organization with the org key and sends the item to server
+func (m *mediator) writeOrgItem(ctx context.Context, org Org, wrapper models.DataWrapper) (models.SyncResult, error) {
+	var err error
+	if wrapper, err = models.SealLabel(wrapper, org.Key); err != nil {
+		return models.SyncResult{}, err
+	}
+	wrapper.OwnerID = org.Organization.ID
+	results, err := m.transport.WriteOrgData(ctx, auth.CurrentUser.SessionID, models.OrgData{
+		OrgID:      org.Organization.ID,
+		KeyVersion: org.Member.KeyVersion,
+		Data:       []models.DataWrapper{wrapper},
+	})
+	if err != nil {
+		return models.SyncResult{}, err
+	}
+	if len(results) != 1 {
+		return models.SyncResult{}, fmt.Errorf("%w: %d results for one item", ErrServer, len(results))
+	}
+	return results[0], nil
+}
@@ -0,0 +1,191 @@
+package sync
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gynshu-one/goph-keeper/client/auth"
+	"github.com/gynshu-one/goph-keeper/client/storage"
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/zalando/go-keyring"
+)
+
+// orgTransport keeps organizations of all users in memory like server does
+// conflicts is how many next rotations fail as if items were changed meanwhile
+type orgTransport struct {
+	*shareTransport
+	org       models.Organization
+	members   map[string]models.Member
+	orgItems  map[string]models.DataWrapper
+	conflicts int
+}
+
+func (t *orgTransport) CreateOrg(_ context.Context, _ string, membership models.Membership) (models.Membership, error) {
+	t.org = models.Organization{ID: "org", Name: membership.Organization.Name, KeyVersion: 1}
+	owner := models.Member{OrgID: t.org.ID, UserID: auth.CurrentUser.Username, Role: models.RoleOwner,
+		Key: membership.Member.Key, KeyVersion: 1, Accepted: true}
+	t.members[owner.UserID] = owner
+	return models.Membership{Organization: t.org, Member: owner}, nil
+}
+
+func (t *orgTransport) Orgs(_ context.Context, _ string) ([]models.Membership, error) {
+	member, ok := t.members[auth.CurrentUser.Username]
+	if !ok {
+		return nil, nil
+	}
+	return []models.Membership{{Organization: t.org, Member: member}}, nil
+}
+
+func (t *orgTransport) Members(_ context.Context, _, _ string) (members []models.Member, err error) {
+	for _, member := range t.members {
+		member.Key = nil
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+func (t *orgTransport) SetMember(_ context.Context, _ string, member models.Member) (models.Member, error) {
+	if member.KeyVersion != t.org.KeyVersion {
+		return models.Member{}, &Error{Kind: ErrConflict}
+	}
+	t.members[member.UserID] = member
+	return member, nil
+}
+
+func (t *orgTransport) RemoveMember(_ context.Context, _, _, userID string) error {
+	delete(t.members, userID)
+	return nil
+}
+
+func (t *orgTransport) AcceptInvite(_ context.Context, _, _ string) error {
+	member := t.members[auth.CurrentUser.Username]
+	member.Accepted = true
+	t.members[member.UserID] = member
+	return nil
+}
+
+func (t *orgTransport) OrgData(_ context.Context, _, _ string) (models.OrgData, error) {
+	data := models.OrgData{OrgID: t.org.ID, KeyVersion: t.org.KeyVersion}
+	for _, item := range t.orgItems {
+		data.Data = append(data.Data, item)
+	}
+	return data, nil
+}
+
+func (t *orgTransport) WriteOrgData(_ context.Context, _ string, data models.OrgData) (results []models.SyncResult, err error) {
+	if data.KeyVersion != t.org.KeyVersion {
+		return nil, &Error{Kind: ErrConflict}
+	}
+	for _, item := range data.Data {
+		item.Revision++
+		t.orgItems[item.ID] = item
+		results = append(results, models.SyncResult{ID: item.ID, Status: models.StatusApplied})
+	}
+	return results, nil
+}
+
+func (t *orgTransport) RotateOrgKey(_ context.Context, _ string, rotation models.KeyRotation) ([]models.SyncResult, error) {
+	if t.conflicts > 0 || rotation.KeyVersion != t.org.KeyVersion+1 || len(rotation.Members) != len(t.members) {
+		t.conflicts--
+		return nil, &Error{Kind: ErrConflict}
+	}
+	t.org.KeyVersion = rotation.KeyVersion
+	for _, member := range rotation.Members {
+		stored := t.members[member.UserID]
+		stored.Key, stored.KeyVersion = member.Key, rotation.KeyVersion
+		t.members[member.UserID] = stored
+	}
+	return t.WriteOrgData(context.Background(), "", models.OrgData{KeyVersion: rotation.KeyVersion, Data: rotation.Data})
+}
+
+func TestOrgs(t *testing.T) {
+	keyring.MockInit()
+	ctx := context.Background()
+	transport := &orgTransport{
+		shareTransport: newShareTransport(),
+		members:        make(map[string]models.Member),
+		orgItems:       make(map[string]models.DataWrapper),
+	}
+	users := make(map[string]*mediator)
+	for _, username := range []string{"bob", "carol", "alice"} {
+		signIn(username)
+		users[username] = newMediatorWith(storage.NewStorage(), transport)
+		if err := users[username].SetupKeys(ctx); err != nil {
+			t.Fatalf("SetupKeys of %s failed with error: %v", username, err)
+		}
+	}
+
+	// Alice creates the organization with an item and invites the others
+	alice := users["alice"]
+	org, err := alice.CreateOrg(ctx, "team")
+	if err != nil {
+		t.Fatalf("CreateOrg failed with error: %v", err)
+	}
+	login := &models.Login{Username: "team", Password: "pass"}
+	result, err := alice.SaveOrgItem(ctx, org, login, models.DataWrapper{Type: models.LoginType, Name: "site", CollectionID: "c"})
+	if err != nil || result.Status != models.StatusApplied {
+		t.Fatalf("SaveOrgItem failed with %+v and %v", result, err)
+	}
+	if item := transport.orgItems[result.ID]; item.OwnerID != "org" || len(item.Key) != 0 {
+		t.Errorf("Org item is not owned by the organization: %+v", item)
+	}
+	if item := transport.orgItems[result.ID]; item.LabelVersion != models.LabelSealed || item.Type != models.SealedType {
+		t.Errorf("Label of org item is sent in plaintext: %s %s", item.Type, item.Name)
+	}
+	for _, username := range []string{"bob", "carol"} {
+		if err = alice.Invite(ctx, org, username, models.RoleMember); err != nil {
+			t.Fatalf("Invite failed with error: %v", err)
+		}
+	}
+
+	// Invited users open the org key and read the item
+	var oldKey string
+	for _, username := range []string{"bob", "carol"} {
+		signIn(username)
+		if err = users[username].Accept(ctx, "org"); err != nil {
+			t.Fatalf("Accept failed with error: %v", err)
+		}
+		orgs, err := users[username].Orgs(ctx)
+		if err != nil || len(orgs) != 1 || !orgs[0].Member.Accepted {
+			t.Fatalf("Expected accepted organization, got %+v and %v", orgs, err)
+		}
+		items, err := users[username].OrgItems(ctx, orgs[0])
+		if err != nil || len(items) != 1 || items[0].Name != "site" {
+			t.Fatalf("Expected 1 org item named site, got %+v and %v", items, err)
+		}
+		data, err := storage.Open(items[0], orgs[0].Key)
+		if err != nil || data.(models.Login).Password != "pass" {
+			t.Fatalf("Org item is not opened: %+v, %v", data, err)
+		}
+		oldKey = orgs[0].Key
+	}
+
+	// Removed user can't read items with the key it had, rotation is made again after a conflict
+	signIn("alice")
+	transport.conflicts = 1
+	if err = alice.RemoveMember(ctx, "org", "carol"); err != nil {
+		t.Fatalf("RemoveMember failed with error: %v", err)
+	}
+	if transport.org.KeyVersion != 2 || len(transport.members) != 2 {
+		t.Fatalf("Expected key version 2 and 2 members, got %d and %d", transport.org.KeyVersion, len(transport.members))
+	}
+	if _, err = storage.Open(transport.orgItems[result.ID], oldKey); err == nil {
+		t.Error("Org item is opened with the key of removed user")
+	}
+	// Writes with the old key are rejected
+	if _, err = alice.SaveOrgItem(ctx, org, login, models.DataWrapper{Type: models.LoginType}); err == nil {
+		t.Error("Item encrypted with the old org key is accepted")
+	}
+	signIn("bob")
+	orgs, err := users["bob"].Orgs(ctx)
+	if err != nil || len(orgs) != 1 {
+		t.Fatalf("Expected 1 organization, got %+v and %v", orgs, err)
+	}
+	items, err := users["bob"].OrgItems(ctx, orgs[0])
+	if err != nil || len(items) != 1 || items[0].Name != "site" {
+		t.Fatalf("Expected 1 org item named site, got %+v and %v", items, err)
+	}
+	if _, err = storage.Open(items[0], orgs[0].Key); err != nil {
+		t.Errorf("Org item is not opened with the rotated key: %v", err)
+	}
+}
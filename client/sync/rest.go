@@ -0,0 +1,642 @@
+package sync
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/gynshu-one/goph-keeper/common/utils"
+)
+
+const (
+	RegisterEndpoint = "/user/create"
+	LoginEndpoint    = "/user/login"
+	Endpoint         = "/user/sync"
+	HistoryEndpoint  = "/user/history/"
+	EventsEndpoint   = "/user/events"
+	ChangesEndpoint  = "/user/changes"
+	BlobsEndpoint    = "/user/blobs"
+	KeysEndpoint     = "/user/keys"
+	SharesEndpoint   = "/user/shares"
+	SharedEndpoint   = "/user/shared"
+	OrgsEndpoint     = "/user/orgs"
+	ContactsEndpoint = "/user/emergency/contacts"
+	GrantorsEndpoint = "/user/emergency/grantors"
+	SendsEndpoint    = "/user/sends"
+	// SendEndpoint is the public endpoint links of sends point to
+	SendEndpoint = "/send/"
+)
+
+// restTransport talks to REST API of the server with resty
+type restTransport struct {
+	client  *resty.Client
+	baseURL string
+	// encoding is the content encoding of requests learned from server's responses
+	encoding atomic.Value
+}
+
+func newRESTTransport(baseURL string) *restTransport {
+	tr := &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+	return &restTransport{
+		client:  resty.NewWithClient(&http.Client{Transport: tr}),
+		baseURL: baseURL,
+	}
+}
+
+// SignUp sends request to server to create new user
+func (t *restTransport) SignUp(ctx context.Context, username, password string) (string, error) {
+	// Make request to server
+	get, err := t.client.NewRequest().SetContext(ctx).
+		SetQueryParam("email", username).
+		SetQueryParam("password", password).Get(t.baseURL + RegisterEndpoint)
+	if err != nil {
+		return "", offline(err)
+	}
+	if get.StatusCode() != 200 {
+		return "", statusError(get.StatusCode(), get.Body())
+	}
+	t.learnEncoding(get.Header())
+	return sessionFromCookies(get.Cookies())
+}
+
+// SignIn sends request to server to login user
+func (t *restTransport) SignIn(ctx context.Context, username, password string) (string, error) {
+	// Make request to server
+	get, err := t.client.NewRequest().SetContext(ctx).
+		SetQueryParam("email", username).
+		SetQueryParam("password", password).Get(t.baseURL + LoginEndpoint)
+	if err != nil {
+		return "", offline(err)
+	}
+	if get.StatusCode() != 200 {
+		return "", statusError(get.StatusCode(), get.Body())
+	}
+	t.learnEncoding(get.Header())
+	return sessionFromCookies(get.Cookies())
+}
+
+// Sync posts items to sync endpoint, usage is read from response headers
+// request is compressed with the encoding server advertised, response is compressed by server if it supports it
+func (t *restTransport) Sync(ctx context.Context, sessionID string, cursor int64, pushOnly bool, key string, sent []models.DataWrapper) (*models.SyncResponse, models.Usage, error) {
+	body, err := json.Marshal(sent)
+	if err != nil {
+		return nil, models.Usage{}, err
+	}
+
+	// Make request to server to get data don't forget to set cookie
+	request := t.client.NewRequest().SetContext(ctx).SetDoNotParseResponse(true).
+		SetHeader("Content-Type", "application/json").
+		SetHeader("Accept-Encoding", utils.AcceptEncoding).
+		SetCookie(&http.Cookie{
+			Name:  "session_id",
+			Value: sessionID,
+		})
+	if encoding := t.requestEncoding(); encoding != "" {
+		body, err = compress(encoding, body)
+		if err != nil {
+			return nil, models.Usage{}, err
+		}
+		request.SetHeader("Content-Encoding", encoding)
+	}
+	if cursor > 0 {
+		request.SetQueryParam(models.CursorParam, strconv.FormatInt(cursor, 10))
+	}
+	if pushOnly {
+		request.SetQueryParam(models.PushOnlyParam, "true")
+	}
+	if key != "" {
+		request.SetHeader(models.IdempotencyKeyHeader, key)
+	}
+	response, err := request.SetBody(body).Post(t.baseURL + Endpoint)
+	if err != nil {
+		return nil, models.Usage{}, offline(err)
+	}
+	t.learnEncoding(response.Header())
+	body, err = readBody(response)
+	if err != nil {
+		return nil, models.Usage{}, err
+	}
+
+	// Check if server rejected the data, e.g. session expired or quota is exceeded
+	if response.StatusCode() >= http.StatusBadRequest {
+		return nil, models.Usage{}, statusError(response.StatusCode(), body)
+	}
+	usage := parseUsage(response.Header())
+
+	// Check if response is empty
+	if response.StatusCode() == http.StatusNoContent || len(body) == 0 {
+		return nil, usage, nil
+	}
+
+	var syncResponse models.SyncResponse
+	if err = json.Unmarshal(body, &syncResponse); err != nil {
+		return nil, usage, err
+	}
+	return &syncResponse, usage, nil
+}
+
+// requestEncoding returns encoding requests are compressed with, empty if server didn't advertise any
+func (t *restTransport) requestEncoding() string {
+	encoding, _ := t.encoding.Load().(string)
+	return encoding
+}
+
+// learnEncoding remembers the best encoding server advertised in Accept-Encoding header of its response
+// server that doesn't advertise encodings gets uncompressed requests
+func (t *restTransport) learnEncoding(header http.Header) {
+	t.encoding.Store(utils.NegotiateEncoding(header.Get("Accept-Encoding")))
+}
+
+// compress compresses data with the encoding
+func compress(encoding string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder, err := utils.NewEncoder(encoding, &buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = encoder.Write(data); err != nil {
+		return nil, err
+	}
+	if err = encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Changes reads changes endpoint line by line, response is decompressed while it's read
+func (t *restTransport) Changes(ctx context.Context, sessionID string, cursor int64, resume bool, handle func(line models.ChangeLine) error) error {
+	request := t.client.NewRequest().SetContext(ctx).SetDoNotParseResponse(true).
+		SetHeader("Accept", models.ChangesContentType).
+		SetHeader("Accept-Encoding", utils.AcceptEncoding).
+		SetCookie(&http.Cookie{
+			Name:  "session_id",
+			Value: sessionID,
+		})
+	if cursor > 0 {
+		request.SetQueryParam(models.CursorParam, strconv.FormatInt(cursor, 10))
+	}
+	if resume {
+		request.SetQueryParam(models.ResumeParam, "true")
+	}
+	response, err := request.Get(t.baseURL + ChangesEndpoint)
+	if err != nil {
+		return offline(err)
+	}
+	body, err := bodyReader(response)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = body.Close()
+	}()
+	if response.StatusCode() != http.StatusOK {
+		message, _ := io.ReadAll(body)
+		return statusError(response.StatusCode(), message)
+	}
+
+	decoder := json.NewDecoder(body)
+	for {
+		var line models.ChangeLine
+		err = decoder.Decode(&line)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = handle(line); err != nil {
+			return err
+		}
+	}
+}
+
+// readBody reads and closes raw body of the response decompressing it if needed
+func readBody(response *resty.Response) ([]byte, error) {
+	body, err := bodyReader(response)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = body.Close()
+	}()
+	return io.ReadAll(body)
+}
+
+// bodyReader returns raw body of the response decompressing it if needed, closing it closes raw body
+func bodyReader(response *resty.Response) (io.ReadCloser, error) {
+	raw := response.RawBody()
+	encoding := response.Header().Get("Content-Encoding")
+	if encoding == "" || encoding == "identity" {
+		return raw, nil
+	}
+	decoder, err := utils.NewDecoder(encoding, raw)
+	if err != nil {
+		_ = raw.Close()
+		return nil, err
+	}
+	return decodedBody{ReadCloser: decoder, raw: raw}, nil
+}
+
+// decodedBody closes decoder and raw body it reads from
+type decodedBody struct {
+	io.ReadCloser
+	raw io.Closer
+}
+
+func (b decodedBody) Close() error {
+	_ = b.ReadCloser.Close()
+	return b.raw.Close()
+}
+
+// parseUsage reads usage headers of sync response, missing headers are zero
+func parseUsage(header http.Header) models.Usage {
+	get := func(key string) int64 {
+		v, _ := strconv.ParseInt(header.Get(key), 10, 64)
+		return v
+	}
+	return models.Usage{
+		Items:       get(models.UsageItemsHeader),
+		Bytes:       get(models.UsageBytesHeader),
+		MaxItems:    get(models.UsageMaxItemsHeader),
+		MaxBytes:    get(models.UsageMaxBytesHeader),
+		MaxItemSize: get(models.UsageMaxItemSizeHeader),
+	}
+}
+
+// History sends request to server to get previous versions of the item
+func (t *restTransport) History(ctx context.Context, sessionID, id string) (revisions []models.DataWrapper, err error) {
+	err = t.getJSON(ctx, sessionID, HistoryEndpoint+url.PathEscape(id), &revisions)
+	return revisions, err
+}
+
+// Revision sends request to server to get previous version of the item
+func (t *restTransport) Revision(ctx context.Context, sessionID, id string, revision int64) (data models.DataWrapper, err error) {
+	err = t.getJSON(ctx, sessionID, HistoryEndpoint+url.PathEscape(id)+"/"+strconv.FormatInt(revision, 10), &data)
+	return data, err
+}
+
+// getJSON makes authorized GET request to the server endpoint and unmarshals response to result
+func (t *restTransport) getJSON(ctx context.Context, sessionID, endpoint string, result any) error {
+	response, err := t.client.NewRequest().SetContext(ctx).SetCookie(&http.Cookie{
+		Name:  "session_id",
+		Value: sessionID,
+	}).Get(t.baseURL + endpoint)
+	if err != nil {
+		return offline(err)
+	}
+	if response.StatusCode() != http.StatusOK {
+		return statusError(response.StatusCode(), response.Body())
+	}
+	return json.Unmarshal(response.Body(), result)
+}
+
+// sendJSON makes authorized request with the method and body in json format to the server endpoint
+// and unmarshals response to result if it's not nil
+func (t *restTransport) sendJSON(ctx context.Context, sessionID, method, endpoint string, body, result any) error {
+	request := t.client.NewRequest().SetContext(ctx).SetCookie(&http.Cookie{
+		Name:  "session_id",
+		Value: sessionID,
+	})
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		request.SetHeader("Content-Type", "application/json").SetBody(encoded)
+	}
+	response, err := request.Execute(method, t.baseURL+endpoint)
+	if err != nil {
+		return offline(err)
+	}
+	if response.StatusCode() != http.StatusOK {
+		return statusError(response.StatusCode(), response.Body())
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(response.Body(), result)
+}
+
+// Keys gets keys of the user from keys endpoint
+func (t *restTransport) Keys(ctx context.Context, sessionID string) (keys models.UserKeys, err error) {
+	err = t.getJSON(ctx, sessionID, KeysEndpoint, &keys)
+	return keys, err
+}
+
+// SetKeys puts keys of the user to keys endpoint
+func (t *restTransport) SetKeys(ctx context.Context, sessionID string, keys models.UserKeys) error {
+	return t.sendJSON(ctx, sessionID, http.MethodPut, KeysEndpoint, keys, nil)
+}
+
+// PublicKey gets public key of the user with the email from keys endpoint
+func (t *restTransport) PublicKey(ctx context.Context, sessionID, email string) ([]byte, error) {
+	var keys models.UserKeys
+	err := t.getJSON(ctx, sessionID, KeysEndpoint+"/"+url.PathEscape(email), &keys)
+	return keys.PublicKey, err
+}
+
+// Share puts the share to shares endpoint
+func (t *restTransport) Share(ctx context.Context, sessionID string, share models.Share) (stored models.Share, err error) {
+	err = t.sendJSON(ctx, sessionID, http.MethodPut, SharesEndpoint, share, &stored)
+	return stored, err
+}
+
+// Shares gets shares of the item from shares endpoint
+func (t *restTransport) Shares(ctx context.Context, sessionID, itemID string) (shares []models.Share, err error) {
+	err = t.getJSON(ctx, sessionID, SharesEndpoint+"/"+url.PathEscape(itemID), &shares)
+	return shares, err
+}
+
+// Unshare deletes the share of the item with the user
+func (t *restTransport) Unshare(ctx context.Context, sessionID, itemID, userID string) error {
+	return t.sendJSON(ctx, sessionID, http.MethodDelete,
+		SharesEndpoint+"/"+url.PathEscape(itemID)+"/"+url.PathEscape(userID), nil, nil)
+}
+
+// Shared gets items shared with the user from shared endpoint
+func (t *restTransport) Shared(ctx context.Context, sessionID string) (items []models.SharedItem, err error) {
+	err = t.getJSON(ctx, sessionID, SharedEndpoint, &items)
+	return items, err
+}
+
+// UpdateShared puts the edit of the shared item to shared endpoint
+func (t *restTransport) UpdateShared(ctx context.Context, sessionID string, data models.DataWrapper) (result models.SyncResult, err error) {
+	err = t.sendJSON(ctx, sessionID, http.MethodPut, SharedEndpoint, data, &result)
+	return result, err
+}
+
+// CreateOrg puts the organization to orgs endpoint
+func (t *restTransport) CreateOrg(ctx context.Context, sessionID string, membership models.Membership) (created models.Membership, err error) {
+	err = t.sendJSON(ctx, sessionID, http.MethodPut, OrgsEndpoint, membership, &created)
+	return created, err
+}
+
+// Orgs gets organizations of the user
+func (t *restTransport) Orgs(ctx context.Context, sessionID string) (memberships []models.Membership, err error) {
+	err = t.getJSON(ctx, sessionID, OrgsEndpoint, &memberships)
+	return memberships, err
+}
+
+// Members gets members of the organization
+func (t *restTransport) Members(ctx context.Context, sessionID, orgID string) (members []models.Member, err error) {
+	err = t.getJSON(ctx, sessionID, orgEndpoint(orgID, "members"), &members)
+	return members, err
+}
+
+// SetMember puts the member to members of the organization
+func (t *restTransport) SetMember(ctx context.Context, sessionID string, member models.Member) (stored models.Member, err error) {
+	err = t.sendJSON(ctx, sessionID, http.MethodPut, orgEndpoint(member.OrgID, "members"), member, &stored)
+	return stored, err
+}
+
+// RemoveMember deletes the member of the organization
+func (t *restTransport) RemoveMember(ctx context.Context, sessionID, orgID, userID string) error {
+	return t.sendJSON(ctx, sessionID, http.MethodDelete, orgEndpoint(orgID, "members")+"/"+url.PathEscape(userID), nil, nil)
+}
+
+// AcceptInvite posts to accept endpoint of the organization
+func (t *restTransport) AcceptInvite(ctx context.Context, sessionID, orgID string) error {
+	return t.sendJSON(ctx, sessionID, http.MethodPost, orgEndpoint(orgID, "accept"), nil, nil)
+}
+
+// Collections gets collections of the organization
+func (t *restTransport) Collections(ctx context.Context, sessionID, orgID string) (collections []models.Collection, err error) {
+	err = t.getJSON(ctx, sessionID, orgEndpoint(orgID, "collections"), &collections)
+	return collections, err
+}
+
+// SetCollection puts the collection to collections of the organization
+func (t *restTransport) SetCollection(ctx context.Context, sessionID string, collection models.Collection) (stored models.Collection, err error) {
+	err = t.sendJSON(ctx, sessionID, http.MethodPut, orgEndpoint(collection.OrgID, "collections"), collection, &stored)
+	return stored, err
+}
+
+// OrgData gets items of the organization
+func (t *restTransport) OrgData(ctx context.Context, sessionID, orgID string) (data models.OrgData, err error) {
+	err = t.getJSON(ctx, sessionID, orgEndpoint(orgID, "data"), &data)
+	return data, err
+}
+
+// WriteOrgData puts items of the organization
+func (t *restTransport) WriteOrgData(ctx context.Context, sessionID string, data models.OrgData) (results []models.SyncResult, err error) {
+	err = t.sendJSON(ctx, sessionID, http.MethodPut, orgEndpoint(data.OrgID, "data"), data, &results)
+	return results, err
+}
+
+// RotateOrgKey puts the rotation to key endpoint of the organization
+func (t *restTransport) RotateOrgKey(ctx context.Context, sessionID string, rotation models.KeyRotation) (results []models.SyncResult, err error) {
+	err = t.sendJSON(ctx, sessionID, http.MethodPut, orgEndpoint(rotation.OrgID, "key"), rotation, &results)
+	return results, err
+}
+
+// EmergencyContacts gets trusted contacts of the user
+func (t *restTransport) EmergencyContacts(ctx context.Context, sessionID string) (contacts []models.EmergencyAccess, err error) {
+	err = t.getJSON(ctx, sessionID, ContactsEndpoint, &contacts)
+	return contacts, err
+}
+
+// SetEmergencyContact puts the contact to contacts endpoint
+func (t *restTransport) SetEmergencyContact(ctx context.Context, sessionID string, access models.EmergencyAccess) (stored models.EmergencyAccess, err error) {
+	err = t.sendJSON(ctx, sessionID, http.MethodPut, ContactsEndpoint, access, &stored)
+	return stored, err
+}
+
+// RemoveEmergencyContact deletes the trusted contact
+func (t *restTransport) RemoveEmergencyContact(ctx context.Context, sessionID, granteeID string) error {
+	return t.sendJSON(ctx, sessionID, http.MethodDelete, ContactsEndpoint+"/"+url.PathEscape(granteeID), nil, nil)
+}
+
+// RejectEmergency posts to reject endpoint of the trusted contact
+func (t *restTransport) RejectEmergency(ctx context.Context, sessionID, granteeID string) (access models.EmergencyAccess, err error) {
+	err = t.sendJSON(ctx, sessionID, http.MethodPost, ContactsEndpoint+"/"+url.PathEscape(granteeID)+"/reject", nil, &access)
+	return access, err
+}
+
+// EmergencyGrantors gets users that trust the user
+func (t *restTransport) EmergencyGrantors(ctx context.Context, sessionID string) (grantors []models.EmergencyAccess, err error) {
+	err = t.getJSON(ctx, sessionID, GrantorsEndpoint, &grantors)
+	return grantors, err
+}
+
+// RequestEmergency posts to request endpoint of the grantor
+func (t *restTransport) RequestEmergency(ctx context.Context, sessionID, grantorID string) (access models.EmergencyAccess, err error) {
+	err = t.sendJSON(ctx, sessionID, http.MethodPost, GrantorsEndpoint+"/"+url.PathEscape(grantorID)+"/request", nil, &access)
+	return access, err
+}
+
+// EmergencyVault gets vault endpoint of the grantor
+func (t *restTransport) EmergencyVault(ctx context.Context, sessionID, grantorID string) (vault models.EmergencyVault, err error) {
+	err = t.getJSON(ctx, sessionID, GrantorsEndpoint+"/"+url.PathEscape(grantorID)+"/vault", &vault)
+	return vault, err
+}
+
+// Sends gets sends of the user
+func (t *restTransport) Sends(ctx context.Context, sessionID string) (sends []models.Send, err error) {
+	err = t.getJSON(ctx, sessionID, SendsEndpoint, &sends)
+	return sends, err
+}
+
+// CreateSend puts the send to sends endpoint
+func (t *restTransport) CreateSend(ctx context.Context, sessionID string, send models.Send) (created models.Send, err error) {
+	err = t.sendJSON(ctx, sessionID, http.MethodPut, SendsEndpoint, send, &created)
+	return created, err
+}
+
+// DeleteSend deletes the send
+func (t *restTransport) DeleteSend(ctx context.Context, sessionID, id string) error {
+	return t.sendJSON(ctx, sessionID, http.MethodDelete, SendsEndpoint+"/"+url.PathEscape(id), nil, nil)
+}
+
+// ReceiveSend posts to the public endpoint of the send without a session
+func (t *restTransport) ReceiveSend(ctx context.Context, id string) (send models.Send, err error) {
+	response, err := t.client.NewRequest().SetContext(ctx).Post(t.baseURL + SendEndpoint + url.PathEscape(id))
+	if err != nil {
+		return send, offline(err)
+	}
+	if response.StatusCode() != http.StatusOK {
+		return send, statusError(response.StatusCode(), response.Body())
+	}
+	return send, json.Unmarshal(response.Body(), &send)
+}
+
+// orgEndpoint returns the endpoint of the organization under OrgsEndpoint
+func orgEndpoint(orgID, endpoint string) string {
+	return OrgsEndpoint + "/" + url.PathEscape(orgID) + "/" + endpoint
+}
+
+// Events reads server-sent events of the events endpoint
+func (t *restTransport) Events(ctx context.Context, sessionID string, handle func(event models.ChangeEvent)) error {
+	response, err := t.client.NewRequest().SetContext(ctx).SetDoNotParseResponse(true).
+		SetHeader("Accept", "text/event-stream").SetCookie(&http.Cookie{
+		Name:  "session_id",
+		Value: sessionID,
+	}).Get(t.baseURL + EventsEndpoint)
+	if err != nil {
+		return offline(err)
+	}
+	body := response.RawBody()
+	defer func() {
+		_ = body.Close()
+	}()
+	if response.StatusCode() != http.StatusOK {
+		return statusError(response.StatusCode(), nil)
+	}
+
+	// Server-sent events are separated by empty lines, lines starting with colon are comments
+	var name, data string
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if name == models.ChangeEventName {
+				var event models.ChangeEvent
+				if err = json.Unmarshal([]byte(data), &event); err != nil {
+					return err
+				}
+				handle(event)
+			}
+			name, data = "", ""
+		case strings.HasPrefix(line, "event:"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data += strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// CreateBlob posts the blob to blobs endpoint
+func (t *restTransport) CreateBlob(ctx context.Context, sessionID string, blob models.Blob) (created models.Blob, err error) {
+	body, err := json.Marshal(blob)
+	if err != nil {
+		return created, err
+	}
+	response, err := t.client.NewRequest().SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetCookie(&http.Cookie{
+			Name:  "session_id",
+			Value: sessionID,
+		}).SetBody(body).Post(t.baseURL + BlobsEndpoint)
+	if err != nil {
+		return created, offline(err)
+	}
+	if response.StatusCode() != http.StatusOK {
+		return created, statusError(response.StatusCode(), response.Body())
+	}
+	return created, json.Unmarshal(response.Body(), &created)
+}
+
+// UploadBlob patches the blob with the chunk at the offset
+func (t *restTransport) UploadBlob(ctx context.Context, sessionID, id string, offset int64, chunk []byte) (blob models.Blob, err error) {
+	response, err := t.client.NewRequest().SetContext(ctx).
+		SetHeader("Content-Type", "application/octet-stream").
+		SetHeader(models.UploadOffsetHeader, strconv.FormatInt(offset, 10)).
+		SetCookie(&http.Cookie{
+			Name:  "session_id",
+			Value: sessionID,
+		}).SetBody(chunk).Patch(t.baseURL + BlobsEndpoint + "/" + url.PathEscape(id))
+	if err != nil {
+		return blob, offline(err)
+	}
+	if response.StatusCode() == http.StatusConflict {
+		return blob, &Error{Kind: ErrBlobOffset, Message: strings.TrimSpace(string(response.Body()))}
+	}
+	if response.StatusCode() != http.StatusOK {
+		return blob, statusError(response.StatusCode(), response.Body())
+	}
+	return blob, json.Unmarshal(response.Body(), &blob)
+}
+
+// DownloadBlob gets a chunk of the blob with a range request
+func (t *restTransport) DownloadBlob(ctx context.Context, sessionID, id string, offset int64) ([]byte, error) {
+	response, err := t.client.NewRequest().SetContext(ctx).
+		SetHeader("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+models.BlobChunkSize-1)).
+		SetCookie(&http.Cookie{
+			Name:  "session_id",
+			Value: sessionID,
+		}).Get(t.baseURL + BlobsEndpoint + "/" + url.PathEscape(id))
+	if err != nil {
+		return nil, offline(err)
+	}
+	if response.StatusCode() != http.StatusPartialContent && response.StatusCode() != http.StatusOK {
+		return nil, statusError(response.StatusCode(), response.Body())
+	}
+	return response.Body(), nil
+}
+
+// sessionFromCookies returns value of session_id cookie
+func sessionFromCookies(cookie []*http.Cookie) (string, error) {
+	// Read cookie from response
+	if len(cookie) == 0 {
+		return "", fmt.Errorf("failed to get cookie")
+	}
+
+	// Loop through cookies and the one with session_id
+	for _, c := range cookie {
+		if c.Name == "session_id" {
+			if c.Value == "" {
+				return "", fmt.Errorf("failed to get cookie")
+			}
+			return c.Value, nil
+		}
+	}
+	return "", fmt.Errorf("failed to get cookie")
+}
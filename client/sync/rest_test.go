@@ -0,0 +1,81 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/gynshu-one/goph-keeper/common/utils"
+)
+
+func TestSyncCompression(t *testing.T) {
+	// Server supports only gzip and compresses responses with it
+	var encodings []string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := r.Header.Get("Content-Encoding")
+		encodings = append(encodings, encoding)
+		body := r.Body
+		if encoding != "" {
+			decoder, err := utils.NewDecoder(encoding, r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+				return
+			}
+			body = decoder
+		}
+		var sent []models.DataWrapper
+		if err := json.NewDecoder(body).Decode(&sent); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Accept-Encoding", utils.EncodingGzip)
+		w.Header().Set("Content-Encoding", utils.EncodingGzip)
+		encoder, _ := utils.NewEncoder(utils.EncodingGzip, w)
+		_ = json.NewEncoder(encoder).Encode(models.SyncResponse{Data: sent, Cursor: 1})
+		_ = encoder.Close()
+	}))
+	defer server.Close()
+
+	transport := newRESTTransport(server.URL)
+	sent := []models.DataWrapper{{ID: "1", Data: []byte("data")}}
+	for i := 0; i < 2; i++ {
+		response, _, err := transport.Sync(context.Background(), "test", 0, false, "", sent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(response.Data) != 1 || string(response.Data[0].Data) != "data" || response.Cursor != 1 {
+			t.Errorf("Unexpected response %+v", response)
+		}
+	}
+
+	// First request is plain, next one uses encoding advertised by server
+	if len(encodings) != 2 || encodings[0] != "" || encodings[1] != utils.EncodingGzip {
+		t.Errorf("Unexpected request encodings %q", encodings)
+	}
+}
+
+func TestOffline(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	transport := newRESTTransport(server.URL)
+	if _, err := transport.SignIn(context.Background(), "testuser", "password"); !errors.Is(err, ErrOffline) {
+		t.Errorf("Expected ErrOffline, got %v", err)
+	}
+	_, _, err := transport.Sync(context.Background(), "test", 0, true, "", nil)
+	if !errors.Is(err, ErrOffline) {
+		t.Errorf("Expected ErrOffline, got %v", err)
+	}
+
+	// Canceled request is not a connection problem
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = transport.SignIn(ctx, "testuser", "password"); err == nil || errors.Is(err, ErrOffline) {
+		t.Errorf("Expected cancellation error, got %v", err)
+	}
+}
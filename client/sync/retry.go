@@ -0,0 +1,410 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+)
+
+const (
+	// retryAttempts is how many times request is made before its error is returned
+	retryAttempts = 3
+	// retryDelay is the delay before the first retry, it doubles with every next one
+	retryDelay = 500 * time.Millisecond
+)
+
+// retryTransport repeats requests of Transport that failed because server was not reachable or failed,
+// and the request that failed because session expired is repeated once with a new session
+// SignUp and SignIn are not repeated, events stream is reconnected by its caller
+type retryTransport struct {
+	Transport
+	// renew signs in again and returns id of the new session
+	renew func(ctx context.Context) (string, error)
+	// delay is the delay before the first retry
+	delay time.Duration
+}
+
+func newRetryTransport(transport Transport, renew func(ctx context.Context) (string, error)) *retryTransport {
+	return &retryTransport{Transport: transport, renew: renew, delay: retryDelay}
+}
+
+// Sync is repeated with the same key, so server doesn't apply it twice
+func (t *retryTransport) Sync(ctx context.Context, sessionID string, cursor int64, pushOnly bool, key string, sent []models.DataWrapper) (response *models.SyncResponse, usage models.Usage, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		response, usage, err = t.Transport.Sync(ctx, sessionID, cursor, pushOnly, key, sent)
+		return err
+	})
+	return response, usage, err
+}
+
+// Changes is repeated only if nothing was received, interrupted stream is resumed by its caller from checkpoint
+func (t *retryTransport) Changes(ctx context.Context, sessionID string, cursor int64, resume bool, handle func(line models.ChangeLine) error) error {
+	received := false
+	return t.do(ctx, sessionID, func(sessionID string) error {
+		err := t.Transport.Changes(ctx, sessionID, cursor, resume, func(line models.ChangeLine) error {
+			received = true
+			return handle(line)
+		})
+		if err != nil && received {
+			return finalError{err}
+		}
+		return err
+	})
+}
+
+// History is repeated as any read
+func (t *retryTransport) History(ctx context.Context, sessionID, id string) (revisions []models.DataWrapper, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		revisions, err = t.Transport.History(ctx, sessionID, id)
+		return err
+	})
+	return revisions, err
+}
+
+// Revision is repeated as any read
+func (t *retryTransport) Revision(ctx context.Context, sessionID, id string, revision int64) (data models.DataWrapper, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		data, err = t.Transport.Revision(ctx, sessionID, id, revision)
+		return err
+	})
+	return data, err
+}
+
+// CreateBlob is repeated as it returns the same blob
+func (t *retryTransport) CreateBlob(ctx context.Context, sessionID string, blob models.Blob) (created models.Blob, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		created, err = t.Transport.CreateBlob(ctx, sessionID, blob)
+		return err
+	})
+	return created, err
+}
+
+// UploadBlob is repeated at the same offset, chunk that was written already fails with ErrBlobOffset
+func (t *retryTransport) UploadBlob(ctx context.Context, sessionID, id string, offset int64, chunk []byte) (blob models.Blob, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		blob, err = t.Transport.UploadBlob(ctx, sessionID, id, offset, chunk)
+		return err
+	})
+	return blob, err
+}
+
+// DownloadBlob is repeated as any read
+func (t *retryTransport) DownloadBlob(ctx context.Context, sessionID, id string, offset int64) (chunk []byte, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		chunk, err = t.Transport.DownloadBlob(ctx, sessionID, id, offset)
+		return err
+	})
+	return chunk, err
+}
+
+// Keys is repeated as any read
+func (t *retryTransport) Keys(ctx context.Context, sessionID string) (keys models.UserKeys, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		keys, err = t.Transport.Keys(ctx, sessionID)
+		return err
+	})
+	return keys, err
+}
+
+// SetKeys is repeated, keys set by the request whose response was lost are read back by its caller
+func (t *retryTransport) SetKeys(ctx context.Context, sessionID string, keys models.UserKeys) error {
+	return t.do(ctx, sessionID, func(sessionID string) error {
+		return t.Transport.SetKeys(ctx, sessionID, keys)
+	})
+}
+
+// PublicKey is repeated as any read
+func (t *retryTransport) PublicKey(ctx context.Context, sessionID, email string) (key []byte, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		key, err = t.Transport.PublicKey(ctx, sessionID, email)
+		return err
+	})
+	return key, err
+}
+
+// Share is repeated as it replaces the same share
+func (t *retryTransport) Share(ctx context.Context, sessionID string, share models.Share) (stored models.Share, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		stored, err = t.Transport.Share(ctx, sessionID, share)
+		return err
+	})
+	return stored, err
+}
+
+// Shares is repeated as any read
+func (t *retryTransport) Shares(ctx context.Context, sessionID, itemID string) (shares []models.Share, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		shares, err = t.Transport.Shares(ctx, sessionID, itemID)
+		return err
+	})
+	return shares, err
+}
+
+// Unshare is repeated, share deleted by the request whose response was lost fails with ErrNotFound
+func (t *retryTransport) Unshare(ctx context.Context, sessionID, itemID, userID string) error {
+	return t.do(ctx, sessionID, func(sessionID string) error {
+		return t.Transport.Unshare(ctx, sessionID, itemID, userID)
+	})
+}
+
+// Shared is repeated as any read
+func (t *retryTransport) Shared(ctx context.Context, sessionID string) (items []models.SharedItem, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		items, err = t.Transport.Shared(ctx, sessionID)
+		return err
+	})
+	return items, err
+}
+
+// UpdateShared is repeated, edit applied by the request whose response was lost is reported as stale
+func (t *retryTransport) UpdateShared(ctx context.Context, sessionID string, data models.DataWrapper) (result models.SyncResult, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		result, err = t.Transport.UpdateShared(ctx, sessionID, data)
+		return err
+	})
+	return result, err
+}
+
+// CreateOrg is not repeated, the organization created by the request whose response was lost would be made twice
+func (t *retryTransport) CreateOrg(ctx context.Context, sessionID string, membership models.Membership) (created models.Membership, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		created, err = t.Transport.CreateOrg(ctx, sessionID, membership)
+		if err != nil && !errors.Is(err, ErrUnauthorized) {
+			return finalError{err}
+		}
+		return err
+	})
+	return created, err
+}
+
+// Orgs is repeated as any read
+func (t *retryTransport) Orgs(ctx context.Context, sessionID string) (memberships []models.Membership, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		memberships, err = t.Transport.Orgs(ctx, sessionID)
+		return err
+	})
+	return memberships, err
+}
+
+// Members is repeated as any read
+func (t *retryTransport) Members(ctx context.Context, sessionID, orgID string) (members []models.Member, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		members, err = t.Transport.Members(ctx, sessionID, orgID)
+		return err
+	})
+	return members, err
+}
+
+// SetMember is repeated as it replaces the same member
+func (t *retryTransport) SetMember(ctx context.Context, sessionID string, member models.Member) (stored models.Member, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		stored, err = t.Transport.SetMember(ctx, sessionID, member)
+		return err
+	})
+	return stored, err
+}
+
+// RemoveMember is repeated, member removed by the request whose response was lost fails with ErrNotFound
+func (t *retryTransport) RemoveMember(ctx context.Context, sessionID, orgID, userID string) error {
+	return t.do(ctx, sessionID, func(sessionID string) error {
+		return t.Transport.RemoveMember(ctx, sessionID, orgID, userID)
+	})
+}
+
+// AcceptInvite is repeated as accepting again changes nothing
+func (t *retryTransport) AcceptInvite(ctx context.Context, sessionID, orgID string) error {
+	return t.do(ctx, sessionID, func(sessionID string) error {
+		return t.Transport.AcceptInvite(ctx, sessionID, orgID)
+	})
+}
+
+// Collections is repeated as any read
+func (t *retryTransport) Collections(ctx context.Context, sessionID, orgID string) (collections []models.Collection, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		collections, err = t.Transport.Collections(ctx, sessionID, orgID)
+		return err
+	})
+	return collections, err
+}
+
+// SetCollection is repeated only for collections with id, new one would be added twice
+func (t *retryTransport) SetCollection(ctx context.Context, sessionID string, collection models.Collection) (stored models.Collection, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		stored, err = t.Transport.SetCollection(ctx, sessionID, collection)
+		if err != nil && collection.ID == "" && !errors.Is(err, ErrUnauthorized) {
+			return finalError{err}
+		}
+		return err
+	})
+	return stored, err
+}
+
+// OrgData is repeated as any read
+func (t *retryTransport) OrgData(ctx context.Context, sessionID, orgID string) (data models.OrgData, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		data, err = t.Transport.OrgData(ctx, sessionID, orgID)
+		return err
+	})
+	return data, err
+}
+
+// WriteOrgData is repeated, items written by the request whose response was lost are reported as stale
+func (t *retryTransport) WriteOrgData(ctx context.Context, sessionID string, data models.OrgData) (results []models.SyncResult, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		results, err = t.Transport.WriteOrgData(ctx, sessionID, data)
+		return err
+	})
+	return results, err
+}
+
+// RotateOrgKey is repeated, rotation applied by the request whose response was lost fails with ErrConflict
+func (t *retryTransport) RotateOrgKey(ctx context.Context, sessionID string, rotation models.KeyRotation) (results []models.SyncResult, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		results, err = t.Transport.RotateOrgKey(ctx, sessionID, rotation)
+		return err
+	})
+	return results, err
+}
+
+// EmergencyContacts is repeated as any read
+func (t *retryTransport) EmergencyContacts(ctx context.Context, sessionID string) (contacts []models.EmergencyAccess, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		contacts, err = t.Transport.EmergencyContacts(ctx, sessionID)
+		return err
+	})
+	return contacts, err
+}
+
+// SetEmergencyContact is repeated as it replaces the same contact
+func (t *retryTransport) SetEmergencyContact(ctx context.Context, sessionID string, access models.EmergencyAccess) (stored models.EmergencyAccess, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		stored, err = t.Transport.SetEmergencyContact(ctx, sessionID, access)
+		return err
+	})
+	return stored, err
+}
+
+// RemoveEmergencyContact is repeated, contact removed by the request whose response was lost fails with ErrNotFound
+func (t *retryTransport) RemoveEmergencyContact(ctx context.Context, sessionID, granteeID string) error {
+	return t.do(ctx, sessionID, func(sessionID string) error {
+		return t.Transport.RemoveEmergencyContact(ctx, sessionID, granteeID)
+	})
+}
+
+// RejectEmergency is repeated, request rejected by the request whose response was lost fails with ErrConflict
+func (t *retryTransport) RejectEmergency(ctx context.Context, sessionID, granteeID string) (access models.EmergencyAccess, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		access, err = t.Transport.RejectEmergency(ctx, sessionID, granteeID)
+		return err
+	})
+	return access, err
+}
+
+// EmergencyGrantors is repeated as any read
+func (t *retryTransport) EmergencyGrantors(ctx context.Context, sessionID string) (grantors []models.EmergencyAccess, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		grantors, err = t.Transport.EmergencyGrantors(ctx, sessionID)
+		return err
+	})
+	return grantors, err
+}
+
+// RequestEmergency is repeated as requesting again doesn't restart the waiting period
+func (t *retryTransport) RequestEmergency(ctx context.Context, sessionID, grantorID string) (access models.EmergencyAccess, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		access, err = t.Transport.RequestEmergency(ctx, sessionID, grantorID)
+		return err
+	})
+	return access, err
+}
+
+// EmergencyVault is repeated as any read
+func (t *retryTransport) EmergencyVault(ctx context.Context, sessionID, grantorID string) (vault models.EmergencyVault, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		vault, err = t.Transport.EmergencyVault(ctx, sessionID, grantorID)
+		return err
+	})
+	return vault, err
+}
+
+// Sends is repeated as any read
+func (t *retryTransport) Sends(ctx context.Context, sessionID string) (sends []models.Send, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		sends, err = t.Transport.Sends(ctx, sessionID)
+		return err
+	})
+	return sends, err
+}
+
+// CreateSend is not repeated once sent, server gives every send a new id
+func (t *retryTransport) CreateSend(ctx context.Context, sessionID string, send models.Send) (created models.Send, err error) {
+	err = t.do(ctx, sessionID, func(sessionID string) error {
+		created, err = t.Transport.CreateSend(ctx, sessionID, send)
+		if err != nil && !errors.Is(err, ErrUnauthorized) {
+			return finalError{err}
+		}
+		return err
+	})
+	return created, err
+}
+
+// DeleteSend is repeated, send deleted by the request whose response was lost fails with ErrNotFound
+func (t *retryTransport) DeleteSend(ctx context.Context, sessionID, id string) error {
+	return t.do(ctx, sessionID, func(sessionID string) error {
+		return t.Transport.DeleteSend(ctx, sessionID, id)
+	})
+}
+
+// ReceiveSend is not repeated, the view counted by the request whose response was lost can't be taken back
+func (t *retryTransport) ReceiveSend(ctx context.Context, id string) (models.Send, error) {
+	return t.Transport.ReceiveSend(ctx, id)
+}
+
+// finalError is the error of request that must not be repeated
+type finalError struct {
+	err error
+}
+
+func (e finalError) Error() string {
+	return e.err.Error()
+}
+
+func (e finalError) Unwrap() error {
+	return e.err
+}
+
+// do calls request with the session until it succeeds, fails with error that can't be fixed by retry
+// or retryAttempts are made, the delay between attempts grows with jitter
+// expired session is renewed once and the request is repeated right away
+func (t *retryTransport) do(ctx context.Context, sessionID string, request func(sessionID string) error) (err error) {
+	renewed := false
+	for attempt := 1; ; attempt++ {
+		err = request(sessionID)
+		var final finalError
+		switch {
+		case err == nil:
+			return nil
+		case errors.As(err, &final):
+			return final.err
+		case errors.Is(err, ErrUnauthorized) && !renewed:
+			renewed = true
+			var renewErr error
+			if sessionID, renewErr = t.renew(ctx); renewErr != nil {
+				return errors.Join(err, renewErr)
+			}
+			continue
+		case !retryable(err) || attempt >= retryAttempts:
+			return err
+		}
+
+		timer := time.NewTimer(backoff(t.delay, attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
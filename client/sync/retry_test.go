@@ -0,0 +1,112 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+)
+
+// flakyTransport fails requests with errors from the list in order
+type flakyTransport struct {
+	Transport
+	errs     []error
+	sessions []string
+	keys     []string
+}
+
+func (t *flakyTransport) Sync(_ context.Context, sessionID string, _ int64, _ bool, key string, _ []models.DataWrapper) (*models.SyncResponse, models.Usage, error) {
+	t.sessions = append(t.sessions, sessionID)
+	t.keys = append(t.keys, key)
+	if len(t.errs) == 0 {
+		return &models.SyncResponse{}, models.Usage{}, nil
+	}
+	err := t.errs[0]
+	t.errs = t.errs[1:]
+	return nil, models.Usage{}, err
+}
+
+func (t *flakyTransport) Changes(_ context.Context, sessionID string, _ int64, _ bool, handle func(line models.ChangeLine) error) error {
+	t.sessions = append(t.sessions, sessionID)
+	if err := handle(models.ChangeLine{Checkpoint: &models.Checkpoint{}}); err != nil {
+		return err
+	}
+	return &Error{Kind: ErrOffline}
+}
+
+func TestRetryTransport(t *testing.T) {
+	renew := func(context.Context) (string, error) {
+		return "renewed", nil
+	}
+	newFlaky := func(errs ...error) (*flakyTransport, *retryTransport) {
+		flaky := &flakyTransport{errs: errs}
+		transport := newRetryTransport(flaky, renew)
+		transport.delay = 0
+		return flaky, transport
+	}
+
+	t.Run("server errors are retried with the same key", func(t *testing.T) {
+		flaky, transport := newFlaky(&Error{Kind: ErrOffline}, &Error{Kind: ErrServer})
+		if _, _, err := transport.Sync(context.Background(), "test", 0, true, "key", nil); err != nil {
+			t.Fatalf("Sync failed with error: %v", err)
+		}
+		if len(flaky.keys) != 3 || flaky.keys[0] != "key" || flaky.keys[2] != "key" {
+			t.Errorf("Unexpected keys %q", flaky.keys)
+		}
+	})
+
+	t.Run("retries are limited", func(t *testing.T) {
+		flaky, transport := newFlaky(&Error{Kind: ErrServer}, &Error{Kind: ErrServer}, &Error{Kind: ErrServer})
+		if _, _, err := transport.Sync(context.Background(), "test", 0, true, "key", nil); !errors.Is(err, ErrServer) {
+			t.Errorf("Expected ErrServer, got %v", err)
+		}
+		if len(flaky.keys) != retryAttempts {
+			t.Errorf("Made %d attempts, want %d", len(flaky.keys), retryAttempts)
+		}
+	})
+
+	t.Run("rejected request is not retried", func(t *testing.T) {
+		flaky, transport := newFlaky(&Error{Kind: ErrQuotaExceeded})
+		if _, _, err := transport.Sync(context.Background(), "test", 0, true, "key", nil); !errors.Is(err, ErrQuotaExceeded) {
+			t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+		}
+		if len(flaky.keys) != 1 {
+			t.Errorf("Made %d attempts, want 1", len(flaky.keys))
+		}
+	})
+
+	t.Run("expired session is renewed once", func(t *testing.T) {
+		flaky, transport := newFlaky(&Error{Kind: ErrUnauthorized})
+		if _, _, err := transport.Sync(context.Background(), "expired", 0, true, "key", nil); err != nil {
+			t.Fatalf("Sync failed with error: %v", err)
+		}
+		if len(flaky.sessions) != 2 || flaky.sessions[1] != "renewed" {
+			t.Errorf("Unexpected sessions %q", flaky.sessions)
+		}
+
+		flaky, transport = newFlaky(&Error{Kind: ErrUnauthorized}, &Error{Kind: ErrUnauthorized})
+		if _, _, err := transport.Sync(context.Background(), "expired", 0, true, "key", nil); !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("Expected ErrUnauthorized, got %v", err)
+		}
+	})
+
+	t.Run("interrupted stream is not repeated", func(t *testing.T) {
+		flaky, transport := newFlaky()
+		err := transport.Changes(context.Background(), "test", 0, false, func(models.ChangeLine) error {
+			return nil
+		})
+		if !errors.Is(err, ErrOffline) || len(flaky.sessions) != 1 {
+			t.Errorf("Expected one interrupted stream, got %v after %d attempts", err, len(flaky.sessions))
+		}
+	})
+}
+
+func TestStatusError(t *testing.T) {
+	for code, kind := range map[int]error{401: ErrUnauthorized, 413: ErrQuotaExceeded, 400: ErrRequest, 404: ErrNotFound, 503: ErrServer} {
+		err := statusError(code, []byte("message\n"))
+		if !errors.Is(err, kind) || err.Error() != kind.Error()+": message" {
+			t.Errorf("Unexpected error %v for status code %d", err, code)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package sync
+
+import (
+	"context"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/client/storage"
+)
+
+// maxBackoff bounds the delay before retrying failed sync
+const maxBackoff = 5 * time.Minute
+
+// Status is the state of background sync reported by Scheduler
+type Status struct {
+	// Syncing is set while sync is in progress
+	Syncing bool
+	// Paused is set when sync is due but postponed until Resume
+	Paused bool
+	// LastSync is the time of the last successful sync
+	LastSync time.Time
+	// Err is the error of the last sync, nil if it succeeded
+	Err error
+	// Retry is the delay before the next attempt after failure
+	Retry time.Duration
+	// Changed means the last sync received changes or sent local ones, so the items list is outdated
+	Changed bool
+}
+
+// Scheduler syncs in background: every poll interval to receive changes of other devices
+// and every dump interval if there are local changes not sent yet
+// failed sync is retried with jittered exponential backoff, timers are ignored until it succeeds
+// sync is postponed while paused, e.g. while the user edits an item
+type Scheduler struct {
+	mediator Mediator
+	storage  storage.Storage
+	poll     time.Duration
+	dump     time.Duration
+	report   func(status Status)
+	trigger  chan struct{}
+	paused   atomic.Bool
+	// pending is set when sync was due while paused
+	pending atomic.Bool
+}
+
+// NewScheduler creates scheduler that syncs with the mediator, report is called on every change of status
+// from the scheduler goroutine, zero or negative interval disables its timer
+func NewScheduler(mediator Mediator, storage storage.Storage, poll, dump time.Duration, report func(status Status)) *Scheduler {
+	if report == nil {
+		report = func(Status) {}
+	}
+	return &Scheduler{
+		mediator: mediator,
+		storage:  storage,
+		poll:     poll,
+		dump:     dump,
+		report:   report,
+		trigger:  make(chan struct{}, 1),
+	}
+}
+
+// Run syncs on timers until ctx is done
+func (s *Scheduler) Run(ctx context.Context) {
+	poll, stopPoll := ticker(s.poll)
+	defer stopPoll()
+	dump, stopDump := ticker(s.dump)
+	defer stopDump()
+
+	var (
+		status   Status
+		retry    <-chan time.Time
+		failures int
+	)
+	for {
+		due := false
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll:
+			due = failures == 0
+		case <-dump:
+			due = failures == 0 && len(s.storage.Dirty()) > 0
+		case <-retry:
+			due = true
+		case <-s.trigger:
+			due = true
+		}
+		if !due {
+			continue
+		}
+		if s.paused.Load() {
+			s.pending.Store(true)
+			status.Paused = true
+			s.report(status)
+			continue
+		}
+
+		status.Paused, status.Syncing = false, true
+		s.report(status)
+		cursor, dirty := s.storage.Cursor(), len(s.storage.Dirty())
+		err := s.mediator.Sync(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		status.Syncing, status.Err = false, err
+		if err != nil {
+			failures++
+			status.Retry = backoff(s.poll, failures)
+			status.Changed = false
+			retry = time.After(status.Retry)
+		} else {
+			failures, status.Retry, retry = 0, 0, nil
+			status.LastSync = time.Now()
+			status.Changed = dirty > 0 || s.storage.Cursor() != cursor
+		}
+		s.report(status)
+	}
+}
+
+// Trigger makes the scheduler sync as soon as possible, e.g. when server reports changes
+func (s *Scheduler) Trigger() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Pause postpones syncs until Resume
+func (s *Scheduler) Pause() {
+	s.paused.Store(true)
+}
+
+// Resume allows syncs again and makes the one postponed while paused
+func (s *Scheduler) Resume() {
+	s.paused.Store(false)
+	if s.pending.Swap(false) {
+		s.Trigger()
+	}
+}
+
+// ticker returns channel of ticker with the interval and func that stops it
+// channel is nil if interval is not positive, so it never fires
+func ticker(interval time.Duration) (<-chan time.Time, func()) {
+	if interval <= 0 {
+		return nil, func() {}
+	}
+	t := time.NewTicker(interval)
+	return t.C, t.Stop
+}
+
+// backoff returns delay before retrying sync failed the given number of times in a row
+// the delay doubles with every failure starting from the interval up to maxBackoff,
+// it's randomized between half and full value, so clients don't retry all at once
+func backoff(interval time.Duration, failures int) time.Duration {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	delay := interval
+	for i := 1; i < failures && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
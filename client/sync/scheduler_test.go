@@ -0,0 +1,123 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/client/storage"
+	"github.com/gynshu-one/goph-keeper/common/models"
+)
+
+// countingMediator counts syncs and fails while fail is set
+type countingMediator struct {
+	Mediator
+	storage storage.Storage
+	syncs   atomic.Int32
+	fail    atomic.Bool
+}
+
+func (m *countingMediator) Sync(context.Context) error {
+	m.syncs.Add(1)
+	if m.fail.Load() {
+		return errors.New("server is down")
+	}
+	return m.storage.Checkpoint(models.Checkpoint{Cursor: int64(m.syncs.Load()), Done: true})
+}
+
+// runScheduler runs the scheduler until the test ends, statuses are sent to the returned channel
+func runScheduler(t *testing.T, mediator *countingMediator, poll, dump time.Duration) (*Scheduler, <-chan Status) {
+	t.Helper()
+	statuses := make(chan Status, 100)
+	scheduler := NewScheduler(mediator, mediator.storage, poll, dump, func(status Status) {
+		statuses <- status
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		scheduler.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return scheduler, statuses
+}
+
+// waitStatus returns the first status that is not Syncing
+func waitStatus(t *testing.T, statuses <-chan Status) Status {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case status := <-statuses:
+			if !status.Syncing {
+				return status
+			}
+		case <-timeout:
+			t.Fatal("No status reported")
+		}
+	}
+}
+
+func TestSchedulerPoll(t *testing.T) {
+	mediator := &countingMediator{storage: storage.NewStorage()}
+	_, statuses := runScheduler(t, mediator, 10*time.Millisecond, 0)
+
+	for i := 0; i < 2; i++ {
+		status := waitStatus(t, statuses)
+		if status.Err != nil || status.LastSync.IsZero() || !status.Changed {
+			t.Errorf("Unexpected status %+v", status)
+		}
+	}
+}
+
+func TestSchedulerBackoff(t *testing.T) {
+	mediator := &countingMediator{storage: storage.NewStorage()}
+	mediator.fail.Store(true)
+	scheduler, statuses := runScheduler(t, mediator, time.Minute, 0)
+
+	// Failed sync is retried after growing delay
+	scheduler.Trigger()
+	status := waitStatus(t, statuses)
+	if status.Err == nil || status.Retry < 30*time.Second || status.Retry > time.Minute {
+		t.Errorf("Unexpected status after the first failure %+v", status)
+	}
+	mediator.fail.Store(false)
+	scheduler.Trigger()
+	if status = waitStatus(t, statuses); status.Err != nil || status.Retry != 0 {
+		t.Errorf("Unexpected status after success %+v", status)
+	}
+}
+
+func TestSchedulerPause(t *testing.T) {
+	mediator := &countingMediator{storage: storage.NewStorage()}
+	scheduler, statuses := runScheduler(t, mediator, 0, 0)
+
+	// Sync due while paused is made on resume
+	scheduler.Pause()
+	scheduler.Trigger()
+	if status := waitStatus(t, statuses); !status.Paused {
+		t.Errorf("Expected paused status, got %+v", status)
+	}
+	if mediator.syncs.Load() != 0 {
+		t.Errorf("Synced while paused")
+	}
+	scheduler.Resume()
+	if status := waitStatus(t, statuses); status.Paused || status.LastSync.IsZero() {
+		t.Errorf("Expected sync after resume, got %+v", status)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for failures, want := range map[int]time.Duration{1: time.Second, 3: 4 * time.Second, 20: maxBackoff} {
+		for i := 0; i < 10; i++ {
+			if delay := backoff(time.Second, failures); delay < want/2 || delay > want {
+				t.Errorf("Backoff after %d failures is %s, want between %s and %s", failures, delay, want/2, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package sync
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/client/auth"
+	"github.com/gynshu-one/goph-keeper/client/config"
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/gynshu-one/goph-keeper/common/utils"
+)
+
+// CreateSend encrypts the secret with a random key, stores it on server and returns the link to it
+// the send can be viewed maxViews times until lifetime is over, the key is kept in the fragment of the link,
+// so the server never sees it
+func (m *mediator) CreateSend(ctx context.Context, secret string, lifetime time.Duration, maxViews int64) (string, error) {
+	key, err := utils.GenerateItemKey()
+	if err != nil {
+		return "", err
+	}
+	data, err := utils.EncryptData([]byte(secret), key)
+	if err != nil {
+		return "", err
+	}
+	send, err := m.transport.CreateSend(ctx, auth.CurrentUser.SessionID, models.Send{
+		Data:      data,
+		ExpiresAt: time.Now().Add(lifetime).Unix(),
+		MaxViews:  maxViews,
+	})
+	if err != nil {
+		return "", err
+	}
+	return sendLink(send.ID, key), nil
+}
+
+// Sends returns sends of the user that can still be viewed
+func (m *mediator) Sends(ctx context.Context) ([]models.Send, error) {
+	return m.transport.Sends(ctx, auth.CurrentUser.SessionID)
+}
+
+// DeleteSend removes the send of the user, its link doesn't open anymore
+func (m *mediator) DeleteSend(ctx context.Context, id string) error {
+	return m.transport.DeleteSend(ctx, auth.CurrentUser.SessionID, id)
+}
+
+// ReceiveSend opens the link of a send and returns its secret, every call uses up a view of the send
+// it needs no session, so links are opened without signing in
+func (m *mediator) ReceiveSend(ctx context.Context, link string) (string, error) {
+	id, key, err := parseSendLink(link)
+	if err != nil {
+		return "", err
+	}
+	send, err := m.transport.ReceiveSend(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	secret, err := utils.DecryptData(send.Data, key)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+// sendLink returns the link to the send at the REST address of the server with the key in its fragment
+func sendLink(id, key string) string {
+	return "https://" + config.GetConfig().ServerIP + SendEndpoint + url.PathEscape(id) + "#" + key
+}
+
+// parseSendLink returns id and key of the send from its link, the host of the link doesn't matter
+func parseSendLink(link string) (id, key string, err error) {
+	parsed, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || !strings.HasPrefix(parsed.Path, SendEndpoint) {
+		return "", "", ErrInvalidLink
+	}
+	id = strings.TrimPrefix(parsed.Path, SendEndpoint)
+	if id == "" || strings.Contains(id, "/") || parsed.Fragment == "" {
+		return "", "", ErrInvalidLink
+	}
+	return id, parsed.Fragment, nil
+}
@@ -0,0 +1,100 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/client/storage"
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/gynshu-one/goph-keeper/common/utils"
+	"github.com/zalando/go-keyring"
+)
+
+// sendTransport keeps sends like server does
+type sendTransport struct {
+	*shareTransport
+	sends map[string]models.Send
+}
+
+func (t *sendTransport) CreateSend(_ context.Context, _ string, send models.Send) (models.Send, error) {
+	send.ID = "send" + strconv.Itoa(len(t.sends))
+	t.sends[send.ID] = send
+	return send, nil
+}
+
+func (t *sendTransport) ReceiveSend(_ context.Context, id string) (models.Send, error) {
+	send, ok := t.sends[id]
+	if !ok || !send.ViewableAt(time.Now().Unix()) {
+		return models.Send{}, &Error{Kind: ErrNotFound}
+	}
+	send.Views++
+	t.sends[id] = send
+	if send.Views == send.MaxViews {
+		delete(t.sends, id)
+	}
+	return send, nil
+}
+
+func TestSend(t *testing.T) {
+	keyring.MockInit()
+	signIn("alice")
+	ctx := context.Background()
+	transport := &sendTransport{shareTransport: newShareTransport(), sends: make(map[string]models.Send)}
+	m := newMediatorWith(storage.NewStorage(), transport)
+
+	link, err := m.CreateSend(ctx, "wifi password", time.Hour, 1)
+	if err != nil {
+		t.Fatalf("CreateSend failed with error: %v", err)
+	}
+	id, key, found := strings.Cut(strings.TrimPrefix(link, "https://localhost:8080/send/"), "#")
+	if !found || id != "send0" || key == "" {
+		t.Fatalf("Unexpected link %s", link)
+	}
+	send := transport.sends[id]
+	if strings.Contains(string(send.Data), "wifi password") || send.MaxViews != 1 || send.ExpiresAt <= time.Now().Unix() {
+		t.Fatalf("Unexpected send on server: %+v", send)
+	}
+
+	// The secret is opened once, the key never reaches the server
+	secret, err := m.ReceiveSend(ctx, link)
+	if err != nil || secret != "wifi password" {
+		t.Fatalf("Expected the secret, got %q and %v", secret, err)
+	}
+	if _, err = m.ReceiveSend(ctx, link); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected %v for viewed send, got %v", ErrNotFound, err)
+	}
+
+	// Link must have the id and the key
+	for _, invalid := range []string{"https://localhost:8080/send/send0", "https://localhost:8080/user/send0#key",
+		"https://localhost:8080/send/#key", "not a link"} {
+		if _, err = m.ReceiveSend(ctx, invalid); !errors.Is(err, ErrInvalidLink) {
+			t.Errorf("Expected %v for %s, got %v", ErrInvalidLink, invalid, err)
+		}
+	}
+
+	// Wrong key doesn't open the secret
+	link, err = m.CreateSend(ctx, "wifi password", time.Hour, 1)
+	if err != nil {
+		t.Fatalf("CreateSend failed with error: %v", err)
+	}
+	if _, err = m.ReceiveSend(ctx, link[:strings.Index(link, "#")]+"#"+strings.Repeat("0", 64)); err == nil {
+		t.Error("Secret is opened with a wrong key")
+	}
+
+	// Send is written by anyone, truncated one is an error rather than a panic
+	link, err = m.CreateSend(ctx, "wifi password", time.Hour, 1)
+	if err != nil {
+		t.Fatalf("CreateSend failed with error: %v", err)
+	}
+	id, _, _ = strings.Cut(strings.TrimPrefix(link, "https://localhost:8080/send/"), "#")
+	send = transport.sends[id]
+	send.Data = send.Data[:3]
+	transport.sends[id] = send
+	if _, err = m.ReceiveSend(ctx, link); !errors.Is(err, utils.ErrCiphertextTooShort) {
+		t.Errorf("Expected %v for truncated send, got %v", utils.ErrCiphertextTooShort, err)
+	}
+}
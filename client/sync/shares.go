@@ -0,0 +1,209 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/client/auth"
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/gynshu-one/goph-keeper/common/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// SharedItem is the item other user shared with the user
+type SharedItem struct {
+	models.SharedItem
+	// Key is the opened key of the item, data of the item is encrypted with it
+	Key string
+}
+
+// keyPair is the key pair of the user with the private key decrypted
+type keyPair struct {
+	// username is the user the keys belong to, keys of another user are read again
+	username string
+	public   []byte
+	private  []byte
+}
+
+// SetupKeys reads the key pair of the user from server
+// user without keys gets a new pair, its private key is encrypted with the secret, so server can't use it
+func (m *mediator) SetupKeys(ctx context.Context) error {
+	_, err := m.keyPair(ctx)
+	return err
+}
+
+// keyPair returns the key pair of the user making it if the user has none
+func (m *mediator) keyPair(ctx context.Context) (*keyPair, error) {
+	m.mu.RLock()
+	keys := m.keys
+	m.mu.RUnlock()
+	if keys != nil && keys.username == auth.CurrentUser.Username {
+		return keys, nil
+	}
+
+	// Private key is kept on server encrypted with the secret, so it's never sent without it
+	secret, err := auth.Secret()
+	if err != nil {
+		return nil, err
+	}
+	stored, err := m.transport.Keys(ctx, auth.CurrentUser.SessionID)
+	if errors.Is(err, ErrNotFound) {
+		stored, err = m.newKeys(ctx, secret)
+	}
+	if err != nil {
+		return nil, err
+	}
+	private, err := utils.DecryptData(stored.PrivateKey, secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt private key: %w", err)
+	}
+	keys = &keyPair{username: auth.CurrentUser.Username, public: stored.PublicKey, private: private}
+	m.mu.Lock()
+	m.keys = keys
+	m.mu.Unlock()
+	return keys, nil
+}
+
+// newKeys makes a new key pair and stores it on server with the private key encrypted with the secret
+// keys are set once, so keys set by another device at the same time are read back
+func (m *mediator) newKeys(ctx context.Context, secret string) (models.UserKeys, error) {
+	public, private, err := utils.GenerateKeyPair()
+	if err != nil {
+		return models.UserKeys{}, err
+	}
+	encrypted, err := utils.EncryptData(private, secret)
+	if err != nil {
+		return models.UserKeys{}, err
+	}
+	keys := models.UserKeys{PublicKey: public, PrivateKey: encrypted}
+	if err = m.transport.SetKeys(ctx, auth.CurrentUser.SessionID, keys); err != nil {
+		return m.transport.Keys(ctx, auth.CurrentUser.SessionID)
+	}
+	return keys, nil
+}
+
+// ShareItem shares the item with the user with the email
+// item encrypted with the secret gets a key of its own first and is synced, so the secret is never shared
+func (m *mediator) ShareItem(ctx context.Context, id, email string, permission models.Permission) error {
+	key, err := m.storage.ItemKey(id)
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		if key, err = m.storage.Rekey(id); err != nil {
+			return err
+		}
+	}
+	if err = m.syncItem(ctx, id); err != nil {
+		return err
+	}
+	return m.share(ctx, id, key, email, permission)
+}
+
+// share seals the key of the item to public key of the user and shares the item with it
+func (m *mediator) share(ctx context.Context, id, key, email string, permission models.Permission) error {
+	public, err := m.transport.PublicKey(ctx, auth.CurrentUser.SessionID, email)
+	if err != nil {
+		return err
+	}
+	sealed, err := utils.SealKey(key, public)
+	if err != nil {
+		return err
+	}
+	_, err = m.transport.Share(ctx, auth.CurrentUser.SessionID,
+		models.Share{ItemID: id, UserID: email, Key: sealed, Permission: permission})
+	return err
+}
+
+// syncItem syncs and checks that server has the last version of the item
+func (m *mediator) syncItem(ctx context.Context, id string) error {
+	if err := m.Sync(ctx); err != nil {
+		return err
+	}
+	if result, ok := m.Result(id); ok && result.Status != models.StatusApplied && result.Status != models.StatusStale {
+		return fmt.Errorf("%w: %s", ErrNotSynced, result.Status)
+	}
+	return nil
+}
+
+// Shares returns the users the item is shared with
+func (m *mediator) Shares(ctx context.Context, id string) ([]models.Share, error) {
+	return m.transport.Shares(ctx, auth.CurrentUser.SessionID, id)
+}
+
+// Revoke revokes the share of the item with the user
+// the item gets a new key, so the user can't read its next versions with the key it had,
+// and the item is shared again with the rest of the users
+func (m *mediator) Revoke(ctx context.Context, id, userID string) error {
+	shares, err := m.transport.Shares(ctx, auth.CurrentUser.SessionID, id)
+	if err != nil {
+		return err
+	}
+	err = m.transport.Unshare(ctx, auth.CurrentUser.SessionID, id, userID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	key, err := m.storage.Rekey(id)
+	if err != nil {
+		return err
+	}
+	if err = m.syncItem(ctx, id); err != nil {
+		return err
+	}
+	for _, share := range shares {
+		if share.UserID == userID {
+			continue
+		}
+		if err = m.share(ctx, id, key, share.UserID, share.Permission); err != nil {
+			return fmt.Errorf("failed to share again with %s: %w", share.UserID, err)
+		}
+	}
+	return nil
+}
+
+// Shared returns items other users shared with the user with their keys opened by the private key
+// and their names decrypted, item whose key or name can't be opened is skipped
+func (m *mediator) Shared(ctx context.Context) ([]SharedItem, error) {
+	keys, err := m.keyPair(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items, err := m.transport.Shared(ctx, auth.CurrentUser.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]SharedItem, 0, len(items))
+	for _, item := range items {
+		key, err := utils.OpenKey(item.Share.Key, keys.private)
+		if err != nil {
+			log.Err(err).Str("item", item.Share.ItemID).Str("owner", item.Share.OwnerID).
+				Msg("failed to open key of shared item")
+			continue
+		}
+		if item.Data, err = models.OpenLabel(item.Data, key); err != nil {
+			log.Err(err).Str("item", item.Share.ItemID).Str("owner", item.Share.OwnerID).
+				Msg("failed to decrypt label of shared item")
+			continue
+		}
+		result = append(result, SharedItem{SharedItem: item, Key: key})
+	}
+	return result, nil
+}
+
+// UpdateShared encrypts the data with the key of the shared item and sends it to server as its next version
+// server keeps name, type, folder and collection of the item, the label is sealed anyway so it never leaves in plaintext
+func (m *mediator) UpdateShared(ctx context.Context, item SharedItem, data models.BasicData) (models.SyncResult, error) {
+	encrypted, err := data.EncryptAll(item.Key)
+	if err != nil {
+		return models.SyncResult{}, err
+	}
+	wrapper := item.Data
+	if wrapper, err = models.SealLabel(wrapper, item.Key); err != nil {
+		return models.SyncResult{}, err
+	}
+	wrapper.Data = encrypted
+	wrapper.UpdatedAt = time.Now().Unix()
+	return m.transport.UpdateShared(ctx, auth.CurrentUser.SessionID, wrapper)
+}
@@ -0,0 +1,183 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gynshu-one/goph-keeper/client/auth"
+	"github.com/gynshu-one/goph-keeper/client/storage"
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/zalando/go-keyring"
+)
+
+// shareTransport keeps keys, items and shares of all users in memory like server does
+// the user of the request is auth.CurrentUser
+type shareTransport struct {
+	Transport
+	keys    map[string]models.UserKeys
+	items   map[string]models.DataWrapper
+	shares  map[string]models.Share
+	setKeys int
+}
+
+func newShareTransport() *shareTransport {
+	return &shareTransport{
+		keys:   make(map[string]models.UserKeys),
+		items:  make(map[string]models.DataWrapper),
+		shares: make(map[string]models.Share),
+	}
+}
+
+func (t *shareTransport) Sync(_ context.Context, _ string, cursor int64, _ bool, _ string, sent []models.DataWrapper) (*models.SyncResponse, models.Usage, error) {
+	response := &models.SyncResponse{Cursor: cursor}
+	for _, item := range sent {
+		item.Revision++
+		t.items[item.ID] = item
+		response.Results = append(response.Results, models.SyncResult{ID: item.ID, Status: models.StatusApplied})
+	}
+	return response, models.Usage{}, nil
+}
+
+func (t *shareTransport) Changes(_ context.Context, _ string, cursor int64, _ bool, handle func(line models.ChangeLine) error) error {
+	return handle(models.ChangeLine{Checkpoint: &models.Checkpoint{Cursor: cursor, Done: true}})
+}
+
+func (t *shareTransport) Keys(_ context.Context, _ string) (models.UserKeys, error) {
+	keys, ok := t.keys[auth.CurrentUser.Username]
+	if !ok {
+		return keys, &Error{Kind: ErrNotFound}
+	}
+	return keys, nil
+}
+
+func (t *shareTransport) SetKeys(_ context.Context, _ string, keys models.UserKeys) error {
+	t.setKeys++
+	t.keys[auth.CurrentUser.Username] = keys
+	return nil
+}
+
+func (t *shareTransport) PublicKey(_ context.Context, _, email string) ([]byte, error) {
+	keys, ok := t.keys[email]
+	if !ok {
+		return nil, &Error{Kind: ErrNotFound}
+	}
+	return keys.PublicKey, nil
+}
+
+func (t *shareTransport) Share(_ context.Context, _ string, share models.Share) (models.Share, error) {
+	share.OwnerID = auth.CurrentUser.Username
+	t.shares[share.ItemID+":"+share.UserID] = share
+	return share, nil
+}
+
+func (t *shareTransport) Shares(_ context.Context, _, itemID string) (shares []models.Share, err error) {
+	for _, share := range t.shares {
+		if share.ItemID == itemID {
+			shares = append(shares, share)
+		}
+	}
+	return shares, nil
+}
+
+func (t *shareTransport) Unshare(_ context.Context, _, itemID, userID string) error {
+	delete(t.shares, itemID+":"+userID)
+	return nil
+}
+
+func (t *shareTransport) Shared(_ context.Context, _ string) (items []models.SharedItem, err error) {
+	for _, share := range t.shares {
+		if share.UserID == auth.CurrentUser.Username {
+			items = append(items, models.SharedItem{Share: share, Data: t.items[share.ItemID]})
+		}
+	}
+	return items, nil
+}
+
+// signIn switches the current user to the user with its own secret
+func signIn(username string) {
+	auth.CurrentUser.Username = username
+	auth.CurrentUser.SessionID = username
+	auth.SetSecret(username + "_secret")
+}
+
+func TestSharing(t *testing.T) {
+	keyring.MockInit()
+	ctx := context.Background()
+	transport := newShareTransport()
+	users := make(map[string]*mediator)
+	for _, username := range []string{"bob", "carol", "alice"} {
+		signIn(username)
+		users[username] = newMediatorWith(storage.NewStorage(), transport)
+		if err := users[username].SetupKeys(ctx); err != nil {
+			t.Fatalf("SetupKeys of %s failed with error: %v", username, err)
+		}
+	}
+	// Keys are read once and made once
+	if err := users["alice"].SetupKeys(ctx); err != nil || transport.setKeys != 3 {
+		t.Errorf("Expected 3 key pairs, got %d and %v", transport.setKeys, err)
+	}
+	if string(transport.keys["alice"].PrivateKey) == string(users["alice"].keys.private) {
+		t.Error("Private key is stored without encryption")
+	}
+	// Private key is never stored encrypted with an empty secret
+	auth.CurrentUser.Username = "dave"
+	if err := newMediatorWith(storage.NewStorage(), transport).SetupKeys(ctx); !errors.Is(err, auth.ErrNoSecret) ||
+		transport.setKeys != 3 {
+		t.Errorf("Expected %v and no new key pair, got %v and %d key pairs", auth.ErrNoSecret, err, transport.setKeys)
+	}
+	signIn("alice")
+
+	// Item encrypted with the secret gets its own key when it's shared
+	alice := users["alice"]
+	login := &models.Login{Username: "alice", Password: "pass"}
+	if err := alice.storage.AddEncrypt(login, models.DataWrapper{ID: "item", Type: models.LoginType, Name: "site"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, username := range []string{"bob", "carol"} {
+		if err := alice.ShareItem(ctx, "item", username, models.PermissionWrite); err != nil {
+			t.Fatalf("ShareItem failed with error: %v", err)
+		}
+	}
+	if len(transport.items["item"].Key) == 0 {
+		t.Fatal("Shared item was synced without its key")
+	}
+	if item := transport.items["item"]; item.LabelVersion != models.LabelSealed || item.Type != models.SealedType {
+		t.Errorf("Label of shared item is sent in plaintext: %s %s", item.Type, item.Name)
+	}
+	if _, _, err := alice.storage.FindDecrypt("item"); err != nil {
+		t.Errorf("Owner can't decrypt shared item: %v", err)
+	}
+
+	// Bob opens the key and edits the item
+	signIn("bob")
+	shared, err := users["bob"].Shared(ctx)
+	if err != nil || len(shared) != 1 || shared[0].Data.Name != "site" {
+		t.Fatalf("Expected 1 shared item named site, got %+v and %v", shared, err)
+	}
+	data, err := storage.Open(shared[0].Data, shared[0].Key)
+	if err != nil || data.(models.Login).Password != "pass" {
+		t.Fatalf("Shared item is not opened: %+v, %v", data, err)
+	}
+	oldKey := shared[0].Key
+
+	// Revoked user can't read the next version, the rest of users can
+	signIn("alice")
+	if err = alice.Revoke(ctx, "item", "bob"); err != nil {
+		t.Fatalf("Revoke failed with error: %v", err)
+	}
+	if len(transport.shares) != 1 {
+		t.Errorf("Expected 1 share left, got %+v", transport.shares)
+	}
+	if _, err = storage.Open(transport.items["item"], oldKey); err == nil {
+		t.Error("Item is opened with the key of revoked user")
+	}
+	signIn("carol")
+	shared, err = users["carol"].Shared(ctx)
+	if err != nil || len(shared) != 1 {
+		t.Fatalf("Expected 1 shared item, got %d and %v", len(shared), err)
+	}
+	if _, err = storage.Open(shared[0].Data, shared[0].Key); err != nil {
+		t.Errorf("Item is not opened with the rotated key: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package sync
+
+import (
+	"context"
+
+	"github.com/gynshu-one/goph-keeper/client/config"
+	"github.com/gynshu-one/goph-keeper/common/models"
+)
+
+// Transport carries requests of Mediator to server
+// it's implemented for REST API with resty and for gRPC API
+type Transport interface {
+	// SignUp registers the user and returns id of its new session
+	SignUp(ctx context.Context, username, password string) (string, error)
+	// SignIn returns id of a new session of the user
+	SignIn(ctx context.Context, username, password string) (string, error)
+	// Sync sends items changed locally and the cursor of the last sync
+	// returns nil response if server had nothing to send
+	// pushOnly response has no changes of other devices, they are read with Changes
+	// request repeated with the same key gets the same response, so it's safe to retry
+	Sync(ctx context.Context, sessionID string, cursor int64, pushOnly bool, key string, sent []models.DataWrapper) (*models.SyncResponse, models.Usage, error)
+	// Changes calls handle for every line of changes stream since the cursor
+	// resume continues interrupted download of all data from its checkpoint
+	Changes(ctx context.Context, sessionID string, cursor int64, resume bool, handle func(line models.ChangeLine) error) error
+	// History returns previous versions of the item with the given id, newest first, without data
+	History(ctx context.Context, sessionID, id string) ([]models.DataWrapper, error)
+	// Revision returns previous version of the item with its encrypted data
+	Revision(ctx context.Context, sessionID, id string, revision int64) (models.DataWrapper, error)
+	// Events calls handle for every change of user's data until the stream is closed or ctx is done
+	Events(ctx context.Context, sessionID string, handle func(event models.ChangeEvent)) error
+	// CreateBlob starts upload of the blob or returns it with the offset its upload continues from
+	CreateBlob(ctx context.Context, sessionID string, blob models.Blob) (models.Blob, error)
+	// UploadBlob writes the chunk of the blob at the offset and returns the blob with new offset
+	// chunk that is not at the end of uploaded content fails with ErrBlobOffset
+	UploadBlob(ctx context.Context, sessionID, id string, offset int64, chunk []byte) (models.Blob, error)
+	// DownloadBlob returns up to models.BlobChunkSize bytes of the uploaded blob from the offset
+	DownloadBlob(ctx context.Context, sessionID, id string, offset int64) ([]byte, error)
+	// Keys returns keys of the user, user without keys gets ErrNotFound
+	Keys(ctx context.Context, sessionID string) (models.UserKeys, error)
+	// SetKeys sets keys of the user, they are set once
+	SetKeys(ctx context.Context, sessionID string, keys models.UserKeys) error
+	// PublicKey returns public key of the user with the email
+	PublicKey(ctx context.Context, sessionID, email string) ([]byte, error)
+	// Share shares the item with another user or replaces the key and permission of its share
+	Share(ctx context.Context, sessionID string, share models.Share) (models.Share, error)
+	// Shares returns shares of the item of the user
+	Shares(ctx context.Context, sessionID, itemID string) ([]models.Share, error)
+	// Unshare revokes the share of the item with the user
+	Unshare(ctx context.Context, sessionID, itemID, userID string) error
+	// Shared returns items other users shared with the user
+	Shared(ctx context.Context, sessionID string) ([]models.SharedItem, error)
+	// UpdateShared sends an edit of the item shared with the user
+	UpdateShared(ctx context.Context, sessionID string, data models.DataWrapper) (models.SyncResult, error)
+	// CreateOrg creates an organization owned by the user with the org key sealed to it
+	CreateOrg(ctx context.Context, sessionID string, membership models.Membership) (models.Membership, error)
+	// Orgs returns organizations of the user including the ones it's invited to
+	Orgs(ctx context.Context, sessionID string) ([]models.Membership, error)
+	// Members returns members of the organization without their keys
+	Members(ctx context.Context, sessionID, orgID string) ([]models.Member, error)
+	// SetMember invites the user to the organization or changes its role
+	SetMember(ctx context.Context, sessionID string, member models.Member) (models.Member, error)
+	// RemoveMember removes the user from the organization
+	RemoveMember(ctx context.Context, sessionID, orgID, userID string) error
+	// AcceptInvite accepts the invitation of the user to the organization
+	AcceptInvite(ctx context.Context, sessionID, orgID string) error
+	// Collections returns collections of the organization
+	Collections(ctx context.Context, sessionID, orgID string) ([]models.Collection, error)
+	// SetCollection adds the collection to the organization or renames it
+	SetCollection(ctx context.Context, sessionID string, collection models.Collection) (models.Collection, error)
+	// OrgData returns items of the organization
+	OrgData(ctx context.Context, sessionID, orgID string) (models.OrgData, error)
+	// WriteOrgData sends items of the organization, items encrypted with an old org key fail with ErrConflict
+	WriteOrgData(ctx context.Context, sessionID string, data models.OrgData) ([]models.SyncResult, error)
+	// RotateOrgKey replaces the org key, rotation made from old items fails with ErrConflict
+	RotateOrgKey(ctx context.Context, sessionID string, rotation models.KeyRotation) ([]models.SyncResult, error)
+	// EmergencyContacts returns trusted contacts of the user without their keys
+	EmergencyContacts(ctx context.Context, sessionID string) ([]models.EmergencyAccess, error)
+	// SetEmergencyContact adds the trusted contact of the user or changes its waiting period
+	SetEmergencyContact(ctx context.Context, sessionID string, access models.EmergencyAccess) (models.EmergencyAccess, error)
+	// RemoveEmergencyContact removes the trusted contact of the user
+	RemoveEmergencyContact(ctx context.Context, sessionID, granteeID string) error
+	// RejectEmergency rejects the request of the trusted contact, access that wasn't requested fails with ErrConflict
+	RejectEmergency(ctx context.Context, sessionID, granteeID string) (models.EmergencyAccess, error)
+	// EmergencyGrantors returns users that trust the user, keys are sent only when access is granted
+	EmergencyGrantors(ctx context.Context, sessionID string) ([]models.EmergencyAccess, error)
+	// RequestEmergency requests access to the vault of the grantor
+	RequestEmergency(ctx context.Context, sessionID, grantorID string) (models.EmergencyAccess, error)
+	// EmergencyVault returns the vault of the grantor, it fails with ErrRequest until the waiting period is over
+	EmergencyVault(ctx context.Context, sessionID, grantorID string) (models.EmergencyVault, error)
+	// Sends returns sends of the user without their data
+	Sends(ctx context.Context, sessionID string) ([]models.Send, error)
+	// CreateSend stores the send with the encrypted secret, server chooses its id
+	CreateSend(ctx context.Context, sessionID string, send models.Send) (models.Send, error)
+	// DeleteSend removes the send of the user
+	DeleteSend(ctx context.Context, sessionID, id string) error
+	// ReceiveSend returns the send with the id and counts the view, it needs no session
+	// expired or viewed send fails with ErrNotFound
+	ReceiveSend(ctx context.Context, id string) (models.Send, error)
+}
+
+// newTransport returns Transport chosen in config
+func newTransport() Transport {
+	if config.GetConfig().Transport == config.TransportGRPC {
+		return newGRPCTransport(config.GetConfig().GrpcAddr)
+	}
+	return newRESTTransport("https://" + config.GetConfig().ServerIP)
+}
@@ -1,12 +1,9 @@
 package models
 
-import (
-	"encoding/json"
-	"github.com/gynshu-one/goph-keeper/common/utils"
-)
-
 // BankCard is a struct for bank card
 type BankCard struct {
+	// Meta is tags and favorite flag of the item
+	Meta `bson:",inline"`
 	// Info is the additional info about the card
 	Info string `json:"info" bson:"info"`
 	// CardType is the type of card such as Visa, MasterCard, etc.
@@ -23,18 +20,10 @@ type BankCard struct {
 
 // EncryptAll encrypts all sensitive fields
 func (data *BankCard) EncryptAll(passphrase string) (encryptedData []byte, err error) {
-	marshaled, err := json.Marshal(data)
-	if err != nil {
-		return nil, err
-	}
-	return utils.EncryptData(marshaled, passphrase)
+	return seal(data, passphrase)
 }
 
 // DecryptAll decrypts all sensitive fields
 func (data *BankCard) DecryptAll(passphrase string, encrypteData []byte) error {
-	decrypted, err := utils.DecryptData(encrypteData, passphrase)
-	if err != nil {
-		return err
-	}
-	return json.Unmarshal(decrypted, &data)
+	return unseal(passphrase, encrypteData, data)
 }
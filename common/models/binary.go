@@ -1,32 +1,32 @@
 package models
 
-import (
-	"encoding/json"
-	"github.com/gynshu-one/goph-keeper/common/utils"
-)
-
 // Binary is a struct for binary data
 type Binary struct {
+	// Meta is tags and favorite flag of the item
+	Meta `bson:",inline"`
 	// Info is the additional info about the binary
 	Info string `json:"info" bson:"info"`
-	// Binary is the binary data
+	// Binary is the binary data, it's empty if the file is kept in Blob
 	Binary []byte `json:"binary" bson:"binary"`
+	// Blob is the reference to the file uploaded apart from the item
+	// items created before blobs were added keep the file in Binary
+	Blob *BlobRef `json:"blob,omitempty" bson:"blob,omitempty"`
+}
+
+// Size returns the size of the file
+func (data *Binary) Size() int64 {
+	if data.Blob != nil {
+		return data.Blob.Size
+	}
+	return int64(len(data.Binary))
 }
 
 // EncryptAll encrypts all sensitive data
 func (data *Binary) EncryptAll(passphrase string) (encryptedData []byte, err error) {
-	marshaled, err := json.Marshal(data)
-	if err != nil {
-		return nil, err
-	}
-	return utils.EncryptData(marshaled, passphrase)
+	return seal(data, passphrase)
 }
 
 // DecryptAll decrypts all sensitive data
 func (data *Binary) DecryptAll(passphrase string, encrypteData []byte) error {
-	decrypted, err := utils.DecryptData(encrypteData, passphrase)
-	if err != nil {
-		return err
-	}
-	return json.Unmarshal(decrypted, &data)
+	return unseal(passphrase, encrypteData, data)
 }
@@ -0,0 +1,44 @@
+package models
+
+// Blob is the content of a file attached to an item, it's stored by server apart from items
+// and uploaded in chunks, so interrupted upload continues from Offset
+// content is encrypted by client, server only checks its size and hash
+type Blob struct {
+	// ID is the unique identifier of the blob chosen by client
+	ID      string `json:"id"`
+	OwnerID string `json:"owner_id"`
+	// Size is the size of the whole content in bytes
+	Size int64 `json:"size"`
+	// Hash is hex encoded sha256 of the whole content, server verifies it when the last chunk is written
+	Hash string `json:"hash"`
+	// Offset is how many bytes are uploaded, blob can be downloaded when it reaches Size
+	Offset    int64 `json:"offset"`
+	CreatedAt int64 `json:"created_at"`
+}
+
+// Complete tells whether all content of the blob is uploaded
+func (b Blob) Complete() bool {
+	return b.Offset == b.Size
+}
+
+// BlobRef is a reference to the blob kept in encrypted data of the item
+type BlobRef struct {
+	ID string `json:"id"`
+	// Size is the size of the file before encryption
+	Size int64 `json:"size"`
+	// Hash is hex encoded sha256 of the uploaded encrypted content,
+	// client checks the downloaded content against it, so server can't replace it
+	Hash string `json:"hash"`
+	// Key is the key the file is encrypted with, so users the item is shared with can decrypt it
+	// files attached before it was added are encrypted with the secret of the owner and have no key
+	Key string `json:"key,omitempty"`
+}
+
+// BlobChunkSize is the maximum size of a chunk in one upload or download request
+const BlobChunkSize = 1 << 20
+
+// UploadOffsetHeader is the header of blob upload request with the offset the chunk is written at
+const UploadOffsetHeader = "Upload-Offset"
+
+// BlobHashHeader is the header of blob download response with Blob.Hash
+const BlobHashHeader = "X-Blob-Hash"
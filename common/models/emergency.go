@@ -0,0 +1,56 @@
+package models
+
+// EmergencyStatus is the state of emergency access of the contact to the vault of the user
+type EmergencyStatus string
+
+const (
+	// EmergencyIdle is the contact that didn't request access
+	EmergencyIdle EmergencyStatus = "idle"
+	// EmergencyRequested is the request the user can reject until the waiting period is over
+	EmergencyRequested EmergencyStatus = "requested"
+	// EmergencyRejected is the request the user rejected, the contact can request again
+	EmergencyRejected EmergencyStatus = "rejected"
+	// EmergencyGranted is the request whose waiting period is over, the contact gets the vault key then
+	// it is never stored, see EmergencyAccess.StatusAt
+	EmergencyGranted EmergencyStatus = "granted"
+)
+
+// daySeconds is the length of a day of the waiting period
+const daySeconds = 24 * 60 * 60
+
+// EmergencyAccess lets the trusted contact get into the vault of the grantor
+// if the grantor doesn't reject the request of the contact during the waiting period
+type EmergencyAccess struct {
+	// GrantorID is the email of the user whose vault is accessed
+	GrantorID string `json:"grantor_id" bson:"grantor_id"`
+	// GranteeID is the email of the trusted contact
+	GranteeID string `json:"grantee_id" bson:"grantee_id"`
+	// WaitDays is how many days after the request the grantor can reject it
+	WaitDays int64 `json:"wait_days" bson:"wait_days"`
+	// Key is the vault key of the grantor sealed to the public key of the contact
+	// server sends it to the contact only when access is granted
+	Key         []byte          `json:"key,omitempty" bson:"key"`
+	Status      EmergencyStatus `json:"status" bson:"status"`
+	RequestedAt int64           `json:"requested_at,omitempty" bson:"requested_at"`
+	CreatedAt   int64           `json:"created_at" bson:"created_at"`
+}
+
+// GrantedAt returns unix time access is granted at if the request is not rejected
+func (a EmergencyAccess) GrantedAt() int64 {
+	return a.RequestedAt + a.WaitDays*daySeconds
+}
+
+// StatusAt returns the status at the unix time, requested access becomes granted when the waiting period is over
+func (a EmergencyAccess) StatusAt(now int64) EmergencyStatus {
+	if a.Status == EmergencyRequested && now >= a.GrantedAt() {
+		return EmergencyGranted
+	}
+	return a.Status
+}
+
+// EmergencyVault is the vault of the grantor opened to the trusted contact
+// items are encrypted with the vault key or with their own keys encrypted with it
+type EmergencyVault struct {
+	Access EmergencyAccess `json:"access"`
+	Data   []DataWrapper   `json:"data"`
+}
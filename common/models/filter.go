@@ -0,0 +1,20 @@
+package models
+
+// SavedFilter is a filter of items the user saved to open it as a quick list, e.g. "favorites tag:prod"
+// it is stored as any other item, so its query is encrypted and it's synced the same way
+type SavedFilter struct {
+	// Name is the name of the quick list
+	Name string `json:"name" bson:"name"`
+	// Query is the filter, see client/storage ParseFilter
+	Query string `json:"query" bson:"query"`
+}
+
+// EncryptAll encrypts all sensitive fields
+func (data *SavedFilter) EncryptAll(passphrase string) (encryptedData []byte, err error) {
+	return seal(data, passphrase)
+}
+
+// DecryptAll decrypts all sensitive fields
+func (data *SavedFilter) DecryptAll(passphrase string, encrypteData []byte) error {
+	return unseal(passphrase, encrypteData, data)
+}
@@ -0,0 +1,18 @@
+package models
+
+// Folder groups items of the user, folders are nested by FolderID of their DataWrapper
+// it is stored as any other item, so its name is encrypted and it's synced the same way
+type Folder struct {
+	// Name is the name of the folder
+	Name string `json:"name" bson:"name"`
+}
+
+// EncryptAll encrypts all sensitive fields
+func (data *Folder) EncryptAll(passphrase string) (encryptedData []byte, err error) {
+	return seal(data, passphrase)
+}
+
+// DecryptAll decrypts all sensitive fields
+func (data *Folder) DecryptAll(passphrase string, encrypteData []byte) error {
+	return unseal(passphrase, encrypteData, data)
+}
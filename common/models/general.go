@@ -19,15 +19,36 @@ type DataWrapper struct {
 	ID string `json:"id" bson:"_id"`
 	// OwnerID is the user who owns this data
 	OwnerID string `json:"owner_id" bson:"owner_id"`
-	// Type is the type of the data such as ArbitraryTextType, BankCardType, BinaryType, LoginType
-	Type      string `json:"type" bson:"type"`
+	// Type is the type of the data such as ArbitraryTextType, BankCardType, BinaryType, LoginType, SSHKeyType, FolderType, FilterType
+	// it's SealedType when the label is sealed
+	Type string `json:"type" bson:"type"`
+	// Name is the name of the item given by the user, sealed together with Type when the label is sealed
 	Name      string `json:"name" bson:"name"`
 	UpdatedAt int64  `json:"updated_at" bson:"updated_at"`
 	CreatedAt int64  `json:"created_at" bson:"created_at"`
 	DeletedAt int64  `json:"deleted_at" bson:"deleted_at"`
+	// Revision is the version number of the data assigned by the server
+	// it starts from 1 and is incremented on every accepted update
+	// client sends the revision it edited, update of not the current revision is a conflict
+	Revision int64 `json:"revision" bson:"revision"`
+	// Seq is the position of the last change of the data in the owner's change feed assigned by the server
+	// it grows with every accepted change of any owner's data
+	Seq int64 `json:"seq" bson:"seq"`
 	// This is the actual data that is stored in the database
 	// Encrypted with user's secret
 	Data []byte `json:"data" bson:"data"`
+	// Blobs are ids of blobs the encrypted data refers to,
+	// server accepts the data only if they are uploaded
+	Blobs []string `json:"blobs,omitempty" bson:"blobs,omitempty"`
+	// Key is the key Data is encrypted with, encrypted with owner's secret
+	// item gets its own key when it's shared for the first time, Data of item without Key is encrypted with the secret
+	Key []byte `json:"key,omitempty" bson:"key,omitempty"`
+	// CollectionID is the collection the item of an organization is in, empty for items of users
+	CollectionID string `json:"collection_id,omitempty" bson:"collection_id,omitempty"`
+	// FolderID is the folder of the user the item or folder is in, empty for the root
+	FolderID string `json:"folder_id,omitempty" bson:"folder_id,omitempty"`
+	// LabelVersion tells how Name and Type are stored, LabelPlain or LabelSealed
+	LabelVersion int32 `json:"label_version,omitempty" bson:"label_version,omitempty"`
 }
 
 const (
@@ -35,4 +56,7 @@ const (
 	BankCardType      = "bank_card"
 	BinaryType        = "binary"
 	LoginType         = "login"
+	FolderType        = "folder"
+	FilterType        = "filter"
+	SSHKeyType        = "ssh_key"
 )
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// Versions of DataWrapper.LabelVersion
+const (
+	// LabelPlain is the version of items saved before labels were encrypted, Name and Type are plaintext
+	LabelPlain = 0
+	// LabelSealed is the version of items whose Name and Type are encrypted together into Name
+	// with the passphrase their data is encrypted with, Type is SealedType then
+	LabelSealed = 1
+)
+
+// SealedType is Type of items with sealed label, server doesn't know what kind of data they hold
+const SealedType = "sealed"
+
+// ErrUnknownLabel is returned for labels of versions newer than this version knows
+var ErrUnknownLabel = errors.New("unknown label version")
+
+// label is Name and Type of the item as they are sealed into Name
+type label struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// SealLabel encrypts Name and Type of the wrapper with the passphrase its data is encrypted with
+// they are kept in Name as base64 text, so server stores it as any other name, wrapper with sealed label is returned as is
+func SealLabel(wrapper DataWrapper, passphrase string) (DataWrapper, error) {
+	if wrapper.LabelVersion != LabelPlain {
+		return wrapper, nil
+	}
+	sealed, err := seal(label{Name: wrapper.Name, Type: wrapper.Type}, passphrase)
+	if err != nil {
+		return wrapper, err
+	}
+	wrapper.Name = base64.StdEncoding.EncodeToString(sealed)
+	wrapper.Type = SealedType
+	wrapper.LabelVersion = LabelSealed
+	return wrapper, nil
+}
+
+// OpenLabel decrypts Name and Type of the wrapper sealed by SealLabel, wrapper with plaintext label is returned as is
+func OpenLabel(wrapper DataWrapper, passphrase string) (DataWrapper, error) {
+	switch wrapper.LabelVersion {
+	case LabelPlain:
+		return wrapper, nil
+	case LabelSealed:
+	default:
+		return wrapper, fmt.Errorf("%w: %d", ErrUnknownLabel, wrapper.LabelVersion)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(wrapper.Name)
+	if err != nil {
+		return wrapper, fmt.Errorf("failed to decode label: %w", err)
+	}
+	var opened label
+	if err = unseal(passphrase, sealed, &opened); err != nil {
+		return wrapper, fmt.Errorf("failed to decrypt label: %w", err)
+	}
+	wrapper.Name, wrapper.Type = opened.Name, opened.Type
+	wrapper.LabelVersion = LabelPlain
+	return wrapper, nil
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSealLabel(t *testing.T) {
+	passphrase := "item key"
+	wrapper := DataWrapper{ID: "1", Type: LoginType, Name: "Company VPN"}
+
+	sealed, err := SealLabel(wrapper, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sealed.Type != SealedType || sealed.LabelVersion != LabelSealed || strings.Contains(sealed.Name, "VPN") {
+		t.Fatalf("Label is not sealed: %+v", sealed)
+	}
+	if again, err := SealLabel(sealed, passphrase); err != nil || again.Name != sealed.Name {
+		t.Errorf("Sealed label was sealed again: %+v, %v", again, err)
+	}
+
+	opened, err := OpenLabel(sealed, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened.Type != LoginType || opened.Name != "Company VPN" || opened.LabelVersion != LabelPlain {
+		t.Errorf("Unexpected opened label %+v", opened)
+	}
+	if _, err = OpenLabel(sealed, "another key"); err == nil {
+		t.Errorf("Label was opened with another key")
+	}
+
+	// Plaintext name is never taken for a sealed one, whatever it looks like
+	plain := DataWrapper{Type: BankCardType, Name: "enc:Personal Visa"}
+	if opened, err = OpenLabel(plain, passphrase); err != nil || opened.Name != plain.Name || opened.Type != plain.Type {
+		t.Errorf("Plaintext label was changed: %+v, %v", opened, err)
+	}
+
+	// Label sealed by another user may be anything, short one is an error rather than a panic
+	truncated := sealed
+	truncated.Name = base64.StdEncoding.EncodeToString([]byte("abc"))
+	if _, err = OpenLabel(truncated, passphrase); err == nil {
+		t.Errorf("Truncated label was opened")
+	}
+
+	sealed.LabelVersion = LabelSealed + 1
+	if _, err = OpenLabel(sealed, passphrase); !errors.Is(err, ErrUnknownLabel) {
+		t.Errorf("Expected %v, got %v", ErrUnknownLabel, err)
+	}
+}
@@ -1,17 +1,16 @@
 package models
 
 import (
-	"encoding/json"
 	"time"
 
-	"github.com/gynshu-one/goph-keeper/common/utils"
-
 	"github.com/pquerna/otp/totp"
 )
 
 // Login is the model for a login
 // All changes should be done through methods to ensure data consistency and update time
 type Login struct {
+	// Meta is tags and favorite flag of the item
+	Meta `bson:",inline"`
 	// Info is the additional info about the login
 	Info string `json:"info" bson:"info"`
 	// Username is the username
@@ -26,20 +25,12 @@ type Login struct {
 
 // EncryptAll encrypts all sensitive data
 func (data *Login) EncryptAll(passphrase string) (encryptedData []byte, err error) {
-	marshaled, err := json.Marshal(data)
-	if err != nil {
-		return nil, err
-	}
-	return utils.EncryptData(marshaled, passphrase)
+	return seal(data, passphrase)
 }
 
 // DecryptAll decrypts all sensitive data
 func (data *Login) DecryptAll(passphrase string, encrypteData []byte) error {
-	decrypted, err := utils.DecryptData(encrypteData, passphrase)
-	if err != nil {
-		return err
-	}
-	return json.Unmarshal(decrypted, &data)
+	return unseal(passphrase, encrypteData, data)
 }
 
 // RegisterOneTime registers a new one-time password
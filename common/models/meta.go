@@ -0,0 +1,52 @@
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// Meta is what the user labels an item with, it's embedded into every item type
+// so it is encrypted with the item and server can't read it
+type Meta struct {
+	// Tags are free-form labels of the item, lower case and sorted
+	Tags []string `json:"tags,omitempty" bson:"tags,omitempty"`
+	// Favorite marks items the user wants at hand
+	Favorite bool `json:"favorite,omitempty" bson:"favorite,omitempty"`
+}
+
+// Labeled is implemented by item types that embed Meta
+type Labeled interface {
+	Labels() Meta
+}
+
+// Labels returns tags and favorite flag of the item
+func (m Meta) Labels() Meta {
+	return m
+}
+
+// HasTag tells whether the item is labeled with the tag, case is ignored
+func (m Meta) HasTag(tag string) bool {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	for _, t := range m.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTags returns tags from comma separated input, e.g. "prod, Work,prod" is [prod work]
+func ParseTags(in string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range strings.Split(in, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
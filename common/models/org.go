@@ -0,0 +1,88 @@
+package models
+
+// Role is what a member can do in the organization
+type Role string
+
+const (
+	// RoleOwner manages the organization and its admins, the user who created it is its only owner
+	RoleOwner Role = "owner"
+	// RoleAdmin manages members and collections and rotates the org key
+	RoleAdmin Role = "admin"
+	// RoleMember reads and edits items of the organization
+	RoleMember Role = "member"
+	// RoleReadOnly only reads items of the organization
+	RoleReadOnly Role = "read_only"
+)
+
+// Valid tells whether the role is known
+func (r Role) Valid() bool {
+	return r == RoleOwner || r == RoleAdmin || r == RoleMember || r == RoleReadOnly
+}
+
+// CanManage tells whether the role lets invite and remove members, add collections and rotate the org key
+func (r Role) CanManage() bool {
+	return r == RoleOwner || r == RoleAdmin
+}
+
+// CanWrite tells whether the role lets edit items of the organization
+func (r Role) CanWrite() bool {
+	return r == RoleOwner || r == RoleAdmin || r == RoleMember
+}
+
+// Organization is a vault shared by its members
+// items of the organization are DataWrapper with OwnerID set to ID of the organization,
+// they are encrypted with the org key which every member has sealed to its public key
+type Organization struct {
+	ID   string `json:"id" bson:"_id"`
+	Name string `json:"name" bson:"name"`
+	// KeyVersion is the version of the org key, it's incremented every time the key is rotated
+	KeyVersion int64 `json:"key_version" bson:"key_version"`
+	CreatedAt  int64 `json:"created_at" bson:"created_at"`
+}
+
+// Member is a user of the organization
+type Member struct {
+	OrgID string `json:"org_id" bson:"org_id"`
+	// UserID is the email of the user
+	UserID string `json:"user_id" bson:"user_id"`
+	Role   Role   `json:"role" bson:"role"`
+	// Key is the org key sealed to the public key of the user
+	Key []byte `json:"key" bson:"key"`
+	// KeyVersion is the version of the org key Key is
+	KeyVersion int64 `json:"key_version" bson:"key_version"`
+	// Accepted is set when the user accepts the invitation, data of the organization is available after that
+	Accepted  bool  `json:"accepted" bson:"accepted"`
+	CreatedAt int64 `json:"created_at" bson:"created_at"`
+}
+
+// Membership is the organization together with the membership of the user in it
+type Membership struct {
+	Organization Organization `json:"organization"`
+	Member       Member       `json:"member"`
+}
+
+// Collection groups items of the organization, every item of the organization is in one
+type Collection struct {
+	ID        string `json:"id" bson:"_id"`
+	OrgID     string `json:"org_id" bson:"org_id"`
+	Name      string `json:"name" bson:"name"`
+	CreatedAt int64  `json:"created_at" bson:"created_at"`
+}
+
+// OrgData is the data of the organization encrypted with the org key of KeyVersion
+// it's returned when the data is read and sent when it's changed,
+// data encrypted with another version of the key is rejected
+type OrgData struct {
+	OrgID      string        `json:"org_id"`
+	KeyVersion int64         `json:"key_version"`
+	Data       []DataWrapper `json:"data"`
+}
+
+// KeyRotation replaces the org key with the new one of KeyVersion
+// every member gets the new key in Members and every item of the organization is encrypted with it in Data
+type KeyRotation struct {
+	OrgID      string        `json:"org_id"`
+	KeyVersion int64         `json:"key_version"`
+	Members    []Member      `json:"members"`
+	Data       []DataWrapper `json:"data"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gynshu-one/goph-keeper/common/utils"
+)
+
+// zstdFormat marks plaintext compressed with zstd before encryption
+// plaintext without the mark is json, it always starts with '{'
+// so data encrypted before compression was added is still read
+const zstdFormat byte = 1
+
+// minCompressSize is the size of marshaled data compression is tried from,
+// smaller data doesn't get smaller
+const minCompressSize = 128
+
+// seal marshals data to json, compresses it if that makes it smaller and encrypts it with the passphrase
+// encrypted data is incompressible, so compression has to happen before encryption
+func seal(data any, passphrase string) ([]byte, error) {
+	plaintext, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(plaintext) >= minCompressSize {
+		compressed := append([]byte{zstdFormat}, utils.Compress(plaintext)...)
+		if len(compressed) < len(plaintext) {
+			plaintext = compressed
+		}
+	}
+	return utils.EncryptData(plaintext, passphrase)
+}
+
+// unseal decrypts data sealed by seal, decompresses it if needed and unmarshals it to data
+func unseal(passphrase string, encrypted []byte, data any) error {
+	plaintext, err := utils.DecryptData(encrypted, passphrase)
+	if err != nil {
+		return err
+	}
+	if len(plaintext) > 0 && plaintext[0] == zstdFormat {
+		plaintext, err = utils.Decompress(plaintext[1:])
+		if err != nil {
+			return fmt.Errorf("failed to decompress data: %w", err)
+		}
+	}
+	return json.Unmarshal(plaintext, data)
+}
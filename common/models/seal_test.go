@@ -0,0 +1,148 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/gynshu-one/goph-keeper/common/utils"
+)
+
+func TestSealCompresses(t *testing.T) {
+	passphrase := "my passphrase"
+	data := &Binary{Info: "log", Binary: bytes.Repeat([]byte("2023-09-11 INFO request handled\n"), 1000)}
+	marshaled, _ := json.Marshal(data)
+
+	sealed, err := data.EncryptAll(passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sealed) >= len(marshaled)/2 {
+		t.Errorf("Sealed data is %d bytes, json is %d bytes", len(sealed), len(marshaled))
+	}
+
+	decrypted := &Binary{}
+	if err = decrypted.DecryptAll(passphrase, sealed); err != nil {
+		t.Fatal(err)
+	}
+	if decrypted.Info != data.Info || !bytes.Equal(decrypted.Binary, data.Binary) {
+		t.Errorf("Decrypted data doesn't match")
+	}
+}
+
+func TestUnsealUncompressed(t *testing.T) {
+	passphrase := "my passphrase"
+
+	// Data encrypted before compression was added is plain json
+	marshaled, _ := json.Marshal(&Login{Username: "user", Password: strings.Repeat("p", 500)})
+	encrypted, err := utils.EncryptData(marshaled, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	login := &Login{}
+	if err = login.DecryptAll(passphrase, encrypted); err != nil {
+		t.Fatal(err)
+	}
+	if login.Username != "user" || len(login.Password) != 500 {
+		t.Errorf("Unexpected login %+v", login)
+	}
+
+	// Small data is not compressed
+	data := &ArbitraryText{Text: "short"}
+	marshaled, _ = json.Marshal(data)
+	sealed, err := data.EncryptAll(passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := utils.DecryptData(sealed, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plaintext, marshaled) {
+		t.Errorf("Short text was compressed")
+	}
+}
+
+// BenchmarkSeal compares size of a realistic vault encrypted as plain json and compressed before encryption
+func BenchmarkSeal(b *testing.B) {
+	passphrase := "my passphrase"
+	items := vault()
+	encryptors := map[string]func(data BasicData) ([]byte, error){
+		"plain": func(data BasicData) ([]byte, error) {
+			marshaled, err := json.Marshal(data)
+			if err != nil {
+				return nil, err
+			}
+			return utils.EncryptData(marshaled, passphrase)
+		},
+		"compressed": func(data BasicData) ([]byte, error) {
+			return data.EncryptAll(passphrase)
+		},
+	}
+	for name, encrypt := range encryptors {
+		b.Run(name, func(b *testing.B) {
+			var size int
+			for i := 0; i < b.N; i++ {
+				size = 0
+				for _, data := range items {
+					encrypted, err := encrypt(data)
+					if err != nil {
+						b.Fatal(err)
+					}
+					size += len(encrypted)
+				}
+			}
+			b.ReportMetric(float64(size), "vault-bytes")
+		})
+	}
+}
+
+// vault returns items of a typical user: many logins and notes, some cards and a few files,
+// text files compress well, photos don't
+func vault() []BasicData {
+	rnd := rand.New(rand.NewSource(1))
+	words := strings.Fields("the quick brown fox jumps over lazy dog password account bank email server " +
+		"login secret note backup code recovery home work personal family travel")
+	sentence := func(n int) string {
+		parts := make([]string, n)
+		for i := range parts {
+			parts[i] = words[rnd.Intn(len(words))]
+		}
+		return strings.Join(parts, " ")
+	}
+
+	var items []BasicData
+	for i := 0; i < 200; i++ {
+		items = append(items, &Login{
+			Info:     sentence(5),
+			Username: fmt.Sprintf("user%d@example.com", i),
+			Password: fmt.Sprintf("%x", randomBytes(12+i%8)),
+		})
+	}
+	for i := 0; i < 20; i++ {
+		items = append(items, &BankCard{
+			Info:     sentence(3),
+			CardNum:  fmt.Sprintf("4000 %04d %04d %04d", rnd.Intn(10000), rnd.Intn(10000), rnd.Intn(10000)),
+			CardName: "JOHN DOE",
+			CardCvv:  fmt.Sprintf("%03d", rnd.Intn(1000)),
+			CardExp:  "12/27",
+		})
+	}
+	for i := 0; i < 50; i++ {
+		items = append(items, &ArbitraryText{Text: sentence(20 + rnd.Intn(300))})
+	}
+	for i := 0; i < 3; i++ {
+		items = append(items, &Binary{Info: "notes.txt", Binary: []byte(sentence(10000))})
+		items = append(items, &Binary{Info: "photo.jpg", Binary: randomBytes(50000)})
+	}
+	return items
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	rand.New(rand.NewSource(int64(n))).Read(b)
+	return b
+}
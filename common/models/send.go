@@ -0,0 +1,23 @@
+package models
+
+// Send is a secret shared by a link with anyone, even without an account
+// client encrypts the secret with a random key that is kept in the fragment of the link and never sent to the server
+type Send struct {
+	// ID is the random id in the link, server generates it
+	ID string `json:"id" bson:"_id"`
+	// OwnerID is the email of the user that created the send
+	OwnerID string `json:"owner_id" bson:"owner_id"`
+	// Data is the encrypted secret, it is not sent when the owner lists sends
+	Data []byte `json:"data,omitempty" bson:"data"`
+	// ExpiresAt is unix time the send can't be viewed after
+	ExpiresAt int64 `json:"expires_at" bson:"expires_at"`
+	// MaxViews is how many times the send can be viewed, it is deleted after the last view
+	MaxViews  int64 `json:"max_views" bson:"max_views"`
+	Views     int64 `json:"views" bson:"views"`
+	CreatedAt int64 `json:"created_at" bson:"created_at"`
+}
+
+// ViewableAt reports whether the send can be viewed once more at the unix time
+func (s Send) ViewableAt(now int64) bool {
+	return now < s.ExpiresAt && s.Views < s.MaxViews
+}
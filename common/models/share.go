@@ -0,0 +1,34 @@
+package models
+
+// Permission is what the user an item is shared with can do with it
+type Permission string
+
+const (
+	// PermissionRead lets the user only read the item
+	PermissionRead Permission = "read"
+	// PermissionWrite lets the user also edit the item
+	PermissionWrite Permission = "write"
+)
+
+// Valid tells whether the permission is known
+func (p Permission) Valid() bool {
+	return p == PermissionRead || p == PermissionWrite
+}
+
+// Share gives the user access to the item of the owner
+type Share struct {
+	ItemID  string `json:"item_id" bson:"item_id"`
+	OwnerID string `json:"owner_id" bson:"owner_id"`
+	// UserID is the email of the user the item is shared with
+	UserID string `json:"user_id" bson:"user_id"`
+	// Key is the key of the item sealed to the public key of the user
+	Key        []byte     `json:"key" bson:"key"`
+	Permission Permission `json:"permission" bson:"permission"`
+	CreatedAt  int64      `json:"created_at" bson:"created_at"`
+}
+
+// SharedItem is the item shared with the user together with the share
+type SharedItem struct {
+	Share Share       `json:"share"`
+	Data  DataWrapper `json:"data"`
+}
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SSH key algorithms GenerateSSHKey supports
+const (
+	SSHKeyEd25519 = "ed25519"
+	SSHKeyRSA     = "rsa"
+)
+
+// rsaKeyBits is the size of generated RSA keys
+const rsaKeyBits = 4096
+
+// ErrUnknownAlgorithm is returned by GenerateSSHKey for algorithms other than SSHKeyEd25519 and SSHKeyRSA
+var ErrUnknownAlgorithm = errors.New("unknown ssh key algorithm")
+
+// SSHKey is a struct for ssh key pair
+type SSHKey struct {
+	// Meta is tags and favorite flag of the item
+	Meta `bson:",inline"`
+	// PrivateKey is the private key in PEM, OpenSSH format for generated keys
+	PrivateKey string `json:"private_key" bson:"private_key"`
+	// PublicKey is the public key in authorized_keys format
+	PublicKey string `json:"public_key" bson:"public_key"`
+	// Comment is the comment of the key, usually user@host
+	Comment string `json:"comment" bson:"comment"`
+	// Passphrase is the passphrase PrivateKey is encrypted with, empty if it's not encrypted
+	Passphrase string `json:"passphrase" bson:"passphrase"`
+}
+
+// EncryptAll encrypts all sensitive data
+func (data *SSHKey) EncryptAll(passphrase string) (encryptedData []byte, err error) {
+	return seal(data, passphrase)
+}
+
+// DecryptAll decrypts all sensitive data
+func (data *SSHKey) DecryptAll(passphrase string, encrypteData []byte) error {
+	return unseal(passphrase, encrypteData, data)
+}
+
+// GenerateSSHKey generates a new key pair with the algorithm, SSHKeyEd25519 or SSHKeyRSA
+// the private key is encrypted with the passphrase if it's not empty
+func GenerateSSHKey(algorithm, comment, passphrase string) (SSHKey, error) {
+	var private any
+	var err error
+	switch algorithm {
+	case SSHKeyEd25519:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	case SSHKeyRSA:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	default:
+		return SSHKey{}, fmt.Errorf("%w: %s", ErrUnknownAlgorithm, algorithm)
+	}
+	if err != nil {
+		return SSHKey{}, err
+	}
+
+	var block *pem.Block
+	if passphrase != "" {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(private, comment, []byte(passphrase))
+	} else {
+		block, err = ssh.MarshalPrivateKey(private, comment)
+	}
+	if err != nil {
+		return SSHKey{}, err
+	}
+	key := SSHKey{PrivateKey: string(pem.EncodeToMemory(block)), Comment: comment, Passphrase: passphrase}
+	return key, key.fillPublicKey(private)
+}
+
+// ImportSSHKey reads the private key in PEM and fills the public key from it
+// passphrase is needed only if the private key is encrypted
+func ImportSSHKey(privateKey []byte, comment, passphrase string) (SSHKey, error) {
+	key := SSHKey{PrivateKey: string(privateKey), Comment: comment, Passphrase: passphrase}
+	private, err := key.RawKey()
+	if err != nil {
+		return SSHKey{}, err
+	}
+	return key, key.fillPublicKey(private)
+}
+
+// RawKey returns the private key decrypted with the passphrase
+// it's *rsa.PrivateKey, *ecdsa.PrivateKey or ed25519.PrivateKey
+func (data *SSHKey) RawKey() (any, error) {
+	var private any
+	var err error
+	if data.Passphrase != "" {
+		private, err = ssh.ParseRawPrivateKeyWithPassphrase([]byte(data.PrivateKey), []byte(data.Passphrase))
+	} else {
+		private, err = ssh.ParseRawPrivateKey([]byte(data.PrivateKey))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+	// ed25519 keys are returned by pointer, ssh.NewSignerFromKey takes them by value too
+	if key, ok := private.(*ed25519.PrivateKey); ok {
+		return *key, nil
+	}
+	return private, nil
+}
+
+// fillPublicKey sets PublicKey from the private key with the comment appended
+func (data *SSHKey) fillPublicKey(private any) error {
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		return err
+	}
+	data.PublicKey = strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+	if data.Comment != "" {
+		data.PublicKey += " " + data.Comment
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestGenerateSSHKey(t *testing.T) {
+	key, err := GenerateSSHKey(SSHKeyEd25519, "alice@laptop", "secret")
+	if err != nil {
+		t.Fatalf("GenerateSSHKey returned an error: %v", err)
+	}
+	if !strings.HasPrefix(key.PublicKey, "ssh-ed25519 ") || !strings.HasSuffix(key.PublicKey, " alice@laptop") {
+		t.Errorf("Unexpected public key: %s", key.PublicKey)
+	}
+	// Private key is encrypted with the passphrase
+	var missing *ssh.PassphraseMissingError
+	if _, err = ssh.ParseRawPrivateKey([]byte(key.PrivateKey)); !errors.As(err, &missing) {
+		t.Errorf("Expected private key encrypted, got %v", err)
+	}
+	if _, err = key.RawKey(); err != nil {
+		t.Errorf("RawKey returned an error: %v", err)
+	}
+
+	if _, err = GenerateSSHKey("dsa", "", ""); !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Errorf("Expected %v, got %v", ErrUnknownAlgorithm, err)
+	}
+}
+
+func TestImportSSHKey(t *testing.T) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Keys made by older ssh-keygen are PEM encrypted this way
+	block, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(private),
+		[]byte("secret"), x509.PEMCipherAES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := pem.EncodeToMemory(block)
+
+	key, err := ImportSSHKey(encoded, "", "secret")
+	if err != nil {
+		t.Fatalf("ImportSSHKey returned an error: %v", err)
+	}
+	public, err := ssh.NewPublicKey(&private.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.PublicKey != strings.TrimSpace(string(ssh.MarshalAuthorizedKey(public))) {
+		t.Errorf("Unexpected public key: %s", key.PublicKey)
+	}
+	if _, err = ImportSSHKey(encoded, "", "wrong"); err == nil {
+		t.Error("Key is imported with a wrong passphrase")
+	}
+	if _, err = ImportSSHKey([]byte("not a key"), "", ""); err == nil {
+		t.Error("Invalid key is imported")
+	}
+}
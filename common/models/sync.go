@@ -0,0 +1,88 @@
+package models
+
+// SyncStatus is the result of syncing one item
+type SyncStatus string
+
+const (
+	// StatusApplied means the item was stored by server
+	StatusApplied SyncStatus = "applied"
+	// StatusStale means server already has the same content of the item
+	StatusStale SyncStatus = "stale"
+	// StatusRejected means the item can't be stored at all,
+	// e.g. it belongs to another user or was permanently removed
+	StatusRejected SyncStatus = "rejected"
+	// StatusConflict means the item was edited on top of a revision that is not the current one on server
+	StatusConflict SyncStatus = "conflict"
+)
+
+// SyncResult is the status of one item sent to server
+type SyncResult struct {
+	ID     string     `json:"id"`
+	Status SyncStatus `json:"status"`
+	// Reason explains why the item was not applied
+	Reason string `json:"reason,omitempty"`
+	// Server is the current version of the item on server in case of conflict
+	Server *DataWrapper `json:"server,omitempty"`
+}
+
+// SyncResponse is the response of sync endpoint
+type SyncResponse struct {
+	// Results has a status for every item sent by client in the same order
+	Results []SyncResult `json:"results"`
+	// Data is the data of the user changed since the cursor sent by client
+	// and current versions of sent items that were stale
+	Data []DataWrapper `json:"data"`
+	// Cursor is the position in the user's change feed the client should send on the next sync
+	Cursor int64 `json:"cursor"`
+	// Reset means Data is all data of the user and the client should replace its data with it
+	// it happens on the first sync and when changes since the cursor are no longer known
+	Reset bool `json:"reset"`
+}
+
+// CursorParam is the query parameter of sync endpoint with the cursor of the last sync
+const CursorParam = "cursor"
+
+// ChangeEvent is sent to subscribed clients when data of the user was changed
+type ChangeEvent struct {
+	// Cursor is the position of the change in the user's change feed,
+	// client that has synced up to it doesn't need to sync
+	Cursor int64 `json:"cursor"`
+}
+
+// ChangeEventName is the name of server-sent event with ChangeEvent in data
+const ChangeEventName = "change"
+
+// PushOnlyParam is the query parameter of sync endpoint that asks server not to send changes in the response,
+// client downloads them from changes endpoint then
+const PushOnlyParam = "push_only"
+
+// ResumeParam is the query parameter of changes endpoint that tells the cursor is a checkpoint
+// of interrupted download of all data, so server continues it rather than starts it again
+const ResumeParam = "resume"
+
+// ChangesContentType is the content type of changes endpoint response,
+// every line is a ChangeLine in json format
+const ChangesContentType = "application/x-ndjson"
+
+// ChangeLine is one line of changes stream, it has either changed item or checkpoint
+type ChangeLine struct {
+	Data       *DataWrapper `json:"data,omitempty"`
+	Checkpoint *Checkpoint  `json:"checkpoint,omitempty"`
+}
+
+// Checkpoint is the position in the changes stream client can resume from
+// the first line of stream is a checkpoint that tells whether the stream is Reset
+// and the last one is Done, stream that ended without Done was interrupted
+type Checkpoint struct {
+	// Cursor is the position in the user's change feed all items before the checkpoint include up to
+	// client that resumes sends it as the cursor
+	Cursor int64 `json:"cursor"`
+	// Reset means the stream lists all data of the user, client should drop items it has synced before
+	Reset bool `json:"reset,omitempty"`
+	// Done means the stream has all changes
+	Done bool `json:"done,omitempty"`
+}
+
+// IdempotencyKeyHeader is the header of sync request with a key unique for the request,
+// server returns the same response to a repeated request with the key instead of applying it again
+const IdempotencyKeyHeader = "Idempotency-Key"
@@ -0,0 +1,30 @@
+package models
+
+// Usage is the storage usage of a user and the limits set by server
+// zero limit means there is no limit
+type Usage struct {
+	// Items is the number of stored items, deleted ones are not counted
+	Items int64 `json:"items"`
+	// Bytes is the total size of stored items as ItemSize counts it
+	Bytes int64 `json:"bytes"`
+	// MaxItems is the maximum number of items a user can store
+	MaxItems int64 `json:"max_items"`
+	// MaxBytes is the maximum total size of encrypted data a user can store
+	MaxBytes int64 `json:"max_bytes"`
+	// MaxItemSize is the maximum size of one item as ItemSize counts it
+	MaxItemSize int64 `json:"max_item_size"`
+}
+
+// ItemSize is the size of the item counted in quota: its encrypted data, name and key
+func ItemSize(data DataWrapper) int64 {
+	return int64(len(data.Data) + len(data.Name) + len(data.Key))
+}
+
+// Headers used to report Usage in sync response
+const (
+	UsageItemsHeader       = "X-Usage-Items"
+	UsageBytesHeader       = "X-Usage-Bytes"
+	UsageMaxItemsHeader    = "X-Usage-Max-Items"
+	UsageMaxBytesHeader    = "X-Usage-Max-Bytes"
+	UsageMaxItemSizeHeader = "X-Usage-Max-Item-Size"
+)
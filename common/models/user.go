@@ -12,4 +12,15 @@ type User struct {
 	UpdatedAt int64 `json:"updated_at" bson:"updated_at"`
 	// DeletedAt is the time when this user was deleted
 	DeletedAt int64 `json:"deleted_at" bson:"deleted_at"`
+	// Keys are the keys other users share items with, nil until client of the user sets them
+	Keys *UserKeys `json:"keys,omitempty" bson:"keys,omitempty"`
+}
+
+// UserKeys is the X25519 key pair of the user
+// items are shared with the user by wrapping their keys to PublicKey
+type UserKeys struct {
+	PublicKey []byte `json:"public_key" bson:"public_key"`
+	// PrivateKey is encrypted with user's secret, server never sees it in plain
+	// it's empty when keys of another user are returned
+	PrivateKey []byte `json:"private_key,omitempty" bson:"private_key"`
 }
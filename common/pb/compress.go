@@ -0,0 +1,43 @@
+package pb
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/gynshu-one/goph-keeper/common/utils"
+	"google.golang.org/grpc/encoding"
+	// gzip compressor is registered by the package
+	_ "google.golang.org/grpc/encoding/gzip"
+)
+
+// ZstdCompressor is the name of zstd compressor of gRPC messages
+// client uses it with grpc.UseCompressor, server answers with the compressor of the request
+const ZstdCompressor = utils.EncodingZstd
+
+func init() {
+	encoding.RegisterCompressor(zstdCompressor{})
+}
+
+// zstdCompressor is gRPC compressor that uses zstd
+type zstdCompressor struct{}
+
+func (zstdCompressor) Name() string {
+	return ZstdCompressor
+}
+
+func (zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return utils.NewEncoder(utils.EncodingZstd, w)
+}
+
+// Decompress decodes the whole message at once, its size is limited by gRPC
+func (zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	compressed, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	decompressed, err := utils.Decompress(compressed)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(decompressed), nil
+}
@@ -0,0 +1,534 @@
+package pb
+
+import "github.com/gynshu-one/goph-keeper/common/models"
+
+// FromData converts models.DataWrapper to Data
+func FromData(data models.DataWrapper) *Data {
+	return &Data{
+		Id:           data.ID,
+		OwnerId:      data.OwnerID,
+		Type:         data.Type,
+		Name:         data.Name,
+		UpdatedAt:    data.UpdatedAt,
+		CreatedAt:    data.CreatedAt,
+		DeletedAt:    data.DeletedAt,
+		Revision:     data.Revision,
+		Seq:          data.Seq,
+		Data:         data.Data,
+		Blobs:        data.Blobs,
+		Key:          data.Key,
+		CollectionId: data.CollectionID,
+		FolderId:     data.FolderID,
+		LabelVersion: data.LabelVersion,
+	}
+}
+
+// Model converts Data to models.DataWrapper, nil Data is zero value
+func (x *Data) Model() models.DataWrapper {
+	if x == nil {
+		return models.DataWrapper{}
+	}
+	return models.DataWrapper{
+		ID:           x.Id,
+		OwnerID:      x.OwnerId,
+		Type:         x.Type,
+		Name:         x.Name,
+		UpdatedAt:    x.UpdatedAt,
+		CreatedAt:    x.CreatedAt,
+		DeletedAt:    x.DeletedAt,
+		Revision:     x.Revision,
+		Seq:          x.Seq,
+		Data:         x.Data,
+		Blobs:        x.Blobs,
+		Key:          x.Key,
+		CollectionID: x.CollectionId,
+		FolderID:     x.FolderId,
+		LabelVersion: x.LabelVersion,
+	}
+}
+
+// FromDataList converts slice of models.DataWrapper to slice of Data
+func FromDataList(list []models.DataWrapper) []*Data {
+	result := make([]*Data, len(list))
+	for i, data := range list {
+		result[i] = FromData(data)
+	}
+	return result
+}
+
+// DataList converts slice of Data to slice of models.DataWrapper
+func DataList(list []*Data) []models.DataWrapper {
+	result := make([]models.DataWrapper, len(list))
+	for i, data := range list {
+		result[i] = data.Model()
+	}
+	return result
+}
+
+// FromSyncResponse converts models.SyncResponse and usage of the user to SyncResponse
+func FromSyncResponse(response models.SyncResponse, usage models.Usage) *SyncResponse {
+	results := make([]*SyncResult, len(response.Results))
+	for i, result := range response.Results {
+		results[i] = FromSyncResult(result)
+	}
+	return &SyncResponse{
+		Results: results,
+		Data:    FromDataList(response.Data),
+		Cursor:  response.Cursor,
+		Reset_:  response.Reset,
+		Usage: &Usage{
+			Items:       usage.Items,
+			Bytes:       usage.Bytes,
+			MaxItems:    usage.MaxItems,
+			MaxBytes:    usage.MaxBytes,
+			MaxItemSize: usage.MaxItemSize,
+		},
+	}
+}
+
+// Model converts SyncResponse to models.SyncResponse and usage of the user
+func (x *SyncResponse) Model() (models.SyncResponse, models.Usage) {
+	response := models.SyncResponse{
+		Results: make([]models.SyncResult, len(x.GetResults())),
+		Data:    DataList(x.GetData()),
+		Cursor:  x.GetCursor(),
+		Reset:   x.GetReset_(),
+	}
+	for i, result := range x.GetResults() {
+		response.Results[i] = result.Model()
+	}
+	usage := x.GetUsage()
+	return response, models.Usage{
+		Items:       usage.GetItems(),
+		Bytes:       usage.GetBytes(),
+		MaxItems:    usage.GetMaxItems(),
+		MaxBytes:    usage.GetMaxBytes(),
+		MaxItemSize: usage.GetMaxItemSize(),
+	}
+}
+
+// FromSyncResult converts models.SyncResult to SyncResult
+func FromSyncResult(result models.SyncResult) *SyncResult {
+	converted := &SyncResult{
+		Id:     result.ID,
+		Status: string(result.Status),
+		Reason: result.Reason,
+	}
+	if result.Server != nil {
+		converted.Server = FromData(*result.Server)
+	}
+	return converted
+}
+
+// Model converts SyncResult to models.SyncResult
+func (x *SyncResult) Model() models.SyncResult {
+	result := models.SyncResult{
+		ID:     x.GetId(),
+		Status: models.SyncStatus(x.GetStatus()),
+		Reason: x.GetReason(),
+	}
+	if x.GetServer() != nil {
+		server := x.GetServer().Model()
+		result.Server = &server
+	}
+	return result
+}
+
+// FromChangeLine converts models.ChangeLine to ChangeLine
+func FromChangeLine(line models.ChangeLine) *ChangeLine {
+	result := &ChangeLine{}
+	if line.Data != nil {
+		result.Data = FromData(*line.Data)
+	}
+	if line.Checkpoint != nil {
+		result.Checkpoint = &Checkpoint{
+			Cursor: line.Checkpoint.Cursor,
+			Reset_: line.Checkpoint.Reset,
+			Done:   line.Checkpoint.Done,
+		}
+	}
+	return result
+}
+
+// Model converts ChangeLine to models.ChangeLine
+func (x *ChangeLine) Model() models.ChangeLine {
+	var line models.ChangeLine
+	if x.GetData() != nil {
+		data := x.GetData().Model()
+		line.Data = &data
+	}
+	if checkpoint := x.GetCheckpoint(); checkpoint != nil {
+		line.Checkpoint = &models.Checkpoint{
+			Cursor: checkpoint.GetCursor(),
+			Reset:  checkpoint.GetReset_(),
+			Done:   checkpoint.GetDone(),
+		}
+	}
+	return line
+}
+
+// FromBlob converts models.Blob to Blob
+func FromBlob(blob models.Blob) *Blob {
+	return &Blob{
+		Id:        blob.ID,
+		Size:      blob.Size,
+		Hash:      blob.Hash,
+		Offset:    blob.Offset,
+		CreatedAt: blob.CreatedAt,
+	}
+}
+
+// Model converts Blob to models.Blob, nil Blob is zero value
+func (x *Blob) Model() models.Blob {
+	if x == nil {
+		return models.Blob{}
+	}
+	return models.Blob{
+		ID:        x.Id,
+		Size:      x.Size,
+		Hash:      x.Hash,
+		Offset:    x.Offset,
+		CreatedAt: x.CreatedAt,
+	}
+}
+
+// FromUserKeys converts models.UserKeys to UserKeys
+func FromUserKeys(keys models.UserKeys) *UserKeys {
+	return &UserKeys{PublicKey: keys.PublicKey, PrivateKey: keys.PrivateKey}
+}
+
+// Model converts UserKeys to models.UserKeys
+func (x *UserKeys) Model() models.UserKeys {
+	return models.UserKeys{PublicKey: x.GetPublicKey(), PrivateKey: x.GetPrivateKey()}
+}
+
+// FromShare converts models.Share to Share
+func FromShare(share models.Share) *Share {
+	return &Share{
+		ItemId:     share.ItemID,
+		OwnerId:    share.OwnerID,
+		UserId:     share.UserID,
+		Key:        share.Key,
+		Permission: string(share.Permission),
+		CreatedAt:  share.CreatedAt,
+	}
+}
+
+// Model converts Share to models.Share, nil Share is zero value
+func (x *Share) Model() models.Share {
+	if x == nil {
+		return models.Share{}
+	}
+	return models.Share{
+		ItemID:     x.ItemId,
+		OwnerID:    x.OwnerId,
+		UserID:     x.UserId,
+		Key:        x.Key,
+		Permission: models.Permission(x.Permission),
+		CreatedAt:  x.CreatedAt,
+	}
+}
+
+// FromShareList converts slice of models.Share to slice of Share
+func FromShareList(list []models.Share) []*Share {
+	result := make([]*Share, len(list))
+	for i, share := range list {
+		result[i] = FromShare(share)
+	}
+	return result
+}
+
+// ShareList converts slice of Share to slice of models.Share
+func ShareList(list []*Share) []models.Share {
+	result := make([]models.Share, len(list))
+	for i, share := range list {
+		result[i] = share.Model()
+	}
+	return result
+}
+
+// FromSharedItems converts slice of models.SharedItem to slice of SharedItem
+func FromSharedItems(items []models.SharedItem) []*SharedItem {
+	result := make([]*SharedItem, len(items))
+	for i, item := range items {
+		result[i] = &SharedItem{Share: FromShare(item.Share), Data: FromData(item.Data)}
+	}
+	return result
+}
+
+// SharedItems converts slice of SharedItem to slice of models.SharedItem
+func SharedItems(items []*SharedItem) []models.SharedItem {
+	result := make([]models.SharedItem, len(items))
+	for i, item := range items {
+		result[i] = models.SharedItem{Share: item.GetShare().Model(), Data: item.GetData().Model()}
+	}
+	return result
+}
+
+// FromOrganization converts models.Organization to Organization
+func FromOrganization(org models.Organization) *Organization {
+	return &Organization{Id: org.ID, Name: org.Name, KeyVersion: org.KeyVersion, CreatedAt: org.CreatedAt}
+}
+
+// Model converts Organization to models.Organization, nil Organization is zero value
+func (x *Organization) Model() models.Organization {
+	if x == nil {
+		return models.Organization{}
+	}
+	return models.Organization{ID: x.Id, Name: x.Name, KeyVersion: x.KeyVersion, CreatedAt: x.CreatedAt}
+}
+
+// FromMember converts models.Member to Member
+func FromMember(member models.Member) *Member {
+	return &Member{
+		OrgId:      member.OrgID,
+		UserId:     member.UserID,
+		Role:       string(member.Role),
+		Key:        member.Key,
+		KeyVersion: member.KeyVersion,
+		Accepted:   member.Accepted,
+		CreatedAt:  member.CreatedAt,
+	}
+}
+
+// Model converts Member to models.Member, nil Member is zero value
+func (x *Member) Model() models.Member {
+	if x == nil {
+		return models.Member{}
+	}
+	return models.Member{
+		OrgID:      x.OrgId,
+		UserID:     x.UserId,
+		Role:       models.Role(x.Role),
+		Key:        x.Key,
+		KeyVersion: x.KeyVersion,
+		Accepted:   x.Accepted,
+		CreatedAt:  x.CreatedAt,
+	}
+}
+
+// FromMemberList converts slice of models.Member to slice of Member
+func FromMemberList(list []models.Member) []*Member {
+	result := make([]*Member, len(list))
+	for i, member := range list {
+		result[i] = FromMember(member)
+	}
+	return result
+}
+
+// MemberList converts slice of Member to slice of models.Member
+func MemberList(list []*Member) []models.Member {
+	result := make([]models.Member, len(list))
+	for i, member := range list {
+		result[i] = member.Model()
+	}
+	return result
+}
+
+// FromMembership converts models.Membership to Membership
+func FromMembership(membership models.Membership) *Membership {
+	return &Membership{Organization: FromOrganization(membership.Organization), Member: FromMember(membership.Member)}
+}
+
+// Model converts Membership to models.Membership
+func (x *Membership) Model() models.Membership {
+	return models.Membership{Organization: x.GetOrganization().Model(), Member: x.GetMember().Model()}
+}
+
+// FromMemberships converts slice of models.Membership to slice of Membership
+func FromMemberships(list []models.Membership) []*Membership {
+	result := make([]*Membership, len(list))
+	for i, membership := range list {
+		result[i] = FromMembership(membership)
+	}
+	return result
+}
+
+// Memberships converts slice of Membership to slice of models.Membership
+func Memberships(list []*Membership) []models.Membership {
+	result := make([]models.Membership, len(list))
+	for i, membership := range list {
+		result[i] = membership.Model()
+	}
+	return result
+}
+
+// FromCollection converts models.Collection to Collection
+func FromCollection(collection models.Collection) *Collection {
+	return &Collection{Id: collection.ID, OrgId: collection.OrgID, Name: collection.Name, CreatedAt: collection.CreatedAt}
+}
+
+// Model converts Collection to models.Collection
+func (x *Collection) Model() models.Collection {
+	return models.Collection{ID: x.GetId(), OrgID: x.GetOrgId(), Name: x.GetName(), CreatedAt: x.GetCreatedAt()}
+}
+
+// FromCollectionList converts slice of models.Collection to slice of Collection
+func FromCollectionList(list []models.Collection) []*Collection {
+	result := make([]*Collection, len(list))
+	for i, collection := range list {
+		result[i] = FromCollection(collection)
+	}
+	return result
+}
+
+// CollectionList converts slice of Collection to slice of models.Collection
+func CollectionList(list []*Collection) []models.Collection {
+	result := make([]models.Collection, len(list))
+	for i, collection := range list {
+		result[i] = collection.Model()
+	}
+	return result
+}
+
+// FromOrgData converts models.OrgData to OrgData
+func FromOrgData(data models.OrgData) *OrgData {
+	return &OrgData{OrgId: data.OrgID, KeyVersion: data.KeyVersion, Data: FromDataList(data.Data)}
+}
+
+// Model converts OrgData to models.OrgData
+func (x *OrgData) Model() models.OrgData {
+	return models.OrgData{OrgID: x.GetOrgId(), KeyVersion: x.GetKeyVersion(), Data: DataList(x.GetData())}
+}
+
+// FromKeyRotation converts models.KeyRotation to KeyRotation
+func FromKeyRotation(rotation models.KeyRotation) *KeyRotation {
+	return &KeyRotation{
+		OrgId:      rotation.OrgID,
+		KeyVersion: rotation.KeyVersion,
+		Members:    FromMemberList(rotation.Members),
+		Data:       FromDataList(rotation.Data),
+	}
+}
+
+// Model converts KeyRotation to models.KeyRotation
+func (x *KeyRotation) Model() models.KeyRotation {
+	return models.KeyRotation{
+		OrgID:      x.GetOrgId(),
+		KeyVersion: x.GetKeyVersion(),
+		Members:    MemberList(x.GetMembers()),
+		Data:       DataList(x.GetData()),
+	}
+}
+
+// FromSyncResults converts slice of models.SyncResult to slice of SyncResult
+func FromSyncResults(results []models.SyncResult) []*SyncResult {
+	converted := make([]*SyncResult, len(results))
+	for i, result := range results {
+		converted[i] = FromSyncResult(result)
+	}
+	return converted
+}
+
+// SyncResults converts slice of SyncResult to slice of models.SyncResult
+func SyncResults(results []*SyncResult) []models.SyncResult {
+	converted := make([]models.SyncResult, len(results))
+	for i, result := range results {
+		converted[i] = result.Model()
+	}
+	return converted
+}
+
+// FromEmergencyAccess converts models.EmergencyAccess to EmergencyAccess
+func FromEmergencyAccess(access models.EmergencyAccess) *EmergencyAccess {
+	return &EmergencyAccess{
+		GrantorId:   access.GrantorID,
+		GranteeId:   access.GranteeID,
+		WaitDays:    access.WaitDays,
+		Key:         access.Key,
+		Status:      string(access.Status),
+		RequestedAt: access.RequestedAt,
+		CreatedAt:   access.CreatedAt,
+	}
+}
+
+// Model converts EmergencyAccess to models.EmergencyAccess, nil EmergencyAccess is zero value
+func (x *EmergencyAccess) Model() models.EmergencyAccess {
+	if x == nil {
+		return models.EmergencyAccess{}
+	}
+	return models.EmergencyAccess{
+		GrantorID:   x.GrantorId,
+		GranteeID:   x.GranteeId,
+		WaitDays:    x.WaitDays,
+		Key:         x.Key,
+		Status:      models.EmergencyStatus(x.Status),
+		RequestedAt: x.RequestedAt,
+		CreatedAt:   x.CreatedAt,
+	}
+}
+
+// FromEmergencyList converts slice of models.EmergencyAccess to slice of EmergencyAccess
+func FromEmergencyList(list []models.EmergencyAccess) []*EmergencyAccess {
+	result := make([]*EmergencyAccess, len(list))
+	for i, access := range list {
+		result[i] = FromEmergencyAccess(access)
+	}
+	return result
+}
+
+// EmergencyList converts slice of EmergencyAccess to slice of models.EmergencyAccess
+func EmergencyList(list []*EmergencyAccess) []models.EmergencyAccess {
+	result := make([]models.EmergencyAccess, len(list))
+	for i, access := range list {
+		result[i] = access.Model()
+	}
+	return result
+}
+
+// FromEmergencyVault converts models.EmergencyVault to EmergencyVault
+func FromEmergencyVault(vault models.EmergencyVault) *EmergencyVault {
+	return &EmergencyVault{Access: FromEmergencyAccess(vault.Access), Data: FromDataList(vault.Data)}
+}
+
+// Model converts EmergencyVault to models.EmergencyVault
+func (x *EmergencyVault) Model() models.EmergencyVault {
+	return models.EmergencyVault{Access: x.GetAccess().Model(), Data: DataList(x.GetData())}
+}
+
+// FromSend converts models.Send to Send
+func FromSend(send models.Send) *Send {
+	return &Send{
+		Id:        send.ID,
+		OwnerId:   send.OwnerID,
+		Data:      send.Data,
+		ExpiresAt: send.ExpiresAt,
+		MaxViews:  send.MaxViews,
+		Views:     send.Views,
+		CreatedAt: send.CreatedAt,
+	}
+}
+
+// Model converts Send to models.Send, nil Send is zero value
+func (x *Send) Model() models.Send {
+	if x == nil {
+		return models.Send{}
+	}
+	return models.Send{
+		ID:        x.Id,
+		OwnerID:   x.OwnerId,
+		Data:      x.Data,
+		ExpiresAt: x.ExpiresAt,
+		MaxViews:  x.MaxViews,
+		Views:     x.Views,
+		CreatedAt: x.CreatedAt,
+	}
+}
+
+// FromSendList converts slice of models.Send to slice of Send
+func FromSendList(list []models.Send) []*Send {
+	result := make([]*Send, len(list))
+	for i, send := range list {
+		result[i] = FromSend(send)
+	}
+	return result
+}
+
+// SendList converts slice of Send to slice of models.Send
+func SendList(list []*Send) []models.Send {
+	result := make([]models.Send, len(list))
+	for i, send := range list {
+		result[i] = send.Model()
+	}
+	return result
+}
@@ -0,0 +1,8 @@
+// Package pb contains gRPC API of the server generated from keeper.proto
+// and conversions between its messages and models
+// Regenerate it after changing keeper.proto:
+//
+//	protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative keeper.proto
+package pb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative keeper.proto
@@ -0,0 +1,1773 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             (unknown)
+// source: keeper.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	Keeper_Register_FullMethodName               = "/keeper.Keeper/Register"
+	Keeper_Login_FullMethodName                  = "/keeper.Keeper/Login"
+	Keeper_Logout_FullMethodName                 = "/keeper.Keeper/Logout"
+	Keeper_Sync_FullMethodName                   = "/keeper.Keeper/Sync"
+	Keeper_ListRevisions_FullMethodName          = "/keeper.Keeper/ListRevisions"
+	Keeper_GetRevision_FullMethodName            = "/keeper.Keeper/GetRevision"
+	Keeper_Events_FullMethodName                 = "/keeper.Keeper/Events"
+	Keeper_Changes_FullMethodName                = "/keeper.Keeper/Changes"
+	Keeper_CreateBlob_FullMethodName             = "/keeper.Keeper/CreateBlob"
+	Keeper_UploadBlob_FullMethodName             = "/keeper.Keeper/UploadBlob"
+	Keeper_DownloadBlob_FullMethodName           = "/keeper.Keeper/DownloadBlob"
+	Keeper_GetKeys_FullMethodName                = "/keeper.Keeper/GetKeys"
+	Keeper_SetKeys_FullMethodName                = "/keeper.Keeper/SetKeys"
+	Keeper_GetPublicKey_FullMethodName           = "/keeper.Keeper/GetPublicKey"
+	Keeper_ShareItem_FullMethodName              = "/keeper.Keeper/ShareItem"
+	Keeper_ListShares_FullMethodName             = "/keeper.Keeper/ListShares"
+	Keeper_RevokeShare_FullMethodName            = "/keeper.Keeper/RevokeShare"
+	Keeper_Shared_FullMethodName                 = "/keeper.Keeper/Shared"
+	Keeper_UpdateShared_FullMethodName           = "/keeper.Keeper/UpdateShared"
+	Keeper_CreateOrg_FullMethodName              = "/keeper.Keeper/CreateOrg"
+	Keeper_ListOrgs_FullMethodName               = "/keeper.Keeper/ListOrgs"
+	Keeper_ListMembers_FullMethodName            = "/keeper.Keeper/ListMembers"
+	Keeper_SetMember_FullMethodName              = "/keeper.Keeper/SetMember"
+	Keeper_RemoveMember_FullMethodName           = "/keeper.Keeper/RemoveMember"
+	Keeper_AcceptInvite_FullMethodName           = "/keeper.Keeper/AcceptInvite"
+	Keeper_ListCollections_FullMethodName        = "/keeper.Keeper/ListCollections"
+	Keeper_SetCollection_FullMethodName          = "/keeper.Keeper/SetCollection"
+	Keeper_GetOrgData_FullMethodName             = "/keeper.Keeper/GetOrgData"
+	Keeper_WriteOrgData_FullMethodName           = "/keeper.Keeper/WriteOrgData"
+	Keeper_RotateOrgKey_FullMethodName           = "/keeper.Keeper/RotateOrgKey"
+	Keeper_ListEmergencyContacts_FullMethodName  = "/keeper.Keeper/ListEmergencyContacts"
+	Keeper_SetEmergencyContact_FullMethodName    = "/keeper.Keeper/SetEmergencyContact"
+	Keeper_RemoveEmergencyContact_FullMethodName = "/keeper.Keeper/RemoveEmergencyContact"
+	Keeper_RejectEmergencyAccess_FullMethodName  = "/keeper.Keeper/RejectEmergencyAccess"
+	Keeper_ListEmergencyGrantors_FullMethodName  = "/keeper.Keeper/ListEmergencyGrantors"
+	Keeper_RequestEmergencyAccess_FullMethodName = "/keeper.Keeper/RequestEmergencyAccess"
+	Keeper_GetEmergencyVault_FullMethodName      = "/keeper.Keeper/GetEmergencyVault"
+	Keeper_ListSends_FullMethodName              = "/keeper.Keeper/ListSends"
+	Keeper_CreateSend_FullMethodName             = "/keeper.Keeper/CreateSend"
+	Keeper_DeleteSend_FullMethodName             = "/keeper.Keeper/DeleteSend"
+	Keeper_ReceiveSend_FullMethodName            = "/keeper.Keeper/ReceiveSend"
+)
+
+// KeeperClient is the client API for Keeper service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Keeper is the gRPC API of the server, it mirrors the REST API
+// every method except Register and Login requires session id in "session_id" metadata
+type KeeperClient interface {
+	// Register creates a new user and a session for it
+	Register(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (*Session, error)
+	// Login creates a new session for the user
+	Login(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (*Session, error)
+	// Logout ends the session of the request or all sessions of the user
+	Logout(ctx context.Context, in *LogoutRequest, opts ...grpc.CallOption) (*LogoutResponse, error)
+	// Sync stores the items changed by client and returns the data changed since the cursor
+	Sync(ctx context.Context, in *SyncRequest, opts ...grpc.CallOption) (*SyncResponse, error)
+	// ListRevisions returns previous versions of the item without data, newest first
+	ListRevisions(ctx context.Context, in *ListRevisionsRequest, opts ...grpc.CallOption) (*ListRevisionsResponse, error)
+	// GetRevision returns previous version of the item with its data
+	GetRevision(ctx context.Context, in *GetRevisionRequest, opts ...grpc.CallOption) (*Data, error)
+	// Events streams changes of the user's data until client cancels the call
+	Events(ctx context.Context, in *EventsRequest, opts ...grpc.CallOption) (Keeper_EventsClient, error)
+	// Changes streams the data changed since the cursor with checkpoints to resume from
+	Changes(ctx context.Context, in *ChangesRequest, opts ...grpc.CallOption) (Keeper_ChangesClient, error)
+	// CreateBlob starts upload of a file or returns the blob with the offset its upload continues from
+	CreateBlob(ctx context.Context, in *Blob, opts ...grpc.CallOption) (*Blob, error)
+	// UploadBlob writes a chunk of the blob at the offset
+	UploadBlob(ctx context.Context, in *BlobChunk, opts ...grpc.CallOption) (*Blob, error)
+	// DownloadBlob returns a chunk of the uploaded blob from the offset
+	DownloadBlob(ctx context.Context, in *BlobChunk, opts ...grpc.CallOption) (*BlobChunk, error)
+	// GetKeys returns keys of the user, NotFound if they are not set
+	GetKeys(ctx context.Context, in *GetKeysRequest, opts ...grpc.CallOption) (*UserKeys, error)
+	// SetKeys sets keys of the user once
+	SetKeys(ctx context.Context, in *UserKeys, opts ...grpc.CallOption) (*SetKeysResponse, error)
+	// GetPublicKey returns public key of another user
+	GetPublicKey(ctx context.Context, in *PublicKeyRequest, opts ...grpc.CallOption) (*UserKeys, error)
+	// ShareItem shares the item of the user with another user or replaces key and permission of the share
+	ShareItem(ctx context.Context, in *Share, opts ...grpc.CallOption) (*Share, error)
+	// ListShares returns shares of the item of the user
+	ListShares(ctx context.Context, in *ListSharesRequest, opts ...grpc.CallOption) (*ListSharesResponse, error)
+	// RevokeShare revokes the share of the item of the user with another user
+	RevokeShare(ctx context.Context, in *RevokeShareRequest, opts ...grpc.CallOption) (*RevokeShareResponse, error)
+	// Shared returns items other users shared with the user
+	Shared(ctx context.Context, in *SharedRequest, opts ...grpc.CallOption) (*SharedResponse, error)
+	// UpdateShared stores an edit of the item shared with the user with write permission
+	UpdateShared(ctx context.Context, in *Data, opts ...grpc.CallOption) (*SyncResult, error)
+	// CreateOrg creates an organization owned by the user with the org key sealed to it
+	CreateOrg(ctx context.Context, in *Membership, opts ...grpc.CallOption) (*Membership, error)
+	// ListOrgs returns organizations of the user including the ones it's invited to
+	ListOrgs(ctx context.Context, in *ListOrgsRequest, opts ...grpc.CallOption) (*ListOrgsResponse, error)
+	// ListMembers returns members of the organization without their keys
+	ListMembers(ctx context.Context, in *OrgRequest, opts ...grpc.CallOption) (*ListMembersResponse, error)
+	// SetMember invites a user to the organization or changes its role
+	SetMember(ctx context.Context, in *Member, opts ...grpc.CallOption) (*Member, error)
+	// RemoveMember removes a member from the organization or the user leaves it
+	RemoveMember(ctx context.Context, in *RemoveMemberRequest, opts ...grpc.CallOption) (*RemoveMemberResponse, error)
+	// AcceptInvite accepts the invitation of the user to the organization
+	AcceptInvite(ctx context.Context, in *OrgRequest, opts ...grpc.CallOption) (*Member, error)
+	// ListCollections returns collections of the organization
+	ListCollections(ctx context.Context, in *OrgRequest, opts ...grpc.CallOption) (*ListCollectionsResponse, error)
+	// SetCollection adds a collection to the organization or renames it
+	SetCollection(ctx context.Context, in *Collection, opts ...grpc.CallOption) (*Collection, error)
+	// GetOrgData returns items of the organization with the version of the org key they are encrypted with
+	GetOrgData(ctx context.Context, in *OrgRequest, opts ...grpc.CallOption) (*OrgData, error)
+	// WriteOrgData stores items of the organization, FailedPrecondition if they are encrypted with an old org key
+	WriteOrgData(ctx context.Context, in *OrgData, opts ...grpc.CallOption) (*WriteOrgDataResponse, error)
+	// RotateOrgKey replaces the org key and re-encrypts every item, Aborted if items were changed meanwhile
+	RotateOrgKey(ctx context.Context, in *KeyRotation, opts ...grpc.CallOption) (*WriteOrgDataResponse, error)
+	// ListEmergencyContacts returns trusted contacts of the user without their keys
+	ListEmergencyContacts(ctx context.Context, in *ListEmergencyRequest, opts ...grpc.CallOption) (*ListEmergencyResponse, error)
+	// SetEmergencyContact adds a trusted contact with the vault key sealed to it or changes its waiting period
+	SetEmergencyContact(ctx context.Context, in *EmergencyAccess, opts ...grpc.CallOption) (*EmergencyAccess, error)
+	// RemoveEmergencyContact removes the trusted contact of the user
+	RemoveEmergencyContact(ctx context.Context, in *EmergencyRequest, opts ...grpc.CallOption) (*RemoveEmergencyContactResponse, error)
+	// RejectEmergencyAccess rejects the request of the trusted contact
+	RejectEmergencyAccess(ctx context.Context, in *EmergencyRequest, opts ...grpc.CallOption) (*EmergencyAccess, error)
+	// ListEmergencyGrantors returns users that trust the user, keys are sent only when access is granted
+	ListEmergencyGrantors(ctx context.Context, in *ListEmergencyRequest, opts ...grpc.CallOption) (*ListEmergencyResponse, error)
+	// RequestEmergencyAccess requests access to the vault of the grantor
+	RequestEmergencyAccess(ctx context.Context, in *EmergencyRequest, opts ...grpc.CallOption) (*EmergencyAccess, error)
+	// GetEmergencyVault returns the vault of the grantor, PermissionDenied until the waiting period is over
+	GetEmergencyVault(ctx context.Context, in *EmergencyRequest, opts ...grpc.CallOption) (*EmergencyVault, error)
+	// ListSends returns sends of the user without their data
+	ListSends(ctx context.Context, in *ListSendsRequest, opts ...grpc.CallOption) (*ListSendsResponse, error)
+	// CreateSend creates a send with the encrypted secret, the server chooses its id
+	CreateSend(ctx context.Context, in *Send, opts ...grpc.CallOption) (*Send, error)
+	// DeleteSend removes the send of the user before it expires
+	DeleteSend(ctx context.Context, in *SendRequest, opts ...grpc.CallOption) (*DeleteSendResponse, error)
+	// ReceiveSend returns the send and counts the view, it needs no session, NotFound once it is expired or viewed
+	ReceiveSend(ctx context.Context, in *SendRequest, opts ...grpc.CallOption) (*Send, error)
+}
+
+type keeperClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewKeeperClient(cc grpc.ClientConnInterface) KeeperClient {
+	return &keeperClient{cc}
+}
+
+func (c *keeperClient) Register(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (*Session, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Session)
+	err := c.cc.Invoke(ctx, Keeper_Register_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) Login(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (*Session, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Session)
+	err := c.cc.Invoke(ctx, Keeper_Login_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) Logout(ctx context.Context, in *LogoutRequest, opts ...grpc.CallOption) (*LogoutResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LogoutResponse)
+	err := c.cc.Invoke(ctx, Keeper_Logout_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) Sync(ctx context.Context, in *SyncRequest, opts ...grpc.CallOption) (*SyncResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SyncResponse)
+	err := c.cc.Invoke(ctx, Keeper_Sync_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) ListRevisions(ctx context.Context, in *ListRevisionsRequest, opts ...grpc.CallOption) (*ListRevisionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListRevisionsResponse)
+	err := c.cc.Invoke(ctx, Keeper_ListRevisions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) GetRevision(ctx context.Context, in *GetRevisionRequest, opts ...grpc.CallOption) (*Data, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Data)
+	err := c.cc.Invoke(ctx, Keeper_GetRevision_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) Events(ctx context.Context, in *EventsRequest, opts ...grpc.CallOption) (Keeper_EventsClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Keeper_ServiceDesc.Streams[0], Keeper_Events_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &keeperEventsClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Keeper_EventsClient interface {
+	Recv() (*ChangeEvent, error)
+	grpc.ClientStream
+}
+
+type keeperEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *keeperEventsClient) Recv() (*ChangeEvent, error) {
+	m := new(ChangeEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *keeperClient) Changes(ctx context.Context, in *ChangesRequest, opts ...grpc.CallOption) (Keeper_ChangesClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Keeper_ServiceDesc.Streams[1], Keeper_Changes_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &keeperChangesClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Keeper_ChangesClient interface {
+	Recv() (*ChangeLine, error)
+	grpc.ClientStream
+}
+
+type keeperChangesClient struct {
+	grpc.ClientStream
+}
+
+func (x *keeperChangesClient) Recv() (*ChangeLine, error) {
+	m := new(ChangeLine)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *keeperClient) CreateBlob(ctx context.Context, in *Blob, opts ...grpc.CallOption) (*Blob, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Blob)
+	err := c.cc.Invoke(ctx, Keeper_CreateBlob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) UploadBlob(ctx context.Context, in *BlobChunk, opts ...grpc.CallOption) (*Blob, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Blob)
+	err := c.cc.Invoke(ctx, Keeper_UploadBlob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) DownloadBlob(ctx context.Context, in *BlobChunk, opts ...grpc.CallOption) (*BlobChunk, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BlobChunk)
+	err := c.cc.Invoke(ctx, Keeper_DownloadBlob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) GetKeys(ctx context.Context, in *GetKeysRequest, opts ...grpc.CallOption) (*UserKeys, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UserKeys)
+	err := c.cc.Invoke(ctx, Keeper_GetKeys_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) SetKeys(ctx context.Context, in *UserKeys, opts ...grpc.CallOption) (*SetKeysResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetKeysResponse)
+	err := c.cc.Invoke(ctx, Keeper_SetKeys_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) GetPublicKey(ctx context.Context, in *PublicKeyRequest, opts ...grpc.CallOption) (*UserKeys, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UserKeys)
+	err := c.cc.Invoke(ctx, Keeper_GetPublicKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) ShareItem(ctx context.Context, in *Share, opts ...grpc.CallOption) (*Share, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Share)
+	err := c.cc.Invoke(ctx, Keeper_ShareItem_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) ListShares(ctx context.Context, in *ListSharesRequest, opts ...grpc.CallOption) (*ListSharesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListSharesResponse)
+	err := c.cc.Invoke(ctx, Keeper_ListShares_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) RevokeShare(ctx context.Context, in *RevokeShareRequest, opts ...grpc.CallOption) (*RevokeShareResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RevokeShareResponse)
+	err := c.cc.Invoke(ctx, Keeper_RevokeShare_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) Shared(ctx context.Context, in *SharedRequest, opts ...grpc.CallOption) (*SharedResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SharedResponse)
+	err := c.cc.Invoke(ctx, Keeper_Shared_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) UpdateShared(ctx context.Context, in *Data, opts ...grpc.CallOption) (*SyncResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SyncResult)
+	err := c.cc.Invoke(ctx, Keeper_UpdateShared_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) CreateOrg(ctx context.Context, in *Membership, opts ...grpc.CallOption) (*Membership, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Membership)
+	err := c.cc.Invoke(ctx, Keeper_CreateOrg_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) ListOrgs(ctx context.Context, in *ListOrgsRequest, opts ...grpc.CallOption) (*ListOrgsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListOrgsResponse)
+	err := c.cc.Invoke(ctx, Keeper_ListOrgs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) ListMembers(ctx context.Context, in *OrgRequest, opts ...grpc.CallOption) (*ListMembersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListMembersResponse)
+	err := c.cc.Invoke(ctx, Keeper_ListMembers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) SetMember(ctx context.Context, in *Member, opts ...grpc.CallOption) (*Member, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Member)
+	err := c.cc.Invoke(ctx, Keeper_SetMember_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) RemoveMember(ctx context.Context, in *RemoveMemberRequest, opts ...grpc.CallOption) (*RemoveMemberResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RemoveMemberResponse)
+	err := c.cc.Invoke(ctx, Keeper_RemoveMember_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) AcceptInvite(ctx context.Context, in *OrgRequest, opts ...grpc.CallOption) (*Member, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Member)
+	err := c.cc.Invoke(ctx, Keeper_AcceptInvite_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) ListCollections(ctx context.Context, in *OrgRequest, opts ...grpc.CallOption) (*ListCollectionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListCollectionsResponse)
+	err := c.cc.Invoke(ctx, Keeper_ListCollections_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) SetCollection(ctx context.Context, in *Collection, opts ...grpc.CallOption) (*Collection, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Collection)
+	err := c.cc.Invoke(ctx, Keeper_SetCollection_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) GetOrgData(ctx context.Context, in *OrgRequest, opts ...grpc.CallOption) (*OrgData, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OrgData)
+	err := c.cc.Invoke(ctx, Keeper_GetOrgData_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) WriteOrgData(ctx context.Context, in *OrgData, opts ...grpc.CallOption) (*WriteOrgDataResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WriteOrgDataResponse)
+	err := c.cc.Invoke(ctx, Keeper_WriteOrgData_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) RotateOrgKey(ctx context.Context, in *KeyRotation, opts ...grpc.CallOption) (*WriteOrgDataResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WriteOrgDataResponse)
+	err := c.cc.Invoke(ctx, Keeper_RotateOrgKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) ListEmergencyContacts(ctx context.Context, in *ListEmergencyRequest, opts ...grpc.CallOption) (*ListEmergencyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListEmergencyResponse)
+	err := c.cc.Invoke(ctx, Keeper_ListEmergencyContacts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) SetEmergencyContact(ctx context.Context, in *EmergencyAccess, opts ...grpc.CallOption) (*EmergencyAccess, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EmergencyAccess)
+	err := c.cc.Invoke(ctx, Keeper_SetEmergencyContact_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) RemoveEmergencyContact(ctx context.Context, in *EmergencyRequest, opts ...grpc.CallOption) (*RemoveEmergencyContactResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RemoveEmergencyContactResponse)
+	err := c.cc.Invoke(ctx, Keeper_RemoveEmergencyContact_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) RejectEmergencyAccess(ctx context.Context, in *EmergencyRequest, opts ...grpc.CallOption) (*EmergencyAccess, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EmergencyAccess)
+	err := c.cc.Invoke(ctx, Keeper_RejectEmergencyAccess_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) ListEmergencyGrantors(ctx context.Context, in *ListEmergencyRequest, opts ...grpc.CallOption) (*ListEmergencyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListEmergencyResponse)
+	err := c.cc.Invoke(ctx, Keeper_ListEmergencyGrantors_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) RequestEmergencyAccess(ctx context.Context, in *EmergencyRequest, opts ...grpc.CallOption) (*EmergencyAccess, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EmergencyAccess)
+	err := c.cc.Invoke(ctx, Keeper_RequestEmergencyAccess_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) GetEmergencyVault(ctx context.Context, in *EmergencyRequest, opts ...grpc.CallOption) (*EmergencyVault, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EmergencyVault)
+	err := c.cc.Invoke(ctx, Keeper_GetEmergencyVault_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) ListSends(ctx context.Context, in *ListSendsRequest, opts ...grpc.CallOption) (*ListSendsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListSendsResponse)
+	err := c.cc.Invoke(ctx, Keeper_ListSends_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) CreateSend(ctx context.Context, in *Send, opts ...grpc.CallOption) (*Send, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Send)
+	err := c.cc.Invoke(ctx, Keeper_CreateSend_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) DeleteSend(ctx context.Context, in *SendRequest, opts ...grpc.CallOption) (*DeleteSendResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteSendResponse)
+	err := c.cc.Invoke(ctx, Keeper_DeleteSend_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keeperClient) ReceiveSend(ctx context.Context, in *SendRequest, opts ...grpc.CallOption) (*Send, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Send)
+	err := c.cc.Invoke(ctx, Keeper_ReceiveSend_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// KeeperServer is the server API for Keeper service.
+// All implementations must embed UnimplementedKeeperServer
+// for forward compatibility
+//
+// Keeper is the gRPC API of the server, it mirrors the REST API
+// every method except Register and Login requires session id in "session_id" metadata
+type KeeperServer interface {
+	// Register creates a new user and a session for it
+	Register(context.Context, *Credentials) (*Session, error)
+	// Login creates a new session for the user
+	Login(context.Context, *Credentials) (*Session, error)
+	// Logout ends the session of the request or all sessions of the user
+	Logout(context.Context, *LogoutRequest) (*LogoutResponse, error)
+	// Sync stores the items changed by client and returns the data changed since the cursor
+	Sync(context.Context, *SyncRequest) (*SyncResponse, error)
+	// ListRevisions returns previous versions of the item without data, newest first
+	ListRevisions(context.Context, *ListRevisionsRequest) (*ListRevisionsResponse, error)
+	// GetRevision returns previous version of the item with its data
+	GetRevision(context.Context, *GetRevisionRequest) (*Data, error)
+	// Events streams changes of the user's data until client cancels the call
+	Events(*EventsRequest, Keeper_EventsServer) error
+	// Changes streams the data changed since the cursor with checkpoints to resume from
+	Changes(*ChangesRequest, Keeper_ChangesServer) error
+	// CreateBlob starts upload of a file or returns the blob with the offset its upload continues from
+	CreateBlob(context.Context, *Blob) (*Blob, error)
+	// UploadBlob writes a chunk of the blob at the offset
+	UploadBlob(context.Context, *BlobChunk) (*Blob, error)
+	// DownloadBlob returns a chunk of the uploaded blob from the offset
+	DownloadBlob(context.Context, *BlobChunk) (*BlobChunk, error)
+	// GetKeys returns keys of the user, NotFound if they are not set
+	GetKeys(context.Context, *GetKeysRequest) (*UserKeys, error)
+	// SetKeys sets keys of the user once
+	SetKeys(context.Context, *UserKeys) (*SetKeysResponse, error)
+	// GetPublicKey returns public key of another user
+	GetPublicKey(context.Context, *PublicKeyRequest) (*UserKeys, error)
+	// ShareItem shares the item of the user with another user or replaces key and permission of the share
+	ShareItem(context.Context, *Share) (*Share, error)
+	// ListShares returns shares of the item of the user
+	ListShares(context.Context, *ListSharesRequest) (*ListSharesResponse, error)
+	// RevokeShare revokes the share of the item of the user with another user
+	RevokeShare(context.Context, *RevokeShareRequest) (*RevokeShareResponse, error)
+	// Shared returns items other users shared with the user
+	Shared(context.Context, *SharedRequest) (*SharedResponse, error)
+	// UpdateShared stores an edit of the item shared with the user with write permission
+	UpdateShared(context.Context, *Data) (*SyncResult, error)
+	// CreateOrg creates an organization owned by the user with the org key sealed to it
+	CreateOrg(context.Context, *Membership) (*Membership, error)
+	// ListOrgs returns organizations of the user including the ones it's invited to
+	ListOrgs(context.Context, *ListOrgsRequest) (*ListOrgsResponse, error)
+	// ListMembers returns members of the organization without their keys
+	ListMembers(context.Context, *OrgRequest) (*ListMembersResponse, error)
+	// SetMember invites a user to the organization or changes its role
+	SetMember(context.Context, *Member) (*Member, error)
+	// RemoveMember removes a member from the organization or the user leaves it
+	RemoveMember(context.Context, *RemoveMemberRequest) (*RemoveMemberResponse, error)
+	// AcceptInvite accepts the invitation of the user to the organization
+	AcceptInvite(context.Context, *OrgRequest) (*Member, error)
+	// ListCollections returns collections of the organization
+	ListCollections(context.Context, *OrgRequest) (*ListCollectionsResponse, error)
+	// SetCollection adds a collection to the organization or renames it
+	SetCollection(context.Context, *Collection) (*Collection, error)
+	// GetOrgData returns items of the organization with the version of the org key they are encrypted with
+	GetOrgData(context.Context, *OrgRequest) (*OrgData, error)
+	// WriteOrgData stores items of the organization, FailedPrecondition if they are encrypted with an old org key
+	WriteOrgData(context.Context, *OrgData) (*WriteOrgDataResponse, error)
+	// RotateOrgKey replaces the org key and re-encrypts every item, Aborted if items were changed meanwhile
+	RotateOrgKey(context.Context, *KeyRotation) (*WriteOrgDataResponse, error)
+	// ListEmergencyContacts returns trusted contacts of the user without their keys
+	ListEmergencyContacts(context.Context, *ListEmergencyRequest) (*ListEmergencyResponse, error)
+	// SetEmergencyContact adds a trusted contact with the vault key sealed to it or changes its waiting period
+	SetEmergencyContact(context.Context, *EmergencyAccess) (*EmergencyAccess, error)
+	// RemoveEmergencyContact removes the trusted contact of the user
+	RemoveEmergencyContact(context.Context, *EmergencyRequest) (*RemoveEmergencyContactResponse, error)
+	// RejectEmergencyAccess rejects the request of the trusted contact
+	RejectEmergencyAccess(context.Context, *EmergencyRequest) (*EmergencyAccess, error)
+	// ListEmergencyGrantors returns users that trust the user, keys are sent only when access is granted
+	ListEmergencyGrantors(context.Context, *ListEmergencyRequest) (*ListEmergencyResponse, error)
+	// RequestEmergencyAccess requests access to the vault of the grantor
+	RequestEmergencyAccess(context.Context, *EmergencyRequest) (*EmergencyAccess, error)
+	// GetEmergencyVault returns the vault of the grantor, PermissionDenied until the waiting period is over
+	GetEmergencyVault(context.Context, *EmergencyRequest) (*EmergencyVault, error)
+	// ListSends returns sends of the user without their data
+	ListSends(context.Context, *ListSendsRequest) (*ListSendsResponse, error)
+	// CreateSend creates a send with the encrypted secret, the server chooses its id
+	CreateSend(context.Context, *Send) (*Send, error)
+	// DeleteSend removes the send of the user before it expires
+	DeleteSend(context.Context, *SendRequest) (*DeleteSendResponse, error)
+	// ReceiveSend returns the send and counts the view, it needs no session, NotFound once it is expired or viewed
+	ReceiveSend(context.Context, *SendRequest) (*Send, error)
+	mustEmbedUnimplementedKeeperServer()
+}
+
+// UnimplementedKeeperServer must be embedded to have forward compatible implementations.
+type UnimplementedKeeperServer struct {
+}
+
+func (UnimplementedKeeperServer) Register(context.Context, *Credentials) (*Session, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Register not implemented")
+}
+func (UnimplementedKeeperServer) Login(context.Context, *Credentials) (*Session, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Login not implemented")
+}
+func (UnimplementedKeeperServer) Logout(context.Context, *LogoutRequest) (*LogoutResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Logout not implemented")
+}
+func (UnimplementedKeeperServer) Sync(context.Context, *SyncRequest) (*SyncResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Sync not implemented")
+}
+func (UnimplementedKeeperServer) ListRevisions(context.Context, *ListRevisionsRequest) (*ListRevisionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRevisions not implemented")
+}
+func (UnimplementedKeeperServer) GetRevision(context.Context, *GetRevisionRequest) (*Data, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRevision not implemented")
+}
+func (UnimplementedKeeperServer) Events(*EventsRequest, Keeper_EventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method Events not implemented")
+}
+func (UnimplementedKeeperServer) Changes(*ChangesRequest, Keeper_ChangesServer) error {
+	return status.Errorf(codes.Unimplemented, "method Changes not implemented")
+}
+func (UnimplementedKeeperServer) CreateBlob(context.Context, *Blob) (*Blob, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateBlob not implemented")
+}
+func (UnimplementedKeeperServer) UploadBlob(context.Context, *BlobChunk) (*Blob, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UploadBlob not implemented")
+}
+func (UnimplementedKeeperServer) DownloadBlob(context.Context, *BlobChunk) (*BlobChunk, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DownloadBlob not implemented")
+}
+func (UnimplementedKeeperServer) GetKeys(context.Context, *GetKeysRequest) (*UserKeys, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetKeys not implemented")
+}
+func (UnimplementedKeeperServer) SetKeys(context.Context, *UserKeys) (*SetKeysResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetKeys not implemented")
+}
+func (UnimplementedKeeperServer) GetPublicKey(context.Context, *PublicKeyRequest) (*UserKeys, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPublicKey not implemented")
+}
+func (UnimplementedKeeperServer) ShareItem(context.Context, *Share) (*Share, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ShareItem not implemented")
+}
+func (UnimplementedKeeperServer) ListShares(context.Context, *ListSharesRequest) (*ListSharesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListShares not implemented")
+}
+func (UnimplementedKeeperServer) RevokeShare(context.Context, *RevokeShareRequest) (*RevokeShareResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeShare not implemented")
+}
+func (UnimplementedKeeperServer) Shared(context.Context, *SharedRequest) (*SharedResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Shared not implemented")
+}
+func (UnimplementedKeeperServer) UpdateShared(context.Context, *Data) (*SyncResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateShared not implemented")
+}
+func (UnimplementedKeeperServer) CreateOrg(context.Context, *Membership) (*Membership, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateOrg not implemented")
+}
+func (UnimplementedKeeperServer) ListOrgs(context.Context, *ListOrgsRequest) (*ListOrgsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListOrgs not implemented")
+}
+func (UnimplementedKeeperServer) ListMembers(context.Context, *OrgRequest) (*ListMembersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMembers not implemented")
+}
+func (UnimplementedKeeperServer) SetMember(context.Context, *Member) (*Member, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetMember not implemented")
+}
+func (UnimplementedKeeperServer) RemoveMember(context.Context, *RemoveMemberRequest) (*RemoveMemberResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveMember not implemented")
+}
+func (UnimplementedKeeperServer) AcceptInvite(context.Context, *OrgRequest) (*Member, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AcceptInvite not implemented")
+}
+func (UnimplementedKeeperServer) ListCollections(context.Context, *OrgRequest) (*ListCollectionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCollections not implemented")
+}
+func (UnimplementedKeeperServer) SetCollection(context.Context, *Collection) (*Collection, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetCollection not implemented")
+}
+func (UnimplementedKeeperServer) GetOrgData(context.Context, *OrgRequest) (*OrgData, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOrgData not implemented")
+}
+func (UnimplementedKeeperServer) WriteOrgData(context.Context, *OrgData) (*WriteOrgDataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WriteOrgData not implemented")
+}
+func (UnimplementedKeeperServer) RotateOrgKey(context.Context, *KeyRotation) (*WriteOrgDataResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RotateOrgKey not implemented")
+}
+func (UnimplementedKeeperServer) ListEmergencyContacts(context.Context, *ListEmergencyRequest) (*ListEmergencyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListEmergencyContacts not implemented")
+}
+func (UnimplementedKeeperServer) SetEmergencyContact(context.Context, *EmergencyAccess) (*EmergencyAccess, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetEmergencyContact not implemented")
+}
+func (UnimplementedKeeperServer) RemoveEmergencyContact(context.Context, *EmergencyRequest) (*RemoveEmergencyContactResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveEmergencyContact not implemented")
+}
+func (UnimplementedKeeperServer) RejectEmergencyAccess(context.Context, *EmergencyRequest) (*EmergencyAccess, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RejectEmergencyAccess not implemented")
+}
+func (UnimplementedKeeperServer) ListEmergencyGrantors(context.Context, *ListEmergencyRequest) (*ListEmergencyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListEmergencyGrantors not implemented")
+}
+func (UnimplementedKeeperServer) RequestEmergencyAccess(context.Context, *EmergencyRequest) (*EmergencyAccess, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestEmergencyAccess not implemented")
+}
+func (UnimplementedKeeperServer) GetEmergencyVault(context.Context, *EmergencyRequest) (*EmergencyVault, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEmergencyVault not implemented")
+}
+func (UnimplementedKeeperServer) ListSends(context.Context, *ListSendsRequest) (*ListSendsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSends not implemented")
+}
+func (UnimplementedKeeperServer) CreateSend(context.Context, *Send) (*Send, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateSend not implemented")
+}
+func (UnimplementedKeeperServer) DeleteSend(context.Context, *SendRequest) (*DeleteSendResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSend not implemented")
+}
+func (UnimplementedKeeperServer) ReceiveSend(context.Context, *SendRequest) (*Send, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReceiveSend not implemented")
+}
+func (UnimplementedKeeperServer) mustEmbedUnimplementedKeeperServer() {}
+
+// UnsafeKeeperServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to KeeperServer will
+// result in compilation errors.
+type UnsafeKeeperServer interface {
+	mustEmbedUnimplementedKeeperServer()
+}
+
+func RegisterKeeperServer(s grpc.ServiceRegistrar, srv KeeperServer) {
+	s.RegisterService(&Keeper_ServiceDesc, srv)
+}
+
+func _Keeper_Register_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Credentials)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).Register(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_Register_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).Register(ctx, req.(*Credentials))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_Login_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Credentials)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).Login(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_Login_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).Login(ctx, req.(*Credentials))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_Logout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LogoutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).Logout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_Logout_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).Logout(ctx, req.(*LogoutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_Sync_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SyncRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).Sync(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_Sync_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).Sync(ctx, req.(*SyncRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_ListRevisions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRevisionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).ListRevisions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_ListRevisions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).ListRevisions(ctx, req.(*ListRevisionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_GetRevision_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRevisionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).GetRevision(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_GetRevision_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).GetRevision(ctx, req.(*GetRevisionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_Events_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(EventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(KeeperServer).Events(m, &keeperEventsServer{ServerStream: stream})
+}
+
+type Keeper_EventsServer interface {
+	Send(*ChangeEvent) error
+	grpc.ServerStream
+}
+
+type keeperEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *keeperEventsServer) Send(m *ChangeEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Keeper_Changes_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ChangesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(KeeperServer).Changes(m, &keeperChangesServer{ServerStream: stream})
+}
+
+type Keeper_ChangesServer interface {
+	Send(*ChangeLine) error
+	grpc.ServerStream
+}
+
+type keeperChangesServer struct {
+	grpc.ServerStream
+}
+
+func (x *keeperChangesServer) Send(m *ChangeLine) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Keeper_CreateBlob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Blob)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).CreateBlob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_CreateBlob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).CreateBlob(ctx, req.(*Blob))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_UploadBlob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlobChunk)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).UploadBlob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_UploadBlob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).UploadBlob(ctx, req.(*BlobChunk))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_DownloadBlob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlobChunk)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).DownloadBlob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_DownloadBlob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).DownloadBlob(ctx, req.(*BlobChunk))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_GetKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).GetKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_GetKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).GetKeys(ctx, req.(*GetKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_SetKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserKeys)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).SetKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_SetKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).SetKeys(ctx, req.(*UserKeys))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_GetPublicKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PublicKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).GetPublicKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_GetPublicKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).GetPublicKey(ctx, req.(*PublicKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_ShareItem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Share)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).ShareItem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_ShareItem_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).ShareItem(ctx, req.(*Share))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_ListShares_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSharesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).ListShares(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_ListShares_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).ListShares(ctx, req.(*ListSharesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_RevokeShare_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeShareRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).RevokeShare(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_RevokeShare_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).RevokeShare(ctx, req.(*RevokeShareRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_Shared_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SharedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).Shared(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_Shared_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).Shared(ctx, req.(*SharedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_UpdateShared_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Data)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).UpdateShared(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_UpdateShared_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).UpdateShared(ctx, req.(*Data))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_CreateOrg_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Membership)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).CreateOrg(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_CreateOrg_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).CreateOrg(ctx, req.(*Membership))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_ListOrgs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListOrgsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).ListOrgs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_ListOrgs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).ListOrgs(ctx, req.(*ListOrgsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_ListMembers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OrgRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).ListMembers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_ListMembers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).ListMembers(ctx, req.(*OrgRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_SetMember_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Member)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).SetMember(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_SetMember_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).SetMember(ctx, req.(*Member))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_RemoveMember_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveMemberRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).RemoveMember(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_RemoveMember_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).RemoveMember(ctx, req.(*RemoveMemberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_AcceptInvite_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OrgRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).AcceptInvite(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_AcceptInvite_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).AcceptInvite(ctx, req.(*OrgRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_ListCollections_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OrgRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).ListCollections(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_ListCollections_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).ListCollections(ctx, req.(*OrgRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_SetCollection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Collection)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).SetCollection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_SetCollection_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).SetCollection(ctx, req.(*Collection))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_GetOrgData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OrgRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).GetOrgData(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_GetOrgData_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).GetOrgData(ctx, req.(*OrgRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_WriteOrgData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OrgData)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).WriteOrgData(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_WriteOrgData_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).WriteOrgData(ctx, req.(*OrgData))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_RotateOrgKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeyRotation)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).RotateOrgKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_RotateOrgKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).RotateOrgKey(ctx, req.(*KeyRotation))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_ListEmergencyContacts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListEmergencyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).ListEmergencyContacts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_ListEmergencyContacts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).ListEmergencyContacts(ctx, req.(*ListEmergencyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_SetEmergencyContact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmergencyAccess)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).SetEmergencyContact(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_SetEmergencyContact_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).SetEmergencyContact(ctx, req.(*EmergencyAccess))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_RemoveEmergencyContact_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmergencyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).RemoveEmergencyContact(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_RemoveEmergencyContact_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).RemoveEmergencyContact(ctx, req.(*EmergencyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_RejectEmergencyAccess_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmergencyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).RejectEmergencyAccess(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_RejectEmergencyAccess_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).RejectEmergencyAccess(ctx, req.(*EmergencyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_ListEmergencyGrantors_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListEmergencyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).ListEmergencyGrantors(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_ListEmergencyGrantors_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).ListEmergencyGrantors(ctx, req.(*ListEmergencyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_RequestEmergencyAccess_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmergencyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).RequestEmergencyAccess(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_RequestEmergencyAccess_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).RequestEmergencyAccess(ctx, req.(*EmergencyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_GetEmergencyVault_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmergencyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).GetEmergencyVault(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_GetEmergencyVault_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).GetEmergencyVault(ctx, req.(*EmergencyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_ListSends_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSendsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).ListSends(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_ListSends_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).ListSends(ctx, req.(*ListSendsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_CreateSend_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Send)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).CreateSend(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_CreateSend_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).CreateSend(ctx, req.(*Send))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_DeleteSend_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).DeleteSend(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_DeleteSend_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).DeleteSend(ctx, req.(*SendRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Keeper_ReceiveSend_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeeperServer).ReceiveSend(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Keeper_ReceiveSend_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeeperServer).ReceiveSend(ctx, req.(*SendRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Keeper_ServiceDesc is the grpc.ServiceDesc for Keeper service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Keeper_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "keeper.Keeper",
+	HandlerType: (*KeeperServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Register",
+			Handler:    _Keeper_Register_Handler,
+		},
+		{
+			MethodName: "Login",
+			Handler:    _Keeper_Login_Handler,
+		},
+		{
+			MethodName: "Logout",
+			Handler:    _Keeper_Logout_Handler,
+		},
+		{
+			MethodName: "Sync",
+			Handler:    _Keeper_Sync_Handler,
+		},
+		{
+			MethodName: "ListRevisions",
+			Handler:    _Keeper_ListRevisions_Handler,
+		},
+		{
+			MethodName: "GetRevision",
+			Handler:    _Keeper_GetRevision_Handler,
+		},
+		{
+			MethodName: "CreateBlob",
+			Handler:    _Keeper_CreateBlob_Handler,
+		},
+		{
+			MethodName: "UploadBlob",
+			Handler:    _Keeper_UploadBlob_Handler,
+		},
+		{
+			MethodName: "DownloadBlob",
+			Handler:    _Keeper_DownloadBlob_Handler,
+		},
+		{
+			MethodName: "GetKeys",
+			Handler:    _Keeper_GetKeys_Handler,
+		},
+		{
+			MethodName: "SetKeys",
+			Handler:    _Keeper_SetKeys_Handler,
+		},
+		{
+			MethodName: "GetPublicKey",
+			Handler:    _Keeper_GetPublicKey_Handler,
+		},
+		{
+			MethodName: "ShareItem",
+			Handler:    _Keeper_ShareItem_Handler,
+		},
+		{
+			MethodName: "ListShares",
+			Handler:    _Keeper_ListShares_Handler,
+		},
+		{
+			MethodName: "RevokeShare",
+			Handler:    _Keeper_RevokeShare_Handler,
+		},
+		{
+			MethodName: "Shared",
+			Handler:    _Keeper_Shared_Handler,
+		},
+		{
+			MethodName: "UpdateShared",
+			Handler:    _Keeper_UpdateShared_Handler,
+		},
+		{
+			MethodName: "CreateOrg",
+			Handler:    _Keeper_CreateOrg_Handler,
+		},
+		{
+			MethodName: "ListOrgs",
+			Handler:    _Keeper_ListOrgs_Handler,
+		},
+		{
+			MethodName: "ListMembers",
+			Handler:    _Keeper_ListMembers_Handler,
+		},
+		{
+			MethodName: "SetMember",
+			Handler:    _Keeper_SetMember_Handler,
+		},
+		{
+			MethodName: "RemoveMember",
+			Handler:    _Keeper_RemoveMember_Handler,
+		},
+		{
+			MethodName: "AcceptInvite",
+			Handler:    _Keeper_AcceptInvite_Handler,
+		},
+		{
+			MethodName: "ListCollections",
+			Handler:    _Keeper_ListCollections_Handler,
+		},
+		{
+			MethodName: "SetCollection",
+			Handler:    _Keeper_SetCollection_Handler,
+		},
+		{
+			MethodName: "GetOrgData",
+			Handler:    _Keeper_GetOrgData_Handler,
+		},
+		{
+			MethodName: "WriteOrgData",
+			Handler:    _Keeper_WriteOrgData_Handler,
+		},
+		{
+			MethodName: "RotateOrgKey",
+			Handler:    _Keeper_RotateOrgKey_Handler,
+		},
+		{
+			MethodName: "ListEmergencyContacts",
+			Handler:    _Keeper_ListEmergencyContacts_Handler,
+		},
+		{
+			MethodName: "SetEmergencyContact",
+			Handler:    _Keeper_SetEmergencyContact_Handler,
+		},
+		{
+			MethodName: "RemoveEmergencyContact",
+			Handler:    _Keeper_RemoveEmergencyContact_Handler,
+		},
+		{
+			MethodName: "RejectEmergencyAccess",
+			Handler:    _Keeper_RejectEmergencyAccess_Handler,
+		},
+		{
+			MethodName: "ListEmergencyGrantors",
+			Handler:    _Keeper_ListEmergencyGrantors_Handler,
+		},
+		{
+			MethodName: "RequestEmergencyAccess",
+			Handler:    _Keeper_RequestEmergencyAccess_Handler,
+		},
+		{
+			MethodName: "GetEmergencyVault",
+			Handler:    _Keeper_GetEmergencyVault_Handler,
+		},
+		{
+			MethodName: "ListSends",
+			Handler:    _Keeper_ListSends_Handler,
+		},
+		{
+			MethodName: "CreateSend",
+			Handler:    _Keeper_CreateSend_Handler,
+		},
+		{
+			MethodName: "DeleteSend",
+			Handler:    _Keeper_DeleteSend_Handler,
+		},
+		{
+			MethodName: "ReceiveSend",
+			Handler:    _Keeper_ReceiveSend_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Events",
+			Handler:       _Keeper_Events_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Changes",
+			Handler:       _Keeper_Changes_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "keeper.proto",
+}
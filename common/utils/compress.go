@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content encodings supported by server and client
+const (
+	EncodingZstd = "zstd"
+	EncodingGzip = "gzip"
+)
+
+// Encodings are supported content encodings in order of preference
+var Encodings = []string{EncodingZstd, EncodingGzip}
+
+// AcceptEncoding is the value of Accept-Encoding header with all supported encodings
+var AcceptEncoding = strings.Join(Encodings, ", ")
+
+// maxDecodedSize limits memory used by zstd decoder for one frame
+const maxDecodedSize = 1 << 30
+
+// ErrUnsupportedEncoding is returned for content encoding that is not supported
+var ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+
+var (
+	// zstd encoder and decoder without stream are safe for concurrent EncodeAll and DecodeAll
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(maxDecodedSize))
+)
+
+// NegotiateEncoding returns the most preferred supported encoding listed in Accept-Encoding header
+// encodings with q=0 are not accepted, returns empty string if none is supported
+func NegotiateEncoding(accept string) string {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+				continue
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	for _, encoding := range Encodings {
+		if accepted[encoding] {
+			return encoding
+		}
+	}
+	return ""
+}
+
+// NewEncoder returns writer that compresses everything written to w with the encoding
+// it must be closed to flush compressed data
+func NewEncoder(encoding string, w io.Writer) (io.WriteCloser, error) {
+	switch encoding {
+	case EncodingZstd:
+		return zstd.NewWriter(w)
+	case EncodingGzip:
+		return gzip.NewWriter(w), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedEncoding, encoding)
+}
+
+// NewDecoder returns reader that decompresses r with the encoding
+func NewDecoder(encoding string, r io.Reader) (io.ReadCloser, error) {
+	switch encoding {
+	case EncodingZstd:
+		decoder, err := zstd.NewReader(r, zstd.WithDecoderMaxMemory(maxDecodedSize), zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return zstdReadCloser{decoder}, nil
+	case EncodingGzip:
+		return gzip.NewReader(r)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedEncoding, encoding)
+}
+
+// zstdReadCloser releases zstd decoder on Close
+type zstdReadCloser struct {
+	*zstd.Decoder
+}
+
+func (r zstdReadCloser) Close() error {
+	r.Decoder.Close()
+	return nil
+}
+
+// Compress compresses data with zstd
+func Compress(data []byte) []byte {
+	return zstdEncoder.EncodeAll(data, nil)
+}
+
+// Decompress decompresses data compressed by Compress
+func Decompress(data []byte) ([]byte, error) {
+	return zstdDecoder.DecodeAll(data, nil)
+}
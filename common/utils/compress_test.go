@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                     "",
+		"identity":             "",
+		"gzip":                 EncodingGzip,
+		"gzip, zstd":           EncodingZstd,
+		"ZSTD;q=0.5, gzip;q=1": EncodingZstd,
+		"zstd;q=0, gzip":       EncodingGzip,
+		"zstd; q=0.000, br":    "",
+		"deflate, br":          "",
+	}
+	for accept, want := range tests {
+		if got := NegotiateEncoding(accept); got != want {
+			t.Errorf("NegotiateEncoding(%q) = %q, want %q", accept, got, want)
+		}
+	}
+}
+
+func TestEncoders(t *testing.T) {
+	data := bytes.Repeat([]byte("compress me "), 1000)
+	for _, encoding := range Encodings {
+		var buf bytes.Buffer
+		encoder, err := NewEncoder(encoding, &buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = encoder.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		if err = encoder.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if buf.Len() >= len(data) {
+			t.Errorf("%s didn't compress data: %d bytes", encoding, buf.Len())
+		}
+
+		decoder, err := NewDecoder(encoding, &buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := io.ReadAll(decoder)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = decoder.Close()
+		if !bytes.Equal(decoded, data) {
+			t.Errorf("%s decoded data doesn't match", encoding)
+		}
+	}
+
+	if _, err := NewEncoder("br", io.Discard); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Errorf("Expected ErrUnsupportedEncoding, got %v", err)
+	}
+	if _, err := NewDecoder("br", bytes.NewReader(nil)); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Errorf("Expected ErrUnsupportedEncoding, got %v", err)
+	}
+}
+
+func TestCompress(t *testing.T) {
+	data := bytes.Repeat([]byte("compress me "), 1000)
+	decompressed, err := Decompress(Compress(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decompressed, data) {
+		t.Errorf("Decompressed data doesn't match")
+	}
+}
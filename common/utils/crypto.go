@@ -3,11 +3,16 @@ package utils
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
+	"errors"
 	"io"
 )
 
+// ErrCiphertextTooShort is returned by DecryptData for data too short to hold the nonce and the tag
+var ErrCiphertextTooShort = errors.New("ciphertext too short")
+
 // EncryptData encrypts data (Any length from 1 to ~) using a user's master key
 func EncryptData(data []byte, key string) ([]byte, error) {
 	key, err := deriveAESKey(key)
@@ -38,6 +43,33 @@ func EncryptData(data []byte, key string) ([]byte, error) {
 	return ciphertext, nil
 }
 
+// EncryptDataDeterministic encrypts data like EncryptData, but the nonce is derived from the key, context and data
+// so the same data in the same context is always encrypted the same way and content stored on the server is shared,
+// ciphertexts reveal only whether they have the same data. It is decrypted by DecryptData
+func EncryptDataDeterministic(data, context []byte, key string) ([]byte, error) {
+	key, err := deriveAESKey(key)
+	if err != nil {
+		return nil, err
+	}
+	c, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(c)
+	if err != nil {
+		return nil, err
+	}
+
+	// Nonce is HMAC of the data with the nonce key, which is HMAC of a fixed label with the encryption key,
+	// so the nonce key is a pseudorandom key of its own rather than a variation of the passphrase
+	mac := hmac.New(sha256.New, nonceKey(key))
+	mac.Write(context)
+	mac.Write(data)
+	nonce := mac.Sum(nil)[:gcm.NonceSize()]
+
+	return gcm.Seal(nonce, nonce, data, nil), nil
+}
+
 // DecryptData decrypts data (any length from 1 to ~) using a user's master key
 func DecryptData(ciphertext []byte, key string) ([]byte, error) {
 	key, err := deriveAESKey(key)
@@ -58,6 +90,10 @@ func DecryptData(ciphertext []byte, key string) ([]byte, error) {
 
 	// Get the nonce size
 	nonceSize := gcm.NonceSize()
+	// Data of other users, e.g. names of shared items, may be anything
+	if len(ciphertext) < nonceSize+gcm.Overhead() {
+		return nil, ErrCiphertextTooShort
+	}
 
 	// Get the nonce
 	nonce, ciphertext := ciphertext[:nonceSize], ciphertext[nonceSize:]
@@ -70,6 +106,13 @@ func DecryptData(ciphertext []byte, key string) ([]byte, error) {
 	return plaintext, nil
 }
 
+// nonceKey derives the key of EncryptDataDeterministic nonces from the AES key
+func nonceKey(aesKey string) []byte {
+	mac := hmac.New(sha256.New, []byte(aesKey))
+	mac.Write([]byte("goph-keeper deterministic nonce"))
+	return mac.Sum(nil)
+}
+
 // deriveAESKey derives a 256-bit AES key from a user's master key
 func deriveAESKey(userKey string) (string, error) {
 	// Hash the user-provided key using SHA-256 to generate a 256-bit key
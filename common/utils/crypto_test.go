@@ -35,6 +35,31 @@ func TestEncryptAndDecryptData(t *testing.T) {
 	}
 }
 
+func TestEncryptDataDeterministic(t *testing.T) {
+	masterKey := genRandomString(32)
+	data := []byte("the same file chunk")
+
+	first, err := EncryptDataDeterministic(data, []byte{1}, masterKey)
+	if err != nil {
+		t.Fatalf("Error encrypting data: %v", err)
+	}
+	second, _ := EncryptDataDeterministic(data, []byte{1}, masterKey)
+	if hex.EncodeToString(first) != hex.EncodeToString(second) {
+		t.Error("The same data is encrypted differently")
+	}
+	// Other context or key give other ciphertext
+	other, _ := EncryptDataDeterministic(data, []byte{2}, masterKey)
+	otherKey, _ := EncryptDataDeterministic(data, []byte{1}, genRandomString(32))
+	if hex.EncodeToString(first) == hex.EncodeToString(other) || hex.EncodeToString(first) == hex.EncodeToString(otherKey) {
+		t.Error("Ciphertext doesn't depend on context and key")
+	}
+
+	decrypted, err := DecryptData(first, masterKey)
+	if err != nil || string(decrypted) != string(data) {
+		t.Errorf("Expected %q decrypted, got %q and %v", data, decrypted, err)
+	}
+}
+
 func genRandomString(length int) string {
 	// Generate a random string of given length.
 	randomBytes := make([]byte, length)
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+)
+
+// ErrInvalidSealedKey is returned by OpenKey when the sealed key is too short to be made by SealKey
+var ErrInvalidSealedKey = errors.New("invalid sealed key")
+
+// MinSealedKeySize is the least size of a key sealed by SealKey: ephemeral public key, nonce and tag
+const MinSealedKeySize = 32 + 12 + 16
+
+// GenerateKeyPair returns a new X25519 key pair of the user, other users wrap keys of shared items to the public key
+func GenerateKeyPair() (public, private []byte, err error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key.PublicKey().Bytes(), key.Bytes(), nil
+}
+
+// GenerateItemKey returns a new random key of an item, it's used as passphrase of EncryptData
+func GenerateItemKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
+
+// SealKey encrypts the key to the X25519 public key, so only the owner of the private key can open it
+// a new ephemeral key pair is made for every call, its public key is the first 32 bytes of the result
+func SealKey(key string, public []byte) ([]byte, error) {
+	recipient, err := ecdh.X25519().NewPublicKey(public)
+	if err != nil {
+		return nil, err
+	}
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return nil, err
+	}
+	ephemeralPublic := ephemeral.PublicKey().Bytes()
+	encrypted, err := EncryptData([]byte(key), sealingKey(shared, ephemeralPublic, public))
+	if err != nil {
+		return nil, err
+	}
+	return append(ephemeralPublic, encrypted...), nil
+}
+
+// OpenKey decrypts the key sealed by SealKey with the X25519 private key
+func OpenKey(sealed, private []byte) (string, error) {
+	if len(sealed) < MinSealedKeySize {
+		return "", ErrInvalidSealedKey
+	}
+	key, err := ecdh.X25519().NewPrivateKey(private)
+	if err != nil {
+		return "", err
+	}
+	ephemeral, err := ecdh.X25519().NewPublicKey(sealed[:32])
+	if err != nil {
+		return "", err
+	}
+	shared, err := key.ECDH(ephemeral)
+	if err != nil {
+		return "", err
+	}
+	opened, err := DecryptData(sealed[32:], sealingKey(shared, sealed[:32], key.PublicKey().Bytes()))
+	if err != nil {
+		return "", err
+	}
+	return string(opened), nil
+}
+
+// sealingKey derives the key SealKey encrypts with from the shared secret and both public keys
+func sealingKey(shared, ephemeral, recipient []byte) string {
+	hash := sha256.New()
+	hash.Write(shared)
+	hash.Write(ephemeral)
+	hash.Write(recipient)
+	return string(hash.Sum(nil))
+}
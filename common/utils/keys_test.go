@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSealAndOpenKey(t *testing.T) {
+	public, private, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("Error generating key pair: %v", err)
+	}
+	key, err := GenerateItemKey()
+	if err != nil {
+		t.Fatalf("Error generating item key: %v", err)
+	}
+
+	sealed, err := SealKey(key, public)
+	if err != nil {
+		t.Fatalf("Error sealing key: %v", err)
+	}
+	// Every sealing has its own ephemeral key
+	again, _ := SealKey(key, public)
+	if bytes.Equal(sealed, again) {
+		t.Error("The key is sealed the same way twice")
+	}
+
+	opened, err := OpenKey(sealed, private)
+	if err != nil || opened != key {
+		t.Errorf("Expected %q opened, got %q and %v", key, opened, err)
+	}
+
+	// Other private key can't open it
+	_, otherPrivate, _ := GenerateKeyPair()
+	if _, err = OpenKey(sealed, otherPrivate); err == nil {
+		t.Error("Key is opened with another private key")
+	}
+	// Keys are sealed by other users, short ones are errors rather than panics
+	for _, size := range []int{10, 32, MinSealedKeySize - 1} {
+		if _, err = OpenKey(sealed[:size], private); err != ErrInvalidSealedKey {
+			t.Errorf("Expected %v for %d bytes, got %v", ErrInvalidSealedKey, size, err)
+		}
+	}
+}
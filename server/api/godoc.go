@@ -1,3 +1,4 @@
 // Package api Contains all the api routes for the server
 // Handlers, middlewares, session management and routes are defined here
+// gRPC API serving the same handlers is in rpc package
 package api
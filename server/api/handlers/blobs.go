@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/gynshu-one/goph-keeper/server/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// CreateBlob starts upload of a file attached to an item
+// request is models.Blob in json format with id, size and hash of the whole content
+// response is models.Blob with Offset the upload continues from, so creating it again resumes the upload
+// Blob that doesn't fit Limits is rejected with 413 status
+func (h *handler) CreateBlob(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var blob models.Blob
+	if err = json.NewDecoder(io.LimitReader(r.Body, 1<<10)).Decode(&blob); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	blob, err = h.NewBlob(r.Context(), session.GetUserID(), blob)
+	if err != nil {
+		http.Error(w, err.Error(), blobStatus(err))
+		return
+	}
+	writeJSON(w, blob)
+}
+
+// UploadBlob writes a chunk of the blob, blob id is passed as "id" url parameter: /user/blobs/{id}
+// offset of the chunk is passed in models.UploadOffsetHeader, body is the chunk of up to models.BlobChunkSize bytes
+// response is models.Blob with new Offset
+// chunk that is not at the end of uploaded content is rejected with 409 status and current offset in the header,
+// content that doesn't match the hash is dropped and rejected with 422 status
+func (h *handler) UploadBlob(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get(models.UploadOffsetHeader), 10, 64)
+	if err != nil {
+		http.Error(w, ErrInvalidOffset.Error(), http.StatusBadRequest)
+		return
+	}
+	chunk, err := io.ReadAll(http.MaxBytesReader(w, r.Body, models.BlobChunkSize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, fmt.Sprintf("%s: limit is %d bytes", ErrBodyTooLarge, maxBytesErr.Limit),
+				http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	blob, err := h.WriteBlob(r.Context(), session.GetUserID(), chi.URLParam(r, "id"), offset, chunk)
+	if err != nil {
+		if errors.Is(err, storage.ErrBlobOffset) || errors.Is(err, storage.ErrBlobHash) {
+			w.Header().Set(models.UploadOffsetHeader, strconv.FormatInt(blob.Offset, 10))
+		}
+		http.Error(w, err.Error(), blobStatus(err))
+		return
+	}
+	w.Header().Set(models.UploadOffsetHeader, strconv.FormatInt(blob.Offset, 10))
+	writeJSON(w, blob)
+}
+
+// DownloadBlob returns content of the uploaded blob, blob id is passed as "id" url parameter: /user/blobs/{id}
+// blobs of items shared with the user are downloaded the same way
+// part of the content is requested with "Range: bytes=start-end" header, so interrupted download is continued
+// content is read from storage chunk by chunk, hash of the whole content is in models.BlobHashHeader
+func (h *handler) DownloadBlob(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID, err := h.blobOwner(r.Context(), session.GetUserID(), chi.URLParam(r, "id"))
+	var blob models.Blob
+	if err == nil {
+		blob, err = h.storage.GetBlob(r.Context(), userID, chi.URLParam(r, "id"))
+	}
+	if err == nil && !blob.Complete() {
+		err = storage.ErrBlobIncomplete
+	}
+	if err != nil {
+		http.Error(w, err.Error(), blobStatus(err))
+		return
+	}
+	start, end, partial, err := parseRange(r.Header.Get("Range"), blob.Size)
+	if err != nil {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", blob.Size))
+		http.Error(w, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("Content-Length", strconv.FormatInt(end-start, 10))
+	w.Header().Set(models.BlobHashHeader, blob.Hash)
+	if partial {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end-1, blob.Size))
+		w.WriteHeader(http.StatusPartialContent)
+	}
+	for offset := start; offset < end; offset += models.BlobChunkSize {
+		chunk, err := h.storage.ReadBlob(r.Context(), userID, blob.ID, offset, min(models.BlobChunkSize, end-offset))
+		if err != nil {
+			// Headers are sent already, client sees the content is shorter than Content-Length
+			log.Err(err).Str("blob", blob.ID).Msg("failed to read blob")
+			return
+		}
+		if _, err = w.Write(chunk); err != nil {
+			return
+		}
+	}
+}
+
+// NewBlob starts upload of the blob of the user, it is shared by REST and gRPC APIs
+// blob that was created before is returned with its Offset
+// returns ErrInvalidBlob, ErrBlobTooLarge or ErrQuotaExceeded if the blob can't be stored
+func (h *handler) NewBlob(ctx context.Context, userID string, blob models.Blob) (models.Blob, error) {
+	if blob.ID == "" || blob.Size <= 0 || !validHash(blob.Hash) {
+		return models.Blob{}, ErrInvalidBlob
+	}
+	if h.limits.MaxBlobSize > 0 && blob.Size > h.limits.MaxBlobSize {
+		return models.Blob{}, fmt.Errorf("%w: blob has %d bytes, limit is %d bytes",
+			ErrBlobTooLarge, blob.Size, h.limits.MaxBlobSize)
+	}
+	blob.OwnerID = userID
+	blob.CreatedAt = time.Now().Unix()
+
+	unlock := h.locks.lock(userID)
+	defer unlock()
+
+	// Upload that is continued is counted already, content the user has uploaded is shared and counted once
+	if _, err := h.storage.GetBlob(ctx, userID, blob.ID); err == nil {
+		return h.storage.CreateBlob(ctx, blob)
+	}
+	if _, err := h.storage.FindBlob(ctx, userID, blob.Hash); err == nil {
+		return h.storage.CreateBlob(ctx, blob)
+	}
+	usage, err := h.usage(ctx, userID)
+	if err != nil {
+		return models.Blob{}, err
+	}
+	if h.limits.MaxBytes > 0 && usage.Bytes+blob.Size > h.limits.MaxBytes {
+		return models.Blob{}, fmt.Errorf("%w: blob would make %d bytes, limit is %d bytes",
+			ErrQuotaExceeded, usage.Bytes+blob.Size, h.limits.MaxBytes)
+	}
+	return h.storage.CreateBlob(ctx, blob)
+}
+
+// WriteBlob writes the chunk of the blob of the user at the offset, it is shared by REST and gRPC APIs
+func (h *handler) WriteBlob(ctx context.Context, userID, id string, offset int64, chunk []byte) (models.Blob, error) {
+	if len(chunk) > models.BlobChunkSize {
+		return models.Blob{}, fmt.Errorf("%w: chunk has %d bytes, limit is %d bytes",
+			ErrBodyTooLarge, len(chunk), models.BlobChunkSize)
+	}
+	return h.storage.WriteBlob(ctx, userID, id, offset, chunk)
+}
+
+// ReadBlob returns up to models.BlobChunkSize bytes of the blob of the user or of an item shared with the user
+// from the offset together with the blob, it is shared by REST and gRPC APIs
+func (h *handler) ReadBlob(ctx context.Context, userID, id string, offset int64) ([]byte, models.Blob, error) {
+	userID, err := h.blobOwner(ctx, userID, id)
+	if err != nil {
+		return nil, models.Blob{}, err
+	}
+	blob, err := h.storage.GetBlob(ctx, userID, id)
+	if err != nil {
+		return nil, blob, err
+	}
+	chunk, err := h.storage.ReadBlob(ctx, userID, id, offset, models.BlobChunkSize)
+	return chunk, blob, err
+}
+
+// missingBlob returns id of a blob the data refers to that is not uploaded, empty if all of them are
+func (h *handler) missingBlob(ctx context.Context, data models.DataWrapper) (string, error) {
+	for _, id := range data.Blobs {
+		blob, err := h.storage.GetBlob(ctx, data.OwnerID, id)
+		if errors.Is(err, storage.ErrBlobNotFound) || err == nil && !blob.Complete() {
+			return id, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// usage returns usage of the user counting stored data and blobs
+func (h *handler) usage(ctx context.Context, userID string) (models.Usage, error) {
+	stored, err := h.storage.DataUsage(ctx, userID)
+	if err != nil {
+		return models.Usage{}, err
+	}
+	// Blobs are not changed by sync, so usage projected for sync counts them as they are
+	blobs, err := h.storage.BlobsSize(ctx, userID)
+	if err != nil {
+		return models.Usage{}, err
+	}
+	stored.Bytes += blobs
+	return h.limits.usage(stored), nil
+}
+
+// parseRange parses "bytes=start-end" header of the blob of the size
+// returns the part to send with exclusive end, the whole blob if there is no header
+func parseRange(header string, size int64) (start, end int64, partial bool, err error) {
+	if header == "" {
+		return 0, size, false, nil
+	}
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	from, to, found := strings.Cut(spec, "-")
+	if !ok || !found || strings.Contains(spec, ",") {
+		return 0, 0, false, ErrInvalidRange
+	}
+	if start, err = strconv.ParseInt(from, 10, 64); err != nil || start < 0 || start >= size {
+		return 0, 0, false, ErrInvalidRange
+	}
+	end = size
+	if to != "" {
+		last, err := strconv.ParseInt(to, 10, 64)
+		if err != nil || last < start {
+			return 0, 0, false, ErrInvalidRange
+		}
+		end = min(size, last+1)
+	}
+	return start, end, true, nil
+}
+
+// validHash checks that hash is hex encoded sha256
+func validHash(hash string) bool {
+	decoded, err := hex.DecodeString(hash)
+	return err == nil && len(decoded) == 32
+}
+
+// blobStatus returns http status of the error of blob request
+func blobStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidBlob):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrBlobTooLarge), errors.Is(err, ErrQuotaExceeded), errors.Is(err, ErrBodyTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, storage.ErrBlobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrBlobExists), errors.Is(err, storage.ErrBlobOffset),
+		errors.Is(err, storage.ErrBlobIncomplete):
+		return http.StatusConflict
+	case errors.Is(err, storage.ErrBlobHash):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,144 @@
+package handlers_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	auth "github.com/gynshu-one/goph-keeper/server/api/auth"
+	"github.com/gynshu-one/goph-keeper/server/api/handlers"
+	"github.com/gynshu-one/goph-keeper/server/api/router"
+	"github.com/gynshu-one/goph-keeper/server/storage"
+)
+
+func TestBlobs(t *testing.T) {
+	s := storage.NewMemoryStorage(storage.Options{})
+	r := router.NewRouter(handlers.NewHandlers(s, handlers.Limits{MaxBytes: 5 << 20, MaxBlobSize: 3 << 20}))
+
+	userID := "testUserID"
+	session, _ := auth.Sessions.CreateSession(userID)
+	cookie := &http.Cookie{Name: "session_id", Value: session.ID}
+	serve := func(request *http.Request) *httptest.ResponseRecorder {
+		request.AddCookie(cookie)
+		response := httptest.NewRecorder()
+		r.ServeHTTP(response, request)
+		return response
+	}
+	create := func(blob models.Blob) (*httptest.ResponseRecorder, models.Blob) {
+		body, err := json.Marshal(blob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response := serve(httptest.NewRequest(http.MethodPost, "/user/blobs", bytes.NewReader(body)))
+		var created models.Blob
+		if response.Code == http.StatusOK {
+			if err = json.Unmarshal(response.Body.Bytes(), &created); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return response, created
+	}
+	upload := func(offset int64, chunk []byte) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPatch, "/user/blobs/blob1", bytes.NewReader(chunk))
+		request.Header.Set(models.UploadOffsetHeader, strconv.FormatInt(offset, 10))
+		return serve(request)
+	}
+
+	content := bytes.Repeat([]byte("encrypted file "), 100000)
+	sum := sha256.Sum256(content)
+	blob := models.Blob{ID: "blob1", Size: int64(len(content)), Hash: hex.EncodeToString(sum[:])}
+
+	// Item can't refer to a blob before it's uploaded
+	item := models.DataWrapper{ID: "1", OwnerID: userID, Type: models.BinaryType, Data: []byte("ref"), Blobs: []string{"blob1"}}
+	if status := syncItem(t, serve, item); status != models.StatusRejected {
+		t.Errorf("Expected item with missing blob rejected, got %s", status)
+	}
+
+	if response, _ := create(models.Blob{ID: "big", Size: 4 << 20, Hash: blob.Hash}); response.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected blob over the limit rejected, got %d", response.Code)
+	}
+	if response, _ := create(models.Blob{ID: "bad", Size: 10, Hash: "not a hash"}); response.Code != http.StatusBadRequest {
+		t.Errorf("Expected blob without hash rejected, got %d", response.Code)
+	}
+	response, created := create(blob)
+	if response.Code != http.StatusOK || created.Offset != 0 || created.OwnerID != userID {
+		t.Fatalf("Unexpected response %d %+v", response.Code, created)
+	}
+
+	// Interrupted upload continues from the offset returned on create
+	if response = upload(0, content[:models.BlobChunkSize]); response.Code != http.StatusOK {
+		t.Fatalf("Upload of the first chunk failed with %d: %s", response.Code, response.Body)
+	}
+	if response, created = create(blob); created.Offset != models.BlobChunkSize {
+		t.Fatalf("Expected upload to continue from %d, got %d", models.BlobChunkSize, created.Offset)
+	}
+	response = upload(0, content[:10])
+	if response.Code != http.StatusConflict || response.Header().Get(models.UploadOffsetHeader) != strconv.Itoa(models.BlobChunkSize) {
+		t.Errorf("Expected conflict with current offset, got %d %q", response.Code, response.Header().Get(models.UploadOffsetHeader))
+	}
+	if response = upload(models.BlobChunkSize, content[models.BlobChunkSize:]); response.Code != http.StatusOK {
+		t.Fatalf("Upload of the last chunk failed with %d: %s", response.Code, response.Body)
+	}
+
+	// Blob counts in usage
+	response = serve(httptest.NewRequest(http.MethodGet, "/user/usage", nil))
+	var usage models.Usage
+	if err := json.Unmarshal(response.Body.Bytes(), &usage); err != nil || usage.Bytes != blob.Size {
+		t.Errorf("Expected usage of %d bytes, got %+v and %v", blob.Size, usage, err)
+	}
+
+	// The same content is not uploaded and counted again
+	copied := blob
+	copied.ID = "blob2"
+	if response, created = create(copied); response.Code != http.StatusOK || !created.Complete() {
+		t.Errorf("Expected blob with the same content complete, got %d %+v", response.Code, created)
+	}
+	response = serve(httptest.NewRequest(http.MethodGet, "/user/usage", nil))
+	if err := json.Unmarshal(response.Body.Bytes(), &usage); err != nil || usage.Bytes != blob.Size {
+		t.Errorf("Expected shared content counted once, got %+v and %v", usage, err)
+	}
+
+	if status := syncItem(t, serve, item); status != models.StatusApplied {
+		t.Errorf("Expected item with uploaded blob applied, got %s", status)
+	}
+
+	// Download of the whole blob and of its rest
+	response = serve(httptest.NewRequest(http.MethodGet, "/user/blobs/blob1", nil))
+	if response.Code != http.StatusOK || !bytes.Equal(response.Body.Bytes(), content) || response.Header().Get(models.BlobHashHeader) != blob.Hash {
+		t.Errorf("Unexpected download %d of %d bytes", response.Code, response.Body.Len())
+	}
+	request := httptest.NewRequest(http.MethodGet, "/user/blobs/blob1", nil)
+	request.Header.Set("Range", "bytes=1000-")
+	if response = serve(request); response.Code != http.StatusPartialContent || !bytes.Equal(response.Body.Bytes(), content[1000:]) {
+		t.Errorf("Unexpected download of the rest %d of %d bytes", response.Code, response.Body.Len())
+	}
+	request = httptest.NewRequest(http.MethodGet, "/user/blobs/blob1", nil)
+	request.Header.Set("Range", "bytes="+strconv.Itoa(len(content))+"-")
+	if response = serve(request); response.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("Expected range past the end rejected, got %d", response.Code)
+	}
+	if response = serve(httptest.NewRequest(http.MethodGet, "/user/blobs/unknown", nil)); response.Code != http.StatusNotFound {
+		t.Errorf("Expected unknown blob not found, got %d", response.Code)
+	}
+}
+
+// syncItem syncs the item with serve and returns its status
+func syncItem(t *testing.T, serve func(*http.Request) *httptest.ResponseRecorder, item models.DataWrapper) models.SyncStatus {
+	t.Helper()
+	body, err := json.Marshal([]models.DataWrapper{item})
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := serve(httptest.NewRequest(http.MethodPost, "/user/sync", bytes.NewReader(body)))
+	var sync models.SyncResponse
+	if err = json.Unmarshal(response.Body.Bytes(), &sync); err != nil {
+		t.Fatalf("Failed to read sync response %d %s: %v", response.Code, response.Body, err)
+	}
+	return sync.Results[0].Status
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/rs/zerolog/log"
+)
+
+// checkpointInterval is how many items are streamed between checkpoints
+const checkpointInterval = 100
+
+// Changes streams changes of the user's data after the cursor in models.CursorParam
+// as NDJSON of models.ChangeLine, items are read from storage one by one, so memory use is bounded
+// interrupted stream is resumed from its last checkpoint, models.ResumeParam is set to "true"
+// if the stream was Reset, so server continues listing all data
+func (h *handler) Changes(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	cursor, err := parseCursor(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resume := r.URL.Query().Get(models.ResumeParam) == "true"
+
+	w.Header().Set("Content-Type", models.ChangesContentType)
+	controller := http.NewResponseController(w)
+	encoder := json.NewEncoder(w)
+	written := false
+	err = h.StreamChanges(r.Context(), session.GetUserID(), cursor, resume, func(line models.ChangeLine) error {
+		written = true
+		if err := encoder.Encode(line); err != nil {
+			return err
+		}
+		if line.Checkpoint == nil {
+			return nil
+		}
+		return controller.Flush()
+	})
+	if err != nil {
+		if !written {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// Client resumes from the last checkpoint
+		log.Debug().Err(err).Msg("changes stream interrupted")
+	}
+}
+
+// StreamChanges emits changes of the user's data after the cursor line by line, it is shared by REST and gRPC APIs
+// the first line is a checkpoint that tells whether the stream is Reset, then go items in order of Seq
+// with a checkpoint after every checkpointInterval items and the last checkpoint is Done
+// resume continues interrupted listing of all data from its checkpoint
+func (h *handler) StreamChanges(ctx context.Context, userID string, cursor int64, resume bool, emit func(line models.ChangeLine) error) error {
+	// Changes are written under the lock, so all changes up to the cursor read under it are visible
+	unlock := h.locks.lock(userID)
+	start, err := h.storage.CheckChanges(ctx, userID, cursor, resume)
+	unlock()
+	if err != nil {
+		return err
+	}
+
+	after := cursor
+	if start.Reset {
+		after = 0
+	}
+	if err = emit(models.ChangeLine{Checkpoint: &models.Checkpoint{Cursor: after, Reset: start.Reset}}); err != nil {
+		return err
+	}
+
+	streamed := 0
+	err = h.storage.EachChange(ctx, userID, after, func(data models.DataWrapper) error {
+		if err := emit(models.ChangeLine{Data: &data}); err != nil {
+			return err
+		}
+		streamed++
+		if streamed%checkpointInterval != 0 {
+			return nil
+		}
+		// Changes after the cursor read at start may be not visible yet, so checkpoint doesn't go beyond it
+		return emit(models.ChangeLine{Checkpoint: &models.Checkpoint{Cursor: min(data.Seq, start.Cursor)}})
+	})
+	if err != nil {
+		return err
+	}
+
+	// Purged models are marked in the feed before they are removed,
+	// so checking again tells whether some changes were missing from the stream
+	if !start.Reset {
+		end, err := h.storage.CheckChanges(ctx, userID, cursor, resume)
+		if err != nil {
+			return err
+		}
+		if end.Reset {
+			// Client starts again with all data
+			return emit(models.ChangeLine{Checkpoint: &models.Checkpoint{}})
+		}
+	}
+	return emit(models.ChangeLine{Checkpoint: &models.Checkpoint{Cursor: start.Cursor, Done: true}})
+}
@@ -0,0 +1,103 @@
+package handlers_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	auth "github.com/gynshu-one/goph-keeper/server/api/auth"
+	"github.com/gynshu-one/goph-keeper/server/api/handlers"
+	"github.com/gynshu-one/goph-keeper/server/storage"
+)
+
+// readChanges reads changes stream of the handler since the cursor
+// returns checkpoints and number of items between them
+func readChanges(t *testing.T, hndlr handlers.Handlers, session *auth.Session, query string) (checkpoints []models.Checkpoint, items []int) {
+	t.Helper()
+	request := httptest.NewRequest(http.MethodGet, "/user/changes?"+query, nil)
+	request.AddCookie(&http.Cookie{Name: "session_id", Value: session.ID})
+	response := httptest.NewRecorder()
+	hndlr.Changes(response, request)
+	if response.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, response.Code)
+	}
+	if contentType := response.Header().Get("Content-Type"); contentType != models.ChangesContentType {
+		t.Errorf("Unexpected content type %s", contentType)
+	}
+
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		var line models.ChangeLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatal(err)
+		}
+		if line.Checkpoint != nil {
+			checkpoints = append(checkpoints, *line.Checkpoint)
+			items = append(items, 0)
+			continue
+		}
+		if len(items) == 0 {
+			t.Fatal("Stream doesn't start with a checkpoint")
+		}
+		items[len(items)-1]++
+	}
+	return checkpoints, items
+}
+
+func TestChanges(t *testing.T) {
+	hndlr := handlers.NewHandlers(storage.NewMemoryStorage(storage.Options{}), handlers.Limits{})
+	userID := "changesUserID"
+	session, _ := auth.Sessions.CreateSession(userID)
+
+	// Push only sync doesn't return changes
+	var items []models.DataWrapper
+	for i := 0; i < 150; i++ {
+		items = append(items, models.DataWrapper{ID: strconv.Itoa(i), OwnerID: userID, Data: []byte("data"), UpdatedAt: 10})
+	}
+	response := syncSince(t, hndlr, session, "0&"+models.PushOnlyParam+"=true", items)
+	if response.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, response.Code)
+	}
+	var responseData models.SyncResponse
+	if err := json.Unmarshal(response.Body.Bytes(), &responseData); err != nil {
+		t.Fatal(err)
+	}
+	if len(responseData.Results) != 150 || len(responseData.Data) != 0 || responseData.Reset {
+		t.Errorf("Unexpected push only response with %d results, %d items", len(responseData.Results), len(responseData.Data))
+	}
+
+	// All data is streamed in pages
+	checkpoints, counts := readChanges(t, hndlr, session, "")
+	want := []models.Checkpoint{{Reset: true}, {Cursor: 100}, {Cursor: 150, Done: true}}
+	if len(checkpoints) != 3 || checkpoints[0] != want[0] || checkpoints[1] != want[1] || checkpoints[2] != want[2] {
+		t.Fatalf("Unexpected checkpoints %+v", checkpoints)
+	}
+	if counts[0] != 100 || counts[1] != 50 {
+		t.Errorf("Unexpected page sizes %v", counts)
+	}
+
+	// Interrupted download is resumed from the checkpoint
+	checkpoints, counts = readChanges(t, hndlr, session, models.CursorParam+"=100&"+models.ResumeParam+"=true")
+	if len(checkpoints) != 2 || checkpoints[0] != (models.Checkpoint{Cursor: 100}) || counts[0] != 50 || !checkpoints[1].Done {
+		t.Errorf("Unexpected resumed stream %+v %v", checkpoints, counts)
+	}
+
+	// Nothing changed since the cursor
+	checkpoints, counts = readChanges(t, hndlr, session, models.CursorParam+"=150")
+	if len(checkpoints) != 2 || counts[0] != 0 || checkpoints[1] != (models.Checkpoint{Cursor: 150, Done: true}) {
+		t.Errorf("Unexpected empty stream %+v %v", checkpoints, counts)
+	}
+
+	// Invalid cursor
+	request := httptest.NewRequest(http.MethodGet, "/user/changes?"+models.CursorParam+"=abc", nil)
+	request.AddCookie(&http.Cookie{Name: "session_id", Value: session.ID})
+	recorder := httptest.NewRecorder()
+	hndlr.Changes(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, recorder.Code)
+	}
+}
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/gynshu-one/goph-keeper/common/utils"
+	"github.com/gynshu-one/goph-keeper/server/storage"
+)
+
+// maxWaitDays is the longest waiting period of emergency access
+const maxWaitDays = 90
+
+// ListEmergencyContacts returns trusted contacts of the user: /user/emergency/contacts
+// response is []models.EmergencyAccess in json format without their keys
+func (h *handler) ListEmergencyContacts(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	contacts, err := h.EmergencyContacts(r.Context(), session.GetUserID())
+	if err != nil {
+		http.Error(w, err.Error(), emergencyStatus(err))
+		return
+	}
+	writeJSON(w, contacts)
+}
+
+// SetEmergencyContact adds a trusted contact of the user or changes its waiting period
+// request is models.EmergencyAccess in json format with email of the contact in GranteeID,
+// waiting period and the vault key sealed to public key of the contact, response is the stored models.EmergencyAccess
+func (h *handler) SetEmergencyContact(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var access models.EmergencyAccess
+	if err = json.NewDecoder(io.LimitReader(r.Body, 1<<10)).Decode(&access); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	access, err = h.TrustContact(r.Context(), session.GetUserID(), access)
+	if err != nil {
+		http.Error(w, err.Error(), emergencyStatus(err))
+		return
+	}
+	writeJSON(w, access)
+}
+
+// DeleteEmergencyContact removes the trusted contact of the user: /user/emergency/contacts/{user}
+func (h *handler) DeleteEmergencyContact(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err = h.RemoveContact(r.Context(), session.GetUserID(), chi.URLParam(r, "user")); err != nil {
+		http.Error(w, err.Error(), emergencyStatus(err))
+		return
+	}
+}
+
+// RejectEmergencyAccess rejects the request of the trusted contact: /user/emergency/contacts/{user}/reject
+// response is the rejected models.EmergencyAccess without its key
+func (h *handler) RejectEmergencyAccess(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	access, err := h.RejectAccess(r.Context(), session.GetUserID(), chi.URLParam(r, "user"))
+	if err != nil {
+		http.Error(w, err.Error(), emergencyStatus(err))
+		return
+	}
+	writeJSON(w, access)
+}
+
+// ListEmergencyGrantors returns users that trust the user: /user/emergency/grantors
+// response is []models.EmergencyAccess in json format, keys are sent only when access is granted
+func (h *handler) ListEmergencyGrantors(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	grantors, err := h.EmergencyGrantors(r.Context(), session.GetUserID())
+	if err != nil {
+		http.Error(w, err.Error(), emergencyStatus(err))
+		return
+	}
+	writeJSON(w, grantors)
+}
+
+// RequestEmergencyAccess requests access to the vault of the grantor: /user/emergency/grantors/{user}/request
+// response is the requested models.EmergencyAccess, it is granted when the waiting period is over
+func (h *handler) RequestEmergencyAccess(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	access, err := h.RequestAccess(r.Context(), session.GetUserID(), chi.URLParam(r, "user"))
+	if err != nil {
+		http.Error(w, err.Error(), emergencyStatus(err))
+		return
+	}
+	writeJSON(w, access)
+}
+
+// GetEmergencyVault returns the vault of the grantor once access is granted: /user/emergency/grantors/{user}/vault
+// response is models.EmergencyVault in json format with the sealed vault key and not deleted items
+func (h *handler) GetEmergencyVault(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	vault, err := h.EmergencyVault(r.Context(), session.GetUserID(), chi.URLParam(r, "user"))
+	if err != nil {
+		http.Error(w, err.Error(), emergencyStatus(err))
+		return
+	}
+	writeJSON(w, vault)
+}
+
+// EmergencyContacts returns trusted contacts of the user without their keys, it is shared by REST and gRPC APIs
+func (h *handler) EmergencyContacts(ctx context.Context, userID string) ([]models.EmergencyAccess, error) {
+	contacts, err := h.storage.GetEmergencyContacts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	for i := range contacts {
+		contacts[i].Status, contacts[i].Key = contacts[i].StatusAt(now), nil
+	}
+	return contacts, nil
+}
+
+// TrustContact adds the trusted contact of the user, it is shared by REST and gRPC APIs
+// the contact must be another user with keys, setting it again replaces the key and waiting period
+// and drops the request of the contact
+func (h *handler) TrustContact(ctx context.Context, userID string, access models.EmergencyAccess) (models.EmergencyAccess, error) {
+	if access.GranteeID == "" || access.GranteeID == userID || access.WaitDays < 1 || access.WaitDays > maxWaitDays ||
+		len(access.Key) < utils.MinSealedKeySize {
+		return models.EmergencyAccess{}, ErrInvalidEmergency
+	}
+	if _, err := h.PublicKey(ctx, access.GranteeID); err != nil {
+		return models.EmergencyAccess{}, err
+	}
+	unlock := h.locks.lock(userID)
+	defer unlock()
+
+	access.GrantorID, access.Status, access.RequestedAt = userID, models.EmergencyIdle, 0
+	access.CreatedAt = time.Now().Unix()
+	existing, err := h.storage.GetEmergency(ctx, userID, access.GranteeID)
+	switch {
+	case errors.Is(err, storage.ErrEmergencyNotFound):
+	case err != nil:
+		return models.EmergencyAccess{}, err
+	default:
+		access.CreatedAt = existing.CreatedAt
+	}
+	if err = h.storage.SetEmergency(ctx, access); err != nil {
+		return models.EmergencyAccess{}, err
+	}
+	access.Key = nil
+	return access, nil
+}
+
+// RemoveContact removes the trusted contact of the user, it is shared by REST and gRPC APIs
+func (h *handler) RemoveContact(ctx context.Context, userID, granteeID string) error {
+	unlock := h.locks.lock(userID)
+	defer unlock()
+
+	return h.storage.DeleteEmergency(ctx, userID, granteeID)
+}
+
+// RejectAccess rejects the request of the trusted contact, it is shared by REST and gRPC APIs
+// granted access is rejected the same way, so the user takes it back when it's reachable again
+func (h *handler) RejectAccess(ctx context.Context, userID, granteeID string) (models.EmergencyAccess, error) {
+	unlock := h.locks.lock(userID)
+	defer unlock()
+
+	access, err := h.storage.GetEmergency(ctx, userID, granteeID)
+	if err != nil {
+		return models.EmergencyAccess{}, err
+	}
+	if access.Status != models.EmergencyRequested {
+		return models.EmergencyAccess{}, ErrAccessNotRequested
+	}
+	access.Status = models.EmergencyRejected
+	if err = h.storage.SetEmergency(ctx, access); err != nil {
+		return models.EmergencyAccess{}, err
+	}
+	access.Key = nil
+	return access, nil
+}
+
+// EmergencyGrantors returns users that trust the user, it is shared by REST and gRPC APIs
+// keys are sent only for granted access
+func (h *handler) EmergencyGrantors(ctx context.Context, userID string) ([]models.EmergencyAccess, error) {
+	grantors, err := h.storage.GetEmergencyGrantors(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	for i := range grantors {
+		grantors[i].Status = grantors[i].StatusAt(now)
+		if grantors[i].Status != models.EmergencyGranted {
+			grantors[i].Key = nil
+		}
+	}
+	return grantors, nil
+}
+
+// RequestAccess requests access to the vault of the grantor, it is shared by REST and gRPC APIs
+// requesting it again doesn't restart the waiting period, rejected access may be requested again
+func (h *handler) RequestAccess(ctx context.Context, userID, grantorID string) (models.EmergencyAccess, error) {
+	unlock := h.locks.lock(grantorID)
+	defer unlock()
+
+	access, err := h.storage.GetEmergency(ctx, grantorID, userID)
+	if err != nil {
+		return models.EmergencyAccess{}, err
+	}
+	if access.Status != models.EmergencyRequested {
+		access.Status, access.RequestedAt = models.EmergencyRequested, time.Now().Unix()
+		if err = h.storage.SetEmergency(ctx, access); err != nil {
+			return models.EmergencyAccess{}, err
+		}
+	}
+	access.Status, access.Key = access.StatusAt(time.Now().Unix()), nil
+	return access, nil
+}
+
+// EmergencyVault returns the sealed vault key and not deleted items of the grantor, it is shared by REST and gRPC APIs
+// returns ErrAccessWaiting until the waiting period is over and ErrAccessNotRequested if access wasn't requested
+func (h *handler) EmergencyVault(ctx context.Context, userID, grantorID string) (models.EmergencyVault, error) {
+	access, err := h.storage.GetEmergency(ctx, grantorID, userID)
+	if err != nil {
+		return models.EmergencyVault{}, err
+	}
+	switch access.Status = access.StatusAt(time.Now().Unix()); access.Status {
+	case models.EmergencyGranted:
+	case models.EmergencyRequested:
+		return models.EmergencyVault{}, ErrAccessWaiting
+	default:
+		return models.EmergencyVault{}, ErrAccessNotRequested
+	}
+	data, err := h.storage.GetData(ctx, grantorID)
+	if err != nil {
+		return models.EmergencyVault{}, err
+	}
+	data = slices.DeleteFunc(data, func(item models.DataWrapper) bool { return item.DeletedAt != 0 })
+	return models.EmergencyVault{Access: access, Data: data}, nil
+}
+
+func emergencyStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidEmergency):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrAccessWaiting):
+		return http.StatusForbidden
+	case errors.Is(err, ErrKeysNotFound), errors.Is(err, ErrUserNotFound), errors.Is(err, storage.ErrEmergencyNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrAccessNotRequested):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
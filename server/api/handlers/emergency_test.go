@@ -0,0 +1,149 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	auth "github.com/gynshu-one/goph-keeper/server/api/auth"
+	"github.com/gynshu-one/goph-keeper/server/api/handlers"
+	"github.com/gynshu-one/goph-keeper/server/api/router"
+	"github.com/gynshu-one/goph-keeper/server/storage"
+)
+
+func TestEmergencyAccess(t *testing.T) {
+	s := storage.NewMemoryStorage(storage.Options{})
+	r := router.NewRouter(handlers.NewHandlers(s, handlers.Limits{}))
+	emails := []string{"owner@example.com", "friend@example.com", "stranger@example.com"}
+	servers := make(map[string]func(*http.Request) *httptest.ResponseRecorder)
+	for _, email := range emails {
+		if err := s.CreateUser(context.Background(), models.User{Email: email}); err != nil {
+			t.Fatal(err)
+		}
+		keys := models.UserKeys{PublicKey: bytes.Repeat([]byte{1}, 32), PrivateKey: []byte("encrypted")}
+		if err := s.SetUserKeys(context.Background(), email, keys); err != nil {
+			t.Fatal(err)
+		}
+		session, _ := auth.Sessions.CreateSession(email)
+		cookie := &http.Cookie{Name: "session_id", Value: session.ID}
+		servers[email] = func(request *http.Request) *httptest.ResponseRecorder {
+			request.AddCookie(cookie)
+			response := httptest.NewRecorder()
+			r.ServeHTTP(response, request)
+			return response
+		}
+	}
+	owner, friend, stranger := servers[emails[0]], servers[emails[1]], servers[emails[2]]
+	send := func(serve func(*http.Request) *httptest.ResponseRecorder, method, target string, v any) *httptest.ResponseRecorder {
+		var body []byte
+		if v != nil {
+			var err error
+			if body, err = json.Marshal(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return serve(httptest.NewRequest(method, target, bytes.NewReader(body)))
+	}
+	decode := func(response *httptest.ResponseRecorder, v any) {
+		if response.Code != http.StatusOK {
+			t.Fatalf("Request failed with %d: %s", response.Code, response.Body)
+		}
+		if err := json.Unmarshal(response.Body.Bytes(), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	grantor := "/user/emergency/grantors/" + emails[0]
+	item := models.DataWrapper{ID: "item", OwnerID: emails[0], Type: models.LoginType, Data: []byte("encrypted")}
+	if _, err := s.SetData(context.Background(), item); err != nil {
+		t.Fatal(err)
+	}
+
+	// Owner trusts the friend, the contact must be valid
+	contact := models.EmergencyAccess{GranteeID: emails[1], WaitDays: 3, Key: sealedKey("sealed vault key")}
+	for _, invalid := range []models.EmergencyAccess{
+		{GranteeID: emails[0], WaitDays: 3, Key: sealedKey("key")},
+		{GranteeID: emails[1], WaitDays: 0, Key: sealedKey("key")},
+		{GranteeID: emails[1], WaitDays: 91, Key: sealedKey("key")},
+		{GranteeID: emails[1], WaitDays: 3},
+		{GranteeID: emails[1], WaitDays: 3, Key: []byte("too short to be sealed")},
+	} {
+		if code := send(owner, http.MethodPut, "/user/emergency/contacts", invalid).Code; code != http.StatusBadRequest {
+			t.Errorf("Expected %+v rejected, got %d", invalid, code)
+		}
+	}
+	if code := send(owner, http.MethodPut, "/user/emergency/contacts", models.EmergencyAccess{GranteeID: "nobody@example.com",
+		WaitDays: 3, Key: sealedKey("key")}).Code; code != http.StatusNotFound {
+		t.Errorf("Expected unknown contact rejected, got %d", code)
+	}
+	var stored models.EmergencyAccess
+	decode(send(owner, http.MethodPut, "/user/emergency/contacts", contact), &stored)
+	if stored.GrantorID != emails[0] || stored.Status != models.EmergencyIdle || stored.Key != nil {
+		t.Fatalf("Unexpected contact: %+v", stored)
+	}
+
+	// Friend sees the grantor without the key and can't open the vault before it requests access
+	var grantors []models.EmergencyAccess
+	decode(send(friend, http.MethodGet, "/user/emergency/grantors", nil), &grantors)
+	if len(grantors) != 1 || grantors[0].GrantorID != emails[0] || grantors[0].Key != nil {
+		t.Fatalf("Unexpected grantors: %+v", grantors)
+	}
+	if code := send(friend, http.MethodGet, grantor+"/vault", nil).Code; code != http.StatusConflict {
+		t.Errorf("Expected vault closed before request, got %d", code)
+	}
+	if code := send(stranger, http.MethodPost, grantor+"/request", nil).Code; code != http.StatusNotFound {
+		t.Errorf("Expected stranger can't request access, got %d", code)
+	}
+
+	// Owner rejects the request during the waiting period
+	decode(send(friend, http.MethodPost, grantor+"/request", nil), &stored)
+	if stored.Status != models.EmergencyRequested || stored.RequestedAt == 0 || stored.Key != nil {
+		t.Fatalf("Unexpected request: %+v", stored)
+	}
+	if code := send(friend, http.MethodGet, grantor+"/vault", nil).Code; code != http.StatusForbidden {
+		t.Errorf("Expected vault closed while waiting, got %d", code)
+	}
+	var contacts []models.EmergencyAccess
+	decode(send(owner, http.MethodGet, "/user/emergency/contacts", nil), &contacts)
+	if len(contacts) != 1 || contacts[0].Status != models.EmergencyRequested {
+		t.Fatalf("Expected requested contact, got %+v", contacts)
+	}
+	decode(send(owner, http.MethodPost, "/user/emergency/contacts/"+emails[1]+"/reject", nil), &stored)
+	if stored.Status != models.EmergencyRejected {
+		t.Errorf("Expected rejected access, got %+v", stored)
+	}
+	if code := send(owner, http.MethodPost, "/user/emergency/contacts/"+emails[1]+"/reject", nil).Code; code != http.StatusConflict {
+		t.Errorf("Expected reject without request rejected, got %d", code)
+	}
+
+	// Access is granted when the waiting period is over
+	send(friend, http.MethodPost, grantor+"/request", nil)
+	access, err := s.GetEmergency(context.Background(), emails[0], emails[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	access.RequestedAt -= 3 * 24 * 60 * 60
+	if err = s.SetEmergency(context.Background(), access); err != nil {
+		t.Fatal(err)
+	}
+	decode(send(friend, http.MethodGet, "/user/emergency/grantors", nil), &grantors)
+	if len(grantors) != 1 || grantors[0].Status != models.EmergencyGranted || !bytes.Equal(grantors[0].Key, sealedKey("sealed vault key")) {
+		t.Fatalf("Expected granted access with key, got %+v", grantors)
+	}
+	var vault models.EmergencyVault
+	decode(send(friend, http.MethodGet, grantor+"/vault", nil), &vault)
+	if !bytes.Equal(vault.Access.Key, sealedKey("sealed vault key")) || len(vault.Data) != 1 || vault.Data[0].ID != item.ID {
+		t.Fatalf("Unexpected vault: %+v", vault)
+	}
+
+	// Removed contact loses access
+	if code := send(owner, http.MethodDelete, "/user/emergency/contacts/"+emails[1], nil).Code; code != http.StatusOK {
+		t.Fatalf("Remove failed with %d", code)
+	}
+	if code := send(friend, http.MethodGet, grantor+"/vault", nil).Code; code != http.StatusNotFound {
+		t.Errorf("Expected removed contact can't open the vault, got %d", code)
+	}
+}
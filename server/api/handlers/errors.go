@@ -3,5 +3,40 @@ package handlers
 import "errors"
 
 var (
-	ErrNoDataFound = errors.New("no data found")
+	ErrForeignData          = errors.New("item belongs to another user")
+	ErrInvalidCursor        = errors.New("invalid cursor")
+	ErrBodyTooLarge         = errors.New("request body is too large")
+	ErrItemTooLarge         = errors.New("item is too large")
+	ErrQuotaExceeded        = errors.New("quota exceeded")
+	ErrEmptyEmail           = errors.New("email is empty")
+	ErrInvalidEmail         = errors.New("email is invalid")
+	ErrEmptyPassword        = errors.New("password is empty")
+	ErrUserExists           = errors.New("user with this email already exists")
+	ErrUserNotFound         = errors.New("user not found")
+	ErrWrongPassword        = errors.New("invalid master key")
+	ErrIdempotencyKeyReused = errors.New("idempotency key was used for another request")
+	ErrInvalidBlob          = errors.New("blob must have id, size and sha256 hash")
+	ErrBlobTooLarge         = errors.New("blob is too large")
+	ErrBlobMissing          = errors.New("blob of the item is not uploaded")
+	ErrInvalidOffset        = errors.New("invalid upload offset")
+	ErrInvalidRange         = errors.New("invalid range")
+	ErrInvalidKeys          = errors.New("keys must have x25519 public key and encrypted private key")
+	ErrKeysNotFound         = errors.New("user has no keys")
+	ErrInvalidShare         = errors.New("share must have item, another user, key and read or write permission")
+	ErrItemNotFound         = errors.New("item not found")
+	ErrItemKeyMissing       = errors.New("item has no key of its own")
+	ErrReadOnly             = errors.New("item is shared read only")
+	ErrInvalidOrg           = errors.New("organization must have name and the org key sealed to its owner")
+	ErrNotMember            = errors.New("user is not a member of the organization")
+	ErrForbidden            = errors.New("role of the user doesn't allow it")
+	ErrInvalidMember        = errors.New("member must have another user, role other than owner and the org key sealed to the user")
+	ErrInvalidCollection    = errors.New("collection must have name")
+	ErrCollectionNotFound   = errors.New("collection not found")
+	ErrOrgBlobs             = errors.New("files can't be attached to items of organizations")
+	ErrInvalidRotation      = errors.New("rotation must have the new key of every member and every item encrypted with it")
+	ErrRotationConflict     = errors.New("items of the organization were changed since the rotation was made")
+	ErrInvalidEmergency     = errors.New("emergency contact must be another user with the vault key sealed to it and waiting period of 1 to 90 days")
+	ErrAccessNotRequested   = errors.New("emergency access is not requested")
+	ErrAccessWaiting        = errors.New("waiting period of emergency access is not over")
+	ErrInvalidSend          = errors.New("send must have data up to 64 KiB, 1 to 100 views and expiry within 30 days")
 )
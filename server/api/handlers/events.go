@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/rs/zerolog/log"
+)
+
+// keepAliveInterval is how often a comment is sent to idle event streams,
+// so proxies and clients don't close them
+const keepAliveInterval = 25 * time.Second
+
+// hub delivers change events to subscribed clients of every user
+type hub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan models.ChangeEvent]struct{}
+}
+
+func newHub() *hub {
+	return &hub{subscribers: make(map[string]map[chan models.ChangeEvent]struct{})}
+}
+
+// subscribe returns channel with change events of the user and function that closes the subscription
+func (h *hub) subscribe(userID string) (<-chan models.ChangeEvent, func()) {
+	events := make(chan models.ChangeEvent, 1)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[chan models.ChangeEvent]struct{})
+	}
+	h.subscribers[userID][events] = struct{}{}
+
+	return events, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subscribers[userID], events)
+		if len(h.subscribers[userID]) == 0 {
+			delete(h.subscribers, userID)
+		}
+	}
+}
+
+// publish sends the event to all subscribers of the user without blocking
+// slow subscriber gets only the latest event, that's enough as it has the latest cursor
+func (h *hub) publish(userID string, event models.ChangeEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for events := range h.subscribers[userID] {
+		select {
+		case <-events:
+		default:
+		}
+		events <- event
+	}
+}
+
+// Subscribe returns channel with change events of the user's data and function that closes the subscription
+// it is used by event streams of REST and gRPC APIs
+func (h *handler) Subscribe(userID string) (<-chan models.ChangeEvent, func()) {
+	return h.events.subscribe(userID)
+}
+
+// Events streams changes of the user's data as server-sent events
+// every event is models.ChangeEvent named models.ChangeEventName,
+// client syncs when event cursor is ahead of its own
+func (h *handler) Events(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	events, unsubscribe := h.Subscribe(session.GetUserID())
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	controller := http.NewResponseController(w)
+	if err = controller.Flush(); err != nil {
+		log.Err(err).Msg("event stream is not supported")
+		return
+	}
+
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		case event := <-events:
+			var data []byte
+			data, err = json.Marshal(event)
+			if err != nil {
+				log.Err(err).Msg("failed to marshal event")
+				return
+			}
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", models.ChangeEventName, data)
+		}
+		if err == nil {
+			err = controller.Flush()
+		}
+		if err != nil {
+			log.Debug().Err(err).Msg("event stream closed")
+			return
+		}
+	}
+}
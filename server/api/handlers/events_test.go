@@ -0,0 +1,82 @@
+package handlers_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	auth "github.com/gynshu-one/goph-keeper/server/api/auth"
+	"github.com/gynshu-one/goph-keeper/server/api/handlers"
+	"github.com/gynshu-one/goph-keeper/server/api/router"
+	"github.com/gynshu-one/goph-keeper/server/storage"
+)
+
+func TestEvents(t *testing.T) {
+	ts := httptest.NewServer(router.NewRouter(
+		handlers.NewHandlers(storage.NewMemoryStorage(storage.Options{}), handlers.Limits{})))
+	defer ts.Close()
+
+	userID := "eventsUserID"
+	session, _ := auth.Sessions.CreateSession(userID)
+	cookie := &http.Cookie{Name: "session_id", Value: session.ID}
+
+	// Subscribe to events
+	request, err := http.NewRequest(http.MethodGet, ts.URL+"/user/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.AddCookie(cookie)
+	stream, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	defer stream.Body.Close()
+	if stream.StatusCode != http.StatusOK || stream.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Unexpected subscription response %d %s", stream.StatusCode, stream.Header.Get("Content-Type"))
+	}
+
+	// Another device syncs a change
+	body, err := json.Marshal([]models.DataWrapper{{ID: "1", OwnerID: userID, Data: []byte("data"), UpdatedAt: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, err = http.NewRequest(http.MethodPost, ts.URL+"/user/sync", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.AddCookie(cookie)
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	response.Body.Close()
+
+	// Read the event
+	received := make(chan string, 1)
+	go func() {
+		var lines []string
+		scanner := bufio.NewScanner(stream.Body)
+		for scanner.Scan() {
+			if scanner.Text() == "" {
+				received <- strings.Join(lines, "\n")
+				return
+			}
+			lines = append(lines, scanner.Text())
+		}
+	}()
+	select {
+	case event := <-received:
+		want := "event: " + models.ChangeEventName + "\ndata: {\"cursor\":1}"
+		if event != want {
+			t.Errorf("Expected event %q, got %q", want, event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Event wasn't received")
+	}
+}
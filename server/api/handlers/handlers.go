@@ -1,12 +1,17 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
 	"github.com/gynshu-one/goph-keeper/common/models"
 	"github.com/gynshu-one/goph-keeper/server/storage"
 	"github.com/rs/zerolog/log"
-	"io"
-	"net/http"
 )
 
 // Handlers is an interface for all handlers at once
@@ -18,25 +23,116 @@ type Handlers interface {
 	LogoutUser(w http.ResponseWriter, r *http.Request)
 
 	SyncUserData(w http.ResponseWriter, r *http.Request)
+
+	ListRevisions(w http.ResponseWriter, r *http.Request)
+
+	GetRevision(w http.ResponseWriter, r *http.Request)
+
+	Usage(w http.ResponseWriter, r *http.Request)
+
+	Events(w http.ResponseWriter, r *http.Request)
+
+	Changes(w http.ResponseWriter, r *http.Request)
+
+	CreateBlob(w http.ResponseWriter, r *http.Request)
+
+	UploadBlob(w http.ResponseWriter, r *http.Request)
+
+	DownloadBlob(w http.ResponseWriter, r *http.Request)
+
+	GetKeys(w http.ResponseWriter, r *http.Request)
+
+	SetKeys(w http.ResponseWriter, r *http.Request)
+
+	GetPublicKey(w http.ResponseWriter, r *http.Request)
+
+	ShareItem(w http.ResponseWriter, r *http.Request)
+
+	ListShares(w http.ResponseWriter, r *http.Request)
+
+	RevokeShare(w http.ResponseWriter, r *http.Request)
+
+	SharedItems(w http.ResponseWriter, r *http.Request)
+
+	UpdateShared(w http.ResponseWriter, r *http.Request)
+
+	ListOrgs(w http.ResponseWriter, r *http.Request)
+
+	CreateOrg(w http.ResponseWriter, r *http.Request)
+
+	ListMembers(w http.ResponseWriter, r *http.Request)
+
+	SetMember(w http.ResponseWriter, r *http.Request)
+
+	DeleteMember(w http.ResponseWriter, r *http.Request)
+
+	AcceptInvite(w http.ResponseWriter, r *http.Request)
+
+	ListCollections(w http.ResponseWriter, r *http.Request)
+
+	SetCollection(w http.ResponseWriter, r *http.Request)
+
+	GetOrgData(w http.ResponseWriter, r *http.Request)
+
+	SetOrgData(w http.ResponseWriter, r *http.Request)
+
+	RotateOrgKey(w http.ResponseWriter, r *http.Request)
+
+	ListEmergencyContacts(w http.ResponseWriter, r *http.Request)
+
+	SetEmergencyContact(w http.ResponseWriter, r *http.Request)
+
+	DeleteEmergencyContact(w http.ResponseWriter, r *http.Request)
+
+	RejectEmergencyAccess(w http.ResponseWriter, r *http.Request)
+
+	ListEmergencyGrantors(w http.ResponseWriter, r *http.Request)
+
+	RequestEmergencyAccess(w http.ResponseWriter, r *http.Request)
+
+	GetEmergencyVault(w http.ResponseWriter, r *http.Request)
+
+	ListSends(w http.ResponseWriter, r *http.Request)
+
+	CreateSend(w http.ResponseWriter, r *http.Request)
+
+	DeleteSend(w http.ResponseWriter, r *http.Request)
+
+	ReceiveSend(w http.ResponseWriter, r *http.Request)
+
+	ShowSend(w http.ResponseWriter, r *http.Request)
 }
 
 type handler struct {
 	storage storage.Storage
+	limits  Limits
+	locks   *userLocks
+	events  *hub
+	replays *replays
 }
 
 // NewHandlers creates a new handlers instance
-func NewHandlers(storage storage.Storage) *handler {
+// limits are applied to every user's data
+func NewHandlers(storage storage.Storage, limits Limits) *handler {
 	return &handler{
 		storage: storage,
+		limits:  limits,
+		locks:   newUserLocks(),
+		events:  newHub(),
+		replays: newReplays(),
 	}
 }
 
 // SyncUserData syncs the data for a user
-// If client didn't send any data, all data from server is returned
+// Client sends the data changed since the last sync and the cursor of the last sync in models.CursorParam,
+// server returns the data changed since the cursor and the new cursor
+// Client without cursor gets all data
 // All new data is added to the db all existing data is updated by the newest one
-// If some data is missing from the client, it will be deleted from the db
 // Data's sensitive fields should be encrypted into binary
 // data should be sent in the []models.DataWrapper format:
+// Data is written atomically, response is models.SyncResponse with a status of every sent item
+// Sync that exceeds Limits is rejected with 413 status, usage is reported in models.Usage headers
+// item that refers to blobs that are not uploaded is rejected
 func (h *handler) SyncUserData(w http.ResponseWriter, r *http.Request) {
 	// Fist we need to get user id from session
 	session, err := FindSession(r)
@@ -47,6 +143,16 @@ func (h *handler) SyncUserData(w http.ResponseWriter, r *http.Request) {
 
 	userID := session.GetUserID()
 
+	cursor, err := parseCursor(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pushOnly := r.URL.Query().Get(models.PushOnlyParam) == "true"
+
+	if h.limits.MaxBodySize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, h.limits.MaxBodySize)
+	}
 	defer func(Body io.ReadCloser) {
 		err = Body.Close()
 		if err != nil {
@@ -59,52 +165,215 @@ func (h *handler) SyncUserData(w http.ResponseWriter, r *http.Request) {
 	// Decode data from client
 	decoder := json.NewDecoder(r.Body)
 	err = decoder.Decode(&fromClient)
-	if err != nil {
-		if err.Error() != "EOF" {
-			log.Debug().Err(err).Msg("failed to decode data")
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		} else {
-			err = nil
+	if err != nil && !errors.Is(err, io.EOF) {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, fmt.Sprintf("%s: limit is %d bytes", ErrBodyTooLarge, maxBytesErr.Limit),
+				http.StatusRequestEntityTooLarge)
+			return
 		}
+		log.Debug().Err(err).Msg("failed to decode data")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	sessUserID := session.GetUserID()
+	key := r.Header.Get(models.IdempotencyKeyHeader)
+	response, usage, err := h.Sync(r.Context(), userID, cursor, pushOnly, key, fromClient)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrIdempotencyKeyReused):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		case errors.Is(err, ErrQuotaExceeded):
+			setUsageHeaders(w, usage)
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		case errors.Is(err, ErrItemTooLarge):
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	setUsageHeaders(w, usage)
+	writeJSON(w, response)
+}
 
-	// Upsert data to db if needed
-	for _, data := range fromClient {
-		if data.OwnerID != sessUserID {
+// Sync stores the data from client of the user and returns the data changed since the cursor
+// together with usage of the user after sync, it is shared by REST and gRPC APIs
+// pushOnly response has no changes and the same cursor, client downloads changes with StreamChanges then
+// returns ErrItemTooLarge or ErrQuotaExceeded with current usage if the data doesn't fit Limits
+// request with idempotency key that was already applied gets the same response without applying it again
+func (h *handler) Sync(ctx context.Context, userID string, cursor int64, pushOnly bool, key string, fromClient []models.DataWrapper) (models.SyncResponse, models.Usage, error) {
+	// Only data of the user is synced
+	results := make([]models.SyncResult, len(fromClient))
+	owned := make([]models.DataWrapper, 0, len(fromClient))
+	ownedIdx := make([]int, 0, len(fromClient))
+	for i, data := range fromClient {
+		if data.OwnerID != userID {
 			log.Info().Msg("user tried to sync data that doesn't belong to him")
+			results[i] = models.SyncResult{ID: data.ID, Status: models.StatusRejected, Reason: ErrForeignData.Error()}
 			continue
 		}
-		err = h.storage.SetData(r.Context(), data)
+		if err := h.limits.checkItem(data); err != nil {
+			return models.SyncResponse{}, models.Usage{}, err
+		}
+		// Item can't refer to a file server doesn't have
+		missing, err := h.missingBlob(ctx, data)
 		if err != nil {
-			log.Err(err).Msg("failed to upsert data")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+			return models.SyncResponse{}, models.Usage{}, err
+		}
+		if missing != "" {
+			results[i] = models.SyncResult{ID: data.ID, Status: models.StatusRejected,
+				Reason: fmt.Sprintf("%s: %s", ErrBlobMissing, missing)}
+			continue
 		}
+		owned = append(owned, data)
+		ownedIdx = append(ownedIdx, i)
 	}
 
-	// Get fresh data from db
-	storedData, err := h.storage.GetData(r.Context(), userID)
+	unlock := h.locks.lock(userID)
+	defer unlock()
+
+	if key == "" {
+		return h.apply(ctx, userID, cursor, pushOnly, owned, ownedIdx, results)
+	}
+	hash, err := requestHash(cursor, pushOnly, fromClient)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return models.SyncResponse{}, models.Usage{}, err
+	}
+	kept, ok, err := h.replays.get(userID, key, hash)
+	if err != nil || ok {
+		return kept.response, kept.usage, err
+	}
+	response, usage, err := h.apply(ctx, userID, cursor, pushOnly, owned, ownedIdx, results)
+	if err != nil {
+		return response, usage, err
 	}
+	h.replays.put(userID, key, replay{hash: hash, response: response, usage: usage})
+	return response, usage, nil
+}
 
-	if len(storedData) == 0 {
-		http.Error(w, ErrNoDataFound.Error(), http.StatusNoContent)
+// apply stores owned data of the user and returns the response of sync, must be called under the user's lock
+// results has a result for every item sent by client, owned items get results at ownedIdx
+func (h *handler) apply(ctx context.Context, userID string, cursor int64, pushOnly bool, owned []models.DataWrapper, ownedIdx []int, results []models.SyncResult) (models.SyncResponse, models.Usage, error) {
+	// Check that data fits user's quota, only the sent items are read so sync doesn't load the whole vault
+	ids := make([]string, len(owned))
+	for i, data := range owned {
+		ids[i] = data.ID
+	}
+	storedData, err := h.storage.GetDataByIDs(ctx, userID, ids)
+	if err != nil {
+		return models.SyncResponse{}, models.Usage{}, err
+	}
+	usage, err := h.usage(ctx, userID)
+	if err != nil {
+		return models.SyncResponse{}, models.Usage{}, err
+	}
+	projected := h.limits.project(usage, storedData, owned)
+	if err = h.limits.checkQuota(usage, projected); err != nil {
+		return models.SyncResponse{}, usage, err
 	}
 
-	// Marshal and send
-	marshalledData, err := json.Marshal(storedData)
+	// Upsert data to db if needed, all or nothing
+	applied, err := h.storage.SetDataBatch(ctx, owned)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Err(err).Msg("failed to upsert data")
+		return models.SyncResponse{}, usage, err
+	}
+	appliedData := make([]models.DataWrapper, 0, len(applied))
+	for i, result := range applied {
+		results[ownedIdx[i]] = result
+		if result.Status == models.StatusApplied {
+			appliedData = append(appliedData, owned[i])
+		}
+	}
+	usage = h.limits.project(usage, storedData, appliedData)
+
+	if pushOnly {
+		changes, err := h.storage.CheckChanges(ctx, userID, cursor, false)
+		if err != nil {
+			return models.SyncResponse{}, usage, err
+		}
+		if len(appliedData) > 0 {
+			h.events.publish(userID, models.ChangeEvent{Cursor: changes.Cursor})
+		}
+		return models.SyncResponse{
+			Results: results,
+			Data:    withCurrent(nil, storedData, applied),
+			Cursor:  cursor,
+		}, usage, nil
+	}
+
+	// Get changes since client's cursor
+	changes, err := h.storage.GetChanges(ctx, userID, cursor)
+	if err != nil {
+		return models.SyncResponse{}, usage, err
+	}
+	if len(appliedData) > 0 {
+		// Other devices of the user sync when they get the event
+		h.events.publish(userID, models.ChangeEvent{Cursor: changes.Cursor})
+	}
+	response := models.SyncResponse{
+		Results: results,
+		Data:    changes.Data,
+		Cursor:  changes.Cursor,
+		Reset:   changes.Reset,
+	}
+	if !changes.Reset {
+		// Client should get current revisions of items it sent again even if they are older than cursor
+		response.Data = withCurrent(response.Data, storedData, applied)
+	}
+	return response, usage, nil
+}
+
+// parseCursor reads the cursor of the last sync from models.CursorParam, missing cursor is zero
+func parseCursor(r *http.Request) (cursor int64, err error) {
+	if param := r.URL.Query().Get(models.CursorParam); param != "" {
+		cursor, err = strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			return 0, ErrInvalidCursor
+		}
+	}
+	return cursor, nil
+}
+
+// withCurrent adds stored versions of items that were stale to changes
+// versions of items in conflict are sent in their results
+func withCurrent(changes, stored []models.DataWrapper, results []models.SyncResult) []models.DataWrapper {
+	changed := make(map[string]struct{}, len(changes))
+	for _, data := range changes {
+		changed[data.ID] = struct{}{}
+	}
+	current := make(map[string]models.DataWrapper, len(stored))
+	for _, data := range stored {
+		current[data.ID] = data
+	}
+	for _, result := range results {
+		if result.Status != models.StatusStale {
+			continue
+		}
+		data, ok := current[result.ID]
+		if _, sent := changed[result.ID]; !ok || sent {
+			continue
+		}
+		changes = append(changes, data)
+		changed[result.ID] = struct{}{}
+	}
+	return changes
+}
+
+// Usage returns storage usage of the user and server limits
+// response is models.Usage in json format
+func (h *handler) Usage(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	_, err = w.Write(marshalledData)
+	usage, err := h.usage(r.Context(), session.GetUserID())
 	if err != nil {
-		log.Err(err).Msg("failed to write response")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	writeJSON(w, usage)
 }
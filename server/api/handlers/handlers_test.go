@@ -7,6 +7,7 @@ import (
 	"github.com/gynshu-one/goph-keeper/server/storage"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
@@ -17,13 +18,11 @@ import (
 )
 
 func TestSyncUserData(t *testing.T) {
-	// Create a mock storage implementation.
-	mock := &storage.MockStorage{
-		Data: make(map[string][]models.DataWrapper),
-	}
+	// Create an in-memory storage implementation.
+	mock := storage.NewMemoryStorage(storage.Options{})
 
-	// Create a test handler with the mock storage.
-	hndlr := handlers.NewHandlers(mock)
+	// Create a test handler with the in-memory storage.
+	hndlr := handlers.NewHandlers(mock, handlers.Limits{})
 
 	// Create a test user session.
 	userID := "testUserID"
@@ -70,18 +69,28 @@ func TestSyncUserData(t *testing.T) {
 	}
 
 	// Unmarshal the response body to check if data was saved correctly.
-	var responseData []models.DataWrapper
+	var responseData models.SyncResponse
 	err = json.Unmarshal(response.Body.Bytes(), &responseData)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// Check if the response data matches the test data.
-	if len(responseData) != len(testData) {
-		t.Errorf("Expected %d items in response, got %d", len(testData), len(responseData))
+	if len(responseData.Data) != len(testData) {
+		t.Errorf("Expected %d items in response, got %d", len(testData), len(responseData.Data))
+	}
+
+	// Check that every item got its status.
+	if len(responseData.Results) != len(testData) {
+		t.Fatalf("Expected %d results in response, got %d", len(testData), len(responseData.Results))
+	}
+	for i, result := range responseData.Results {
+		if result.ID != testData[i].ID || result.Status != models.StatusApplied {
+			t.Errorf("Expected item %s to be applied, got %+v", testData[i].ID, result)
+		}
 	}
 
-	// Verify that the data was saved in the mock storage.
+	// Verify that the data was saved in the in-memory storage.
 	storedData, err := mock.GetData(context.Background(), userID)
 	if err != nil {
 		t.Errorf("Failed to retrieve data from storage: %v", err)
@@ -91,3 +100,150 @@ func TestSyncUserData(t *testing.T) {
 		t.Errorf("Expected %d items in storage, got %d", len(testData), len(storedData))
 	}
 }
+
+func TestSyncResults(t *testing.T) {
+	hndlr := handlers.NewHandlers(storage.NewMemoryStorage(storage.Options{}), handlers.Limits{})
+	userID := "resultsUserID"
+	session, _ := auth.Sessions.CreateSession(userID)
+
+	first := models.DataWrapper{ID: "1", OwnerID: userID, Data: []byte("first"), UpdatedAt: 10}
+	if response := syncItems(t, hndlr, session, []models.DataWrapper{first}); response.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, response.Code)
+	}
+
+	// Edit of revision that is not the current one
+	conflicting := first
+	conflicting.Data, conflicting.Revision = []byte("other"), 2
+	// The same content sent again
+	older := first
+	older.UpdatedAt = 5
+	foreign := models.DataWrapper{ID: "2", OwnerID: "someone else", UpdatedAt: 10}
+	newItem := models.DataWrapper{ID: "3", OwnerID: userID, Data: []byte("new"), UpdatedAt: 10}
+
+	response := syncItems(t, hndlr, session, []models.DataWrapper{conflicting, older, foreign, newItem})
+	if response.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, response.Code)
+	}
+	var responseData models.SyncResponse
+	if err := json.Unmarshal(response.Body.Bytes(), &responseData); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []models.SyncStatus{models.StatusConflict, models.StatusStale, models.StatusRejected, models.StatusApplied}
+	if len(responseData.Results) != len(want) {
+		t.Fatalf("Expected %d results, got %d", len(want), len(responseData.Results))
+	}
+	for i, status := range want {
+		if responseData.Results[i].Status != status {
+			t.Errorf("Expected result %d to be %s, got %+v", i, status, responseData.Results[i])
+		}
+	}
+	if len(responseData.Data) != 2 {
+		t.Errorf("Expected 2 items in response, got %d", len(responseData.Data))
+	}
+	if server := responseData.Results[0].Server; server == nil || string(server.Data) != "first" {
+		t.Errorf("Expected server version in conflict, got %+v", server)
+	}
+}
+
+func TestSyncCursor(t *testing.T) {
+	hndlr := handlers.NewHandlers(storage.NewMemoryStorage(storage.Options{}), handlers.Limits{})
+	userID := "cursorUserID"
+	session, _ := auth.Sessions.CreateSession(userID)
+	decode := func(response *httptest.ResponseRecorder) models.SyncResponse {
+		t.Helper()
+		if response.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, response.Code)
+		}
+		var responseData models.SyncResponse
+		if err := json.Unmarshal(response.Body.Bytes(), &responseData); err != nil {
+			t.Fatal(err)
+		}
+		return responseData
+	}
+
+	// First sync returns all data
+	first := models.DataWrapper{ID: "1", OwnerID: userID, Data: []byte("first"), UpdatedAt: 10}
+	second := models.DataWrapper{ID: "2", OwnerID: userID, Data: []byte("second"), UpdatedAt: 10}
+	responseData := decode(syncItems(t, hndlr, session, []models.DataWrapper{first, second}))
+	if !responseData.Reset || responseData.Cursor != 2 || len(responseData.Data) != 2 {
+		t.Fatalf("Unexpected first sync response %+v", responseData)
+	}
+	cursor := strconv.FormatInt(responseData.Cursor, 10)
+
+	// Nothing changed since the cursor
+	responseData = decode(syncSince(t, hndlr, session, cursor, nil))
+	if responseData.Reset || responseData.Cursor != 2 || len(responseData.Data) != 0 {
+		t.Errorf("Expected no changes, got %+v", responseData)
+	}
+
+	// Only changed item is returned, and the current version of item client failed to update
+	edited := first
+	edited.Data, edited.Revision = []byte("edited"), 1
+	resent := second
+	responseData = decode(syncSince(t, hndlr, session, cursor, []models.DataWrapper{edited, resent}))
+	if responseData.Reset || responseData.Cursor != 3 || len(responseData.Data) != 2 {
+		t.Fatalf("Unexpected delta response %+v", responseData)
+	}
+	if string(responseData.Data[0].Data) != "edited" || responseData.Data[1].Revision != 1 {
+		t.Errorf("Unexpected delta data %+v", responseData.Data)
+	}
+
+	// Invalid cursor
+	if response := syncSince(t, hndlr, session, "abc", nil); response.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, response.Code)
+	}
+}
+
+func TestSyncIdempotency(t *testing.T) {
+	hndlr := handlers.NewHandlers(storage.NewMemoryStorage(storage.Options{}), handlers.Limits{})
+	userID := "idempotencyUserID"
+	session, _ := auth.Sessions.CreateSession(userID)
+	send := func(key string, items []models.DataWrapper) *httptest.ResponseRecorder {
+		t.Helper()
+		requestData, err := json.Marshal(items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		request := httptest.NewRequest(http.MethodPost, "/user/sync", bytes.NewReader(requestData))
+		request.AddCookie(&http.Cookie{Name: "session_id", Value: session.ID})
+		request.Header.Set(models.IdempotencyKeyHeader, key)
+		response := httptest.NewRecorder()
+		hndlr.SyncUserData(response, request)
+		return response
+	}
+
+	// Repeated request gets the response of the first one
+	item := models.DataWrapper{ID: "1", OwnerID: userID, Data: []byte("first"), UpdatedAt: 10}
+	first := send("key", []models.DataWrapper{item})
+	repeated := send("key", []models.DataWrapper{item})
+	if first.Code != http.StatusOK || repeated.Code != http.StatusOK {
+		t.Fatalf("Unexpected status codes %d and %d", first.Code, repeated.Code)
+	}
+	if first.Body.String() != repeated.Body.String() {
+		t.Errorf("Repeated request got another response %s, want %s", repeated.Body.String(), first.Body.String())
+	}
+	var responseData models.SyncResponse
+	if err := json.Unmarshal(repeated.Body.Bytes(), &responseData); err != nil {
+		t.Fatal(err)
+	}
+	if len(responseData.Results) != 1 || responseData.Results[0].Status != models.StatusApplied {
+		t.Errorf("Unexpected results %+v", responseData.Results)
+	}
+
+	// Another request with the same key
+	item.Data = []byte("second")
+	if response := send("key", []models.DataWrapper{item}); response.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status code %d, got %d", http.StatusUnprocessableEntity, response.Code)
+	}
+
+	// Request without the key is applied again
+	item.Data = []byte("first")
+	response := send("", []models.DataWrapper{item})
+	if err := json.Unmarshal(response.Body.Bytes(), &responseData); err != nil {
+		t.Fatal(err)
+	}
+	if len(responseData.Results) != 1 || responseData.Results[0].Status == models.StatusApplied {
+		t.Errorf("Unexpected results %+v", responseData.Results)
+	}
+}
@@ -1,8 +1,11 @@
 package handlers
 
 import (
-	auth "github.com/gynshu-one/goph-keeper/server/api/auth"
+	"encoding/json"
 	"net/http"
+
+	auth "github.com/gynshu-one/goph-keeper/server/api/auth"
+	"github.com/rs/zerolog/log"
 )
 
 // FindSession finds the session for the request
@@ -18,3 +21,18 @@ func FindSession(r *http.Request) (*auth.Session, error) {
 	}
 	return session, nil
 }
+
+// writeJSON marshals v and writes it to w
+func writeJSON(w http.ResponseWriter, v any) {
+	marshalledData, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(marshalledData)
+	if err != nil {
+		log.Err(err).Msg("failed to write response")
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gynshu-one/goph-keeper/server/storage"
+)
+
+// ListRevisions returns previous versions of the user's item, newest first
+// item id is passed as "id" url parameter: /user/history/{id}
+// response is []models.DataWrapper in json format without Data field
+func (h *handler) ListRevisions(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	revisions, err := h.storage.GetRevisions(r.Context(), session.GetUserID(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, revisions)
+}
+
+// GetRevision returns one previous version of the user's item with its encrypted data
+// item id and revision are passed as url parameters: /user/history/{id}/{revision}
+// response is models.DataWrapper in json format
+func (h *handler) GetRevision(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	revision, err := strconv.ParseInt(chi.URLParam(r, "revision"), 10, 64)
+	if err != nil {
+		http.Error(w, "revision is invalid", http.StatusBadRequest)
+		return
+	}
+
+	data, err := h.storage.GetRevision(r.Context(), session.GetUserID(), chi.URLParam(r, "id"), revision)
+	if err != nil {
+		if errors.Is(err, storage.ErrRevisionNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, data)
+}
@@ -0,0 +1,88 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	auth "github.com/gynshu-one/goph-keeper/server/api/auth"
+	"github.com/gynshu-one/goph-keeper/server/api/handlers"
+	"github.com/gynshu-one/goph-keeper/server/api/router"
+	"github.com/gynshu-one/goph-keeper/server/storage"
+)
+
+func TestRevisions(t *testing.T) {
+	// Create an in-memory storage that keeps history.
+	s := storage.NewMemoryStorage(storage.Options{MaxRevisions: 5})
+	r := router.NewRouter(handlers.NewHandlers(s, handlers.Limits{}))
+
+	userID := "testUserID"
+	session, _ := auth.Sessions.CreateSession(userID)
+	cookie := &http.Cookie{Name: "session_id", Value: session.ID}
+
+	// Sync two versions of the same item.
+	for i, data := range []string{"first", "second"} {
+		body, err := json.Marshal([]models.DataWrapper{{
+			ID:        "1",
+			OwnerID:   userID,
+			Name:      "Test Data",
+			Data:      []byte(data),
+			UpdatedAt: int64(i + 1),
+			Revision:  int64(i),
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		request := httptest.NewRequest(http.MethodPost, "/user/sync", bytes.NewReader(body))
+		request.AddCookie(cookie)
+		response := httptest.NewRecorder()
+		r.ServeHTTP(response, request)
+		if response.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, response.Code)
+		}
+	}
+
+	// List revisions.
+	request := httptest.NewRequest(http.MethodGet, "/user/history/1", nil)
+	request.AddCookie(cookie)
+	response := httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if response.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, response.Code)
+	}
+	var revisions []models.DataWrapper
+	if err := json.Unmarshal(response.Body.Bytes(), &revisions); err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 1 || revisions[0].Revision != 1 {
+		t.Fatalf("Expected one revision with number 1, got %+v", revisions)
+	}
+
+	// Fetch the revision.
+	request = httptest.NewRequest(http.MethodGet, "/user/history/1/1", nil)
+	request.AddCookie(cookie)
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if response.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, response.Code)
+	}
+	var revision models.DataWrapper
+	if err := json.Unmarshal(response.Body.Bytes(), &revision); err != nil {
+		t.Fatal(err)
+	}
+	if string(revision.Data) != "first" {
+		t.Errorf("Expected data of the first version, got %s", revision.Data)
+	}
+
+	// Unknown revision.
+	request = httptest.NewRequest(http.MethodGet, "/user/history/1/7", nil)
+	request.AddCookie(cookie)
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if response.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, response.Code)
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+)
+
+const (
+	// replayTTL is how long response of sync with idempotency key is kept for repeated requests
+	replayTTL = 10 * time.Minute
+	// maxReplays bounds the number of kept responses
+	maxReplays = 10000
+)
+
+// replay is the response of sync with idempotency key
+type replay struct {
+	// hash is the hash of the request, the same key with another request is an error
+	hash     [sha256.Size]byte
+	response models.SyncResponse
+	usage    models.Usage
+	expires  time.Time
+}
+
+// replays keeps responses of syncs with idempotency keys, so retried request is not applied twice
+type replays struct {
+	mu      sync.Mutex
+	entries map[string]replay
+}
+
+func newReplays() *replays {
+	return &replays{entries: make(map[string]replay)}
+}
+
+// get returns kept response of the request with the key of the user
+// returns ErrIdempotencyKeyReused if the key was used for another request
+func (r *replays) get(userID, key string, hash [sha256.Size]byte) (replay, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[userID+"/"+key]
+	if !ok || time.Now().After(entry.expires) {
+		return replay{}, false, nil
+	}
+	if entry.hash != hash {
+		return replay{}, false, ErrIdempotencyKeyReused
+	}
+	return entry, true, nil
+}
+
+// put keeps the response for replayTTL, expired responses are dropped when there are too many of them
+func (r *replays) put(userID, key string, entry replay) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if len(r.entries) >= maxReplays {
+		for k, e := range r.entries {
+			if now.After(e.expires) {
+				delete(r.entries, k)
+			}
+		}
+	}
+	// Still full, repeated request without kept response is applied again and gets stale results
+	for k := range r.entries {
+		if len(r.entries) < maxReplays {
+			break
+		}
+		delete(r.entries, k)
+	}
+	entry.expires = now.Add(replayTTL)
+	r.entries[userID+"/"+key] = entry
+}
+
+// requestHash returns hash of sync request
+func requestHash(cursor int64, pushOnly bool, data []models.DataWrapper) ([sha256.Size]byte, error) {
+	body, err := json.Marshal(struct {
+		Cursor   int64                `json:"cursor"`
+		PushOnly bool                 `json:"push_only"`
+		Data     []models.DataWrapper `json:"data"`
+	}{cursor, pushOnly, data})
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(body), nil
+}
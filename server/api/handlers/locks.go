@@ -0,0 +1,43 @@
+package handlers
+
+import "sync"
+
+// userLocks serializes syncs of the same user
+// so a change cursor is never returned while a write before it is still in flight
+type userLocks struct {
+	mu    sync.Mutex
+	locks map[string]*userLock
+}
+
+type userLock struct {
+	mu sync.Mutex
+	// refs is the number of syncs holding or waiting for the lock
+	refs int
+}
+
+func newUserLocks() *userLocks {
+	return &userLocks{locks: make(map[string]*userLock)}
+}
+
+// lock locks the user and returns function that unlocks it
+func (l *userLocks) lock(userID string) (unlock func()) {
+	l.mu.Lock()
+	lock, ok := l.locks[userID]
+	if !ok {
+		lock = &userLock{}
+		l.locks[userID] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	lock.mu.Lock()
+	return func() {
+		lock.mu.Unlock()
+		l.mu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(l.locks, userID)
+		}
+		l.mu.Unlock()
+	}
+}
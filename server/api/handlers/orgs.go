@@ -0,0 +1,589 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/gynshu-one/goph-keeper/common/utils"
+	"github.com/gynshu-one/goph-keeper/server/storage"
+)
+
+// ListOrgs returns organizations of the user including the ones it's invited to
+// response is []models.Membership in json format, Key of the membership is the org key sealed to the user
+func (h *handler) ListOrgs(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	memberships, err := h.Orgs(r.Context(), session.GetUserID())
+	if err != nil {
+		http.Error(w, err.Error(), orgStatus(err))
+		return
+	}
+	writeJSON(w, memberships)
+}
+
+// CreateOrg creates an organization owned by the user, request is models.Membership in json format
+// with name of the organization and the org key sealed to the public key of the user in Member.Key
+// response is the created models.Membership
+func (h *handler) CreateOrg(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var membership models.Membership
+	if err = json.NewDecoder(io.LimitReader(r.Body, 1<<10)).Decode(&membership); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	membership, err = h.NewOrg(r.Context(), session.GetUserID(), membership)
+	if err != nil {
+		http.Error(w, err.Error(), orgStatus(err))
+		return
+	}
+	writeJSON(w, membership)
+}
+
+// ListMembers returns members of the organization, org id is passed as "org" url parameter: /user/orgs/{org}/members
+// response is []models.Member in json format without their keys
+func (h *handler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	members, err := h.Members(r.Context(), session.GetUserID(), chi.URLParam(r, "org"))
+	if err != nil {
+		http.Error(w, err.Error(), orgStatus(err))
+		return
+	}
+	writeJSON(w, members)
+}
+
+// SetMember invites a user to the organization or changes its role, request is models.Member in json format
+// with user id, role and the current org key sealed to public key of the user
+// only owner and admins manage members, response is the stored models.Member
+func (h *handler) SetMember(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var member models.Member
+	if err = json.NewDecoder(io.LimitReader(r.Body, 1<<10)).Decode(&member); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	member.OrgID = chi.URLParam(r, "org")
+	member, err = h.Invite(r.Context(), session.GetUserID(), member)
+	if err != nil {
+		http.Error(w, err.Error(), orgStatus(err))
+		return
+	}
+	writeJSON(w, member)
+}
+
+// DeleteMember removes the user from the organization, or the user leaves it if it's the user itself
+// org id and user are passed as url parameters: /user/orgs/{org}/members/{user}
+// client rotates the org key after that, so the user can't read new versions of items
+func (h *handler) DeleteMember(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.RemoveMember(r.Context(), session.GetUserID(), chi.URLParam(r, "org"), chi.URLParam(r, "user"))
+	if err != nil {
+		http.Error(w, err.Error(), orgStatus(err))
+		return
+	}
+}
+
+// AcceptInvite accepts the invitation of the user to the organization: /user/orgs/{org}/accept
+// response is the accepted models.Member
+func (h *handler) AcceptInvite(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	member, err := h.Accept(r.Context(), session.GetUserID(), chi.URLParam(r, "org"))
+	if err != nil {
+		http.Error(w, err.Error(), orgStatus(err))
+		return
+	}
+	writeJSON(w, member)
+}
+
+// ListCollections returns collections of the organization: /user/orgs/{org}/collections
+// response is []models.Collection in json format
+func (h *handler) ListCollections(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	collections, err := h.Collections(r.Context(), session.GetUserID(), chi.URLParam(r, "org"))
+	if err != nil {
+		http.Error(w, err.Error(), orgStatus(err))
+		return
+	}
+	writeJSON(w, collections)
+}
+
+// SetCollection adds a collection to the organization or renames it, request is models.Collection in json format
+// collection without id is added, response is the stored models.Collection
+func (h *handler) SetCollection(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var collection models.Collection
+	if err = json.NewDecoder(io.LimitReader(r.Body, 1<<10)).Decode(&collection); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	collection.OrgID = chi.URLParam(r, "org")
+	collection, err = h.PutCollection(r.Context(), session.GetUserID(), collection)
+	if err != nil {
+		http.Error(w, err.Error(), orgStatus(err))
+		return
+	}
+	writeJSON(w, collection)
+}
+
+// GetOrgData returns items of the organization: /user/orgs/{org}/data
+// response is models.OrgData in json format with the version of the org key items are encrypted with
+func (h *handler) GetOrgData(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, err := h.OrgData(r.Context(), session.GetUserID(), chi.URLParam(r, "org"))
+	if err != nil {
+		http.Error(w, err.Error(), orgStatus(err))
+		return
+	}
+	writeJSON(w, data)
+}
+
+// SetOrgData stores items of the organization, request is models.OrgData in json format
+// data encrypted with an old org key is rejected with 409 status, response is []models.SyncResult like in sync
+func (h *handler) SetOrgData(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var data models.OrgData
+	if !h.decodeLimited(w, r, &data) {
+		return
+	}
+	data.OrgID = chi.URLParam(r, "org")
+	results, err := h.WriteOrgData(r.Context(), session.GetUserID(), data)
+	if err != nil {
+		http.Error(w, err.Error(), orgStatus(err))
+		return
+	}
+	writeJSON(w, results)
+}
+
+// RotateOrgKey replaces the org key, request is models.KeyRotation in json format
+// with the new key sealed to every member and every item encrypted with it: /user/orgs/{org}/key
+// rotation made while items were changed is rejected with 409 status, response is []models.SyncResult
+func (h *handler) RotateOrgKey(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var rotation models.KeyRotation
+	if !h.decodeLimited(w, r, &rotation) {
+		return
+	}
+	rotation.OrgID = chi.URLParam(r, "org")
+	results, err := h.RotateKey(r.Context(), session.GetUserID(), rotation)
+	if err != nil {
+		http.Error(w, err.Error(), orgStatus(err))
+		return
+	}
+	writeJSON(w, results)
+}
+
+// decodeLimited decodes the body of the request up to Limits.MaxBodySize into v
+// writes the error to w and returns false if it fails
+func (h *handler) decodeLimited(w http.ResponseWriter, r *http.Request, v any) bool {
+	if h.limits.MaxBodySize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, h.limits.MaxBodySize)
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, ErrBodyTooLarge.Error(), http.StatusRequestEntityTooLarge)
+			return false
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// Orgs returns organizations of the user, it is shared by REST and gRPC APIs
+func (h *handler) Orgs(ctx context.Context, userID string) ([]models.Membership, error) {
+	return h.storage.GetMemberships(ctx, userID)
+}
+
+// NewOrg creates the organization with the user as its owner, it is shared by REST and gRPC APIs
+// the user must have keys, the org key is sealed to them by client
+func (h *handler) NewOrg(ctx context.Context, userID string, membership models.Membership) (models.Membership, error) {
+	if membership.Organization.Name == "" || len(membership.Member.Key) < utils.MinSealedKeySize {
+		return models.Membership{}, ErrInvalidOrg
+	}
+	if _, err := h.Keys(ctx, userID); err != nil {
+		return models.Membership{}, err
+	}
+	now := time.Now().Unix()
+	org := models.Organization{ID: uuid.New().String(), Name: membership.Organization.Name, KeyVersion: 1, CreatedAt: now}
+	owner := models.Member{
+		OrgID:      org.ID,
+		UserID:     userID,
+		Role:       models.RoleOwner,
+		Key:        membership.Member.Key,
+		KeyVersion: org.KeyVersion,
+		Accepted:   true,
+		CreatedAt:  now,
+	}
+	if err := h.storage.CreateOrg(ctx, org, owner); err != nil {
+		return models.Membership{}, err
+	}
+	return models.Membership{Organization: org, Member: owner}, nil
+}
+
+// Members returns members of the organization without their keys, it is shared by REST and gRPC APIs
+func (h *handler) Members(ctx context.Context, userID, orgID string) ([]models.Member, error) {
+	if _, _, err := h.orgMember(ctx, orgID, userID, nil); err != nil {
+		return nil, err
+	}
+	members, err := h.storage.GetMembers(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range members {
+		members[i].Key = nil
+	}
+	return members, nil
+}
+
+// Invite adds the user to the organization or changes its role, it is shared by REST and gRPC APIs
+// owner and admins manage members and readers, only owner manages admins and nobody changes the owner
+// the key must be of the current version, invited user accepts the invitation to get data of the organization
+func (h *handler) Invite(ctx context.Context, userID string, member models.Member) (models.Member, error) {
+	if member.UserID == "" || member.UserID == userID || !member.Role.Valid() || member.Role == models.RoleOwner ||
+		len(member.Key) < utils.MinSealedKeySize {
+		return models.Member{}, ErrInvalidMember
+	}
+	org, caller, err := h.orgMember(ctx, member.OrgID, userID, models.Role.CanManage)
+	if err != nil {
+		return models.Member{}, err
+	}
+	if member.KeyVersion != org.KeyVersion {
+		return models.Member{}, storage.ErrKeyVersion
+	}
+	if member.Role == models.RoleAdmin && caller.Role != models.RoleOwner {
+		return models.Member{}, ErrForbidden
+	}
+	member.Accepted, member.CreatedAt = false, time.Now().Unix()
+	existing, err := h.storage.GetMember(ctx, member.OrgID, member.UserID)
+	switch {
+	case errors.Is(err, storage.ErrMemberNotFound):
+	case err != nil:
+		return models.Member{}, err
+	case existing.Role == models.RoleOwner, existing.Role == models.RoleAdmin && caller.Role != models.RoleOwner:
+		return models.Member{}, ErrForbidden
+	default:
+		member.Accepted, member.CreatedAt = existing.Accepted, existing.CreatedAt
+	}
+	if _, err = h.PublicKey(ctx, member.UserID); err != nil {
+		return models.Member{}, err
+	}
+	if err = h.storage.SetMember(ctx, member); err != nil {
+		return models.Member{}, err
+	}
+	return member, nil
+}
+
+// Accept accepts the invitation of the user to the organization, it is shared by REST and gRPC APIs
+func (h *handler) Accept(ctx context.Context, userID, orgID string) (models.Member, error) {
+	member, err := h.storage.GetMember(ctx, orgID, userID)
+	if errors.Is(err, storage.ErrMemberNotFound) {
+		return models.Member{}, ErrNotMember
+	}
+	if err != nil || member.Accepted {
+		return member, err
+	}
+	member.Accepted = true
+	if err = h.storage.SetMember(ctx, member); err != nil {
+		return models.Member{}, err
+	}
+	return member, nil
+}
+
+// RemoveMember removes the member from the organization, it is shared by REST and gRPC APIs
+// any member can leave, owner and admins remove others like they manage them in Invite
+func (h *handler) RemoveMember(ctx context.Context, userID, orgID, memberID string) error {
+	callerRole := models.RoleReadOnly
+	if memberID != userID {
+		_, caller, err := h.orgMember(ctx, orgID, userID, models.Role.CanManage)
+		if err != nil {
+			return err
+		}
+		callerRole = caller.Role
+	}
+	target, err := h.storage.GetMember(ctx, orgID, memberID)
+	if errors.Is(err, storage.ErrMemberNotFound) && memberID == userID {
+		return ErrNotMember
+	}
+	if err != nil {
+		return err
+	}
+	if target.Role == models.RoleOwner ||
+		target.Role == models.RoleAdmin && memberID != userID && callerRole != models.RoleOwner {
+		return ErrForbidden
+	}
+	return h.storage.DeleteMember(ctx, orgID, memberID)
+}
+
+// Collections returns collections of the organization, it is shared by REST and gRPC APIs
+func (h *handler) Collections(ctx context.Context, userID, orgID string) ([]models.Collection, error) {
+	if _, _, err := h.orgMember(ctx, orgID, userID, nil); err != nil {
+		return nil, err
+	}
+	return h.storage.GetCollections(ctx, orgID)
+}
+
+// PutCollection adds the collection to the organization or renames it, it is shared by REST and gRPC APIs
+func (h *handler) PutCollection(ctx context.Context, userID string, collection models.Collection) (models.Collection, error) {
+	if collection.Name == "" {
+		return models.Collection{}, ErrInvalidCollection
+	}
+	if _, _, err := h.orgMember(ctx, collection.OrgID, userID, models.Role.CanManage); err != nil {
+		return models.Collection{}, err
+	}
+	if collection.ID == "" {
+		collection.ID, collection.CreatedAt = uuid.New().String(), time.Now().Unix()
+	} else {
+		collections, err := h.storage.GetCollections(ctx, collection.OrgID)
+		if err != nil {
+			return models.Collection{}, err
+		}
+		i := slices.IndexFunc(collections, func(c models.Collection) bool { return c.ID == collection.ID })
+		if i < 0 {
+			return models.Collection{}, ErrCollectionNotFound
+		}
+		collection.CreatedAt = collections[i].CreatedAt
+	}
+	if err := h.storage.SetCollection(ctx, collection); err != nil {
+		return models.Collection{}, err
+	}
+	return collection, nil
+}
+
+// OrgData returns not deleted items of the organization, it is shared by REST and gRPC APIs
+func (h *handler) OrgData(ctx context.Context, userID, orgID string) (models.OrgData, error) {
+	org, _, err := h.orgMember(ctx, orgID, userID, nil)
+	if err != nil {
+		return models.OrgData{}, err
+	}
+	data, err := h.orgItems(ctx, orgID)
+	if err != nil {
+		return models.OrgData{}, err
+	}
+	return models.OrgData{OrgID: orgID, KeyVersion: org.KeyVersion, Data: data}, nil
+}
+
+// WriteOrgData stores items of the organization, it is shared by REST and gRPC APIs
+// the user must be allowed to write and items must be encrypted with the current org key,
+// otherwise storage.ErrKeyVersion is returned, items are applied like in sync and count to quota of the organization
+func (h *handler) WriteOrgData(ctx context.Context, userID string, data models.OrgData) ([]models.SyncResult, error) {
+	unlock := h.locks.lock(data.OrgID)
+	defer unlock()
+
+	org, _, err := h.orgMember(ctx, data.OrgID, userID, models.Role.CanWrite)
+	if err != nil {
+		return nil, err
+	}
+	if data.KeyVersion != org.KeyVersion {
+		return nil, storage.ErrKeyVersion
+	}
+	if err = h.checkOrgItems(ctx, org.ID, data.Data); err != nil {
+		return nil, err
+	}
+	return h.applyOrg(ctx, org.ID, data.Data)
+}
+
+// RotateKey replaces the org key with the next version, it is shared by REST and gRPC APIs
+// every member must get the new key and every item must be encrypted with it,
+// otherwise ErrInvalidRotation is returned, rotation made from old versions of items gets ErrRotationConflict
+func (h *handler) RotateKey(ctx context.Context, userID string, rotation models.KeyRotation) ([]models.SyncResult, error) {
+	unlock := h.locks.lock(rotation.OrgID)
+	defer unlock()
+
+	org, _, err := h.orgMember(ctx, rotation.OrgID, userID, models.Role.CanManage)
+	if err != nil {
+		return nil, err
+	}
+	if rotation.KeyVersion != org.KeyVersion+1 {
+		return nil, storage.ErrKeyVersion
+	}
+	members, err := h.storage.GetMembers(ctx, org.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(rotation.Members) != len(members) {
+		return nil, ErrInvalidRotation
+	}
+	for _, member := range members {
+		i := slices.IndexFunc(rotation.Members, func(m models.Member) bool { return m.UserID == member.UserID })
+		if i < 0 || len(rotation.Members[i].Key) < utils.MinSealedKeySize {
+			return nil, ErrInvalidRotation
+		}
+		member.Key = rotation.Members[i].Key
+		rotation.Members[i] = member
+	}
+
+	stored, err := h.orgItems(ctx, org.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(rotation.Data) != len(stored) {
+		return nil, ErrInvalidRotation
+	}
+	for _, item := range stored {
+		i := slices.IndexFunc(rotation.Data, func(data models.DataWrapper) bool { return data.ID == item.ID })
+		if i < 0 {
+			return nil, ErrInvalidRotation
+		}
+		if rotation.Data[i].Revision != item.Revision {
+			return nil, ErrRotationConflict
+		}
+	}
+	if err = h.checkOrgItems(ctx, org.ID, rotation.Data); err != nil {
+		return nil, err
+	}
+	// Items are re-encrypted first, if storing the keys fails the rotation is made again from the new items
+	results, err := h.applyOrg(ctx, org.ID, rotation.Data)
+	if err != nil {
+		return nil, err
+	}
+	if err = h.storage.RotateOrgKey(ctx, org.ID, rotation.KeyVersion, rotation.Members); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// orgMember returns the organization and the membership of the user in it
+// the user must have accepted the invitation and its role must be allowed, any role is allowed if allowed is nil
+// returns ErrNotMember for organizations the user is not a member of, so they look like missing ones
+func (h *handler) orgMember(ctx context.Context, orgID, userID string, allowed func(models.Role) bool) (models.Organization, models.Member, error) {
+	member, err := h.storage.GetMember(ctx, orgID, userID)
+	if errors.Is(err, storage.ErrMemberNotFound) || err == nil && !member.Accepted {
+		return models.Organization{}, models.Member{}, ErrNotMember
+	}
+	if err != nil {
+		return models.Organization{}, models.Member{}, err
+	}
+	if allowed != nil && !allowed(member.Role) {
+		return models.Organization{}, models.Member{}, ErrForbidden
+	}
+	org, err := h.storage.GetOrg(ctx, orgID)
+	return org, member, err
+}
+
+// orgItems returns not deleted items of the organization
+func (h *handler) orgItems(ctx context.Context, orgID string) ([]models.DataWrapper, error) {
+	data, err := h.storage.GetData(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(data, func(item models.DataWrapper) bool { return item.DeletedAt != 0 }), nil
+}
+
+// checkOrgItems makes items owned by the organization and checks they fit Limits,
+// are in its collections and have no files, which are encrypted with keys of users
+func (h *handler) checkOrgItems(ctx context.Context, orgID string, items []models.DataWrapper) error {
+	collections, err := h.storage.GetCollections(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		item := &items[i]
+		item.OwnerID, item.Key = orgID, nil
+		if len(item.Blobs) > 0 {
+			return ErrOrgBlobs
+		}
+		if !slices.ContainsFunc(collections, func(c models.Collection) bool { return c.ID == item.CollectionID }) {
+			return ErrCollectionNotFound
+		}
+		if err = h.limits.checkItem(*item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyOrg stores items of the organization like sync does, must be called under the lock of the organization
+func (h *handler) applyOrg(ctx context.Context, orgID string, items []models.DataWrapper) ([]models.SyncResult, error) {
+	results := make([]models.SyncResult, len(items))
+	idx := make([]int, len(items))
+	for i := range idx {
+		idx[i] = i
+	}
+	response, _, err := h.apply(ctx, orgID, 0, true, items, idx, results)
+	if err != nil {
+		return nil, err
+	}
+	return response.Results, nil
+}
+
+// orgStatus returns http status of the error of organization requests
+func orgStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidOrg), errors.Is(err, ErrInvalidMember), errors.Is(err, ErrInvalidCollection),
+		errors.Is(err, ErrOrgBlobs), errors.Is(err, ErrInvalidRotation):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrNotMember), errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrKeysNotFound), errors.Is(err, ErrUserNotFound), errors.Is(err, ErrCollectionNotFound),
+		errors.Is(err, storage.ErrOrgNotFound), errors.Is(err, storage.ErrMemberNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrKeyVersion), errors.Is(err, ErrRotationConflict),
+		errors.Is(err, storage.ErrCollectionExists):
+		return http.StatusConflict
+	case errors.Is(err, ErrItemTooLarge), errors.Is(err, ErrQuotaExceeded):
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusInternalServerError
+}
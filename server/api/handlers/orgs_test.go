@@ -0,0 +1,205 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	auth "github.com/gynshu-one/goph-keeper/server/api/auth"
+	"github.com/gynshu-one/goph-keeper/server/api/handlers"
+	"github.com/gynshu-one/goph-keeper/server/api/router"
+	"github.com/gynshu-one/goph-keeper/server/storage"
+)
+
+func TestOrgs(t *testing.T) {
+	s := storage.NewMemoryStorage(storage.Options{})
+	r := router.NewRouter(handlers.NewHandlers(s, handlers.Limits{}))
+	emails := []string{"owner@example.com", "admin@example.com", "member@example.com", "reader@example.com", "outsider@example.com"}
+	servers := make(map[string]func(*http.Request) *httptest.ResponseRecorder)
+	for _, email := range emails {
+		if err := s.CreateUser(context.Background(), models.User{Email: email}); err != nil {
+			t.Fatal(err)
+		}
+		keys := models.UserKeys{PublicKey: bytes.Repeat([]byte{1}, 32), PrivateKey: []byte("encrypted")}
+		if err := s.SetUserKeys(context.Background(), email, keys); err != nil {
+			t.Fatal(err)
+		}
+		session, _ := auth.Sessions.CreateSession(email)
+		cookie := &http.Cookie{Name: "session_id", Value: session.ID}
+		servers[email] = func(request *http.Request) *httptest.ResponseRecorder {
+			request.AddCookie(cookie)
+			response := httptest.NewRecorder()
+			r.ServeHTTP(response, request)
+			return response
+		}
+	}
+	owner, admin, member, reader, outsider := servers[emails[0]], servers[emails[1]], servers[emails[2]], servers[emails[3]], servers[emails[4]]
+	send := func(serve func(*http.Request) *httptest.ResponseRecorder, method, target string, v any) *httptest.ResponseRecorder {
+		var body []byte
+		if v != nil {
+			var err error
+			if body, err = json.Marshal(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return serve(httptest.NewRequest(method, target, bytes.NewReader(body)))
+	}
+	get := func(serve func(*http.Request) *httptest.ResponseRecorder, target string) *httptest.ResponseRecorder {
+		return send(serve, http.MethodGet, target, nil)
+	}
+	put := func(serve func(*http.Request) *httptest.ResponseRecorder, target string, v any) *httptest.ResponseRecorder {
+		return send(serve, http.MethodPut, target, v)
+	}
+	decode := func(response *httptest.ResponseRecorder, v any) {
+		if response.Code != http.StatusOK {
+			t.Fatalf("Request failed with %d: %s", response.Code, response.Body)
+		}
+		if err := json.Unmarshal(response.Body.Bytes(), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Owner creates the organization and a collection
+	if response := put(owner, "/user/orgs", models.Membership{Organization: models.Organization{Name: "team"}}); response.Code != http.StatusBadRequest {
+		t.Errorf("Expected organization without key rejected, got %d", response.Code)
+	}
+	if response := put(owner, "/user/orgs", models.Membership{Organization: models.Organization{Name: "team"},
+		Member: models.Member{Key: []byte("short")}}); response.Code != http.StatusBadRequest {
+		t.Errorf("Expected organization with key too short to be sealed rejected, got %d", response.Code)
+	}
+	var created models.Membership
+	decode(put(owner, "/user/orgs", models.Membership{
+		Organization: models.Organization{Name: "team"},
+		Member:       models.Member{Key: sealedKey("sealed to owner")},
+	}), &created)
+	if created.Organization.ID == "" || created.Organization.KeyVersion != 1 || created.Member.Role != models.RoleOwner ||
+		!created.Member.Accepted {
+		t.Fatalf("Unexpected created organization: %+v", created)
+	}
+	org := "/user/orgs/" + created.Organization.ID
+	var collection models.Collection
+	decode(put(owner, org+"/collections", models.Collection{Name: "servers"}), &collection)
+
+	// Owner invites members, only owner invites admins
+	invite := func(serve func(*http.Request) *httptest.ResponseRecorder, email string, role models.Role) int {
+		return put(serve, org+"/members", models.Member{UserID: email, Role: role, Key: sealedKey("sealed"), KeyVersion: 1}).Code
+	}
+	for email, role := range map[string]models.Role{emails[1]: models.RoleAdmin, emails[2]: models.RoleMember} {
+		if code := invite(owner, email, role); code != http.StatusOK {
+			t.Fatalf("Invite of %s failed with %d", email, code)
+		}
+	}
+	if code := put(owner, org+"/members", models.Member{UserID: emails[3], Role: models.RoleReadOnly, Key: sealedKey("sealed")}).Code; code != http.StatusConflict {
+		t.Errorf("Expected invite with old key version rejected, got %d", code)
+	}
+	// Invited user has no access until it accepts
+	if code := get(admin, org+"/data").Code; code != http.StatusForbidden {
+		t.Errorf("Expected 403 before accept, got %d", code)
+	}
+	for _, serve := range []func(*http.Request) *httptest.ResponseRecorder{admin, member} {
+		if response := send(serve, http.MethodPost, org+"/accept", nil); response.Code != http.StatusOK {
+			t.Fatalf("Accept failed with %d: %s", response.Code, response.Body)
+		}
+	}
+	if code := invite(admin, emails[4], models.RoleAdmin); code != http.StatusForbidden {
+		t.Errorf("Expected admin can't invite admins, got %d", code)
+	}
+	if code := invite(member, emails[4], models.RoleMember); code != http.StatusForbidden {
+		t.Errorf("Expected member can't invite, got %d", code)
+	}
+	if code := invite(admin, emails[3], models.RoleReadOnly); code != http.StatusOK {
+		t.Fatalf("Admin failed to invite reader: %d", code)
+	}
+	send(reader, http.MethodPost, org+"/accept", nil)
+	var members []models.Member
+	decode(get(reader, org+"/members"), &members)
+	if len(members) != 4 {
+		t.Errorf("Expected 4 members, got %+v", members)
+	}
+	for _, m := range members {
+		if m.Key != nil {
+			t.Errorf("Key of %s is sent to other members", m.UserID)
+		}
+	}
+
+	// Members write items, readers and outsiders don't
+	item := models.DataWrapper{ID: "org-item", Type: models.LoginType, Name: "db", Data: []byte("encrypted"), CollectionID: collection.ID}
+	write := func(serve func(*http.Request) *httptest.ResponseRecorder, version int64, item models.DataWrapper) *httptest.ResponseRecorder {
+		return put(serve, org+"/data", models.OrgData{KeyVersion: version, Data: []models.DataWrapper{item}})
+	}
+	var results []models.SyncResult
+	decode(write(member, 1, item), &results)
+	if len(results) != 1 || results[0].Status != models.StatusApplied {
+		t.Fatalf("Unexpected results of org write: %+v", results)
+	}
+	if code := write(reader, 1, item).Code; code != http.StatusForbidden {
+		t.Errorf("Expected reader can't write, got %d", code)
+	}
+	if code := write(outsider, 1, item).Code; code != http.StatusForbidden {
+		t.Errorf("Expected outsider can't write, got %d", code)
+	}
+	if code := get(outsider, org+"/data").Code; code != http.StatusForbidden {
+		t.Errorf("Expected outsider can't read, got %d", code)
+	}
+	if code := write(member, 1, models.DataWrapper{ID: "other", CollectionID: "missing"}).Code; code != http.StatusNotFound {
+		t.Errorf("Expected item without collection rejected, got %d", code)
+	}
+	if code := write(member, 1, models.DataWrapper{ID: "file", CollectionID: collection.ID, Blobs: []string{"blob"}}).Code; code != http.StatusBadRequest {
+		t.Errorf("Expected item with files rejected, got %d", code)
+	}
+	var data models.OrgData
+	decode(get(reader, org+"/data"), &data)
+	if data.KeyVersion != 1 || len(data.Data) != 1 || data.Data[0].OwnerID != created.Organization.ID {
+		t.Fatalf("Unexpected org data: %+v", data)
+	}
+	// Org items are not synced as items of the user
+	if stored, _ := s.GetData(context.Background(), emails[2]); len(stored) != 0 {
+		t.Errorf("Org item is stored as item of the member: %+v", stored)
+	}
+
+	// Admin can't remove admins or owner, owner removes the admin and rotates the key
+	if code := send(admin, http.MethodDelete, org+"/members/"+emails[0], nil).Code; code != http.StatusForbidden {
+		t.Errorf("Expected owner can't be removed, got %d", code)
+	}
+	if code := send(member, http.MethodDelete, org+"/members/"+emails[3], nil).Code; code != http.StatusForbidden {
+		t.Errorf("Expected member can't remove others, got %d", code)
+	}
+	if code := send(owner, http.MethodDelete, org+"/members/"+emails[1], nil).Code; code != http.StatusOK {
+		t.Fatalf("Owner failed to remove admin: %d", code)
+	}
+	if code := get(admin, org+"/data").Code; code != http.StatusForbidden {
+		t.Errorf("Expected removed admin can't read, got %d", code)
+	}
+	rotated := data.Data[0]
+	rotated.Data = []byte("encrypted with new key")
+	rotation := models.KeyRotation{KeyVersion: 2, Data: []models.DataWrapper{rotated}}
+	for _, email := range []string{emails[0], emails[2]} {
+		rotation.Members = append(rotation.Members, models.Member{UserID: email, Key: sealedKey("new sealed")})
+	}
+	if code := put(owner, org+"/key", rotation).Code; code != http.StatusBadRequest {
+		t.Errorf("Expected rotation without every member rejected, got %d", code)
+	}
+	rotation.Members = append(rotation.Members, models.Member{UserID: emails[3], Key: sealedKey("new sealed")})
+	stale := rotation
+	stale.Data = []models.DataWrapper{item}
+	if code := put(owner, org+"/key", stale).Code; code != http.StatusConflict {
+		t.Errorf("Expected rotation of old items rejected, got %d", code)
+	}
+	decode(put(owner, org+"/key", rotation), &results)
+	if len(results) != 1 || results[0].Status != models.StatusApplied {
+		t.Fatalf("Unexpected results of rotation: %+v", results)
+	}
+	if code := write(member, 1, item).Code; code != http.StatusConflict {
+		t.Errorf("Expected write with old key rejected, got %d", code)
+	}
+	var memberships []models.Membership
+	decode(get(member, "/user/orgs"), &memberships)
+	if len(memberships) != 1 || memberships[0].Organization.KeyVersion != 2 || memberships[0].Member.KeyVersion != 2 ||
+		!bytes.Equal(memberships[0].Member.Key, sealedKey("new sealed")) || memberships[0].Member.Role != models.RoleMember {
+		t.Errorf("Unexpected membership after rotation: %+v", memberships)
+	}
+}
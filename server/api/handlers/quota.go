@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+)
+
+// Limits are per-user quotas and payload limits
+// zero value means there is no limit
+type Limits struct {
+	// MaxItems is the maximum number of items a user can store
+	MaxItems int64
+	// MaxBytes is the maximum total size of items and blobs a user can store
+	MaxBytes int64
+	// MaxItemSize is the maximum size of one item, see models.ItemSize
+	MaxItemSize int64
+	// MaxBodySize is the maximum size of sync request body
+	MaxBodySize int64
+	// MaxBlobSize is the maximum size of one file attached to an item, blobs count in MaxBytes too
+	MaxBlobSize int64
+}
+
+// usage returns stored usage with the limits
+func (l Limits) usage(stored models.Usage) models.Usage {
+	stored.MaxItems = l.MaxItems
+	stored.MaxBytes = l.MaxBytes
+	stored.MaxItemSize = l.MaxItemSize
+	return stored
+}
+
+// project returns usage as it would be after applying the data from client
+// stored has the current versions of the items client sent, items that are not stored yet are missing from it
+// it follows storage rules: only edit of the current revision replaces stored data
+func (l Limits) project(usage models.Usage, stored, fromClient []models.DataWrapper) models.Usage {
+	current := make(map[string]models.DataWrapper, len(stored))
+	for _, data := range stored {
+		current[data.ID] = data
+	}
+	for _, data := range fromClient {
+		previous, ok := current[data.ID]
+		if ok && previous.Revision != data.Revision {
+			continue
+		}
+		if ok && previous.DeletedAt == 0 {
+			usage.Items--
+			usage.Bytes -= models.ItemSize(previous)
+		}
+		if data.DeletedAt == 0 {
+			usage.Items++
+			usage.Bytes += models.ItemSize(data)
+		}
+		data.Revision++
+		current[data.ID] = data
+	}
+	return usage
+}
+
+// checkItem checks if the data from client fits per-item limit
+func (l Limits) checkItem(data models.DataWrapper) error {
+	if size := models.ItemSize(data); l.MaxItemSize > 0 && size > l.MaxItemSize {
+		return fmt.Errorf("%w: item %s has %d bytes, limit is %d bytes",
+			ErrItemTooLarge, data.ID, size, l.MaxItemSize)
+	}
+	return nil
+}
+
+// checkQuota checks if projected usage fits quotas
+// sync that doesn't make usage bigger is always allowed, so users over quota still can delete items
+func (l Limits) checkQuota(current, projected models.Usage) error {
+	if l.MaxItems > 0 && projected.Items > l.MaxItems && projected.Items > current.Items {
+		return fmt.Errorf("%w: sync would store %d items, limit is %d items",
+			ErrQuotaExceeded, projected.Items, l.MaxItems)
+	}
+	if l.MaxBytes > 0 && projected.Bytes > l.MaxBytes && projected.Bytes > current.Bytes {
+		return fmt.Errorf("%w: sync would store %d bytes, limit is %d bytes",
+			ErrQuotaExceeded, projected.Bytes, l.MaxBytes)
+	}
+	return nil
+}
+
+// setUsageHeaders reports usage in response headers
+func setUsageHeaders(w http.ResponseWriter, usage models.Usage) {
+	w.Header().Set(models.UsageItemsHeader, strconv.FormatInt(usage.Items, 10))
+	w.Header().Set(models.UsageBytesHeader, strconv.FormatInt(usage.Bytes, 10))
+	w.Header().Set(models.UsageMaxItemsHeader, strconv.FormatInt(usage.MaxItems, 10))
+	w.Header().Set(models.UsageMaxBytesHeader, strconv.FormatInt(usage.MaxBytes, 10))
+	w.Header().Set(models.UsageMaxItemSizeHeader, strconv.FormatInt(usage.MaxItemSize, 10))
+}
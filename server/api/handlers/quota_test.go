@@ -0,0 +1,145 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	auth "github.com/gynshu-one/goph-keeper/server/api/auth"
+	"github.com/gynshu-one/goph-keeper/server/api/handlers"
+	"github.com/gynshu-one/goph-keeper/server/storage"
+)
+
+// syncItems sends the items to SyncUserData of the handler on behalf of the session
+func syncItems(t *testing.T, hndlr handlers.Handlers, session *auth.Session, items []models.DataWrapper) *httptest.ResponseRecorder {
+	t.Helper()
+	return syncSince(t, hndlr, session, "", items)
+}
+
+// syncSince sends the items with the cursor of the last sync to SyncUserData of the handler
+func syncSince(t *testing.T, hndlr handlers.Handlers, session *auth.Session, cursor string, items []models.DataWrapper) *httptest.ResponseRecorder {
+	t.Helper()
+	requestData, err := json.Marshal(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := "/user/sync"
+	if cursor != "" {
+		target += "?" + models.CursorParam + "=" + cursor
+	}
+	request := httptest.NewRequest(http.MethodPost, target, bytes.NewReader(requestData))
+	request.AddCookie(&http.Cookie{Name: "session_id", Value: session.ID})
+	response := httptest.NewRecorder()
+	hndlr.SyncUserData(response, request)
+	return response
+}
+
+func TestSyncLimits(t *testing.T) {
+	userID := "quotaUserID"
+	session, _ := auth.Sessions.CreateSession(userID)
+	item := func(id string, size int, updatedAt int64) models.DataWrapper {
+		return models.DataWrapper{
+			ID:        id,
+			OwnerID:   userID,
+			Data:      bytes.Repeat([]byte("a"), size),
+			UpdatedAt: updatedAt,
+		}
+	}
+
+	t.Run("item too large", func(t *testing.T) {
+		hndlr := handlers.NewHandlers(storage.NewMemoryStorage(storage.Options{}), handlers.Limits{MaxItemSize: 10})
+		response := syncItems(t, hndlr, session, []models.DataWrapper{item("1", 11, 1)})
+		if response.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected status code %d, got %d", http.StatusRequestEntityTooLarge, response.Code)
+		}
+		if !strings.Contains(response.Body.String(), handlers.ErrItemTooLarge.Error()) {
+			t.Errorf("Expected error about item size, got %s", response.Body.String())
+		}
+
+		// Name and key count in the size too
+		named := item("2", 6, 1)
+		named.Name, named.Key = "abc", []byte("key")
+		response = syncItems(t, hndlr, session, []models.DataWrapper{named})
+		if response.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected status code %d, got %d", http.StatusRequestEntityTooLarge, response.Code)
+		}
+	})
+
+	t.Run("body too large", func(t *testing.T) {
+		hndlr := handlers.NewHandlers(storage.NewMemoryStorage(storage.Options{}), handlers.Limits{MaxBodySize: 50})
+		response := syncItems(t, hndlr, session, []models.DataWrapper{item("1", 100, 1)})
+		if response.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected status code %d, got %d", http.StatusRequestEntityTooLarge, response.Code)
+		}
+		if !strings.Contains(response.Body.String(), handlers.ErrBodyTooLarge.Error()) {
+			t.Errorf("Expected error about body size, got %s", response.Body.String())
+		}
+	})
+
+	t.Run("quota", func(t *testing.T) {
+		stor := storage.NewMemoryStorage(storage.Options{})
+		hndlr := handlers.NewHandlers(stor, handlers.Limits{MaxItems: 2, MaxBytes: 100})
+
+		// Fits the quota
+		response := syncItems(t, hndlr, session, []models.DataWrapper{item("1", 40, 1), item("2", 40, 1)})
+		if response.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, response.Code)
+		}
+		if got := response.Header().Get(models.UsageItemsHeader); got != "2" {
+			t.Errorf("Expected 2 items in usage header, got %s", got)
+		}
+		if got := response.Header().Get(models.UsageBytesHeader); got != "80" {
+			t.Errorf("Expected 80 bytes in usage header, got %s", got)
+		}
+
+		// Too many items
+		response = syncItems(t, hndlr, session, []models.DataWrapper{item("3", 1, 1)})
+		if response.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected status code %d, got %d", http.StatusRequestEntityTooLarge, response.Code)
+		}
+
+		// Too many bytes
+		bigger := item("2", 61, 2)
+		bigger.Revision = 1
+		response = syncItems(t, hndlr, session, []models.DataWrapper{bigger})
+		if response.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected status code %d, got %d", http.StatusRequestEntityTooLarge, response.Code)
+		}
+
+		// Deleting one item makes room for another
+		deleted := item("1", 0, 2)
+		deleted.DeletedAt, deleted.Revision = 2, 1
+		response = syncItems(t, hndlr, session, []models.DataWrapper{deleted, item("3", 1, 1)})
+		if response.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, response.Code, response.Body.String())
+		}
+
+		// Usage endpoint
+		request := httptest.NewRequest(http.MethodGet, "/user/usage", nil)
+		request.AddCookie(&http.Cookie{Name: "session_id", Value: session.ID})
+		usageResponse := httptest.NewRecorder()
+		hndlr.Usage(usageResponse, request)
+		var usage models.Usage
+		if err := json.Unmarshal(usageResponse.Body.Bytes(), &usage); err != nil {
+			t.Fatal(err)
+		}
+		if usage.Items != 2 || usage.Bytes != 41 || usage.MaxItems != 2 || usage.MaxBytes != 100 {
+			t.Errorf("Unexpected usage %+v", usage)
+		}
+
+		// Renaming an item counts its name
+		renamed := item("3", 1, 2)
+		renamed.Name, renamed.Revision = "renamed", 1
+		response = syncItems(t, hndlr, session, []models.DataWrapper{renamed})
+		if response.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, response.Code, response.Body.String())
+		}
+		if got := response.Header().Get(models.UsageBytesHeader); got != "48" {
+			t.Errorf("Expected 48 bytes in usage header, got %s", got)
+		}
+	})
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/gynshu-one/goph-keeper/server/storage"
+)
+
+const (
+	// maxSendSize is the biggest encrypted secret of a send
+	maxSendSize = 64 << 10
+	// maxSendViews is the most views a send may have
+	maxSendViews = 100
+	// maxSendLifetime is the longest time a send lives
+	maxSendLifetime = 30 * 24 * time.Hour
+)
+
+// ListSends returns sends of the user: /user/sends
+// response is []models.Send in json format without their data
+func (h *handler) ListSends(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sends, err := h.Sends(r.Context(), session.GetUserID())
+	if err != nil {
+		http.Error(w, err.Error(), sendStatus(err))
+		return
+	}
+	writeJSON(w, sends)
+}
+
+// CreateSend creates a send of the user: /user/sends
+// request is models.Send in json format with the encrypted secret, expiry and max views,
+// response is the stored models.Send without its data, its id goes to the link
+func (h *handler) CreateSend(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var send models.Send
+	if err = json.NewDecoder(io.LimitReader(r.Body, 2*maxSendSize)).Decode(&send); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	send, err = h.NewSend(r.Context(), session.GetUserID(), send)
+	if err != nil {
+		http.Error(w, err.Error(), sendStatus(err))
+		return
+	}
+	writeJSON(w, send)
+}
+
+// DeleteSend removes the send of the user before it expires: /user/sends/{id}
+func (h *handler) DeleteSend(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err = h.RemoveSend(r.Context(), session.GetUserID(), chi.URLParam(r, "id")); err != nil {
+		http.Error(w, err.Error(), sendStatus(err))
+		return
+	}
+}
+
+// ReceiveSend returns the send by the id from its link and counts the view: /send/{id}
+// it needs no session, response is models.Send in json format with the encrypted secret
+// it is POST, so link previews of messengers don't use up views, GET of the link returns ShowSend page
+func (h *handler) ReceiveSend(w http.ResponseWriter, r *http.Request) {
+	send, err := h.OpenSend(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), sendStatus(err))
+		return
+	}
+	writeJSON(w, send)
+}
+
+// ShowSend returns the page that opens the send in a browser: /send/{id}
+// the page posts to the same address and decrypts the secret with the key from the fragment of the link,
+// so the key never reaches the server; the view is counted only when the reader asks to show the secret
+func (h *handler) ShowSend(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", sendPagePolicy)
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	_, _ = io.WriteString(w, sendPage)
+}
+
+// Sends returns sends of the user without their data, it is shared by REST and gRPC APIs
+func (h *handler) Sends(ctx context.Context, userID string) ([]models.Send, error) {
+	sends, err := h.storage.GetSends(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	result := sends[:0]
+	for _, send := range sends {
+		// Expired sends wait for the job to remove them
+		if send.ViewableAt(now) {
+			send.Data = nil
+			result = append(result, send)
+		}
+	}
+	return result, nil
+}
+
+// NewSend creates the send of the user with a random id, it is shared by REST and gRPC APIs
+func (h *handler) NewSend(ctx context.Context, userID string, send models.Send) (models.Send, error) {
+	now := time.Now()
+	if len(send.Data) == 0 || len(send.Data) > maxSendSize || send.MaxViews < 1 || send.MaxViews > maxSendViews ||
+		send.ExpiresAt <= now.Unix() || send.ExpiresAt > now.Add(maxSendLifetime).Unix() {
+		return models.Send{}, ErrInvalidSend
+	}
+	send.ID, send.OwnerID, send.Views, send.CreatedAt = uuid.NewString(), userID, 0, now.Unix()
+	if err := h.storage.CreateSend(ctx, send); err != nil {
+		return models.Send{}, err
+	}
+	send.Data = nil
+	return send, nil
+}
+
+// RemoveSend removes the send of the user, it is shared by REST and gRPC APIs
+func (h *handler) RemoveSend(ctx context.Context, userID, id string) error {
+	return h.storage.DeleteSend(ctx, userID, id)
+}
+
+// OpenSend counts a view of the send and returns it with its data, it is shared by REST and gRPC APIs
+// anyone with the link opens it, so the owner is not disclosed
+func (h *handler) OpenSend(ctx context.Context, id string) (models.Send, error) {
+	send, err := h.storage.ViewSend(ctx, id, time.Now().Unix())
+	if err != nil {
+		return models.Send{}, err
+	}
+	send.OwnerID = ""
+	return send, nil
+}
+
+func sendStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidSend):
+		return http.StatusBadRequest
+	case errors.Is(err, storage.ErrSendNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// sendScript decrypts the send like utils.DecryptData: AES-GCM with SHA-256 of the key, nonce goes first
+const sendScript = `
+document.getElementById("show").addEventListener("click", async () => {
+	const out = document.getElementById("out");
+	const key = decodeURIComponent(location.hash.slice(1));
+	if (!key) {
+		out.textContent = "The link has no key.";
+		return;
+	}
+	document.getElementById("show").disabled = true;
+	try {
+		const response = await fetch(location.pathname, {method: "POST", cache: "no-store"});
+		if (!response.ok) {
+			out.textContent = response.status === 404 ? "The send has expired or was viewed already." : await response.text();
+			return;
+		}
+		const send = await response.json();
+		const data = Uint8Array.from(atob(send.data), c => c.charCodeAt(0));
+		const digest = await crypto.subtle.digest("SHA-256", new TextEncoder().encode(key));
+		const aes = await crypto.subtle.importKey("raw", digest, "AES-GCM", false, ["decrypt"]);
+		const plain = await crypto.subtle.decrypt({name: "AES-GCM", iv: data.slice(0, 12)}, aes, data.slice(12));
+		out.textContent = new TextDecoder().decode(plain);
+	} catch (e) {
+		out.textContent = "The secret can't be decrypted, check the link.";
+	}
+});
+`
+
+// sendPage is the page ShowSend returns, it has no data of the send
+const sendPage = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Goph-keeper send</title></head>
+<body>
+<p>Someone shared a secret with you. Showing it uses up one of its views.</p>
+<button id="show">Show secret</button>
+<pre id="out"></pre>
+<script>` + sendScript + `</script>
+</body>
+</html>
+`
+
+// sendPagePolicy lets the page run only its own script and post only to the server
+var sendPagePolicy = func() string {
+	sum := sha256.Sum256([]byte(sendScript))
+	return "default-src 'none'; connect-src 'self'; script-src 'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'"
+}()
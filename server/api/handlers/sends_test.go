@@ -0,0 +1,129 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	auth "github.com/gynshu-one/goph-keeper/server/api/auth"
+	"github.com/gynshu-one/goph-keeper/server/api/handlers"
+	"github.com/gynshu-one/goph-keeper/server/api/router"
+	"github.com/gynshu-one/goph-keeper/server/storage"
+)
+
+func TestSends(t *testing.T) {
+	r := router.NewRouter(handlers.NewHandlers(storage.NewMemoryStorage(storage.Options{}), handlers.Limits{}))
+	serve := func(email string) func(*http.Request) *httptest.ResponseRecorder {
+		session, _ := auth.Sessions.CreateSession(email)
+		cookie := &http.Cookie{Name: "session_id", Value: session.ID}
+		return func(request *http.Request) *httptest.ResponseRecorder {
+			request.AddCookie(cookie)
+			response := httptest.NewRecorder()
+			r.ServeHTTP(response, request)
+			return response
+		}
+	}
+	owner, other := serve("owner@example.com"), serve("other@example.com")
+	// Anyone with the link receives the send without a session
+	anyone := func(request *http.Request) *httptest.ResponseRecorder {
+		response := httptest.NewRecorder()
+		r.ServeHTTP(response, request)
+		return response
+	}
+	send := func(serve func(*http.Request) *httptest.ResponseRecorder, method, target string, v any) *httptest.ResponseRecorder {
+		var body []byte
+		if v != nil {
+			var err error
+			if body, err = json.Marshal(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return serve(httptest.NewRequest(method, target, bytes.NewReader(body)))
+	}
+	decode := func(response *httptest.ResponseRecorder, v any) {
+		if response.Code != http.StatusOK {
+			t.Fatalf("Request failed with %d: %s", response.Code, response.Body)
+		}
+		if err := json.Unmarshal(response.Body.Bytes(), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Send must have data, views and expiry in limits
+	expiresAt := time.Now().Add(time.Hour).Unix()
+	for _, invalid := range []models.Send{
+		{ExpiresAt: expiresAt, MaxViews: 1},
+		{Data: []byte("secret"), ExpiresAt: expiresAt},
+		{Data: []byte("secret"), ExpiresAt: expiresAt, MaxViews: 101},
+		{Data: []byte("secret"), ExpiresAt: time.Now().Add(-time.Minute).Unix(), MaxViews: 1},
+		{Data: []byte("secret"), ExpiresAt: time.Now().Add(31 * 24 * time.Hour).Unix(), MaxViews: 1},
+		{Data: make([]byte, 64<<10+1), ExpiresAt: expiresAt, MaxViews: 1},
+	} {
+		if code := send(owner, http.MethodPut, "/user/sends", invalid).Code; code != http.StatusBadRequest {
+			t.Errorf("Expected send with %d bytes, %d views and expiry %d rejected, got %d",
+				len(invalid.Data), invalid.MaxViews, invalid.ExpiresAt, code)
+		}
+	}
+	var created models.Send
+	decode(send(owner, http.MethodPut, "/user/sends", models.Send{ID: "chosen", OwnerID: "other@example.com",
+		Data: []byte("secret"), ExpiresAt: expiresAt, MaxViews: 2, Views: 5}), &created)
+	if created.ID == "" || created.ID == "chosen" || created.OwnerID != "owner@example.com" || created.Views != 0 ||
+		created.Data != nil {
+		t.Fatalf("Unexpected send: %+v", created)
+	}
+	if code := send(owner, http.MethodPut, "/user/sends", nil).Code; code != http.StatusBadRequest {
+		t.Errorf("Expected empty body rejected, got %d", code)
+	}
+
+	// Receiving needs no session and counts views, the owner sees them
+	var received models.Send
+	decode(send(anyone, http.MethodPost, "/send/"+created.ID, nil), &received)
+	if string(received.Data) != "secret" || received.Views != 1 || received.OwnerID != "" {
+		t.Fatalf("Unexpected received send: %+v", received)
+	}
+	var sends []models.Send
+	decode(send(owner, http.MethodGet, "/user/sends", nil), &sends)
+	if len(sends) != 1 || sends[0].ID != created.ID || sends[0].Views != 1 || sends[0].Data != nil {
+		t.Fatalf("Unexpected sends: %+v", sends)
+	}
+	// Browser gets the page that decrypts the send, the page itself doesn't use up a view
+	page := send(anyone, http.MethodGet, "/send/"+created.ID, nil)
+	if page.Code != http.StatusOK || !strings.HasPrefix(page.Header().Get("Content-Type"), "text/html") ||
+		!strings.Contains(page.Header().Get("Content-Security-Policy"), "script-src 'sha256-") ||
+		strings.Contains(page.Body.String(), base64.StdEncoding.EncodeToString([]byte("secret"))) {
+		t.Errorf("Unexpected send page %d %v", page.Code, page.Header())
+	}
+	decode(send(owner, http.MethodGet, "/user/sends", nil), &sends)
+	if len(sends) != 1 || sends[0].Views != 1 {
+		t.Fatalf("Expected page not to count a view: %+v", sends)
+	}
+
+	// The last view deletes the send
+	decode(send(anyone, http.MethodPost, "/send/"+created.ID, nil), &received)
+	if code := send(anyone, http.MethodPost, "/send/"+created.ID, nil).Code; code != http.StatusNotFound {
+		t.Errorf("Expected viewed send gone, got %d", code)
+	}
+	decode(send(owner, http.MethodGet, "/user/sends", nil), &sends)
+	if len(sends) != 0 {
+		t.Errorf("Expected no sends, got %+v", sends)
+	}
+
+	// Only the owner deletes its send
+	decode(send(owner, http.MethodPut, "/user/sends", models.Send{Data: []byte("secret"), ExpiresAt: expiresAt,
+		MaxViews: 1}), &created)
+	if code := send(other, http.MethodDelete, "/user/sends/"+created.ID, nil).Code; code != http.StatusNotFound {
+		t.Errorf("Expected foreign send not deleted, got %d", code)
+	}
+	if code := send(owner, http.MethodDelete, "/user/sends/"+created.ID, nil).Code; code != http.StatusOK {
+		t.Fatalf("Delete failed with %d", code)
+	}
+	if code := send(anyone, http.MethodPost, "/send/"+created.ID, nil).Code; code != http.StatusNotFound {
+		t.Errorf("Expected deleted send gone, got %d", code)
+	}
+}
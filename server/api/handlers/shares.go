@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/gynshu-one/goph-keeper/common/utils"
+	"github.com/gynshu-one/goph-keeper/server/storage"
+)
+
+// x25519KeySize is the size of X25519 public key
+const x25519KeySize = 32
+
+// GetKeys returns keys of the user, the private key is encrypted by client
+// response is models.UserKeys in json format, user without keys gets 404 status
+func (h *handler) GetKeys(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	keys, err := h.Keys(r.Context(), session.GetUserID())
+	if err != nil {
+		http.Error(w, err.Error(), shareStatus(err))
+		return
+	}
+	writeJSON(w, keys)
+}
+
+// SetKeys sets keys of the user, request is models.UserKeys in json format
+// keys are set once, setting them again is rejected with 409 status
+func (h *handler) SetKeys(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var keys models.UserKeys
+	if err = json.NewDecoder(io.LimitReader(r.Body, 1<<10)).Decode(&keys); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err = h.PutKeys(r.Context(), session.GetUserID(), keys); err != nil {
+		http.Error(w, err.Error(), shareStatus(err))
+		return
+	}
+}
+
+// GetPublicKey returns public key of another user, email is passed as "email" url parameter: /user/keys/{email}
+// response is models.UserKeys in json format without private key
+func (h *handler) GetPublicKey(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.PublicKey(r.Context(), chi.URLParam(r, "email"))
+	if err != nil {
+		http.Error(w, err.Error(), shareStatus(err))
+		return
+	}
+	writeJSON(w, keys)
+}
+
+// ShareItem shares the user's item with another user, request is models.Share in json format
+// with item id, user id, permission and the key of the item sealed to public key of the user
+// sharing the item again replaces the key and permission, response is the stored models.Share
+func (h *handler) ShareItem(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var share models.Share
+	if err = json.NewDecoder(io.LimitReader(r.Body, 1<<10)).Decode(&share); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	share, err = h.Share(r.Context(), session.GetUserID(), share)
+	if err != nil {
+		http.Error(w, err.Error(), shareStatus(err))
+		return
+	}
+	writeJSON(w, share)
+}
+
+// ListShares returns shares of the user's item, item id is passed as "id" url parameter: /user/shares/{id}
+// response is []models.Share in json format
+func (h *handler) ListShares(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	shares, err := h.storage.GetItemShares(r.Context(), session.GetUserID(), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), shareStatus(err))
+		return
+	}
+	writeJSON(w, shares)
+}
+
+// RevokeShare revokes the share of the user's item with another user
+// item id and user are passed as url parameters: /user/shares/{id}/{user}
+// client rotates the key of the item after that, so the user can't read new versions of it
+func (h *handler) RevokeShare(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.storage.DeleteShare(r.Context(), session.GetUserID(), chi.URLParam(r, "id"), chi.URLParam(r, "user"))
+	if err != nil {
+		http.Error(w, err.Error(), shareStatus(err))
+		return
+	}
+}
+
+// SharedItems returns items other users shared with the user
+// response is []models.SharedItem in json format
+func (h *handler) SharedItems(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	items, err := h.storage.GetSharedData(r.Context(), session.GetUserID())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, items)
+}
+
+// UpdateShared stores an edit of the item shared with the user with write permission
+// request is models.DataWrapper in json format, response is models.SyncResult like in sync
+func (h *handler) UpdateShared(w http.ResponseWriter, r *http.Request) {
+	session, err := FindSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if h.limits.MaxBodySize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, h.limits.MaxBodySize)
+	}
+	var data models.DataWrapper
+	if err = json.NewDecoder(r.Body).Decode(&data); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, ErrBodyTooLarge.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result, err := h.EditShared(r.Context(), session.GetUserID(), data)
+	if err != nil {
+		http.Error(w, err.Error(), shareStatus(err))
+		return
+	}
+	writeJSON(w, result)
+}
+
+// Keys returns keys of the user, it is shared by REST and gRPC APIs
+func (h *handler) Keys(ctx context.Context, userID string) (models.UserKeys, error) {
+	user, err := h.storage.GetUser(ctx, userID)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		return models.UserKeys{}, ErrUserNotFound
+	}
+	if err != nil {
+		return models.UserKeys{}, err
+	}
+	if user.Keys == nil {
+		return models.UserKeys{}, ErrKeysNotFound
+	}
+	return *user.Keys, nil
+}
+
+// PutKeys sets keys of the user once, it is shared by REST and gRPC APIs
+func (h *handler) PutKeys(ctx context.Context, userID string, keys models.UserKeys) error {
+	if len(keys.PublicKey) != x25519KeySize || len(keys.PrivateKey) == 0 {
+		return ErrInvalidKeys
+	}
+	return h.storage.SetUserKeys(ctx, userID, keys)
+}
+
+// PublicKey returns public key of the user with the email, it is shared by REST and gRPC APIs
+func (h *handler) PublicKey(ctx context.Context, email string) (models.UserKeys, error) {
+	keys, err := h.Keys(ctx, email)
+	return models.UserKeys{PublicKey: keys.PublicKey}, err
+}
+
+// Share shares the item of the user with another user that has keys, it is shared by REST and gRPC APIs
+// only items with their own key are shared, so the user never gets the secret of the owner
+func (h *handler) Share(ctx context.Context, userID string, share models.Share) (models.Share, error) {
+	if share.ItemID == "" || share.UserID == "" || share.UserID == userID || len(share.Key) < utils.MinSealedKeySize ||
+		!share.Permission.Valid() {
+		return models.Share{}, ErrInvalidShare
+	}
+	item, err := h.ownItem(ctx, userID, share.ItemID)
+	if err != nil {
+		return models.Share{}, err
+	}
+	if len(item.Key) == 0 {
+		return models.Share{}, ErrItemKeyMissing
+	}
+	if _, err = h.PublicKey(ctx, share.UserID); err != nil {
+		return models.Share{}, err
+	}
+	share.OwnerID = userID
+	share.CreatedAt = time.Now().Unix()
+	if err = h.storage.SetShare(ctx, share); err != nil {
+		return models.Share{}, err
+	}
+	return share, nil
+}
+
+// EditShared stores an edit of the item shared with the user, it is shared by REST and gRPC APIs
+// the edit counts to quota of the owner, the user changes only the data of the item:
+// it can't delete the item, rename or move it, or change its key and files
+// conflicting edit gets the current version of the item in the result like in sync
+func (h *handler) EditShared(ctx context.Context, userID string, data models.DataWrapper) (models.SyncResult, error) {
+	items, err := h.storage.GetSharedData(ctx, userID)
+	if err != nil {
+		return models.SyncResult{}, err
+	}
+	i := slices.IndexFunc(items, func(item models.SharedItem) bool {
+		return item.Data.ID == data.ID
+	})
+	if i < 0 {
+		return models.SyncResult{}, ErrItemNotFound
+	}
+	shared := items[i]
+	if shared.Share.Permission != models.PermissionWrite {
+		return models.SyncResult{}, ErrReadOnly
+	}
+	// Only the data is edited, the rest is the owner's
+	data.OwnerID = shared.Share.OwnerID
+	data.Name, data.Type, data.LabelVersion = shared.Data.Name, shared.Data.Type, shared.Data.LabelVersion
+	data.CollectionID, data.FolderID = shared.Data.CollectionID, shared.Data.FolderID
+	data.Key, data.Blobs, data.DeletedAt = shared.Data.Key, shared.Data.Blobs, 0
+	if err = h.limits.checkItem(data); err != nil {
+		return models.SyncResult{}, err
+	}
+
+	unlock := h.locks.lock(data.OwnerID)
+	defer unlock()
+	results := make([]models.SyncResult, 1)
+	response, _, err := h.apply(ctx, data.OwnerID, 0, true, []models.DataWrapper{data}, []int{0}, results)
+	if err != nil {
+		return models.SyncResult{}, err
+	}
+	return response.Results[0], nil
+}
+
+// ownItem returns the not deleted item of the user, ErrItemNotFound if there is no such item
+func (h *handler) ownItem(ctx context.Context, userID, id string) (models.DataWrapper, error) {
+	data, err := h.storage.GetDataByIDs(ctx, userID, []string{id})
+	if err != nil {
+		return models.DataWrapper{}, err
+	}
+	if len(data) == 0 || data[0].DeletedAt != 0 {
+		return models.DataWrapper{}, ErrItemNotFound
+	}
+	return data[0], nil
+}
+
+// blobOwner returns the owner of the blob the user can download,
+// it's the user or the owner of an item shared with the user that refers to the blob
+func (h *handler) blobOwner(ctx context.Context, userID, id string) (string, error) {
+	_, err := h.storage.GetBlob(ctx, userID, id)
+	if !errors.Is(err, storage.ErrBlobNotFound) {
+		return userID, err
+	}
+	items, sharedErr := h.storage.GetSharedData(ctx, userID)
+	if sharedErr != nil {
+		return "", sharedErr
+	}
+	for _, item := range items {
+		if slices.Contains(item.Data.Blobs, id) {
+			return item.Share.OwnerID, nil
+		}
+	}
+	return "", err
+}
+
+// shareStatus returns http status of the error of keys and shares requests
+func shareStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidKeys), errors.Is(err, ErrInvalidShare), errors.Is(err, ErrItemKeyMissing):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrReadOnly):
+		return http.StatusForbidden
+	case errors.Is(err, ErrKeysNotFound), errors.Is(err, ErrUserNotFound), errors.Is(err, ErrItemNotFound),
+		errors.Is(err, storage.ErrShareNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrKeysExist):
+		return http.StatusConflict
+	case errors.Is(err, ErrItemTooLarge), errors.Is(err, ErrQuotaExceeded):
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,193 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/gynshu-one/goph-keeper/common/utils"
+	auth "github.com/gynshu-one/goph-keeper/server/api/auth"
+	"github.com/gynshu-one/goph-keeper/server/api/handlers"
+	"github.com/gynshu-one/goph-keeper/server/api/router"
+	"github.com/gynshu-one/goph-keeper/server/storage"
+)
+
+// sealedKey returns a fake key of the size utils.SealKey makes, server only checks the size of sealed keys
+func sealedKey(text string) []byte {
+	return append([]byte(text), make([]byte, utils.MinSealedKeySize)...)
+}
+
+func TestSharing(t *testing.T) {
+	s := storage.NewMemoryStorage(storage.Options{})
+	r := router.NewRouter(handlers.NewHandlers(s, handlers.Limits{}))
+	servers := make(map[string]func(*http.Request) *httptest.ResponseRecorder)
+	for _, email := range []string{"owner@example.com", "reader@example.com", "writer@example.com", "nokeys@example.com"} {
+		if err := s.CreateUser(context.Background(), models.User{Email: email}); err != nil {
+			t.Fatal(err)
+		}
+		session, _ := auth.Sessions.CreateSession(email)
+		cookie := &http.Cookie{Name: "session_id", Value: session.ID}
+		servers[email] = func(request *http.Request) *httptest.ResponseRecorder {
+			request.AddCookie(cookie)
+			response := httptest.NewRecorder()
+			r.ServeHTTP(response, request)
+			return response
+		}
+	}
+	owner, reader, writer := servers["owner@example.com"], servers["reader@example.com"], servers["writer@example.com"]
+	send := func(serve func(*http.Request) *httptest.ResponseRecorder, method, target string, v any) *httptest.ResponseRecorder {
+		body, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return serve(httptest.NewRequest(method, target, bytes.NewReader(body)))
+	}
+	put := func(serve func(*http.Request) *httptest.ResponseRecorder, target string, v any) *httptest.ResponseRecorder {
+		return send(serve, http.MethodPut, target, v)
+	}
+
+	// Keys are set once
+	if response := owner(httptest.NewRequest(http.MethodGet, "/user/keys", nil)); response.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for user without keys, got %d", response.Code)
+	}
+	if response := put(owner, "/user/keys", models.UserKeys{PublicKey: []byte("short")}); response.Code != http.StatusBadRequest {
+		t.Errorf("Expected invalid keys rejected, got %d", response.Code)
+	}
+	for email, serve := range servers {
+		if email == "nokeys@example.com" {
+			continue
+		}
+		keys := models.UserKeys{PublicKey: bytes.Repeat([]byte{1}, 32), PrivateKey: []byte("encrypted " + email)}
+		if response := put(serve, "/user/keys", keys); response.Code != http.StatusOK {
+			t.Fatalf("Failed to set keys of %s: %d %s", email, response.Code, response.Body)
+		}
+	}
+	if response := put(owner, "/user/keys", models.UserKeys{PublicKey: bytes.Repeat([]byte{2}, 32), PrivateKey: []byte("other")}); response.Code != http.StatusConflict {
+		t.Errorf("Expected keys not replaced, got %d", response.Code)
+	}
+	response := owner(httptest.NewRequest(http.MethodGet, "/user/keys/reader@example.com", nil))
+	var public models.UserKeys
+	if err := json.Unmarshal(response.Body.Bytes(), &public); err != nil || len(public.PublicKey) != 32 || public.PrivateKey != nil {
+		t.Errorf("Expected only public key of another user, got %s", response.Body)
+	}
+
+	// Files of shared items are downloaded by users they are shared with
+	content := []byte("encrypted file")
+	sum := sha256.Sum256(content)
+	blob := models.Blob{ID: "blob1", Size: int64(len(content)), Hash: hex.EncodeToString(sum[:])}
+	if response = send(owner, http.MethodPost, "/user/blobs", blob); response.Code != http.StatusOK {
+		t.Fatalf("Create of blob failed with %d: %s", response.Code, response.Body)
+	}
+	request := httptest.NewRequest(http.MethodPatch, "/user/blobs/blob1", bytes.NewReader(content))
+	request.Header.Set(models.UploadOffsetHeader, "0")
+	if response = owner(request); response.Code != http.StatusOK {
+		t.Fatalf("Upload failed with %d: %s", response.Code, response.Body)
+	}
+
+	item := models.DataWrapper{ID: "item", OwnerID: "owner@example.com", Type: models.BinaryType, Name: "sealed name",
+		CollectionID: "collection", Data: []byte("data"), Blobs: []string{"blob1"}}
+	if status := syncItem(t, owner, item); status != models.StatusApplied {
+		t.Fatalf("Expected item applied, got %s", status)
+	}
+	share := models.Share{ItemID: "item", UserID: "reader@example.com", Key: sealedKey("sealed"), Permission: models.PermissionRead}
+	// Item encrypted with the secret of the owner is never shared
+	if response = put(owner, "/user/shares", share); response.Code != http.StatusBadRequest {
+		t.Errorf("Expected item without key not shared, got %d", response.Code)
+	}
+	item.Revision, item.Key = 1, []byte("item key")
+	if status := syncItem(t, owner, item); status != models.StatusApplied {
+		t.Fatalf("Expected item with key applied, got %s", status)
+	}
+	if response = put(owner, "/user/shares", share); response.Code != http.StatusOK {
+		t.Fatalf("Share failed with %d: %s", response.Code, response.Body)
+	}
+	share.UserID, share.Permission = "writer@example.com", models.PermissionWrite
+	if response = put(owner, "/user/shares", share); response.Code != http.StatusOK {
+		t.Fatalf("Share failed with %d: %s", response.Code, response.Body)
+	}
+	for _, invalid := range []models.Share{
+		{ItemID: "item", UserID: "nokeys@example.com", Key: sealedKey("sealed"), Permission: models.PermissionRead},
+		{ItemID: "missing", UserID: "reader@example.com", Key: sealedKey("sealed"), Permission: models.PermissionRead},
+	} {
+		if response = put(owner, "/user/shares", invalid); response.Code != http.StatusNotFound {
+			t.Errorf("Expected share %+v rejected with 404, got %d", invalid, response.Code)
+		}
+	}
+	if response = put(owner, "/user/shares", models.Share{ItemID: "item", UserID: "reader@example.com", Key: sealedKey("sealed"), Permission: "admin"}); response.Code != http.StatusBadRequest {
+		t.Errorf("Expected unknown permission rejected, got %d", response.Code)
+	}
+	if response = put(owner, "/user/shares", models.Share{ItemID: "item", UserID: "reader@example.com", Key: []byte("short"), Permission: models.PermissionRead}); response.Code != http.StatusBadRequest {
+		t.Errorf("Expected key too short to be sealed rejected, got %d", response.Code)
+	}
+	// Only the owner shares the item
+	if response = put(reader, "/user/shares", models.Share{ItemID: "item", UserID: "writer@example.com", Key: sealedKey("sealed"), Permission: models.PermissionRead}); response.Code != http.StatusNotFound {
+		t.Errorf("Expected share of foreign item rejected, got %d", response.Code)
+	}
+
+	var shares []models.Share
+	response = owner(httptest.NewRequest(http.MethodGet, "/user/shares/item", nil))
+	if err := json.Unmarshal(response.Body.Bytes(), &shares); err != nil || len(shares) != 2 {
+		t.Errorf("Expected 2 shares, got %s", response.Body)
+	}
+
+	var shared []models.SharedItem
+	response = reader(httptest.NewRequest(http.MethodGet, "/user/shared", nil))
+	if err := json.Unmarshal(response.Body.Bytes(), &shared); err != nil || len(shared) != 1 ||
+		shared[0].Data.ID != "item" || !bytes.Equal(shared[0].Share.Key, sealedKey("sealed")) || shared[0].Share.OwnerID != "owner@example.com" {
+		t.Fatalf("Unexpected shared items %s", response.Body)
+	}
+	if response = reader(httptest.NewRequest(http.MethodGet, "/user/blobs/blob1", nil)); response.Code != http.StatusOK ||
+		!bytes.Equal(response.Body.Bytes(), content) {
+		t.Errorf("Expected file of shared item downloaded, got %d", response.Code)
+	}
+	if response = servers["nokeys@example.com"](httptest.NewRequest(http.MethodGet, "/user/blobs/blob1", nil)); response.Code != http.StatusNotFound {
+		t.Errorf("Expected file of not shared item not found, got %d", response.Code)
+	}
+
+	// Reader can't edit, writer edits on behalf of the owner
+	edit := shared[0].Data
+	edit.Data, edit.Key, edit.Blobs = []byte("edited"), []byte("other key"), nil
+	edit.Name, edit.CollectionID = "renamed", "other collection"
+	if response = put(reader, "/user/shared", edit); response.Code != http.StatusForbidden {
+		t.Errorf("Expected edit of read only item forbidden, got %d", response.Code)
+	}
+	response = put(writer, "/user/shared", edit)
+	var result models.SyncResult
+	if err := json.Unmarshal(response.Body.Bytes(), &result); err != nil || result.Status != models.StatusApplied {
+		t.Fatalf("Expected edit applied, got %d %s", response.Code, response.Body)
+	}
+	stored, _ := s.GetData(context.Background(), "owner@example.com")
+	if len(stored) != 1 || string(stored[0].Data) != "edited" || string(stored[0].Key) != "item key" ||
+		len(stored[0].Blobs) != 1 || stored[0].Revision != 3 ||
+		stored[0].Name != "sealed name" || stored[0].CollectionID != "collection" {
+		t.Errorf("Expected edit with name, collection, key and files of the owner, got %+v", stored)
+	}
+	// Edit of an old revision is a conflict
+	edit.Data = []byte("edited again")
+	response = put(writer, "/user/shared", edit)
+	if err := json.Unmarshal(response.Body.Bytes(), &result); err != nil || result.Status != models.StatusConflict ||
+		result.Server == nil || result.Server.Revision != 3 {
+		t.Errorf("Expected conflict with current version, got %s", response.Body)
+	}
+
+	// Revoked user loses access
+	if response = owner(httptest.NewRequest(http.MethodDelete, "/user/shares/item/reader@example.com", nil)); response.Code != http.StatusOK {
+		t.Fatalf("Revoke failed with %d", response.Code)
+	}
+	if response = owner(httptest.NewRequest(http.MethodDelete, "/user/shares/item/reader@example.com", nil)); response.Code != http.StatusNotFound {
+		t.Errorf("Expected revoked share not found, got %d", response.Code)
+	}
+	response = reader(httptest.NewRequest(http.MethodGet, "/user/shared", nil))
+	if err := json.Unmarshal(response.Body.Bytes(), &shared); err != nil || len(shared) != 0 {
+		t.Errorf("Expected nothing shared after revoke, got %s", response.Body)
+	}
+	if response = reader(httptest.NewRequest(http.MethodGet, "/user/blobs/blob1", nil)); response.Code != http.StatusNotFound {
+		t.Errorf("Expected file not found after revoke, got %d", response.Code)
+	}
+}
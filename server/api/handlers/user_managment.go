@@ -1,11 +1,12 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"github.com/gynshu-one/goph-keeper/common/models"
 	auth "github.com/gynshu-one/goph-keeper/server/api/auth"
 	"github.com/gynshu-one/goph-keeper/server/api/utils"
-	"go.mongodb.org/mongo-driver/mongo"
+	"github.com/gynshu-one/goph-keeper/server/storage"
 	"net/http"
 	"time"
 )
@@ -20,25 +21,40 @@ import (
 //
 //	in response you will get a session_id cookie and Authorization header with session id
 func (h *handler) CreateUser(w http.ResponseWriter, r *http.Request) {
-	var user models.User
-
-	// Read and validate params
-	email := r.FormValue("email")
-	password := r.FormValue("password")
-
-	if email == "" {
-		http.Error(w, "email is empty", http.StatusBadRequest)
+	session, err := h.Register(r.Context(), r.FormValue("email"), r.FormValue("password"))
+	if err != nil {
+		http.Error(w, err.Error(), userErrorStatus(err))
 		return
 	}
-	if !utils.ValidateEmail(email) {
-		http.Error(w, "email is invalid", http.StatusBadRequest)
+	writeSession(w, session)
+}
+
+// LoginUser logs in a user
+// returns a session ID and an error if something went wrong
+// user must pass email as "email" and password as "password" url parameters (GET request)
+// in response you will get a session_id cookie and Authorization header with session id
+func (h *handler) LoginUser(w http.ResponseWriter, r *http.Request) {
+	session, err := h.Login(r.Context(), r.FormValue("email"), r.FormValue("password"))
+	if err != nil {
+		http.Error(w, err.Error(), userErrorStatus(err))
 		return
 	}
+	writeSession(w, session)
+}
+
+// Register creates a new user with hashed password and a 24-hour session for it
+// it is shared by REST and gRPC APIs
+func (h *handler) Register(ctx context.Context, email, password string) (*auth.Session, error) {
+	var user models.User
+
+	// Validate params
+	if err := validateEmail(email); err != nil {
+		return nil, err
+	}
 	user.Email = email
 
 	if password == "" {
-		http.Error(w, "password is empty", http.StatusBadRequest)
-		return
+		return nil, ErrEmptyPassword
 	}
 
 	// Hash master key (no salt and pepper for now)
@@ -51,87 +67,73 @@ func (h *handler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	user.UpdatedAt = time.Now().Unix()
 
 	// Try to create a new user
-	err := h.storage.CreateUser(r.Context(), user)
+	err := h.storage.CreateUser(ctx, user)
 	if err != nil {
-		if mongo.IsDuplicateKeyError(err) {
-			http.Error(w, "user with this email already exists", http.StatusBadRequest)
-			return
+		if errors.Is(err, storage.ErrUserExists) {
+			return nil, ErrUserExists
 		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 
 	// Don't forget to create a session for the user
-	session, err := auth.Sessions.CreateSession(user.Email)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	// Header
-	w.Header().Set("Authorization", "Bearer "+session.ID)
-
-	// Don't forget to set a cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:  "session_id",
-		Value: session.ID,
-	})
-	_, err = w.Write([]byte(session.ID))
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
+	return auth.Sessions.CreateSession(user.Email)
 }
 
-// LoginUser logs in a user
-// returns a session ID and an error if something went wrong
-// user must pass email as "email" and password as "password" url parameters (GET request)
-// in response you will get a session_id cookie and Authorization header with session id
-func (h *handler) LoginUser(w http.ResponseWriter, r *http.Request) {
-	var user models.User
-
-	// Read and validate params
-	email := r.FormValue("email")
-	if email == "" {
-		http.Error(w, "email is empty", http.StatusBadRequest)
-		return
-	}
-	if !utils.ValidateEmail(email) {
-		http.Error(w, "email is invalid", http.StatusBadRequest)
-		return
+// Login checks password of the user and creates a new session for it
+// it is shared by REST and gRPC APIs
+func (h *handler) Login(ctx context.Context, email, password string) (*auth.Session, error) {
+	if err := validateEmail(email); err != nil {
+		return nil, err
 	}
 
 	// Find user in db
-	user, err := h.storage.GetUser(r.Context(), email)
+	user, err := h.storage.GetUser(ctx, email)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			http.Error(w, "user not found", http.StatusBadRequest)
-			return
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return nil, ErrUserNotFound
 		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 
 	// If exists, check password
-	password := r.FormValue("password")
 	if password == "" {
-		http.Error(w, "master key is empty", http.StatusBadRequest)
-		return
+		return nil, ErrEmptyPassword
 	}
 	if !utils.CheckMasterKey(user.Passphrase, password) {
-		http.Error(w, "invalid master key", http.StatusBadRequest)
-		return
+		return nil, ErrWrongPassword
 	}
 
 	// Clean mem
 	password = utils.GenRandomString(len(password) + 1)
 
-	session, err := auth.Sessions.CreateSession(user.Email)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	return auth.Sessions.CreateSession(user.Email)
+}
+
+// validateEmail checks that email is not empty and valid
+func validateEmail(email string) error {
+	if email == "" {
+		return ErrEmptyEmail
+	}
+	if !utils.ValidateEmail(email) {
+		return ErrInvalidEmail
+	}
+	return nil
+}
+
+// userErrorStatus returns http status of the error of Register or Login
+func userErrorStatus(err error) int {
+	for _, userErr := range []error{
+		ErrEmptyEmail, ErrInvalidEmail, ErrEmptyPassword, ErrUserExists, ErrUserNotFound, ErrWrongPassword,
+	} {
+		if errors.Is(err, userErr) {
+			return http.StatusBadRequest
+		}
 	}
+	return http.StatusInternalServerError
+}
 
+// writeSession sends session id in Authorization header, session_id cookie and body
+func writeSession(w http.ResponseWriter, session *auth.Session) {
 	// Header
 	w.Header().Set("Authorization", "Bearer "+session.ID)
 	// Cookie
@@ -139,7 +141,7 @@ func (h *handler) LoginUser(w http.ResponseWriter, r *http.Request) {
 		Name:  "session_id",
 		Value: session.ID,
 	})
-	_, err = w.Write([]byte(session.ID))
+	_, err := w.Write([]byte(session.ID))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
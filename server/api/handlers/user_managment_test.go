@@ -16,13 +16,11 @@ import (
 )
 
 func TestCreateUser(t *testing.T) {
-	// Create a mock database.
-	mockStorage := &storage.MockStorage{
-		User: models.User{},
-	}
+	// Create an in-memory storage.
+	mockStorage := storage.NewMemoryStorage(storage.Options{})
 
-	// Create a test handlers with the mock database.
-	hand := handlers.NewHandlers(mockStorage)
+	// Create a test handlers with the in-memory storage.
+	hand := handlers.NewHandlers(mockStorage, handlers.Limits{})
 
 	// Create test data for the request.
 	formData := url.Values{
@@ -42,7 +40,7 @@ func TestCreateUser(t *testing.T) {
 		t.Errorf("Expected status code %d, got %d", http.StatusOK, response.Code)
 	}
 
-	// Check if the user was created in the mock database.
+	// Check if the user was created in the in-memory storage.
 	user, err := mockStorage.GetUser(context.Background(), formData.Get("email"))
 	if err != nil {
 		t.Error("User not created")
@@ -59,15 +57,13 @@ func TestCreateUser(t *testing.T) {
 }
 
 func TestLoginUser(t *testing.T) {
-	// Create a mock database.
-	mockStorage := &storage.MockStorage{
-		User: models.User{},
-	}
+	// Create an in-memory storage.
+	mockStorage := storage.NewMemoryStorage(storage.Options{})
 
-	// Create a test handlers with the mock database.
-	hand := handlers.NewHandlers(mockStorage)
+	// Create a test handlers with the in-memory storage.
+	hand := handlers.NewHandlers(mockStorage, handlers.Limits{})
 
-	// Create a test user and add it to the mock database.
+	// Create a test user and add it to the in-memory storage.
 	user := models.User{
 		Email:      "test@example.com",
 		Passphrase: utils.HashMasterKey("password123"),
@@ -103,15 +99,13 @@ func TestLoginUser(t *testing.T) {
 }
 
 func TestLogoutUser(t *testing.T) {
-	// Create a mock database.
-	mockStorage := &storage.MockStorage{
-		User: models.User{},
-	}
+	// Create an in-memory storage.
+	mockStorage := storage.NewMemoryStorage(storage.Options{})
 
-	// Create a test handlers with the mock database.
-	hand := handlers.NewHandlers(mockStorage)
+	// Create a test handlers with the in-memory storage.
+	hand := handlers.NewHandlers(mockStorage, handlers.Limits{})
 
-	// Create a test user and add it to the mock database.
+	// Create a test user and add it to the in-memory storage.
 	user := models.User{
 		Email:      "test@example.com",
 		Passphrase: utils.HashMasterKey("password123"),
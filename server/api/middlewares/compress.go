@@ -0,0 +1,122 @@
+package middlewares
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gynshu-one/goph-keeper/common/utils"
+	"github.com/rs/zerolog/log"
+)
+
+// Compress decompresses request bodies sent with supported Content-Encoding
+// and compresses responses with the most preferred encoding client accepts
+// supported encodings are advertised in Accept-Encoding response header,
+// so client knows it can compress requests
+func Compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Accept-Encoding", utils.AcceptEncoding)
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		// Decompress request
+		if encoding := r.Header.Get("Content-Encoding"); encoding != "" && encoding != "identity" {
+			body, err := utils.NewDecoder(encoding, r.Body)
+			if err != nil {
+				status := http.StatusBadRequest
+				if errors.Is(err, utils.ErrUnsupportedEncoding) {
+					status = http.StatusUnsupportedMediaType
+				}
+				http.Error(w, err.Error(), status)
+				return
+			}
+			r.Body = &decodedBody{ReadCloser: body, raw: r.Body}
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+			r.ContentLength = -1
+		}
+
+		// Compress response
+		encoding := utils.NegotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		defer func() {
+			if err := cw.Close(); err != nil {
+				log.Debug().Err(err).Msg("failed to compress response")
+			}
+		}()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// decodedBody closes both decoder and original body
+type decodedBody struct {
+	io.ReadCloser
+	raw io.ReadCloser
+}
+
+func (b *decodedBody) Close() error {
+	return errors.Join(b.ReadCloser.Close(), b.raw.Close())
+}
+
+// compressWriter compresses response body, encoder is created on the first write
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	encoder     io.WriteCloser
+	wroteHeader bool
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if status != http.StatusNoContent && status != http.StatusNotModified {
+		w.Header().Set("Content-Encoding", w.encoding)
+		w.Header().Del("Content-Length")
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.encoder == nil {
+		encoder, err := utils.NewEncoder(w.encoding, w.ResponseWriter)
+		if err != nil {
+			return 0, err
+		}
+		w.encoder = encoder
+	}
+	return w.encoder.Write(p)
+}
+
+// Close flushes compressed data
+func (w *compressWriter) Close() error {
+	if w.encoder == nil {
+		return nil
+	}
+	return w.encoder.Close()
+}
+
+// FlushError sends data compressed so far to client, it is called by http.ResponseController
+func (w *compressWriter) FlushError() error {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := w.encoder.(interface{ Flush() error }); ok {
+		if err := flusher.Flush(); err != nil {
+			return err
+		}
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the original writer
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
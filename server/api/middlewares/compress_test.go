@@ -0,0 +1,126 @@
+package middlewares_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/gynshu-one/goph-keeper/common/utils"
+	"github.com/gynshu-one/goph-keeper/server/api/middlewares"
+)
+
+// echo returns request body as response
+var echo = middlewares.Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	_, _ = io.Copy(w, r.Body)
+}))
+
+func encode(t testing.TB, encoding string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	encoder, err := utils.NewEncoder(encoding, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = encoder.Write(data)
+	if err = encoder.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCompress(t *testing.T) {
+	data := bytes.Repeat([]byte(`{"id":"1","data":"c29tZSBkYXRh"},`), 100)
+
+	for _, encoding := range utils.Encodings {
+		request := httptest.NewRequest(http.MethodPost, "/user/sync", bytes.NewReader(encode(t, encoding, data)))
+		request.Header.Set("Content-Encoding", encoding)
+		request.Header.Set("Accept-Encoding", encoding)
+		response := httptest.NewRecorder()
+		echo.ServeHTTP(response, request)
+
+		if got := response.Header().Get("Content-Encoding"); got != encoding {
+			t.Errorf("Expected %s response, got %q", encoding, got)
+		}
+		if got := response.Header().Get("Accept-Encoding"); got != utils.AcceptEncoding {
+			t.Errorf("Expected supported encodings advertised, got %q", got)
+		}
+		decoder, err := utils.NewDecoder(encoding, response.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(decoder)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(body, data) {
+			t.Errorf("%s body doesn't match", encoding)
+		}
+	}
+
+	// Client that doesn't accept compression gets plain response
+	request := httptest.NewRequest(http.MethodPost, "/user/sync", bytes.NewReader(data))
+	response := httptest.NewRecorder()
+	echo.ServeHTTP(response, request)
+	if response.Header().Get("Content-Encoding") != "" || !bytes.Equal(response.Body.Bytes(), data) {
+		t.Errorf("Expected plain response")
+	}
+
+	// Unknown encoding of request
+	request = httptest.NewRequest(http.MethodPost, "/user/sync", bytes.NewReader(data))
+	request.Header.Set("Content-Encoding", "br")
+	response = httptest.NewRecorder()
+	echo.ServeHTTP(response, request)
+	if response.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected status code %d, got %d", http.StatusUnsupportedMediaType, response.Code)
+	}
+}
+
+// BenchmarkSyncPayload compares size of sync payload of a realistic vault with every encoding
+// encrypted data is incompressible, compression wins back base64 overhead of json
+func BenchmarkSyncPayload(b *testing.B) {
+	rnd := rand.New(rand.NewSource(1))
+	var items []models.DataWrapper
+	add := func(n int, kind string, minSize, maxSize int) {
+		for i := 0; i < n; i++ {
+			data := make([]byte, minSize+rnd.Intn(maxSize-minSize))
+			rnd.Read(data)
+			items = append(items, models.DataWrapper{
+				ID:        fmt.Sprintf("%08x-%04x-%04x", rnd.Uint32(), rnd.Intn(1<<16), rnd.Intn(1<<16)),
+				OwnerID:   "user@example.com",
+				Type:      kind,
+				Name:      fmt.Sprintf("%s %d", kind, i),
+				UpdatedAt: 1694400000 + int64(rnd.Intn(1e7)),
+				CreatedAt: 1694400000,
+				Revision:  int64(1 + rnd.Intn(5)),
+				Seq:       int64(len(items) + 1),
+				Data:      data,
+			})
+		}
+	}
+	add(200, models.LoginType, 100, 300)
+	add(20, models.BankCardType, 150, 200)
+	add(50, models.ArbitraryTextType, 100, 2000)
+	add(6, models.BinaryType, 10000, 50000)
+	payload, err := json.Marshal(items)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, encoding := range append([]string{"identity"}, utils.Encodings...) {
+		b.Run(encoding, func(b *testing.B) {
+			size := len(payload)
+			for i := 0; i < b.N; i++ {
+				if encoding != "identity" {
+					size = len(encode(b, encoding, payload))
+				}
+			}
+			b.ReportMetric(float64(size), "payload-bytes")
+		})
+	}
+}
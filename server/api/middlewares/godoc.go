@@ -1,2 +1,3 @@
-// Package middlewares for now contains only one middleware for session management
+// Package middlewares contains middleware for session management
+// and for compression of requests and responses
 package middlewares
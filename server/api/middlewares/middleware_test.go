@@ -1,7 +1,6 @@
 package middlewares_test
 
 import (
-	"github.com/gynshu-one/goph-keeper/common/models"
 	auth "github.com/gynshu-one/goph-keeper/server/api/auth"
 	"github.com/gynshu-one/goph-keeper/server/api/handlers"
 	"github.com/gynshu-one/goph-keeper/server/api/router"
@@ -12,13 +11,11 @@ import (
 )
 
 func TestSessionCheck(t *testing.T) {
-	//// Create a mock database.
-	stor := &storage.MockStorage{
-		User: models.User{},
-	}
+	//// Create an in-memory storage.
+	stor := storage.NewMemoryStorage(storage.Options{})
 
-	// Create a test handler with the mock database.
-	hndlr := handlers.NewHandlers(stor)
+	// Create a test handler with the in-memory storage.
+	hndlr := handlers.NewHandlers(stor, handlers.Limits{})
 
 	// Create a new router using the NewRouter function
 	r := router.NewRouter(hndlr)
@@ -15,6 +15,35 @@ import (
 // /user/login
 // /user/logout
 // /user/sync
+// /user/history/{id}
+// /user/history/{id}/{revision}
+// /user/usage
+// /user/events
+// /user/changes
+// /user/blobs
+// /user/blobs/{id}
+// /user/keys
+// /user/keys/{email}
+// /user/shares
+// /user/shares/{id}
+// /user/shares/{id}/{user}
+// /user/shared
+// /user/orgs
+// /user/orgs/{org}/members
+// /user/orgs/{org}/members/{user}
+// /user/orgs/{org}/accept
+// /user/orgs/{org}/collections
+// /user/orgs/{org}/data
+// /user/orgs/{org}/key
+// /user/emergency/contacts
+// /user/emergency/contacts/{user}
+// /user/emergency/contacts/{user}/reject
+// /user/emergency/grantors
+// /user/emergency/grantors/{user}/request
+// /user/emergency/grantors/{user}/vault
+// /user/sends
+// /user/sends/{id}
+// /send/{id}
 func NewRouter(handlers handlers.Handlers) *chi.Mux {
 	// New Chi router
 	r := chi.NewRouter()
@@ -25,14 +54,63 @@ func NewRouter(handlers handlers.Handlers) *chi.Mux {
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
-	r.Use(middleware.Timeout(60 * time.Second))
-
 	r.Route("/user", func(r chi.Router) {
-		r.With().Get("/create", handlers.CreateUser)
-		r.With().Get("/login", handlers.LoginUser)
-		r.With(middlewares.SessionCheck).Get("/logout", handlers.LogoutUser)
-		r.With(middlewares.SessionCheck).Post("/sync", handlers.SyncUserData)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.Timeout(60 * time.Second))
+			r.Use(middlewares.Compress)
+
+			r.With().Get("/create", handlers.CreateUser)
+			r.With().Get("/login", handlers.LoginUser)
+			r.With(middlewares.SessionCheck).Get("/logout", handlers.LogoutUser)
+			r.With(middlewares.SessionCheck).Post("/sync", handlers.SyncUserData)
+			r.With(middlewares.SessionCheck).Get("/history/{id}", handlers.ListRevisions)
+			r.With(middlewares.SessionCheck).Get("/history/{id}/{revision}", handlers.GetRevision)
+			r.With(middlewares.SessionCheck).Get("/usage", handlers.Usage)
+			// Blob chunks are encrypted by client, so client sends them uncompressed
+			r.With(middlewares.SessionCheck).Post("/blobs", handlers.CreateBlob)
+			r.With(middlewares.SessionCheck).Patch("/blobs/{id}", handlers.UploadBlob)
+			r.With(middlewares.SessionCheck).Get("/keys", handlers.GetKeys)
+			r.With(middlewares.SessionCheck).Put("/keys", handlers.SetKeys)
+			r.With(middlewares.SessionCheck).Get("/keys/{email}", handlers.GetPublicKey)
+			r.With(middlewares.SessionCheck).Put("/shares", handlers.ShareItem)
+			r.With(middlewares.SessionCheck).Get("/shares/{id}", handlers.ListShares)
+			r.With(middlewares.SessionCheck).Delete("/shares/{id}/{user}", handlers.RevokeShare)
+			r.With(middlewares.SessionCheck).Get("/shared", handlers.SharedItems)
+			r.With(middlewares.SessionCheck).Put("/shared", handlers.UpdateShared)
+			r.With(middlewares.SessionCheck).Get("/orgs", handlers.ListOrgs)
+			r.With(middlewares.SessionCheck).Put("/orgs", handlers.CreateOrg)
+			r.With(middlewares.SessionCheck).Get("/orgs/{org}/members", handlers.ListMembers)
+			r.With(middlewares.SessionCheck).Put("/orgs/{org}/members", handlers.SetMember)
+			r.With(middlewares.SessionCheck).Delete("/orgs/{org}/members/{user}", handlers.DeleteMember)
+			r.With(middlewares.SessionCheck).Post("/orgs/{org}/accept", handlers.AcceptInvite)
+			r.With(middlewares.SessionCheck).Get("/orgs/{org}/collections", handlers.ListCollections)
+			r.With(middlewares.SessionCheck).Put("/orgs/{org}/collections", handlers.SetCollection)
+			r.With(middlewares.SessionCheck).Get("/orgs/{org}/data", handlers.GetOrgData)
+			r.With(middlewares.SessionCheck).Put("/orgs/{org}/data", handlers.SetOrgData)
+			r.With(middlewares.SessionCheck).Put("/orgs/{org}/key", handlers.RotateOrgKey)
+			r.With(middlewares.SessionCheck).Get("/emergency/contacts", handlers.ListEmergencyContacts)
+			r.With(middlewares.SessionCheck).Put("/emergency/contacts", handlers.SetEmergencyContact)
+			r.With(middlewares.SessionCheck).Delete("/emergency/contacts/{user}", handlers.DeleteEmergencyContact)
+			r.With(middlewares.SessionCheck).Post("/emergency/contacts/{user}/reject", handlers.RejectEmergencyAccess)
+			r.With(middlewares.SessionCheck).Get("/emergency/grantors", handlers.ListEmergencyGrantors)
+			r.With(middlewares.SessionCheck).Post("/emergency/grantors/{user}/request", handlers.RequestEmergencyAccess)
+			r.With(middlewares.SessionCheck).Get("/emergency/grantors/{user}/vault", handlers.GetEmergencyVault)
+			r.With(middlewares.SessionCheck).Get("/sends", handlers.ListSends)
+			r.With(middlewares.SessionCheck).Put("/sends", handlers.CreateSend)
+			r.With(middlewares.SessionCheck).Delete("/sends/{id}", handlers.DeleteSend)
+		})
+		// Download of a big file may take long, so it has no timeout
+		r.With(middlewares.SessionCheck).Get("/blobs/{id}", handlers.DownloadBlob)
+		// Download of changes may take long for big vaults, so it has no timeout
+		r.With(middlewares.SessionCheck, middlewares.Compress).Get("/changes", handlers.Changes)
+		// Event stream is open as long as client is running, so it has no timeout
+		// and is not compressed, its events are tiny and must be flushed right away
+		r.With(middlewares.SessionCheck).Get("/events", handlers.Events)
 	})
+	// Sends are opened by links without an account, so they need no session
+	r.With(middleware.Timeout(60*time.Second)).Post("/send/{id}", handlers.ReceiveSend)
+	// Link opened in a browser gets the page that posts to it and decrypts the secret
+	r.Get("/send/{id}", handlers.ShowSend)
 
 	return r
 }
@@ -1,7 +1,6 @@
 package router
 
 import (
-	"github.com/gynshu-one/goph-keeper/common/models"
 	auth "github.com/gynshu-one/goph-keeper/server/api/auth"
 	"github.com/gynshu-one/goph-keeper/server/api/handlers"
 	"github.com/gynshu-one/goph-keeper/server/storage"
@@ -11,13 +10,11 @@ import (
 )
 
 func TestNewRouter(t *testing.T) {
-	// Create a mock storage implementation.
-	mock := &storage.MockStorage{
-		Data: make(map[string][]models.DataWrapper),
-	}
+	// Create an in-memory storage implementation.
+	mock := storage.NewMemoryStorage(storage.Options{})
 
-	// Create a test handler with the mock storage.
-	hndlr := handlers.NewHandlers(mock)
+	// Create a test handler with the in-memory storage.
+	hndlr := handlers.NewHandlers(mock, handlers.Limits{})
 
 	// Create a new router.
 	r := NewRouter(hndlr)
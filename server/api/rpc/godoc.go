@@ -0,0 +1,3 @@
+// Package rpc implements gRPC API of the server defined in common/pb
+// It serves the same handlers as REST API and authenticates requests with the same sessions
+package rpc
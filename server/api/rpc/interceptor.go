@@ -0,0 +1,84 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gynshu-one/goph-keeper/common/pb"
+	auth "github.com/gynshu-one/goph-keeper/server/api/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// SessionMetadata is the metadata key of session id
+// "authorization" metadata with "Bearer <session id>" is accepted too
+const SessionMetadata = "session_id"
+
+// public are the methods that don't require a session
+var public = map[string]bool{
+	pb.Keeper_Register_FullMethodName:    true,
+	pb.Keeper_Login_FullMethodName:       true,
+	pb.Keeper_ReceiveSend_FullMethodName: true,
+}
+
+type sessionKey struct{}
+
+// SessionFromContext returns the session of the request checked by interceptors
+func SessionFromContext(ctx context.Context) (*auth.Session, bool) {
+	session, ok := ctx.Value(sessionKey{}).(*auth.Session)
+	return session, ok
+}
+
+// UnaryAuth checks the session of every unary call except public ones
+// and puts it to the context of the call
+func UnaryAuth(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if public[info.FullMethod] {
+		return handler(ctx, req)
+	}
+	ctx, err := authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// StreamAuth checks the session of every streaming call and puts it to the context of the stream
+func StreamAuth(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticate finds the session by metadata of the call
+func authenticate(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var sessionID string
+	if values := md.Get(SessionMetadata); len(values) > 0 {
+		sessionID = values[0]
+	} else if values = md.Get("authorization"); len(values) > 0 {
+		sessionID = strings.TrimPrefix(values[0], "Bearer ")
+	}
+	if sessionID == "" {
+		return nil, status.Error(codes.Unauthenticated, "session id is empty")
+	}
+
+	session, err := auth.Sessions.GetSession(sessionID)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return context.WithValue(ctx, sessionKey{}, session), nil
+}
+
+// authStream is a server stream with authenticated context
+type authStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,541 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/gynshu-one/goph-keeper/common/pb"
+	auth "github.com/gynshu-one/goph-keeper/server/api/auth"
+	"github.com/gynshu-one/goph-keeper/server/api/handlers"
+	"github.com/gynshu-one/goph-keeper/server/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Service is the part of handlers shared by REST and gRPC APIs
+type Service interface {
+	Register(ctx context.Context, email, password string) (*auth.Session, error)
+	Login(ctx context.Context, email, password string) (*auth.Session, error)
+	Sync(ctx context.Context, userID string, cursor int64, pushOnly bool, key string, data []models.DataWrapper) (models.SyncResponse, models.Usage, error)
+	StreamChanges(ctx context.Context, userID string, cursor int64, resume bool, emit func(line models.ChangeLine) error) error
+	Subscribe(userID string) (<-chan models.ChangeEvent, func())
+	NewBlob(ctx context.Context, userID string, blob models.Blob) (models.Blob, error)
+	WriteBlob(ctx context.Context, userID, id string, offset int64, chunk []byte) (models.Blob, error)
+	ReadBlob(ctx context.Context, userID, id string, offset int64) ([]byte, models.Blob, error)
+	Keys(ctx context.Context, userID string) (models.UserKeys, error)
+	PutKeys(ctx context.Context, userID string, keys models.UserKeys) error
+	PublicKey(ctx context.Context, email string) (models.UserKeys, error)
+	Share(ctx context.Context, userID string, share models.Share) (models.Share, error)
+	EditShared(ctx context.Context, userID string, data models.DataWrapper) (models.SyncResult, error)
+	NewOrg(ctx context.Context, userID string, membership models.Membership) (models.Membership, error)
+	Orgs(ctx context.Context, userID string) ([]models.Membership, error)
+	Members(ctx context.Context, userID, orgID string) ([]models.Member, error)
+	Invite(ctx context.Context, userID string, member models.Member) (models.Member, error)
+	RemoveMember(ctx context.Context, userID, orgID, memberID string) error
+	Accept(ctx context.Context, userID, orgID string) (models.Member, error)
+	Collections(ctx context.Context, userID, orgID string) ([]models.Collection, error)
+	PutCollection(ctx context.Context, userID string, collection models.Collection) (models.Collection, error)
+	OrgData(ctx context.Context, userID, orgID string) (models.OrgData, error)
+	WriteOrgData(ctx context.Context, userID string, data models.OrgData) ([]models.SyncResult, error)
+	RotateKey(ctx context.Context, userID string, rotation models.KeyRotation) ([]models.SyncResult, error)
+	EmergencyContacts(ctx context.Context, userID string) ([]models.EmergencyAccess, error)
+	TrustContact(ctx context.Context, userID string, access models.EmergencyAccess) (models.EmergencyAccess, error)
+	RemoveContact(ctx context.Context, userID, granteeID string) error
+	RejectAccess(ctx context.Context, userID, granteeID string) (models.EmergencyAccess, error)
+	EmergencyGrantors(ctx context.Context, userID string) ([]models.EmergencyAccess, error)
+	RequestAccess(ctx context.Context, userID, grantorID string) (models.EmergencyAccess, error)
+	EmergencyVault(ctx context.Context, userID, grantorID string) (models.EmergencyVault, error)
+	Sends(ctx context.Context, userID string) ([]models.Send, error)
+	NewSend(ctx context.Context, userID string, send models.Send) (models.Send, error)
+	RemoveSend(ctx context.Context, userID, id string) error
+	OpenSend(ctx context.Context, id string) (models.Send, error)
+}
+
+type server struct {
+	pb.UnimplementedKeeperServer
+	service Service
+	storage storage.Storage
+}
+
+// NewServer returns gRPC server with Keeper service and auth interceptors
+// history is read from the storage directly
+func NewServer(service Service, storage storage.Storage, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(UnaryAuth),
+		grpc.ChainStreamInterceptor(StreamAuth),
+	)
+	s := grpc.NewServer(opts...)
+	pb.RegisterKeeperServer(s, &server{service: service, storage: storage})
+	return s
+}
+
+// Register creates a new user and a session for it
+func (s *server) Register(ctx context.Context, in *pb.Credentials) (*pb.Session, error) {
+	session, err := s.service.Register(ctx, in.GetEmail(), in.GetPassword())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.Session{Id: session.ID}, nil
+}
+
+// Login creates a new session for the user
+func (s *server) Login(ctx context.Context, in *pb.Credentials) (*pb.Session, error) {
+	session, err := s.service.Login(ctx, in.GetEmail(), in.GetPassword())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.Session{Id: session.ID}, nil
+}
+
+// Logout ends the session of the request or all sessions of the user
+func (s *server) Logout(ctx context.Context, in *pb.LogoutRequest) (*pb.LogoutResponse, error) {
+	session, _ := SessionFromContext(ctx)
+	var err error
+	if in.GetAll() {
+		err = auth.Sessions.DeleteAllSessions(session.GetUserID())
+	} else {
+		err = auth.Sessions.DeleteSession(session.ID)
+	}
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.LogoutResponse{}, nil
+}
+
+// Sync stores the items changed by client and returns the data changed since the cursor
+func (s *server) Sync(ctx context.Context, in *pb.SyncRequest) (*pb.SyncResponse, error) {
+	session, _ := SessionFromContext(ctx)
+	response, usage, err := s.service.Sync(ctx, session.GetUserID(), in.GetCursor(), in.GetPushOnly(), in.GetIdempotencyKey(),
+		pb.DataList(in.GetData()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return pb.FromSyncResponse(response, usage), nil
+}
+
+// Changes streams the data changed since the cursor with checkpoints to resume from
+func (s *server) Changes(in *pb.ChangesRequest, stream pb.Keeper_ChangesServer) error {
+	session, _ := SessionFromContext(stream.Context())
+	err := s.service.StreamChanges(stream.Context(), session.GetUserID(), in.GetCursor(), in.GetResume(),
+		func(line models.ChangeLine) error {
+			return stream.Send(pb.FromChangeLine(line))
+		})
+	if err != nil {
+		return toStatus(err)
+	}
+	return nil
+}
+
+// CreateBlob starts upload of a file or returns the blob with the offset its upload continues from
+func (s *server) CreateBlob(ctx context.Context, in *pb.Blob) (*pb.Blob, error) {
+	session, _ := SessionFromContext(ctx)
+	blob, err := s.service.NewBlob(ctx, session.GetUserID(), in.Model())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return pb.FromBlob(blob), nil
+}
+
+// UploadBlob writes a chunk of the blob at the offset
+// chunk that is not at the end of uploaded content fails with FailedPrecondition,
+// client creates the blob again to learn where to continue from
+func (s *server) UploadBlob(ctx context.Context, in *pb.BlobChunk) (*pb.Blob, error) {
+	session, _ := SessionFromContext(ctx)
+	blob, err := s.service.WriteBlob(ctx, session.GetUserID(), in.GetId(), in.GetOffset(), in.GetData())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return pb.FromBlob(blob), nil
+}
+
+// DownloadBlob returns a chunk of the uploaded blob from the offset
+func (s *server) DownloadBlob(ctx context.Context, in *pb.BlobChunk) (*pb.BlobChunk, error) {
+	session, _ := SessionFromContext(ctx)
+	data, _, err := s.service.ReadBlob(ctx, session.GetUserID(), in.GetId(), in.GetOffset())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.BlobChunk{Id: in.GetId(), Offset: in.GetOffset(), Data: data}, nil
+}
+
+// GetKeys returns keys of the user, NotFound if they are not set
+func (s *server) GetKeys(ctx context.Context, _ *pb.GetKeysRequest) (*pb.UserKeys, error) {
+	session, _ := SessionFromContext(ctx)
+	keys, err := s.service.Keys(ctx, session.GetUserID())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return pb.FromUserKeys(keys), nil
+}
+
+// SetKeys sets keys of the user once
+func (s *server) SetKeys(ctx context.Context, in *pb.UserKeys) (*pb.SetKeysResponse, error) {
+	session, _ := SessionFromContext(ctx)
+	if err := s.service.PutKeys(ctx, session.GetUserID(), in.Model()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.SetKeysResponse{}, nil
+}
+
+// GetPublicKey returns public key of another user
+func (s *server) GetPublicKey(ctx context.Context, in *pb.PublicKeyRequest) (*pb.UserKeys, error) {
+	keys, err := s.service.PublicKey(ctx, in.GetEmail())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return pb.FromUserKeys(keys), nil
+}
+
+// ShareItem shares the item of the user with another user or replaces key and permission of the share
+func (s *server) ShareItem(ctx context.Context, in *pb.Share) (*pb.Share, error) {
+	session, _ := SessionFromContext(ctx)
+	share, err := s.service.Share(ctx, session.GetUserID(), in.Model())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return pb.FromShare(share), nil
+}
+
+// ListShares returns shares of the item of the user
+func (s *server) ListShares(ctx context.Context, in *pb.ListSharesRequest) (*pb.ListSharesResponse, error) {
+	session, _ := SessionFromContext(ctx)
+	shares, err := s.storage.GetItemShares(ctx, session.GetUserID(), in.GetItemId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.ListSharesResponse{Shares: pb.FromShareList(shares)}, nil
+}
+
+// RevokeShare revokes the share of the item of the user with another user
+func (s *server) RevokeShare(ctx context.Context, in *pb.RevokeShareRequest) (*pb.RevokeShareResponse, error) {
+	session, _ := SessionFromContext(ctx)
+	if err := s.storage.DeleteShare(ctx, session.GetUserID(), in.GetItemId(), in.GetUserId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.RevokeShareResponse{}, nil
+}
+
+// Shared returns items other users shared with the user
+func (s *server) Shared(ctx context.Context, _ *pb.SharedRequest) (*pb.SharedResponse, error) {
+	session, _ := SessionFromContext(ctx)
+	items, err := s.storage.GetSharedData(ctx, session.GetUserID())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.SharedResponse{Items: pb.FromSharedItems(items)}, nil
+}
+
+// UpdateShared stores an edit of the item shared with the user with write permission
+func (s *server) UpdateShared(ctx context.Context, in *pb.Data) (*pb.SyncResult, error) {
+	session, _ := SessionFromContext(ctx)
+	result, err := s.service.EditShared(ctx, session.GetUserID(), in.Model())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return pb.FromSyncResult(result), nil
+}
+
+// CreateOrg creates an organization owned by the user with the org key sealed to it
+func (s *server) CreateOrg(ctx context.Context, in *pb.Membership) (*pb.Membership, error) {
+	session, _ := SessionFromContext(ctx)
+	membership, err := s.service.NewOrg(ctx, session.GetUserID(), in.Model())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return pb.FromMembership(membership), nil
+}
+
+// ListOrgs returns organizations of the user including the ones it's invited to
+func (s *server) ListOrgs(ctx context.Context, _ *pb.ListOrgsRequest) (*pb.ListOrgsResponse, error) {
+	session, _ := SessionFromContext(ctx)
+	memberships, err := s.service.Orgs(ctx, session.GetUserID())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.ListOrgsResponse{Memberships: pb.FromMemberships(memberships)}, nil
+}
+
+// ListMembers returns members of the organization without their keys
+func (s *server) ListMembers(ctx context.Context, in *pb.OrgRequest) (*pb.ListMembersResponse, error) {
+	session, _ := SessionFromContext(ctx)
+	members, err := s.service.Members(ctx, session.GetUserID(), in.GetOrgId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.ListMembersResponse{Members: pb.FromMemberList(members)}, nil
+}
+
+// SetMember invites a user to the organization or changes its role
+func (s *server) SetMember(ctx context.Context, in *pb.Member) (*pb.Member, error) {
+	session, _ := SessionFromContext(ctx)
+	member, err := s.service.Invite(ctx, session.GetUserID(), in.Model())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return pb.FromMember(member), nil
+}
+
+// RemoveMember removes a member from the organization or the user leaves it
+func (s *server) RemoveMember(ctx context.Context, in *pb.RemoveMemberRequest) (*pb.RemoveMemberResponse, error) {
+	session, _ := SessionFromContext(ctx)
+	if err := s.service.RemoveMember(ctx, session.GetUserID(), in.GetOrgId(), in.GetUserId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.RemoveMemberResponse{}, nil
+}
+
+// AcceptInvite accepts the invitation of the user to the organization
+func (s *server) AcceptInvite(ctx context.Context, in *pb.OrgRequest) (*pb.Member, error) {
+	session, _ := SessionFromContext(ctx)
+	member, err := s.service.Accept(ctx, session.GetUserID(), in.GetOrgId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return pb.FromMember(member), nil
+}
+
+// ListCollections returns collections of the organization
+func (s *server) ListCollections(ctx context.Context, in *pb.OrgRequest) (*pb.ListCollectionsResponse, error) {
+	session, _ := SessionFromContext(ctx)
+	collections, err := s.service.Collections(ctx, session.GetUserID(), in.GetOrgId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.ListCollectionsResponse{Collections: pb.FromCollectionList(collections)}, nil
+}
+
+// SetCollection adds a collection to the organization or renames it
+func (s *server) SetCollection(ctx context.Context, in *pb.Collection) (*pb.Collection, error) {
+	session, _ := SessionFromContext(ctx)
+	collection, err := s.service.PutCollection(ctx, session.GetUserID(), in.Model())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return pb.FromCollection(collection), nil
+}
+
+// GetOrgData returns items of the organization with the version of the org key they are encrypted with
+func (s *server) GetOrgData(ctx context.Context, in *pb.OrgRequest) (*pb.OrgData, error) {
+	session, _ := SessionFromContext(ctx)
+	data, err := s.service.OrgData(ctx, session.GetUserID(), in.GetOrgId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return pb.FromOrgData(data), nil
+}
+
+// WriteOrgData stores items of the organization
+func (s *server) WriteOrgData(ctx context.Context, in *pb.OrgData) (*pb.WriteOrgDataResponse, error) {
+	session, _ := SessionFromContext(ctx)
+	results, err := s.service.WriteOrgData(ctx, session.GetUserID(), in.Model())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.WriteOrgDataResponse{Results: pb.FromSyncResults(results)}, nil
+}
+
+// RotateOrgKey replaces the org key and re-encrypts every item
+func (s *server) RotateOrgKey(ctx context.Context, in *pb.KeyRotation) (*pb.WriteOrgDataResponse, error) {
+	session, _ := SessionFromContext(ctx)
+	results, err := s.service.RotateKey(ctx, session.GetUserID(), in.Model())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.WriteOrgDataResponse{Results: pb.FromSyncResults(results)}, nil
+}
+
+// ListEmergencyContacts returns trusted contacts of the user without their keys
+func (s *server) ListEmergencyContacts(ctx context.Context, _ *pb.ListEmergencyRequest) (*pb.ListEmergencyResponse, error) {
+	session, _ := SessionFromContext(ctx)
+	contacts, err := s.service.EmergencyContacts(ctx, session.GetUserID())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.ListEmergencyResponse{Access: pb.FromEmergencyList(contacts)}, nil
+}
+
+// SetEmergencyContact adds a trusted contact with the vault key sealed to it or changes its waiting period
+func (s *server) SetEmergencyContact(ctx context.Context, in *pb.EmergencyAccess) (*pb.EmergencyAccess, error) {
+	session, _ := SessionFromContext(ctx)
+	access, err := s.service.TrustContact(ctx, session.GetUserID(), in.Model())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return pb.FromEmergencyAccess(access), nil
+}
+
+// RemoveEmergencyContact removes the trusted contact of the user
+func (s *server) RemoveEmergencyContact(ctx context.Context, in *pb.EmergencyRequest) (*pb.RemoveEmergencyContactResponse, error) {
+	session, _ := SessionFromContext(ctx)
+	if err := s.service.RemoveContact(ctx, session.GetUserID(), in.GetUserId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.RemoveEmergencyContactResponse{}, nil
+}
+
+// RejectEmergencyAccess rejects the request of the trusted contact
+func (s *server) RejectEmergencyAccess(ctx context.Context, in *pb.EmergencyRequest) (*pb.EmergencyAccess, error) {
+	session, _ := SessionFromContext(ctx)
+	access, err := s.service.RejectAccess(ctx, session.GetUserID(), in.GetUserId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return pb.FromEmergencyAccess(access), nil
+}
+
+// ListEmergencyGrantors returns users that trust the user, keys are sent only when access is granted
+func (s *server) ListEmergencyGrantors(ctx context.Context, _ *pb.ListEmergencyRequest) (*pb.ListEmergencyResponse, error) {
+	session, _ := SessionFromContext(ctx)
+	grantors, err := s.service.EmergencyGrantors(ctx, session.GetUserID())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.ListEmergencyResponse{Access: pb.FromEmergencyList(grantors)}, nil
+}
+
+// RequestEmergencyAccess requests access to the vault of the grantor
+func (s *server) RequestEmergencyAccess(ctx context.Context, in *pb.EmergencyRequest) (*pb.EmergencyAccess, error) {
+	session, _ := SessionFromContext(ctx)
+	access, err := s.service.RequestAccess(ctx, session.GetUserID(), in.GetUserId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return pb.FromEmergencyAccess(access), nil
+}
+
+// GetEmergencyVault returns the vault of the grantor once access is granted
+func (s *server) GetEmergencyVault(ctx context.Context, in *pb.EmergencyRequest) (*pb.EmergencyVault, error) {
+	session, _ := SessionFromContext(ctx)
+	vault, err := s.service.EmergencyVault(ctx, session.GetUserID(), in.GetUserId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return pb.FromEmergencyVault(vault), nil
+}
+
+// ListSends returns sends of the user without their data
+func (s *server) ListSends(ctx context.Context, _ *pb.ListSendsRequest) (*pb.ListSendsResponse, error) {
+	session, _ := SessionFromContext(ctx)
+	sends, err := s.service.Sends(ctx, session.GetUserID())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.ListSendsResponse{Sends: pb.FromSendList(sends)}, nil
+}
+
+// CreateSend creates a send with the encrypted secret, the server chooses its id
+func (s *server) CreateSend(ctx context.Context, in *pb.Send) (*pb.Send, error) {
+	session, _ := SessionFromContext(ctx)
+	send, err := s.service.NewSend(ctx, session.GetUserID(), in.Model())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return pb.FromSend(send), nil
+}
+
+// DeleteSend removes the send of the user before it expires
+func (s *server) DeleteSend(ctx context.Context, in *pb.SendRequest) (*pb.DeleteSendResponse, error) {
+	session, _ := SessionFromContext(ctx)
+	if err := s.service.RemoveSend(ctx, session.GetUserID(), in.GetId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.DeleteSendResponse{}, nil
+}
+
+// ReceiveSend returns the send and counts the view, it needs no session
+func (s *server) ReceiveSend(ctx context.Context, in *pb.SendRequest) (*pb.Send, error) {
+	send, err := s.service.OpenSend(ctx, in.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return pb.FromSend(send), nil
+}
+
+// ListRevisions returns previous versions of the item without data, newest first
+func (s *server) ListRevisions(ctx context.Context, in *pb.ListRevisionsRequest) (*pb.ListRevisionsResponse, error) {
+	session, _ := SessionFromContext(ctx)
+	revisions, err := s.storage.GetRevisions(ctx, session.GetUserID(), in.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.ListRevisionsResponse{Revisions: pb.FromDataList(revisions)}, nil
+}
+
+// GetRevision returns previous version of the item with its data
+func (s *server) GetRevision(ctx context.Context, in *pb.GetRevisionRequest) (*pb.Data, error) {
+	session, _ := SessionFromContext(ctx)
+	data, err := s.storage.GetRevision(ctx, session.GetUserID(), in.GetId(), in.GetRevision())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return pb.FromData(data), nil
+}
+
+// Events streams changes of the user's data until client cancels the call
+func (s *server) Events(_ *pb.EventsRequest, stream pb.Keeper_EventsServer) error {
+	session, _ := SessionFromContext(stream.Context())
+	events, unsubscribe := s.service.Subscribe(session.GetUserID())
+	defer unsubscribe()
+
+	// Headers tell client that it is subscribed
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event := <-events:
+			if err := stream.Send(&pb.ChangeEvent{Cursor: event.Cursor}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// toStatus converts errors of handlers and storage to gRPC status
+func toStatus(err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, handlers.ErrEmptyEmail), errors.Is(err, handlers.ErrInvalidEmail),
+		errors.Is(err, handlers.ErrEmptyPassword), errors.Is(err, handlers.ErrIdempotencyKeyReused),
+		errors.Is(err, handlers.ErrInvalidBlob), errors.Is(err, storage.ErrBlobHash),
+		errors.Is(err, handlers.ErrInvalidKeys), errors.Is(err, handlers.ErrInvalidShare),
+		errors.Is(err, handlers.ErrItemKeyMissing), errors.Is(err, handlers.ErrInvalidOrg),
+		errors.Is(err, handlers.ErrInvalidMember), errors.Is(err, handlers.ErrInvalidCollection),
+		errors.Is(err, handlers.ErrOrgBlobs), errors.Is(err, handlers.ErrInvalidRotation),
+		errors.Is(err, handlers.ErrInvalidEmergency), errors.Is(err, handlers.ErrInvalidSend):
+		code = codes.InvalidArgument
+	case errors.Is(err, handlers.ErrUserExists), errors.Is(err, storage.ErrBlobExists),
+		errors.Is(err, storage.ErrKeysExist), errors.Is(err, storage.ErrCollectionExists):
+		code = codes.AlreadyExists
+	case errors.Is(err, handlers.ErrUserNotFound), errors.Is(err, storage.ErrRevisionNotFound),
+		errors.Is(err, storage.ErrBlobNotFound), errors.Is(err, handlers.ErrKeysNotFound),
+		errors.Is(err, handlers.ErrItemNotFound), errors.Is(err, storage.ErrShareNotFound),
+		errors.Is(err, handlers.ErrCollectionNotFound), errors.Is(err, storage.ErrOrgNotFound),
+		errors.Is(err, storage.ErrMemberNotFound), errors.Is(err, storage.ErrEmergencyNotFound),
+		errors.Is(err, storage.ErrSendNotFound):
+		code = codes.NotFound
+	case errors.Is(err, handlers.ErrReadOnly), errors.Is(err, handlers.ErrNotMember),
+		errors.Is(err, handlers.ErrForbidden), errors.Is(err, handlers.ErrAccessWaiting):
+		code = codes.PermissionDenied
+	case errors.Is(err, storage.ErrBlobOffset), errors.Is(err, storage.ErrBlobIncomplete),
+		errors.Is(err, storage.ErrKeyVersion), errors.Is(err, handlers.ErrAccessNotRequested):
+		code = codes.FailedPrecondition
+	case errors.Is(err, handlers.ErrRotationConflict):
+		code = codes.Aborted
+	case errors.Is(err, handlers.ErrWrongPassword):
+		code = codes.Unauthenticated
+	case errors.Is(err, handlers.ErrItemTooLarge), errors.Is(err, handlers.ErrQuotaExceeded),
+		errors.Is(err, handlers.ErrBlobTooLarge), errors.Is(err, handlers.ErrBodyTooLarge):
+		code = codes.ResourceExhausted
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+	}
+	return status.Error(code, err.Error())
+}
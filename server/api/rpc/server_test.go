@@ -0,0 +1,416 @@
+package rpc_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/common/pb"
+	"github.com/gynshu-one/goph-keeper/common/utils"
+	"github.com/gynshu-one/goph-keeper/server/api/handlers"
+	"github.com/gynshu-one/goph-keeper/server/api/rpc"
+	"github.com/gynshu-one/goph-keeper/server/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newClient starts gRPC server over in-memory connection and returns client of it
+func newClient(t *testing.T) pb.KeeperClient {
+	t.Helper()
+	stor := storage.NewMemoryStorage(storage.Options{MaxRevisions: 5})
+	server := rpc.NewServer(handlers.NewHandlers(stor, handlers.Limits{MaxItemSize: 16}), stor)
+	listener := bufconn.Listen(1 << 20)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.UseCompressor(pb.ZstdCompressor)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return pb.NewKeeperClient(conn)
+}
+
+// withSession returns context with session id in metadata
+func withSession(ctx context.Context, session *pb.Session) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, rpc.SessionMetadata, session.GetId())
+}
+
+// sealedKey returns a fake key of the size utils.SealKey makes, server only checks the size of sealed keys
+func sealedKey(text string) []byte {
+	return append([]byte(text), make([]byte, utils.MinSealedKeySize)...)
+}
+
+func assertCode(t *testing.T, err error, code codes.Code) {
+	t.Helper()
+	if status.Code(err) != code {
+		t.Errorf("Expected code %s, got %v", code, err)
+	}
+}
+
+func TestKeeper(t *testing.T) {
+	client := newClient(t)
+	ctx := context.Background()
+	credentials := &pb.Credentials{Email: "grpc@example.com", Password: "password"}
+
+	// Registration and login
+	session, err := client.Register(ctx, credentials)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Register(ctx, credentials)
+	assertCode(t, err, codes.AlreadyExists)
+	_, err = client.Login(ctx, &pb.Credentials{Email: credentials.Email, Password: "wrong"})
+	assertCode(t, err, codes.Unauthenticated)
+	other, err := client.Login(ctx, credentials)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sync requires a session
+	_, err = client.Sync(ctx, &pb.SyncRequest{})
+	assertCode(t, err, codes.Unauthenticated)
+
+	// The other device listens to changes
+	eventsCtx, cancel := context.WithTimeout(withSession(ctx, other), 5*time.Second)
+	defer cancel()
+	events, err := client.Events(eventsCtx, &pb.EventsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Headers are sent when server starts to handle the stream
+	if _, err = events.Header(); err != nil {
+		t.Fatal(err)
+	}
+
+	item := &pb.Data{Id: "1", OwnerId: credentials.Email, Name: "first", Data: []byte("first")}
+	response, err := client.Sync(withSession(ctx, session), &pb.SyncRequest{Data: []*pb.Data{item}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.GetResults()) != 1 || response.GetResults()[0].GetStatus() != "applied" {
+		t.Errorf("Unexpected results %+v", response.GetResults())
+	}
+	if !response.GetReset_() || len(response.GetData()) != 1 || response.GetUsage().GetItems() != 1 {
+		t.Errorf("Unexpected response %+v", response)
+	}
+	event, err := events.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.GetCursor() != response.GetCursor() {
+		t.Errorf("Event cursor is %d, want %d", event.GetCursor(), response.GetCursor())
+	}
+
+	// Edit of the item keeps the previous version in history
+	item.Revision, item.Data = 1, []byte("second")
+	response, err = client.Sync(withSession(ctx, session), &pb.SyncRequest{
+		Cursor: response.GetCursor(),
+		Data:   []*pb.Data{item},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.GetReset_() || len(response.GetData()) != 1 || response.GetData()[0].GetRevision() != 2 {
+		t.Errorf("Unexpected response %+v", response)
+	}
+	revisions, err := client.ListRevisions(withSession(ctx, session), &pb.ListRevisionsRequest{Id: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions.GetRevisions()) != 1 || revisions.GetRevisions()[0].GetRevision() != 1 {
+		t.Errorf("Unexpected revisions %+v", revisions.GetRevisions())
+	}
+	revision, err := client.GetRevision(withSession(ctx, session), &pb.GetRevisionRequest{Id: "1", Revision: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(revision.GetData()) != "first" {
+		t.Errorf("Expected data of the first version, got %s", revision.GetData())
+	}
+	_, err = client.GetRevision(withSession(ctx, session), &pb.GetRevisionRequest{Id: "1", Revision: 7})
+	assertCode(t, err, codes.NotFound)
+
+	// Push only sync leaves changes to the stream
+	pushed, err := client.Sync(withSession(ctx, session), &pb.SyncRequest{
+		Data:     []*pb.Data{{Id: "2", OwnerId: credentials.Email, Data: []byte("third")}},
+		PushOnly: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pushed.GetData()) != 0 || pushed.GetReset_() {
+		t.Errorf("Unexpected push only response %+v", pushed)
+	}
+	changes, err := client.Changes(withSession(ctx, session), &pb.ChangesRequest{Cursor: response.GetCursor()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []*pb.ChangeLine
+	for {
+		line, err := changes.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 3 || lines[1].GetData().GetId() != "2" || !lines[2].GetCheckpoint().GetDone() {
+		t.Errorf("Unexpected changes %+v", lines)
+	}
+
+	// Limits
+	item.Revision, item.Data = 2, []byte("more than ten bytes")
+	_, err = client.Sync(withSession(ctx, session), &pb.SyncRequest{Data: []*pb.Data{item}})
+	assertCode(t, err, codes.ResourceExhausted)
+
+	// Logout of all sessions
+	if _, err = client.Logout(withSession(ctx, session), &pb.LogoutRequest{All: true}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Sync(withSession(ctx, other), &pb.SyncRequest{})
+	assertCode(t, err, codes.Unauthenticated)
+}
+
+func TestSharing(t *testing.T) {
+	client := newClient(t)
+	ctx := context.Background()
+	owner, err := client.Register(ctx, &pb.Credentials{Email: "owner@example.com", Password: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := client.Register(ctx, &pb.Credentials{Email: "user@example.com", Password: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.GetKeys(withSession(ctx, user), &pb.GetKeysRequest{})
+	assertCode(t, err, codes.NotFound)
+	keys := &pb.UserKeys{PublicKey: make([]byte, 32), PrivateKey: []byte("encrypted")}
+	if _, err = client.SetKeys(withSession(ctx, user), keys); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.SetKeys(withSession(ctx, user), keys)
+	assertCode(t, err, codes.AlreadyExists)
+	public, err := client.GetPublicKey(withSession(ctx, owner), &pb.PublicKeyRequest{Email: "user@example.com"})
+	if err != nil || len(public.GetPublicKey()) != 32 || len(public.GetPrivateKey()) != 0 {
+		t.Errorf("Expected only public key, got %+v and %v", public, err)
+	}
+
+	item := &pb.Data{Id: "1", OwnerId: "owner@example.com", Data: []byte("data"), Key: []byte("key")}
+	if _, err = client.Sync(withSession(ctx, owner), &pb.SyncRequest{Data: []*pb.Data{item}}); err != nil {
+		t.Fatal(err)
+	}
+	share := &pb.Share{ItemId: "1", UserId: "user@example.com", Key: sealedKey("sealed"), Permission: "read"}
+	if _, err = client.ShareItem(withSession(ctx, owner), share); err != nil {
+		t.Fatal(err)
+	}
+	shared, err := client.Shared(withSession(ctx, user), &pb.SharedRequest{})
+	if err != nil || len(shared.GetItems()) != 1 || shared.GetItems()[0].GetData().GetId() != "1" ||
+		string(shared.GetItems()[0].GetData().GetKey()) != "key" {
+		t.Fatalf("Unexpected shared items %+v and %v", shared, err)
+	}
+	edit := shared.GetItems()[0].GetData()
+	edit.Data = []byte("edit")
+	_, err = client.UpdateShared(withSession(ctx, user), edit)
+	assertCode(t, err, codes.PermissionDenied)
+
+	share.Permission = "write"
+	if _, err = client.ShareItem(withSession(ctx, owner), share); err != nil {
+		t.Fatal(err)
+	}
+	result, err := client.UpdateShared(withSession(ctx, user), edit)
+	if err != nil || result.GetStatus() != "applied" {
+		t.Errorf("Expected edit applied, got %+v and %v", result, err)
+	}
+
+	shares, err := client.ListShares(withSession(ctx, owner), &pb.ListSharesRequest{ItemId: "1"})
+	if err != nil || len(shares.GetShares()) != 1 || shares.GetShares()[0].GetPermission() != "write" {
+		t.Errorf("Unexpected shares %+v and %v", shares, err)
+	}
+	if _, err = client.RevokeShare(withSession(ctx, owner), &pb.RevokeShareRequest{ItemId: "1", UserId: "user@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.RevokeShare(withSession(ctx, owner), &pb.RevokeShareRequest{ItemId: "1", UserId: "user@example.com"})
+	assertCode(t, err, codes.NotFound)
+	_, err = client.UpdateShared(withSession(ctx, user), edit)
+	assertCode(t, err, codes.NotFound)
+}
+
+func TestOrgs(t *testing.T) {
+	client := newClient(t)
+	ctx := context.Background()
+	sessions := make(map[string]*pb.Session)
+	for _, email := range []string{"owner@example.com", "reader@example.com"} {
+		session, err := client.Register(ctx, &pb.Credentials{Email: email, Password: "password"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys := &pb.UserKeys{PublicKey: make([]byte, 32), PrivateKey: []byte("encrypted")}
+		if _, err = client.SetKeys(withSession(ctx, session), keys); err != nil {
+			t.Fatal(err)
+		}
+		sessions[email] = session
+	}
+	owner, reader := withSession(ctx, sessions["owner@example.com"]), withSession(ctx, sessions["reader@example.com"])
+
+	created, err := client.CreateOrg(owner, &pb.Membership{
+		Organization: &pb.Organization{Name: "team"},
+		Member:       &pb.Member{Key: sealedKey("sealed")},
+	})
+	if err != nil || created.GetOrganization().GetKeyVersion() != 1 || created.GetMember().GetRole() != "owner" {
+		t.Fatalf("Unexpected created organization %+v and %v", created, err)
+	}
+	orgID := created.GetOrganization().GetId()
+	collection, err := client.SetCollection(owner, &pb.Collection{OrgId: orgID, Name: "servers"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.SetMember(owner, &pb.Member{OrgId: orgID, UserId: "reader@example.com", Role: "read_only",
+		Key: sealedKey("sealed"), KeyVersion: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.GetOrgData(reader, &pb.OrgRequest{OrgId: orgID})
+	assertCode(t, err, codes.PermissionDenied)
+	if _, err = client.AcceptInvite(reader, &pb.OrgRequest{OrgId: orgID}); err != nil {
+		t.Fatal(err)
+	}
+
+	item := &pb.Data{Id: "1", Data: []byte("data"), CollectionId: collection.GetId()}
+	written, err := client.WriteOrgData(owner, &pb.OrgData{OrgId: orgID, KeyVersion: 1, Data: []*pb.Data{item}})
+	if err != nil || len(written.GetResults()) != 1 || written.GetResults()[0].GetStatus() != "applied" {
+		t.Fatalf("Unexpected results %+v and %v", written, err)
+	}
+	_, err = client.WriteOrgData(reader, &pb.OrgData{OrgId: orgID, KeyVersion: 1, Data: []*pb.Data{item}})
+	assertCode(t, err, codes.PermissionDenied)
+	data, err := client.GetOrgData(reader, &pb.OrgRequest{OrgId: orgID})
+	if err != nil || len(data.GetData()) != 1 || data.GetData()[0].GetOwnerId() != orgID {
+		t.Fatalf("Unexpected org data %+v and %v", data, err)
+	}
+
+	rotation := &pb.KeyRotation{OrgId: orgID, KeyVersion: 2, Data: []*pb.Data{item}, Members: []*pb.Member{
+		{UserId: "owner@example.com", Key: sealedKey("new")}, {UserId: "reader@example.com", Key: sealedKey("new")}}}
+	_, err = client.RotateOrgKey(owner, rotation)
+	assertCode(t, err, codes.Aborted)
+	rotation.Data = data.GetData()
+	if _, err = client.RotateOrgKey(owner, rotation); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.WriteOrgData(owner, &pb.OrgData{OrgId: orgID, KeyVersion: 1, Data: []*pb.Data{item}})
+	assertCode(t, err, codes.FailedPrecondition)
+	if _, err = client.RemoveMember(owner, &pb.RemoveMemberRequest{OrgId: orgID, UserId: "reader@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	orgs, err := client.ListOrgs(reader, &pb.ListOrgsRequest{})
+	if err != nil || len(orgs.GetMemberships()) != 0 {
+		t.Errorf("Expected removed member has no organizations, got %+v and %v", orgs, err)
+	}
+}
+
+func TestEmergencyAccess(t *testing.T) {
+	client := newClient(t)
+	ctx := context.Background()
+	sessions := make(map[string]*pb.Session)
+	for _, email := range []string{"owner@example.com", "friend@example.com"} {
+		session, err := client.Register(ctx, &pb.Credentials{Email: email, Password: "password"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys := &pb.UserKeys{PublicKey: make([]byte, 32), PrivateKey: []byte("encrypted")}
+		if _, err = client.SetKeys(withSession(ctx, session), keys); err != nil {
+			t.Fatal(err)
+		}
+		sessions[email] = session
+	}
+	owner, friend := withSession(ctx, sessions["owner@example.com"]), withSession(ctx, sessions["friend@example.com"])
+
+	_, err := client.SetEmergencyContact(owner, &pb.EmergencyAccess{GranteeId: "friend@example.com", Key: sealedKey("sealed")})
+	assertCode(t, err, codes.InvalidArgument)
+	_, err = client.SetEmergencyContact(owner, &pb.EmergencyAccess{GranteeId: "friend@example.com", WaitDays: 2, Key: sealedKey("sealed")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	grantor := &pb.EmergencyRequest{UserId: "owner@example.com"}
+	_, err = client.GetEmergencyVault(friend, grantor)
+	assertCode(t, err, codes.FailedPrecondition)
+	requested, err := client.RequestEmergencyAccess(friend, grantor)
+	if err != nil || requested.GetStatus() != "requested" || requested.GetKey() != nil {
+		t.Fatalf("Unexpected request %+v and %v", requested, err)
+	}
+	_, err = client.GetEmergencyVault(friend, grantor)
+	assertCode(t, err, codes.PermissionDenied)
+
+	contacts, err := client.ListEmergencyContacts(owner, &pb.ListEmergencyRequest{})
+	if err != nil || len(contacts.GetAccess()) != 1 || contacts.GetAccess()[0].GetStatus() != "requested" {
+		t.Fatalf("Unexpected contacts %+v and %v", contacts, err)
+	}
+	contact := &pb.EmergencyRequest{UserId: "friend@example.com"}
+	if _, err = client.RejectEmergencyAccess(owner, contact); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.RejectEmergencyAccess(owner, contact)
+	assertCode(t, err, codes.FailedPrecondition)
+	grantors, err := client.ListEmergencyGrantors(friend, &pb.ListEmergencyRequest{})
+	if err != nil || len(grantors.GetAccess()) != 1 || grantors.GetAccess()[0].GetStatus() != "rejected" {
+		t.Fatalf("Unexpected grantors %+v and %v", grantors, err)
+	}
+
+	if _, err = client.RemoveEmergencyContact(owner, contact); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.RequestEmergencyAccess(friend, grantor)
+	assertCode(t, err, codes.NotFound)
+}
+
+func TestSends(t *testing.T) {
+	client := newClient(t)
+	ctx := context.Background()
+	session, err := client.Register(ctx, &pb.Credentials{Email: "owner@example.com", Password: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner := withSession(ctx, session)
+
+	expiresAt := time.Now().Add(time.Hour).Unix()
+	_, err = client.CreateSend(owner, &pb.Send{Data: []byte("secret"), ExpiresAt: expiresAt})
+	assertCode(t, err, codes.InvalidArgument)
+	_, err = client.CreateSend(ctx, &pb.Send{Data: []byte("secret"), ExpiresAt: expiresAt, MaxViews: 1})
+	assertCode(t, err, codes.Unauthenticated)
+	created, err := client.CreateSend(owner, &pb.Send{Data: []byte("secret"), ExpiresAt: expiresAt, MaxViews: 1})
+	if err != nil || created.GetId() == "" || created.GetData() != nil {
+		t.Fatalf("Unexpected send %+v and %v", created, err)
+	}
+	sends, err := client.ListSends(owner, &pb.ListSendsRequest{})
+	if err != nil || len(sends.GetSends()) != 1 || sends.GetSends()[0].GetId() != created.GetId() {
+		t.Fatalf("Unexpected sends %+v and %v", sends, err)
+	}
+
+	// Anyone receives the send without a session, once
+	received, err := client.ReceiveSend(ctx, &pb.SendRequest{Id: created.GetId()})
+	if err != nil || string(received.GetData()) != "secret" || received.GetViews() != 1 {
+		t.Fatalf("Unexpected received send %+v and %v", received, err)
+	}
+	_, err = client.ReceiveSend(ctx, &pb.SendRequest{Id: created.GetId()})
+	assertCode(t, err, codes.NotFound)
+	_, err = client.DeleteSend(owner, &pb.SendRequest{Id: created.GetId()})
+	assertCode(t, err, codes.NotFound)
+}
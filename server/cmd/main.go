@@ -1,18 +1,25 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	server "github.com/gynshu-one/goph-keeper/server/api/handlers"
 	"github.com/gynshu-one/goph-keeper/server/api/router"
+	"github.com/gynshu-one/goph-keeper/server/api/rpc"
 	"github.com/gynshu-one/goph-keeper/server/config"
+	"github.com/gynshu-one/goph-keeper/server/jobs"
 	"github.com/gynshu-one/goph-keeper/server/storage"
 
 	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 var (
@@ -32,13 +39,35 @@ func main() {
 	db := config.NewDb()
 
 	// Init storage
-	newStorage := storage.NewStorage(db.Collection("user-data"), db.Collection("users"))
+	newStorage := storage.NewStorage(db, storage.Options{
+		MaxRevisions: config.GetConfig().MaxRevisions,
+		Blobs:        config.NewBlobBackend(db),
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	err := newStorage.EnsureIndexes(ctx)
+	cancel()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create indexes")
+	}
 
 	// Init handlers
-	handlers := server.NewHandlers(newStorage)
+	handlers := server.NewHandlers(newStorage, server.Limits{
+		MaxItems:    config.GetConfig().MaxItems,
+		MaxBytes:    config.GetConfig().MaxBytes,
+		MaxItemSize: config.GetConfig().MaxItemSize,
+		MaxBodySize: config.GetConfig().MaxBodySize,
+		MaxBlobSize: config.GetConfig().MaxBlobSize,
+	})
 
 	r := router.NewRouter(handlers)
 
+	// Background jobs live until shutdown
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go jobs.NewPurger(newStorage, config.GetConfig().Retention, config.GetConfig().PurgeInterval).Run(jobsCtx)
+	go jobs.NewCollector(newStorage, config.GetConfig().BlobGrace, config.GetConfig().PurgeInterval).Run(jobsCtx)
+	go jobs.NewExpirer(newStorage, config.GetConfig().PurgeInterval).Run(jobsCtx)
+
 	log.Info().Msg("Starting server")
 
 	go func() {
@@ -54,11 +83,39 @@ func main() {
 		}
 	}()
 
+	// gRPC API is served with the same handlers and certificate
+	var grpcServer *grpc.Server
+	if port := config.GetConfig().GrpcServerPort; port != "" {
+		creds, err := credentials.NewServerTLSFromFile(config.GetConfig().CertFilePath, config.GetConfig().KeyFilePath)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to load certificate")
+		}
+		opts := []grpc.ServerOption{grpc.Creds(creds)}
+		if maxBody := config.GetConfig().MaxBodySize; maxBody > 0 {
+			opts = append(opts, grpc.MaxRecvMsgSize(int(maxBody)))
+		}
+		grpcServer = rpc.NewServer(handlers, newStorage, opts...)
+		listener, err := net.Listen("tcp", ":"+port)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to listen")
+		}
+		go func() {
+			log.Info().Msgf("Listening grpc on %s", port)
+			if err := grpcServer.Serve(listener); err != nil {
+				log.Fatal().Err(err).Msg("Failed to serve grpc")
+			}
+		}()
+	}
+
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Info().Msg("Shutdown Server ...")
+	stopJobs()
+	if grpcServer != nil {
+		grpcServer.Stop()
+	}
 	log.Info().Msg("Server exiting")
 
 }
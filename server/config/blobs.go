@@ -0,0 +1,47 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/server/storage/blobstore"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// NewBlobBackend returns the backend content of files is kept in, chosen by blob_backend flag
+// GridFS backend keeps files in the same database
+func NewBlobBackend(db *mongo.Database) blobstore.Backend {
+	switch GetConfig().BlobBackend {
+	case "gridfs":
+		return blobstore.NewGridFS(db)
+	case "fs":
+		dir := GetConfig().BlobDir
+		if dir == "" {
+			homeDir, err := os.UserHomeDir()
+			if err != nil {
+				log.Fatal().Err(err).Msg("Failed to get user home dir")
+			}
+			dir = filepath.Join(homeDir, ".goph-keeper", "blobs")
+		}
+		backend, err := blobstore.NewFS(dir)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to create blob dir")
+		}
+		log.Info().Msgf("Keeping files in %s", dir)
+		return backend
+	case "s3":
+		backend := blobstore.NewS3(GetConfig().S3)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := backend.EnsureBucket(ctx); err != nil {
+			log.Fatal().Err(err).Msg("Failed to create S3 bucket")
+		}
+		log.Info().Msgf("Keeping files in S3 bucket %s", GetConfig().S3.Bucket)
+		return backend
+	}
+	log.Fatal().Msgf("Unknown blob backend %s", GetConfig().BlobBackend)
+	return nil
+}
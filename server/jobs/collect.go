@@ -0,0 +1,53 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/server/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// Collector periodically removes uploaded files no item refers to anymore
+// a file is uploaded before the item that refers to it is synced, so files younger than grace are kept
+type Collector struct {
+	storage  storage.Storage
+	grace    time.Duration
+	interval time.Duration
+}
+
+// NewCollector creates a new Collector
+// grace is how long a file is kept without items that refer to it, interval is how often files are collected
+func NewCollector(storage storage.Storage, grace, interval time.Duration) *Collector {
+	return &Collector{
+		storage:  storage,
+		grace:    grace,
+		interval: interval,
+	}
+}
+
+// Run collects unused files every interval until ctx is done
+func (c *Collector) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		count, err := c.Collect(ctx)
+		if err != nil {
+			log.Err(err).Msg("failed to collect unused blobs")
+		} else if count > 0 {
+			log.Info().Msgf("Collected %d unused blobs", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Collect removes files created more than grace ago that no item or its previous version refers to
+// returns number of removed files
+func (c *Collector) Collect(ctx context.Context) (int64, error) {
+	return c.storage.CollectBlobs(ctx, time.Now().Add(-c.grace).Unix())
+}
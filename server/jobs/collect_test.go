@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/gynshu-one/goph-keeper/server/storage"
+)
+
+func TestCollect(t *testing.T) {
+	ctx := context.Background()
+	s := storage.NewMemoryStorage(storage.Options{})
+	now := time.Now().Unix()
+
+	// Unused file uploaded long ago, one uploaded right now that waits for its item
+	content := []byte("content")
+	sum := sha256.Sum256(content)
+	for id, createdAt := range map[string]int64{"old": now - 3600, "new": now} {
+		_, err := s.CreateBlob(ctx, models.Blob{ID: id, OwnerID: "user", Size: 7, Hash: hex.EncodeToString(sum[:]), CreatedAt: createdAt})
+		if err != nil {
+			t.Fatalf("CreateBlob returned an error: %v", err)
+		}
+	}
+
+	count, err := NewCollector(s, 10*time.Minute, time.Hour).Collect(ctx)
+	if err != nil {
+		t.Fatalf("Collect returned an error: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 collected blob, got %d", count)
+	}
+	if _, err = s.GetBlob(ctx, "user", "old"); !errors.Is(err, storage.ErrBlobNotFound) {
+		t.Errorf("Expected old blob collected, got %v", err)
+	}
+	if _, err = s.GetBlob(ctx, "user", "new"); err != nil {
+		t.Errorf("Expected new blob kept, got %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/server/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// Expirer periodically removes sends that expired before anyone viewed them enough times
+// expired sends can't be viewed anyway, it only frees the storage
+type Expirer struct {
+	storage  storage.Storage
+	interval time.Duration
+}
+
+// NewExpirer creates a new Expirer, interval is how often expired sends are removed
+func NewExpirer(storage storage.Storage, interval time.Duration) *Expirer {
+	return &Expirer{
+		storage:  storage,
+		interval: interval,
+	}
+}
+
+// Run removes expired sends every interval until ctx is done
+func (e *Expirer) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		count, err := e.Expire(ctx)
+		if err != nil {
+			log.Err(err).Msg("failed to remove expired sends")
+		} else if count > 0 {
+			log.Info().Msgf("Removed %d expired sends", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Expire removes sends that are expired now
+// returns number of removed sends
+func (e *Expirer) Expire(ctx context.Context) (int64, error) {
+	return e.storage.DeleteExpiredSends(ctx, time.Now().Unix())
+}
@@ -0,0 +1,38 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/gynshu-one/goph-keeper/server/storage"
+)
+
+func TestExpire(t *testing.T) {
+	ctx := context.Background()
+	s := storage.NewMemoryStorage(storage.Options{})
+	now := time.Now().Unix()
+
+	// One send expired a minute ago, one expires in an hour
+	for id, expiresAt := range map[string]int64{"expired": now - 60, "alive": now + 3600} {
+		if err := s.CreateSend(ctx, models.Send{ID: id, OwnerID: "user", ExpiresAt: expiresAt, MaxViews: 1}); err != nil {
+			t.Fatalf("CreateSend returned an error: %v", err)
+		}
+	}
+
+	count, err := NewExpirer(s, time.Hour).Expire(ctx)
+	if err != nil {
+		t.Fatalf("Expire returned an error: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 expired send, got %d", count)
+	}
+	sends, err := s.GetSends(ctx, "user")
+	if err != nil {
+		t.Fatalf("GetSends returned an error: %v", err)
+	}
+	if len(sends) != 1 || sends[0].ID != "alive" {
+		t.Errorf("Expected only alive send to be kept, got %+v", sends)
+	}
+}
@@ -0,0 +1,5 @@
+// Package jobs contains background workers of the server
+// such as Purger which permanently removes deleted items after retention period
+// Collector which removes uploaded files no item refers to
+// and Expirer which removes expired sends
+package jobs
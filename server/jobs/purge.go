@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/server/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// Purger periodically removes items that were deleted more than retention ago
+// deleted items (tombstones) are kept for retention so offline clients learn about deletion on sync,
+// after that storage remembers only ids of purged items, so they can't be created again
+type Purger struct {
+	storage   storage.Storage
+	retention time.Duration
+	interval  time.Duration
+}
+
+// NewPurger creates a new Purger
+// retention is how long deleted items are kept, interval is how often they are purged
+func NewPurger(storage storage.Storage, retention, interval time.Duration) *Purger {
+	return &Purger{
+		storage:   storage,
+		retention: retention,
+		interval:  interval,
+	}
+}
+
+// Run purges deleted items every interval until ctx is done
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		count, err := p.Purge(ctx)
+		if err != nil {
+			log.Err(err).Msg("failed to purge deleted items")
+		} else if count > 0 {
+			log.Info().Msgf("Purged %d deleted items", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge removes items that were deleted more than retention ago
+// returns number of removed items
+func (p *Purger) Purge(ctx context.Context) (int64, error) {
+	return p.storage.PurgeDeleted(ctx, time.Now().Add(-p.retention).Unix())
+}
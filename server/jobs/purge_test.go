@@ -0,0 +1,55 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/gynshu-one/goph-keeper/server/storage"
+)
+
+func TestPurge(t *testing.T) {
+	ctx := context.Background()
+	s := storage.NewMemoryStorage(storage.Options{})
+	now := time.Now().Unix()
+
+	// One item deleted long ago, one deleted recently
+	for id, deletedAt := range map[string]int64{"old": now - 3600, "recent": now - 60} {
+		_, err := s.SetData(ctx, models.DataWrapper{ID: id, OwnerID: "user", UpdatedAt: deletedAt, DeletedAt: deletedAt})
+		if err != nil {
+			t.Fatalf("SetData returned an error: %v", err)
+		}
+	}
+
+	count, err := NewPurger(s, 10*time.Minute, time.Hour).Purge(ctx)
+	if err != nil {
+		t.Fatalf("Purge returned an error: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 purged item, got %d", count)
+	}
+	data, err := s.GetData(ctx, "user")
+	if err != nil {
+		t.Fatalf("GetData returned an error: %v", err)
+	}
+	if len(data) != 1 || data[0].ID != "recent" {
+		t.Errorf("Expected only recent item to be kept, got %+v", data)
+	}
+}
+
+func TestRunStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		NewPurger(storage.NewMemoryStorage(storage.Options{}), time.Hour, time.Millisecond).Run(ctx)
+		close(done)
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Run didn't stop after context was canceled")
+	}
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// revertTimeout is how long reverting of a failed batch may take
+const revertTimeout = 10 * time.Second
+
+// illegalOperationCode is returned by standalone mongo servers on transaction start
+const illegalOperationCode = 20
+
+// SetDataBatch sets all the models like SetData does
+// it runs in a transaction when mongo is a replica set,
+// otherwise writes that were applied before a failure are reverted
+func (s *storage) SetDataBatch(ctx context.Context, data []models.DataWrapper) ([]models.SyncResult, error) {
+	if !s.noTransactions.Load() {
+		results, err := s.setDataTransaction(ctx, data)
+		if !isTransactionNotSupported(err) {
+			return results, err
+		}
+		s.noTransactions.Store(true)
+		log.Warn().Msg("mongo doesn't support transactions, failed sync batches will be reverted instead")
+	}
+	return s.setDataCompensated(ctx, data)
+}
+
+// setDataTransaction sets all the models in one transaction
+func (s *storage) setDataTransaction(ctx context.Context, data []models.DataWrapper) (results []models.SyncResult, err error) {
+	session, err := s.dataCollection.Database().Client().StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		// Transaction may be retried, so results are collected from scratch
+		results = make([]models.SyncResult, 0, len(data))
+		for _, d := range data {
+			result, _, err := s.setData(sessCtx, d)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, result)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// applied is a write of the batch that can be reverted
+type applied struct {
+	id string
+	// previous is the replaced version, nil if the model was created
+	previous *models.DataWrapper
+}
+
+// setDataCompensated sets the models one by one and reverts applied writes if any of them fails
+func (s *storage) setDataCompensated(ctx context.Context, data []models.DataWrapper) ([]models.SyncResult, error) {
+	results := make([]models.SyncResult, 0, len(data))
+	var writes []applied
+	for _, d := range data {
+		result, previous, err := s.setData(ctx, d)
+		if err != nil {
+			s.revert(writes)
+			return nil, err
+		}
+		if result.Status == models.StatusApplied {
+			writes = append(writes, applied{id: d.ID, previous: previous})
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// revert restores models changed by a failed batch in reverse order
+// it uses its own context, so batches of cancelled requests are reverted too
+// history trimmed by the batch can't be restored
+func (s *storage) revert(writes []applied) {
+	ctx, cancel := context.WithTimeout(context.Background(), revertTimeout)
+	defer cancel()
+	for i := len(writes) - 1; i >= 0; i-- {
+		var err error
+		if writes[i].previous == nil {
+			_, err = s.dataCollection.DeleteOne(ctx, bson.D{{"_id", writes[i].id}})
+		} else {
+			_, err = s.dataCollection.ReplaceOne(ctx, bson.D{{"_id", writes[i].id}}, writes[i].previous)
+			if err == nil {
+				_, err = s.historyCollection.DeleteOne(ctx, bson.D{{"_id", newRevision(*writes[i].previous).Key}})
+			}
+		}
+		if err != nil {
+			log.Err(err).Str("id", writes[i].id).Msg("failed to revert sync batch")
+		}
+	}
+}
+
+// isTransactionNotSupported checks if the error means mongo is not a replica set
+func isTransactionNotSupported(err error) bool {
+	var serverErr mongo.ServerError
+	return errors.As(err, &serverErr) && serverErr.HasErrorCode(illegalOperationCode)
+}
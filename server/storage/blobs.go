@@ -0,0 +1,523 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/gynshu-one/goph-keeper/server/storage/blobstore"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// blob is models.Blob as it is stored in blobs collection
+type blob struct {
+	ID        string `bson:"_id"`
+	OwnerID   string `bson:"owner_id"`
+	Size      int64  `bson:"size"`
+	Hash      string `bson:"hash"`
+	Offset    int64  `bson:"offset"`
+	CreatedAt int64  `bson:"created_at"`
+	// State is marshaled sha256 of content uploaded so far, so chunks are hashed as they come
+	State []byte `bson:"state"`
+}
+
+func (b blob) model() models.Blob {
+	return models.Blob{ID: b.ID, OwnerID: b.OwnerID, Size: b.Size, Hash: b.Hash, Offset: b.Offset, CreatedAt: b.CreatedAt}
+}
+
+// chunk is a part of blob content as it is stored in chunks collection
+type chunk struct {
+	// Key is BlobID and Offset joined with colon
+	Key    string `bson:"_id"`
+	BlobID string `bson:"blob_id"`
+	Offset int64  `bson:"offset"`
+	End    int64  `bson:"end"`
+	Data   []byte `bson:"data"`
+}
+
+// content is uploaded content as it is stored in contents collection, id is the hash of the content
+// the content itself is in Options.Blobs under Key, a new Key is used every time it's stored,
+// so content removed with its last reference never deletes the same content stored again
+type content struct {
+	Hash      string `bson:"_id"`
+	Key       string `bson:"key"`
+	Size      int64  `bson:"size"`
+	Refs      int64  `bson:"refs"`
+	CreatedAt int64  `bson:"created_at"`
+}
+
+// CreateBlob starts upload of the blob, creating the same blob again returns it with its Offset
+// blob with the content the owner has uploaded already is created complete
+// returns ErrBlobExists if the id is taken by a blob with other size, hash or owner
+func (s *storage) CreateBlob(ctx context.Context, model models.Blob) (models.Blob, error) {
+	doc := blob{ID: model.ID, OwnerID: model.OwnerID, Size: model.Size, Hash: model.Hash, CreatedAt: model.CreatedAt}
+	shared, err := s.shareContent(ctx, model)
+	if err != nil {
+		return models.Blob{}, err
+	}
+	if shared {
+		doc.Offset = doc.Size
+	}
+	_, err = s.blobCollection.InsertOne(ctx, doc)
+	if err == nil {
+		return doc.model(), nil
+	}
+	if shared {
+		if releaseErr := s.releaseContent(ctx, doc.Hash); releaseErr != nil {
+			log.Err(releaseErr).Str("hash", doc.Hash).Msg("failed to release blob content")
+		}
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return models.Blob{}, err
+	}
+	if err = s.blobCollection.FindOne(ctx, bson.D{{"_id", model.ID}}).Decode(&doc); err != nil {
+		return models.Blob{}, err
+	}
+	if !sameBlob(doc.model(), model) {
+		return models.Blob{}, ErrBlobExists
+	}
+	return doc.model(), nil
+}
+
+// GetBlob returns the blob of the user, ErrBlobNotFound if there is no such blob
+func (s *storage) GetBlob(ctx context.Context, userID, id string) (models.Blob, error) {
+	doc, err := s.findBlob(ctx, userID, id)
+	return doc.model(), err
+}
+
+// shareContent adds a reference to the content of the blob if the owner has uploaded it already
+func (s *storage) shareContent(ctx context.Context, model models.Blob) (bool, error) {
+	existing, err := s.FindBlob(ctx, model.OwnerID, model.Hash)
+	if errors.Is(err, ErrBlobNotFound) || err == nil && existing.Size != model.Size {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return s.refContent(ctx, model.Hash)
+}
+
+// FindBlob returns an uploaded blob of the user with the hash, ErrBlobNotFound if there is no such blob
+func (s *storage) FindBlob(ctx context.Context, userID, hash string) (models.Blob, error) {
+	var doc blob
+	err := s.blobCollection.FindOne(ctx, bson.D{
+		{"owner_id", userID},
+		{"hash", hash},
+		{"$expr", bson.D{{"$eq", bson.A{"$offset", "$size"}}}},
+	}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Blob{}, ErrBlobNotFound
+	}
+	return doc.model(), err
+}
+
+func (s *storage) findBlob(ctx context.Context, userID, id string) (doc blob, err error) {
+	err = s.blobCollection.FindOne(ctx, bson.D{{"_id", id}, {"owner_id", userID}}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return doc, ErrBlobNotFound
+	}
+	return doc, err
+}
+
+// WriteBlob writes the chunk at the offset and returns the blob with new Offset
+// chunk is stored first and the blob is moved forward only if nobody wrote at the offset concurrently
+// content that doesn't match the hash is dropped and ErrBlobHash is returned
+// with the last chunk the content is moved from chunks to Options.Blobs, unless it is stored there already
+func (s *storage) WriteBlob(ctx context.Context, userID, id string, offset int64, data []byte) (models.Blob, error) {
+	doc, err := s.findBlob(ctx, userID, id)
+	if err != nil {
+		return models.Blob{}, err
+	}
+	if err = checkWrite(doc.model(), offset, data); err != nil {
+		return doc.model(), err
+	}
+	state, hash, err := hashChunk(doc.State, data)
+	if err != nil {
+		return doc.model(), err
+	}
+
+	end := offset + int64(len(data))
+	if end == doc.Size {
+		if hash != doc.Hash {
+			return s.resetBlob(ctx, doc)
+		}
+		return s.completeBlob(ctx, doc, data)
+	}
+	_, err = s.chunkCollection.InsertOne(ctx, chunk{
+		Key:    id + ":" + strconv.FormatInt(offset, 10),
+		BlobID: id,
+		Offset: offset,
+		End:    end,
+		Data:   data,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return doc.model(), ErrBlobOffset
+	}
+	if err != nil {
+		return doc.model(), err
+	}
+
+	res, err := s.blobCollection.UpdateOne(ctx,
+		bson.D{{"_id", id}, {"offset", offset}},
+		bson.D{{"$set", bson.D{{"offset", end}, {"state", state}}}})
+	if err != nil {
+		return doc.model(), err
+	}
+	if res.MatchedCount == 0 {
+		return doc.model(), ErrBlobOffset
+	}
+	doc.Offset, doc.State = end, state
+	return doc.model(), nil
+}
+
+// completeBlob stores content of the blob from its chunks and the last chunk and marks the blob uploaded
+// the last chunk is not stored in chunks, so if storing fails it can be written again
+func (s *storage) completeBlob(ctx context.Context, doc blob, last []byte) (models.Blob, error) {
+	stored, err := s.refContent(ctx, doc.Hash)
+	if err != nil {
+		return doc.model(), err
+	}
+	if !stored {
+		res, err := s.chunkCollection.Find(ctx, bson.D{{"blob_id", doc.ID}}, options.Find().SetSort(bson.D{{"offset", 1}}))
+		if err != nil {
+			return doc.model(), err
+		}
+		err = s.storeContent(ctx, doc.Hash, doc.Size,
+			io.MultiReader(&chunkReader{ctx: ctx, cursor: res}, bytes.NewReader(last)))
+		if closeErr := res.Close(ctx); closeErr != nil {
+			log.Err(closeErr).Msg("failed to close cursor")
+		}
+		if err != nil {
+			return doc.model(), err
+		}
+	}
+
+	res, err := s.blobCollection.UpdateOne(ctx,
+		bson.D{{"_id", doc.ID}, {"offset", doc.Offset}},
+		bson.D{{"$set", bson.D{{"offset", doc.Size}, {"state", nil}}}})
+	if err == nil && res.MatchedCount == 0 {
+		// Completed concurrently, the content has its reference already
+		err = ErrBlobOffset
+	}
+	if err != nil {
+		if releaseErr := s.releaseContent(ctx, doc.Hash); releaseErr != nil {
+			log.Err(releaseErr).Str("hash", doc.Hash).Msg("failed to release blob content")
+		}
+		return doc.model(), err
+	}
+	if _, err = s.chunkCollection.DeleteMany(ctx, bson.D{{"blob_id", doc.ID}}); err != nil {
+		log.Err(err).Str("blob", doc.ID).Msg("failed to remove uploaded chunks")
+	}
+	doc.Offset, doc.State = doc.Size, nil
+	return doc.model(), nil
+}
+
+// resetBlob drops uploaded content of the blob that didn't match its hash
+func (s *storage) resetBlob(ctx context.Context, doc blob) (models.Blob, error) {
+	_, err := s.blobCollection.UpdateOne(ctx, bson.D{{"_id", doc.ID}},
+		bson.D{{"$set", bson.D{{"offset", 0}, {"state", nil}}}})
+	if err != nil {
+		return doc.model(), err
+	}
+	if _, err = s.chunkCollection.DeleteMany(ctx, bson.D{{"blob_id", doc.ID}}); err != nil {
+		return doc.model(), err
+	}
+	doc.Offset, doc.State = 0, nil
+	return doc.model(), ErrBlobHash
+}
+
+// refContent adds a reference to the content with the hash, false if it is not stored
+func (s *storage) refContent(ctx context.Context, hash string) (bool, error) {
+	err := s.contentCollection.FindOneAndUpdate(ctx, bson.D{{"_id", hash}},
+		bson.D{{"$inc", bson.D{{"refs", 1}}}}).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// storeContent puts the content to Options.Blobs and adds the first reference to it
+// if the same content was stored concurrently, that one is referenced and this one is removed
+func (s *storage) storeContent(ctx context.Context, hash string, size int64, data io.Reader) error {
+	key := contentKey(hash)
+	if err := putContent(ctx, s.opts.Blobs, key, hash, size, data); err != nil {
+		return err
+	}
+	res, err := s.contentCollection.UpdateOne(ctx, bson.D{{"_id", hash}},
+		bson.D{
+			{"$inc", bson.D{{"refs", 1}}},
+			{"$setOnInsert", bson.D{{"key", key}, {"size", size}, {"created_at", time.Now().Unix()}}},
+		},
+		options.Update().SetUpsert(true))
+	if err == nil && res.UpsertedCount == 1 {
+		return nil
+	}
+	if deleteErr := s.opts.Blobs.Delete(ctx, key); deleteErr != nil {
+		log.Err(deleteErr).Str("key", key).Msg("failed to remove blob content")
+	}
+	return err
+}
+
+// releaseContent removes a reference to the content with the hash
+// the content is removed when nothing refers to it anymore
+func (s *storage) releaseContent(ctx context.Context, hash string) error {
+	var c content
+	err := s.contentCollection.FindOneAndUpdate(ctx, bson.D{{"_id", hash}},
+		bson.D{{"$inc", bson.D{{"refs", -1}}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&c)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil || c.Refs > 0 {
+		return err
+	}
+	// Content that got a new reference meanwhile is kept
+	res, err := s.contentCollection.DeleteOne(ctx, bson.D{{"_id", hash}, {"key", c.Key}, {"refs", bson.D{{"$lte", 0}}}})
+	if err != nil || res.DeletedCount == 0 {
+		return err
+	}
+	return s.opts.Blobs.Delete(ctx, c.Key)
+}
+
+// chunkReader reads content of the chunks the cursor returns one after another
+type chunkReader struct {
+	ctx    context.Context
+	cursor *mongo.Cursor
+	buf    []byte
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if !r.cursor.Next(r.ctx) {
+			if err := r.cursor.Err(); err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+		var c chunk
+		if err := r.cursor.Decode(&c); err != nil {
+			return 0, err
+		}
+		r.buf = c.Data
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// ReadBlob returns up to length bytes of the blob content from the offset
+func (s *storage) ReadBlob(ctx context.Context, userID, id string, offset, length int64) ([]byte, error) {
+	doc, err := s.findBlob(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	end, err := checkRead(doc.model(), offset, length)
+	if err != nil {
+		return nil, err
+	}
+	var c content
+	err = s.contentCollection.FindOne(ctx, bson.D{{"_id", doc.Hash}}).Decode(&c)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("content of blob %s is missing", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return readContent(ctx, s.opts.Blobs, c.Key, offset, end)
+}
+
+// BlobsSize returns total size of blobs of the user including not uploaded ones
+// blobs that share content are counted once
+func (s *storage) BlobsSize(ctx context.Context, userID string) (int64, error) {
+	res, err := s.blobCollection.Find(ctx, bson.D{{"owner_id", userID}},
+		options.Find().SetProjection(bson.D{{"size", 1}, {"hash", 1}, {"offset", 1}}))
+	if err != nil {
+		return 0, err
+	}
+	defer func(res *mongo.Cursor, ctx context.Context) {
+		if closeErr := res.Close(ctx); closeErr != nil {
+			log.Err(closeErr).Msg("failed to close cursor")
+		}
+	}(res, ctx)
+
+	var blobs []models.Blob
+	for res.Next(ctx) {
+		var doc blob
+		if err = res.Decode(&doc); err != nil {
+			return 0, err
+		}
+		blobs = append(blobs, doc.model())
+	}
+	return blobsSize(blobs), res.Err()
+}
+
+// CollectBlobs removes blobs created before the given unix time that no model or previous version refers to
+// returns number of removed blobs
+func (s *storage) CollectBlobs(ctx context.Context, before int64) (count int64, err error) {
+	res, err := s.blobCollection.Find(ctx, bson.D{{"created_at", bson.D{{"$lt", before}}}},
+		options.Find().SetProjection(bson.D{{"state", 0}}))
+	if err != nil {
+		return 0, err
+	}
+	defer func(res *mongo.Cursor, ctx context.Context) {
+		if closeErr := res.Close(ctx); closeErr != nil {
+			log.Err(closeErr).Msg("failed to close cursor")
+		}
+	}(res, ctx)
+
+	for res.Next(ctx) {
+		var doc blob
+		if err = res.Decode(&doc); err != nil {
+			return count, err
+		}
+		used, err := s.blobUsed(ctx, doc)
+		if err != nil {
+			return count, err
+		}
+		if used {
+			continue
+		}
+		deleted, err := s.blobCollection.DeleteOne(ctx, bson.D{{"_id", doc.ID}, {"offset", doc.Offset}})
+		if err != nil {
+			return count, err
+		}
+		if deleted.DeletedCount == 0 {
+			// Chunk was written meanwhile, it's collected next time
+			continue
+		}
+		count++
+		if _, err = s.chunkCollection.DeleteMany(ctx, bson.D{{"blob_id", doc.ID}}); err != nil {
+			return count, err
+		}
+		if doc.model().Complete() {
+			if err = s.releaseContent(ctx, doc.Hash); err != nil {
+				return count, err
+			}
+		}
+	}
+	return count, res.Err()
+}
+
+// blobUsed checks if a model of the blob owner or its previous version refers to the blob
+func (s *storage) blobUsed(ctx context.Context, doc blob) (bool, error) {
+	for _, collection := range []*mongo.Collection{s.dataCollection, s.historyCollection} {
+		err := collection.FindOne(ctx, bson.D{{"owner_id", doc.OwnerID}, {"blobs", doc.ID}},
+			options.FindOne().SetProjection(bson.D{{"_id", 1}})).Err()
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// sameBlob checks if created blob is the same as the stored one, so its upload can be continued
+func sameBlob(stored, created models.Blob) bool {
+	return stored.OwnerID == created.OwnerID && stored.Size == created.Size && stored.Hash == created.Hash
+}
+
+// checkWrite checks that the chunk continues uploaded content of the blob and fits in it
+func checkWrite(blob models.Blob, offset int64, chunk []byte) error {
+	if offset != blob.Offset {
+		return fmt.Errorf("%w: uploaded %d bytes, chunk is at %d", ErrBlobOffset, blob.Offset, offset)
+	}
+	if len(chunk) == 0 || offset+int64(len(chunk)) > blob.Size {
+		return fmt.Errorf("%w: chunk of %d bytes at %d doesn't fit blob of %d bytes",
+			ErrBlobOffset, len(chunk), offset, blob.Size)
+	}
+	return nil
+}
+
+// checkRead checks that the blob is uploaded and the offset is in it,
+// returns the end of the part to read
+func checkRead(blob models.Blob, offset, length int64) (int64, error) {
+	if !blob.Complete() {
+		return 0, ErrBlobIncomplete
+	}
+	if offset < 0 || offset > blob.Size || length < 0 {
+		return 0, fmt.Errorf("%w: %d is out of blob of %d bytes", ErrBlobOffset, offset, blob.Size)
+	}
+	return min(blob.Size, offset+length), nil
+}
+
+// hashChunk continues sha256 with the state of content hashed before with the chunk
+// returns the new state and hex encoded hash of all content
+func hashChunk(state, chunk []byte) ([]byte, string, error) {
+	hash := sha256.New()
+	if len(state) > 0 {
+		if err := hash.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err != nil {
+			return nil, "", err
+		}
+	}
+	hash.Write(chunk)
+	state, err := hash.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return nil, "", err
+	}
+	return state, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// contentKey returns a new key to put content with the hash under
+func contentKey(hash string) string {
+	return hash + "-" + uuid.NewString()
+}
+
+// putContent puts the content to the backend checking it against the hash
+// content that doesn't match is removed and ErrBlobHash is returned
+func putContent(ctx context.Context, backend blobstore.Backend, key, hash string, size int64, data io.Reader) error {
+	sum := sha256.New()
+	if err := backend.Put(ctx, key, io.TeeReader(data, sum), size); err != nil {
+		return err
+	}
+	if hex.EncodeToString(sum.Sum(nil)) != hash {
+		if err := backend.Delete(ctx, key); err != nil {
+			log.Err(err).Str("key", key).Msg("failed to remove blob content")
+		}
+		return ErrBlobHash
+	}
+	return nil
+}
+
+// readContent reads the part of content from offset to end from the backend
+func readContent(ctx context.Context, backend blobstore.Backend, key string, offset, end int64) ([]byte, error) {
+	if offset == end {
+		return []byte{}, nil
+	}
+	data, err := backend.Get(ctx, key, offset, end-offset)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) != end-offset {
+		return nil, fmt.Errorf("content %s has %d bytes from %d, want %d", key, len(data), offset, end-offset)
+	}
+	return data, nil
+}
+
+// blobsSize returns total size of the blobs, uploaded blobs with the same hash are counted once
+func blobsSize(blobs []models.Blob) (size int64) {
+	counted := make(map[string]bool)
+	for _, blob := range blobs {
+		if blob.Complete() {
+			if counted[blob.Hash] {
+				continue
+			}
+			counted[blob.Hash] = true
+		}
+		size += blob.Size
+	}
+	return size
+}
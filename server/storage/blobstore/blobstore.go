@@ -0,0 +1,31 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned by Backend.Get when there is no object with the given key
+var ErrNotFound = errors.New("blob content not found")
+
+// Backend keeps objects written once and read in parts
+// objects are never changed after Put, storage writes new content under a new key
+type Backend interface {
+	// Put stores size bytes read from content under the key
+	Put(ctx context.Context, key string, content io.Reader, size int64) error
+	// Get returns up to length bytes of the object from the offset
+	// returns ErrNotFound if there is no object with the key
+	Get(ctx context.Context, key string, offset, length int64) ([]byte, error)
+	// Delete removes the object, removing missing object is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// readContent reads exactly size bytes of content, so backends don't store truncated objects
+func readContent(content io.Reader, size int64) ([]byte, error) {
+	data := make([]byte, size)
+	if _, err := io.ReadFull(content, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,112 @@
+package blobstore_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gynshu-one/goph-keeper/server/storage/blobstore"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testBackend checks that the backend stores, reads in parts and deletes objects
+func testBackend(t *testing.T, backend blobstore.Backend) {
+	ctx := context.Background()
+	content := bytes.Repeat([]byte("0123456789"), 100)
+
+	if _, err := backend.Get(ctx, "missing", 0, 10); !errors.Is(err, blobstore.ErrNotFound) {
+		t.Errorf("Expected %v, got %v", blobstore.ErrNotFound, err)
+	}
+	if err := backend.Put(ctx, "key1", bytes.NewReader(content), int64(len(content))); err != nil {
+		t.Fatalf("Put returned an error: %v", err)
+	}
+	// Content shorter than size is not stored
+	if err := backend.Put(ctx, "short", bytes.NewReader(content[:10]), 20); err == nil {
+		t.Error("Put of short content didn't fail")
+	}
+	if _, err := backend.Get(ctx, "short", 0, 10); !errors.Is(err, blobstore.ErrNotFound) {
+		t.Errorf("Expected short content not stored, got %v", err)
+	}
+
+	part, err := backend.Get(ctx, "key1", 995, 3)
+	if err != nil || string(part) != "567" {
+		t.Errorf("Expected part 567, got %q and %v", part, err)
+	}
+	tail, err := backend.Get(ctx, "key1", 990, 100)
+	if err != nil || !bytes.Equal(tail, content[990:]) {
+		t.Errorf("Expected the tail, got %q and %v", tail, err)
+	}
+	whole, err := backend.Get(ctx, "key1", 0, int64(len(content)))
+	if err != nil || !bytes.Equal(whole, content) {
+		t.Errorf("Expected the whole content, got %d bytes and %v", len(whole), err)
+	}
+
+	if err = backend.Delete(ctx, "key1"); err != nil {
+		t.Fatalf("Delete returned an error: %v", err)
+	}
+	if _, err = backend.Get(ctx, "key1", 0, 10); !errors.Is(err, blobstore.ErrNotFound) {
+		t.Errorf("Expected deleted object not found, got %v", err)
+	}
+	if err = backend.Delete(ctx, "key1"); err != nil {
+		t.Errorf("Delete of missing object returned an error: %v", err)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	testBackend(t, blobstore.NewMemory())
+}
+
+func TestFS(t *testing.T) {
+	backend, err := blobstore.NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testBackend(t, backend)
+}
+
+func TestS3(t *testing.T) {
+	server := newFakeS3(t, "access", "bucket")
+	backend := blobstore.NewS3(blobstore.S3Options{
+		Endpoint:  server.URL,
+		Region:    "us-east-1",
+		Bucket:    "bucket",
+		AccessKey: "access",
+		SecretKey: "secret",
+	})
+	if err := backend.EnsureBucket(context.Background()); err != nil {
+		t.Fatalf("EnsureBucket returned an error: %v", err)
+	}
+	// Existing bucket is fine
+	if err := backend.EnsureBucket(context.Background()); err != nil {
+		t.Fatalf("EnsureBucket of existing bucket returned an error: %v", err)
+	}
+	testBackend(t, backend)
+}
+
+// TestGridFS runs against a real MongoDB, set GOPH_KEEPER_TEST_MONGO_URI to enable it
+func TestGridFS(t *testing.T) {
+	uri := os.Getenv("GOPH_KEEPER_TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("GOPH_KEEPER_TEST_MONGO_URI is not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+	defer func() {
+		_ = client.Disconnect(context.Background())
+	}()
+	db := client.Database("gk-test-" + strings.ReplaceAll(uuid.NewString(), "-", ""))
+	defer func() {
+		_ = db.Drop(context.Background())
+	}()
+	testBackend(t, blobstore.NewGridFS(db))
+}
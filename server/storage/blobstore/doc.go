@@ -0,0 +1,5 @@
+// Package blobstore provides backends that keep content of uploaded files for storage.Storage
+// storage decides what is stored under which key, backends only put, read and delete whole objects.
+// NewMemory keeps objects in memory and is used in tests, NewFS keeps them in a directory,
+// NewGridFS in MongoDB GridFS and NewS3 in any S3-compatible object storage (MinIO is used as a local stand-in).
+package blobstore
@@ -0,0 +1,100 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// fs is a Backend that keeps every object in a file of the directory
+type fs struct {
+	dir string
+}
+
+// NewFS returns a Backend that keeps objects in files of dir, the directory is created if it doesn't exist
+func NewFS(dir string) (*fs, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &fs{dir: dir}, nil
+}
+
+// path returns the file of the key, files are spread by the first two characters of the key
+// so a directory doesn't get too many entries
+func (f *fs) path(key string) string {
+	key = filepath.Base(key)
+	if len(key) < 2 {
+		return filepath.Join(f.dir, key)
+	}
+	return filepath.Join(f.dir, key[:2], key)
+}
+
+// Put writes content to a temporary file and renames it, so readers never see a partial object
+func (f *fs) Put(ctx context.Context, key string, content io.Reader, size int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	path := f.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Does nothing if the file was renamed
+		_ = os.Remove(file.Name())
+	}()
+
+	written, err := io.Copy(file, io.LimitReader(content, size))
+	if err == nil && written != size {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// Get returns up to length bytes of the object from the offset
+func (f *fs) Get(ctx context.Context, key string, offset, length int64) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	file, err := os.Open(f.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	data := make([]byte, length)
+	n, err := file.ReadAt(data, offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+// Delete removes the file of the object
+func (f *fs) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err := os.Remove(f.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
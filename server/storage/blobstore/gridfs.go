@@ -0,0 +1,105 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// gridFSBucketName is the name of GridFS bucket objects are kept in
+const gridFSBucketName = "blob-content"
+
+// gridFS is a Backend that keeps objects in MongoDB GridFS, key is the id of GridFS file
+type gridFS struct {
+	db *mongo.Database
+}
+
+// NewGridFS returns a Backend that keeps objects in GridFS of the database
+func NewGridFS(db *mongo.Database) *gridFS {
+	return &gridFS{db: db}
+}
+
+// bucket returns GridFS bucket with deadlines of ctx
+// deadlines are set on the bucket, so every call gets its own one
+func (g *gridFS) bucket(ctx context.Context) (*gridfs.Bucket, error) {
+	bucket, err := gridfs.NewBucket(g.db, options.GridFSBucket().SetName(gridFSBucketName))
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err = bucket.SetReadDeadline(deadline); err != nil {
+			return nil, err
+		}
+		if err = bucket.SetWriteDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+	return bucket, nil
+}
+
+// Put uploads content as GridFS file with the key as its id
+func (g *gridFS) Put(ctx context.Context, key string, content io.Reader, size int64) error {
+	bucket, err := g.bucket(ctx)
+	if err != nil {
+		return err
+	}
+	stream, err := bucket.OpenUploadStreamWithID(key, key)
+	if err != nil {
+		return err
+	}
+	written, err := io.Copy(stream, io.LimitReader(content, size))
+	if err == nil && written != size {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		// Chunks written so far are removed
+		_ = stream.Abort()
+		return err
+	}
+	return stream.Close()
+}
+
+// Get returns up to length bytes of the object from the offset, chunks before the offset are skipped
+func (g *gridFS) Get(ctx context.Context, key string, offset, length int64) ([]byte, error) {
+	bucket, err := g.bucket(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := bucket.OpenDownloadStream(key)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = stream.Close()
+	}()
+
+	if _, err = stream.Skip(offset); err != nil {
+		return nil, err
+	}
+	data := make([]byte, length)
+	n, err := io.ReadFull(stream, data)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+// Delete removes GridFS file and its chunks
+func (g *gridFS) Delete(ctx context.Context, key string) error {
+	bucket, err := g.bucket(ctx)
+	if err != nil {
+		return err
+	}
+	err = bucket.DeleteContext(ctx, key)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,60 @@
+package blobstore
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// memory is a Backend that keeps objects in a map
+type memory struct {
+	mu      sync.RWMutex
+	objects map[string][]byte
+}
+
+// NewMemory returns a Backend that keeps objects in memory
+func NewMemory() *memory {
+	return &memory{objects: make(map[string][]byte)}
+}
+
+// Put stores size bytes read from content under the key
+func (m *memory) Put(ctx context.Context, key string, content io.Reader, size int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	data, err := readContent(content, size)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = data
+	return nil
+}
+
+// Get returns up to length bytes of the object from the offset
+func (m *memory) Get(ctx context.Context, key string, offset, length int64) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, ok := m.objects[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	offset = min(offset, int64(len(data)))
+	end := min(offset+length, int64(len(data)))
+	return append([]byte(nil), data[offset:end]...), nil
+}
+
+// Delete removes the object
+func (m *memory) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, key)
+	return nil
+}
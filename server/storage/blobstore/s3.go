@@ -0,0 +1,198 @@
+package blobstore
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// unsignedPayload is sent instead of hash of the body, so content is streamed without reading it twice
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3Options are the settings of S3-compatible object storage
+type S3Options struct {
+	// Endpoint is the url of the storage, e.g. https://s3.eu-central-1.amazonaws.com or http://localhost:9000
+	Endpoint string
+	// Region is the region requests are signed for, MinIO accepts us-east-1
+	Region string
+	// Bucket is the bucket objects are kept in, it is addressed in the path, so it works with MinIO
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// Client sends the requests, http.DefaultClient if nil
+	Client *http.Client
+}
+
+// s3 is a Backend that keeps objects in S3-compatible object storage
+// requests are signed with AWS Signature Version 4
+type s3 struct {
+	opts S3Options
+	now  func() time.Time
+}
+
+// NewS3 returns a Backend that keeps objects in the bucket of S3-compatible storage
+func NewS3(opts S3Options) *s3 {
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	opts.Endpoint = strings.TrimSuffix(opts.Endpoint, "/")
+	return &s3{opts: opts, now: time.Now}
+}
+
+// EnsureBucket creates the bucket if it doesn't exist, it is safe to call it on every start
+func (s *s3) EnsureBucket(ctx context.Context) error {
+	res, err := s.do(ctx, http.MethodPut, "", nil, -1, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	// 409 means the bucket exists
+	if res.StatusCode == http.StatusConflict {
+		return nil
+	}
+	return checkResponse(res, http.MethodPut, s.opts.Bucket)
+}
+
+// Put uploads content as one object
+func (s *s3) Put(ctx context.Context, key string, content io.Reader, size int64) error {
+	res, err := s.do(ctx, http.MethodPut, key, io.LimitReader(content, size), size, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return checkResponse(res, http.MethodPut, key)
+}
+
+// Get returns up to length bytes of the object from the offset, only that range is downloaded
+func (s *s3) Get(ctx context.Context, key string, offset, length int64) ([]byte, error) {
+	if length <= 0 {
+		return []byte{}, nil
+	}
+	header := http.Header{"Range": {fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)}}
+	res, err := s.do(ctx, http.MethodGet, key, nil, -1, header)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	case http.StatusRequestedRangeNotSatisfiable:
+		// Offset is at the end of the object
+		return []byte{}, nil
+	}
+	if err = checkResponse(res, http.MethodGet, key); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(io.LimitReader(res.Body, length))
+}
+
+// Delete removes the object, S3 doesn't fail on missing objects
+func (s *s3) Delete(ctx context.Context, key string) error {
+	res, err := s.do(ctx, http.MethodDelete, key, nil, -1, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return checkResponse(res, http.MethodDelete, key)
+}
+
+// do sends signed request for the object with the key, or for the bucket if key is empty
+// size is the length of the body, -1 if there is no body
+func (s *s3) do(ctx context.Context, method, key string, body io.Reader, size int64, header http.Header) (*http.Response, error) {
+	path := "/" + uriEncode(s.opts.Bucket)
+	if key != "" {
+		path += "/" + uriEncode(key)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.opts.Endpoint+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.URL.RawPath = path
+	if size >= 0 {
+		req.ContentLength = size
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	s.sign(req)
+	return s.opts.Client.Do(req)
+}
+
+// sign adds AWS Signature Version 4 to the request
+// see https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func (s *s3) sign(req *http.Request) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": unsignedPayload,
+		"x-amz-date":           amzDate,
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		unsignedPayload,
+	}, "\n")
+	scope := date + "/" + s.opts.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.opts.SecretKey), date)
+	key = hmacSHA256(key, s.opts.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.opts.AccessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// uriEncode encodes everything except unreserved characters, as signature requires
+func uriEncode(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// checkResponse returns error with the message of the storage if the request failed
+func checkResponse(res *http.Response, method, key string) error {
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return nil
+	}
+	message, _ := io.ReadAll(io.LimitReader(res.Body, 1<<10))
+	return fmt.Errorf("s3 %s %s failed with %s: %s", method, key, res.Status, message)
+}
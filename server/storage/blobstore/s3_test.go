@@ -0,0 +1,81 @@
+package blobstore_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newFakeS3 starts a tiny S3-compatible server with path-style buckets
+// it checks that requests are signed by the access key and keeps objects in memory
+func newFakeS3(t *testing.T, accessKey, bucket string) *httptest.Server {
+	var mu sync.Mutex
+	created := false
+	objects := make(map[string][]byte)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential="+accessKey+"/") ||
+			!strings.Contains(auth, "/us-east-1/s3/aws4_request") || r.Header.Get("X-Amz-Date") == "" {
+			http.Error(w, "AccessDenied", http.StatusForbidden)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+
+		path := strings.TrimPrefix(r.URL.Path, "/")
+		name, key, _ := strings.Cut(path, "/")
+		if name != bucket {
+			http.Error(w, "NoSuchBucket", http.StatusNotFound)
+			return
+		}
+		if key == "" {
+			if created {
+				http.Error(w, "BucketAlreadyOwnedByYou", http.StatusConflict)
+				return
+			}
+			created = true
+			return
+		}
+		if !created {
+			http.Error(w, "NoSuchBucket", http.StatusNotFound)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPut:
+			data, err := io.ReadAll(r.Body)
+			if err != nil || int64(len(data)) != r.ContentLength {
+				http.Error(w, "IncompleteBody", http.StatusBadRequest)
+				return
+			}
+			objects[key] = data
+		case http.MethodGet:
+			data, ok := objects[key]
+			if !ok {
+				http.Error(w, "NoSuchKey", http.StatusNotFound)
+				return
+			}
+			var start, end int
+			if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end); err != nil {
+				_, _ = w.Write(data)
+				return
+			}
+			if start >= len(data) {
+				http.Error(w, "InvalidRange", http.StatusRequestedRangeNotSatisfiable)
+				return
+			}
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write(data[start:min(end+1, len(data))])
+		case http.MethodDelete:
+			delete(objects, key)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
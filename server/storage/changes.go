@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// feed is the state of user's change feed
+type feed struct {
+	OwnerID string `bson:"_id"`
+	// Seq is the last assigned position
+	Seq int64 `bson:"seq"`
+	// PurgedSeq is the last position of purged models,
+	// changes before it can't be listed anymore
+	PurgedSeq int64 `bson:"purged_seq"`
+}
+
+// nextSeq assigns the next position in the user's change feed
+func (s *storage) nextSeq(ctx context.Context, userID string) (int64, error) {
+	var f feed
+	err := s.seqCollection.FindOneAndUpdate(ctx,
+		bson.D{{"_id", userID}},
+		bson.D{{"$inc", bson.D{{"seq", 1}}}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&f)
+	return f.Seq, err
+}
+
+// getFeed returns the state of user's change feed, zero feed if the user has no changes yet
+func (s *storage) getFeed(ctx context.Context, userID string) (feed, error) {
+	var f feed
+	err := s.seqCollection.FindOne(ctx, bson.D{{"_id", userID}}).Decode(&f)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return feed{OwnerID: userID}, nil
+	}
+	return f, err
+}
+
+// GetChanges returns the data of the user changed after the given position in the user's change feed
+// if the changes are not known anymore, e.g. some of them were purged, all data is returned with Reset set
+func (s *storage) GetChanges(ctx context.Context, userID string, since int64) (changes Changes, err error) {
+	// Cursor is read first, so changes written meanwhile are sent again next time rather than lost
+	f, err := s.getFeed(ctx, userID)
+	if err != nil {
+		return changes, err
+	}
+	changes.Cursor = f.Seq
+
+	if since <= 0 || since > f.Seq {
+		return s.allChanges(ctx, userID, changes)
+	}
+
+	res, err := s.dataCollection.Find(ctx,
+		bson.D{{"owner_id", userID}, {"seq", bson.D{{"$gt", since}}}},
+		options.Find().SetSort(bson.D{{"seq", 1}}))
+	if err != nil {
+		return changes, err
+	}
+	defer func(res *mongo.Cursor, ctx context.Context) {
+		if closeErr := res.Close(ctx); closeErr != nil {
+			log.Err(closeErr).Msg("failed to close cursor")
+		}
+	}(res, ctx)
+	if err = res.All(ctx, &changes.Data); err != nil {
+		return changes, err
+	}
+
+	// Purged models are marked in the feed before they are removed,
+	// so reading the feed again tells whether some changes are missing from the result
+	f, err = s.getFeed(ctx, userID)
+	if err != nil {
+		return changes, err
+	}
+	if f.PurgedSeq > since {
+		return s.allChanges(ctx, userID, changes)
+	}
+	return changes, nil
+}
+
+// CheckChanges returns Cursor and Reset of the changes after the given position without Data
+// resume means since is a checkpoint of interrupted listing of all data,
+// so it is continued rather than reset again by purged changes
+func (s *storage) CheckChanges(ctx context.Context, userID string, since int64, resume bool) (changes Changes, err error) {
+	f, err := s.getFeed(ctx, userID)
+	if err != nil {
+		return changes, err
+	}
+	changes.Cursor = f.Seq
+	changes.Reset = since <= 0 || since > f.Seq || !resume && f.PurgedSeq > since
+	return changes, nil
+}
+
+// eachChangeBatchSize is how many models are read from database at once by EachChange
+const eachChangeBatchSize = 100
+
+// EachChange calls fn for every model of the user with Seq after the given position in order of Seq
+// models are read one by one, so memory use doesn't depend on the size of user's data
+// it stops and returns the error fn returns
+func (s *storage) EachChange(ctx context.Context, userID string, after int64, fn func(data models.DataWrapper) error) error {
+	res, err := s.dataCollection.Find(ctx,
+		bson.D{{"owner_id", userID}, {"seq", bson.D{{"$gt", after}}}},
+		options.Find().SetSort(bson.D{{"seq", 1}}).SetBatchSize(eachChangeBatchSize))
+	if err != nil {
+		return err
+	}
+	defer func(res *mongo.Cursor, ctx context.Context) {
+		if closeErr := res.Close(ctx); closeErr != nil {
+			log.Err(closeErr).Msg("failed to close cursor")
+		}
+	}(res, ctx)
+
+	for res.Next(ctx) {
+		var data models.DataWrapper
+		if err = res.Decode(&data); err != nil {
+			return err
+		}
+		if err = fn(data); err != nil {
+			return err
+		}
+	}
+	return res.Err()
+}
+
+// allChanges returns all data of the user as changes that reset client's data
+func (s *storage) allChanges(ctx context.Context, userID string, changes Changes) (Changes, error) {
+	data, err := s.GetData(ctx, userID)
+	if err != nil {
+		return changes, err
+	}
+	changes.Data = data
+	changes.Reset = true
+	return changes, nil
+}
+
+// markPurged moves purged position of the user's change feed to the position of purged model
+func (s *storage) markPurged(ctx context.Context, userID string, seq int64) error {
+	_, err := s.seqCollection.UpdateOne(ctx,
+		bson.D{{"_id", userID}},
+		bson.D{{"$max", bson.D{{"purged_seq", seq}}}},
+		options.Update().SetUpsert(true))
+	return err
+}
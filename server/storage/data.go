@@ -2,41 +2,117 @@ package storage
 
 import (
 	"context"
+	"errors"
+
 	"github.com/gynshu-one/goph-keeper/common/models"
 	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // SetData sets the model with the given id
-// if {it exists and update date of new data is newer it will be updated}, {otherwise it will be created.}
-func (s *storage) SetData(ctx context.Context, data models.DataWrapper) error {
-	// Create a new document in mongo
-	_, err := s.dataCollection.InsertOne(ctx, data)
+// if {it exists and new data is an edit of the stored revision it will be updated}, {otherwise it will be created.}
+// Revision is assigned by the storage, previous version of updated model is moved to history
+// models that were purged are rejected
+func (s *storage) SetData(ctx context.Context, data models.DataWrapper) (models.SyncResult, error) {
+	result, _, err := s.setData(ctx, data)
+	return result, err
+}
+
+// setData does the same as SetData and also returns the version of the model it replaced,
+// nil if the model was created or not applied
+func (s *storage) setData(ctx context.Context, data models.DataWrapper) (models.SyncResult, *models.DataWrapper, error) {
+	result := models.SyncResult{ID: data.ID}
+
+	// Client that was offline for a long time may still have the model that was purged
+	purged, err := s.isPurged(ctx, data.ID)
 	if err != nil {
-		if mongo.IsDuplicateKeyError(err) {
-			// We have it already
-			// So if the owner id and update time are valid, update it
-			filter := bson.D{
-				{"_id", data.ID},
-				{"owner_id", data.OwnerID},
-				{"updated_at", bson.D{{"$lt", data.UpdatedAt}}}}
-
-			update := bson.D{
-				{"$set", bson.D{
-					{"data", data.Data},
-					{"name", data.Name},
-					{"updated_at", data.UpdatedAt},
-					{"deleted_at", data.DeletedAt},
-				}},
-			}
-			_, err = s.dataCollection.UpdateOne(ctx, filter, update)
+		return result, nil, err
+	}
+	if purged {
+		result.Status, result.Reason = models.StatusRejected, reasonPurged
+		return result, nil, nil
+	}
+
+	for {
+		var stored models.DataWrapper
+		err = s.dataCollection.FindOne(ctx, bson.D{{"_id", data.ID}}).Decode(&stored)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// Create a new document in mongo
+			data.Revision = 1
+			data.Seq, err = s.nextSeq(ctx, data.OwnerID)
 			if err != nil {
-				return err
+				return result, nil, err
+			}
+			_, err = s.dataCollection.InsertOne(ctx, data)
+			if err == nil {
+				result.Status = models.StatusApplied
+				return result, nil, nil
 			}
+			if mongo.IsDuplicateKeyError(err) {
+				// Created concurrently, compare with it
+				continue
+			}
+		}
+		if err != nil {
+			return result, nil, err
+		}
+
+		// We have it already
+		// So if the owner id and update time are valid, update it
+		if compared := compare(stored, data); compared.Status != "" {
+			return compared, nil, nil
+		}
+
+		seq, err := s.nextSeq(ctx, data.OwnerID)
+		if err != nil {
+			return result, nil, err
+		}
+
+		// Update only the version we compared with
+		filter := bson.D{
+			{"_id", data.ID},
+			{"owner_id", data.OwnerID},
+			{"revision", stored.Revision}}
+
+		set := bson.D{
+			{"data", data.Data},
+			{"blobs", data.Blobs},
+			{"key", data.Key},
+			{"collection_id", data.CollectionID},
+			{"folder_id", data.FolderID},
+			{"name", data.Name},
+			{"updated_at", data.UpdatedAt},
+			{"deleted_at", data.DeletedAt},
+			{"seq", seq},
+		}
+		sealed := sealsLabel(stored, data)
+		if sealed {
+			set = append(set, bson.E{"type", data.Type}, bson.E{"label_version", data.LabelVersion})
+		}
+		update := bson.D{
+			{"$set", set},
+			{"$inc", bson.D{{"revision", 1}}},
+		}
+
+		// Get the document as it was before the update to keep it in history
+		var previous models.DataWrapper
+		err = s.dataCollection.FindOneAndUpdate(ctx, filter, update,
+			options.FindOneAndUpdate().SetReturnDocument(options.Before)).Decode(&previous)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// Updated concurrently, compare with the new version
+			continue
 		}
+		if err != nil {
+			return result, nil, err
+		}
+		result.Status = models.StatusApplied
+		if sealed {
+			return result, &previous, s.purgeHistory(ctx, previous)
+		}
+		return result, &previous, s.archive(ctx, previous)
 	}
-	return err
 }
 
 // GetData returns the all data from database associated with the given user id.
@@ -58,3 +134,55 @@ func (s *storage) GetData(ctx context.Context, userID string) (result []models.D
 	}
 	return result, err
 }
+
+// GetDataByIDs returns the data of the user with the given ids, ids the user has no data with are skipped
+func (s *storage) GetDataByIDs(ctx context.Context, userID string, ids []string) (result []models.DataWrapper, err error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	res, err := s.dataCollection.Find(ctx, bson.D{{"_id", bson.D{{"$in", ids}}}, {"owner_id", userID}})
+	if err != nil {
+		return nil, err
+	}
+	defer func(res *mongo.Cursor, ctx context.Context) {
+		if closeErr := res.Close(ctx); closeErr != nil {
+			log.Err(closeErr).Msg("failed to close cursor")
+		}
+	}(res, ctx)
+	err = res.All(ctx, &result)
+	return result, err
+}
+
+// DataUsage returns Items and Bytes of not deleted data of the user
+// sizes are summed by the database, so the data is not loaded
+func (s *storage) DataUsage(ctx context.Context, userID string) (models.Usage, error) {
+	// Same sum as models.ItemSize
+	size := bson.D{{"$add", bson.A{
+		bson.D{{"$ifNull", bson.A{bson.D{{"$binarySize", "$data"}}, 0}}},
+		bson.D{{"$strLenBytes", bson.D{{"$ifNull", bson.A{"$name", ""}}}}},
+		bson.D{{"$ifNull", bson.A{bson.D{{"$binarySize", "$key"}}, 0}}},
+	}}}
+	res, err := s.dataCollection.Aggregate(ctx, mongo.Pipeline{
+		{{"$match", bson.D{{"owner_id", userID}, {"deleted_at", 0}}}},
+		{{"$group", bson.D{{"_id", nil}, {"items", bson.D{{"$sum", 1}}}, {"bytes", bson.D{{"$sum", size}}}}}},
+	})
+	if err != nil {
+		return models.Usage{}, err
+	}
+	defer func(res *mongo.Cursor, ctx context.Context) {
+		if closeErr := res.Close(ctx); closeErr != nil {
+			log.Err(closeErr).Msg("failed to close cursor")
+		}
+	}(res, ctx)
+
+	var usage struct {
+		Items int64 `bson:"items"`
+		Bytes int64 `bson:"bytes"`
+	}
+	if res.Next(ctx) {
+		if err = res.Decode(&usage); err != nil {
+			return models.Usage{}, err
+		}
+	}
+	return models.Usage{Items: usage.Items, Bytes: usage.Bytes}, res.Err()
+}
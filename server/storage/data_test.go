@@ -17,10 +17,15 @@ func TestSetData(t *testing.T) {
 	mt := mtest.New(t, opts)
 	defer mt.Close()
 	mt.Run("test", func(mt *mtest.T) {
-		mt.AddMockResponses(bson.D{{"ok", 1}})
+		// Purged and stored lookups find nothing, seq is assigned, then insert succeeds
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "test.user-data-purged", mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, "test.user-data", mtest.FirstBatch),
+			bson.D{{"ok", 1}, {"value", bson.D{{"_id", "user1"}, {"seq", 1}}}},
+			bson.D{{"ok", 1}})
 		db := mt.Client.Database("test")
 
-		s := NewStorage(db.Collection("user-data"), db.Collection("users"))
+		s := NewStorage(db, Options{})
 		data := models.DataWrapper{
 			ID:        "123456",
 			OwnerID:   "user1",
@@ -28,10 +33,13 @@ func TestSetData(t *testing.T) {
 			Name:      "My data",
 			UpdatedAt: time.Now().Unix(),
 		}
-		err := s.SetData(ctx, data)
+		result, err := s.SetData(ctx, data)
 		if err != nil {
 			t.Errorf("expected no error, got %v", err)
 		}
+		if result.Status != models.StatusApplied {
+			t.Errorf("expected applied status, got %s", result.Status)
+		}
 	})
 }
 
@@ -43,7 +51,7 @@ func TestGetData(t *testing.T) {
 	defer mt.Close()
 	mt.Run("test", func(mt *mtest.T) {
 		db := mt.Client.Database("test")
-		s := NewStorage(db.Collection("user-data"), db.Collection("users"))
+		s := NewStorage(db, Options{})
 		mt.AddMockResponses(mtest.CreateCursorResponse(1,
 			"DBName.CollectionName",
 			mtest.FirstBatch, bson.D{
@@ -1,5 +1,7 @@
-// Package storage provides a simple in-memory storage for the goph-keeper service.
-// Every  operation is performed on the cache and asynchronously on the database.
+// Package storage provides storages for the goph-keeper service.
+// NewStorage is backed by MongoDB, NewMemoryStorage keeps everything in memory and is used in tests.
+// Both must pass the conformance suite from the storagetest package.
+// Content of uploaded files is kept once per hash in a backend from the blobstore package.
 // Storage itself, does not know Which exact model it is storing, it only knows that it is storing models.DataWrapper.
 // Sensitive fields are encrypted by client before sending to the server.
 package storage
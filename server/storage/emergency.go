@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// emergency is models.EmergencyAccess stored in mongo, a user is a trusted contact of a grantor once
+type emergency struct {
+	ID                     string `bson:"_id"`
+	models.EmergencyAccess `bson:",inline"`
+}
+
+// emergencyID returns id of the trusted contact of the grantor
+func emergencyID(grantorID, granteeID string) string {
+	return grantorID + ":" + granteeID
+}
+
+// SetEmergency adds the contact of the grantor or replaces its waiting period, key and state of its request
+func (s *storage) SetEmergency(ctx context.Context, access models.EmergencyAccess) error {
+	_, err := s.emergency.UpdateOne(ctx, bson.D{{"_id", emergencyID(access.GrantorID, access.GranteeID)}},
+		bson.D{
+			{"$set", bson.D{
+				{"wait_days", access.WaitDays},
+				{"key", access.Key},
+				{"status", access.Status},
+				{"requested_at", access.RequestedAt},
+			}},
+			{"$setOnInsert", bson.D{
+				{"grantor_id", access.GrantorID},
+				{"grantee_id", access.GranteeID},
+				{"created_at", access.CreatedAt},
+			}},
+		},
+		options.Update().SetUpsert(true))
+	return err
+}
+
+// GetEmergency returns the contact of the grantor, ErrEmergencyNotFound if there is no such contact
+func (s *storage) GetEmergency(ctx context.Context, grantorID, granteeID string) (models.EmergencyAccess, error) {
+	var stored emergency
+	err := s.emergency.FindOne(ctx, bson.D{{"_id", emergencyID(grantorID, granteeID)}}).Decode(&stored)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.EmergencyAccess{}, ErrEmergencyNotFound
+	}
+	return stored.EmergencyAccess, err
+}
+
+// GetEmergencyContacts returns trusted contacts of the grantor, oldest first
+func (s *storage) GetEmergencyContacts(ctx context.Context, grantorID string) ([]models.EmergencyAccess, error) {
+	return s.findEmergency(ctx, bson.D{{"grantor_id", grantorID}})
+}
+
+// GetEmergencyGrantors returns users that trust the grantee, oldest first
+func (s *storage) GetEmergencyGrantors(ctx context.Context, granteeID string) ([]models.EmergencyAccess, error) {
+	return s.findEmergency(ctx, bson.D{{"grantee_id", granteeID}})
+}
+
+// DeleteEmergency removes the contact of the grantor, ErrEmergencyNotFound if there is no such contact
+func (s *storage) DeleteEmergency(ctx context.Context, grantorID, granteeID string) error {
+	res, err := s.emergency.DeleteOne(ctx, bson.D{{"_id", emergencyID(grantorID, granteeID)}})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrEmergencyNotFound
+	}
+	return nil
+}
+
+// findEmergency returns trusted contacts that match the filter, oldest first
+func (s *storage) findEmergency(ctx context.Context, filter bson.D) ([]models.EmergencyAccess, error) {
+	cursor, err := s.emergency.Find(ctx, filter,
+		options.Find().SetSort(bson.D{{"created_at", 1}, {"_id", 1}}))
+	if err != nil {
+		return nil, err
+	}
+	var stored []emergency
+	if err = cursor.All(ctx, &stored); err != nil {
+		return nil, err
+	}
+	result := make([]models.EmergencyAccess, len(stored))
+	for i, model := range stored {
+		result[i] = model.EmergencyAccess
+	}
+	return result, nil
+}
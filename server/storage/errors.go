@@ -0,0 +1,39 @@
+package storage
+
+import "errors"
+
+var (
+	// ErrUserExists is returned by CreateUser when the email is already registered
+	ErrUserExists = errors.New("user already exists")
+	// ErrUserNotFound is returned by GetUser when there is no user with the given email
+	ErrUserNotFound = errors.New("user not found")
+	// ErrRevisionNotFound is returned by GetRevision when there is no such version of the model
+	ErrRevisionNotFound = errors.New("revision not found")
+	// ErrBlobNotFound is returned when the user has no blob with the given id
+	ErrBlobNotFound = errors.New("blob not found")
+	// ErrBlobExists is returned by CreateBlob when the id is taken by another blob
+	ErrBlobExists = errors.New("blob with this id already exists")
+	// ErrBlobOffset is returned by WriteBlob when the chunk is not written at the end of uploaded content
+	// and by ReadBlob when the offset is out of the blob
+	ErrBlobOffset = errors.New("wrong blob offset")
+	// ErrBlobHash is returned by WriteBlob when the uploaded content doesn't match the hash of the blob
+	ErrBlobHash = errors.New("blob content doesn't match its hash")
+	// ErrBlobIncomplete is returned by ReadBlob when the blob is not uploaded yet
+	ErrBlobIncomplete = errors.New("blob is not uploaded")
+	// ErrKeysExist is returned by SetUserKeys when the user has keys already
+	ErrKeysExist = errors.New("user keys are already set")
+	// ErrShareNotFound is returned by DeleteShare when the item is not shared with the user
+	ErrShareNotFound = errors.New("share not found")
+	// ErrOrgNotFound is returned when there is no organization with the given id
+	ErrOrgNotFound = errors.New("organization not found")
+	// ErrMemberNotFound is returned when the user is not a member of the organization
+	ErrMemberNotFound = errors.New("member not found")
+	// ErrKeyVersion is returned by RotateOrgKey when the org key was rotated since the rotation was made
+	ErrKeyVersion = errors.New("org key was rotated meanwhile")
+	// ErrCollectionExists is returned by SetCollection when the id is taken by a collection of another organization
+	ErrCollectionExists = errors.New("collection with this id already exists")
+	// ErrEmergencyNotFound is returned when the user is not a trusted contact of the grantor
+	ErrEmergencyNotFound = errors.New("emergency contact not found")
+	// ErrSendNotFound is returned when there is no such send or it has expired or was viewed enough times
+	ErrSendNotFound = errors.New("send not found")
+)
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// revision is a previous version of models.DataWrapper as it is stored in history collection
+type revision struct {
+	// Key is ItemID and Revision joined with colon
+	Key       string   `bson:"_id"`
+	ItemID    string   `bson:"item_id"`
+	OwnerID   string   `bson:"owner_id"`
+	Type      string   `bson:"type"`
+	Name      string   `bson:"name"`
+	UpdatedAt int64    `bson:"updated_at"`
+	CreatedAt int64    `bson:"created_at"`
+	DeletedAt int64    `bson:"deleted_at"`
+	Revision  int64    `bson:"revision"`
+	Data      []byte   `bson:"data"`
+	Blobs     []string `bson:"blobs,omitempty"`
+	// DataKey is models.DataWrapper.Key, Key is taken by the id of the revision
+	DataKey      []byte `bson:"data_key,omitempty"`
+	CollectionID string `bson:"collection_id,omitempty"`
+	FolderID     string `bson:"folder_id,omitempty"`
+	LabelVersion int32  `bson:"label_version,omitempty"`
+}
+
+func newRevision(data models.DataWrapper) revision {
+	return revision{
+		Key:          data.ID + ":" + strconv.FormatInt(data.Revision, 10),
+		ItemID:       data.ID,
+		OwnerID:      data.OwnerID,
+		Type:         data.Type,
+		Name:         data.Name,
+		UpdatedAt:    data.UpdatedAt,
+		CreatedAt:    data.CreatedAt,
+		DeletedAt:    data.DeletedAt,
+		Revision:     data.Revision,
+		Data:         data.Data,
+		Blobs:        data.Blobs,
+		DataKey:      data.Key,
+		CollectionID: data.CollectionID,
+		FolderID:     data.FolderID,
+		LabelVersion: data.LabelVersion,
+	}
+}
+
+func (r revision) wrapper() models.DataWrapper {
+	return models.DataWrapper{
+		ID:           r.ItemID,
+		OwnerID:      r.OwnerID,
+		Type:         r.Type,
+		Name:         r.Name,
+		UpdatedAt:    r.UpdatedAt,
+		CreatedAt:    r.CreatedAt,
+		DeletedAt:    r.DeletedAt,
+		Revision:     r.Revision,
+		Data:         r.Data,
+		Blobs:        r.Blobs,
+		Key:          r.DataKey,
+		CollectionID: r.CollectionID,
+		FolderID:     r.FolderID,
+		LabelVersion: r.LabelVersion,
+	}
+}
+
+// archive stores previous version of the model in history
+// and removes versions that are older than Options.MaxRevisions
+func (s *storage) archive(ctx context.Context, previous models.DataWrapper) error {
+	if s.opts.MaxRevisions <= 0 {
+		return nil
+	}
+	_, err := s.historyCollection.InsertOne(ctx, newRevision(previous))
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	_, err = s.historyCollection.DeleteMany(ctx, bson.D{
+		{"item_id", previous.ID},
+		{"owner_id", previous.OwnerID},
+		{"revision", bson.D{{"$lte", previous.Revision - int64(s.opts.MaxRevisions)}}},
+	})
+	return err
+}
+
+// purgeHistory removes all previous versions of the model, e.g. when their labels are plaintext
+func (s *storage) purgeHistory(ctx context.Context, previous models.DataWrapper) error {
+	_, err := s.historyCollection.DeleteMany(ctx, bson.D{
+		{"item_id", previous.ID},
+		{"owner_id", previous.OwnerID},
+	})
+	return err
+}
+
+// GetRevisions returns previous versions of the model with the given id, newest first
+// Data field of returned models is empty
+func (s *storage) GetRevisions(ctx context.Context, userID, id string) (result []models.DataWrapper, err error) {
+	res, err := s.historyCollection.Find(ctx,
+		bson.D{{"item_id", id}, {"owner_id", userID}},
+		options.Find().SetSort(bson.D{{"revision", -1}}).SetProjection(bson.D{{"data", 0}}))
+	if err != nil {
+		return
+	}
+	defer func(res *mongo.Cursor, ctx context.Context) {
+		if closeErr := res.Close(ctx); closeErr != nil {
+			log.Err(closeErr).Msg("failed to close cursor")
+		}
+	}(res, ctx)
+
+	var revisions []revision
+	err = res.All(ctx, &revisions)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range revisions {
+		result = append(result, r.wrapper())
+	}
+	return result, err
+}
+
+// GetRevision returns previous version of the model with the given id and revision
+// returns ErrRevisionNotFound if there is no such version
+func (s *storage) GetRevision(ctx context.Context, userID, id string, rev int64) (models.DataWrapper, error) {
+	var r revision
+	err := s.historyCollection.FindOne(ctx,
+		bson.D{{"item_id", id}, {"owner_id", userID}, {"revision", rev}}).Decode(&r)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return models.DataWrapper{}, ErrRevisionNotFound
+		}
+		return models.DataWrapper{}, err
+	}
+	return r.wrapper(), nil
+}
@@ -0,0 +1,1114 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/gynshu-one/goph-keeper/server/storage/blobstore"
+)
+
+// memoryStorage is an in-memory Storage implementation
+// it follows the same rules as the mongo one and is used in tests and local runs
+type memoryStorage struct {
+	opts Options
+	mu   *sync.RWMutex
+	// data is a map of models.DataWrapper key is ID field of data
+	data map[string]models.DataWrapper
+	// order keeps insertion order of data ids, the same as mongo natural order
+	order []string
+	// purged is a set of permanently removed data ids
+	purged map[string]struct{}
+	// seq is the last position of every user's change feed, key is user id
+	seq map[string]int64
+	// purgedSeq is the last position of purged data of every user, key is user id
+	purgedSeq map[string]int64
+	// history is a map of previous versions of data key is ID field of data, oldest first
+	history map[string][]models.DataWrapper
+	// users is a map of models.User key is email
+	users map[string]models.User
+	// blobs is a map of uploaded files key is blob id
+	blobs map[string]*memoryBlob
+	// contents is a map of content stored in opts.Blobs key is hash of the content
+	contents map[string]*content
+	// shares is a map of items shared with users key is shareID of the share
+	shares map[string]models.Share
+	// orgs is a map of models.Organization key is id
+	orgs map[string]models.Organization
+	// members is a map of members of organizations key is memberID of the member
+	members map[string]models.Member
+	// collections is a map of models.Collection key is id
+	collections map[string]models.Collection
+	// emergency is a map of trusted contacts key is emergencyID of the contact
+	emergency map[string]models.EmergencyAccess
+	// sends is a map of models.Send key is id
+	sends map[string]models.Send
+}
+
+// memoryBlob is a blob with content and hash of the content uploaded so far
+// content is moved to opts.Blobs when the blob is uploaded
+type memoryBlob struct {
+	blob    models.Blob
+	state   []byte
+	content []byte
+}
+
+// NewMemoryStorage returns a new in-memory Storage.
+func NewMemoryStorage(opts Options) *memoryStorage {
+	if opts.Blobs == nil {
+		opts.Blobs = blobstore.NewMemory()
+	}
+	return &memoryStorage{
+		opts:        opts,
+		mu:          &sync.RWMutex{},
+		data:        make(map[string]models.DataWrapper),
+		history:     make(map[string][]models.DataWrapper),
+		purged:      make(map[string]struct{}),
+		seq:         make(map[string]int64),
+		purgedSeq:   make(map[string]int64),
+		users:       make(map[string]models.User),
+		blobs:       make(map[string]*memoryBlob),
+		contents:    make(map[string]*content),
+		shares:      make(map[string]models.Share),
+		orgs:        make(map[string]models.Organization),
+		members:     make(map[string]models.Member),
+		collections: make(map[string]models.Collection),
+		emergency:   make(map[string]models.EmergencyAccess),
+		sends:       make(map[string]models.Send),
+	}
+}
+
+// SetData sets the model with the given id
+// if {it exists, belongs to the same owner and new data is an edit of the stored revision it will be updated},
+// {otherwise it will be created.}
+// Revision is assigned by the storage, previous version of updated model is moved to history
+// models that were purged are rejected
+func (m *memoryStorage) SetData(ctx context.Context, data models.DataWrapper) (models.SyncResult, error) {
+	if err := ctx.Err(); err != nil {
+		return models.SyncResult{ID: data.ID}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.set(data), nil
+}
+
+// SetDataBatch sets all the models like SetData does
+// the whole batch is applied under one lock, so nobody sees it half-applied
+func (m *memoryStorage) SetDataBatch(ctx context.Context, data []models.DataWrapper) ([]models.SyncResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	results := make([]models.SyncResult, 0, len(data))
+	for _, d := range data {
+		results = append(results, m.set(d))
+	}
+	return results, nil
+}
+
+// set sets the model, caller must hold the lock
+func (m *memoryStorage) set(data models.DataWrapper) models.SyncResult {
+	if _, ok := m.purged[data.ID]; ok {
+		return models.SyncResult{ID: data.ID, Status: models.StatusRejected, Reason: reasonPurged}
+	}
+
+	stored, ok := m.data[data.ID]
+	if !ok {
+		data.Revision = 1
+		data.Seq = m.nextSeq(data.OwnerID)
+		m.data[data.ID] = copyWrapper(data)
+		m.order = append(m.order, data.ID)
+		return models.SyncResult{ID: data.ID, Status: models.StatusApplied}
+	}
+
+	// We have it already
+	// So if the owner id and update time are valid, update it
+	if result := compare(stored, data); result.Status != "" {
+		return result
+	}
+	if sealsLabel(stored, data) {
+		delete(m.history, data.ID)
+		stored.Type, stored.LabelVersion = data.Type, data.LabelVersion
+	} else {
+		m.archive(stored)
+	}
+	stored.Data = copyBytes(data.Data)
+	stored.Blobs = copyStrings(data.Blobs)
+	stored.Key = copyBytes(data.Key)
+	stored.CollectionID = data.CollectionID
+	stored.FolderID = data.FolderID
+	stored.Name = data.Name
+	stored.UpdatedAt = data.UpdatedAt
+	stored.DeletedAt = data.DeletedAt
+	stored.Revision++
+	stored.Seq = m.nextSeq(data.OwnerID)
+	m.data[data.ID] = stored
+	return models.SyncResult{ID: data.ID, Status: models.StatusApplied}
+}
+
+// nextSeq assigns the next position in the user's change feed, caller must hold the lock
+func (m *memoryStorage) nextSeq(userID string) int64 {
+	m.seq[userID]++
+	return m.seq[userID]
+}
+
+// GetChanges returns the data of the user changed after the given position in the user's change feed
+// if the changes are not known anymore, e.g. some of them were purged, all data is returned with Reset set
+func (m *memoryStorage) GetChanges(ctx context.Context, userID string, since int64) (changes Changes, err error) {
+	if err = ctx.Err(); err != nil {
+		return changes, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	changes.Cursor = m.seq[userID]
+	changes.Reset = since <= 0 || since > changes.Cursor || m.purgedSeq[userID] > since
+	for _, id := range m.order {
+		data := m.data[id]
+		if data.OwnerID == userID && (changes.Reset || data.Seq > since) {
+			changes.Data = append(changes.Data, copyWrapper(data))
+		}
+	}
+	if !changes.Reset {
+		sort.Slice(changes.Data, func(i, j int) bool {
+			return changes.Data[i].Seq < changes.Data[j].Seq
+		})
+	}
+	return changes, nil
+}
+
+// CheckChanges returns Cursor and Reset of the changes after the given position without Data
+// resume means since is a checkpoint of interrupted listing of all data,
+// so it is continued rather than reset again by purged changes
+func (m *memoryStorage) CheckChanges(ctx context.Context, userID string, since int64, resume bool) (changes Changes, err error) {
+	if err = ctx.Err(); err != nil {
+		return changes, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	changes.Cursor = m.seq[userID]
+	changes.Reset = since <= 0 || since > changes.Cursor || !resume && m.purgedSeq[userID] > since
+	return changes, nil
+}
+
+// EachChange calls fn for every model of the user with Seq after the given position in order of Seq
+// fn is called without the lock held, so it may be slow
+func (m *memoryStorage) EachChange(ctx context.Context, userID string, after int64, fn func(data models.DataWrapper) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.RLock()
+	var changed []models.DataWrapper
+	for _, id := range m.order {
+		data := m.data[id]
+		if data.OwnerID == userID && data.Seq > after {
+			changed = append(changed, copyWrapper(data))
+		}
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(changed, func(i, j int) bool {
+		return changed[i].Seq < changed[j].Seq
+	})
+	for _, data := range changed {
+		if err := fn(data); err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetData returns the all data associated with the given user id.
+func (m *memoryStorage) GetData(ctx context.Context, userID string) (result []models.DataWrapper, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, id := range m.order {
+		if m.data[id].OwnerID == userID {
+			result = append(result, copyWrapper(m.data[id]))
+		}
+	}
+	return result, nil
+}
+
+// GetDataByIDs returns the data of the user with the given ids, ids the user has no data with are skipped
+func (m *memoryStorage) GetDataByIDs(ctx context.Context, userID string, ids []string) (result []models.DataWrapper, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, id := range ids {
+		if data, ok := m.data[id]; ok && data.OwnerID == userID {
+			result = append(result, copyWrapper(data))
+		}
+	}
+	return result, nil
+}
+
+// DataUsage returns Items and Bytes of not deleted data of the user
+func (m *memoryStorage) DataUsage(ctx context.Context, userID string) (usage models.Usage, err error) {
+	if err = ctx.Err(); err != nil {
+		return usage, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, data := range m.data {
+		if data.OwnerID == userID && data.DeletedAt == 0 {
+			usage.Items++
+			usage.Bytes += models.ItemSize(data)
+		}
+	}
+	return usage, nil
+}
+
+// PurgeDeleted permanently removes models of all users that were deleted before the given unix time
+// together with their history, returns number of removed models
+func (m *memoryStorage) PurgeDeleted(ctx context.Context, before int64) (count int64, err error) {
+	if err = ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	order := m.order[:0]
+	for _, id := range m.order {
+		data := m.data[id]
+		if data.DeletedAt == 0 || data.DeletedAt >= before {
+			order = append(order, id)
+			continue
+		}
+		if data.Seq > m.purgedSeq[data.OwnerID] {
+			m.purgedSeq[data.OwnerID] = data.Seq
+		}
+		delete(m.data, id)
+		delete(m.history, id)
+		m.purged[id] = struct{}{}
+		count++
+	}
+	m.order = order
+	return count, nil
+}
+
+// archive stores previous version of the model in history
+// and removes versions that are older than Options.MaxRevisions
+// caller must hold the lock
+func (m *memoryStorage) archive(previous models.DataWrapper) {
+	if m.opts.MaxRevisions <= 0 {
+		return
+	}
+	history := append(m.history[previous.ID], previous)
+	if len(history) > m.opts.MaxRevisions {
+		history = history[len(history)-m.opts.MaxRevisions:]
+	}
+	m.history[previous.ID] = history
+}
+
+// GetRevisions returns previous versions of the model with the given id, newest first
+// Data field of returned models is empty
+func (m *memoryStorage) GetRevisions(ctx context.Context, userID, id string) (result []models.DataWrapper, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	history := m.history[id]
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].OwnerID != userID {
+			continue
+		}
+		r := history[i]
+		r.Data = nil
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+// GetRevision returns previous version of the model with the given id and revision
+// returns ErrRevisionNotFound if there is no such version
+func (m *memoryStorage) GetRevision(ctx context.Context, userID, id string, revision int64) (models.DataWrapper, error) {
+	if err := ctx.Err(); err != nil {
+		return models.DataWrapper{}, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, r := range m.history[id] {
+		if r.OwnerID == userID && r.Revision == revision {
+			return copyWrapper(r), nil
+		}
+	}
+	return models.DataWrapper{}, ErrRevisionNotFound
+}
+
+// CreateUser creates a new user
+// returns ErrUserExists if the email is already taken
+func (m *memoryStorage) CreateUser(ctx context.Context, user models.User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[user.Email]; ok {
+		return ErrUserExists
+	}
+	m.users[user.Email] = user
+	return nil
+}
+
+// GetUser returns the user with the given email
+// returns ErrUserNotFound if there is no such user
+func (m *memoryStorage) GetUser(ctx context.Context, email string) (models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return models.User{}, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.users[email]
+	if !ok {
+		return models.User{}, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// SetUserKeys sets keys of the user if it has none
+// returns ErrUserNotFound if there is no such user and ErrKeysExist if the user has keys
+func (m *memoryStorage) SetUserKeys(ctx context.Context, email string, keys models.UserKeys) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[email]
+	if !ok {
+		return ErrUserNotFound
+	}
+	if user.Keys != nil {
+		return ErrKeysExist
+	}
+	user.Keys = &keys
+	m.users[email] = user
+	return nil
+}
+
+// SetShare shares the item with the user, sharing it again replaces the key and permission
+func (m *memoryStorage) SetShare(ctx context.Context, share models.Share) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := shareID(share.ItemID, share.UserID)
+	if stored, ok := m.shares[id]; ok {
+		stored.Key, stored.Permission = share.Key, share.Permission
+		share = stored
+	}
+	m.shares[id] = share
+	return nil
+}
+
+// DeleteShare revokes the share of the owner's item with the user
+// returns ErrShareNotFound if the item is not shared with the user
+func (m *memoryStorage) DeleteShare(ctx context.Context, ownerID, itemID, userID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := shareID(itemID, userID)
+	if share, ok := m.shares[id]; !ok || share.OwnerID != ownerID {
+		return ErrShareNotFound
+	}
+	delete(m.shares, id)
+	return nil
+}
+
+// GetItemShares returns shares of the owner's item, oldest first
+func (m *memoryStorage) GetItemShares(ctx context.Context, ownerID, itemID string) ([]models.Share, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.findShares(func(share models.Share) bool {
+		return share.OwnerID == ownerID && share.ItemID == itemID
+	}), nil
+}
+
+// GetSharedData returns current versions of items shared with the user together with their shares, oldest first
+// deleted items and items that are gone are skipped
+func (m *memoryStorage) GetSharedData(ctx context.Context, userID string) ([]models.SharedItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	shares := m.findShares(func(share models.Share) bool {
+		return share.UserID == userID
+	})
+	if len(shares) == 0 {
+		return nil, nil
+	}
+	data := make([]models.DataWrapper, 0, len(shares))
+	for _, share := range shares {
+		if item, ok := m.data[share.ItemID]; ok {
+			data = append(data, item)
+		}
+	}
+	return sharedItems(shares, data), nil
+}
+
+// findShares returns shares that match, oldest first, must be called under the lock
+func (m *memoryStorage) findShares(match func(share models.Share) bool) []models.Share {
+	var shares []models.Share
+	for _, share := range m.shares {
+		if match(share) {
+			shares = append(shares, share)
+		}
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].CreatedAt != shares[j].CreatedAt {
+			return shares[i].CreatedAt < shares[j].CreatedAt
+		}
+		return shareID(shares[i].ItemID, shares[i].UserID) < shareID(shares[j].ItemID, shares[j].UserID)
+	})
+	return shares
+}
+
+// CreateOrg creates the organization together with its owner
+func (m *memoryStorage) CreateOrg(ctx context.Context, org models.Organization, owner models.Member) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.orgs[org.ID] = org
+	m.members[memberID(owner.OrgID, owner.UserID)] = copyMember(owner)
+	return nil
+}
+
+// GetOrg returns the organization, ErrOrgNotFound if there is no such organization
+func (m *memoryStorage) GetOrg(ctx context.Context, id string) (models.Organization, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Organization{}, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	org, ok := m.orgs[id]
+	if !ok {
+		return org, ErrOrgNotFound
+	}
+	return org, nil
+}
+
+// SetMember adds the user to the organization or replaces its role, key and acceptance
+func (m *memoryStorage) SetMember(ctx context.Context, member models.Member) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := memberID(member.OrgID, member.UserID)
+	if stored, ok := m.members[id]; ok {
+		member.CreatedAt = stored.CreatedAt
+	}
+	m.members[id] = copyMember(member)
+	return nil
+}
+
+// GetMember returns the member of the organization, ErrMemberNotFound if the user is not a member
+func (m *memoryStorage) GetMember(ctx context.Context, orgID, userID string) (models.Member, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Member{}, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	member, ok := m.members[memberID(orgID, userID)]
+	if !ok {
+		return member, ErrMemberNotFound
+	}
+	return copyMember(member), nil
+}
+
+// GetMembers returns members of the organization, oldest first
+func (m *memoryStorage) GetMembers(ctx context.Context, orgID string) ([]models.Member, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.findMembers(func(member models.Member) bool {
+		return member.OrgID == orgID
+	}), nil
+}
+
+// DeleteMember removes the user from the organization, ErrMemberNotFound if the user is not a member
+func (m *memoryStorage) DeleteMember(ctx context.Context, orgID, userID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := memberID(orgID, userID)
+	if _, ok := m.members[id]; !ok {
+		return ErrMemberNotFound
+	}
+	delete(m.members, id)
+	return nil
+}
+
+// GetMemberships returns organizations of the user together with its memberships, oldest first
+func (m *memoryStorage) GetMemberships(ctx context.Context, userID string) ([]models.Membership, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	members := m.findMembers(func(member models.Member) bool {
+		return member.UserID == userID
+	})
+	if len(members) == 0 {
+		return nil, nil
+	}
+	orgs := make([]models.Organization, 0, len(members))
+	for _, member := range members {
+		if org, ok := m.orgs[member.OrgID]; ok {
+			orgs = append(orgs, org)
+		}
+	}
+	return memberships(members, orgs), nil
+}
+
+// RotateOrgKey sets the version of the org key to version and replaces keys of the members with the given ones
+// returns ErrOrgNotFound if there is no such organization
+// and ErrKeyVersion if the current version is not the one before version
+func (m *memoryStorage) RotateOrgKey(ctx context.Context, orgID string, version int64, members []models.Member) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	org, ok := m.orgs[orgID]
+	if !ok {
+		return ErrOrgNotFound
+	}
+	if org.KeyVersion != version-1 {
+		return ErrKeyVersion
+	}
+	org.KeyVersion = version
+	m.orgs[orgID] = org
+	for _, member := range members {
+		id := memberID(orgID, member.UserID)
+		stored, ok := m.members[id]
+		if !ok {
+			continue
+		}
+		stored.Key, stored.KeyVersion = copyBytes(member.Key), version
+		m.members[id] = stored
+	}
+	return nil
+}
+
+// SetCollection creates the collection or renames it
+// returns ErrCollectionExists if the id is taken by a collection of another organization
+func (m *memoryStorage) SetCollection(ctx context.Context, collection models.Collection) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if stored, ok := m.collections[collection.ID]; ok {
+		if stored.OrgID != collection.OrgID {
+			return ErrCollectionExists
+		}
+		collection.CreatedAt = stored.CreatedAt
+	}
+	m.collections[collection.ID] = collection
+	return nil
+}
+
+// GetCollections returns collections of the organization, oldest first
+func (m *memoryStorage) GetCollections(ctx context.Context, orgID string) ([]models.Collection, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var collections []models.Collection
+	for _, collection := range m.collections {
+		if collection.OrgID == orgID {
+			collections = append(collections, collection)
+		}
+	}
+	sort.Slice(collections, func(i, j int) bool {
+		if collections[i].CreatedAt != collections[j].CreatedAt {
+			return collections[i].CreatedAt < collections[j].CreatedAt
+		}
+		return collections[i].ID < collections[j].ID
+	})
+	return collections, nil
+}
+
+// findMembers returns members that match, oldest first, must be called under the lock
+func (m *memoryStorage) findMembers(match func(member models.Member) bool) []models.Member {
+	var members []models.Member
+	for _, member := range m.members {
+		if match(member) {
+			members = append(members, copyMember(member))
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].CreatedAt != members[j].CreatedAt {
+			return members[i].CreatedAt < members[j].CreatedAt
+		}
+		return memberID(members[i].OrgID, members[i].UserID) < memberID(members[j].OrgID, members[j].UserID)
+	})
+	return members
+}
+
+// SetEmergency adds the contact of the grantor or replaces its waiting period, key and state of its request
+func (m *memoryStorage) SetEmergency(ctx context.Context, access models.EmergencyAccess) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := emergencyID(access.GrantorID, access.GranteeID)
+	if stored, ok := m.emergency[id]; ok {
+		access.CreatedAt = stored.CreatedAt
+	}
+	m.emergency[id] = copyEmergency(access)
+	return nil
+}
+
+// GetEmergency returns the contact of the grantor, ErrEmergencyNotFound if there is no such contact
+func (m *memoryStorage) GetEmergency(ctx context.Context, grantorID, granteeID string) (models.EmergencyAccess, error) {
+	if err := ctx.Err(); err != nil {
+		return models.EmergencyAccess{}, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	access, ok := m.emergency[emergencyID(grantorID, granteeID)]
+	if !ok {
+		return access, ErrEmergencyNotFound
+	}
+	return copyEmergency(access), nil
+}
+
+// GetEmergencyContacts returns trusted contacts of the grantor, oldest first
+func (m *memoryStorage) GetEmergencyContacts(ctx context.Context, grantorID string) ([]models.EmergencyAccess, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.findEmergency(func(access models.EmergencyAccess) bool {
+		return access.GrantorID == grantorID
+	}), nil
+}
+
+// GetEmergencyGrantors returns users that trust the grantee, oldest first
+func (m *memoryStorage) GetEmergencyGrantors(ctx context.Context, granteeID string) ([]models.EmergencyAccess, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.findEmergency(func(access models.EmergencyAccess) bool {
+		return access.GranteeID == granteeID
+	}), nil
+}
+
+// DeleteEmergency removes the contact of the grantor, ErrEmergencyNotFound if there is no such contact
+func (m *memoryStorage) DeleteEmergency(ctx context.Context, grantorID, granteeID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := emergencyID(grantorID, granteeID)
+	if _, ok := m.emergency[id]; !ok {
+		return ErrEmergencyNotFound
+	}
+	delete(m.emergency, id)
+	return nil
+}
+
+// findEmergency returns trusted contacts that match, oldest first, must be called under the lock
+func (m *memoryStorage) findEmergency(match func(access models.EmergencyAccess) bool) []models.EmergencyAccess {
+	var result []models.EmergencyAccess
+	for _, access := range m.emergency {
+		if match(access) {
+			result = append(result, copyEmergency(access))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt != result[j].CreatedAt {
+			return result[i].CreatedAt < result[j].CreatedAt
+		}
+		return emergencyID(result[i].GrantorID, result[i].GranteeID) < emergencyID(result[j].GrantorID, result[j].GranteeID)
+	})
+	return result
+}
+
+// CreateSend stores the new send
+func (m *memoryStorage) CreateSend(ctx context.Context, send models.Send) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sends[send.ID] = copySend(send)
+	return nil
+}
+
+// GetSends returns sends of the owner that are not deleted yet, oldest first
+func (m *memoryStorage) GetSends(ctx context.Context, ownerID string) ([]models.Send, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []models.Send
+	for _, send := range m.sends {
+		if send.OwnerID == ownerID {
+			result = append(result, copySend(send))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt != result[j].CreatedAt {
+			return result[i].CreatedAt < result[j].CreatedAt
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
+
+// DeleteSend removes the send of the owner, ErrSendNotFound if the owner has no such send
+func (m *memoryStorage) DeleteSend(ctx context.Context, ownerID, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if send, ok := m.sends[id]; !ok || send.OwnerID != ownerID {
+		return ErrSendNotFound
+	}
+	delete(m.sends, id)
+	return nil
+}
+
+// ViewSend counts a view of the send and returns it, the send is removed after its last view
+// returns ErrSendNotFound if there is no such send or it can't be viewed at the unix time anymore
+func (m *memoryStorage) ViewSend(ctx context.Context, id string, now int64) (models.Send, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Send{}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	send, ok := m.sends[id]
+	if !ok || !send.ViewableAt(now) {
+		return models.Send{}, ErrSendNotFound
+	}
+	send.Views++
+	if send.Views >= send.MaxViews {
+		delete(m.sends, id)
+	} else {
+		m.sends[id] = send
+	}
+	return copySend(send), nil
+}
+
+// DeleteExpiredSends removes sends that expired before the unix time, returns number of removed sends
+func (m *memoryStorage) DeleteExpiredSends(ctx context.Context, before int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var count int64
+	for id, send := range m.sends {
+		if send.ExpiresAt <= before {
+			delete(m.sends, id)
+			count++
+		}
+	}
+	return count, nil
+}
+
+// CreateBlob starts upload of the blob, creating the same blob again returns it with its Offset
+// blob with the content the owner has uploaded already is created complete
+// returns ErrBlobExists if the id is taken by a blob with other size, hash or owner
+func (m *memoryStorage) CreateBlob(ctx context.Context, blob models.Blob) (models.Blob, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Blob{}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if stored, ok := m.blobs[blob.ID]; ok {
+		if !sameBlob(stored.blob, blob) {
+			return models.Blob{}, ErrBlobExists
+		}
+		return stored.blob, nil
+	}
+	blob.Offset = 0
+	if existing, ok := m.findBlob(blob.OwnerID, blob.Hash); ok && existing.Size == blob.Size {
+		m.contents[blob.Hash].Refs++
+		blob.Offset = blob.Size
+	}
+	m.blobs[blob.ID] = &memoryBlob{blob: blob}
+	return blob, nil
+}
+
+// GetBlob returns the blob of the user, ErrBlobNotFound if there is no such blob
+func (m *memoryStorage) GetBlob(ctx context.Context, userID, id string) (models.Blob, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Blob{}, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stored, ok := m.blobs[id]
+	if !ok || stored.blob.OwnerID != userID {
+		return models.Blob{}, ErrBlobNotFound
+	}
+	return stored.blob, nil
+}
+
+// FindBlob returns an uploaded blob of the user with the hash, ErrBlobNotFound if there is no such blob
+func (m *memoryStorage) FindBlob(ctx context.Context, userID, hash string) (models.Blob, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Blob{}, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	blob, ok := m.findBlob(userID, hash)
+	if !ok {
+		return models.Blob{}, ErrBlobNotFound
+	}
+	return blob, nil
+}
+
+// findBlob returns an uploaded blob of the user with the hash, caller must hold the lock
+func (m *memoryStorage) findBlob(userID, hash string) (models.Blob, bool) {
+	for _, stored := range m.blobs {
+		if stored.blob.OwnerID == userID && stored.blob.Hash == hash && stored.blob.Complete() {
+			return stored.blob, true
+		}
+	}
+	return models.Blob{}, false
+}
+
+// WriteBlob writes the chunk at the offset and returns the blob with new Offset
+// content that doesn't match the hash is dropped and ErrBlobHash is returned
+// with the last chunk the content is put to opts.Blobs, unless it is stored there already
+func (m *memoryStorage) WriteBlob(ctx context.Context, userID, id string, offset int64, chunk []byte) (models.Blob, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Blob{}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.blobs[id]
+	if !ok || stored.blob.OwnerID != userID {
+		return models.Blob{}, ErrBlobNotFound
+	}
+	if err := checkWrite(stored.blob, offset, chunk); err != nil {
+		return stored.blob, err
+	}
+	state, hash, err := hashChunk(stored.state, chunk)
+	if err != nil {
+		return stored.blob, err
+	}
+	end := offset + int64(len(chunk))
+	if end < stored.blob.Size {
+		stored.state = state
+		stored.content = append(stored.content, chunk...)
+		stored.blob.Offset = end
+		return stored.blob, nil
+	}
+
+	if hash != stored.blob.Hash {
+		stored.state, stored.content, stored.blob.Offset = nil, nil, 0
+		return stored.blob, ErrBlobHash
+	}
+	if c, ok := m.contents[hash]; ok {
+		c.Refs++
+	} else {
+		key := contentKey(hash)
+		data := append(stored.content, chunk...)
+		if err = putContent(ctx, m.opts.Blobs, key, hash, stored.blob.Size, bytes.NewReader(data)); err != nil {
+			return stored.blob, err
+		}
+		m.contents[hash] = &content{Hash: hash, Key: key, Size: stored.blob.Size, Refs: 1}
+	}
+	stored.state, stored.content, stored.blob.Offset = nil, nil, end
+	return stored.blob, nil
+}
+
+// ReadBlob returns up to length bytes of the blob content from the offset
+func (m *memoryStorage) ReadBlob(ctx context.Context, userID, id string, offset, length int64) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stored, ok := m.blobs[id]
+	if !ok || stored.blob.OwnerID != userID {
+		return nil, ErrBlobNotFound
+	}
+	end, err := checkRead(stored.blob, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	return readContent(ctx, m.opts.Blobs, m.contents[stored.blob.Hash].Key, offset, end)
+}
+
+// BlobsSize returns total size of blobs of the user including not uploaded ones
+// blobs that share content are counted once
+func (m *memoryStorage) BlobsSize(ctx context.Context, userID string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var blobs []models.Blob
+	for _, stored := range m.blobs {
+		if stored.blob.OwnerID == userID {
+			blobs = append(blobs, stored.blob)
+		}
+	}
+	return blobsSize(blobs), nil
+}
+
+// CollectBlobs removes blobs created before the given unix time that no model or previous version refers to
+// returns number of removed blobs
+func (m *memoryStorage) CollectBlobs(ctx context.Context, before int64) (count int64, err error) {
+	if err = ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, stored := range m.blobs {
+		if stored.blob.CreatedAt >= before || m.blobUsed(stored.blob) {
+			continue
+		}
+		delete(m.blobs, id)
+		count++
+		if !stored.blob.Complete() {
+			continue
+		}
+		c := m.contents[stored.blob.Hash]
+		if c.Refs--; c.Refs > 0 {
+			continue
+		}
+		delete(m.contents, c.Hash)
+		if err = m.opts.Blobs.Delete(ctx, c.Key); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// blobUsed checks if a model of the blob owner or its previous version refers to the blob,
+// caller must hold the lock
+func (m *memoryStorage) blobUsed(blob models.Blob) bool {
+	for id, data := range m.data {
+		if data.OwnerID != blob.OwnerID {
+			continue
+		}
+		if slices.Contains(data.Blobs, blob.ID) {
+			return true
+		}
+		for _, previous := range m.history[id] {
+			if slices.Contains(previous.Blobs, blob.ID) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// copyWrapper returns a copy of the wrapper that doesn't share Data, Blobs and Key with the original
+func copyWrapper(data models.DataWrapper) models.DataWrapper {
+	data.Data = copyBytes(data.Data)
+	data.Blobs = copyStrings(data.Blobs)
+	data.Key = copyBytes(data.Key)
+	return data
+}
+
+func copyMember(member models.Member) models.Member {
+	member.Key = copyBytes(member.Key)
+	return member
+}
+
+func copyEmergency(access models.EmergencyAccess) models.EmergencyAccess {
+	access.Key = copyBytes(access.Key)
+	return access
+}
+
+func copySend(send models.Send) models.Send {
+	send.Data = copyBytes(send.Data)
+	return send
+}
+
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte(nil), b...)
+}
+
+func copyStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string(nil), s...)
+}
@@ -0,0 +1,14 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/gynshu-one/goph-keeper/server/storage"
+	"github.com/gynshu-one/goph-keeper/server/storage/storagetest"
+)
+
+func TestMemoryStorageConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T, opts storage.Options) storage.Storage {
+		return storage.NewMemoryStorage(opts)
+	})
+}
@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// member is models.Member stored in mongo, a user is a member of an organization once
+type member struct {
+	ID            string `bson:"_id"`
+	models.Member `bson:",inline"`
+}
+
+// memberID returns id of the membership of the user in the organization
+func memberID(orgID, userID string) string {
+	return orgID + ":" + userID
+}
+
+// CreateOrg creates the organization together with its owner
+func (s *storage) CreateOrg(ctx context.Context, org models.Organization, owner models.Member) error {
+	if _, err := s.orgCollection.InsertOne(ctx, org); err != nil {
+		return err
+	}
+	return s.SetMember(ctx, owner)
+}
+
+// GetOrg returns the organization, ErrOrgNotFound if there is no such organization
+func (s *storage) GetOrg(ctx context.Context, id string) (models.Organization, error) {
+	var org models.Organization
+	err := s.orgCollection.FindOne(ctx, bson.D{{"_id", id}}).Decode(&org)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return org, ErrOrgNotFound
+	}
+	return org, err
+}
+
+// SetMember adds the user to the organization or replaces its role, key and acceptance
+func (s *storage) SetMember(ctx context.Context, model models.Member) error {
+	_, err := s.memberCollection.UpdateOne(ctx, bson.D{{"_id", memberID(model.OrgID, model.UserID)}},
+		bson.D{
+			{"$set", bson.D{
+				{"role", model.Role},
+				{"key", model.Key},
+				{"key_version", model.KeyVersion},
+				{"accepted", model.Accepted},
+			}},
+			{"$setOnInsert", bson.D{
+				{"org_id", model.OrgID},
+				{"user_id", model.UserID},
+				{"created_at", model.CreatedAt},
+			}},
+		},
+		options.Update().SetUpsert(true))
+	return err
+}
+
+// GetMember returns the member of the organization, ErrMemberNotFound if the user is not a member
+func (s *storage) GetMember(ctx context.Context, orgID, userID string) (models.Member, error) {
+	var stored member
+	err := s.memberCollection.FindOne(ctx, bson.D{{"_id", memberID(orgID, userID)}}).Decode(&stored)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Member{}, ErrMemberNotFound
+	}
+	return stored.Member, err
+}
+
+// GetMembers returns members of the organization, oldest first
+func (s *storage) GetMembers(ctx context.Context, orgID string) ([]models.Member, error) {
+	return s.findMembers(ctx, bson.D{{"org_id", orgID}})
+}
+
+// DeleteMember removes the user from the organization, ErrMemberNotFound if the user is not a member
+func (s *storage) DeleteMember(ctx context.Context, orgID, userID string) error {
+	res, err := s.memberCollection.DeleteOne(ctx, bson.D{{"_id", memberID(orgID, userID)}})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrMemberNotFound
+	}
+	return nil
+}
+
+// GetMemberships returns organizations of the user together with its memberships, oldest first
+func (s *storage) GetMemberships(ctx context.Context, userID string) ([]models.Membership, error) {
+	members, err := s.findMembers(ctx, bson.D{{"user_id", userID}})
+	if err != nil || len(members) == 0 {
+		return nil, err
+	}
+	ids := make([]string, len(members))
+	for i, model := range members {
+		ids[i] = model.OrgID
+	}
+	cursor, err := s.orgCollection.Find(ctx, bson.D{{"_id", bson.D{{"$in", ids}}}})
+	if err != nil {
+		return nil, err
+	}
+	var orgs []models.Organization
+	if err = cursor.All(ctx, &orgs); err != nil {
+		return nil, err
+	}
+	return memberships(members, orgs), nil
+}
+
+// RotateOrgKey sets the version of the org key to version and replaces keys of the members with the given ones
+// returns ErrOrgNotFound if there is no such organization
+// and ErrKeyVersion if the current version is not the one before version
+func (s *storage) RotateOrgKey(ctx context.Context, orgID string, version int64, members []models.Member) error {
+	res, err := s.orgCollection.UpdateOne(ctx, bson.D{{"_id", orgID}, {"key_version", version - 1}},
+		bson.D{{"$set", bson.D{{"key_version", version}}}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		if _, err = s.GetOrg(ctx, orgID); err != nil {
+			return err
+		}
+		return ErrKeyVersion
+	}
+	for _, model := range members {
+		_, err = s.memberCollection.UpdateOne(ctx, bson.D{{"_id", memberID(orgID, model.UserID)}},
+			bson.D{{"$set", bson.D{{"key", model.Key}, {"key_version", version}}}})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetCollection creates the collection or renames it
+// returns ErrCollectionExists if the id is taken by a collection of another organization
+func (s *storage) SetCollection(ctx context.Context, collection models.Collection) error {
+	_, err := s.collections.UpdateOne(ctx, bson.D{{"_id", collection.ID}, {"org_id", collection.OrgID}},
+		bson.D{
+			{"$set", bson.D{{"name", collection.Name}}},
+			{"$setOnInsert", bson.D{{"created_at", collection.CreatedAt}}},
+		},
+		options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrCollectionExists
+	}
+	return err
+}
+
+// GetCollections returns collections of the organization, oldest first
+func (s *storage) GetCollections(ctx context.Context, orgID string) ([]models.Collection, error) {
+	cursor, err := s.collections.Find(ctx, bson.D{{"org_id", orgID}},
+		options.Find().SetSort(bson.D{{"created_at", 1}, {"_id", 1}}))
+	if err != nil {
+		return nil, err
+	}
+	var collections []models.Collection
+	if err = cursor.All(ctx, &collections); err != nil {
+		return nil, err
+	}
+	return collections, nil
+}
+
+// findMembers returns members that match the filter, oldest first
+func (s *storage) findMembers(ctx context.Context, filter bson.D) ([]models.Member, error) {
+	cursor, err := s.memberCollection.Find(ctx, filter,
+		options.Find().SetSort(bson.D{{"created_at", 1}, {"_id", 1}}))
+	if err != nil {
+		return nil, err
+	}
+	var stored []member
+	if err = cursor.All(ctx, &stored); err != nil {
+		return nil, err
+	}
+	members := make([]models.Member, len(stored))
+	for i, model := range stored {
+		members[i] = model.Member
+	}
+	return members, nil
+}
+
+// memberships joins the members with their organizations in order of members
+func memberships(members []models.Member, orgs []models.Organization) []models.Membership {
+	byID := make(map[string]models.Organization, len(orgs))
+	for _, org := range orgs {
+		byID[org.ID] = org
+	}
+	result := make([]models.Membership, 0, len(members))
+	for _, model := range members {
+		org, ok := byID[model.OrgID]
+		if !ok {
+			continue
+		}
+		result = append(result, models.Membership{Organization: org, Member: model})
+	}
+	return result
+}
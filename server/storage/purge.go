@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// purged is a record about permanently removed model
+// it keeps clients that were offline longer than retention period from creating the model again
+type purged struct {
+	ID       string `bson:"_id"`
+	OwnerID  string `bson:"owner_id"`
+	PurgedAt int64  `bson:"purged_at"`
+	// Seq is the position of the deletion in the owner's change feed
+	Seq int64 `bson:"seq"`
+}
+
+// PurgeDeleted permanently removes models of all users that were deleted before the given unix time
+// together with their history, returns number of removed models
+func (s *storage) PurgeDeleted(ctx context.Context, before int64) (count int64, err error) {
+	filter := bson.D{{"deleted_at", bson.D{{"$gt", 0}, {"$lt", before}}}}
+	res, err := s.dataCollection.Find(ctx, filter,
+		options.Find().SetProjection(bson.D{{"_id", 1}, {"owner_id", 1}, {"seq", 1}}))
+	if err != nil {
+		return 0, err
+	}
+	defer func(res *mongo.Cursor, ctx context.Context) {
+		if closeErr := res.Close(ctx); closeErr != nil {
+			log.Err(closeErr).Msg("failed to close cursor")
+		}
+	}(res, ctx)
+
+	for res.Next(ctx) {
+		var tombstone purged
+		if err = res.Decode(&tombstone); err != nil {
+			return count, err
+		}
+
+		// Clients that haven't seen the deletion yet will get all data instead of changes
+		if err = s.markPurged(ctx, tombstone.OwnerID, tombstone.Seq); err != nil {
+			return count, err
+		}
+
+		// Check deletion time and position again, the model could be restored since we found it
+		var deleted *mongo.DeleteResult
+		deleted, err = s.dataCollection.DeleteOne(ctx, bson.D{{"_id", tombstone.ID}, {"seq", tombstone.Seq}, filter[0]})
+		if err != nil {
+			return count, err
+		}
+		if deleted.DeletedCount == 0 {
+			continue
+		}
+		count++
+
+		tombstone.PurgedAt = time.Now().Unix()
+		_, err = s.purgedCollection.InsertOne(ctx, tombstone)
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return count, err
+		}
+		_, err = s.historyCollection.DeleteMany(ctx, bson.D{{"item_id", tombstone.ID}})
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, res.Err()
+}
+
+// isPurged checks if the model with the given id was permanently removed
+func (s *storage) isPurged(ctx context.Context, id string) (bool, error) {
+	err := s.purgedCollection.FindOne(ctx, bson.D{{"_id", id}}).Err()
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
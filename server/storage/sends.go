@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateSend stores the new send
+func (s *storage) CreateSend(ctx context.Context, send models.Send) error {
+	_, err := s.sendCollection.InsertOne(ctx, send)
+	return err
+}
+
+// GetSends returns sends of the owner that are not deleted yet, oldest first
+func (s *storage) GetSends(ctx context.Context, ownerID string) ([]models.Send, error) {
+	cursor, err := s.sendCollection.Find(ctx, bson.D{{"owner_id", ownerID}},
+		options.Find().SetSort(bson.D{{"created_at", 1}, {"_id", 1}}))
+	if err != nil {
+		return nil, err
+	}
+	var sends []models.Send
+	if err = cursor.All(ctx, &sends); err != nil {
+		return nil, err
+	}
+	return sends, nil
+}
+
+// DeleteSend removes the send of the owner, ErrSendNotFound if the owner has no such send
+func (s *storage) DeleteSend(ctx context.Context, ownerID, id string) error {
+	res, err := s.sendCollection.DeleteOne(ctx, bson.D{{"_id", id}, {"owner_id", ownerID}})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrSendNotFound
+	}
+	return nil
+}
+
+// ViewSend counts a view of the send and returns it, the send is removed after its last view
+// returns ErrSendNotFound if there is no such send or it can't be viewed at the unix time anymore
+func (s *storage) ViewSend(ctx context.Context, id string, now int64) (models.Send, error) {
+	// The view is counted only while the send is viewable, so concurrent views never exceed MaxViews
+	var send models.Send
+	err := s.sendCollection.FindOneAndUpdate(ctx,
+		bson.D{
+			{"_id", id},
+			{"expires_at", bson.D{{"$gt", now}}},
+			{"$expr", bson.D{{"$lt", bson.A{"$views", "$max_views"}}}},
+		},
+		bson.D{{"$inc", bson.D{{"views", 1}}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&send)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Send{}, ErrSendNotFound
+	}
+	if err != nil {
+		return models.Send{}, err
+	}
+	if send.Views >= send.MaxViews {
+		if _, err = s.sendCollection.DeleteOne(ctx, bson.D{{"_id", id}}); err != nil {
+			return models.Send{}, err
+		}
+	}
+	return send, nil
+}
+
+// DeleteExpiredSends removes sends that expired before the unix time, returns number of removed sends
+func (s *storage) DeleteExpiredSends(ctx context.Context, before int64) (int64, error) {
+	res, err := s.sendCollection.DeleteMany(ctx, bson.D{{"expires_at", bson.D{{"$lte", before}}}})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// share is models.Share stored in mongo, an item is shared with a user once
+type share struct {
+	ID           string `bson:"_id"`
+	models.Share `bson:",inline"`
+}
+
+// shareID returns id of the share of the item with the user
+func shareID(itemID, userID string) string {
+	return itemID + ":" + userID
+}
+
+// SetShare shares the item with the user, sharing it again replaces the key and permission
+func (s *storage) SetShare(ctx context.Context, model models.Share) error {
+	id := shareID(model.ItemID, model.UserID)
+	_, err := s.shareCollection.UpdateOne(ctx, bson.D{{"_id", id}},
+		bson.D{
+			{"$set", bson.D{{"key", model.Key}, {"permission", model.Permission}}},
+			{"$setOnInsert", bson.D{
+				{"item_id", model.ItemID},
+				{"owner_id", model.OwnerID},
+				{"user_id", model.UserID},
+				{"created_at", model.CreatedAt},
+			}},
+		},
+		options.Update().SetUpsert(true))
+	return err
+}
+
+// DeleteShare revokes the share of the owner's item with the user
+// returns ErrShareNotFound if the item is not shared with the user
+func (s *storage) DeleteShare(ctx context.Context, ownerID, itemID, userID string) error {
+	res, err := s.shareCollection.DeleteOne(ctx,
+		bson.D{{"_id", shareID(itemID, userID)}, {"owner_id", ownerID}})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrShareNotFound
+	}
+	return nil
+}
+
+// GetItemShares returns shares of the owner's item, oldest first
+func (s *storage) GetItemShares(ctx context.Context, ownerID, itemID string) ([]models.Share, error) {
+	return s.findShares(ctx, bson.D{{"owner_id", ownerID}, {"item_id", itemID}})
+}
+
+// GetSharedData returns current versions of items shared with the user together with their shares, oldest first
+// deleted items and items that are gone are skipped
+func (s *storage) GetSharedData(ctx context.Context, userID string) ([]models.SharedItem, error) {
+	shares, err := s.findShares(ctx, bson.D{{"user_id", userID}})
+	if err != nil || len(shares) == 0 {
+		return nil, err
+	}
+	ids := make([]string, len(shares))
+	for i, model := range shares {
+		ids[i] = model.ItemID
+	}
+	cursor, err := s.dataCollection.Find(ctx, bson.D{{"_id", bson.D{{"$in", ids}}}, {"deleted_at", 0}})
+	if err != nil {
+		return nil, err
+	}
+	var data []models.DataWrapper
+	if err = cursor.All(ctx, &data); err != nil {
+		return nil, err
+	}
+	return sharedItems(shares, data), nil
+}
+
+// findShares returns shares that match the filter, oldest first
+func (s *storage) findShares(ctx context.Context, filter bson.D) ([]models.Share, error) {
+	cursor, err := s.shareCollection.Find(ctx, filter,
+		options.Find().SetSort(bson.D{{"created_at", 1}, {"_id", 1}}))
+	if err != nil {
+		return nil, err
+	}
+	var stored []share
+	if err = cursor.All(ctx, &stored); err != nil {
+		return nil, err
+	}
+	shares := make([]models.Share, len(stored))
+	for i, model := range stored {
+		shares[i] = model.Share
+	}
+	return shares, nil
+}
+
+// sharedItems joins the shares with not deleted data of their owners in order of shares
+func sharedItems(shares []models.Share, data []models.DataWrapper) []models.SharedItem {
+	byID := make(map[string]models.DataWrapper, len(data))
+	for _, model := range data {
+		byID[model.ID] = model
+	}
+	items := make([]models.SharedItem, 0, len(shares))
+	for _, model := range shares {
+		item, ok := byID[model.ItemID]
+		if !ok || item.OwnerID != model.OwnerID || item.DeletedAt != 0 {
+			continue
+		}
+		items = append(items, models.SharedItem{Share: model, Data: item})
+	}
+	return items
+}
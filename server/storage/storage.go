@@ -2,14 +2,73 @@ package storage
 
 import (
 	"context"
+	"sync/atomic"
+
 	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/gynshu-one/goph-keeper/server/storage/blobstore"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-// Storage is a struct that holds a sync.Map to store all models.
+const (
+	dataCollectionName    = "user-data"
+	userCollectionName    = "users"
+	historyCollectionName = "user-data-history"
+	purgedCollectionName  = "user-data-purged"
+	seqCollectionName     = "user-data-seq"
+	blobCollectionName    = "blobs"
+	chunkCollectionName   = "blob-chunks"
+	contentCollectionName = "blob-contents"
+	shareCollectionName   = "shares"
+	orgCollectionName     = "orgs"
+	memberCollectionName  = "org-members"
+	collectionsName       = "org-collections"
+	emergencyName         = "emergency-access"
+	sendCollectionName    = "sends"
+)
+
+// DefaultMaxRevisions is the default number of previous versions kept for every item
+const DefaultMaxRevisions = 10
+
+// Options are the settings shared by all Storage implementations
+type Options struct {
+	// MaxRevisions is how many previous versions of every item are kept
+	// zero disables history
+	MaxRevisions int
+	// Blobs keeps content of uploaded blobs, if nil it is kept in memory by memory storage
+	// and in GridFS of the database by mongo storage
+	Blobs blobstore.Backend
+}
+
+// Changes are the changes of user's data since some position in the change feed
+type Changes struct {
+	// Data is the changed data ordered by Seq, or all data if Reset is set
+	Data []models.DataWrapper
+	// Cursor is the last position in the change feed the changes include
+	Cursor int64
+	// Reset means Data is all data of the user
+	Reset bool
+}
+
+// Storage is a struct that holds mongo collections to store all models.
 type storage struct {
-	dataCollection *mongo.Collection
-	userCollection *mongo.Collection
+	opts              Options
+	dataCollection    *mongo.Collection
+	userCollection    *mongo.Collection
+	historyCollection *mongo.Collection
+	purgedCollection  *mongo.Collection
+	seqCollection     *mongo.Collection
+	blobCollection    *mongo.Collection
+	chunkCollection   *mongo.Collection
+	contentCollection *mongo.Collection
+	shareCollection   *mongo.Collection
+	orgCollection     *mongo.Collection
+	memberCollection  *mongo.Collection
+	collections       *mongo.Collection
+	emergency         *mongo.Collection
+	sendCollection    *mongo.Collection
+	// noTransactions is set when mongo turns out to be a standalone server
+	noTransactions atomic.Bool
 }
 
 // Storage is an interface for all storage types.
@@ -18,18 +77,254 @@ type storage struct {
 type Storage interface {
 	// GetData returns the all data from database associated with the given user id.
 	GetData(ctx context.Context, userID string) ([]models.DataWrapper, error)
-	// SetData sets the model with the given id
-	SetData(ctx context.Context, data models.DataWrapper) error
+	// GetDataByIDs returns the data of the user with the given ids, ids the user has no data with are skipped
+	GetDataByIDs(ctx context.Context, userID string, ids []string) ([]models.DataWrapper, error)
+	// DataUsage returns Items and Bytes of not deleted data of the user, bytes are counted by models.ItemSize
+	// it is counted by storage, so the data is not loaded
+	DataUsage(ctx context.Context, userID string) (models.Usage, error)
+	// GetChanges returns the data of the user changed after the given position in the user's change feed
+	// if the changes are not known anymore, e.g. some of them were purged, all data is returned with Reset set
+	GetChanges(ctx context.Context, userID string, since int64) (Changes, error)
+	// CheckChanges returns Cursor and Reset of the changes after the given position without Data
+	// resume means since is a checkpoint of interrupted listing of all data,
+	// so it is continued rather than reset again by purged changes
+	CheckChanges(ctx context.Context, userID string, since int64, resume bool) (Changes, error)
+	// EachChange calls fn for every model of the user with Seq after the given position in order of Seq
+	// models are read one by one, so memory use doesn't depend on the size of user's data
+	// it stops and returns the error fn returns
+	EachChange(ctx context.Context, userID string, after int64, fn func(data models.DataWrapper) error) error
+	// SetData sets the model with the given id if it is an edit of the stored revision
+	// edit of another revision is a conflict, result has the stored version then
+	// applied model gets the next Seq of its owner's change feed
+	// previous version of updated model is kept in history
+	// models that were purged are never created again
+	// returned result tells whether the model was applied and why not
+	SetData(ctx context.Context, data models.DataWrapper) (models.SyncResult, error)
+	// SetDataBatch sets all the models like SetData does, atomically:
+	// if it returns error, none of the models are applied
+	// results are in the same order as the models
+	SetDataBatch(ctx context.Context, data []models.DataWrapper) ([]models.SyncResult, error)
+	// PurgeDeleted permanently removes models of all users that were deleted before the given unix time
+	// together with their history, returns number of removed models
+	PurgeDeleted(ctx context.Context, before int64) (int64, error)
+	// GetRevisions returns previous versions of the model with the given id, newest first
+	// Data field of returned models is empty
+	GetRevisions(ctx context.Context, userID, id string) ([]models.DataWrapper, error)
+	// GetRevision returns previous version of the model with the given id and revision
+	// returns ErrRevisionNotFound if there is no such version
+	GetRevision(ctx context.Context, userID, id string, revision int64) (models.DataWrapper, error)
 	// CreateUser creates a new user in the database
 	CreateUser(ctx context.Context, user models.User) error
 	// GetUser returns the user with the given email
 	GetUser(ctx context.Context, email string) (models.User, error)
+	BlobStore
+	ShareStore
+	OrgStore
+	EmergencyStore
+	SendStore
+}
+
+// BlobStore keeps content of files attached to models, see models.Blob
+// blobs are uploaded and downloaded in chunks, so transfer of a big file can be continued
+// uploaded content is kept once per hash in Options.Blobs and counts references of blobs to it,
+// so the same file stored by several blobs takes space once
+type BlobStore interface {
+	// CreateBlob starts upload of the blob, creating the same blob again returns it with its Offset,
+	// so interrupted upload is continued from there
+	// if the owner has uploaded a blob with the same hash and size, the new blob shares its content
+	// and is returned complete, content of other users is never shared without upload
+	// returns ErrBlobExists if the id is taken by a blob with other size, hash or owner
+	CreateBlob(ctx context.Context, blob models.Blob) (models.Blob, error)
+	// GetBlob returns the blob of the user, ErrBlobNotFound if there is no such blob
+	GetBlob(ctx context.Context, userID, id string) (models.Blob, error)
+	// WriteBlob writes the chunk at the offset and returns the blob with new Offset
+	// offset must be the current Offset of the blob, otherwise ErrBlobOffset is returned with the blob
+	// when the last chunk is written the content is checked against Hash,
+	// content that doesn't match is dropped and ErrBlobHash is returned
+	WriteBlob(ctx context.Context, userID, id string, offset int64, chunk []byte) (models.Blob, error)
+	// ReadBlob returns up to length bytes of the blob content from the offset
+	// returns ErrBlobIncomplete if the blob is not uploaded yet
+	ReadBlob(ctx context.Context, userID, id string, offset, length int64) ([]byte, error)
+	// FindBlob returns an uploaded blob of the user with the hash, ErrBlobNotFound if there is no such blob
+	FindBlob(ctx context.Context, userID, hash string) (models.Blob, error)
+	// BlobsSize returns total size of blobs of the user including not uploaded ones
+	// blobs that share content are counted once
+	BlobsSize(ctx context.Context, userID string) (int64, error)
+	// CollectBlobs removes blobs of all users created before the given unix time
+	// that no model or its previous version refers to, returns number of removed blobs
+	// content is removed from Options.Blobs with the last blob that refers to it
+	CollectBlobs(ctx context.Context, before int64) (int64, error)
+}
+
+// ShareStore keeps keys of users and items shared between them, see models.Share
+// keys of shared items are sealed by clients, storage never sees them in plain
+type ShareStore interface {
+	// SetUserKeys sets keys of the user, keys that are set are never replaced
+	// returns ErrUserNotFound if there is no such user and ErrKeysExist if the user has keys
+	SetUserKeys(ctx context.Context, email string, keys models.UserKeys) error
+	// SetShare shares the item with the user, sharing it again replaces the key and permission
+	SetShare(ctx context.Context, share models.Share) error
+	// DeleteShare revokes the share of the owner's item with the user
+	// returns ErrShareNotFound if the item is not shared with the user
+	DeleteShare(ctx context.Context, ownerID, itemID, userID string) error
+	// GetItemShares returns shares of the owner's item, oldest first
+	GetItemShares(ctx context.Context, ownerID, itemID string) ([]models.Share, error)
+	// GetSharedData returns current versions of items shared with the user together with their shares, oldest first
+	// deleted items are skipped
+	GetSharedData(ctx context.Context, userID string) ([]models.SharedItem, error)
+}
+
+// OrgStore keeps organizations, their members and collections, see models.Organization
+// items of organizations are stored as data of their owner which is the organization,
+// the org key is sealed by clients to every member, storage never sees it in plain
+type OrgStore interface {
+	// CreateOrg creates the organization together with its owner
+	CreateOrg(ctx context.Context, org models.Organization, owner models.Member) error
+	// GetOrg returns the organization, ErrOrgNotFound if there is no such organization
+	GetOrg(ctx context.Context, id string) (models.Organization, error)
+	// SetMember adds the user to the organization or replaces its role, key and acceptance
+	SetMember(ctx context.Context, member models.Member) error
+	// GetMember returns the member of the organization, ErrMemberNotFound if the user is not a member
+	GetMember(ctx context.Context, orgID, userID string) (models.Member, error)
+	// GetMembers returns members of the organization, oldest first
+	GetMembers(ctx context.Context, orgID string) ([]models.Member, error)
+	// DeleteMember removes the user from the organization, ErrMemberNotFound if the user is not a member
+	DeleteMember(ctx context.Context, orgID, userID string) error
+	// GetMemberships returns organizations of the user together with its memberships, oldest first
+	GetMemberships(ctx context.Context, userID string) ([]models.Membership, error)
+	// RotateOrgKey sets the version of the org key to version and replaces keys of the members with the given ones
+	// returns ErrOrgNotFound if there is no such organization
+	// and ErrKeyVersion if the current version is not the one before version
+	RotateOrgKey(ctx context.Context, orgID string, version int64, members []models.Member) error
+	// SetCollection creates the collection or renames it
+	// returns ErrCollectionExists if the id is taken by a collection of another organization
+	SetCollection(ctx context.Context, collection models.Collection) error
+	// GetCollections returns collections of the organization, oldest first
+	GetCollections(ctx context.Context, orgID string) ([]models.Collection, error)
+}
+
+// EmergencyStore keeps trusted contacts of users, see models.EmergencyAccess
+// the vault key is sealed by clients to the contact, storage never sees it in plain
+type EmergencyStore interface {
+	// SetEmergency adds the contact of the grantor or replaces its waiting period, key and state of its request
+	SetEmergency(ctx context.Context, access models.EmergencyAccess) error
+	// GetEmergency returns the contact of the grantor, ErrEmergencyNotFound if there is no such contact
+	GetEmergency(ctx context.Context, grantorID, granteeID string) (models.EmergencyAccess, error)
+	// GetEmergencyContacts returns trusted contacts of the grantor, oldest first
+	GetEmergencyContacts(ctx context.Context, grantorID string) ([]models.EmergencyAccess, error)
+	// GetEmergencyGrantors returns users that trust the grantee, oldest first
+	GetEmergencyGrantors(ctx context.Context, granteeID string) ([]models.EmergencyAccess, error)
+	// DeleteEmergency removes the contact of the grantor, ErrEmergencyNotFound if there is no such contact
+	DeleteEmergency(ctx context.Context, grantorID, granteeID string) error
+}
+
+// SendStore keeps secrets shared by links, see models.Send
+// they are encrypted with keys storage never sees
+type SendStore interface {
+	// CreateSend stores the new send
+	CreateSend(ctx context.Context, send models.Send) error
+	// GetSends returns sends of the owner that are not deleted yet, oldest first
+	GetSends(ctx context.Context, ownerID string) ([]models.Send, error)
+	// DeleteSend removes the send of the owner, ErrSendNotFound if the owner has no such send
+	DeleteSend(ctx context.Context, ownerID, id string) error
+	// ViewSend counts a view of the send and returns it, the send is removed after its last view
+	// returns ErrSendNotFound if there is no such send or it can't be viewed at the unix time anymore
+	ViewSend(ctx context.Context, id string, now int64) (models.Send, error)
+	// DeleteExpiredSends removes sends that expired before the unix time, returns number of removed sends
+	DeleteExpiredSends(ctx context.Context, before int64) (int64, error)
 }
 
 // NewStorage returns a new Storage.
-func NewStorage(dataCollection, userCollection *mongo.Collection) *storage {
+func NewStorage(db *mongo.Database, opts Options) *storage {
+	if opts.Blobs == nil {
+		opts.Blobs = blobstore.NewGridFS(db)
+	}
 	return &storage{
-		dataCollection: dataCollection,
-		userCollection: userCollection,
+		opts:              opts,
+		dataCollection:    db.Collection(dataCollectionName),
+		userCollection:    db.Collection(userCollectionName),
+		historyCollection: db.Collection(historyCollectionName),
+		purgedCollection:  db.Collection(purgedCollectionName),
+		seqCollection:     db.Collection(seqCollectionName),
+		blobCollection:    db.Collection(blobCollectionName),
+		chunkCollection:   db.Collection(chunkCollectionName),
+		contentCollection: db.Collection(contentCollectionName),
+		shareCollection:   db.Collection(shareCollectionName),
+		orgCollection:     db.Collection(orgCollectionName),
+		memberCollection:  db.Collection(memberCollectionName),
+		collections:       db.Collection(collectionsName),
+		emergency:         db.Collection(emergencyName),
+		sendCollection:    db.Collection(sendCollectionName),
+	}
+}
+
+// EnsureIndexes creates indexes used by storage queries
+// it is safe to call it on every start
+func (s *storage) EnsureIndexes(ctx context.Context) error {
+	_, err := s.dataCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{"owner_id", 1}}},
+		{Keys: bson.D{{"deleted_at", 1}}},
+		{Keys: bson.D{{"owner_id", 1}, {"seq", 1}}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = s.historyCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{"item_id", 1}, {"owner_id", 1}, {"revision", -1}},
+	})
+	if err != nil {
+		return err
+	}
+	// Blobs are looked up by models and previous versions that refer to them
+	for _, collection := range []*mongo.Collection{s.dataCollection, s.historyCollection} {
+		_, err = collection.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{"owner_id", 1}, {"blobs", 1}}})
+		if err != nil {
+			return err
+		}
+	}
+	_, err = s.blobCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{"owner_id", 1}, {"hash", 1}}},
+		{Keys: bson.D{{"created_at", 1}}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = s.chunkCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{"blob_id", 1}, {"offset", 1}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = s.shareCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{"user_id", 1}, {"created_at", 1}}},
+		{Keys: bson.D{{"owner_id", 1}, {"item_id", 1}}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = s.memberCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{"user_id", 1}, {"created_at", 1}}},
+		{Keys: bson.D{{"org_id", 1}, {"created_at", 1}}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = s.collections.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{"org_id", 1}, {"created_at", 1}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = s.emergency.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{"grantor_id", 1}, {"created_at", 1}}},
+		{Keys: bson.D{{"grantee_id", 1}, {"created_at", 1}}},
+	})
+	if err != nil {
+		return err
 	}
+	_, err = s.sendCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{"owner_id", 1}, {"created_at", 1}}},
+		{Keys: bson.D{{"expires_at", 1}}},
+	})
+	return err
 }
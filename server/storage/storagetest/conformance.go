@@ -0,0 +1,1697 @@
+package storagetest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+	"github.com/gynshu-one/goph-keeper/server/storage"
+	"github.com/gynshu-one/goph-keeper/server/storage/blobstore"
+)
+
+// MaxRevisions is the history limit passed to Factory by the suite
+const MaxRevisions = 3
+
+// Factory returns a fresh and empty storage configured with opts for a single test
+// cleanup of the backend should be registered with t.Cleanup
+type Factory func(t *testing.T, opts storage.Options) storage.Storage
+
+// Run runs the whole conformance suite against storages returned by newStorage
+// blob content is kept in a memory backend the suite passes in Options.Blobs
+func Run(t *testing.T, newStorage Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s storage.Storage)
+		// blobTest is used instead of test when it needs to see stored objects
+		blobTest func(t *testing.T, s storage.Storage, blobs *objects)
+	}{
+		{"CreateAndGetUser", testCreateAndGetUser, nil},
+		{"DuplicateUser", testDuplicateUser, nil},
+		{"UnknownUser", testUnknownUser, nil},
+		{"EmptyData", testEmptyData, nil},
+		{"InsertData", testInsertData, nil},
+		{"UpdateCurrentRevision", testUpdateCurrentRevision, nil},
+		{"UpdateIgnoresClock", testUpdateIgnoresClock, nil},
+		{"UpdateOldRevisionConflict", testUpdateOldRevisionConflict, nil},
+		{"ResendIsStale", testResendIsStale, nil},
+		{"UpsertKeepsImmutableFields", testUpsertKeepsImmutableFields, nil},
+		{"MoveToFolder", testMoveToFolder, nil},
+		{"SealLabel", testSealLabel, nil},
+		{"OwnershipIsolation", testOwnershipIsolation, nil},
+		{"ForeignUpdateIgnored", testForeignUpdateIgnored, nil},
+		{"DataByIDs", testDataByIDs, nil},
+		{"DataUsage", testDataUsage, nil},
+		{"SoftDelete", testSoftDelete, nil},
+		{"SoftDeleteOldRevisionConflict", testSoftDeleteOldRevisionConflict, nil},
+		{"Undelete", testUndelete, nil},
+		{"RevisionAssigned", testRevisionAssigned, nil},
+		{"HistoryKept", testHistoryKept, nil},
+		{"HistoryLimit", testHistoryLimit, nil},
+		{"HistoryIsolation", testHistoryIsolation, nil},
+		{"HistoryOfSoftDelete", testHistoryOfSoftDelete, nil},
+		{"RevisionNotFound", testRevisionNotFound, nil},
+		{"PurgeDeleted", testPurgeDeleted, nil},
+		{"PurgedNotRecreated", testPurgedNotRecreated, nil},
+		{"BatchResults", testBatchResults, nil},
+		{"BatchSameItemTwice", testBatchSameItemTwice, nil},
+		{"EmptyBatch", testEmptyBatch, nil},
+		{"ChangesFirstSync", testChangesFirstSync, nil},
+		{"ChangesSinceCursor", testChangesSinceCursor, nil},
+		{"ChangesSeqPerUser", testChangesSeqPerUser, nil},
+		{"ChangesUnknownCursor", testChangesUnknownCursor, nil},
+		{"ChangesAfterPurge", testChangesAfterPurge, nil},
+		{"EachChange", testEachChange, nil},
+		{"CheckChangesResume", testCheckChangesResume, nil},
+		{"BlobRefsKept", testBlobRefsKept, nil},
+		{"BlobUpload", testBlobUpload, nil},
+		{"BlobResume", testBlobResume, nil},
+		{"BlobHashMismatch", testBlobHashMismatch, nil},
+		{"BlobIsolation", testBlobIsolation, nil},
+		{"BlobDedup", nil, testBlobDedup},
+		{"CollectBlobs", nil, testCollectBlobs},
+		{"UserKeys", testUserKeys, nil},
+		{"Shares", testShares, nil},
+		{"SharedData", testSharedData, nil},
+		{"ItemKeyKept", testItemKeyKept, nil},
+		{"Orgs", testOrgs, nil},
+		{"OrgMembers", testOrgMembers, nil},
+		{"RotateOrgKey", testRotateOrgKey, nil},
+		{"Collections", testCollections, nil},
+		{"Emergency", testEmergency, nil},
+		{"Sends", testSends, nil},
+		{"SendViews", testSendViews, nil},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			blobs := &objects{Backend: blobstore.NewMemory(), keys: make(map[string]bool)}
+			s := newStorage(t, storage.Options{MaxRevisions: MaxRevisions, Blobs: blobs})
+			if tt.blobTest != nil {
+				tt.blobTest(t, s, blobs)
+				return
+			}
+			tt.test(t, s)
+		})
+	}
+}
+
+// objects is a blobstore.Backend that remembers keys of stored objects,
+// so tests see when content is shared and when it is removed
+type objects struct {
+	blobstore.Backend
+	mu   sync.Mutex
+	keys map[string]bool
+}
+
+func (o *objects) Put(ctx context.Context, key string, content io.Reader, size int64) error {
+	if err := o.Backend.Put(ctx, key, content, size); err != nil {
+		return err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.keys[key] = true
+	return nil
+}
+
+func (o *objects) Delete(ctx context.Context, key string) error {
+	if err := o.Backend.Delete(ctx, key); err != nil {
+		return err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.keys, key)
+	return nil
+}
+
+func (o *objects) count() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.keys)
+}
+
+func testCreateAndGetUser(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	user := models.User{Email: "user@example.com", Passphrase: "hash", CreatedAt: 1, UpdatedAt: 1}
+	if err := s.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser returned an error: %v", err)
+	}
+	got, err := s.GetUser(ctx, user.Email)
+	if err != nil {
+		t.Fatalf("GetUser returned an error: %v", err)
+	}
+	if got != user {
+		t.Errorf("Expected user %+v, got %+v", user, got)
+	}
+}
+
+func testDuplicateUser(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	user := models.User{Email: "user@example.com", Passphrase: "hash"}
+	if err := s.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser returned an error: %v", err)
+	}
+	user.Passphrase = "other"
+	err := s.CreateUser(ctx, user)
+	if !errors.Is(err, storage.ErrUserExists) {
+		t.Fatalf("Expected %v, got %v", storage.ErrUserExists, err)
+	}
+	got, err := s.GetUser(ctx, user.Email)
+	if err != nil {
+		t.Fatalf("GetUser returned an error: %v", err)
+	}
+	if got.Passphrase != "hash" {
+		t.Errorf("Duplicate CreateUser overwrote the user")
+	}
+}
+
+func testUnknownUser(t *testing.T, s storage.Storage) {
+	_, err := s.GetUser(context.Background(), "nobody@example.com")
+	if !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("Expected %v, got %v", storage.ErrUserNotFound, err)
+	}
+}
+
+func testEmptyData(t *testing.T, s storage.Storage) {
+	data, err := s.GetData(context.Background(), "user1")
+	if err != nil {
+		t.Fatalf("GetData for a user without data returned an error: %v", err)
+	}
+	if len(data) != 0 {
+		t.Errorf("Expected no data, got %d items", len(data))
+	}
+}
+
+func testInsertData(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	items := []models.DataWrapper{
+		wrapper("1", "user1", 10, "first"),
+		wrapper("2", "user1", 10, "second"),
+	}
+	for _, item := range items {
+		mustSet(t, s, item)
+	}
+	got := byID(t, s, "user1")
+	if len(got) != len(items) {
+		t.Fatalf("Expected %d items, got %d", len(items), len(got))
+	}
+	for _, item := range items {
+		assertWrapper(t, got[item.ID], item)
+	}
+
+	// Returned data must not share memory with storage
+	data, err := s.GetData(ctx, "user1")
+	if err != nil {
+		t.Fatalf("GetData returned an error: %v", err)
+	}
+	data[0].Data[0] = 'X'
+	for _, item := range items {
+		assertWrapper(t, byID(t, s, "user1")[item.ID], item)
+	}
+}
+
+func testUpdateCurrentRevision(t *testing.T, s storage.Storage) {
+	mustSet(t, s, wrapper("1", "user1", 10, "old"))
+	newer := wrapper("1", "user1", 20, "new")
+	newer.Name = "renamed"
+	assertStatus(t, mustUpdate(t, s, newer), models.StatusApplied)
+
+	got := byID(t, s, "user1")
+	if len(got) != 1 {
+		t.Fatalf("Upsert created a duplicate, got %d items", len(got))
+	}
+	assertWrapper(t, got["1"], newer)
+}
+
+func testUpdateIgnoresClock(t *testing.T, s storage.Storage) {
+	mustSet(t, s, wrapper("1", "user1", 20, "first"))
+	// Device with clock behind edits the current revision
+	edited := wrapper("1", "user1", 10, "edited")
+	assertStatus(t, mustUpdate(t, s, edited), models.StatusApplied)
+
+	assertWrapper(t, byID(t, s, "user1")["1"], edited)
+}
+
+func testUpdateOldRevisionConflict(t *testing.T, s storage.Storage) {
+	mustSet(t, s, wrapper("1", "user1", 10, "first"))
+	// Both devices edit revision 1, the first one wins
+	edited := wrapper("1", "user1", 20, "edited on device A")
+	edited.Revision = 1
+	assertStatus(t, mustSet(t, s, edited), models.StatusApplied)
+
+	concurrent := wrapper("1", "user1", 30, "edited on device B")
+	concurrent.Revision = 1
+	result, err := s.SetData(context.Background(), concurrent)
+	if err != nil {
+		t.Fatalf("SetData returned an error: %v", err)
+	}
+	assertStatus(t, result.Status, models.StatusConflict)
+
+	// Conflict carries the current version
+	stored := byID(t, s, "user1")["1"]
+	if result.Server == nil {
+		t.Fatalf("Conflict has no server version")
+	}
+	assertWrapper(t, *result.Server, edited)
+	if result.Server.Revision != stored.Revision {
+		t.Errorf("Conflict has revision %d, want %d", result.Server.Revision, stored.Revision)
+	}
+	assertWrapper(t, stored, edited)
+}
+
+func testResendIsStale(t *testing.T, s storage.Storage) {
+	first := wrapper("1", "user1", 10, "first")
+	mustSet(t, s, first)
+	edited := wrapper("1", "user1", 20, "edited")
+	edited.Revision = 1
+	mustSet(t, s, edited)
+
+	// Client didn't get the response and sends the same edit again
+	assertStatus(t, mustSet(t, s, edited), models.StatusStale)
+	if got := byID(t, s, "user1")["1"].Revision; got != 2 {
+		t.Errorf("Resend changed revision to %d", got)
+	}
+}
+
+func testUpsertKeepsImmutableFields(t *testing.T, s storage.Storage) {
+	first := wrapper("1", "user1", 10, "first")
+	mustSet(t, s, first)
+
+	update := wrapper("1", "user1", 20, "second")
+	update.Type = models.BankCardType
+	update.CreatedAt = 15
+	mustUpdate(t, s, update)
+
+	got := byID(t, s, "user1")["1"]
+	if got.Type != first.Type {
+		t.Errorf("Update changed type from %s to %s", first.Type, got.Type)
+	}
+	if got.CreatedAt != first.CreatedAt {
+		t.Errorf("Update changed created_at from %d to %d", first.CreatedAt, got.CreatedAt)
+	}
+	if !bytes.Equal(got.Data, update.Data) {
+		t.Errorf("Update wasn't applied")
+	}
+}
+
+func testMoveToFolder(t *testing.T, s storage.Storage) {
+	mustSet(t, s, wrapper("1", "user1", 10, "item"))
+
+	// Moving keeps the encrypted data, it is a change anyway
+	moved := wrapper("1", "user1", 20, "item")
+	moved.FolderID = "folder"
+	assertStatus(t, mustUpdate(t, s, moved), models.StatusApplied)
+	if got := byID(t, s, "user1")["1"]; got.FolderID != "folder" || got.Revision != 2 {
+		t.Errorf("Expected item in folder at revision 2, got %+v", got)
+	}
+	previous, err := s.GetRevision(context.Background(), "user1", "1", 1)
+	if err != nil {
+		t.Fatalf("GetRevision returned an error: %v", err)
+	}
+	if previous.FolderID != "" {
+		t.Errorf("Expected previous version in the root, got folder %s", previous.FolderID)
+	}
+}
+
+func testSealLabel(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	mustSet(t, s, wrapper("1", "user1", 10, "first"))
+	mustUpdate(t, s, wrapper("1", "user1", 20, "second"))
+
+	// Sealing replaces the type and removes plaintext names of previous versions
+	sealed := wrapper("1", "user1", 30, "second")
+	sealed.Type, sealed.Name, sealed.LabelVersion = models.SealedType, "c2VhbGVk", models.LabelSealed
+	assertStatus(t, mustUpdate(t, s, sealed), models.StatusApplied)
+	got := byID(t, s, "user1")["1"]
+	if got.Type != models.SealedType || got.LabelVersion != models.LabelSealed || got.Name != sealed.Name {
+		t.Errorf("Label wasn't sealed: %+v", got)
+	}
+	revisions, err := s.GetRevisions(ctx, "user1", "1")
+	if err != nil {
+		t.Fatalf("GetRevisions returned an error: %v", err)
+	}
+	if len(revisions) != 0 {
+		t.Errorf("Expected plaintext revisions to be removed, got %+v", revisions)
+	}
+
+	// Versions with sealed labels are kept
+	sealed.UpdatedAt, sealed.Data = 40, []byte("third")
+	assertStatus(t, mustUpdate(t, s, sealed), models.StatusApplied)
+	previous, err := s.GetRevision(ctx, "user1", "1", 3)
+	if err != nil {
+		t.Fatalf("GetRevision returned an error: %v", err)
+	}
+	if previous.Type != models.SealedType || previous.LabelVersion != models.LabelSealed {
+		t.Errorf("Expected sealed previous version, got %+v", previous)
+	}
+
+	// Label can't become plaintext again
+	plain := wrapper("1", "user1", 50, "fourth")
+	assertStatus(t, mustUpdate(t, s, plain), models.StatusRejected)
+	if got = byID(t, s, "user1")["1"]; got.Type != models.SealedType || got.Revision != 4 {
+		t.Errorf("Plaintext label was stored: %+v", got)
+	}
+}
+
+func testOwnershipIsolation(t *testing.T, s storage.Storage) {
+	mustSet(t, s, wrapper("1", "user1", 10, "first"))
+	mustSet(t, s, wrapper("2", "user2", 10, "second"))
+
+	got1 := byID(t, s, "user1")
+	if _, ok := got1["2"]; ok || len(got1) != 1 {
+		t.Errorf("user1 got data of another user: %v", got1)
+	}
+	got2 := byID(t, s, "user2")
+	if _, ok := got2["1"]; ok || len(got2) != 1 {
+		t.Errorf("user2 got data of another user: %v", got2)
+	}
+}
+
+func testForeignUpdateIgnored(t *testing.T, s storage.Storage) {
+	original := wrapper("1", "user1", 10, "mine")
+	mustSet(t, s, original)
+	assertStatus(t, mustSet(t, s, wrapper("1", "user2", 20, "stolen")), models.StatusRejected)
+
+	assertWrapper(t, byID(t, s, "user1")["1"], original)
+	if got := byID(t, s, "user2"); len(got) != 0 {
+		t.Errorf("Foreign update created data for user2: %v", got)
+	}
+}
+
+func testDataByIDs(t *testing.T, s storage.Storage) {
+	mustSet(t, s, wrapper("1", "user1", 10, "first"))
+	mustSet(t, s, wrapper("2", "user1", 10, "second"))
+	mustSet(t, s, wrapper("3", "user2", 10, "third"))
+
+	data, err := s.GetDataByIDs(context.Background(), "user1", []string{"2", "3", "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 1 || data[0].ID != "2" || string(data[0].Data) != "second" {
+		t.Errorf("Expected only item 2 of user1, got %v", data)
+	}
+	if data, err = s.GetDataByIDs(context.Background(), "user1", nil); err != nil || len(data) != 0 {
+		t.Errorf("Expected no data for no ids, got %v, %v", data, err)
+	}
+}
+
+func testDataUsage(t *testing.T, s storage.Storage) {
+	first := wrapper("1", "user1", 10, "first")
+	first.Key = []byte("key")
+	mustSet(t, s, first)
+	mustSet(t, s, wrapper("2", "user1", 10, "second"))
+	mustSet(t, s, wrapper("3", "user2", 10, "third"))
+	deleted := wrapper("2", "user1", 20, "")
+	deleted.Data = nil
+	deleted.DeletedAt = 20
+	mustUpdate(t, s, deleted)
+
+	usage, err := s.DataUsage(context.Background(), "user1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Items != 1 || usage.Bytes != models.ItemSize(first) {
+		t.Errorf("Expected 1 item of %d bytes, got %+v", models.ItemSize(first), usage)
+	}
+	if usage, err = s.DataUsage(context.Background(), "nobody"); err != nil || usage.Items != 0 || usage.Bytes != 0 {
+		t.Errorf("Expected no usage, got %+v, %v", usage, err)
+	}
+}
+
+func testSoftDelete(t *testing.T, s storage.Storage) {
+	mustSet(t, s, wrapper("1", "user1", 10, "data"))
+	deleted := wrapper("1", "user1", 20, "")
+	deleted.Data = nil
+	deleted.DeletedAt = 20
+	mustUpdate(t, s, deleted)
+
+	got, ok := byID(t, s, "user1")["1"]
+	if !ok {
+		t.Fatalf("Soft deleted item must still be returned")
+	}
+	if got.DeletedAt != 20 {
+		t.Errorf("Expected deleted_at 20, got %d", got.DeletedAt)
+	}
+	if len(got.Data) != 0 {
+		t.Errorf("Soft deleted item still has data")
+	}
+}
+
+func testSoftDeleteOldRevisionConflict(t *testing.T, s storage.Storage) {
+	mustSet(t, s, wrapper("1", "user1", 10, "data"))
+	edited := wrapper("1", "user1", 20, "edited")
+	mustUpdate(t, s, edited)
+
+	// Deletion of the first revision must not drop the edit
+	deleted := wrapper("1", "user1", 30, "")
+	deleted.Data = nil
+	deleted.DeletedAt = 30
+	deleted.Revision = 1
+	assertStatus(t, mustSet(t, s, deleted), models.StatusConflict)
+
+	assertWrapper(t, byID(t, s, "user1")["1"], edited)
+}
+
+func testUndelete(t *testing.T, s storage.Storage) {
+	deleted := wrapper("1", "user1", 10, "")
+	deleted.Data = nil
+	deleted.DeletedAt = 10
+	mustSet(t, s, deleted)
+	restored := wrapper("1", "user1", 20, "restored")
+	mustUpdate(t, s, restored)
+
+	assertWrapper(t, byID(t, s, "user1")["1"], restored)
+}
+
+func testRevisionAssigned(t *testing.T, s storage.Storage) {
+	first := wrapper("1", "user1", 10, "first")
+	first.Revision = 42
+	mustSet(t, s, first)
+	if got := byID(t, s, "user1")["1"].Revision; got != 1 {
+		t.Errorf("Expected revision 1 for a new item, got %d", got)
+	}
+
+	mustUpdate(t, s, wrapper("1", "user1", 20, "second"))
+	if got := byID(t, s, "user1")["1"].Revision; got != 2 {
+		t.Errorf("Expected revision 2 after update, got %d", got)
+	}
+
+	// Ignored updates must not change revision
+	conflicting := wrapper("1", "user1", 30, "conflicting")
+	conflicting.Revision = 1
+	mustSet(t, s, conflicting)
+	if got := byID(t, s, "user1")["1"].Revision; got != 2 {
+		t.Errorf("Expected revision 2 after conflicting update, got %d", got)
+	}
+}
+
+func testHistoryKept(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	first := wrapper("1", "user1", 10, "first")
+	mustSet(t, s, first)
+	mustUpdate(t, s, wrapper("1", "user1", 20, "second"))
+	mustUpdate(t, s, wrapper("1", "user1", 30, "third"))
+
+	revisions, err := s.GetRevisions(ctx, "user1", "1")
+	if err != nil {
+		t.Fatalf("GetRevisions returned an error: %v", err)
+	}
+	if len(revisions) != 2 {
+		t.Fatalf("Expected 2 revisions, got %d", len(revisions))
+	}
+	if revisions[0].Revision != 2 || revisions[1].Revision != 1 {
+		t.Errorf("Expected revisions 2 and 1 newest first, got %d and %d", revisions[0].Revision, revisions[1].Revision)
+	}
+	for _, r := range revisions {
+		if len(r.Data) != 0 {
+			t.Errorf("GetRevisions must not return data")
+		}
+	}
+
+	got, err := s.GetRevision(ctx, "user1", "1", 1)
+	if err != nil {
+		t.Fatalf("GetRevision returned an error: %v", err)
+	}
+	first.Revision = 1
+	assertWrapper(t, got, first)
+	if got.Revision != 1 {
+		t.Errorf("Expected revision 1, got %d", got.Revision)
+	}
+}
+
+func testHistoryLimit(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	for i := int64(1); i <= MaxRevisions+3; i++ {
+		mustUpdate(t, s, wrapper("1", "user1", i*10, "data "+strconv.FormatInt(i, 10)))
+	}
+	revisions, err := s.GetRevisions(ctx, "user1", "1")
+	if err != nil {
+		t.Fatalf("GetRevisions returned an error: %v", err)
+	}
+	if len(revisions) != MaxRevisions {
+		t.Fatalf("Expected %d revisions, got %d", MaxRevisions, len(revisions))
+	}
+	// Current revision is MaxRevisions+3, so the newest archived is MaxRevisions+2
+	if oldest := revisions[len(revisions)-1].Revision; oldest != 3 {
+		t.Errorf("Expected the oldest kept revision to be 3, got %d", oldest)
+	}
+	if _, err = s.GetRevision(ctx, "user1", "1", 1); !errors.Is(err, storage.ErrRevisionNotFound) {
+		t.Errorf("Expected %v for a dropped revision, got %v", storage.ErrRevisionNotFound, err)
+	}
+}
+
+func testHistoryIsolation(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	mustSet(t, s, wrapper("1", "user1", 10, "first"))
+	mustUpdate(t, s, wrapper("1", "user1", 20, "second"))
+
+	revisions, err := s.GetRevisions(ctx, "user2", "1")
+	if err != nil {
+		t.Fatalf("GetRevisions returned an error: %v", err)
+	}
+	if len(revisions) != 0 {
+		t.Errorf("user2 got history of another user: %v", revisions)
+	}
+	if _, err = s.GetRevision(ctx, "user2", "1", 1); !errors.Is(err, storage.ErrRevisionNotFound) {
+		t.Errorf("Expected %v, got %v", storage.ErrRevisionNotFound, err)
+	}
+}
+
+func testHistoryOfSoftDelete(t *testing.T, s storage.Storage) {
+	original := wrapper("1", "user1", 10, "data")
+	mustSet(t, s, original)
+	deleted := wrapper("1", "user1", 20, "")
+	deleted.Data = nil
+	deleted.DeletedAt = 20
+	mustUpdate(t, s, deleted)
+
+	// Deleted data can be restored from history
+	got, err := s.GetRevision(context.Background(), "user1", "1", 1)
+	if err != nil {
+		t.Fatalf("GetRevision returned an error: %v", err)
+	}
+	assertWrapper(t, got, original)
+}
+
+func testRevisionNotFound(t *testing.T, s storage.Storage) {
+	_, err := s.GetRevision(context.Background(), "user1", "unknown", 1)
+	if !errors.Is(err, storage.ErrRevisionNotFound) {
+		t.Errorf("Expected %v, got %v", storage.ErrRevisionNotFound, err)
+	}
+}
+
+func testPurgeDeleted(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	live := wrapper("live", "user1", 10, "data")
+	mustSet(t, s, live)
+	for _, id := range []string{"old", "recent", "other"} {
+		owner := "user1"
+		if id == "other" {
+			owner = "user2"
+		}
+		mustSet(t, s, wrapper(id, owner, 10, "data"))
+		deleted := wrapper(id, owner, 20, "")
+		deleted.Data = nil
+		deleted.DeletedAt = 20
+		if id == "recent" {
+			deleted.UpdatedAt, deleted.DeletedAt = 100, 100
+		}
+		mustUpdate(t, s, deleted)
+	}
+
+	count, err := s.PurgeDeleted(ctx, 50)
+	if err != nil {
+		t.Fatalf("PurgeDeleted returned an error: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 purged items, got %d", count)
+	}
+
+	got := byID(t, s, "user1")
+	if _, ok := got["old"]; ok {
+		t.Errorf("Old tombstone wasn't purged")
+	}
+	if _, ok := got["recent"]; !ok {
+		t.Errorf("Recent tombstone was purged")
+	}
+	assertWrapper(t, got["live"], live)
+	if got := byID(t, s, "user2"); len(got) != 0 {
+		t.Errorf("Tombstones of other users weren't purged")
+	}
+
+	// History of purged items is removed as well
+	revisions, err := s.GetRevisions(ctx, "user1", "old")
+	if err != nil {
+		t.Fatalf("GetRevisions returned an error: %v", err)
+	}
+	if len(revisions) != 0 {
+		t.Errorf("History of purged item wasn't removed")
+	}
+
+	// Nothing left to purge
+	count, err = s.PurgeDeleted(ctx, 50)
+	if err != nil {
+		t.Fatalf("PurgeDeleted returned an error: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected nothing to purge, got %d", count)
+	}
+}
+
+func testPurgedNotRecreated(t *testing.T, s storage.Storage) {
+	mustSet(t, s, wrapper("1", "user1", 10, "data"))
+	deleted := wrapper("1", "user1", 20, "")
+	deleted.Data = nil
+	deleted.DeletedAt = 20
+	mustUpdate(t, s, deleted)
+	if _, err := s.PurgeDeleted(context.Background(), 50); err != nil {
+		t.Fatalf("PurgeDeleted returned an error: %v", err)
+	}
+
+	// Client that was offline since before deletion sends the item back
+	assertStatus(t, mustSet(t, s, wrapper("1", "user1", 10, "data")), models.StatusRejected)
+	// Even with newer update time
+	assertStatus(t, mustSet(t, s, wrapper("1", "user1", 60, "edited offline")), models.StatusRejected)
+
+	if got := byID(t, s, "user1"); len(got) != 0 {
+		t.Errorf("Purged item was created again: %v", got)
+	}
+}
+
+func testBatchResults(t *testing.T, s storage.Storage) {
+	mustSet(t, s, wrapper("stale", "user1", 20, "sent twice"))
+	mustSet(t, s, wrapper("foreign", "user2", 10, "theirs"))
+	mustSet(t, s, wrapper("updated", "user1", 10, "old"))
+	mustSet(t, s, wrapper("conflict", "user1", 10, "old"))
+	mustUpdate(t, s, wrapper("conflict", "user1", 20, "edited"))
+
+	updated := wrapper("updated", "user1", 20, "new")
+	updated.Revision = 1
+	conflicting := wrapper("conflict", "user1", 30, "edited too")
+	conflicting.Revision = 1
+	batch := []models.DataWrapper{
+		wrapper("new", "user1", 10, "new"),
+		wrapper("stale", "user1", 20, "sent twice"),
+		wrapper("foreign", "user1", 20, "stolen"),
+		updated,
+		conflicting,
+	}
+	results, err := s.SetDataBatch(context.Background(), batch)
+	if err != nil {
+		t.Fatalf("SetDataBatch returned an error: %v", err)
+	}
+	want := []models.SyncStatus{
+		models.StatusApplied, models.StatusStale, models.StatusRejected, models.StatusApplied, models.StatusConflict}
+	if len(results) != len(want) {
+		t.Fatalf("Got %d results, want %d", len(results), len(want))
+	}
+	for i := range want {
+		if results[i].ID != batch[i].ID {
+			t.Errorf("Result %d is for %s, want %s", i, results[i].ID, batch[i].ID)
+		}
+		assertStatus(t, results[i].Status, want[i])
+	}
+
+	got := byID(t, s, "user1")
+	if len(got) != 4 {
+		t.Fatalf("Expected 4 items, got %d", len(got))
+	}
+	assertWrapper(t, got["new"], batch[0])
+	assertWrapper(t, got["updated"], batch[3])
+}
+
+func testBatchSameItemTwice(t *testing.T, s storage.Storage) {
+	second := wrapper("1", "user1", 20, "second")
+	second.Revision = 1
+	results, err := s.SetDataBatch(context.Background(), []models.DataWrapper{
+		wrapper("1", "user1", 10, "first"),
+		second,
+	})
+	if err != nil {
+		t.Fatalf("SetDataBatch returned an error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Got %d results, want 2", len(results))
+	}
+	assertStatus(t, results[0].Status, models.StatusApplied)
+	assertStatus(t, results[1].Status, models.StatusApplied)
+
+	got := byID(t, s, "user1")["1"]
+	assertWrapper(t, got, second)
+	if got.Revision != 2 {
+		t.Errorf("Revision is %d, want 2", got.Revision)
+	}
+}
+
+func testEmptyBatch(t *testing.T, s storage.Storage) {
+	results, err := s.SetDataBatch(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("SetDataBatch returned an error: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("Got %d results for empty batch", len(results))
+	}
+}
+
+func testChangesFirstSync(t *testing.T, s storage.Storage) {
+	changes := mustChanges(t, s, "user1", 0)
+	if !changes.Reset || changes.Cursor != 0 || len(changes.Data) != 0 {
+		t.Errorf("Unexpected changes of empty storage: %+v", changes)
+	}
+
+	mustSet(t, s, wrapper("1", "user1", 10, "first"))
+	mustSet(t, s, wrapper("2", "user1", 10, "second"))
+	changes = mustChanges(t, s, "user1", 0)
+	if !changes.Reset || changes.Cursor != 2 || len(changes.Data) != 2 {
+		t.Errorf("First sync didn't return all data: %+v", changes)
+	}
+}
+
+func testChangesSinceCursor(t *testing.T, s storage.Storage) {
+	mustSet(t, s, wrapper("1", "user1", 10, "first"))
+	mustSet(t, s, wrapper("2", "user1", 10, "second"))
+	cursor := mustChanges(t, s, "user1", 0).Cursor
+
+	// Nothing changed
+	changes := mustChanges(t, s, "user1", cursor)
+	if changes.Reset || changes.Cursor != cursor || len(changes.Data) != 0 {
+		t.Errorf("Expected no changes, got %+v", changes)
+	}
+
+	// Writes that were not applied are not changes
+	mustSet(t, s, wrapper("2", "user1", 10, "second"))
+	conflicting := wrapper("2", "user1", 5, "conflicting")
+	conflicting.Revision = 7
+	mustSet(t, s, conflicting)
+	deleted := wrapper("2", "user1", 20, "")
+	deleted.DeletedAt = 20
+	mustUpdate(t, s, deleted)
+	mustUpdate(t, s, wrapper("1", "user1", 20, "edited"))
+	mustSet(t, s, wrapper("3", "user1", 20, "third"))
+
+	changes = mustChanges(t, s, "user1", cursor)
+	if changes.Reset || changes.Cursor != cursor+3 {
+		t.Errorf("Unexpected changes %+v", changes)
+	}
+	if len(changes.Data) != 3 {
+		t.Fatalf("Expected 3 changes, got %d", len(changes.Data))
+	}
+	for i, id := range []string{"2", "1", "3"} {
+		if changes.Data[i].ID != id {
+			t.Errorf("Change %d is %s, want %s", i, changes.Data[i].ID, id)
+		}
+		if changes.Data[i].Seq != cursor+int64(i)+1 {
+			t.Errorf("Change %d has seq %d, want %d", i, changes.Data[i].Seq, cursor+int64(i)+1)
+		}
+	}
+	if changes.Data[0].DeletedAt != 20 {
+		t.Errorf("Deletion is not in changes: %+v", changes.Data[0])
+	}
+}
+
+func testChangesSeqPerUser(t *testing.T, s storage.Storage) {
+	mustSet(t, s, wrapper("1", "user1", 10, "first"))
+	cursor := mustChanges(t, s, "user1", 0).Cursor
+
+	mustSet(t, s, wrapper("2", "user2", 10, "theirs"))
+	mustSet(t, s, wrapper("1", "user2", 20, "stolen"))
+
+	changes := mustChanges(t, s, "user1", cursor)
+	if changes.Reset || changes.Cursor != cursor || len(changes.Data) != 0 {
+		t.Errorf("Changes of another user are in the feed: %+v", changes)
+	}
+	if got := mustChanges(t, s, "user2", 0); got.Cursor != 1 || len(got.Data) != 1 {
+		t.Errorf("Unexpected changes of user2: %+v", got)
+	}
+}
+
+func testChangesUnknownCursor(t *testing.T, s storage.Storage) {
+	mustSet(t, s, wrapper("1", "user1", 10, "first"))
+
+	// E.g. client synced with another server
+	changes := mustChanges(t, s, "user1", 100)
+	if !changes.Reset || changes.Cursor != 1 || len(changes.Data) != 1 {
+		t.Errorf("Expected all data for unknown cursor, got %+v", changes)
+	}
+}
+
+func testChangesAfterPurge(t *testing.T, s storage.Storage) {
+	mustSet(t, s, wrapper("1", "user1", 10, "first"))
+	cursor := mustChanges(t, s, "user1", 0).Cursor
+
+	deleted := wrapper("2", "user1", 20, "")
+	deleted.DeletedAt = 20
+	mustSet(t, s, deleted)
+	seen := mustChanges(t, s, "user1", cursor).Cursor
+	mustSet(t, s, wrapper("3", "user1", 30, "third"))
+
+	if _, err := s.PurgeDeleted(context.Background(), 25); err != nil {
+		t.Fatalf("PurgeDeleted returned an error: %v", err)
+	}
+
+	// Client that didn't see the deletion can't get it anymore
+	changes := mustChanges(t, s, "user1", cursor)
+	if !changes.Reset || len(changes.Data) != 2 {
+		t.Errorf("Expected all data after purge, got %+v", changes)
+	}
+	// Client that did see it gets only new changes
+	changes = mustChanges(t, s, "user1", seen)
+	if changes.Reset || len(changes.Data) != 1 || changes.Data[0].ID != "3" {
+		t.Errorf("Expected only new changes, got %+v", changes)
+	}
+}
+
+func testEachChange(t *testing.T, s storage.Storage) {
+	mustSet(t, s, wrapper("1", "user1", 10, "first"))
+	mustSet(t, s, wrapper("2", "user1", 10, "second"))
+	mustSet(t, s, wrapper("3", "user2", 10, "other"))
+	mustUpdate(t, s, wrapper("1", "user1", 20, "first updated"))
+
+	// Models come in order of their last change
+	var ids []string
+	err := s.EachChange(context.Background(), "user1", 0, func(data models.DataWrapper) error {
+		ids = append(ids, data.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("EachChange returned an error: %v", err)
+	}
+	if strings.Join(ids, ",") != "2,1" {
+		t.Errorf("Expected models 2,1, got %v", ids)
+	}
+
+	// Only models after the position, fn error stops the listing
+	stop := errors.New("stop")
+	ids = nil
+	err = s.EachChange(context.Background(), "user1", 1, func(data models.DataWrapper) error {
+		ids = append(ids, data.ID)
+		if data.Seq <= 1 {
+			t.Errorf("Got model %s at position %d", data.ID, data.Seq)
+		}
+		return stop
+	})
+	if !errors.Is(err, stop) || len(ids) != 1 {
+		t.Errorf("Expected listing stopped after one model, got %v and %v", ids, err)
+	}
+}
+
+func testCheckChangesResume(t *testing.T, s storage.Storage) {
+	mustSet(t, s, wrapper("1", "user1", 10, "first"))
+	deleted := wrapper("2", "user1", 10, "")
+	deleted.DeletedAt = 10
+	mustSet(t, s, deleted)
+	mustSet(t, s, wrapper("3", "user1", 30, "third"))
+	if _, err := s.PurgeDeleted(context.Background(), 15); err != nil {
+		t.Fatalf("PurgeDeleted returned an error: %v", err)
+	}
+	check := func(since int64, resume bool) storage.Changes {
+		t.Helper()
+		changes, err := s.CheckChanges(context.Background(), "user1", since, resume)
+		if err != nil {
+			t.Fatalf("CheckChanges returned an error: %v", err)
+		}
+		if changes.Cursor != 3 || changes.Data != nil {
+			t.Errorf("Unexpected changes %+v", changes)
+		}
+		return changes
+	}
+
+	if !check(0, false).Reset || !check(0, true).Reset || !check(10, true).Reset {
+		t.Errorf("Expected reset without known cursor")
+	}
+	// Client that didn't see the deletion can't get it anymore
+	if !check(1, false).Reset {
+		t.Errorf("Expected reset after purge")
+	}
+	// Listing of all data is continued after purge
+	if check(1, true).Reset {
+		t.Errorf("Expected resumed listing not to reset")
+	}
+	if check(2, false).Reset {
+		t.Errorf("Expected no reset for cursor after purged change")
+	}
+}
+
+func testBlobRefsKept(t *testing.T, s storage.Storage) {
+	data := wrapper("1", "user1", 10, "file")
+	data.Blobs = []string{"blob1"}
+	mustSet(t, s, data)
+	data.Blobs = []string{"blob2"}
+	assertStatus(t, mustUpdate(t, s, data), models.StatusApplied)
+
+	if blobs := byID(t, s, "user1")["1"].Blobs; len(blobs) != 1 || blobs[0] != "blob2" {
+		t.Errorf("Expected blob2 reference, got %v", blobs)
+	}
+	previous, err := s.GetRevision(context.Background(), "user1", "1", 1)
+	if err != nil {
+		t.Fatalf("GetRevision returned an error: %v", err)
+	}
+	if len(previous.Blobs) != 1 || previous.Blobs[0] != "blob1" {
+		t.Errorf("Expected blob1 reference in history, got %v", previous.Blobs)
+	}
+}
+
+func testBlobUpload(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	content := bytes.Repeat([]byte("0123456789"), 10)
+	blob := mustCreateBlob(t, s, "blob1", "user1", content)
+
+	// Blob can't be read until all chunks are written
+	if _, err := s.ReadBlob(ctx, "user1", "blob1", 0, 10); !errors.Is(err, storage.ErrBlobIncomplete) {
+		t.Errorf("Expected %v, got %v", storage.ErrBlobIncomplete, err)
+	}
+	for offset := int64(0); offset < blob.Size; offset += 30 {
+		end := min(offset+30, blob.Size)
+		written, err := s.WriteBlob(ctx, "user1", "blob1", offset, content[offset:end])
+		if err != nil {
+			t.Fatalf("WriteBlob at %d returned an error: %v", offset, err)
+		}
+		if written.Offset != end {
+			t.Errorf("Offset is %d after write, want %d", written.Offset, end)
+		}
+	}
+
+	stored, err := s.GetBlob(ctx, "user1", "blob1")
+	if err != nil || !stored.Complete() {
+		t.Fatalf("Expected complete blob, got %+v and %v", stored, err)
+	}
+	part, err := s.ReadBlob(ctx, "user1", "blob1", 25, 40)
+	if err != nil {
+		t.Fatalf("ReadBlob returned an error: %v", err)
+	}
+	if !bytes.Equal(part, content[25:65]) {
+		t.Errorf("Read %q, want %q", part, content[25:65])
+	}
+	tail, err := s.ReadBlob(ctx, "user1", "blob1", 90, 40)
+	if err != nil || !bytes.Equal(tail, content[90:]) {
+		t.Errorf("Expected the tail of the blob, got %q and %v", tail, err)
+	}
+	size, err := s.BlobsSize(ctx, "user1")
+	if err != nil || size != blob.Size {
+		t.Errorf("Expected blobs size %d, got %d and %v", blob.Size, size, err)
+	}
+}
+
+func testBlobResume(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	content := []byte("resumable upload")
+	mustCreateBlob(t, s, "blob1", "user1", content)
+	if _, err := s.WriteBlob(ctx, "user1", "blob1", 0, content[:6]); err != nil {
+		t.Fatalf("WriteBlob returned an error: %v", err)
+	}
+
+	// Creating the same blob again tells where to continue from
+	blob, err := s.CreateBlob(ctx, models.Blob{ID: "blob1", OwnerID: "user1", Size: int64(len(content)), Hash: hexHash(content)})
+	if err != nil || blob.Offset != 6 {
+		t.Fatalf("Expected blob uploaded up to 6, got %+v and %v", blob, err)
+	}
+	// Chunk sent again after its response was lost is not written twice
+	blob, err = s.WriteBlob(ctx, "user1", "blob1", 0, content[:6])
+	if !errors.Is(err, storage.ErrBlobOffset) || blob.Offset != 6 {
+		t.Errorf("Expected %v with offset 6, got %+v and %v", storage.ErrBlobOffset, blob, err)
+	}
+	// Chunk can't go past the end
+	if _, err = s.WriteBlob(ctx, "user1", "blob1", 6, append(content[6:], 'x')); !errors.Is(err, storage.ErrBlobOffset) {
+		t.Errorf("Expected %v, got %v", storage.ErrBlobOffset, err)
+	}
+	if blob, err = s.WriteBlob(ctx, "user1", "blob1", 6, content[6:]); err != nil || !blob.Complete() {
+		t.Errorf("Expected complete blob, got %+v and %v", blob, err)
+	}
+
+	// Another blob can't take the id
+	_, err = s.CreateBlob(ctx, models.Blob{ID: "blob1", OwnerID: "user1", Size: 1, Hash: hexHash([]byte("x"))})
+	if !errors.Is(err, storage.ErrBlobExists) {
+		t.Errorf("Expected %v, got %v", storage.ErrBlobExists, err)
+	}
+}
+
+func testBlobHashMismatch(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	mustCreateBlob(t, s, "blob1", "user1", []byte("expected"))
+	if _, err := s.WriteBlob(ctx, "user1", "blob1", 0, []byte("expe")); err != nil {
+		t.Fatalf("WriteBlob returned an error: %v", err)
+	}
+	blob, err := s.WriteBlob(ctx, "user1", "blob1", 4, []byte("ctex"))
+	if !errors.Is(err, storage.ErrBlobHash) {
+		t.Fatalf("Expected %v, got %v", storage.ErrBlobHash, err)
+	}
+	// Upload starts again
+	if blob.Offset != 0 {
+		t.Errorf("Expected content dropped, got offset %d", blob.Offset)
+	}
+	if _, err = s.WriteBlob(ctx, "user1", "blob1", 0, []byte("expected")); err != nil {
+		t.Errorf("WriteBlob after reset returned an error: %v", err)
+	}
+	if content, err := s.ReadBlob(ctx, "user1", "blob1", 0, 100); err != nil || string(content) != "expected" {
+		t.Errorf("Expected uploaded content, got %q and %v", content, err)
+	}
+}
+
+func testBlobIsolation(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	content := []byte("private")
+	mustCreateBlob(t, s, "blob1", "user1", content)
+	if _, err := s.WriteBlob(ctx, "user1", "blob1", 0, content); err != nil {
+		t.Fatalf("WriteBlob returned an error: %v", err)
+	}
+
+	if _, err := s.GetBlob(ctx, "user2", "blob1"); !errors.Is(err, storage.ErrBlobNotFound) {
+		t.Errorf("Expected %v, got %v", storage.ErrBlobNotFound, err)
+	}
+	if _, err := s.ReadBlob(ctx, "user2", "blob1", 0, 7); !errors.Is(err, storage.ErrBlobNotFound) {
+		t.Errorf("Expected %v, got %v", storage.ErrBlobNotFound, err)
+	}
+	_, err := s.CreateBlob(ctx, models.Blob{ID: "blob1", OwnerID: "user2", Size: int64(len(content)), Hash: hexHash(content)})
+	if !errors.Is(err, storage.ErrBlobExists) {
+		t.Errorf("Expected %v, got %v", storage.ErrBlobExists, err)
+	}
+	if size, _ := s.BlobsSize(ctx, "user2"); size != 0 {
+		t.Errorf("Expected no blobs of user2, got %d bytes", size)
+	}
+}
+
+func testBlobDedup(t *testing.T, s storage.Storage, blobs *objects) {
+	ctx := context.Background()
+	content := []byte("the same encrypted file")
+	mustUploadBlob(t, s, models.Blob{ID: "blob1", OwnerID: "user1"}, content)
+
+	// The owner doesn't upload the content again
+	blob, err := s.CreateBlob(ctx, models.Blob{ID: "blob2", OwnerID: "user1", Size: int64(len(content)), Hash: hexHash(content)})
+	if err != nil || !blob.Complete() {
+		t.Fatalf("Expected blob with the same content complete, got %+v and %v", blob, err)
+	}
+	if read, err := s.ReadBlob(ctx, "user1", "blob2", 0, 100); err != nil || !bytes.Equal(read, content) {
+		t.Errorf("Expected shared content, got %q and %v", read, err)
+	}
+	if found, err := s.FindBlob(ctx, "user1", hexHash(content)); err != nil || !found.Complete() {
+		t.Errorf("Expected blob found by hash, got %+v and %v", found, err)
+	}
+	if size, err := s.BlobsSize(ctx, "user1"); err != nil || size != int64(len(content)) {
+		t.Errorf("Expected shared content counted once, got %d and %v", size, err)
+	}
+
+	// Another user has to upload it, but it is stored once
+	if _, err = s.FindBlob(ctx, "user2", hexHash(content)); !errors.Is(err, storage.ErrBlobNotFound) {
+		t.Errorf("Expected %v, got %v", storage.ErrBlobNotFound, err)
+	}
+	mustUploadBlob(t, s, models.Blob{ID: "blob3", OwnerID: "user2"}, content)
+	if read, err := s.ReadBlob(ctx, "user2", "blob3", 4, 5); err != nil || string(read) != "same " {
+		t.Errorf("Expected part of shared content, got %q and %v", read, err)
+	}
+	if count := blobs.count(); count != 1 {
+		t.Errorf("Expected content stored once, got %d objects", count)
+	}
+}
+
+func testCollectBlobs(t *testing.T, s storage.Storage, blobs *objects) {
+	ctx := context.Background()
+	shared, old, recent := []byte("shared"), []byte("old version"), []byte("recent")
+	mustUploadBlob(t, s, models.Blob{ID: "used", OwnerID: "user1"}, shared)
+	mustUploadBlob(t, s, models.Blob{ID: "copy", OwnerID: "user1"}, shared)
+	mustUploadBlob(t, s, models.Blob{ID: "history", OwnerID: "user1"}, old)
+	mustUploadBlob(t, s, models.Blob{ID: "recent", OwnerID: "user1", CreatedAt: 200}, recent)
+	mustCreateBlob(t, s, "abandoned", "user1", []byte("never uploaded"))
+	// Reference of another user doesn't keep the blob
+	mustUploadBlob(t, s, models.Blob{ID: "foreign", OwnerID: "user1"}, []byte("foreign"))
+
+	data := wrapper("1", "user1", 10, "file")
+	data.Blobs = []string{"history"}
+	mustSet(t, s, data)
+	data.Blobs = []string{"used"}
+	mustUpdate(t, s, data)
+	other := wrapper("2", "user2", 10, "file")
+	other.Blobs = []string{"foreign"}
+	mustSet(t, s, other)
+
+	count, err := s.CollectBlobs(ctx, 100)
+	if err != nil {
+		t.Fatalf("CollectBlobs returned an error: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 collected blobs, got %d", count)
+	}
+	for _, id := range []string{"copy", "abandoned", "foreign"} {
+		if _, err = s.GetBlob(ctx, "user1", id); !errors.Is(err, storage.ErrBlobNotFound) {
+			t.Errorf("Expected %s collected, got %v", id, err)
+		}
+	}
+	// Content of the collected copy is still used
+	if read, err := s.ReadBlob(ctx, "user1", "used", 0, 100); err != nil || !bytes.Equal(read, shared) {
+		t.Errorf("Expected shared content kept, got %q and %v", read, err)
+	}
+	if read, err := s.ReadBlob(ctx, "user1", "history", 0, 100); err != nil || !bytes.Equal(read, old) {
+		t.Errorf("Expected content of previous version kept, got %q and %v", read, err)
+	}
+	if count := blobs.count(); count != 3 {
+		t.Errorf("Expected 3 stored objects, got %d", count)
+	}
+
+	// Purged model doesn't keep its blobs
+	deleted := wrapper("1", "user1", 20, "")
+	deleted.Data, deleted.DeletedAt = nil, 20
+	mustUpdate(t, s, deleted)
+	if _, err = s.PurgeDeleted(ctx, 50); err != nil {
+		t.Fatalf("PurgeDeleted returned an error: %v", err)
+	}
+	if count, err = s.CollectBlobs(ctx, 100); err != nil || count != 2 {
+		t.Errorf("Expected 2 collected blobs, got %d and %v", count, err)
+	}
+	if _, err = s.ReadBlob(ctx, "user1", "used", 0, 100); !errors.Is(err, storage.ErrBlobNotFound) {
+		t.Errorf("Expected %v, got %v", storage.ErrBlobNotFound, err)
+	}
+	if count := blobs.count(); count != 1 {
+		t.Errorf("Expected only recent content stored, got %d objects", count)
+	}
+}
+
+// mustUploadBlob creates the blob for the content and writes the content in one chunk
+func testUserKeys(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	keys := models.UserKeys{PublicKey: []byte("public"), PrivateKey: []byte("private")}
+	if err := s.SetUserKeys(ctx, "nobody@example.com", keys); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("Expected %v, got %v", storage.ErrUserNotFound, err)
+	}
+	if err := s.CreateUser(ctx, models.User{Email: "user@example.com", Passphrase: "hash"}); err != nil {
+		t.Fatalf("CreateUser returned an error: %v", err)
+	}
+	if user, _ := s.GetUser(ctx, "user@example.com"); user.Keys != nil {
+		t.Errorf("New user has keys %+v", user.Keys)
+	}
+	if err := s.SetUserKeys(ctx, "user@example.com", keys); err != nil {
+		t.Fatalf("SetUserKeys returned an error: %v", err)
+	}
+	// Keys are never replaced, items shared with the user would be lost
+	other := models.UserKeys{PublicKey: []byte("other"), PrivateKey: []byte("other")}
+	if err := s.SetUserKeys(ctx, "user@example.com", other); !errors.Is(err, storage.ErrKeysExist) {
+		t.Errorf("Expected %v, got %v", storage.ErrKeysExist, err)
+	}
+	user, err := s.GetUser(ctx, "user@example.com")
+	if err != nil {
+		t.Fatalf("GetUser returned an error: %v", err)
+	}
+	if user.Keys == nil || !bytes.Equal(user.Keys.PublicKey, keys.PublicKey) ||
+		!bytes.Equal(user.Keys.PrivateKey, keys.PrivateKey) || user.Passphrase != "hash" {
+		t.Errorf("Expected keys %+v, got %+v", keys, user)
+	}
+}
+
+func testShares(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	mustSet(t, s, wrapper("item", "owner", 1, "data"))
+	first := models.Share{ItemID: "item", OwnerID: "owner", UserID: "first", Key: []byte("key1"),
+		Permission: models.PermissionRead, CreatedAt: 1}
+	second := models.Share{ItemID: "item", OwnerID: "owner", UserID: "second", Key: []byte("key2"),
+		Permission: models.PermissionWrite, CreatedAt: 2}
+	for _, share := range []models.Share{second, first} {
+		if err := s.SetShare(ctx, share); err != nil {
+			t.Fatalf("SetShare returned an error: %v", err)
+		}
+	}
+	// Sharing again replaces key and permission only
+	first.Key, first.Permission = []byte("rotated"), models.PermissionWrite
+	again := first
+	again.CreatedAt = 3
+	if err := s.SetShare(ctx, again); err != nil {
+		t.Fatalf("SetShare returned an error: %v", err)
+	}
+
+	shares, err := s.GetItemShares(ctx, "owner", "item")
+	if err != nil {
+		t.Fatalf("GetItemShares returned an error: %v", err)
+	}
+	if len(shares) != 2 || !sameShare(shares[0], first) || !sameShare(shares[1], second) {
+		t.Fatalf("Expected shares %+v and %+v, got %+v", first, second, shares)
+	}
+	if shares, _ = s.GetItemShares(ctx, "first", "item"); len(shares) != 0 {
+		t.Errorf("Shares of item are listed for another owner: %+v", shares)
+	}
+
+	// Only the owner revokes shares
+	if err = s.DeleteShare(ctx, "first", "item", "first"); !errors.Is(err, storage.ErrShareNotFound) {
+		t.Errorf("Expected %v, got %v", storage.ErrShareNotFound, err)
+	}
+	if err = s.DeleteShare(ctx, "owner", "item", "first"); err != nil {
+		t.Fatalf("DeleteShare returned an error: %v", err)
+	}
+	if err = s.DeleteShare(ctx, "owner", "item", "first"); !errors.Is(err, storage.ErrShareNotFound) {
+		t.Errorf("Expected %v, got %v", storage.ErrShareNotFound, err)
+	}
+	if shares, _ = s.GetItemShares(ctx, "owner", "item"); len(shares) != 1 || !sameShare(shares[0], second) {
+		t.Errorf("Expected share %+v left, got %+v", second, shares)
+	}
+}
+
+func testSharedData(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	mustSet(t, s, wrapper("item1", "owner", 1, "data1"))
+	mustSet(t, s, wrapper("item2", "owner", 1, "data2"))
+	mustSet(t, s, wrapper("deleted", "owner", 1, "data"))
+	for i, id := range []string{"item2", "item1", "deleted", "missing"} {
+		share := models.Share{ItemID: id, OwnerID: "owner", UserID: "user", Key: []byte("key"),
+			Permission: models.PermissionRead, CreatedAt: int64(i)}
+		if err := s.SetShare(ctx, share); err != nil {
+			t.Fatalf("SetShare returned an error: %v", err)
+		}
+	}
+	// Share that claims another owner doesn't give access to the item
+	if err := s.SetShare(ctx, models.Share{ItemID: "other", OwnerID: "owner", UserID: "user", CreatedAt: 9}); err != nil {
+		t.Fatalf("SetShare returned an error: %v", err)
+	}
+	mustSet(t, s, wrapper("other", "stranger", 1, "data"))
+	deleted := wrapper("deleted", "owner", 2, "data")
+	deleted.DeletedAt = 2
+	mustUpdate(t, s, deleted)
+	updated := wrapper("item1", "owner", 2, "new data")
+	mustUpdate(t, s, updated)
+
+	items, err := s.GetSharedData(ctx, "user")
+	if err != nil {
+		t.Fatalf("GetSharedData returned an error: %v", err)
+	}
+	if len(items) != 2 || items[0].Data.ID != "item2" || items[1].Data.ID != "item1" {
+		t.Fatalf("Expected item2 and item1 shared, got %+v", items)
+	}
+	assertWrapper(t, items[1].Data, updated)
+	if items[1].Share.ItemID != "item1" || items[1].Share.UserID != "user" || items[1].Data.Revision != 2 {
+		t.Errorf("Expected current revision of item1 with its share, got %+v", items[1])
+	}
+	if items, _ = s.GetSharedData(ctx, "owner"); len(items) != 0 {
+		t.Errorf("Owner sees its own items as shared: %+v", items)
+	}
+}
+
+func testItemKeyKept(t *testing.T, s storage.Storage) {
+	data := wrapper("item", "owner", 1, "data")
+	mustSet(t, s, data)
+	// Rotation of the key is an edit even if client sends the same data
+	data.Key = []byte("key")
+	assertStatus(t, mustUpdate(t, s, data), models.StatusApplied)
+	if got := byID(t, s, "owner")["item"]; !bytes.Equal(got.Key, data.Key) {
+		t.Errorf("Expected key %q, got %q", data.Key, got.Key)
+	}
+}
+
+func testOrgs(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	if _, err := s.GetOrg(ctx, "org"); !errors.Is(err, storage.ErrOrgNotFound) {
+		t.Errorf("Expected %v, got %v", storage.ErrOrgNotFound, err)
+	}
+	org := models.Organization{ID: "org", Name: "Team", KeyVersion: 1, CreatedAt: 1}
+	owner := models.Member{OrgID: "org", UserID: "owner", Role: models.RoleOwner, Key: []byte("key"),
+		KeyVersion: 1, Accepted: true, CreatedAt: 1}
+	if err := s.CreateOrg(ctx, org, owner); err != nil {
+		t.Fatalf("CreateOrg returned an error: %v", err)
+	}
+	other := models.Organization{ID: "other", Name: "Other", KeyVersion: 1, CreatedAt: 2}
+	if err := s.CreateOrg(ctx, other, models.Member{OrgID: "other", UserID: "stranger", Role: models.RoleOwner,
+		Key: []byte("other key"), KeyVersion: 1, Accepted: true, CreatedAt: 2}); err != nil {
+		t.Fatalf("CreateOrg returned an error: %v", err)
+	}
+	if got, err := s.GetOrg(ctx, "org"); err != nil || got != org {
+		t.Errorf("Expected %+v, got %+v and %v", org, got, err)
+	}
+	got, err := s.GetMember(ctx, "org", "owner")
+	if err != nil || !sameMember(got, owner) {
+		t.Errorf("Expected owner %+v, got %+v and %v", owner, got, err)
+	}
+
+	// Organization is listed for its members only
+	memberships, err := s.GetMemberships(ctx, "owner")
+	if err != nil {
+		t.Fatalf("GetMemberships returned an error: %v", err)
+	}
+	if len(memberships) != 1 || memberships[0].Organization != org || !sameMember(memberships[0].Member, owner) {
+		t.Errorf("Expected membership in %+v, got %+v", org, memberships)
+	}
+	if memberships, _ = s.GetMemberships(ctx, "nobody"); len(memberships) != 0 {
+		t.Errorf("User without organizations has memberships %+v", memberships)
+	}
+}
+
+func testOrgMembers(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	owner := models.Member{OrgID: "org", UserID: "owner", Role: models.RoleOwner, Key: []byte("key"),
+		KeyVersion: 1, Accepted: true, CreatedAt: 1}
+	if err := s.CreateOrg(ctx, models.Organization{ID: "org", Name: "Team", KeyVersion: 1}, owner); err != nil {
+		t.Fatalf("CreateOrg returned an error: %v", err)
+	}
+	invited := models.Member{OrgID: "org", UserID: "user", Role: models.RoleReadOnly, Key: []byte("user key"),
+		KeyVersion: 1, CreatedAt: 2}
+	if err := s.SetMember(ctx, invited); err != nil {
+		t.Fatalf("SetMember returned an error: %v", err)
+	}
+	// Setting the member again replaces its role and acceptance, not the time it was invited
+	accepted := invited
+	accepted.Role, accepted.Accepted = models.RoleMember, true
+	again := accepted
+	again.CreatedAt = 3
+	if err := s.SetMember(ctx, again); err != nil {
+		t.Fatalf("SetMember returned an error: %v", err)
+	}
+
+	members, err := s.GetMembers(ctx, "org")
+	if err != nil {
+		t.Fatalf("GetMembers returned an error: %v", err)
+	}
+	if len(members) != 2 || !sameMember(members[0], owner) || !sameMember(members[1], accepted) {
+		t.Fatalf("Expected members %+v and %+v, got %+v", owner, accepted, members)
+	}
+	memberships, err := s.GetMemberships(ctx, "user")
+	if err != nil || len(memberships) != 1 || !sameMember(memberships[0].Member, accepted) {
+		t.Errorf("Expected membership %+v, got %+v and %v", accepted, memberships, err)
+	}
+
+	if err = s.DeleteMember(ctx, "org", "user"); err != nil {
+		t.Fatalf("DeleteMember returned an error: %v", err)
+	}
+	if err = s.DeleteMember(ctx, "org", "user"); !errors.Is(err, storage.ErrMemberNotFound) {
+		t.Errorf("Expected %v, got %v", storage.ErrMemberNotFound, err)
+	}
+	if _, err = s.GetMember(ctx, "org", "user"); !errors.Is(err, storage.ErrMemberNotFound) {
+		t.Errorf("Expected %v, got %v", storage.ErrMemberNotFound, err)
+	}
+	if memberships, _ = s.GetMemberships(ctx, "user"); len(memberships) != 0 {
+		t.Errorf("Removed member has memberships %+v", memberships)
+	}
+}
+
+func testRotateOrgKey(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	if err := s.RotateOrgKey(ctx, "org", 2, nil); !errors.Is(err, storage.ErrOrgNotFound) {
+		t.Errorf("Expected %v, got %v", storage.ErrOrgNotFound, err)
+	}
+	owner := models.Member{OrgID: "org", UserID: "owner", Role: models.RoleOwner, Key: []byte("key"),
+		KeyVersion: 1, Accepted: true, CreatedAt: 1}
+	if err := s.CreateOrg(ctx, models.Organization{ID: "org", Name: "Team", KeyVersion: 1}, owner); err != nil {
+		t.Fatalf("CreateOrg returned an error: %v", err)
+	}
+	member := models.Member{OrgID: "org", UserID: "user", Role: models.RoleMember, Key: []byte("user key"),
+		KeyVersion: 1, Accepted: true, CreatedAt: 2}
+	if err := s.SetMember(ctx, member); err != nil {
+		t.Fatalf("SetMember returned an error: %v", err)
+	}
+
+	rotated := []models.Member{
+		{OrgID: "org", UserID: "owner", Key: []byte("new key")},
+		{OrgID: "org", UserID: "user", Key: []byte("new user key")},
+	}
+	if err := s.RotateOrgKey(ctx, "org", 2, rotated); err != nil {
+		t.Fatalf("RotateOrgKey returned an error: %v", err)
+	}
+	// Rotation made before the last one is rejected
+	if err := s.RotateOrgKey(ctx, "org", 2, rotated); !errors.Is(err, storage.ErrKeyVersion) {
+		t.Errorf("Expected %v, got %v", storage.ErrKeyVersion, err)
+	}
+	if org, _ := s.GetOrg(ctx, "org"); org.KeyVersion != 2 {
+		t.Errorf("Expected key version 2, got %d", org.KeyVersion)
+	}
+	members, err := s.GetMembers(ctx, "org")
+	if err != nil || len(members) != 2 {
+		t.Fatalf("Expected 2 members, got %+v and %v", members, err)
+	}
+	owner.Key, owner.KeyVersion = []byte("new key"), 2
+	member.Key, member.KeyVersion = []byte("new user key"), 2
+	if !sameMember(members[0], owner) || !sameMember(members[1], member) {
+		t.Errorf("Expected members %+v and %+v, got %+v", owner, member, members)
+	}
+}
+
+func testCollections(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	for _, collection := range []models.Collection{
+		{ID: "second", OrgID: "org", Name: "Second", CreatedAt: 2},
+		{ID: "first", OrgID: "org", Name: "First", CreatedAt: 1},
+		{ID: "foreign", OrgID: "other", Name: "Foreign", CreatedAt: 1},
+	} {
+		if err := s.SetCollection(ctx, collection); err != nil {
+			t.Fatalf("SetCollection returned an error: %v", err)
+		}
+	}
+	renamed := models.Collection{ID: "first", OrgID: "org", Name: "Renamed", CreatedAt: 3}
+	if err := s.SetCollection(ctx, renamed); err != nil {
+		t.Fatalf("SetCollection returned an error: %v", err)
+	}
+	// Collection of one organization can't be taken by another
+	stolen := models.Collection{ID: "foreign", OrgID: "org", Name: "Stolen", CreatedAt: 3}
+	if err := s.SetCollection(ctx, stolen); !errors.Is(err, storage.ErrCollectionExists) {
+		t.Errorf("Expected %v, got %v", storage.ErrCollectionExists, err)
+	}
+
+	collections, err := s.GetCollections(ctx, "org")
+	if err != nil {
+		t.Fatalf("GetCollections returned an error: %v", err)
+	}
+	renamed.CreatedAt = 1
+	want := []models.Collection{renamed, {ID: "second", OrgID: "org", Name: "Second", CreatedAt: 2}}
+	if !slices.Equal(collections, want) {
+		t.Errorf("Expected collections %+v, got %+v", want, collections)
+	}
+}
+
+func testEmergency(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	if _, err := s.GetEmergency(ctx, "owner", "friend"); !errors.Is(err, storage.ErrEmergencyNotFound) {
+		t.Errorf("Expected %v, got %v", storage.ErrEmergencyNotFound, err)
+	}
+	friend := models.EmergencyAccess{GrantorID: "owner", GranteeID: "friend", WaitDays: 7, Key: []byte("key"),
+		Status: models.EmergencyIdle, CreatedAt: 2}
+	for _, access := range []models.EmergencyAccess{
+		friend,
+		{GrantorID: "owner", GranteeID: "sister", WaitDays: 1, Key: []byte("sister key"), Status: models.EmergencyIdle, CreatedAt: 1},
+		{GrantorID: "other", GranteeID: "friend", WaitDays: 3, Key: []byte("other key"), Status: models.EmergencyIdle, CreatedAt: 3},
+	} {
+		if err := s.SetEmergency(ctx, access); err != nil {
+			t.Fatalf("SetEmergency returned an error: %v", err)
+		}
+	}
+	// Setting the contact again replaces its request, not the time it was added
+	requested := friend
+	requested.Status, requested.RequestedAt = models.EmergencyRequested, 10
+	again := requested
+	again.CreatedAt = 5
+	if err := s.SetEmergency(ctx, again); err != nil {
+		t.Fatalf("SetEmergency returned an error: %v", err)
+	}
+	if got, err := s.GetEmergency(ctx, "owner", "friend"); err != nil || !sameEmergency(got, requested) {
+		t.Errorf("Expected %+v, got %+v and %v", requested, got, err)
+	}
+
+	contacts, err := s.GetEmergencyContacts(ctx, "owner")
+	if err != nil {
+		t.Fatalf("GetEmergencyContacts returned an error: %v", err)
+	}
+	if len(contacts) != 2 || contacts[0].GranteeID != "sister" || !sameEmergency(contacts[1], requested) {
+		t.Errorf("Expected contacts sister and %+v, got %+v", requested, contacts)
+	}
+	grantors, err := s.GetEmergencyGrantors(ctx, "friend")
+	if err != nil {
+		t.Fatalf("GetEmergencyGrantors returned an error: %v", err)
+	}
+	if len(grantors) != 2 || grantors[0].GrantorID != "owner" || grantors[1].GrantorID != "other" {
+		t.Errorf("Expected grantors owner and other, got %+v", grantors)
+	}
+
+	if err = s.DeleteEmergency(ctx, "owner", "friend"); err != nil {
+		t.Fatalf("DeleteEmergency returned an error: %v", err)
+	}
+	if err = s.DeleteEmergency(ctx, "owner", "friend"); !errors.Is(err, storage.ErrEmergencyNotFound) {
+		t.Errorf("Expected %v, got %v", storage.ErrEmergencyNotFound, err)
+	}
+	if grantors, _ = s.GetEmergencyGrantors(ctx, "friend"); len(grantors) != 1 || grantors[0].GrantorID != "other" {
+		t.Errorf("Expected grantor other after removal, got %+v", grantors)
+	}
+}
+
+func testSends(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	for _, send := range []models.Send{
+		{ID: "second", OwnerID: "owner", Data: []byte("secret"), ExpiresAt: 100, MaxViews: 1, CreatedAt: 2},
+		{ID: "first", OwnerID: "owner", Data: []byte("secret"), ExpiresAt: 50, MaxViews: 1, CreatedAt: 1},
+		{ID: "other", OwnerID: "other", Data: []byte("secret"), ExpiresAt: 200, MaxViews: 1, CreatedAt: 3},
+	} {
+		if err := s.CreateSend(ctx, send); err != nil {
+			t.Fatalf("CreateSend returned an error: %v", err)
+		}
+	}
+	sends, err := s.GetSends(ctx, "owner")
+	if err != nil {
+		t.Fatalf("GetSends returned an error: %v", err)
+	}
+	if len(sends) != 2 || sends[0].ID != "first" || sends[1].ID != "second" || string(sends[1].Data) != "secret" {
+		t.Errorf("Expected sends first and second, got %+v", sends)
+	}
+
+	// Only the owner deletes its send
+	if err = s.DeleteSend(ctx, "owner", "other"); !errors.Is(err, storage.ErrSendNotFound) {
+		t.Errorf("Expected %v, got %v", storage.ErrSendNotFound, err)
+	}
+	if err = s.DeleteSend(ctx, "owner", "second"); err != nil {
+		t.Fatalf("DeleteSend returned an error: %v", err)
+	}
+	if _, err = s.ViewSend(ctx, "second", 0); !errors.Is(err, storage.ErrSendNotFound) {
+		t.Errorf("Expected deleted send not found, got %v", err)
+	}
+
+	// Sends that expired at the time are removed
+	count, err := s.DeleteExpiredSends(ctx, 50)
+	if err != nil {
+		t.Fatalf("DeleteExpiredSends returned an error: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 expired send, got %d", count)
+	}
+	if sends, _ = s.GetSends(ctx, "owner"); len(sends) != 0 {
+		t.Errorf("Expected no sends of the owner, got %+v", sends)
+	}
+	if sends, _ = s.GetSends(ctx, "other"); len(sends) != 1 {
+		t.Errorf("Expected send of other kept, got %+v", sends)
+	}
+}
+
+func testSendViews(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	send := models.Send{ID: "send", OwnerID: "owner", Data: []byte("secret"), ExpiresAt: 100, MaxViews: 2, CreatedAt: 1}
+	if err := s.CreateSend(ctx, send); err != nil {
+		t.Fatalf("CreateSend returned an error: %v", err)
+	}
+	if _, err := s.ViewSend(ctx, "send", 100); !errors.Is(err, storage.ErrSendNotFound) {
+		t.Errorf("Expected expired send not found, got %v", err)
+	}
+	if _, err := s.ViewSend(ctx, "unknown", 0); !errors.Is(err, storage.ErrSendNotFound) {
+		t.Errorf("Expected %v, got %v", storage.ErrSendNotFound, err)
+	}
+
+	viewed, err := s.ViewSend(ctx, "send", 10)
+	if err != nil {
+		t.Fatalf("ViewSend returned an error: %v", err)
+	}
+	if viewed.Views != 1 || string(viewed.Data) != "secret" {
+		t.Errorf("Expected first view of the secret, got %+v", viewed)
+	}
+	if sends, _ := s.GetSends(ctx, "owner"); len(sends) != 1 || sends[0].Views != 1 {
+		t.Errorf("Expected the view counted, got %+v", sends)
+	}
+
+	// The last view removes the send
+	if viewed, err = s.ViewSend(ctx, "send", 10); err != nil || viewed.Views != 2 {
+		t.Fatalf("Expected the last view, got %+v and %v", viewed, err)
+	}
+	if _, err = s.ViewSend(ctx, "send", 10); !errors.Is(err, storage.ErrSendNotFound) {
+		t.Errorf("Expected viewed send not found, got %v", err)
+	}
+	if sends, _ := s.GetSends(ctx, "owner"); len(sends) != 0 {
+		t.Errorf("Expected viewed send removed, got %+v", sends)
+	}
+}
+
+func sameEmergency(got, want models.EmergencyAccess) bool {
+	return got.GrantorID == want.GrantorID && got.GranteeID == want.GranteeID && got.WaitDays == want.WaitDays &&
+		bytes.Equal(got.Key, want.Key) && got.Status == want.Status && got.RequestedAt == want.RequestedAt &&
+		got.CreatedAt == want.CreatedAt
+}
+
+func sameMember(got, want models.Member) bool {
+	return got.OrgID == want.OrgID && got.UserID == want.UserID && got.Role == want.Role &&
+		bytes.Equal(got.Key, want.Key) && got.KeyVersion == want.KeyVersion && got.Accepted == want.Accepted &&
+		got.CreatedAt == want.CreatedAt
+}
+
+func sameShare(got, want models.Share) bool {
+	return got.ItemID == want.ItemID && got.OwnerID == want.OwnerID && got.UserID == want.UserID &&
+		bytes.Equal(got.Key, want.Key) && got.Permission == want.Permission && got.CreatedAt == want.CreatedAt
+}
+
+func mustUploadBlob(t *testing.T, s storage.Storage, blob models.Blob, content []byte) {
+	t.Helper()
+	ctx := context.Background()
+	blob.Size, blob.Hash = int64(len(content)), hexHash(content)
+	created, err := s.CreateBlob(ctx, blob)
+	if err != nil {
+		t.Fatalf("CreateBlob returned an error: %v", err)
+	}
+	if created.Complete() {
+		return
+	}
+	if blob, err = s.WriteBlob(ctx, blob.OwnerID, blob.ID, 0, content); err != nil || !blob.Complete() {
+		t.Fatalf("Expected uploaded blob, got %+v and %v", blob, err)
+	}
+}
+
+// mustCreateBlob creates a blob for the content
+func mustCreateBlob(t *testing.T, s storage.Storage, id, owner string, content []byte) models.Blob {
+	t.Helper()
+	blob, err := s.CreateBlob(context.Background(),
+		models.Blob{ID: id, OwnerID: owner, Size: int64(len(content)), Hash: hexHash(content)})
+	if err != nil {
+		t.Fatalf("CreateBlob returned an error: %v", err)
+	}
+	if blob.Offset != 0 {
+		t.Errorf("New blob has offset %d", blob.Offset)
+	}
+	return blob
+}
+
+func hexHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func mustChanges(t *testing.T, s storage.Storage, userID string, since int64) storage.Changes {
+	t.Helper()
+	changes, err := s.GetChanges(context.Background(), userID, since)
+	if err != nil {
+		t.Fatalf("GetChanges returned an error: %v", err)
+	}
+	return changes
+}
+
+func wrapper(id, owner string, updatedAt int64, data string) models.DataWrapper {
+	return models.DataWrapper{
+		ID:        id,
+		OwnerID:   owner,
+		Type:      models.ArbitraryTextType,
+		Name:      "item " + id,
+		CreatedAt: updatedAt,
+		UpdatedAt: updatedAt,
+		Data:      []byte(data),
+	}
+}
+
+// mustUpdate sets the data as an edit of the stored revision, like an up to date client does
+func mustUpdate(t *testing.T, s storage.Storage, data models.DataWrapper) models.SyncStatus {
+	t.Helper()
+	data.Revision = byID(t, s, data.OwnerID)[data.ID].Revision
+	return mustSet(t, s, data)
+}
+
+// mustSet sets the data and returns its sync status
+func mustSet(t *testing.T, s storage.Storage, data models.DataWrapper) models.SyncStatus {
+	t.Helper()
+	result, err := s.SetData(context.Background(), data)
+	if err != nil {
+		t.Fatalf("SetData returned an error: %v", err)
+	}
+	if result.ID != data.ID {
+		t.Errorf("SetData returned result for %s, want %s", result.ID, data.ID)
+	}
+	return result.Status
+}
+
+func assertStatus(t *testing.T, got, want models.SyncStatus) {
+	t.Helper()
+	if got != want {
+		t.Errorf("Status is %s, want %s", got, want)
+	}
+}
+
+func byID(t *testing.T, s storage.Storage, userID string) map[string]models.DataWrapper {
+	t.Helper()
+	data, err := s.GetData(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("GetData returned an error: %v", err)
+	}
+	result := make(map[string]models.DataWrapper, len(data))
+	for _, item := range data {
+		if _, ok := result[item.ID]; ok {
+			t.Fatalf("GetData returned item %s twice", item.ID)
+		}
+		result[item.ID] = item
+	}
+	return result
+}
+
+func assertWrapper(t *testing.T, got, want models.DataWrapper) {
+	t.Helper()
+	if got.ID != want.ID || got.OwnerID != want.OwnerID || got.Name != want.Name ||
+		got.UpdatedAt != want.UpdatedAt || got.DeletedAt != want.DeletedAt || !bytes.Equal(got.Data, want.Data) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
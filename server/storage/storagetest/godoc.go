@@ -0,0 +1,3 @@
+// Package storagetest contains the conformance suite for storage.Storage implementations
+// Every backend (mongo, in-memory and any future one) must pass Run to be used by the server
+package storagetest
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"bytes"
+	"slices"
+
+	"github.com/gynshu-one/goph-keeper/common/models"
+)
+
+// Reasons of sync results that were not applied
+const (
+	reasonPurged   = "item was permanently removed"
+	reasonForeign  = "item belongs to another user"
+	reasonConflict = "item was changed on server since it was edited"
+	reasonLabel    = "label of item can't be stored in plaintext again"
+)
+
+// sealsLabel tells whether data seals the plaintext label of the stored model
+// type and label version are immutable otherwise, plaintext names of previous versions are removed from history then
+func sealsLabel(stored, data models.DataWrapper) bool {
+	return data.LabelVersion > stored.LabelVersion
+}
+
+// compare decides whether data can replace the stored model
+// data must be an edit of the current stored revision, client clock doesn't matter
+// returns result with empty status if it can
+func compare(stored, data models.DataWrapper) models.SyncResult {
+	result := models.SyncResult{ID: data.ID}
+	switch {
+	case stored.OwnerID != data.OwnerID:
+		result.Status, result.Reason = models.StatusRejected, reasonForeign
+	case sameContent(stored, data):
+		// e.g. client didn't receive the response of the previous sync and sent the data again
+		result.Status = models.StatusStale
+	case stored.Revision != data.Revision:
+		// data was edited on top of another version, client has to resolve the conflict
+		server := copyWrapper(stored)
+		result.Status, result.Reason, result.Server = models.StatusConflict, reasonConflict, &server
+	case data.LabelVersion < stored.LabelVersion:
+		result.Status, result.Reason = models.StatusRejected, reasonLabel
+	}
+	return result
+}
+
+// sameContent checks if data has the same content as the stored model
+func sameContent(stored, data models.DataWrapper) bool {
+	return stored.Name == data.Name && stored.LabelVersion == data.LabelVersion && stored.DeletedAt == data.DeletedAt && bytes.Equal(stored.Data, data.Data) &&
+		slices.Equal(stored.Blobs, data.Blobs) && bytes.Equal(stored.Key, data.Key) && stored.CollectionID == data.CollectionID &&
+		stored.FolderID == data.FolderID
+}
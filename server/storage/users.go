@@ -2,18 +2,21 @@ package storage
 
 import (
 	"context"
+	"errors"
+
 	"github.com/gynshu-one/goph-keeper/common/models"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // CreateUser creates a new user in the database
+// returns ErrUserExists if the email is already taken
 func (s *storage) CreateUser(ctx context.Context, user models.User) error {
 	// Try to create a new user
 	_, err := s.userCollection.InsertOne(ctx, user)
 	if err != nil {
 		if mongo.IsDuplicateKeyError(err) {
-			return err
+			return ErrUserExists
 		}
 		return err
 	}
@@ -21,12 +24,34 @@ func (s *storage) CreateUser(ctx context.Context, user models.User) error {
 }
 
 // GetUser  returns the user with the given email
+// returns ErrUserNotFound if there is no such user
 func (s *storage) GetUser(ctx context.Context, email string) (models.User, error) {
 	var user models.User
 	filter := bson.D{{"_id", email}}
 	err := s.userCollection.FindOne(ctx, filter).Decode(&user)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return models.User{}, ErrUserNotFound
+		}
 		return models.User{}, err
 	}
 	return user, nil
 }
+
+// SetUserKeys sets keys of the user if it has none
+// returns ErrUserNotFound if there is no such user and ErrKeysExist if the user has keys
+func (s *storage) SetUserKeys(ctx context.Context, email string, keys models.UserKeys) error {
+	res, err := s.userCollection.UpdateOne(ctx,
+		bson.D{{"_id", email}, {"keys", bson.D{{"$exists", false}}}},
+		bson.D{{"$set", bson.D{{"keys", keys}}}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 1 {
+		return nil
+	}
+	if _, err = s.GetUser(ctx, email); err != nil {
+		return err
+	}
+	return ErrKeysExist
+}
@@ -17,7 +17,7 @@ func TestCreateUser(t *testing.T) {
 	mt.Run("test", func(mt *mtest.T) {
 		mt.AddMockResponses(bson.D{{"ok", 1}})
 		db := mt.Client.Database("test")
-		s := NewStorage(db.Collection("user-data"), db.Collection("users"))
+		s := NewStorage(db, Options{})
 		// Define a test user
 		user := models.User{
 			Email:      "test1@example.com",
@@ -47,7 +47,7 @@ func TestGetUser(t *testing.T) {
 				{"ok", 1},
 				{"key", "value"}}))
 		db := mt.Client.Database("test")
-		s := NewStorage(db.Collection("user-data"), db.Collection("users"))
+		s := NewStorage(db, Options{})
 
 		// Create the test user
 		_, err := s.GetUser(context.Background(), "test1@example.com")